@@ -0,0 +1,19 @@
+// Package blob provides the storage backends for uploaded media: a
+// directory on the local filesystem and any S3-compatible object store.
+package blob
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+var ErrNotFound = errors.New("blob not found")
+
+// Store keeps opaque blobs by key. Keys are slash separated paths such as
+// "cars/<id>/<image>.jpg"; Put replaces any blob under the same key.
+type Store interface {
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
@@ -0,0 +1,108 @@
+package blob
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileStoreRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewFileStore(t.TempDir())
+	require.NoError(t, err)
+
+	require.NoError(t, s.Put(ctx, "cars/1/a.jpg", []byte("image"), "image/jpeg"))
+
+	body, err := s.Get(ctx, "cars/1/a.jpg")
+	require.NoError(t, err)
+	data, _ := io.ReadAll(body)
+	body.Close()
+	assert.Equal(t, []byte("image"), data)
+
+	require.NoError(t, s.Delete(ctx, "cars/1/a.jpg"))
+	require.NoError(t, s.Delete(ctx, "cars/1/a.jpg"))
+	_, err = s.Get(ctx, "cars/1/a.jpg")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestFileStoreStaysInDirectory(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	s, err := NewFileStore(dir + "/media")
+	require.NoError(t, err)
+
+	// ".." cannot climb above the root of the store
+	require.NoError(t, s.Put(ctx, "../../escape.txt", []byte("x"), "text/plain"))
+	body, err := s.Get(ctx, "escape.txt")
+	require.NoError(t, err)
+	body.Close()
+
+	assert.Error(t, s.Put(ctx, "/", []byte("x"), "text/plain"))
+}
+
+// fakeS3 keeps objects in memory and checks every request is signed.
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	sum := sha256.Sum256(body)
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=key/") ||
+		r.Header.Get("X-Amz-Content-Sha256") != hex.EncodeToString(sum[:]) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch r.Method {
+	case http.MethodPut:
+		f.objects[r.URL.Path] = body
+	case http.MethodGet:
+		data, ok := f.objects[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write(data)
+	case http.MethodDelete:
+		delete(f.objects, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func TestS3StoreRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	fake := &fakeS3{objects: make(map[string][]byte)}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	s, err := NewS3Store(S3Config{
+		Endpoint: server.URL, Bucket: "media", AccessKeyID: "key", SecretAccessKey: "secret", PathStyle: true,
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, s.Put(ctx, "cars/1/a b.jpg", []byte("image"), "image/jpeg"))
+	assert.Contains(t, fake.objects, "/media/cars/1/a b.jpg")
+
+	body, err := s.Get(ctx, "cars/1/a b.jpg")
+	require.NoError(t, err)
+	data, _ := io.ReadAll(body)
+	body.Close()
+	assert.Equal(t, []byte("image"), data)
+
+	require.NoError(t, s.Delete(ctx, "cars/1/a b.jpg"))
+	_, err = s.Get(ctx, "cars/1/a b.jpg")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
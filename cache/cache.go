@@ -0,0 +1,16 @@
+// Package cache provides the key/value backends used by the caching
+// service decorators: an in-process LRU and a Redis client.
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// Cache stores opaque values by key. A zero ttl means the entry never
+// expires on its own.
+type Cache interface {
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, keys ...string) error
+}
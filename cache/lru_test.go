@@ -0,0 +1,57 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLRUEvictsLeastRecentlyUsed(t *testing.T) {
+	ctx := context.Background()
+	c := NewLRU(2)
+
+	c.Set(ctx, "a", []byte("1"), 0)
+	c.Set(ctx, "b", []byte("2"), 0)
+	_, _, _ = c.Get(ctx, "a") // a becomes most recently used
+	c.Set(ctx, "c", []byte("3"), 0)
+
+	_, ok, _ := c.Get(ctx, "b")
+	assert.False(t, ok, "b should have been evicted")
+
+	value, ok, _ := c.Get(ctx, "a")
+	assert.True(t, ok)
+	assert.Equal(t, []byte("1"), value)
+	assert.Equal(t, 2, c.Len())
+}
+
+func TestLRUExpiresEntries(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	c := NewLRU(10)
+	c.now = func() time.Time { return now }
+
+	c.Set(ctx, "a", []byte("1"), time.Minute)
+	c.Set(ctx, "b", []byte("2"), 0)
+
+	now = now.Add(2 * time.Minute)
+
+	_, ok, _ := c.Get(ctx, "a")
+	assert.False(t, ok, "a should have expired")
+	_, ok, _ = c.Get(ctx, "b")
+	assert.True(t, ok, "entries without ttl never expire")
+}
+
+func TestLRUDelete(t *testing.T) {
+	ctx := context.Background()
+	c := NewLRU(10)
+
+	c.Set(ctx, "a", []byte("1"), 0)
+	c.Set(ctx, "b", []byte("2"), 0)
+	assert.NoError(t, c.Delete(ctx, "a", "missing"))
+
+	_, ok, _ := c.Get(ctx, "a")
+	assert.False(t, ok)
+	assert.Equal(t, 1, c.Len())
+}
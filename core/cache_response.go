@@ -0,0 +1,103 @@
+package core
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/adohong4/carZone/utils"
+)
+
+// ComputeETag returns a strong ETag built from the hash of body.
+func ComputeETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// SetCacheHeaders writes the validators and Cache-Control for a cacheable
+// response. A zero lastModified omits Last-Modified. Responses sit behind
+// AuthMiddleware, so they are marked private for shared caches.
+func SetCacheHeaders(w http.ResponseWriter, etag string, lastModified time.Time, maxAge time.Duration) {
+	if etag != "" {
+		w.Header().Set("ETag", etag)
+	}
+	if !lastModified.IsZero() {
+		w.Header().Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+	w.Header().Set("Cache-Control", fmt.Sprintf("private, max-age=%d", int(maxAge.Seconds())))
+}
+
+// IsNotModified evaluates If-None-Match and If-Modified-Since as described
+// in RFC 7232: If-None-Match wins when present, If-Modified-Since is only
+// consulted without it.
+func IsNotModified(r *http.Request, etag string, lastModified time.Time) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		return etagMatches(inm, etag)
+	}
+
+	ims := r.Header.Get("If-Modified-Since")
+	if ims == "" || lastModified.IsZero() {
+		return false
+	}
+	since, err := http.ParseTime(ims)
+	if err != nil {
+		return false
+	}
+	// HTTP dates have second precision
+	return !lastModified.Truncate(time.Second).After(since)
+}
+
+// etagMatches does the weak comparison If-None-Match asks for.
+func etagMatches(header, etag string) bool {
+	if etag == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+		if strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// SendNotModified answers with 304 and no body. The validators must have
+// been set already.
+func SendNotModified(w http.ResponseWriter) {
+	w.WriteHeader(utils.NotModified)
+}
+
+// SendCached sends the response with ETag, Last-Modified and Cache-Control
+// headers, or a bare 304 when the request's conditional headers show the
+// client already holds this exact representation.
+func (s *SuccessResponse) SendCached(w http.ResponseWriter, r *http.Request, lastModified time.Time, maxAge time.Duration) {
+	var body bytes.Buffer
+	if err := json.NewEncoder(&body).Encode(s); err != nil {
+		s.Send(w)
+		return
+	}
+
+	etag := ComputeETag(body.Bytes())
+	SetCacheHeaders(w, etag, lastModified, maxAge)
+
+	if IsNotModified(r, etag, lastModified) {
+		SendNotModified(w)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(s.Status)
+	w.Write(body.Bytes())
+}
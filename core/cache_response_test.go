@@ -0,0 +1,60 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSendCachedSetsValidators(t *testing.T) {
+	lastModified := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	r := httptest.NewRequest(http.MethodGet, "/cars/1", nil)
+	w := httptest.NewRecorder()
+
+	NewOK("Car retrieved successfully", map[string]string{"name": "Camry"}).SendCached(w, r, lastModified, time.Minute)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotEmpty(t, w.Header().Get("ETag"))
+	assert.Equal(t, "Wed, 01 May 2024 10:00:00 GMT", w.Header().Get("Last-Modified"))
+	assert.Equal(t, "private, max-age=60", w.Header().Get("Cache-Control"))
+	assert.Contains(t, w.Body.String(), "Camry")
+}
+
+func TestSendCachedIfNoneMatch(t *testing.T) {
+	first := httptest.NewRecorder()
+	NewOK("", "payload").SendCached(first, httptest.NewRequest(http.MethodGet, "/", nil), time.Time{}, time.Minute)
+	etag := first.Header().Get("ETag")
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("If-None-Match", `"other", W/`+etag)
+	w := httptest.NewRecorder()
+	NewOK("", "payload").SendCached(w, r, time.Time{}, time.Minute)
+
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Empty(t, w.Body.String())
+	assert.Equal(t, etag, w.Header().Get("ETag"))
+
+	r.Header.Set("If-None-Match", `"other"`)
+	w = httptest.NewRecorder()
+	NewOK("", "changed").SendCached(w, r, time.Time{}, time.Minute)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestIsNotModifiedSince(t *testing.T) {
+	lastModified := time.Date(2024, 5, 1, 10, 0, 0, 500, time.UTC)
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("If-Modified-Since", "Wed, 01 May 2024 10:00:00 GMT")
+	assert.True(t, IsNotModified(r, `"abc"`, lastModified))
+
+	r.Header.Set("If-Modified-Since", "Wed, 01 May 2024 09:59:59 GMT")
+	assert.False(t, IsNotModified(r, `"abc"`, lastModified))
+
+	// If-None-Match takes precedence over If-Modified-Since
+	r.Header.Set("If-Modified-Since", "Wed, 01 May 2024 10:00:00 GMT")
+	r.Header.Set("If-None-Match", `"xyz"`)
+	assert.False(t, IsNotModified(r, `"abc"`, lastModified))
+}
@@ -62,6 +62,10 @@ type ForbiddenError struct {
 	*ErrorResponse
 }
 
+type TooManyRequestsError struct {
+	*ErrorResponse
+}
+
 func NewConflictRequestError(message ...string) *ConflictRequestError {
 	msg := utils.HTTPStatusMap[utils.Conflict].Reason
 	if len(message) > 0 {
@@ -112,6 +116,16 @@ func NewForbiddenError(message ...string) *ForbiddenError {
 	}
 }
 
+func NewTooManyRequestsError(message ...string) *TooManyRequestsError {
+	msg := utils.HTTPStatusMap[utils.TooManyRequests].Reason
+	if len(message) > 0 {
+		msg = message[0]
+	}
+	return &TooManyRequestsError{
+		ErrorResponse: NewErrorResponse(msg, utils.TooManyRequests),
+	}
+}
+
 // Send Error Response
 func SendErrorResponse(w http.ResponseWriter, err *ErrorResponse) {
 	w.Header().Set("Content-Type", "application/json")
@@ -0,0 +1,233 @@
+// Package decimal does exact base-10 arithmetic for money, tax and discount
+// computations, where float64 rounding errors are not acceptable.
+package decimal
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+var ErrSyntax = errors.New("invalid decimal")
+
+// Decimal is an exact decimal number. The zero value is 0, values are
+// immutable.
+type Decimal struct {
+	r *big.Rat
+}
+
+var (
+	Zero    = Decimal{}
+	Hundred = New(100, 0)
+)
+
+// New returns value * 10^-scale, New(1999, 2) is 19.99.
+func New(value int64, scale int32) Decimal {
+	r := new(big.Rat).SetInt64(value)
+	if scale > 0 {
+		r.Quo(r, pow10(scale))
+	} else if scale < 0 {
+		r.Mul(r, pow10(-scale))
+	}
+	return Decimal{r: r}
+}
+
+// Parse reads a plain decimal like "-12.50". Exponents, fractions and
+// thousands separators are refused.
+func Parse(s string) (Decimal, error) {
+	s = strings.TrimSpace(s)
+	digits := strings.TrimPrefix(strings.TrimPrefix(s, "-"), "+")
+	whole, frac, _ := strings.Cut(digits, ".")
+	if whole == "" && frac == "" || !allDigits(whole) || !allDigits(frac) {
+		return Zero, fmt.Errorf("%w: %q", ErrSyntax, s)
+	}
+
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return Zero, fmt.Errorf("%w: %q", ErrSyntax, s)
+	}
+	return Decimal{r: r}, nil
+}
+
+// FromFloat converts f through its shortest decimal representation, so
+// 19.99 becomes exactly 19.99.
+func FromFloat(f float64) Decimal {
+	d, err := Parse(strconv.FormatFloat(f, 'f', -1, 64))
+	if err != nil {
+		return Zero
+	}
+	return d
+}
+
+func (d Decimal) rat() *big.Rat {
+	if d.r == nil {
+		return new(big.Rat)
+	}
+	return d.r
+}
+
+func (d Decimal) Add(o Decimal) Decimal {
+	return Decimal{r: new(big.Rat).Add(d.rat(), o.rat())}
+}
+
+func (d Decimal) Sub(o Decimal) Decimal {
+	return Decimal{r: new(big.Rat).Sub(d.rat(), o.rat())}
+}
+
+func (d Decimal) Mul(o Decimal) Decimal {
+	return Decimal{r: new(big.Rat).Mul(d.rat(), o.rat())}
+}
+
+// Div divides d by o, which must not be zero.
+func (d Decimal) Div(o Decimal) Decimal {
+	return Decimal{r: new(big.Rat).Quo(d.rat(), o.rat())}
+}
+
+func (d Decimal) Neg() Decimal {
+	return Decimal{r: new(big.Rat).Neg(d.rat())}
+}
+
+func (d Decimal) Cmp(o Decimal) int {
+	return d.rat().Cmp(o.rat())
+}
+
+func (d Decimal) Equal(o Decimal) bool {
+	return d.Cmp(o) == 0
+}
+
+func (d Decimal) Sign() int {
+	return d.rat().Sign()
+}
+
+func (d Decimal) IsZero() bool {
+	return d.Sign() == 0
+}
+
+// Shift returns d * 10^places, Shift(2) turns 19.99 into 1999.
+func (d Decimal) Shift(places int32) Decimal {
+	return d.Mul(New(1, -places))
+}
+
+// Int64 returns d as an integer. ok is false when d has decimals or does
+// not fit in an int64.
+func (d Decimal) Int64() (n int64, ok bool) {
+	r := d.rat()
+	if !r.IsInt() || !r.Num().IsInt64() {
+		return 0, false
+	}
+	return r.Num().Int64(), true
+}
+
+// Round rounds to places decimals, halves away from zero as is usual for
+// invoices.
+func (d Decimal) Round(places int32) Decimal {
+	scale := pow10(places)
+	scaled := new(big.Rat).Mul(d.rat(), scale)
+
+	num, den := scaled.Num(), scaled.Denom()
+	q, m := new(big.Int).QuoRem(num, den, new(big.Int))
+	// |m| * 2 >= den rounds away from zero
+	if m.Abs(m).Lsh(m, 1).Cmp(den) >= 0 {
+		if num.Sign() < 0 {
+			q.Sub(q, big.NewInt(1))
+		} else {
+			q.Add(q, big.NewInt(1))
+		}
+	}
+	return Decimal{r: new(big.Rat).Quo(new(big.Rat).SetInt(q), scale)}
+}
+
+// StringFixed formats d rounded to exactly places decimals.
+func (d Decimal) StringFixed(places int32) string {
+	return d.Round(places).rat().FloatString(int(places))
+}
+
+// String formats d with as many decimals as it needs, up to 18.
+func (d Decimal) String() string {
+	s := d.rat().FloatString(18)
+	if strings.Contains(s, ".") {
+		s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	}
+	if s == "-0" {
+		return "0"
+	}
+	return s
+}
+
+// Float64 is for display only, computations stay in Decimal.
+func (d Decimal) Float64() float64 {
+	f, _ := d.rat().Float64()
+	return f
+}
+
+// MarshalJSON writes d as a string, JSON numbers are read as floats by most
+// clients.
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.Quote(d.String())), nil
+}
+
+// UnmarshalJSON accepts strings and numbers, numbers are read from their
+// text so no float rounding happens.
+func (d *Decimal) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if s == "null" {
+		*d = Zero
+		return nil
+	}
+	if unquoted, err := strconv.Unquote(s); err == nil {
+		s = unquoted
+	}
+	parsed, err := Parse(s)
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
+
+// Value stores d as text, which NUMERIC columns accept.
+func (d Decimal) Value() (driver.Value, error) {
+	return d.String(), nil
+}
+
+func (d *Decimal) Scan(src interface{}) error {
+	var (
+		parsed Decimal
+		err    error
+	)
+	switch v := src.(type) {
+	case nil:
+		parsed = Zero
+	case []byte:
+		parsed, err = Parse(string(v))
+	case string:
+		parsed, err = Parse(v)
+	case int64:
+		parsed = New(v, 0)
+	case float64:
+		parsed = FromFloat(v)
+	default:
+		err = fmt.Errorf("cannot scan %T into a decimal", src)
+	}
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
+
+func pow10(n int32) *big.Rat {
+	return new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil))
+}
+
+func allDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,84 @@
+package decimal
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func must(t *testing.T, s string) Decimal {
+	d, err := Parse(s)
+	require.NoError(t, err)
+	return d
+}
+
+func TestParse(t *testing.T) {
+	assert.Equal(t, "12.5", must(t, "12.50").String())
+	assert.Equal(t, "-0.07", must(t, "-.07").String())
+	assert.Equal(t, "3", must(t, "+3.").String())
+
+	for _, invalid := range []string{"", ".", "1e3", "1/3", "1,000", "abc", "--1"} {
+		_, err := Parse(invalid)
+		assert.ErrorIs(t, err, ErrSyntax, invalid)
+	}
+}
+
+func TestArithmeticIsExact(t *testing.T) {
+	// 0.1 + 0.2 is not 0.3 in float64
+	assert.True(t, must(t, "0.1").Add(must(t, "0.2")).Equal(must(t, "0.3")))
+	assert.Equal(t, "19.99", FromFloat(19.99).String())
+	assert.Equal(t, "59.97", New(1999, 2).Mul(New(3, 0)).String())
+	assert.Equal(t, "0.0825", must(t, "8.25").Div(Hundred).String())
+}
+
+func TestRound(t *testing.T) {
+	cases := map[string]string{
+		"1.005":  "1.01",
+		"1.004":  "1",
+		"-1.005": "-1.01",
+		"2.5":    "2.5",
+		"0.125":  "0.13",
+	}
+	for in, want := range cases {
+		assert.Equal(t, want, must(t, in).Round(2).String(), in)
+	}
+	assert.Equal(t, "3", must(t, "2.5").Round(0).String())
+	assert.Equal(t, "1.00", must(t, "0.999").StringFixed(2))
+	assert.Equal(t, "0.33", New(1, 0).Div(New(3, 0)).StringFixed(2))
+}
+
+func TestJSON(t *testing.T) {
+	var v struct {
+		A Decimal `json:"a"`
+		B Decimal `json:"b"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(`{"a": "10.10", "b": 0.30}`), &v))
+	assert.Equal(t, "10.1", v.A.String())
+	assert.Equal(t, "0.3", v.B.String())
+
+	out, err := json.Marshal(v)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"a": "10.1", "b": "0.3"}`, string(out))
+}
+
+func TestScan(t *testing.T) {
+	var d Decimal
+	require.NoError(t, d.Scan([]byte("123.45")))
+	assert.Equal(t, "123.45", d.String())
+	require.NoError(t, d.Scan(int64(7)))
+	assert.Equal(t, "7", d.String())
+	assert.Error(t, d.Scan(true))
+}
+
+func TestShiftInt64(t *testing.T) {
+	n, ok := must(t, "19.99").Shift(2).Int64()
+	assert.True(t, ok)
+	assert.Equal(t, int64(1999), n)
+
+	_, ok = must(t, "19.999").Shift(2).Int64()
+	assert.False(t, ok)
+	_, ok = must(t, "99999999999999999999").Int64()
+	assert.False(t, ok)
+}
@@ -0,0 +1,45 @@
+package driver
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+
+	_ "modernc.org/sqlite"
+)
+
+// InitSQLiteDB opens the SQLite database file named by SQLITE_PATH
+// (default "carzone.db") and stores it as the shared connection.
+func InitSQLiteDB() error {
+	path := os.Getenv("SQLITE_PATH")
+	if path == "" {
+		path = "carzone.db"
+	}
+
+	// foreign_keys is off by default in SQLite, the car -> engine reference
+	// relies on it. BEGIN IMMEDIATE takes the write lock up front so two
+	// transactions cannot both read and then fail on upgrade.
+	dsn := fmt.Sprintf("file:%s?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_txlock=immediate", path)
+
+	log.Println("Connecting to SQLite database:", path)
+
+	conn, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		log.Printf("Cannot open SQLite database: %v", err)
+		return err
+	}
+
+	// SQLite allows a single writer, one connection keeps transactions serialized.
+	conn.SetMaxOpenConns(1)
+
+	if err := conn.Ping(); err != nil {
+		conn.Close()
+		log.Printf("Cannot ping SQLite database: %v", err)
+		return err
+	}
+
+	db = conn
+	log.Println("SQLite database connected")
+	return nil
+}
@@ -0,0 +1,19 @@
+// Package events provides the sinks the outbox relay publishes car and
+// engine events to: a webhook, a NATS server and Kafka through its REST
+// proxy.
+package events
+
+import (
+	"context"
+
+	"github.com/adohong4/carZone/models"
+)
+
+// Sink publishes events to one destination. Publish returns nil once the
+// destination has taken the event; after an error it may or may not have,
+// and the relay publishes the event again. Receivers drop the duplicates by
+// event ID.
+type Sink interface {
+	Name() string
+	Publish(ctx context.Context, event models.Event) error
+}
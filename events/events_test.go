@@ -0,0 +1,149 @@
+package events
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/adohong4/carZone/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testEvent(t *testing.T) models.Event {
+	event, err := models.NewEvent(models.EventCarCreated, "default", uuid.New(), map[string]string{"name": "Corolla"})
+	require.NoError(t, err)
+	event.Sequence = 7
+	return event
+}
+
+func TestWebhookSink(t *testing.T) {
+	event := testEvent(t)
+	status := http.StatusNoContent
+	var received models.Event
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, event.ID.String(), r.Header.Get("X-Event-ID"))
+		assert.Equal(t, models.EventCarCreated, r.Header.Get("X-Event-Type"))
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	sink, err := NewWebhookSink(server.URL)
+	require.NoError(t, err)
+	require.NoError(t, sink.Publish(context.Background(), event))
+	assert.Equal(t, event.ID, received.ID)
+	assert.JSONEq(t, `{"name":"Corolla"}`, string(received.Data))
+
+	status = http.StatusBadGateway
+	assert.Error(t, sink.Publish(context.Background(), event))
+
+	_, err = NewWebhookSink("ftp://example.com")
+	assert.Error(t, err)
+}
+
+func TestKafkaSink(t *testing.T) {
+	event := testEvent(t)
+	response := `{"offsets":[{"partition":0,"offset":12}]}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/topics/carzone.events", r.URL.Path)
+		assert.Equal(t, "application/vnd.kafka.json.v2+json", r.Header.Get("Content-Type"))
+		var body struct {
+			Records []struct {
+				Key   string       `json:"key"`
+				Value models.Event `json:"value"`
+			} `json:"records"`
+		}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		if assert.Len(t, body.Records, 1) {
+			assert.Equal(t, "car:"+event.EntityID.String(), body.Records[0].Key)
+			assert.Equal(t, event.ID, body.Records[0].Value.ID)
+		}
+		io.WriteString(w, response)
+	}))
+	defer server.Close()
+
+	sink, err := NewKafkaSink(KafkaConfig{URL: server.URL + "/", Topic: "carzone.events"})
+	require.NoError(t, err)
+	require.NoError(t, sink.Publish(context.Background(), event))
+
+	response = `{"offsets":[{"partition":null,"offset":null,"error_code":40403,"error":"topic not found"}]}`
+	err = sink.Publish(context.Background(), event)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "topic not found")
+}
+
+// fakeNATS serves one client connection: it greets it, answers its PINGs
+// and acknowledges what it publishes to a reply subject like a JetStream
+// stream, handing every published message to published.
+func fakeNATS(t *testing.T, published chan<- string) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		reader := bufio.NewReader(conn)
+		fmt.Fprint(conn, "INFO {\"server_id\":\"fake\",\"headers\":true}\r\n")
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			fields := strings.Fields(line)
+			switch {
+			case len(fields) == 0:
+			case fields[0] == "PING":
+				fmt.Fprint(conn, "PONG\r\n")
+			case fields[0] == "HPUB":
+				var total int
+				fmt.Sscan(fields[len(fields)-1], &total)
+				body := make([]byte, total+2)
+				if _, err := io.ReadFull(reader, body); err != nil {
+					return
+				}
+				published <- string(body[:total])
+				if len(fields) == 5 {
+					ack := `{"stream":"CARZONE","seq":1}`
+					fmt.Fprintf(conn, "MSG %s 1 %d\r\n%s\r\n", fields[2], len(ack), ack)
+				}
+			}
+		}
+	}()
+	return "nats://" + listener.Addr().String()
+}
+
+func TestNATSSink(t *testing.T) {
+	for _, jetStream := range []bool{false, true} {
+		t.Run(fmt.Sprint("JetStream=", jetStream), func(t *testing.T) {
+			event := testEvent(t)
+			published := make(chan string, 2)
+			sink, err := NewNATSSink(NATSConfig{URL: fakeNATS(t, published), JetStream: jetStream, Timeout: 5 * time.Second})
+			require.NoError(t, err)
+			defer sink.Close()
+
+			for i := 0; i < 2; i++ {
+				require.NoError(t, sink.Publish(context.Background(), event))
+				message := <-published
+				assert.Contains(t, message, "Nats-Msg-Id: "+event.ID.String())
+				assert.Contains(t, message, `"type":"car.created"`)
+			}
+		})
+	}
+
+	_, err := NewNATSSink(NATSConfig{URL: "http://localhost:4222"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,103 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/adohong4/carZone/models"
+)
+
+// KafkaConfig points a KafkaSink at a topic through a Kafka REST proxy
+// (the Confluent REST API v2), URL is the base URL of the proxy.
+type KafkaConfig struct {
+	URL   string
+	Topic string
+}
+
+// KafkaSink produces every event to a topic with the entity of the event as
+// the record key, so the events of an entity land on one partition in
+// order.
+type KafkaSink struct {
+	endpoint string
+	client   *http.Client
+}
+
+func NewKafkaSink(config KafkaConfig) (*KafkaSink, error) {
+	parsed, err := url.Parse(config.URL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return nil, fmt.Errorf("invalid Kafka REST proxy URL %q", config.URL)
+	}
+	if config.Topic == "" {
+		return nil, fmt.Errorf("Kafka topic is required")
+	}
+	return &KafkaSink{
+		endpoint: strings.TrimSuffix(config.URL, "/") + "/topics/" + url.PathEscape(config.Topic),
+		client:   &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+func (s *KafkaSink) Name() string {
+	return "kafka"
+}
+
+type kafkaRecord struct {
+	Key   string       `json:"key"`
+	Value models.Event `json:"value"`
+}
+
+type kafkaResponse struct {
+	Offsets []struct {
+		Partition int     `json:"partition"`
+		Offset    int64   `json:"offset"`
+		ErrorCode *int    `json:"error_code"`
+		Error     *string `json:"error"`
+	} `json:"offsets"`
+}
+
+func (s *KafkaSink) Publish(ctx context.Context, event models.Event) error {
+	body, err := json.Marshal(map[string][]kafkaRecord{
+		"records": {{Key: event.Entity(), Value: event}},
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/vnd.kafka.json.v2+json")
+	req.Header.Set("Accept", "application/vnd.kafka.v2+json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("Kafka REST proxy responded %s: %s", resp.Status, bytes.TrimSpace(message))
+	}
+
+	var produced kafkaResponse
+	if err := json.NewDecoder(resp.Body).Decode(&produced); err != nil {
+		return fmt.Errorf("invalid Kafka REST proxy response: %w", err)
+	}
+	if len(produced.Offsets) != 1 {
+		return fmt.Errorf("Kafka REST proxy returned %d offsets for 1 record", len(produced.Offsets))
+	}
+	if offset := produced.Offsets[0]; offset.ErrorCode != nil || offset.Error != nil {
+		message := "unknown error"
+		if offset.Error != nil {
+			message = *offset.Error
+		}
+		return fmt.Errorf("Kafka rejected the record: %s", message)
+	}
+	return nil
+}
@@ -0,0 +1,288 @@
+package events
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/adohong4/carZone/models"
+	"github.com/google/uuid"
+)
+
+// NATSConfig points a NATSSink at a server. URL is nats://host:port, or
+// tls://host:port, with an optional user:password or token user info.
+// Events go to Subject followed by their type, e.g. carzone.car.created.
+// With JetStream the sink waits for the stream to acknowledge each event,
+// otherwise for the server to have taken it.
+type NATSConfig struct {
+	URL       string
+	Subject   string
+	JetStream bool
+	Timeout   time.Duration
+}
+
+// NATSSink publishes events over the NATS client protocol on a single
+// connection, which keeps the events of an entity in order. Each event
+// carries its ID as Nats-Msg-Id so JetStream drops redelivered duplicates.
+type NATSSink struct {
+	config NATSConfig
+	url    *url.URL
+
+	mu     sync.Mutex
+	conn   net.Conn
+	reader *bufio.Reader
+	inbox  string
+}
+
+func NewNATSSink(config NATSConfig) (*NATSSink, error) {
+	parsed, err := url.Parse(config.URL)
+	if err != nil || (parsed.Scheme != "nats" && parsed.Scheme != "tls") || parsed.Hostname() == "" {
+		return nil, fmt.Errorf("invalid NATS URL %q", config.URL)
+	}
+	if config.Subject == "" {
+		config.Subject = "carzone"
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 10 * time.Second
+	}
+	return &NATSSink{config: config, url: parsed}, nil
+}
+
+func (s *NATSSink) Name() string {
+	return "nats"
+}
+
+func (s *NATSSink) Publish(ctx context.Context, event models.Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conn == nil {
+		if err := s.connect(ctx); err != nil {
+			return err
+		}
+	}
+	if err := s.publish(ctx, event); err != nil {
+		// the state of the connection is unknown, start over next time
+		s.conn.Close()
+		s.conn = nil
+		return err
+	}
+	return nil
+}
+
+// Close drops the connection to the server.
+func (s *NATSSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
+
+func (s *NATSSink) connect(ctx context.Context) error {
+	host := s.url.Host
+	if s.url.Port() == "" {
+		host = net.JoinHostPort(s.url.Hostname(), "4222")
+	}
+	dialer := &net.Dialer{Timeout: s.config.Timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", host)
+	if err != nil {
+		return err
+	}
+	if s.url.Scheme == "tls" {
+		conn = tls.Client(conn, &tls.Config{ServerName: s.url.Hostname()})
+	}
+	s.conn, s.reader = conn, bufio.NewReader(conn)
+	s.setDeadline(ctx)
+
+	fail := func(err error) error {
+		conn.Close()
+		s.conn = nil
+		return err
+	}
+	line, err := s.readLine()
+	if err != nil {
+		return fail(err)
+	}
+	var info struct {
+		Headers bool `json:"headers"`
+	}
+	if !strings.HasPrefix(line, "INFO ") || json.Unmarshal([]byte(line[5:]), &info) != nil {
+		return fail(fmt.Errorf("unexpected NATS greeting %q", line))
+	}
+	if !info.Headers {
+		return fail(errors.New("NATS server does not support headers"))
+	}
+
+	options := map[string]interface{}{
+		"verbose": false, "pedantic": false, "lang": "go", "version": "carzone",
+		"protocol": 1, "headers": true, "no_responders": true,
+	}
+	if user := s.url.User; user != nil {
+		if password, ok := user.Password(); ok {
+			options["user"], options["pass"] = user.Username(), password
+		} else {
+			options["auth_token"] = user.Username()
+		}
+	}
+	connect, err := json.Marshal(options)
+	if err != nil {
+		return fail(err)
+	}
+	command := "CONNECT " + string(connect) + "\r\n"
+	if s.config.JetStream {
+		s.inbox = "_INBOX." + strings.ReplaceAll(uuid.NewString(), "-", "")
+		command += "SUB " + s.inbox + ".* 1\r\n"
+	}
+	if _, err := io.WriteString(conn, command+"PING\r\n"); err != nil {
+		return fail(err)
+	}
+	if _, err := s.await(""); err != nil {
+		return fail(err)
+	}
+	return nil
+}
+
+func (s *NATSSink) publish(ctx context.Context, event models.Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	headers := "NATS/1.0\r\nNats-Msg-Id: " + event.ID.String() + "\r\n\r\n"
+	subject := s.config.Subject + "." + event.Type
+
+	reply := ""
+	if s.config.JetStream {
+		reply = s.inbox + "." + strings.ReplaceAll(event.ID.String(), "-", "")
+	}
+	command := "HPUB " + subject
+	if reply != "" {
+		command += " " + reply
+	}
+	command += fmt.Sprintf(" %d %d\r\n%s%s\r\n", len(headers), len(headers)+len(payload), headers, payload)
+	if reply == "" {
+		// the PONG comes back once the server has processed the HPUB
+		command += "PING\r\n"
+	}
+
+	s.setDeadline(ctx)
+	if _, err := io.WriteString(s.conn, command); err != nil {
+		return err
+	}
+	ack, err := s.await(reply)
+	if err != nil || reply == "" {
+		return err
+	}
+
+	var result struct {
+		Stream string `json:"stream"`
+		Error  *struct {
+			Description string `json:"description"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(ack, &result); err != nil {
+		return fmt.Errorf("invalid JetStream acknowledgement: %w", err)
+	}
+	if result.Error != nil {
+		return fmt.Errorf("JetStream refused the event: %s", result.Error.Description)
+	}
+	return nil
+}
+
+// await reads from the server until the PONG, when reply is empty, or the
+// message sent to reply arrives and returns its payload. It answers the
+// server's PINGs on the way and fails on -ERR.
+func (s *NATSSink) await(reply string) ([]byte, error) {
+	for {
+		line, err := s.readLine()
+		if err != nil {
+			return nil, err
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		switch strings.ToUpper(fields[0]) {
+		case "PING":
+			if _, err := io.WriteString(s.conn, "PONG\r\n"); err != nil {
+				return nil, err
+			}
+		case "PONG":
+			if reply == "" {
+				return nil, nil
+			}
+		case "-ERR":
+			return nil, fmt.Errorf("NATS error: %s", strings.TrimSpace(strings.TrimPrefix(line, fields[0])))
+		case "MSG", "HMSG":
+			headers, payload, err := s.readMessage(fields)
+			if err != nil {
+				return nil, err
+			}
+			if reply == "" || fields[1] != reply {
+				continue
+			}
+			if status := strings.Fields(headers); len(status) > 1 && status[1] == "503" {
+				return nil, errors.New("no JetStream stream takes the subject")
+			}
+			return payload, nil
+		}
+	}
+}
+
+// readMessage reads the body of the MSG or HMSG whose control line is
+// fields, splitting off the headers of an HMSG.
+func (s *NATSSink) readMessage(fields []string) (string, []byte, error) {
+	sizes := 1
+	if strings.EqualFold(fields[0], "HMSG") {
+		sizes = 2
+	}
+	if len(fields) < 3+sizes {
+		return "", nil, fmt.Errorf("invalid NATS message %q", strings.Join(fields, " "))
+	}
+	total, err := strconv.Atoi(fields[len(fields)-1])
+	if err != nil || total < 0 {
+		return "", nil, fmt.Errorf("invalid NATS message size %q", fields[len(fields)-1])
+	}
+	headerSize := 0
+	if sizes == 2 {
+		headerSize, err = strconv.Atoi(fields[len(fields)-2])
+		if err != nil || headerSize < 0 || headerSize > total {
+			return "", nil, fmt.Errorf("invalid NATS header size %q", fields[len(fields)-2])
+		}
+	}
+	body := make([]byte, total+2)
+	if _, err := io.ReadFull(s.reader, body); err != nil {
+		return "", nil, err
+	}
+	return string(body[:headerSize]), body[headerSize:total], nil
+}
+
+func (s *NATSSink) readLine() (string, error) {
+	line, err := s.reader.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func (s *NATSSink) setDeadline(ctx context.Context) {
+	deadline := time.Now().Add(s.config.Timeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	s.conn.SetDeadline(deadline)
+}
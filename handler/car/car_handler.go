@@ -1,26 +1,39 @@
 package car
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"io"
 	"log"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/adohong4/carZone/core"
 	"github.com/adohong4/carZone/models"
 	"github.com/adohong4/carZone/service"
+	carService "github.com/adohong4/carZone/service/car"
+	currencyService "github.com/adohong4/carZone/service/currency"
+	"github.com/adohong4/carZone/store"
 	"github.com/adohong4/carZone/utils"
 	"github.com/gorilla/mux"
 	"go.opentelemetry.io/otel"
 )
 
+// cacheMaxAge is how long clients may reuse a car response before they
+// have to revalidate it with If-None-Match / If-Modified-Since.
+const cacheMaxAge = 60 * time.Second
+
 type CarHandler struct {
-	service service.CarServiceInterface
+	service  service.CarServiceInterface
+	currency service.CurrencyServiceInterface
 }
 
-func NewCarHandler(service service.CarServiceInterface) *CarHandler {
+func NewCarHandler(service service.CarServiceInterface, currency service.CurrencyServiceInterface) *CarHandler {
 	return &CarHandler{
-		service: service,
+		service:  service,
+		currency: currency,
 	}
 }
 
@@ -42,7 +55,12 @@ func (h *CarHandler) GetCarById(w http.ResponseWriter, r *http.Request) {
 		core.SendErrorResponse(w, core.NewErrorResponse("Internal server error", utils.InternalServerError))
 		return
 	}
-	core.NewOK("Car retrieved successfully", resp).Send(w)
+	converted, ok := h.convert(ctx, w, r, []models.Car{*resp})
+	if !ok {
+		return
+	}
+	lastModified := h.lastModified(ctx, r, converted)
+	core.NewOK("Car retrieved successfully", &converted[0]).SendCached(w, r, lastModified, cacheMaxAge)
 }
 
 func (h *CarHandler) GetCarByBrand(w http.ResponseWriter, r *http.Request) {
@@ -63,8 +81,47 @@ func (h *CarHandler) GetCarByBrand(w http.ResponseWriter, r *http.Request) {
 		core.SendErrorResponse(w, core.NewErrorResponse("Internal server error", utils.InternalServerError))
 		return
 	}
+	resp, ok := h.convert(ctx, w, r, resp)
+	if !ok {
+		return
+	}
+	lastModified := h.lastModified(ctx, r, resp)
+	core.NewOK("Cars retrieved successfully", resp).SendCached(w, r, lastModified, cacheMaxAge)
+}
 
-	core.NewOK("Cars retrieved successfully", resp).Send(w)
+// CompareCars lines up the cars of ?ids=a,b,c field by field, with their
+// prices in ?currency= when it is given.
+func (h *CarHandler) CompareCars(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("CarHandler")
+	ctx, span := tracer.Start(r.Context(), "CompareCars-Handler")
+	defer span.End()
+
+	var ids []string
+	for _, id := range strings.Split(r.URL.Query().Get("ids"), ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			ids = append(ids, id)
+		}
+	}
+
+	cars, err := h.service.CompareCars(ctx, ids)
+	if err != nil {
+		var invalidErr *carService.InvalidError
+		switch {
+		case errors.As(err, &invalidErr):
+			core.SendErrorResponse(w, core.NewBadRequestError(invalidErr.Error()).ErrorResponse)
+		case errors.Is(err, store.ErrCarNotFound):
+			core.SendErrorResponse(w, core.NewNotFoundError("Car not found").ErrorResponse)
+		default:
+			log.Printf("Error comparing cars: %v", err)
+			core.SendErrorResponse(w, core.NewErrorResponse("Internal server error", utils.InternalServerError))
+		}
+		return
+	}
+	cars, ok := h.convert(ctx, w, r, cars)
+	if !ok {
+		return
+	}
+	core.NewOK("Cars compared successfully", models.CompareCars(cars)).Send(w)
 }
 
 func (h *CarHandler) CreateCar(w http.ResponseWriter, r *http.Request) {
@@ -81,7 +138,7 @@ func (h *CarHandler) CreateCar(w http.ResponseWriter, r *http.Request) {
 
 	var carReq models.CarRequest
 	if err = json.Unmarshal(body, &carReq); err != nil {
-		log.Printf("Error unmarshalling request body: ", err)
+		log.Printf("Error unmarshalling request body: %v", err)
 		core.SendErrorResponse(w, core.NewBadRequestError("Invalid car data").ErrorResponse)
 		return
 	}
@@ -89,6 +146,11 @@ func (h *CarHandler) CreateCar(w http.ResponseWriter, r *http.Request) {
 	createdCar, err := h.service.CreateCar(ctx, &carReq)
 	if err != nil {
 		log.Println("Error creating car: ", err)
+		var invalidErr *carService.InvalidError
+		if errors.As(err, &invalidErr) {
+			core.SendErrorResponse(w, core.NewBadRequestError(invalidErr.Error()).ErrorResponse)
+			return
+		}
 		if err.Error() == "Car already exists" {
 			core.SendErrorResponse(w, core.NewConflictRequestError("Car already exists").ErrorResponse)
 			return
@@ -100,6 +162,28 @@ func (h *CarHandler) CreateCar(w http.ResponseWriter, r *http.Request) {
 	core.NewCREATED("Car created successfully", createdCar).Send(w)
 }
 
+// ValidateCar checks a car request without saving it and answers with
+// every violation, a request that would be refused still gets 200.
+func (h *CarHandler) ValidateCar(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("CarHandler")
+	ctx, span := tracer.Start(r.Context(), "ValidateCar-Handler")
+	defer span.End()
+
+	var carReq models.CarRequest
+	if err := json.NewDecoder(r.Body).Decode(&carReq); err != nil {
+		core.SendErrorResponse(w, core.NewBadRequestError("Invalid car data").ErrorResponse)
+		return
+	}
+
+	result, err := h.service.ValidateCar(ctx, &carReq)
+	if err != nil {
+		log.Println("Error validating car: ", err)
+		core.SendErrorResponse(w, core.NewErrorResponse("Internal server error", utils.InternalServerError))
+		return
+	}
+	core.NewOK("Car validated successfully", result).Send(w)
+}
+
 func (h *CarHandler) UpdateCar(w http.ResponseWriter, r *http.Request) {
 	tracer := otel.Tracer("CarHandler")
 	ctx, span := tracer.Start(r.Context(), "UpdateCar-Handler")
@@ -126,6 +210,11 @@ func (h *CarHandler) UpdateCar(w http.ResponseWriter, r *http.Request) {
 	updatedCar, err := h.service.UpdateCar(ctx, id, &carReq)
 	if err != nil {
 		log.Printf("Error updating car: %v", err)
+		var invalidErr *carService.InvalidError
+		if errors.As(err, &invalidErr) {
+			core.SendErrorResponse(w, core.NewBadRequestError(invalidErr.Error()).ErrorResponse)
+			return
+		}
 		if err.Error() == "Car not found" {
 			core.SendErrorResponse(w, core.NewNotFoundError("Car not found").ErrorResponse)
 			return
@@ -148,13 +237,68 @@ func (h *CarHandler) DeleteCar(w http.ResponseWriter, r *http.Request) {
 	_, err := h.service.DeleteCar(ctx, id)
 	if err != nil {
 		log.Printf("Error deleting car: %v", err)
-		if err.Error() == "Car not found" {
+		switch {
+		case err.Error() == "Car not found":
 			core.SendErrorResponse(w, core.NewNotFoundError("Car not found").ErrorResponse)
-			return
+		case errors.Is(err, store.ErrCarHasVehicles):
+			core.SendErrorResponse(w, core.NewConflictRequestError("Car has vehicles").ErrorResponse)
+		case errors.Is(err, store.ErrCarOnOrder):
+			core.SendErrorResponse(w, core.NewConflictRequestError("Car is on an order").ErrorResponse)
+		default:
+			core.SendErrorResponse(w, core.NewErrorResponse("Internal server error", utils.InternalServerError))
 		}
-		core.SendErrorResponse(w, core.NewErrorResponse("Internal server error", utils.InternalServerError))
 		return
 	}
 
 	core.NewOK("Car deleted successfully", nil).Send(w)
 }
+
+// convert prices cars in the ?currency= of the request, if any. It sends
+// the error response itself and reports whether the caller may go on.
+func (h *CarHandler) convert(ctx context.Context, w http.ResponseWriter, r *http.Request, cars []models.Car) ([]models.Car, bool) {
+	currency := r.URL.Query().Get("currency")
+	if currency == "" {
+		return cars, true
+	}
+
+	converted, err := h.currency.ConvertCars(ctx, cars, currency)
+	if err != nil {
+		var invalidErr *currencyService.InvalidError
+		switch {
+		case errors.As(err, &invalidErr), errors.Is(err, currencyService.ErrNoRate):
+			core.SendErrorResponse(w, core.NewBadRequestError(err.Error()).ErrorResponse)
+		default:
+			log.Printf("Error converting car prices: %v", err)
+			core.SendErrorResponse(w, core.NewErrorResponse("Internal server error", utils.InternalServerError))
+		}
+		return nil, false
+	}
+	return converted, true
+}
+
+// lastModified is the latest change among cars and, when their prices were
+// converted, the exchange rates. It is zero when the rates cannot be read,
+// which leaves Last-Modified out.
+func (h *CarHandler) lastModified(ctx context.Context, r *http.Request, cars []models.Car) time.Time {
+	var latest time.Time
+	for _, car := range cars {
+		if changed := car.LastModified(); changed.After(latest) {
+			latest = changed
+		}
+	}
+	if r.URL.Query().Get("currency") == "" {
+		return latest
+	}
+
+	rates, err := h.currency.ListRates(ctx)
+	if err != nil {
+		log.Printf("Error listing exchange rates: %v", err)
+		return time.Time{}
+	}
+	for _, rate := range rates {
+		if rate.UpdatedAt.After(latest) {
+			latest = rate.UpdatedAt
+		}
+	}
+	return latest
+}
@@ -0,0 +1,64 @@
+package car
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/adohong4/carZone/models"
+	"github.com/adohong4/carZone/service"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeCarService returns car for any ID.
+type fakeCarService struct {
+	service.CarServiceInterface
+	car models.Car
+}
+
+func (s *fakeCarService) GetCarById(ctx context.Context, id string) (*models.Car, error) {
+	car := s.car
+	return &car, nil
+}
+
+func getCar(h *CarHandler, ifModifiedSince string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, "/cars/1", nil)
+	r = mux.SetURLVars(r, map[string]string{"id": "1"})
+	if ifModifiedSince != "" {
+		r.Header.Set("If-Modified-Since", ifModifiedSince)
+	}
+	w := httptest.NewRecorder()
+	h.GetCarById(w, r)
+	return w
+}
+
+func TestGetCarByIdIfModifiedSince(t *testing.T) {
+	updated := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	reserved := updated.Add(time.Hour)
+	cars := &fakeCarService{car: models.Car{
+		ID:          uuid.New(),
+		Name:        "Camry",
+		UpdatedAt:   updated,
+		Reservation: &models.Reservation{UpdatedAt: reserved},
+	}}
+	h := NewCarHandler(cars, nil)
+
+	w := getCar(h, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	lastModified := w.Header().Get("Last-Modified")
+	assert.Equal(t, "Wed, 01 May 2024 11:00:00 GMT", lastModified)
+
+	w = getCar(h, lastModified)
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Empty(t, w.Body.String())
+
+	// a promotion starting later changes the response without touching the car
+	cars.car.Promotion = &models.Promotion{CreatedAt: updated, StartsAt: reserved.Add(time.Hour)}
+	w = getCar(h, lastModified)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "Wed, 01 May 2024 12:00:00 GMT", w.Header().Get("Last-Modified"))
+}
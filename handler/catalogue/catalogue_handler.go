@@ -0,0 +1,176 @@
+package catalogue
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/adohong4/carZone/core"
+	"github.com/adohong4/carZone/models"
+	"github.com/adohong4/carZone/service"
+	catalogueService "github.com/adohong4/carZone/service/catalogue"
+	"github.com/adohong4/carZone/store"
+	"github.com/adohong4/carZone/utils"
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel"
+)
+
+type CatalogueHandler struct {
+	service service.CatalogueServiceInterface
+}
+
+func NewCatalogueHandler(service service.CatalogueServiceInterface) *CatalogueHandler {
+	return &CatalogueHandler{
+		service: service,
+	}
+}
+
+func (h *CatalogueHandler) ListBrands(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("CatalogueHandler")
+	ctx, span := tracer.Start(r.Context(), "ListBrands-Handler")
+	defer span.End()
+
+	brands, err := h.service.ListBrands(ctx)
+	if err != nil {
+		sendCatalogueError(w, "Error listing brands", err)
+		return
+	}
+	core.NewOK("Brands retrieved successfully", brands).Send(w)
+}
+
+func (h *CatalogueHandler) GetBrand(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("CatalogueHandler")
+	ctx, span := tracer.Start(r.Context(), "GetBrand-Handler")
+	defer span.End()
+
+	brand, err := h.service.GetBrand(ctx, mux.Vars(r)["id"])
+	if err != nil {
+		sendCatalogueError(w, "Error getting brand", err)
+		return
+	}
+	core.NewOK("Brand retrieved successfully", brand).Send(w)
+}
+
+func (h *CatalogueHandler) CreateBrand(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("CatalogueHandler")
+	ctx, span := tracer.Start(r.Context(), "CreateBrand-Handler")
+	defer span.End()
+
+	var brandReq models.BrandRequest
+	if err := json.NewDecoder(r.Body).Decode(&brandReq); err != nil {
+		core.SendErrorResponse(w, core.NewBadRequestError("Invalid brand data").ErrorResponse)
+		return
+	}
+
+	brand, err := h.service.CreateBrand(ctx, &brandReq)
+	if err != nil {
+		sendCatalogueError(w, "Error creating brand", err)
+		return
+	}
+	core.NewCREATED("Brand created successfully", brand).Send(w)
+}
+
+func (h *CatalogueHandler) UpdateBrand(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("CatalogueHandler")
+	ctx, span := tracer.Start(r.Context(), "UpdateBrand-Handler")
+	defer span.End()
+
+	var brandReq models.BrandRequest
+	if err := json.NewDecoder(r.Body).Decode(&brandReq); err != nil {
+		core.SendErrorResponse(w, core.NewBadRequestError("Invalid brand data").ErrorResponse)
+		return
+	}
+
+	brand, err := h.service.UpdateBrand(ctx, mux.Vars(r)["id"], &brandReq)
+	if err != nil {
+		sendCatalogueError(w, "Error updating brand", err)
+		return
+	}
+	core.NewOK("Brand updated successfully", brand).Send(w)
+}
+
+func (h *CatalogueHandler) DeleteBrand(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("CatalogueHandler")
+	ctx, span := tracer.Start(r.Context(), "DeleteBrand-Handler")
+	defer span.End()
+
+	brand, err := h.service.DeleteBrand(ctx, mux.Vars(r)["id"])
+	if err != nil {
+		sendCatalogueError(w, "Error deleting brand", err)
+		return
+	}
+	core.NewOK("Brand deleted successfully", brand).Send(w)
+}
+
+func (h *CatalogueHandler) CreateModel(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("CatalogueHandler")
+	ctx, span := tracer.Start(r.Context(), "CreateModel-Handler")
+	defer span.End()
+
+	var modelReq models.CarModelRequest
+	if err := json.NewDecoder(r.Body).Decode(&modelReq); err != nil {
+		core.SendErrorResponse(w, core.NewBadRequestError("Invalid model data").ErrorResponse)
+		return
+	}
+
+	model, err := h.service.CreateModel(ctx, mux.Vars(r)["id"], &modelReq)
+	if err != nil {
+		sendCatalogueError(w, "Error creating model", err)
+		return
+	}
+	core.NewCREATED("Model created successfully", model).Send(w)
+}
+
+func (h *CatalogueHandler) UpdateModel(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("CatalogueHandler")
+	ctx, span := tracer.Start(r.Context(), "UpdateModel-Handler")
+	defer span.End()
+
+	var modelReq models.CarModelRequest
+	if err := json.NewDecoder(r.Body).Decode(&modelReq); err != nil {
+		core.SendErrorResponse(w, core.NewBadRequestError("Invalid model data").ErrorResponse)
+		return
+	}
+
+	vars := mux.Vars(r)
+	model, err := h.service.UpdateModel(ctx, vars["id"], vars["modelId"], &modelReq)
+	if err != nil {
+		sendCatalogueError(w, "Error updating model", err)
+		return
+	}
+	core.NewOK("Model updated successfully", model).Send(w)
+}
+
+func (h *CatalogueHandler) DeleteModel(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("CatalogueHandler")
+	ctx, span := tracer.Start(r.Context(), "DeleteModel-Handler")
+	defer span.End()
+
+	vars := mux.Vars(r)
+	model, err := h.service.DeleteModel(ctx, vars["id"], vars["modelId"])
+	if err != nil {
+		sendCatalogueError(w, "Error deleting model", err)
+		return
+	}
+	core.NewOK("Model deleted successfully", model).Send(w)
+}
+
+func sendCatalogueError(w http.ResponseWriter, action string, err error) {
+	var invalid *catalogueService.InvalidError
+	switch {
+	case errors.As(err, &invalid):
+		core.SendErrorResponse(w, core.NewBadRequestError(invalid.Error()).ErrorResponse)
+	case errors.Is(err, catalogueService.ErrBrandNotFound):
+		core.SendErrorResponse(w, core.NewNotFoundError("Brand not found").ErrorResponse)
+	case errors.Is(err, catalogueService.ErrModelNotFound):
+		core.SendErrorResponse(w, core.NewNotFoundError("Model not found").ErrorResponse)
+	case errors.Is(err, catalogueService.ErrNameTaken):
+		core.SendErrorResponse(w, core.NewConflictRequestError("Name is already in the catalogue").ErrorResponse)
+	case errors.Is(err, store.ErrBrandInUse):
+		core.SendErrorResponse(w, core.NewConflictRequestError("Brand is used by cars").ErrorResponse)
+	default:
+		log.Printf("%s: %v", action, err)
+		core.SendErrorResponse(w, core.NewErrorResponse("Internal server error", utils.InternalServerError))
+	}
+}
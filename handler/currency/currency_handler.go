@@ -0,0 +1,65 @@
+package currency
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/adohong4/carZone/core"
+	"github.com/adohong4/carZone/models"
+	"github.com/adohong4/carZone/service"
+	currencyService "github.com/adohong4/carZone/service/currency"
+	"github.com/adohong4/carZone/utils"
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel"
+)
+
+type CurrencyHandler struct {
+	service service.CurrencyServiceInterface
+}
+
+func NewCurrencyHandler(service service.CurrencyServiceInterface) *CurrencyHandler {
+	return &CurrencyHandler{
+		service: service,
+	}
+}
+
+func (h *CurrencyHandler) ListRates(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("CurrencyHandler")
+	ctx, span := tracer.Start(r.Context(), "ListRates-Handler")
+	defer span.End()
+
+	rates, err := h.service.ListRates(ctx)
+	if err != nil {
+		log.Println("Error listing exchange rates: ", err)
+		core.SendErrorResponse(w, core.NewErrorResponse("Internal server error", utils.InternalServerError))
+		return
+	}
+	core.NewOK("Exchange rates retrieved successfully", rates).Send(w)
+}
+
+func (h *CurrencyHandler) SetRate(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("CurrencyHandler")
+	ctx, span := tracer.Start(r.Context(), "SetRate-Handler")
+	defer span.End()
+
+	var rateReq models.ExchangeRateRequest
+	if err := json.NewDecoder(r.Body).Decode(&rateReq); err != nil {
+		core.SendErrorResponse(w, core.NewBadRequestError("Invalid exchange rate data").ErrorResponse)
+		return
+	}
+
+	rate, err := h.service.SetRate(ctx, mux.Vars(r)["currency"], &rateReq)
+	if err != nil {
+		var invalidErr *currencyService.InvalidError
+		if errors.As(err, &invalidErr) {
+			core.SendErrorResponse(w, core.NewBadRequestError(invalidErr.Reason).ErrorResponse)
+			return
+		}
+		log.Println("Error setting exchange rate: ", err)
+		core.SendErrorResponse(w, core.NewErrorResponse("Internal server error", utils.InternalServerError))
+		return
+	}
+	core.NewOK("Exchange rate updated successfully", rate).Send(w)
+}
@@ -0,0 +1,202 @@
+package customer
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/adohong4/carZone/core"
+	"github.com/adohong4/carZone/models"
+	"github.com/adohong4/carZone/service"
+	customerService "github.com/adohong4/carZone/service/customer"
+	"github.com/adohong4/carZone/store"
+	"github.com/adohong4/carZone/utils"
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel"
+)
+
+type CustomerHandler struct {
+	service service.CustomerServiceInterface
+}
+
+func NewCustomerHandler(service service.CustomerServiceInterface) *CustomerHandler {
+	return &CustomerHandler{
+		service: service,
+	}
+}
+
+func (h *CustomerHandler) GetCustomerById(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("CustomerHandler")
+	ctx, span := tracer.Start(r.Context(), "GetCustomerById-Handler")
+	defer span.End()
+
+	customer, err := h.service.GetCustomerById(ctx, mux.Vars(r)["id"])
+	if err != nil {
+		sendCustomerError(w, "Error getting customer", err)
+		return
+	}
+	core.NewOK("Customer retrieved successfully", customer).Send(w)
+}
+
+// ListCustomers lists customers, without notes and cars, whose name or
+// email contains ?q=.
+func (h *CustomerHandler) ListCustomers(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("CustomerHandler")
+	ctx, span := tracer.Start(r.Context(), "ListCustomers-Handler")
+	defer span.End()
+
+	customers, err := h.service.ListCustomers(ctx, r.URL.Query().Get("q"))
+	if err != nil {
+		sendCustomerError(w, "Error listing customers", err)
+		return
+	}
+	core.NewOK("Customers retrieved successfully", customers).Send(w)
+}
+
+func (h *CustomerHandler) CreateCustomer(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("CustomerHandler")
+	ctx, span := tracer.Start(r.Context(), "CreateCustomer-Handler")
+	defer span.End()
+
+	var customerReq models.CustomerRequest
+	if err := json.NewDecoder(r.Body).Decode(&customerReq); err != nil {
+		core.SendErrorResponse(w, core.NewBadRequestError("Invalid customer data").ErrorResponse)
+		return
+	}
+
+	customer, err := h.service.CreateCustomer(ctx, &customerReq)
+	if err != nil {
+		sendCustomerError(w, "Error creating customer", err)
+		return
+	}
+	core.NewCREATED("Customer created successfully", customer).Send(w)
+}
+
+func (h *CustomerHandler) UpdateCustomer(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("CustomerHandler")
+	ctx, span := tracer.Start(r.Context(), "UpdateCustomer-Handler")
+	defer span.End()
+
+	var customerReq models.CustomerRequest
+	if err := json.NewDecoder(r.Body).Decode(&customerReq); err != nil {
+		core.SendErrorResponse(w, core.NewBadRequestError("Invalid customer data").ErrorResponse)
+		return
+	}
+
+	customer, err := h.service.UpdateCustomer(ctx, mux.Vars(r)["id"], &customerReq)
+	if err != nil {
+		sendCustomerError(w, "Error updating customer", err)
+		return
+	}
+	core.NewOK("Customer updated successfully", customer).Send(w)
+}
+
+func (h *CustomerHandler) DeleteCustomer(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("CustomerHandler")
+	ctx, span := tracer.Start(r.Context(), "DeleteCustomer-Handler")
+	defer span.End()
+
+	customer, err := h.service.DeleteCustomer(ctx, mux.Vars(r)["id"])
+	if err != nil {
+		sendCustomerError(w, "Error deleting customer", err)
+		return
+	}
+	core.NewOK("Customer deleted successfully", customer).Send(w)
+}
+
+// AddNote records a note written by the user of the token.
+func (h *CustomerHandler) AddNote(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("CustomerHandler")
+	ctx, span := tracer.Start(r.Context(), "AddNote-Handler")
+	defer span.End()
+
+	var noteReq models.CustomerNoteRequest
+	if err := json.NewDecoder(r.Body).Decode(&noteReq); err != nil {
+		core.SendErrorResponse(w, core.NewBadRequestError("Invalid note data").ErrorResponse)
+		return
+	}
+
+	username, _ := ctx.Value("username").(string)
+	note, err := h.service.AddNote(ctx, mux.Vars(r)["id"], username, &noteReq)
+	if err != nil {
+		sendCustomerError(w, "Error adding customer note", err)
+		return
+	}
+	core.NewCREATED("Note added successfully", note).Send(w)
+}
+
+func (h *CustomerHandler) LinkCar(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("CustomerHandler")
+	ctx, span := tracer.Start(r.Context(), "LinkCar-Handler")
+	defer span.End()
+
+	var linkReq models.CustomerCarRequest
+	if err := json.NewDecoder(r.Body).Decode(&linkReq); err != nil {
+		core.SendErrorResponse(w, core.NewBadRequestError("Invalid car link data").ErrorResponse)
+		return
+	}
+
+	link, err := h.service.LinkCar(ctx, mux.Vars(r)["id"], &linkReq)
+	if err != nil {
+		sendCustomerError(w, "Error linking car to customer", err)
+		return
+	}
+	core.NewOK("Car linked successfully", link).Send(w)
+}
+
+// Export sends everything kept about the customer as a JSON download.
+func (h *CustomerHandler) Export(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("CustomerHandler")
+	ctx, span := tracer.Start(r.Context(), "Export-Handler")
+	defer span.End()
+
+	export, err := h.service.Export(ctx, mux.Vars(r)["id"])
+	if err != nil {
+		sendCustomerError(w, "Error exporting customer", err)
+		return
+	}
+
+	body, err := json.MarshalIndent(export, "", "  ")
+	if err != nil {
+		sendCustomerError(w, "Error marshalling customer export", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", `attachment; filename="customer-`+export.Customer.ID.String()+`.json"`)
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
+
+// Erase removes the customer's personal data, the record stays anonymised.
+func (h *CustomerHandler) Erase(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("CustomerHandler")
+	ctx, span := tracer.Start(r.Context(), "Erase-Handler")
+	defer span.End()
+
+	customer, err := h.service.Erase(ctx, mux.Vars(r)["id"])
+	if err != nil {
+		sendCustomerError(w, "Error erasing customer", err)
+		return
+	}
+	core.NewOK("Customer erased successfully", customer).Send(w)
+}
+
+func sendCustomerError(w http.ResponseWriter, action string, err error) {
+	var invalid *customerService.InvalidError
+	switch {
+	case errors.As(err, &invalid):
+		core.SendErrorResponse(w, core.NewBadRequestError(invalid.Error()).ErrorResponse)
+	case errors.Is(err, store.ErrCustomerNotFound):
+		core.SendErrorResponse(w, core.NewNotFoundError("Customer not found").ErrorResponse)
+	case errors.Is(err, store.ErrCarNotFound):
+		core.SendErrorResponse(w, core.NewBadRequestError("Car not found").ErrorResponse)
+	case errors.Is(err, customerService.ErrCustomerErased):
+		core.SendErrorResponse(w, core.NewConflictRequestError("Customer has been erased").ErrorResponse)
+	default:
+		log.Printf("%s: %v", action, err)
+		core.SendErrorResponse(w, core.NewErrorResponse("Internal server error", utils.InternalServerError))
+	}
+}
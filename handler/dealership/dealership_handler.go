@@ -0,0 +1,86 @@
+package dealership
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/adohong4/carZone/core"
+	"github.com/adohong4/carZone/models"
+	"github.com/adohong4/carZone/service"
+	"github.com/adohong4/carZone/utils"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel"
+)
+
+type DealershipHandler struct {
+	service service.DealershipServiceInterface
+}
+
+func NewDealershipHandler(service service.DealershipServiceInterface) *DealershipHandler {
+	return &DealershipHandler{
+		service: service,
+	}
+}
+
+func (h *DealershipHandler) GetDealerships(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("DealershipHandler")
+	ctx, span := tracer.Start(r.Context(), "GetDealerships-Handler")
+	defer span.End()
+
+	dealerships, err := h.service.GetDealerships(ctx)
+	if err != nil {
+		log.Println("Error getting dealerships: ", err)
+		core.SendErrorResponse(w, core.NewErrorResponse("Internal server error", utils.InternalServerError))
+		return
+	}
+
+	core.NewOK("Dealerships retrieved successfully", dealerships).Send(w)
+}
+
+func (h *DealershipHandler) GetDealershipById(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("DealershipHandler")
+	ctx, span := tracer.Start(r.Context(), "GetDealershipById-Handler")
+	defer span.End()
+
+	id := mux.Vars(r)["id"]
+
+	dealership, err := h.service.GetDealershipById(ctx, id)
+	if err != nil {
+		log.Println("Error getting dealership: ", err)
+		core.SendErrorResponse(w, core.NewBadRequestError("Invalid dealership ID").ErrorResponse)
+		return
+	}
+	if dealership.ID == uuid.Nil {
+		core.SendErrorResponse(w, core.NewNotFoundError("Dealership not found").ErrorResponse)
+		return
+	}
+
+	core.NewOK("Dealership retrieved successfully", dealership).Send(w)
+}
+
+func (h *DealershipHandler) CreateDealership(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("DealershipHandler")
+	ctx, span := tracer.Start(r.Context(), "CreateDealership-Handler")
+	defer span.End()
+
+	var dealershipReq models.DealershipRequest
+	if err := json.NewDecoder(r.Body).Decode(&dealershipReq); err != nil {
+		core.SendErrorResponse(w, core.NewBadRequestError("Invalid dealership data").ErrorResponse)
+		return
+	}
+
+	createdDealership, err := h.service.CreateDealership(ctx, &dealershipReq)
+	if err != nil {
+		log.Println("Error creating dealership: ", err)
+		if models.ValidateDealershipRequest(dealershipReq) != nil {
+			core.SendErrorResponse(w, core.NewBadRequestError(err.Error()).ErrorResponse)
+			return
+		}
+		core.SendErrorResponse(w, core.NewErrorResponse("Internal server error", utils.InternalServerError))
+		return
+	}
+
+	core.NewCREATED("Dealership created successfully", createdDealership).Send(w)
+}
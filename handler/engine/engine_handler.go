@@ -2,19 +2,26 @@ package engine
 
 import (
 	"encoding/json"
+	"errors"
 	"io"
 	"log"
 	"net/http"
+	"time"
 
 	"github.com/adohong4/carZone/core"
 	"github.com/adohong4/carZone/models"
 	"github.com/adohong4/carZone/service"
+	engineService "github.com/adohong4/carZone/service/engine"
 	"github.com/adohong4/carZone/utils"
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 	"go.opentelemetry.io/otel"
 )
 
+// cacheMaxAge is how long clients may reuse an engine response before they
+// have to revalidate it with If-None-Match / If-Modified-Since.
+const cacheMaxAge = 60 * time.Second
+
 type EngineHandler struct {
 	service service.EngineServiceInterface
 }
@@ -44,7 +51,7 @@ func (e *EngineHandler) GetEngineByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	core.NewOK("Engine retrieved successfully", resp).Send(w)
+	core.NewOK("Engine retrieved successfully", resp).SendCached(w, r, resp.UpdatedAt, cacheMaxAge)
 }
 
 func (e *EngineHandler) CreateEngine(w http.ResponseWriter, r *http.Request) {
@@ -69,6 +76,11 @@ func (e *EngineHandler) CreateEngine(w http.ResponseWriter, r *http.Request) {
 
 	createdEngine, err := e.service.CreateEngine(ctx, &engineReq)
 	if err != nil {
+		var invalidErr *engineService.InvalidError
+		if errors.As(err, &invalidErr) {
+			core.SendErrorResponse(w, core.NewBadRequestError(invalidErr.Error()).ErrorResponse)
+			return
+		}
 		w.WriteHeader(http.StatusInternalServerError)
 		log.Println("Error creating engine: ", err)
 		if err.Error() == "engine already exists" {
@@ -108,6 +120,11 @@ func (e *EngineHandler) UpdateEngine(w http.ResponseWriter, r *http.Request) {
 	updatedEngine, err := e.service.UpdateEngine(ctx, id, &engineReq)
 	if err != nil {
 		log.Printf("Error updating engine: %v", err)
+		var invalidErr *engineService.InvalidError
+		if errors.As(err, &invalidErr) {
+			core.SendErrorResponse(w, core.NewBadRequestError(invalidErr.Error()).ErrorResponse)
+			return
+		}
 		if err.Error() == "Engine not found" {
 			core.SendErrorResponse(w, core.NewNotFoundError("Engine not found").ErrorResponse)
 			return
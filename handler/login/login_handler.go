@@ -2,40 +2,179 @@ package login
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
+	"math"
 	"net/http"
+	"strconv"
 
 	"github.com/adohong4/carZone/core"
 	"github.com/adohong4/carZone/helpers"
 	"github.com/adohong4/carZone/models"
+	"github.com/adohong4/carZone/service"
+	loginService "github.com/adohong4/carZone/service/login"
+	"github.com/adohong4/carZone/utils"
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel"
 )
 
-func LoginHandler(w http.ResponseWriter, r *http.Request) {
+type LoginHandler struct {
+	service service.LoginServiceInterface
+}
+
+func NewLoginHandler(service service.LoginServiceInterface) *LoginHandler {
+	return &LoginHandler{
+		service: service,
+	}
+}
+
+func (h *LoginHandler) Login(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("LoginHandler")
+	ctx, span := tracer.Start(r.Context(), "Login-Handler")
+	defer span.End()
+
 	var credentials models.Credentials
 	if err := json.NewDecoder(r.Body).Decode(&credentials); err != nil {
-		// http.Error(w, "Invalid Request Body", http.StatusBadRequest)
 		core.SendErrorResponse(w, core.NewBadRequestError("Invalid request body").ErrorResponse)
 		return
 	}
 
-	valid := (credentials.UserName == "admin" && credentials.Password == "admin123")
-	if !valid {
-		//http.Error(w, "Incorrect Username or Password", http.StatusUnauthorized)
-		core.SendErrorResponse(w, core.NewAuthFailureError("Incorrect Username or Password").ErrorResponse)
+	result, err := h.service.Login(ctx, credentials, helpers.ClientIP(r))
+	if err != nil {
+		sendLoginError(w, err)
+		return
+	}
+
+	if result.SecondFactorRequired {
+		core.NewOK("Second factor required", result).Send(w)
+		return
+	}
+	core.NewOK("Login successful", result).Send(w)
+}
+
+// SecondFactor finishes a login that returned a challenge.
+func (h *LoginHandler) SecondFactor(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("LoginHandler")
+	ctx, span := tracer.Start(r.Context(), "SecondFactor-Handler")
+	defer span.End()
+
+	var request models.SecondFactorRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		core.SendErrorResponse(w, core.NewBadRequestError("Invalid request body").ErrorResponse)
+		return
+	}
+
+	result, err := h.service.SecondFactor(ctx, request.Challenge, request.Code, helpers.ClientIP(r))
+	if err != nil {
+		sendLoginError(w, err)
 		return
 	}
+	core.NewOK("Login successful", result).Send(w)
+}
 
-	tokenString, err := helpers.GenerateToken(credentials.UserName)
+// EnrollTOTP starts TOTP enrollment for the logged in user.
+func (h *LoginHandler) EnrollTOTP(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("LoginHandler")
+	ctx, span := tracer.Start(r.Context(), "EnrollTOTP-Handler")
+	defer span.End()
+
+	username, _ := r.Context().Value("username").(string)
+	enrollment, err := h.service.EnrollTOTP(ctx, username)
 	if err != nil {
-		log.Println("Error Generating token: ", err)
-		//http.Error(w, "Failed to Username or Password", http.StatusUnauthorized)
-		core.SendErrorResponse(w, core.NewAuthFailureError("Failed to Username or Password").ErrorResponse)
+		sendTOTPError(w, err)
 		return
 	}
+	core.NewOK("Scan the code and confirm it", enrollment).Send(w)
+}
+
+// ConfirmTOTP activates TOTP and returns the recovery codes.
+func (h *LoginHandler) ConfirmTOTP(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("LoginHandler")
+	ctx, span := tracer.Start(r.Context(), "ConfirmTOTP-Handler")
+	defer span.End()
 
-	response := map[string]string{"token": tokenString}
-	// w.Header().Set("Content-Type", "application/json")
-	// json.NewEncoder(w).Encode(response)
-	core.NewOK("Login successful", response).Send(w)
+	var request models.TOTPCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		core.SendErrorResponse(w, core.NewBadRequestError("Invalid request body").ErrorResponse)
+		return
+	}
+
+	username, _ := r.Context().Value("username").(string)
+	codes, err := h.service.ConfirmTOTP(ctx, username, request.Code)
+	if err != nil {
+		sendTOTPError(w, err)
+		return
+	}
+
+	response := map[string][]string{"recovery_codes": codes}
+	core.NewOK("TOTP enabled, store the recovery codes safely", response).Send(w)
+}
+
+// DisableTOTP turns the second factor off after checking a current code.
+func (h *LoginHandler) DisableTOTP(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("LoginHandler")
+	ctx, span := tracer.Start(r.Context(), "DisableTOTP-Handler")
+	defer span.End()
+
+	var request models.TOTPCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		core.SendErrorResponse(w, core.NewBadRequestError("Invalid request body").ErrorResponse)
+		return
+	}
+
+	username, _ := r.Context().Value("username").(string)
+	if err := h.service.DisableTOTP(ctx, username, request.Code); err != nil {
+		sendTOTPError(w, err)
+		return
+	}
+	core.NewOK("TOTP disabled", nil).Send(w)
+}
+
+func sendLoginError(w http.ResponseWriter, err error) {
+	var locked *loginService.LockedError
+	switch {
+	case errors.As(err, &locked):
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(locked.RetryAfter.Seconds()))))
+		core.SendErrorResponse(w, core.NewTooManyRequestsError(locked.Error()).ErrorResponse)
+	case errors.Is(err, loginService.ErrInvalidCredentials),
+		errors.Is(err, loginService.ErrInvalidChallenge),
+		errors.Is(err, loginService.ErrInvalidSecondFactor):
+		core.SendErrorResponse(w, core.NewAuthFailureError(err.Error()).ErrorResponse)
+	default:
+		log.Println("Error logging in: ", err)
+		core.SendErrorResponse(w, core.NewAuthFailureError("Failed to Username or Password").ErrorResponse)
+	}
+}
+
+func sendTOTPError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, loginService.ErrInvalidSecondFactor),
+		errors.Is(err, loginService.ErrTOTPNotEnrolled):
+		core.SendErrorResponse(w, core.NewBadRequestError(err.Error()).ErrorResponse)
+	case errors.Is(err, loginService.ErrTOTPEnabled):
+		core.SendErrorResponse(w, core.NewConflictRequestError(err.Error()).ErrorResponse)
+	default:
+		log.Println("Error updating TOTP: ", err)
+		core.SendErrorResponse(w, core.NewErrorResponse("Internal server error", utils.InternalServerError))
+	}
+}
+
+// Unlock lifts the lockout of a username, and of the address given in the
+// optional "ip" query parameter.
+func (h *LoginHandler) Unlock(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("LoginHandler")
+	ctx, span := tracer.Start(r.Context(), "Unlock-Handler")
+	defer span.End()
+
+	username := mux.Vars(r)["username"]
+	ip := r.URL.Query().Get("ip")
+
+	if err := h.service.Unlock(ctx, username, ip); err != nil {
+		log.Printf("Error unlocking %s: %v", username, err)
+		core.SendErrorResponse(w, core.NewErrorResponse("Internal server error", utils.InternalServerError))
+		return
+	}
 
+	log.Printf("Login lockout of %s lifted by %v", username, r.Context().Value("username"))
+	core.NewOK("User unlocked successfully", nil).Send(w)
 }
@@ -0,0 +1,115 @@
+package login
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/adohong4/carZone/core"
+	"github.com/adohong4/carZone/helpers"
+	"github.com/adohong4/carZone/service"
+	loginService "github.com/adohong4/carZone/service/login"
+	"go.opentelemetry.io/otel"
+)
+
+// oidcCookie keeps the signed login flow between the redirect to the
+// identity provider and the callback.
+const oidcCookie = "carzone_oidc"
+
+type OIDCHandler struct {
+	service service.OIDCServiceInterface
+}
+
+func NewOIDCHandler(service service.OIDCServiceInterface) *OIDCHandler {
+	return &OIDCHandler{
+		service: service,
+	}
+}
+
+// Login redirects the browser to the identity provider.
+func (h *OIDCHandler) Login(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("OIDCHandler")
+	ctx, span := tracer.Start(r.Context(), "Login-Handler")
+	defer span.End()
+
+	authURL, flow, err := h.service.Begin(ctx)
+	if err != nil {
+		log.Println("Error starting OIDC login: ", err)
+		core.SendErrorResponse(w, core.NewAuthFailureError("Unable to start login").ErrorResponse)
+		return
+	}
+
+	flowToken, err := helpers.GenerateOIDCFlowToken(*flow)
+	if err != nil {
+		log.Println("Error signing OIDC flow: ", err)
+		core.SendErrorResponse(w, core.NewAuthFailureError("Unable to start login").ErrorResponse)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcCookie,
+		Value:    flowToken,
+		Path:     "/auth/oidc",
+		MaxAge:   600,
+		HttpOnly: true,
+		Secure:   isHTTPS(r),
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// Callback receives the identity provider's redirect and answers with the
+// CarZone token.
+func (h *OIDCHandler) Callback(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("OIDCHandler")
+	ctx, span := tracer.Start(r.Context(), "Callback-Handler")
+	defer span.End()
+
+	query := r.URL.Query()
+	if idpError := query.Get("error"); idpError != "" {
+		log.Printf("OIDC login failed at the identity provider: %s %s", idpError, query.Get("error_description"))
+		core.SendErrorResponse(w, core.NewAuthFailureError("Login was not completed").ErrorResponse)
+		return
+	}
+
+	cookie, err := r.Cookie(oidcCookie)
+	if err != nil {
+		core.SendErrorResponse(w, core.NewAuthFailureError(loginService.ErrInvalidOIDCState.Error()).ErrorResponse)
+		return
+	}
+	flow, err := helpers.ParseOIDCFlowToken(cookie.Value)
+	if err != nil {
+		core.SendErrorResponse(w, core.NewAuthFailureError(loginService.ErrInvalidOIDCState.Error()).ErrorResponse)
+		return
+	}
+
+	// the flow is single use
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcCookie,
+		Value:    "",
+		Path:     "/auth/oidc",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   isHTTPS(r),
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	result, err := h.service.Callback(ctx, flow, query.Get("state"), query.Get("code"))
+	if err != nil {
+		switch {
+		case errors.Is(err, loginService.ErrInvalidOIDCState), errors.Is(err, loginService.ErrNoRole),
+			errors.Is(err, loginService.ErrEmailNotVerified):
+			core.SendErrorResponse(w, core.NewAuthFailureError(err.Error()).ErrorResponse)
+		default:
+			log.Println("Error completing OIDC login: ", err)
+			core.SendErrorResponse(w, core.NewAuthFailureError("Login failed").ErrorResponse)
+		}
+		return
+	}
+
+	core.NewOK("Login successful", result).Send(w)
+}
+
+func isHTTPS(r *http.Request) bool {
+	return r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https"
+}
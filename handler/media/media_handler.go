@@ -0,0 +1,194 @@
+package media
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/adohong4/carZone/core"
+	"github.com/adohong4/carZone/models"
+	"github.com/adohong4/carZone/service"
+	mediaService "github.com/adohong4/carZone/service/media"
+	"github.com/adohong4/carZone/store"
+	"github.com/adohong4/carZone/utils"
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel"
+)
+
+// uploadField is the multipart form field holding the image.
+const uploadField = "image"
+
+type MediaHandler struct {
+	service  service.MediaServiceInterface
+	maxBytes int64
+}
+
+// NewMediaHandler reads at most maxBytes of an uploaded image, the service
+// refuses anything longer.
+func NewMediaHandler(service service.MediaServiceInterface, maxBytes int64) *MediaHandler {
+	return &MediaHandler{
+		service:  service,
+		maxBytes: maxBytes,
+	}
+}
+
+// UploadImage takes a multipart/form-data request with the image in the
+// "image" field. The form is streamed, the image is never read past the
+// size limit.
+func (h *MediaHandler) UploadImage(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("MediaHandler")
+	ctx, span := tracer.Start(r.Context(), "UploadImage-Handler")
+	defer span.End()
+
+	// leave room for the multipart headers around the image
+	r.Body = http.MaxBytesReader(w, r.Body, h.maxBytes+1<<20)
+	reader, err := r.MultipartReader()
+	if err != nil {
+		core.SendErrorResponse(w, core.NewBadRequestError("Request must be multipart/form-data").ErrorResponse)
+		return
+	}
+
+	var data []byte
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			sendMediaError(w, "Error reading upload", err)
+			return
+		}
+		if part.FormName() != uploadField {
+			continue
+		}
+		// one byte past the limit is enough to know it is too large
+		data, err = io.ReadAll(io.LimitReader(part, h.maxBytes+1))
+		if err != nil {
+			sendMediaError(w, "Error reading upload", err)
+			return
+		}
+		break
+	}
+	if int64(len(data)) > h.maxBytes {
+		sendMediaError(w, "Error reading upload", mediaService.ErrTooLarge)
+		return
+	}
+	if data == nil {
+		core.SendErrorResponse(w, core.NewBadRequestError(`Image is Required in the "image" field`).ErrorResponse)
+		return
+	}
+
+	image, err := h.service.Upload(ctx, mux.Vars(r)["id"], data)
+	if err != nil {
+		sendMediaError(w, "Error uploading image", err)
+		return
+	}
+	core.NewCREATED("Image uploaded successfully", image).Send(w)
+}
+
+func (h *MediaHandler) ListImages(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("MediaHandler")
+	ctx, span := tracer.Start(r.Context(), "ListImages-Handler")
+	defer span.End()
+
+	images, err := h.service.ListImages(ctx, mux.Vars(r)["id"])
+	if err != nil {
+		sendMediaError(w, "Error listing images", err)
+		return
+	}
+	core.NewOK("Images retrieved successfully", images).Send(w)
+}
+
+func (h *MediaHandler) SetOrder(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("MediaHandler")
+	ctx, span := tracer.Start(r.Context(), "SetOrder-Handler")
+	defer span.End()
+
+	var orderReq models.CarImageOrderRequest
+	if err := json.NewDecoder(r.Body).Decode(&orderReq); err != nil {
+		core.SendErrorResponse(w, core.NewBadRequestError("Invalid image order data").ErrorResponse)
+		return
+	}
+
+	images, err := h.service.SetOrder(ctx, mux.Vars(r)["id"], &orderReq)
+	if err != nil {
+		sendMediaError(w, "Error ordering images", err)
+		return
+	}
+	core.NewOK("Images ordered successfully", images).Send(w)
+}
+
+func (h *MediaHandler) SetPrimary(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("MediaHandler")
+	ctx, span := tracer.Start(r.Context(), "SetPrimary-Handler")
+	defer span.End()
+
+	vars := mux.Vars(r)
+	image, err := h.service.SetPrimary(ctx, vars["id"], vars["imageId"])
+	if err != nil {
+		sendMediaError(w, "Error setting primary image", err)
+		return
+	}
+	core.NewOK("Primary image set successfully", image).Send(w)
+}
+
+func (h *MediaHandler) DeleteImage(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("MediaHandler")
+	ctx, span := tracer.Start(r.Context(), "DeleteImage-Handler")
+	defer span.End()
+
+	vars := mux.Vars(r)
+	image, err := h.service.DeleteImage(ctx, vars["id"], vars["imageId"])
+	if err != nil {
+		sendMediaError(w, "Error deleting image", err)
+		return
+	}
+	core.NewOK("Image deleted successfully", image).Send(w)
+}
+
+// ServeMedia sends an image or thumbnail file. Files never change under a
+// key, so clients and proxies may keep them for good.
+func (h *MediaHandler) ServeMedia(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("MediaHandler")
+	ctx, span := tracer.Start(r.Context(), "ServeMedia-Handler")
+	defer span.End()
+
+	body, contentType, err := h.service.Open(ctx, mux.Vars(r)["key"])
+	if err != nil {
+		sendMediaError(w, "Error opening image", err)
+		return
+	}
+	defer body.Close()
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+	if _, err := io.Copy(w, body); err != nil {
+		log.Printf("Error sending image: %v", err)
+	}
+}
+
+func sendMediaError(w http.ResponseWriter, action string, err error) {
+	var (
+		invalid  *mediaService.InvalidError
+		tooLarge *http.MaxBytesError
+	)
+	switch {
+	case errors.As(err, &invalid):
+		core.SendErrorResponse(w, core.NewBadRequestError(invalid.Error()).ErrorResponse)
+	case errors.Is(err, mediaService.ErrTooLarge), errors.As(err, &tooLarge):
+		core.SendErrorResponse(w, core.NewErrorResponse("Image is too large", utils.RequestEntityTooLarge))
+	case errors.Is(err, mediaService.ErrUnsupportedType):
+		core.SendErrorResponse(w, core.NewErrorResponse("Image must be a JPEG, PNG or GIF", utils.UnsupportedMediaType))
+	case errors.Is(err, store.ErrCarNotFound):
+		core.SendErrorResponse(w, core.NewNotFoundError("Car not found").ErrorResponse)
+	case errors.Is(err, mediaService.ErrImageNotFound):
+		core.SendErrorResponse(w, core.NewNotFoundError("Image not found").ErrorResponse)
+	default:
+		log.Printf("%s: %v", action, err)
+		core.SendErrorResponse(w, core.NewErrorResponse("Internal server error", utils.InternalServerError))
+	}
+}
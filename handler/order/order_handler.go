@@ -0,0 +1,182 @@
+package order
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/adohong4/carZone/core"
+	"github.com/adohong4/carZone/invoice"
+	"github.com/adohong4/carZone/models"
+	"github.com/adohong4/carZone/service"
+	orderService "github.com/adohong4/carZone/service/order"
+	"github.com/adohong4/carZone/store"
+	"github.com/adohong4/carZone/utils"
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel"
+)
+
+type OrderHandler struct {
+	service service.OrderServiceInterface
+}
+
+func NewOrderHandler(service service.OrderServiceInterface) *OrderHandler {
+	return &OrderHandler{
+		service: service,
+	}
+}
+
+// CreateOrder starts a draft order sold by the user of the token.
+func (h *OrderHandler) CreateOrder(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("OrderHandler")
+	ctx, span := tracer.Start(r.Context(), "CreateOrder-Handler")
+	defer span.End()
+
+	var orderReq models.OrderRequest
+	if err := json.NewDecoder(r.Body).Decode(&orderReq); err != nil {
+		core.SendErrorResponse(w, core.NewBadRequestError("Invalid order data").ErrorResponse)
+		return
+	}
+
+	username, _ := ctx.Value("username").(string)
+	order, err := h.service.CreateOrder(ctx, username, &orderReq)
+	if err != nil {
+		sendOrderError(w, "Error creating order", err)
+		return
+	}
+	core.NewCREATED("Order created successfully", order).Send(w)
+}
+
+func (h *OrderHandler) GetOrder(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("OrderHandler")
+	ctx, span := tracer.Start(r.Context(), "GetOrder-Handler")
+	defer span.End()
+
+	order, err := h.service.GetOrder(ctx, mux.Vars(r)["id"])
+	if err != nil {
+		sendOrderError(w, "Error getting order", err)
+		return
+	}
+	core.NewOK("Order retrieved successfully", order).Send(w)
+}
+
+// ListOrders lists orders, without their lines, optionally of one status.
+func (h *OrderHandler) ListOrders(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("OrderHandler")
+	ctx, span := tracer.Start(r.Context(), "ListOrders-Handler")
+	defer span.End()
+
+	orders, err := h.service.ListOrders(ctx, r.URL.Query().Get("status"))
+	if err != nil {
+		sendOrderError(w, "Error listing orders", err)
+		return
+	}
+	core.NewOK("Orders retrieved successfully", orders).Send(w)
+}
+
+func (h *OrderHandler) UpdateOrder(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("OrderHandler")
+	ctx, span := tracer.Start(r.Context(), "UpdateOrder-Handler")
+	defer span.End()
+
+	var orderReq models.OrderRequest
+	if err := json.NewDecoder(r.Body).Decode(&orderReq); err != nil {
+		core.SendErrorResponse(w, core.NewBadRequestError("Invalid order data").ErrorResponse)
+		return
+	}
+
+	order, err := h.service.UpdateOrder(ctx, mux.Vars(r)["id"], &orderReq)
+	if err != nil {
+		sendOrderError(w, "Error updating order", err)
+		return
+	}
+	core.NewOK("Order updated successfully", order).Send(w)
+}
+
+func (h *OrderHandler) ChangeStatus(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("OrderHandler")
+	ctx, span := tracer.Start(r.Context(), "ChangeStatus-Handler")
+	defer span.End()
+
+	var statusReq models.OrderStatusRequest
+	if err := json.NewDecoder(r.Body).Decode(&statusReq); err != nil {
+		core.SendErrorResponse(w, core.NewBadRequestError("Invalid order status").ErrorResponse)
+		return
+	}
+
+	order, err := h.service.ChangeStatus(ctx, mux.Vars(r)["id"], statusReq.Status)
+	if err != nil {
+		sendOrderError(w, "Error changing order status", err)
+		return
+	}
+	core.NewOK("Order status changed successfully", order).Send(w)
+}
+
+// Invoice renders the invoice of a confirmed order, as PDF with
+// ?format=pdf or an Accept of application/pdf, as HTML otherwise.
+func (h *OrderHandler) Invoice(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("OrderHandler")
+	ctx, span := tracer.Start(r.Context(), "Invoice-Handler")
+	defer span.End()
+
+	inv, err := h.service.Invoice(ctx, mux.Vars(r)["id"])
+	if err != nil {
+		sendOrderError(w, "Error getting invoice", err)
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" && strings.Contains(r.Header.Get("Accept"), "application/pdf") {
+		format = "pdf"
+	}
+
+	var (
+		body        bytes.Buffer
+		contentType string
+		filename    = inv.Order.InvoiceNumber
+	)
+	switch format {
+	case "pdf":
+		contentType, filename = "application/pdf", filename+".pdf"
+		err = invoice.RenderPDF(&body, *inv)
+	case "", "html":
+		contentType, filename = "text/html; charset=utf-8", filename+".html"
+		err = invoice.RenderHTML(&body, *inv)
+	default:
+		core.SendErrorResponse(w, core.NewBadRequestError("Format must be html or pdf").ErrorResponse)
+		return
+	}
+	if err != nil {
+		sendOrderError(w, "Error rendering invoice", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", `inline; filename="`+filename+`"`)
+	w.WriteHeader(http.StatusOK)
+	w.Write(body.Bytes())
+}
+
+func sendOrderError(w http.ResponseWriter, action string, err error) {
+	var invalid *orderService.InvalidError
+	switch {
+	case errors.As(err, &invalid):
+		core.SendErrorResponse(w, core.NewBadRequestError(invalid.Error()).ErrorResponse)
+	case errors.Is(err, orderService.ErrOrderNotFound):
+		core.SendErrorResponse(w, core.NewNotFoundError("Order not found").ErrorResponse)
+	case errors.Is(err, store.ErrCarNotFound):
+		core.SendErrorResponse(w, core.NewBadRequestError("Car not found").ErrorResponse)
+	case errors.Is(err, store.ErrCustomerNotFound):
+		core.SendErrorResponse(w, core.NewBadRequestError("Customer not found").ErrorResponse)
+	case errors.Is(err, store.ErrOrderConflict):
+		core.SendErrorResponse(w, core.NewConflictRequestError("Order was changed by another request or is no longer a draft").ErrorResponse)
+	case errors.Is(err, invoice.ErrNotInvoiced):
+		core.SendErrorResponse(w, core.NewConflictRequestError("Order must be confirmed before it is invoiced").ErrorResponse)
+	default:
+		log.Printf("%s: %v", action, err)
+		core.SendErrorResponse(w, core.NewErrorResponse("Internal server error", utils.InternalServerError))
+	}
+}
@@ -0,0 +1,140 @@
+package pricing
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/adohong4/carZone/core"
+	"github.com/adohong4/carZone/models"
+	"github.com/adohong4/carZone/service"
+	pricingService "github.com/adohong4/carZone/service/pricing"
+	"github.com/adohong4/carZone/store"
+	"github.com/adohong4/carZone/utils"
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel"
+)
+
+type PricingHandler struct {
+	service service.PricingServiceInterface
+}
+
+func NewPricingHandler(service service.PricingServiceInterface) *PricingHandler {
+	return &PricingHandler{
+		service: service,
+	}
+}
+
+func (h *PricingHandler) PriceHistory(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("PricingHandler")
+	ctx, span := tracer.Start(r.Context(), "PriceHistory-Handler")
+	defer span.End()
+
+	prices, err := h.service.PriceHistory(ctx, mux.Vars(r)["id"])
+	if err != nil {
+		sendPricingError(w, "Error getting price history", err)
+		return
+	}
+	core.NewOK("Price history retrieved successfully", prices).Send(w)
+}
+
+// SchedulePrice adds a price to the car in the path, on behalf of the user
+// of the token.
+func (h *PricingHandler) SchedulePrice(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("PricingHandler")
+	ctx, span := tracer.Start(r.Context(), "SchedulePrice-Handler")
+	defer span.End()
+
+	var priceReq models.CarPriceRequest
+	if err := json.NewDecoder(r.Body).Decode(&priceReq); err != nil {
+		core.SendErrorResponse(w, core.NewBadRequestError("Invalid price data").ErrorResponse)
+		return
+	}
+
+	username, _ := ctx.Value("username").(string)
+	price, err := h.service.SchedulePrice(ctx, mux.Vars(r)["id"], username, &priceReq)
+	if err != nil {
+		sendPricingError(w, "Error scheduling price", err)
+		return
+	}
+	core.NewCREATED("Price scheduled successfully", price).Send(w)
+}
+
+func (h *PricingHandler) CancelPrice(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("PricingHandler")
+	ctx, span := tracer.Start(r.Context(), "CancelPrice-Handler")
+	defer span.End()
+
+	vars := mux.Vars(r)
+	price, err := h.service.CancelPrice(ctx, vars["id"], vars["priceId"])
+	if err != nil {
+		sendPricingError(w, "Error cancelling price", err)
+		return
+	}
+	core.NewOK("Price cancelled successfully", price).Send(w)
+}
+
+func (h *PricingHandler) ListPromotions(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("PricingHandler")
+	ctx, span := tracer.Start(r.Context(), "ListPromotions-Handler")
+	defer span.End()
+
+	promotions, err := h.service.ListPromotions(ctx)
+	if err != nil {
+		sendPricingError(w, "Error listing promotions", err)
+		return
+	}
+	core.NewOK("Promotions retrieved successfully", promotions).Send(w)
+}
+
+func (h *PricingHandler) CreatePromotion(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("PricingHandler")
+	ctx, span := tracer.Start(r.Context(), "CreatePromotion-Handler")
+	defer span.End()
+
+	var promotionReq models.PromotionRequest
+	if err := json.NewDecoder(r.Body).Decode(&promotionReq); err != nil {
+		core.SendErrorResponse(w, core.NewBadRequestError("Invalid promotion data").ErrorResponse)
+		return
+	}
+
+	promotion, err := h.service.CreatePromotion(ctx, &promotionReq)
+	if err != nil {
+		sendPricingError(w, "Error creating promotion", err)
+		return
+	}
+	core.NewCREATED("Promotion created successfully", promotion).Send(w)
+}
+
+func (h *PricingHandler) DeletePromotion(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("PricingHandler")
+	ctx, span := tracer.Start(r.Context(), "DeletePromotion-Handler")
+	defer span.End()
+
+	promotion, err := h.service.DeletePromotion(ctx, mux.Vars(r)["id"])
+	if err != nil {
+		sendPricingError(w, "Error deleting promotion", err)
+		return
+	}
+	core.NewOK("Promotion deleted successfully", promotion).Send(w)
+}
+
+func sendPricingError(w http.ResponseWriter, action string, err error) {
+	var invalid *pricingService.InvalidError
+	switch {
+	case errors.As(err, &invalid):
+		core.SendErrorResponse(w, core.NewBadRequestError(invalid.Error()).ErrorResponse)
+	case errors.Is(err, store.ErrCarNotFound):
+		core.SendErrorResponse(w, core.NewNotFoundError("Car not found").ErrorResponse)
+	case errors.Is(err, pricingService.ErrPriceNotFound):
+		core.SendErrorResponse(w, core.NewNotFoundError("Price not found").ErrorResponse)
+	case errors.Is(err, pricingService.ErrPromotionNotFound):
+		core.SendErrorResponse(w, core.NewNotFoundError("Promotion not found").ErrorResponse)
+	case errors.Is(err, pricingService.ErrPriceInEffect):
+		core.SendErrorResponse(w, core.NewConflictRequestError("Price is already in effect").ErrorResponse)
+	default:
+		log.Printf("%s: %v", action, err)
+		core.SendErrorResponse(w, core.NewErrorResponse("Internal server error", utils.InternalServerError))
+	}
+}
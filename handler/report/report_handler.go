@@ -0,0 +1,95 @@
+package report
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/adohong4/carZone/core"
+	"github.com/adohong4/carZone/models"
+	"github.com/adohong4/carZone/service"
+	reportService "github.com/adohong4/carZone/service/report"
+	"github.com/adohong4/carZone/utils"
+	"go.opentelemetry.io/otel"
+)
+
+const cacheMaxAge = 60 * time.Second
+
+type ReportHandler struct {
+	service service.ReportServiceInterface
+}
+
+func NewReportHandler(service service.ReportServiceInterface) *ReportHandler {
+	return &ReportHandler{
+		service: service,
+	}
+}
+
+// InventoryReport aggregates the cars of the dealership by the comma
+// separated ?group_by dimensions over the ?from and ?to period, as CSV with
+// ?format=csv or an Accept of text/csv, as JSON otherwise.
+func (h *ReportHandler) InventoryReport(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("ReportHandler")
+	ctx, span := tracer.Start(r.Context(), "InventoryReport-Handler")
+	defer span.End()
+
+	query := r.URL.Query()
+	reportReq := models.InventoryReportRequest{From: query.Get("from"), To: query.Get("to")}
+	if groupBy := query.Get("group_by"); groupBy != "" {
+		reportReq.GroupBy = strings.Split(groupBy, ",")
+	}
+
+	format := query.Get("format")
+	if format == "" && strings.Contains(r.Header.Get("Accept"), "text/csv") {
+		format = "csv"
+	}
+	if format != "" && format != "json" && format != "csv" {
+		core.SendErrorResponse(w, core.NewBadRequestError("Format must be json or csv").ErrorResponse)
+		return
+	}
+
+	report, err := h.service.InventoryReport(ctx, reportReq)
+	if err != nil {
+		sendReportError(w, "Error building inventory report", err)
+		return
+	}
+
+	if format != "csv" {
+		// a cached report is served as built, it last changed when it was
+		// generated
+		core.NewOK("Inventory report retrieved successfully", report).SendCached(w, r, report.GeneratedAt, cacheMaxAge)
+		return
+	}
+
+	var body bytes.Buffer
+	if err := reportService.WriteInventoryCSV(&body, *report); err != nil {
+		sendReportError(w, "Error writing inventory report", err)
+		return
+	}
+	etag := core.ComputeETag(body.Bytes())
+	core.SetCacheHeaders(w, etag, report.GeneratedAt, cacheMaxAge)
+	if core.IsNotModified(r, etag, report.GeneratedAt) {
+		core.SendNotModified(w)
+		return
+	}
+
+	filename := "inventory-" + report.GeneratedAt.Format("20060102") + ".csv"
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	w.WriteHeader(http.StatusOK)
+	w.Write(body.Bytes())
+}
+
+func sendReportError(w http.ResponseWriter, action string, err error) {
+	var invalid *reportService.InvalidError
+	switch {
+	case errors.As(err, &invalid):
+		core.SendErrorResponse(w, core.NewBadRequestError(invalid.Error()).ErrorResponse)
+	default:
+		log.Printf("%s: %v", action, err)
+		core.SendErrorResponse(w, core.NewErrorResponse("Internal server error", utils.InternalServerError))
+	}
+}
@@ -0,0 +1,97 @@
+package reservation
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/adohong4/carZone/core"
+	"github.com/adohong4/carZone/models"
+	"github.com/adohong4/carZone/service"
+	reservationService "github.com/adohong4/carZone/service/reservation"
+	"github.com/adohong4/carZone/store"
+	"github.com/adohong4/carZone/utils"
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel"
+)
+
+type ReservationHandler struct {
+	service service.ReservationServiceInterface
+}
+
+func NewReservationHandler(service service.ReservationServiceInterface) *ReservationHandler {
+	return &ReservationHandler{
+		service: service,
+	}
+}
+
+// CreateReservation holds the car in the path for a customer, on behalf of
+// the user of the token.
+func (h *ReservationHandler) CreateReservation(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("ReservationHandler")
+	ctx, span := tracer.Start(r.Context(), "CreateReservation-Handler")
+	defer span.End()
+
+	var reservationReq models.ReservationRequest
+	if err := json.NewDecoder(r.Body).Decode(&reservationReq); err != nil {
+		core.SendErrorResponse(w, core.NewBadRequestError("Invalid reservation data").ErrorResponse)
+		return
+	}
+
+	username, _ := ctx.Value("username").(string)
+	reservation, err := h.service.CreateReservation(ctx, mux.Vars(r)["id"], username, &reservationReq)
+	if err != nil {
+		sendReservationError(w, "Error creating reservation", err)
+		return
+	}
+	core.NewCREATED("Reservation created successfully", reservation).Send(w)
+}
+
+func (h *ReservationHandler) ListReservations(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("ReservationHandler")
+	ctx, span := tracer.Start(r.Context(), "ListReservations-Handler")
+	defer span.End()
+
+	reservations, err := h.service.ListReservations(ctx, mux.Vars(r)["id"])
+	if err != nil {
+		sendReservationError(w, "Error listing reservations", err)
+		return
+	}
+	core.NewOK("Reservations retrieved successfully", reservations).Send(w)
+}
+
+func (h *ReservationHandler) ReleaseReservation(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("ReservationHandler")
+	ctx, span := tracer.Start(r.Context(), "ReleaseReservation-Handler")
+	defer span.End()
+
+	vars := mux.Vars(r)
+	reservation, err := h.service.ReleaseReservation(ctx, vars["id"], vars["reservationId"])
+	if err != nil {
+		sendReservationError(w, "Error releasing reservation", err)
+		return
+	}
+	core.NewOK("Reservation released successfully", reservation).Send(w)
+}
+
+func sendReservationError(w http.ResponseWriter, action string, err error) {
+	var invalid *reservationService.InvalidError
+	switch {
+	case errors.As(err, &invalid):
+		core.SendErrorResponse(w, core.NewBadRequestError(invalid.Error()).ErrorResponse)
+	case errors.Is(err, store.ErrCarNotFound):
+		core.SendErrorResponse(w, core.NewNotFoundError("Car not found").ErrorResponse)
+	case errors.Is(err, store.ErrCustomerNotFound):
+		core.SendErrorResponse(w, core.NewBadRequestError("Customer not found").ErrorResponse)
+	case errors.Is(err, reservationService.ErrReservationNotFound):
+		core.SendErrorResponse(w, core.NewNotFoundError("Reservation not found").ErrorResponse)
+	case errors.Is(err, store.ErrCarReserved):
+		core.SendErrorResponse(w, core.NewConflictRequestError("Car is already reserved").ErrorResponse)
+	case errors.Is(err, store.ErrReservationNotActive):
+		core.SendErrorResponse(w, core.NewConflictRequestError("Reservation is no longer active").ErrorResponse)
+	default:
+		log.Printf("%s: %v", action, err)
+		core.SendErrorResponse(w, core.NewErrorResponse("Internal server error", utils.InternalServerError))
+	}
+}
@@ -0,0 +1,66 @@
+package validation
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/adohong4/carZone/core"
+	"github.com/adohong4/carZone/models"
+	"github.com/adohong4/carZone/service"
+	validationService "github.com/adohong4/carZone/service/validation"
+	"github.com/adohong4/carZone/utils"
+	"go.opentelemetry.io/otel"
+)
+
+type ValidationHandler struct {
+	service service.ValidationServiceInterface
+}
+
+func NewValidationHandler(service service.ValidationServiceInterface) *ValidationHandler {
+	return &ValidationHandler{
+		service: service,
+	}
+}
+
+func (h *ValidationHandler) GetRules(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("ValidationHandler")
+	ctx, span := tracer.Start(r.Context(), "GetRules-Handler")
+	defer span.End()
+
+	rules, err := h.service.GetRules(ctx)
+	if err != nil {
+		log.Println("Error getting validation rules: ", err)
+		core.SendErrorResponse(w, core.NewErrorResponse("Internal server error", utils.InternalServerError))
+		return
+	}
+	core.NewOK("Validation rules retrieved successfully", rules).Send(w)
+}
+
+// SetRules replaces the rules the dealership of the token adds to, or
+// overrides in, the defaults.
+func (h *ValidationHandler) SetRules(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("ValidationHandler")
+	ctx, span := tracer.Start(r.Context(), "SetRules-Handler")
+	defer span.End()
+
+	var rulesReq models.ValidationRulesRequest
+	if err := json.NewDecoder(r.Body).Decode(&rulesReq); err != nil {
+		core.SendErrorResponse(w, core.NewBadRequestError("Invalid validation rules").ErrorResponse)
+		return
+	}
+
+	rules, err := h.service.SetRules(ctx, &rulesReq)
+	if err != nil {
+		var invalidErr *validationService.InvalidError
+		if errors.As(err, &invalidErr) {
+			core.SendErrorResponse(w, core.NewBadRequestError(invalidErr.Reason).ErrorResponse)
+			return
+		}
+		log.Println("Error setting validation rules: ", err)
+		core.SendErrorResponse(w, core.NewErrorResponse("Internal server error", utils.InternalServerError))
+		return
+	}
+	core.NewOK("Validation rules updated successfully", rules).Send(w)
+}
@@ -0,0 +1,150 @@
+package vehicle
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/adohong4/carZone/core"
+	"github.com/adohong4/carZone/models"
+	"github.com/adohong4/carZone/service"
+	vehicleService "github.com/adohong4/carZone/service/vehicle"
+	"github.com/adohong4/carZone/store"
+	"github.com/adohong4/carZone/utils"
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel"
+)
+
+type VehicleHandler struct {
+	service service.VehicleServiceInterface
+}
+
+func NewVehicleHandler(service service.VehicleServiceInterface) *VehicleHandler {
+	return &VehicleHandler{
+		service: service,
+	}
+}
+
+func (h *VehicleHandler) GetVehicle(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("VehicleHandler")
+	ctx, span := tracer.Start(r.Context(), "GetVehicle-Handler")
+	defer span.End()
+
+	vehicle, err := h.service.GetVehicle(ctx, mux.Vars(r)["vin"])
+	if err != nil {
+		sendVehicleError(w, "Error getting vehicle", err)
+		return
+	}
+	core.NewOK("Vehicle retrieved successfully", vehicle).Send(w)
+}
+
+// ListVehicles lists units, optionally filtered by the car_id, status and
+// location query parameters.
+func (h *VehicleHandler) ListVehicles(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("VehicleHandler")
+	ctx, span := tracer.Start(r.Context(), "ListVehicles-Handler")
+	defer span.End()
+
+	query := r.URL.Query()
+	filter := models.VehicleFilter{
+		CarID:    query.Get("car_id"),
+		Status:   query.Get("status"),
+		Location: query.Get("location"),
+	}
+
+	vehicles, err := h.service.ListVehicles(ctx, filter)
+	if err != nil {
+		sendVehicleError(w, "Error listing vehicles", err)
+		return
+	}
+	if vehicles == nil {
+		vehicles = []models.Vehicle{}
+	}
+	core.NewOK("Vehicles retrieved successfully", vehicles).Send(w)
+}
+
+func (h *VehicleHandler) ReceiveVehicle(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("VehicleHandler")
+	ctx, span := tracer.Start(r.Context(), "ReceiveVehicle-Handler")
+	defer span.End()
+
+	var vehicleReq models.VehicleRequest
+	if err := json.NewDecoder(r.Body).Decode(&vehicleReq); err != nil {
+		core.SendErrorResponse(w, core.NewBadRequestError("Invalid vehicle data").ErrorResponse)
+		return
+	}
+
+	vehicle, err := h.service.ReceiveVehicle(ctx, &vehicleReq)
+	if err != nil {
+		sendVehicleError(w, "Error receiving vehicle", err)
+		return
+	}
+	core.NewCREATED("Vehicle received successfully", vehicle).Send(w)
+}
+
+func (h *VehicleHandler) MoveVehicle(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("VehicleHandler")
+	ctx, span := tracer.Start(r.Context(), "MoveVehicle-Handler")
+	defer span.End()
+
+	var moveReq models.VehicleMoveRequest
+	if err := json.NewDecoder(r.Body).Decode(&moveReq); err != nil {
+		core.SendErrorResponse(w, core.NewBadRequestError("Invalid vehicle data").ErrorResponse)
+		return
+	}
+
+	vehicle, err := h.service.MoveVehicle(ctx, mux.Vars(r)["vin"], &moveReq)
+	if err != nil {
+		sendVehicleError(w, "Error moving vehicle", err)
+		return
+	}
+	core.NewOK("Vehicle moved successfully", vehicle).Send(w)
+}
+
+// CarStock returns the unit counts of the car model in the path.
+func (h *VehicleHandler) CarStock(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("VehicleHandler")
+	ctx, span := tracer.Start(r.Context(), "CarStock-Handler")
+	defer span.End()
+
+	counts, err := h.service.StockCounts(ctx, mux.Vars(r)["id"])
+	if err != nil {
+		sendVehicleError(w, "Error counting stock", err)
+		return
+	}
+	core.NewOK("Stock retrieved successfully", counts[0]).Send(w)
+}
+
+// Stock returns the unit counts of every car model with units.
+func (h *VehicleHandler) Stock(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("VehicleHandler")
+	ctx, span := tracer.Start(r.Context(), "Stock-Handler")
+	defer span.End()
+
+	counts, err := h.service.StockCounts(ctx, "")
+	if err != nil {
+		sendVehicleError(w, "Error counting stock", err)
+		return
+	}
+	core.NewOK("Stock retrieved successfully", counts).Send(w)
+}
+
+func sendVehicleError(w http.ResponseWriter, action string, err error) {
+	var invalid *vehicleService.InvalidError
+	switch {
+	case errors.As(err, &invalid):
+		core.SendErrorResponse(w, core.NewBadRequestError(invalid.Error()).ErrorResponse)
+	case errors.Is(err, vehicleService.ErrVehicleNotFound):
+		core.SendErrorResponse(w, core.NewNotFoundError("Vehicle not found").ErrorResponse)
+	case errors.Is(err, store.ErrCarNotFound):
+		core.SendErrorResponse(w, core.NewBadRequestError("Car not found").ErrorResponse)
+	case errors.Is(err, store.ErrVehicleExists):
+		core.SendErrorResponse(w, core.NewConflictRequestError("Vehicle already exists").ErrorResponse)
+	case errors.Is(err, store.ErrVehicleConflict):
+		core.SendErrorResponse(w, core.NewConflictRequestError("Vehicle was changed by another request, retry").ErrorResponse)
+	default:
+		log.Printf("%s: %v", action, err)
+		core.SendErrorResponse(w, core.NewErrorResponse("Internal server error", utils.InternalServerError))
+	}
+}
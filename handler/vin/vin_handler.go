@@ -0,0 +1,51 @@
+package vin
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/adohong4/carZone/core"
+	"github.com/adohong4/carZone/models"
+	"github.com/adohong4/carZone/service"
+	"github.com/adohong4/carZone/utils"
+	vinDecoder "github.com/adohong4/carZone/vin"
+	"go.opentelemetry.io/otel"
+)
+
+type VINHandler struct {
+	service service.VINServiceInterface
+}
+
+func NewVINHandler(service service.VINServiceInterface) *VINHandler {
+	return &VINHandler{
+		service: service,
+	}
+}
+
+// Decode answers with what a VIN tells and the car request it pre-fills.
+func (h *VINHandler) Decode(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("VINHandler")
+	ctx, span := tracer.Start(r.Context(), "Decode-Handler")
+	defer span.End()
+
+	var decodeReq models.VINDecodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&decodeReq); err != nil {
+		core.SendErrorResponse(w, core.NewBadRequestError("Invalid VIN data").ErrorResponse)
+		return
+	}
+
+	result, err := h.service.Decode(ctx, decodeReq.VIN)
+	if err != nil {
+		switch {
+		case errors.Is(err, vinDecoder.ErrLength), errors.Is(err, vinDecoder.ErrCharacters), errors.Is(err, vinDecoder.ErrCheckDigit):
+			core.SendErrorResponse(w, core.NewBadRequestError(err.Error()).ErrorResponse)
+		default:
+			log.Println("Error decoding VIN: ", err)
+			core.SendErrorResponse(w, core.NewErrorResponse("Internal server error", utils.InternalServerError))
+		}
+		return
+	}
+	core.NewOK("VIN decoded successfully", result).Send(w)
+}
@@ -0,0 +1,145 @@
+package webhook
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/adohong4/carZone/core"
+	"github.com/adohong4/carZone/models"
+	"github.com/adohong4/carZone/service"
+	webhookService "github.com/adohong4/carZone/service/webhook"
+	"github.com/adohong4/carZone/store"
+	"github.com/adohong4/carZone/utils"
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel"
+)
+
+type WebhookHandler struct {
+	service service.WebhookServiceInterface
+}
+
+func NewWebhookHandler(service service.WebhookServiceInterface) *WebhookHandler {
+	return &WebhookHandler{
+		service: service,
+	}
+}
+
+// CreateWebhook answers with the secret of the webhook, it is not shown
+// again.
+func (h *WebhookHandler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("WebhookHandler")
+	ctx, span := tracer.Start(r.Context(), "CreateWebhook-Handler")
+	defer span.End()
+
+	var webhookReq models.WebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&webhookReq); err != nil {
+		core.SendErrorResponse(w, core.NewBadRequestError("Invalid webhook data").ErrorResponse)
+		return
+	}
+
+	webhook, err := h.service.CreateWebhook(ctx, &webhookReq)
+	if err != nil {
+		sendWebhookError(w, "Error creating webhook", err)
+		return
+	}
+	core.NewCREATED("Webhook created successfully", webhook).Send(w)
+}
+
+func (h *WebhookHandler) ListWebhooks(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("WebhookHandler")
+	ctx, span := tracer.Start(r.Context(), "ListWebhooks-Handler")
+	defer span.End()
+
+	webhooks, err := h.service.ListWebhooks(ctx)
+	if err != nil {
+		sendWebhookError(w, "Error listing webhooks", err)
+		return
+	}
+	core.NewOK("Webhooks retrieved successfully", webhooks).Send(w)
+}
+
+func (h *WebhookHandler) GetWebhook(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("WebhookHandler")
+	ctx, span := tracer.Start(r.Context(), "GetWebhook-Handler")
+	defer span.End()
+
+	webhook, err := h.service.GetWebhook(ctx, mux.Vars(r)["id"])
+	if err != nil {
+		sendWebhookError(w, "Error getting webhook", err)
+		return
+	}
+	core.NewOK("Webhook retrieved successfully", webhook).Send(w)
+}
+
+func (h *WebhookHandler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("WebhookHandler")
+	ctx, span := tracer.Start(r.Context(), "DeleteWebhook-Handler")
+	defer span.End()
+
+	webhook, err := h.service.DeleteWebhook(ctx, mux.Vars(r)["id"])
+	if err != nil {
+		sendWebhookError(w, "Error deleting webhook", err)
+		return
+	}
+	core.NewOK("Webhook deleted successfully", webhook).Send(w)
+}
+
+// ListDeliveries is the delivery log of a webhook, the latest first.
+func (h *WebhookHandler) ListDeliveries(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("WebhookHandler")
+	ctx, span := tracer.Start(r.Context(), "ListDeliveries-Handler")
+	defer span.End()
+
+	deliveries, err := h.service.ListDeliveries(ctx, mux.Vars(r)["id"])
+	if err != nil {
+		sendWebhookError(w, "Error listing webhook deliveries", err)
+		return
+	}
+	core.NewOK("Deliveries retrieved successfully", deliveries).Send(w)
+}
+
+// GetDelivery includes every attempt at the delivery.
+func (h *WebhookHandler) GetDelivery(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("WebhookHandler")
+	ctx, span := tracer.Start(r.Context(), "GetDelivery-Handler")
+	defer span.End()
+
+	vars := mux.Vars(r)
+	delivery, err := h.service.GetDelivery(ctx, vars["id"], vars["deliveryId"])
+	if err != nil {
+		sendWebhookError(w, "Error getting webhook delivery", err)
+		return
+	}
+	core.NewOK("Delivery retrieved successfully", delivery).Send(w)
+}
+
+func (h *WebhookHandler) Redeliver(w http.ResponseWriter, r *http.Request) {
+	tracer := otel.Tracer("WebhookHandler")
+	ctx, span := tracer.Start(r.Context(), "Redeliver-Handler")
+	defer span.End()
+
+	vars := mux.Vars(r)
+	delivery, err := h.service.Redeliver(ctx, vars["id"], vars["deliveryId"])
+	if err != nil {
+		sendWebhookError(w, "Error redelivering webhook delivery", err)
+		return
+	}
+	core.NewOK("Delivery scheduled for redelivery", delivery).Send(w)
+}
+
+func sendWebhookError(w http.ResponseWriter, action string, err error) {
+	var invalid *webhookService.InvalidError
+	switch {
+	case errors.As(err, &invalid):
+		core.SendErrorResponse(w, core.NewBadRequestError(invalid.Error()).ErrorResponse)
+	case errors.Is(err, store.ErrWebhookNotFound):
+		core.SendErrorResponse(w, core.NewNotFoundError("Webhook not found").ErrorResponse)
+	case errors.Is(err, store.ErrDeliveryNotFound):
+		core.SendErrorResponse(w, core.NewNotFoundError("Delivery not found").ErrorResponse)
+	default:
+		log.Printf("%s: %v", action, err)
+		core.SendErrorResponse(w, core.NewErrorResponse("Internal server error", utils.InternalServerError))
+	}
+}
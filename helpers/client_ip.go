@@ -0,0 +1,17 @@
+package helpers
+
+import (
+	"net"
+	"net/http"
+)
+
+// ClientIP returns the address of the peer that sent the request.
+// X-Forwarded-For is ignored on purpose, it is set by the client unless a
+// trusted proxy rewrites it.
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
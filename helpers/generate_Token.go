@@ -1,22 +1,108 @@
 package helpers
 
 import (
+	"errors"
 	"time"
 
+	"github.com/adohong4/carZone/models"
 	"github.com/golang-jwt/jwt/v4"
 )
 
-func GenerateToken(UserName string) (string, error) {
-	expiration := time.Now().Add(24 * time.Hour)
+var jwtKey = []byte("some_value")
 
-	claims := &jwt.StandardClaims{
-		ExpiresAt: expiration.Unix(),
-		IssuedAt:  time.Now().Unix(),
-		Subject:   UserName,
+// PurposeSecondFactor marks the short-lived token handed out between the
+// password step and the TOTP step of a login. It is not an access token.
+const PurposeSecondFactor = "2fa"
+
+// Claims is the payload of CarZone tokens. Access tokens have no Purpose.
+type Claims struct {
+	UserName string `json:"username"`
+	Role     string `json:"role"`
+	TenantID string `json:"tenant_id,omitempty"`
+	Purpose  string `json:"purpose,omitempty"`
+	jwt.StandardClaims
+}
+
+func GenerateToken(user models.User) (string, error) {
+	return signToken(user, "", 24*time.Hour)
+}
+
+// GenerateChallengeToken issues the token that /login/2fa exchanges for an
+// access token once the second factor is verified.
+func GenerateChallengeToken(user models.User) (string, error) {
+	return signToken(user, PurposeSecondFactor, 5*time.Minute)
+}
+
+// ParseChallengeToken validates a challenge token and returns its user.
+func ParseChallengeToken(tokenString string) (models.User, error) {
+	claims := &Claims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.New("unexpected signing method")
+		}
+		return jwtKey, nil
+	})
+	if err != nil || !token.Valid || claims.Purpose != PurposeSecondFactor {
+		return models.User{}, errors.New("invalid challenge token")
+	}
+	return models.User{UserName: claims.UserName, Role: claims.Role, TenantID: claims.TenantID}, nil
+}
+
+// PurposeOIDCFlow marks the token that keeps the state of an OIDC login
+// between the redirect to the identity provider and the callback.
+const PurposeOIDCFlow = "oidc"
+
+type flowClaims struct {
+	Flow    models.OIDCFlow `json:"flow"`
+	Purpose string          `json:"purpose"`
+	jwt.StandardClaims
+}
+
+// GenerateOIDCFlowToken signs flow for the duration of an OIDC login.
+func GenerateOIDCFlowToken(flow models.OIDCFlow) (string, error) {
+	claims := &flowClaims{
+		Flow:    flow,
+		Purpose: PurposeOIDCFlow,
+		StandardClaims: jwt.StandardClaims{
+			ExpiresAt: time.Now().Add(10 * time.Minute).Unix(),
+			IssuedAt:  time.Now().Unix(),
+		},
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(jwtKey)
+}
+
+// ParseOIDCFlowToken validates a token made by GenerateOIDCFlowToken.
+func ParseOIDCFlowToken(tokenString string) (models.OIDCFlow, error) {
+	claims := &flowClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.New("unexpected signing method")
+		}
+		return jwtKey, nil
+	})
+	if err != nil || !token.Valid || claims.Purpose != PurposeOIDCFlow {
+		return models.OIDCFlow{}, errors.New("invalid OIDC flow token")
+	}
+	return claims.Flow, nil
+}
+
+func signToken(user models.User, purpose string, lifetime time.Duration) (string, error) {
+	expiration := time.Now().Add(lifetime)
+
+	claims := &Claims{
+		UserName: user.UserName,
+		Role:     user.Role,
+		TenantID: user.TenantID,
+		Purpose:  purpose,
+		StandardClaims: jwt.StandardClaims{
+			ExpiresAt: expiration.Unix(),
+			IssuedAt:  time.Now().Unix(),
+			Subject:   user.UserName,
+		},
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	signedToken, err := token.SignedString([]byte("some_value"))
+	signedToken, err := token.SignedString(jwtKey)
 	if err != nil {
 		return "", err
 	}
@@ -0,0 +1,114 @@
+// Package invoice renders the invoice of a confirmed order as HTML or PDF.
+package invoice
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/adohong4/carZone/models"
+)
+
+var ErrNotInvoiced = errors.New("order has no invoice number yet")
+
+//go:embed invoice.html
+var htmlSource string
+
+var htmlTemplate = template.Must(template.New("invoice").Funcs(template.FuncMap{
+	"date": func(t time.Time) string { return t.Format("2 January 2006") },
+}).Parse(htmlSource))
+
+// Invoice is an order as sold by a dealership.
+type Invoice struct {
+	Seller models.Dealership
+	Order  models.Order
+}
+
+func (inv Invoice) date() time.Time {
+	if inv.Order.ConfirmedAt != nil {
+		return *inv.Order.ConfirmedAt
+	}
+	return inv.Order.CreatedAt
+}
+
+func (inv Invoice) check() error {
+	if inv.Order.InvoiceNumber == "" {
+		return ErrNotInvoiced
+	}
+	return nil
+}
+
+// RenderHTML writes the invoice as a standalone HTML page.
+func RenderHTML(w io.Writer, inv Invoice) error {
+	if err := inv.check(); err != nil {
+		return err
+	}
+	return htmlTemplate.Execute(w, struct {
+		Invoice
+		Date   time.Time
+		Places int32
+	}{inv, inv.date(), inv.Order.MinorUnits()})
+}
+
+// RenderPDF writes the invoice as a PDF document.
+func RenderPDF(w io.Writer, inv Invoice) error {
+	if err := inv.check(); err != nil {
+		return err
+	}
+	return writePDF(w, textLines(inv))
+}
+
+// textLines lays the invoice out in 90 columns of monospaced text.
+func textLines(inv Invoice) []string {
+	order := inv.Order
+	places := order.MinorUnits()
+	lines := []string{
+		"# INVOICE " + order.InvoiceNumber,
+		"",
+		"Date:   " + inv.date().Format("2 January 2006"),
+		"Seller: " + inv.Seller.Name,
+		"",
+		"# Bill to",
+		order.CustomerName,
+	}
+	if order.CustomerEmail != "" {
+		lines = append(lines, order.CustomerEmail)
+	}
+	lines = append(lines, strings.Split(order.CustomerAddress, "\n")...)
+	lines = append(lines, "", "Salesperson: "+order.Salesperson, "")
+
+	row := "%-40s %5s %13s %13s %13s"
+	lines = append(lines, "# "+fmt.Sprintf(row, "Description", "Qty", "Unit price", "Discount", "Amount"))
+	lines = append(lines, strings.Repeat("-", 88))
+	for _, line := range order.Lines {
+		discount := line.Discount.StringFixed(places)
+		if !line.DiscountPercent.IsZero() {
+			discount = fmt.Sprintf("%s (%s%%)", discount, line.DiscountPercent.String())
+		}
+		lines = append(lines, fmt.Sprintf(row,
+			truncate(line.Description, 40), fmt.Sprint(line.Quantity),
+			line.UnitPrice.StringFixed(places), truncate(discount, 13), line.Total.StringFixed(places)))
+	}
+	lines = append(lines, strings.Repeat("-", 88))
+
+	total := "%74s %13s"
+	lines = append(lines,
+		fmt.Sprintf(total, "Subtotal", order.Subtotal.StringFixed(places)),
+		fmt.Sprintf(total, "Discounts included", order.DiscountTotal.StringFixed(places)),
+		fmt.Sprintf(total, "Tax "+order.TaxRate.String()+"%", order.TaxTotal.StringFixed(places)),
+		"# "+fmt.Sprintf(total, "Total "+order.Currency, order.Total.StringFixed(places)),
+	)
+	return lines
+}
+
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n-1]) + "~"
+}
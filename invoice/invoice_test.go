@@ -0,0 +1,106 @@
+package invoice
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/adohong4/carZone/decimal"
+	"github.com/adohong4/carZone/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testInvoice(lines int) Invoice {
+	confirmedAt := time.Date(2026, 3, 14, 10, 0, 0, 0, time.UTC)
+	order := models.Order{
+		ID:              uuid.New(),
+		InvoiceNumber:   "INV-000042",
+		Status:          models.OrderConfirmed,
+		CustomerName:    "Jane <Doe>",
+		CustomerEmail:   "jane@example.com",
+		CustomerAddress: "1 Main Street\nSpringfield",
+		Salesperson:     "staff",
+		TaxRate:         decimal.New(825, 2),
+		ConfirmedAt:     &confirmedAt,
+	}
+	for i := 0; i < lines; i++ {
+		order.Lines = append(order.Lines, models.OrderLine{
+			Description:     fmt.Sprintf("Toyota Camry (2023) #%d", i+1),
+			Quantity:        1,
+			UnitPrice:       decimal.New(2499999, 2),
+			DiscountPercent: decimal.New(5, 0),
+		})
+	}
+	order.ComputeTotals()
+	return Invoice{Seller: models.Dealership{Name: "CarZone Hanoi"}, Order: order}
+}
+
+func TestRenderHTML(t *testing.T) {
+	var out bytes.Buffer
+	require.NoError(t, RenderHTML(&out, testInvoice(2)))
+
+	html := out.String()
+	assert.Contains(t, html, "Invoice INV-000042")
+	assert.Contains(t, html, "14 March 2026")
+	assert.Contains(t, html, "CarZone Hanoi")
+	assert.Contains(t, html, "Jane &lt;Doe&gt;")
+	assert.Contains(t, html, "24999.99")
+	assert.Contains(t, html, "1250.00 (5%)")
+	assert.Contains(t, html, "51418.73")
+}
+
+func TestRenderRequiresInvoiceNumber(t *testing.T) {
+	inv := testInvoice(1)
+	inv.Order.InvoiceNumber = ""
+	assert.ErrorIs(t, RenderHTML(&bytes.Buffer{}, inv), ErrNotInvoiced)
+	assert.ErrorIs(t, RenderPDF(&bytes.Buffer{}, inv), ErrNotInvoiced)
+}
+
+func TestRenderPDF(t *testing.T) {
+	var out bytes.Buffer
+	require.NoError(t, RenderPDF(&out, testInvoice(2)))
+
+	pdf := out.String()
+	assert.True(t, strings.HasPrefix(pdf, "%PDF-1.4"))
+	assert.True(t, strings.HasSuffix(pdf, "%%EOF\n"))
+	assert.Contains(t, pdf, "/Count 1")
+	assert.Contains(t, pdf, "INVOICE INV-000042")
+	assert.Contains(t, pdf, `Toyota Camry \(2023\) #1`)
+	assert.Contains(t, pdf, "51418.73")
+	checkXref(t, pdf)
+}
+
+func TestRenderPDFPaginates(t *testing.T) {
+	var out bytes.Buffer
+	require.NoError(t, RenderPDF(&out, testInvoice(150)))
+
+	pdf := out.String()
+	assert.Contains(t, pdf, "/Count 3")
+	checkXref(t, pdf)
+}
+
+// checkXref checks every cross-reference entry points at its object.
+func checkXref(t *testing.T, pdf string) {
+	start, err := strconv.Atoi(regexp.MustCompile(`startxref\n(\d+)`).FindStringSubmatch(pdf)[1])
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(pdf[start:], "xref\n"))
+
+	entries := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllStringSubmatch(pdf[start:], -1)
+	require.NotEmpty(t, entries)
+	for i, entry := range entries {
+		offset, err := strconv.Atoi(entry[1])
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(pdf[offset:], fmt.Sprintf("%d 0 obj", i+1)), "object %d", i+1)
+	}
+}
+
+func TestEscape(t *testing.T) {
+	assert.Equal(t, `a\(b\)\\c`, escape(`a(b)\c`))
+	assert.Equal(t, `Citro\353n \200 ?`, escape("Citroën € 日"))
+}
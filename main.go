@@ -7,20 +7,79 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/adohong4/carZone/blob"
+	"github.com/adohong4/carZone/cache"
 	"github.com/adohong4/carZone/driver"
+	"github.com/adohong4/carZone/events"
 	carHandler "github.com/adohong4/carZone/handler/car"
+	catalogueHandler "github.com/adohong4/carZone/handler/catalogue"
+	currencyHandler "github.com/adohong4/carZone/handler/currency"
+	customerHandler "github.com/adohong4/carZone/handler/customer"
+	dealershipHandler "github.com/adohong4/carZone/handler/dealership"
 	engineHandler "github.com/adohong4/carZone/handler/engine"
 	loginHandler "github.com/adohong4/carZone/handler/login"
+	mediaHandler "github.com/adohong4/carZone/handler/media"
+	orderHandler "github.com/adohong4/carZone/handler/order"
+	pricingHandler "github.com/adohong4/carZone/handler/pricing"
+	reportHandler "github.com/adohong4/carZone/handler/report"
+	reservationHandler "github.com/adohong4/carZone/handler/reservation"
+	validationHandler "github.com/adohong4/carZone/handler/validation"
+	vehicleHandler "github.com/adohong4/carZone/handler/vehicle"
+	vinHandler "github.com/adohong4/carZone/handler/vin"
+	webhookHandler "github.com/adohong4/carZone/handler/webhook"
 	middleware "github.com/adohong4/carZone/middleware"
+	"github.com/adohong4/carZone/models"
+	"github.com/adohong4/carZone/money"
+	"github.com/adohong4/carZone/oidc"
+	"github.com/adohong4/carZone/ratelimit"
+	"github.com/adohong4/carZone/service"
+	cachedService "github.com/adohong4/carZone/service/cached"
 	carService "github.com/adohong4/carZone/service/car"
+	catalogueService "github.com/adohong4/carZone/service/catalogue"
+	currencyService "github.com/adohong4/carZone/service/currency"
+	customerService "github.com/adohong4/carZone/service/customer"
+	dealershipService "github.com/adohong4/carZone/service/dealership"
 	engineService "github.com/adohong4/carZone/service/engine"
+	loginService "github.com/adohong4/carZone/service/login"
+	mediaService "github.com/adohong4/carZone/service/media"
+	orderService "github.com/adohong4/carZone/service/order"
+	outboxService "github.com/adohong4/carZone/service/outbox"
+	pricingService "github.com/adohong4/carZone/service/pricing"
+	reportService "github.com/adohong4/carZone/service/report"
+	reservationService "github.com/adohong4/carZone/service/reservation"
+	validationService "github.com/adohong4/carZone/service/validation"
+	vehicleService "github.com/adohong4/carZone/service/vehicle"
+	vinService "github.com/adohong4/carZone/service/vin"
+	webhookService "github.com/adohong4/carZone/service/webhook"
+	"github.com/adohong4/carZone/store"
 	carStore "github.com/adohong4/carZone/store/car"
+	catalogueStore "github.com/adohong4/carZone/store/catalogue"
+	customerStore "github.com/adohong4/carZone/store/customer"
+	dealershipStore "github.com/adohong4/carZone/store/dealership"
 	engineStore "github.com/adohong4/carZone/store/engine"
+	loginStore "github.com/adohong4/carZone/store/login"
+	mediaStore "github.com/adohong4/carZone/store/media"
+	memoryStore "github.com/adohong4/carZone/store/memory"
+	orderStore "github.com/adohong4/carZone/store/order"
+	outboxStore "github.com/adohong4/carZone/store/outbox"
+	priceStore "github.com/adohong4/carZone/store/price"
+	rateStore "github.com/adohong4/carZone/store/rate"
+	reportStore "github.com/adohong4/carZone/store/report"
+	reservationStore "github.com/adohong4/carZone/store/reservation"
+	ruleStore "github.com/adohong4/carZone/store/rule"
+	sqliteStore "github.com/adohong4/carZone/store/sqlite"
+	totpStore "github.com/adohong4/carZone/store/totp"
+	vehicleStore "github.com/adohong4/carZone/store/vehicle"
+	webhookStore "github.com/adohong4/carZone/store/webhook"
+	"github.com/adohong4/carZone/tenant"
 	"github.com/gorilla/mux"
 	"github.com/joho/godotenv"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/redis/go-redis/v9"
 	"go.opentelemetry.io/contrib/instrumentation/github.com/gorilla/mux/otelmux"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
@@ -38,7 +97,7 @@ func main() {
 
 	traceProvider, err := startTracing()
 	if err != nil {
-		log.Fatal("Failed to Start Tracing: %v", err)
+		log.Fatalf("Failed to Start Tracing: %v", err)
 	}
 
 	defer func() {
@@ -49,26 +108,120 @@ func main() {
 
 	otel.SetTracerProvider(traceProvider)
 
-	// Connect database
-	if err := driver.InitDB(); err != nil {
-		log.Fatalf("Unable to initialize the database connection: %v", err)
+	// initialize store, service and handler
+	stores, err := initStores()
+	if err != nil {
+		log.Fatalf("Unable to initialize the stores: %v", err)
 	}
 	defer driver.CloseDB()
 
-	db := driver.GetDB()
-	if db == nil {
-		log.Fatal("database connection is nil, unable to continue")
+	defaultRules, err := validationRules()
+	if err != nil {
+		log.Fatalf("Invalid validation rules: %v", err)
 	}
+	validationService := validationService.NewValidationService(stores.rule, defaultRules)
 
-	// initialize store, service and handler
-	carStore := carStore.New(db)
-	carService := carService.NewCarService(carStore)
+	var carService service.CarServiceInterface = carService.NewCarService(stores.car, stores.engine, stores.catalogue, validationService)
+	var engineService service.EngineServiceInterface = engineService.NewEngineService(stores.engine, validationService)
+	dealershipService := dealershipService.NewDealershipService(stores.dealership)
+	vehicleService := vehicleService.NewVehicleService(stores.vehicle)
+	catalogueService := catalogueService.NewCatalogueService(stores.catalogue)
+	vinService := vinService.NewVINService()
+	orderService := orderService.NewOrderService(stores.order, stores.car, stores.dealership)
+	customerService := customerService.NewCustomerService(stores.customer, stores.reservation, stores.order)
+	var reportService service.ReportServiceInterface = reportService.NewReportService(stores.report)
 
-	engineStore := engineStore.New(db)
-	engineService := engineService.NewEngineService(engineStore)
+	baseCurrency, err := currencyConfig()
+	if err != nil {
+		log.Fatalf("Invalid currency configuration: %v", err)
+	}
+	currencyService := currencyService.NewCurrencyService(stores.rate, baseCurrency)
 
-	carHandler := carHandler.NewCarHandler(carService)
+	lockoutConfig, err := loginConfig()
+	if err != nil {
+		log.Fatalf("Invalid login configuration: %v", err)
+	}
+	loginService := loginService.NewLoginService(stores.login, stores.totp, lockoutConfig)
+
+	serviceCache, cacheTTL, err := initCache()
+	if err != nil {
+		log.Fatalf("Unable to initialize the cache: %v", err)
+	}
+	var carCache *cachedService.CarService
+	if serviceCache != nil {
+		carCache = cachedService.NewCarService(carService, serviceCache, cacheTTL)
+		carService = carCache
+		engineService = cachedService.NewEngineService(engineService, serviceCache, cacheTTL)
+		reportService = cachedService.NewReportService(reportService, serviceCache, cacheTTL)
+	}
+
+	holdConfig, err := reservationConfig()
+	if err != nil {
+		log.Fatalf("Invalid reservation configuration: %v", err)
+	}
+	reservations := reservationService.NewReservationService(stores.reservation, holdConfig)
+	go reservations.RunSweeper(context.Background())
+	// reservations are added after the cache, they change too often
+	carService = reservationService.NewCarService(carService, reservations)
+
+	scheduleConfig, err := pricingConfig()
+	if err != nil {
+		log.Fatalf("Invalid pricing configuration: %v", err)
+	}
+	pricing := pricingService.NewPricingService(stores.price, scheduleConfig)
+	if carCache != nil {
+		pricing.UseCarCache(carCache)
+	}
+	go pricing.RunScheduler(context.Background())
+	// and so are promotions, they start and stop on the minute
+	carService = pricingService.NewCarService(carService, pricing)
+
+	relayConfig, sinks, err := initEvents()
+	if err != nil {
+		log.Fatalf("Unable to initialize event publishing: %v", err)
+	}
+	deliveryConfig, err := webhookConfig()
+	if err != nil {
+		log.Fatalf("Invalid webhook configuration: %v", err)
+	}
+	webhooks := webhookService.NewWebhookService(stores.webhook, deliveryConfig)
+	go webhooks.Run(context.Background())
+	// partner webhooks take the events like any other sink
+	sinks = append(sinks, webhooks)
+	relay := outboxService.NewRelay(stores.outbox, sinks, relayConfig)
+	go relay.Run(context.Background())
+
+	uploadConfig, blobs, err := initMedia()
+	if err != nil {
+		log.Fatalf("Unable to initialize media storage: %v", err)
+	}
+	media := mediaService.NewMediaService(stores.media, blobs, uploadConfig)
+	carService = mediaService.NewCarService(carService, media)
+
+	carHandler := carHandler.NewCarHandler(carService, currencyService)
 	engineHandler := engineHandler.NewEngineHandler(engineService)
+	dealershipHandler := dealershipHandler.NewDealershipHandler(dealershipService)
+	vehicleHandler := vehicleHandler.NewVehicleHandler(vehicleService)
+	vinHandler := vinHandler.NewVINHandler(vinService)
+	reservationHandler := reservationHandler.NewReservationHandler(reservations)
+	orderHandler := orderHandler.NewOrderHandler(orderService)
+	customerHandler := customerHandler.NewCustomerHandler(customerService)
+	reportHandler := reportHandler.NewReportHandler(reportService)
+	webhookHandler := webhookHandler.NewWebhookHandler(webhooks)
+	currencyHandler := currencyHandler.NewCurrencyHandler(currencyService)
+	catalogueHandler := catalogueHandler.NewCatalogueHandler(catalogueService)
+	validationHandler := validationHandler.NewValidationHandler(validationService)
+	pricingHandler := pricingHandler.NewPricingHandler(pricing)
+	mediaHandler := mediaHandler.NewMediaHandler(media, uploadConfig.MaxBytes)
+	oidcService, err := initOIDC()
+	if err != nil {
+		log.Fatalf("Unable to initialize OIDC login: %v", err)
+	}
+	var oidcHandler *loginHandler.OIDCHandler
+	if oidcService != nil {
+		oidcHandler = loginHandler.NewOIDCHandler(oidcService)
+	}
+	loginHandler := loginHandler.NewLoginHandler(loginService)
 
 	// initialize router
 	router := mux.NewRouter()
@@ -76,31 +229,147 @@ func main() {
 	router.Use(otelmux.Middleware("CarZone"))
 	router.Use(middleware.MetricMiddleware)
 
-	// excute schema
-	schemaFile := "store/schema.sql"
-	if err := executeSchemaFile(db, schemaFile); err != nil {
-		log.Fatalf("Cannot excute file schema: %v", err)
+	rateLimitStore, err := initRateLimitStore()
+	if err != nil {
+		log.Fatalf("Unable to initialize the rate limiter: %v", err)
+	}
+	loginLimit, err := rateLimitPolicy("login", "RATE_LIMIT_LOGIN", "5/m", "RATE_LIMIT_LOGIN_KEY", "ip")
+	if err != nil {
+		log.Fatalf("Invalid login rate limit: %v", err)
+	}
+	apiLimit, err := rateLimitPolicy("api", "RATE_LIMIT_API", "300/m", "RATE_LIMIT_API_KEY", "user")
+	if err != nil {
+		log.Fatalf("Invalid API rate limit: %v", err)
 	}
 
-	router.HandleFunc("/login", loginHandler.LoginHandler).Methods("POST")
+	public := router.NewRoute().Subrouter()
+	if loginLimit != nil {
+		public.Use(middleware.RateLimitMiddleware(rateLimitStore, *loginLimit))
+	}
+	public.HandleFunc("/login", loginHandler.Login).Methods("POST")
+	public.HandleFunc("/login/2fa", loginHandler.SecondFactor).Methods("POST")
+	if oidcHandler != nil {
+		public.HandleFunc("/auth/oidc/login", oidcHandler.Login).Methods("GET")
+		public.HandleFunc("/auth/oidc/callback", oidcHandler.Callback).Methods("GET")
+	}
 
 	// Middleware
 	protected := router.PathPrefix("/").Subrouter()
 	protected.Use(middleware.AuthMiddleware)
+	if apiLimit != nil {
+		protected.Use(middleware.RateLimitMiddleware(rateLimitStore, *apiLimit))
+	}
 
 	// Route
+	// before /cars/{id}, which would take "compare" for an ID
+	protected.HandleFunc("/cars/compare", carHandler.CompareCars).Methods("GET")
 	protected.HandleFunc("/cars/{id}", carHandler.GetCarById).Methods("GET")
 	protected.HandleFunc("/cars", carHandler.GetCarByBrand).Methods("GET")
 	protected.HandleFunc("/cars", carHandler.CreateCar).Methods("POST")
+	protected.HandleFunc("/cars/validate", carHandler.ValidateCar).Methods("POST")
 	protected.HandleFunc("/cars/{id}", carHandler.UpdateCar).Methods("PUT")
 	protected.HandleFunc("/cars/{id}", carHandler.DeleteCar).Methods("DELETE")
+	protected.HandleFunc("/cars/{id}/stock", vehicleHandler.CarStock).Methods("GET")
+	protected.HandleFunc("/cars/{id}/reservations", reservationHandler.ListReservations).Methods("GET")
+	protected.HandleFunc("/cars/{id}/reservations", reservationHandler.CreateReservation).Methods("POST")
+	protected.HandleFunc("/cars/{id}/reservations/{reservationId}", reservationHandler.ReleaseReservation).Methods("DELETE")
+	protected.HandleFunc("/cars/{id}/price-history", pricingHandler.PriceHistory).Methods("GET")
+	protected.HandleFunc("/cars/{id}/prices", pricingHandler.SchedulePrice).Methods("POST")
+	protected.HandleFunc("/cars/{id}/prices/{priceId}", pricingHandler.CancelPrice).Methods("DELETE")
+	protected.HandleFunc("/cars/{id}/images", mediaHandler.ListImages).Methods("GET")
+	protected.HandleFunc("/cars/{id}/images", mediaHandler.UploadImage).Methods("POST")
+	protected.HandleFunc("/cars/{id}/images/order", mediaHandler.SetOrder).Methods("PUT")
+	protected.HandleFunc("/cars/{id}/images/{imageId}/primary", mediaHandler.SetPrimary).Methods("POST")
+	protected.HandleFunc("/cars/{id}/images/{imageId}", mediaHandler.DeleteImage).Methods("DELETE")
+
+	protected.HandleFunc("/promotions", pricingHandler.ListPromotions).Methods("GET")
+	protected.HandleFunc("/promotions", pricingHandler.CreatePromotion).Methods("POST")
+	protected.HandleFunc("/promotions/{id}", pricingHandler.DeletePromotion).Methods("DELETE")
+
+	protected.HandleFunc("/vehicles", vehicleHandler.ListVehicles).Methods("GET")
+	protected.HandleFunc("/vehicles", vehicleHandler.ReceiveVehicle).Methods("POST")
+	protected.HandleFunc("/vehicles/{vin}", vehicleHandler.GetVehicle).Methods("GET")
+	protected.HandleFunc("/vehicles/{vin}/move", vehicleHandler.MoveVehicle).Methods("POST")
+	protected.HandleFunc("/stock", vehicleHandler.Stock).Methods("GET")
+
+	protected.HandleFunc("/vin/decode", vinHandler.Decode).Methods("POST")
+
+	protected.HandleFunc("/orders", orderHandler.ListOrders).Methods("GET")
+	protected.HandleFunc("/orders", orderHandler.CreateOrder).Methods("POST")
+	protected.HandleFunc("/orders/{id}", orderHandler.GetOrder).Methods("GET")
+	protected.HandleFunc("/orders/{id}", orderHandler.UpdateOrder).Methods("PUT")
+	protected.HandleFunc("/orders/{id}/status", orderHandler.ChangeStatus).Methods("POST")
+	protected.HandleFunc("/orders/{id}/invoice", orderHandler.Invoice).Methods("GET")
+
+	protected.HandleFunc("/customers", customerHandler.ListCustomers).Methods("GET")
+	protected.HandleFunc("/customers", customerHandler.CreateCustomer).Methods("POST")
+	protected.HandleFunc("/customers/{id}", customerHandler.GetCustomerById).Methods("GET")
+	protected.HandleFunc("/customers/{id}", customerHandler.UpdateCustomer).Methods("PUT")
+	protected.HandleFunc("/customers/{id}", customerHandler.DeleteCustomer).Methods("DELETE")
+	protected.HandleFunc("/customers/{id}/notes", customerHandler.AddNote).Methods("POST")
+	protected.HandleFunc("/customers/{id}/cars", customerHandler.LinkCar).Methods("POST")
+	protected.HandleFunc("/customers/{id}/export", customerHandler.Export).Methods("GET")
+	protected.HandleFunc("/customers/{id}/erase", customerHandler.Erase).Methods("POST")
+
+	protected.HandleFunc("/rates", currencyHandler.ListRates).Methods("GET")
+
+	protected.HandleFunc("/reports/inventory", reportHandler.InventoryReport).Methods("GET")
+
+	protected.HandleFunc("/validation-rules", validationHandler.GetRules).Methods("GET")
+
+	protected.HandleFunc("/brands", catalogueHandler.ListBrands).Methods("GET")
+	protected.HandleFunc("/brands/{id}", catalogueHandler.GetBrand).Methods("GET")
 
 	protected.HandleFunc("/engines/{id}", engineHandler.GetEngineByID).Methods("GET")
 	protected.HandleFunc("/engines", engineHandler.CreateEngine).Methods("POST")
 	protected.HandleFunc("/engines/{id}", engineHandler.UpdateEngine).Methods("PUT")
 	protected.HandleFunc("/engines/{id}", engineHandler.DeleteEngine).Methods("DELETE")
 
+	protected.HandleFunc("/auth/totp/enroll", loginHandler.EnrollTOTP).Methods("POST")
+	protected.HandleFunc("/auth/totp/confirm", loginHandler.ConfirmTOTP).Methods("POST")
+	protected.HandleFunc("/auth/totp", loginHandler.DisableTOTP).Methods("DELETE")
+
+	// partners are subscribed by the admins of their dealership
+	webhookRoutes := protected.PathPrefix("/webhooks").Subrouter()
+	webhookRoutes.Use(middleware.RequireRole(models.RoleAdmin))
+	webhookRoutes.HandleFunc("", webhookHandler.ListWebhooks).Methods("GET")
+	webhookRoutes.HandleFunc("", webhookHandler.CreateWebhook).Methods("POST")
+	webhookRoutes.HandleFunc("/{id}", webhookHandler.GetWebhook).Methods("GET")
+	webhookRoutes.HandleFunc("/{id}", webhookHandler.DeleteWebhook).Methods("DELETE")
+	webhookRoutes.HandleFunc("/{id}/deliveries", webhookHandler.ListDeliveries).Methods("GET")
+	webhookRoutes.HandleFunc("/{id}/deliveries/{deliveryId}", webhookHandler.GetDelivery).Methods("GET")
+	webhookRoutes.HandleFunc("/{id}/deliveries/{deliveryId}/redeliver", webhookHandler.Redeliver).Methods("POST")
+
+	admin := protected.PathPrefix("/admin").Subrouter()
+	admin.Use(middleware.RequireRole(models.RoleAdmin))
+	admin.HandleFunc("/users/{username}/unlock", loginHandler.Unlock).Methods("POST")
+	admin.HandleFunc("/validation-rules", validationHandler.SetRules).Methods("PUT")
+
+	// dealerships are managed by the operator, the admins of the default one
+	dealerships := admin.PathPrefix("/dealerships").Subrouter()
+	dealerships.Use(middleware.RequireTenant(tenant.DefaultID))
+	dealerships.HandleFunc("", dealershipHandler.GetDealerships).Methods("GET")
+	dealerships.HandleFunc("", dealershipHandler.CreateDealership).Methods("POST")
+	dealerships.HandleFunc("/{id}", dealershipHandler.GetDealershipById).Methods("GET")
+
+	// exchange rates are shared by all dealerships
+	rates := admin.PathPrefix("/rates").Subrouter()
+	rates.Use(middleware.RequireTenant(tenant.DefaultID))
+	rates.HandleFunc("/{currency}", currencyHandler.SetRate).Methods("PUT")
+
+	// so is the brand catalogue
+	brands := admin.PathPrefix("/brands").Subrouter()
+	brands.Use(middleware.RequireTenant(tenant.DefaultID))
+	brands.HandleFunc("", catalogueHandler.CreateBrand).Methods("POST")
+	brands.HandleFunc("/{id}", catalogueHandler.UpdateBrand).Methods("PUT")
+	brands.HandleFunc("/{id}", catalogueHandler.DeleteBrand).Methods("DELETE")
+	brands.HandleFunc("/{id}/models", catalogueHandler.CreateModel).Methods("POST")
+	brands.HandleFunc("/{id}/models/{modelId}", catalogueHandler.UpdateModel).Methods("PUT")
+	brands.HandleFunc("/{id}/models/{modelId}", catalogueHandler.DeleteModel).Methods("DELETE")
+
 	router.Handle("/metrics", promhttp.Handler())
+	// image URLs go straight into <img> tags, so they need no token
+	router.HandleFunc("/media/{key:.+}", mediaHandler.ServeMedia).Methods("GET")
 
 	// Port
 	port := os.Getenv("PORT")
@@ -113,6 +382,571 @@ func main() {
 	log.Fatal(http.ListenAndServe(addr, router))
 }
 
+// stores holds the store implementations chosen for the configured backend.
+type stores struct {
+	car    store.CarStoreInterface
+	engine store.EngineStoreInterface
+	login  store.LoginStoreInterface
+	totp   store.TOTPStoreInterface
+
+	dealership store.DealershipStoreInterface
+	vehicle    store.VehicleStoreInterface
+
+	reservation store.ReservationStoreInterface
+	order       store.OrderStoreInterface
+	customer    store.CustomerStoreInterface
+	rate        store.RateStoreInterface
+	price       store.PriceStoreInterface
+	media       store.CarImageStoreInterface
+	catalogue   store.CatalogueStoreInterface
+	rule        store.ValidationRuleStoreInterface
+	report      store.ReportStoreInterface
+	outbox      store.OutboxStoreInterface
+	webhook     store.WebhookStoreInterface
+}
+
+// initStores picks the store backend from DB_DRIVER. "postgres" is the
+// default, "sqlite" uses a single database file for one-box deployments and
+// "memory" keeps everything in process and needs no database, lockouts and
+// TOTP enrollments included.
+func initStores() (*stores, error) {
+	switch os.Getenv("DB_DRIVER") {
+	case "", "postgres":
+		// Connect database
+		if err := driver.InitDB(); err != nil {
+			return nil, fmt.Errorf("Unable to initialize the database connection: %v", err)
+		}
+
+		db := driver.GetDB()
+		if db == nil {
+			return nil, fmt.Errorf("database connection is nil, unable to continue")
+		}
+
+		// excute schema
+		schemaFile := "store/schema.sql"
+		if err := executeSchemaFile(db, schemaFile); err != nil {
+			return nil, fmt.Errorf("Cannot excute file schema: %v", err)
+		}
+
+		return &stores{
+			car:    carStore.New(db),
+			engine: engineStore.New(db),
+			login:  loginStore.New(db),
+			totp:   totpStore.New(db),
+
+			dealership: dealershipStore.New(db),
+			vehicle:    vehicleStore.New(db),
+
+			reservation: reservationStore.New(db),
+			order:       orderStore.New(db),
+			customer:    customerStore.New(db),
+			rate:        rateStore.New(db),
+			price:       priceStore.New(db),
+			media:       mediaStore.New(db),
+			catalogue:   catalogueStore.New(db),
+			rule:        ruleStore.New(db),
+			report:      reportStore.New(db),
+			outbox:      outboxStore.New(db),
+			webhook:     webhookStore.New(db),
+		}, nil
+	case "sqlite":
+		if err := driver.InitSQLiteDB(); err != nil {
+			return nil, fmt.Errorf("Unable to initialize the SQLite database: %v", err)
+		}
+
+		db := driver.GetDB()
+		if err := sqliteStore.Migrate(context.Background(), db); err != nil {
+			return nil, fmt.Errorf("Cannot migrate SQLite database: %v", err)
+		}
+
+		liteStore := sqliteStore.New(db)
+		return &stores{
+			car:    liteStore,
+			engine: liteStore,
+			login:  sqliteStore.NewLoginStore(db),
+			totp:   liteStore,
+
+			dealership: liteStore,
+			vehicle:    liteStore,
+
+			reservation: liteStore,
+			order:       liteStore,
+			customer:    liteStore,
+			rate:        liteStore,
+			price:       liteStore,
+			media:       liteStore,
+			catalogue:   liteStore,
+			rule:        liteStore,
+			report:      liteStore,
+			outbox:      liteStore,
+			webhook:     liteStore,
+		}, nil
+	case "memory":
+		log.Println("Using in-memory store, data will be lost on restart")
+		memStore := memoryStore.New()
+		return &stores{
+			car:    memStore,
+			engine: memStore,
+			login:  memoryStore.NewLoginStore(),
+			totp:   memoryStore.NewTOTPStore(),
+
+			dealership: memStore,
+			vehicle:    memStore,
+
+			reservation: memStore,
+			order:       memStore,
+			customer:    memStore,
+			rate:        memStore,
+			price:       memStore,
+			media:       memStore,
+			catalogue:   memStore,
+			rule:        memStore,
+			report:      memStore,
+			outbox:      memStore,
+			webhook:     memStore,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown DB_DRIVER %q", os.Getenv("DB_DRIVER"))
+	}
+}
+
+// initOIDC sets up single sign-on when OIDC_ISSUER is set. OIDC_ROLE_MAP
+// lists "group=role" pairs, users in none of the groups cannot log in.
+func initOIDC() (service.OIDCServiceInterface, error) {
+	issuer := os.Getenv("OIDC_ISSUER")
+	if issuer == "" {
+		return nil, nil
+	}
+
+	config := oidc.Config{
+		Issuer:       issuer,
+		ClientID:     os.Getenv("OIDC_CLIENT_ID"),
+		ClientSecret: os.Getenv("OIDC_CLIENT_SECRET"),
+		RedirectURL:  os.Getenv("OIDC_REDIRECT_URL"),
+		GroupsClaim:  os.Getenv("OIDC_GROUPS_CLAIM"),
+	}
+	if scopes := os.Getenv("OIDC_SCOPES"); scopes != "" {
+		config.Scopes = strings.Fields(scopes)
+	}
+	if config.ClientID == "" || config.RedirectURL == "" {
+		return nil, fmt.Errorf("OIDC_CLIENT_ID and OIDC_REDIRECT_URL are required with OIDC_ISSUER")
+	}
+
+	roles, err := loginService.ParseRoleMapping(os.Getenv("OIDC_ROLE_MAP"))
+	if err != nil {
+		return nil, err
+	}
+	if len(roles) == 0 {
+		log.Println("OIDC_ROLE_MAP is empty, nobody can log in with OIDC")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	provider, err := oidc.Discover(ctx, config, &http.Client{Timeout: 10 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+	tenantID := os.Getenv("OIDC_TENANT")
+	if tenantID == "" {
+		tenantID = tenant.DefaultID
+	}
+	return loginService.NewOIDCService(provider, roles, tenantID), nil
+}
+
+// loginConfig reads the lockout policy, LOGIN_MAX_FAILURES and
+// LOGIN_MAX_IP_FAILURES failures lock for LOGIN_LOCKOUT_BASE, doubling on
+// every repeat up to LOGIN_LOCKOUT_MAX.
+func loginConfig() (loginService.Config, error) {
+	config := loginService.DefaultConfig()
+
+	for env, target := range map[string]*int{
+		"LOGIN_MAX_FAILURES":    &config.MaxUserFailures,
+		"LOGIN_MAX_IP_FAILURES": &config.MaxIPFailures,
+	} {
+		if value := os.Getenv(env); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil {
+				return config, fmt.Errorf("invalid %s %q: %v", env, value, err)
+			}
+			*target = parsed
+		}
+	}
+
+	for env, target := range map[string]*time.Duration{
+		"LOGIN_LOCKOUT_BASE": &config.BaseLockout,
+		"LOGIN_LOCKOUT_MAX":  &config.MaxLockout,
+	} {
+		if value := os.Getenv(env); value != "" {
+			parsed, err := time.ParseDuration(value)
+			if err != nil {
+				return config, fmt.Errorf("invalid %s %q: %v", env, value, err)
+			}
+			*target = parsed
+		}
+	}
+	return config, nil
+}
+
+// pricingConfig reads how often scheduled prices are applied,
+// PRICE_SCHEDULE_INTERVAL (default 1m).
+func pricingConfig() (pricingService.Config, error) {
+	config := pricingService.DefaultConfig()
+
+	if value := os.Getenv("PRICE_SCHEDULE_INTERVAL"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed <= 0 {
+			return config, fmt.Errorf("invalid PRICE_SCHEDULE_INTERVAL %q", value)
+		}
+		config.ScheduleInterval = parsed
+	}
+	return config, nil
+}
+
+// initEvents sets up the relay of the car and engine events recorded in
+// the outbox. Each of OUTBOX_WEBHOOK_URL, OUTBOX_NATS_URL and
+// OUTBOX_KAFKA_REST_URL adds a sink; with none the events are only kept.
+// The relay polls every OUTBOX_POLL_INTERVAL (default 1s) for up to
+// OUTBOX_BATCH_SIZE events (default 100) and keeps published events for
+// OUTBOX_RETENTION (default 168h).
+func initEvents() (outboxService.Config, []events.Sink, error) {
+	config := outboxService.DefaultConfig()
+	if value := os.Getenv("OUTBOX_POLL_INTERVAL"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed <= 0 {
+			return config, nil, fmt.Errorf("invalid OUTBOX_POLL_INTERVAL %q", value)
+		}
+		config.PollInterval = parsed
+	}
+	if value := os.Getenv("OUTBOX_BATCH_SIZE"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			return config, nil, fmt.Errorf("invalid OUTBOX_BATCH_SIZE %q", value)
+		}
+		config.BatchSize = parsed
+	}
+	if value := os.Getenv("OUTBOX_RETENTION"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed <= 0 {
+			return config, nil, fmt.Errorf("invalid OUTBOX_RETENTION %q", value)
+		}
+		config.Retention = parsed
+	}
+
+	var sinks []events.Sink
+	if target := os.Getenv("OUTBOX_WEBHOOK_URL"); target != "" {
+		sink, err := events.NewWebhookSink(target)
+		if err != nil {
+			return config, nil, err
+		}
+		sinks = append(sinks, sink)
+	}
+	if target := os.Getenv("OUTBOX_NATS_URL"); target != "" {
+		jetStream, _ := strconv.ParseBool(os.Getenv("OUTBOX_NATS_JETSTREAM"))
+		sink, err := events.NewNATSSink(events.NATSConfig{
+			URL:       target,
+			Subject:   os.Getenv("OUTBOX_NATS_SUBJECT"),
+			JetStream: jetStream,
+		})
+		if err != nil {
+			return config, nil, err
+		}
+		sinks = append(sinks, sink)
+	}
+	if target := os.Getenv("OUTBOX_KAFKA_REST_URL"); target != "" {
+		topic := os.Getenv("OUTBOX_KAFKA_TOPIC")
+		if topic == "" {
+			topic = "carzone.events"
+		}
+		sink, err := events.NewKafkaSink(events.KafkaConfig{URL: target, Topic: topic})
+		if err != nil {
+			return config, nil, err
+		}
+		sinks = append(sinks, sink)
+	}
+	for _, sink := range sinks {
+		log.Printf("Publishing car and engine events to %s", sink.Name())
+	}
+	return config, sinks, nil
+}
+
+// webhookConfig reads how partner webhooks are delivered: a failed attempt
+// is retried after WEBHOOK_BACKOFF_BASE (default 10s), doubling up to
+// WEBHOOK_BACKOFF_MAX (default 1h), for WEBHOOK_MAX_ATTEMPTS attempts
+// (default 10) of WEBHOOK_TIMEOUT each (default 10s). Due deliveries are
+// looked for every WEBHOOK_POLL_INTERVAL (default 1s) and finished ones are
+// kept for WEBHOOK_RETENTION (default 720h).
+func webhookConfig() (webhookService.Config, error) {
+	config := webhookService.DefaultConfig()
+
+	if value := os.Getenv("WEBHOOK_MAX_ATTEMPTS"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			return config, fmt.Errorf("invalid WEBHOOK_MAX_ATTEMPTS %q", value)
+		}
+		config.MaxAttempts = parsed
+	}
+
+	for env, target := range map[string]*time.Duration{
+		"WEBHOOK_BACKOFF_BASE":  &config.BaseBackoff,
+		"WEBHOOK_BACKOFF_MAX":   &config.MaxBackoff,
+		"WEBHOOK_TIMEOUT":       &config.Timeout,
+		"WEBHOOK_POLL_INTERVAL": &config.PollInterval,
+		"WEBHOOK_RETENTION":     &config.Retention,
+	} {
+		if value := os.Getenv(env); value != "" {
+			parsed, err := time.ParseDuration(value)
+			if err != nil || parsed <= 0 {
+				return config, fmt.Errorf("invalid %s %q", env, value)
+			}
+			*target = parsed
+		}
+	}
+	if config.MaxBackoff < config.BaseBackoff {
+		return config, fmt.Errorf("WEBHOOK_BACKOFF_MAX must not be below WEBHOOK_BACKOFF_BASE")
+	}
+	return config, nil
+}
+
+// initMedia picks where uploaded images are kept from MEDIA_BACKEND:
+// "local" (default) for files below MEDIA_DIR, "s3" for the S3_BUCKET of
+// an S3-compatible service at S3_ENDPOINT. Uploads are limited to
+// MEDIA_MAX_BYTES (default 10 MiB), images are linked below
+// MEDIA_PUBLIC_URL (default /media/, served by the API).
+func initMedia() (mediaService.Config, blob.Store, error) {
+	config := mediaService.DefaultConfig()
+	if value := os.Getenv("MEDIA_MAX_BYTES"); value != "" {
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || parsed <= 0 {
+			return config, nil, fmt.Errorf("invalid MEDIA_MAX_BYTES %q", value)
+		}
+		config.MaxBytes = parsed
+	}
+	if value := os.Getenv("MEDIA_PUBLIC_URL"); value != "" {
+		config.PublicURL = strings.TrimSuffix(value, "/") + "/"
+	}
+
+	switch os.Getenv("MEDIA_BACKEND") {
+	case "", "local":
+		dir := os.Getenv("MEDIA_DIR")
+		if dir == "" {
+			dir = "media"
+		}
+		blobs, err := blob.NewFileStore(dir)
+		if err != nil {
+			return config, nil, err
+		}
+		log.Printf("Keeping uploaded images in %s", dir)
+		return config, blobs, nil
+	case "s3":
+		pathStyle, _ := strconv.ParseBool(os.Getenv("S3_PATH_STYLE"))
+		blobs, err := blob.NewS3Store(blob.S3Config{
+			Endpoint:        os.Getenv("S3_ENDPOINT"),
+			Region:          os.Getenv("S3_REGION"),
+			Bucket:          os.Getenv("S3_BUCKET"),
+			AccessKeyID:     os.Getenv("S3_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("S3_SECRET_ACCESS_KEY"),
+			PathStyle:       pathStyle,
+		})
+		if err != nil {
+			return config, nil, err
+		}
+		log.Printf("Keeping uploaded images in S3 bucket %s", os.Getenv("S3_BUCKET"))
+		return config, blobs, nil
+	default:
+		return config, nil, fmt.Errorf("unknown MEDIA_BACKEND %q", os.Getenv("MEDIA_BACKEND"))
+	}
+}
+
+// currencyConfig reads CURRENCY_BASE, the currency exchange rates are
+// quoted against (default USD).
+func currencyConfig() (string, error) {
+	base := strings.ToUpper(os.Getenv("CURRENCY_BASE"))
+	if base == "" {
+		return money.DefaultCurrency, nil
+	}
+	if err := money.ValidateCurrency(base); err != nil {
+		return "", fmt.Errorf("invalid CURRENCY_BASE: %v", err)
+	}
+	return base, nil
+}
+
+// validationRules reads the default validation rules of every dealership
+// from the JSON file VALIDATION_RULES, there are none when it is not set.
+func validationRules() ([]models.ValidationRule, error) {
+	path := os.Getenv("VALIDATION_RULES")
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	rules, err := validationService.ParseRules(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	log.Printf("Loaded %d validation rules from %s", len(rules), path)
+	return rules, nil
+}
+
+// reservationConfig reads how long cars are held: RESERVATION_HOLD when the
+// request does not say (default 48h), at most RESERVATION_MAX_HOLD (default
+// 168h), expired holds swept every RESERVATION_SWEEP_INTERVAL (default 1m).
+func reservationConfig() (reservationService.Config, error) {
+	config := reservationService.DefaultConfig()
+
+	for env, target := range map[string]*time.Duration{
+		"RESERVATION_HOLD":           &config.DefaultHold,
+		"RESERVATION_MAX_HOLD":       &config.MaxHold,
+		"RESERVATION_SWEEP_INTERVAL": &config.SweepInterval,
+	} {
+		if value := os.Getenv(env); value != "" {
+			parsed, err := time.ParseDuration(value)
+			if err != nil || parsed <= 0 {
+				return config, fmt.Errorf("invalid %s %q", env, value)
+			}
+			*target = parsed
+		}
+	}
+	return config, nil
+}
+
+// initCache builds the service cache from CACHE_BACKEND: "lru" for an
+// in-process cache, "redis" for a shared one at REDIS_ADDR, empty or "none"
+// to disable caching. Entries live for CACHE_TTL (default 5m).
+func initCache() (cache.Cache, time.Duration, error) {
+	ttl := 5 * time.Minute
+	if value := os.Getenv("CACHE_TTL"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			return nil, 0, fmt.Errorf("invalid CACHE_TTL %q: %v", value, err)
+		}
+		ttl = parsed
+	}
+
+	switch os.Getenv("CACHE_BACKEND") {
+	case "", "none":
+		return nil, ttl, nil
+	case "lru":
+		size := 10000
+		if value := os.Getenv("CACHE_SIZE"); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, 0, fmt.Errorf("invalid CACHE_SIZE %q: %v", value, err)
+			}
+			size = parsed
+		}
+		log.Printf("Using in-process LRU cache (size %d, ttl %s)", size, ttl)
+		return cache.NewLRU(size), ttl, nil
+	case "redis":
+		client, err := initRedis()
+		if err != nil {
+			return nil, 0, err
+		}
+		log.Printf("Using Redis cache at %s (ttl %s)", client.Options().Addr, ttl)
+		return cache.NewRedis(client, "carzone:"), ttl, nil
+	default:
+		return nil, 0, fmt.Errorf("unknown CACHE_BACKEND %q", os.Getenv("CACHE_BACKEND"))
+	}
+}
+
+var redisClient *redis.Client
+
+// initRedis connects to the Redis-compatible server at REDIS_ADDR. The
+// client is shared by every component that needs Redis.
+func initRedis() (*redis.Client, error) {
+	if redisClient != nil {
+		return redisClient, nil
+	}
+
+	addr := os.Getenv("REDIS_ADDR")
+	if addr == "" {
+		addr = "localhost:6379"
+	}
+	dbIndex := 0
+	if value := os.Getenv("REDIS_DB"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid REDIS_DB %q: %v", value, err)
+		}
+		dbIndex = parsed
+	}
+
+	client := redis.NewClient(&redis.Options{
+		Addr:     addr,
+		Password: os.Getenv("REDIS_PASSWORD"),
+		DB:       dbIndex,
+	})
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		return nil, fmt.Errorf("cannot reach Redis at %s: %v", addr, err)
+	}
+	redisClient = client
+	return client, nil
+}
+
+// initRateLimitStore picks where token buckets live from RATE_LIMIT_STORE:
+// "memory" (default) per replica, "redis" or "postgres" to share them
+// between replicas.
+func initRateLimitStore() (ratelimit.Store, error) {
+	switch os.Getenv("RATE_LIMIT_STORE") {
+	case "", "memory":
+		return ratelimit.NewMemoryStore(), nil
+	case "redis":
+		client, err := initRedis()
+		if err != nil {
+			return nil, err
+		}
+		return ratelimit.NewRedisStore(client, "carzone:ratelimit:"), nil
+	case "postgres":
+		db := driver.GetDB()
+		if db == nil || !usesPostgres() {
+			return nil, fmt.Errorf("RATE_LIMIT_STORE=postgres needs DB_DRIVER=postgres")
+		}
+		return ratelimit.NewPostgresStore(db), nil
+	default:
+		return nil, fmt.Errorf("unknown RATE_LIMIT_STORE %q", os.Getenv("RATE_LIMIT_STORE"))
+	}
+}
+
+// rateLimitPolicy reads a route group's limit ("5/m", or "off") and the
+// client key ("ip" or "user") from the environment. It returns
+// nil when the group is not limited.
+func rateLimitPolicy(name, limitEnv, defaultLimit, keyEnv, defaultKey string) (*middleware.RateLimitPolicy, error) {
+	value := os.Getenv(limitEnv)
+	if value == "" {
+		value = defaultLimit
+	}
+	if value == "off" {
+		return nil, nil
+	}
+	limit, err := ratelimit.ParseLimit(value)
+	if err != nil {
+		return nil, err
+	}
+
+	keyName := os.Getenv(keyEnv)
+	if keyName == "" {
+		keyName = defaultKey
+	}
+	var key middleware.RateLimitKeyFunc
+	switch keyName {
+	case "ip":
+		key = middleware.RateLimitByIP
+	case "user":
+		key = middleware.RateLimitByUser
+	default:
+		return nil, fmt.Errorf("unknown %s %q, use ip or user", keyEnv, keyName)
+	}
+
+	return &middleware.RateLimitPolicy{Name: name, Limit: limit, Key: key}, nil
+}
+
+// usesPostgres reports whether DB_DRIVER selects the Postgres backend.
+func usesPostgres() bool {
+	dbDriver := os.Getenv("DB_DRIVER")
+	return dbDriver == "" || dbDriver == "postgres"
+}
+
 func executeSchemaFile(db *sql.DB, fileName string) error {
 	if db == nil {
 		return fmt.Errorf("database connection is nil")
@@ -5,15 +5,14 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/adohong4/carZone/helpers"
+	"github.com/adohong4/carZone/tenant"
 	"github.com/golang-jwt/jwt/v4"
 )
 
 var jwtKey = []byte("some_value")
 
-type Claims struct {
-	UserName string `json:username`
-	jwt.StandardClaims
-}
+type Claims = helpers.Claims
 
 func AuthMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -23,7 +22,7 @@ func AuthMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
-		tokenString := strings.TrimPrefix(authHeader, "Bearer")
+		tokenString := strings.TrimSpace(strings.TrimPrefix(authHeader, "Bearer"))
 
 		claims := &Claims{}
 
@@ -32,12 +31,27 @@ func AuthMiddleware(next http.Handler) http.Handler {
 			return jwtKey, nil
 		})
 
-		if err != nil || !token.Valid {
+		// a 2FA challenge token only proves the password step
+		if err != nil || !token.Valid || claims.Purpose != "" {
 			http.Error(w, "Invalid Token", http.StatusUnauthorized)
 			return
 		}
 
-		ctx := context.WithValue(r.Context(), "username", claims.UserName)
+		// older tokens only carry the username in the subject
+		username := claims.UserName
+		if username == "" {
+			username = claims.Subject
+		}
+
+		// tokens from before dealerships act for the default one
+		tenantID := claims.TenantID
+		if tenantID == "" {
+			tenantID = tenant.DefaultID
+		}
+
+		ctx := context.WithValue(r.Context(), "username", username)
+		ctx = context.WithValue(ctx, "role", claims.Role)
+		ctx = tenant.WithTenant(ctx, tenantID)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
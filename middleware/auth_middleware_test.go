@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/adohong4/carZone/helpers"
+	"github.com/adohong4/carZone/models"
+	"github.com/adohong4/carZone/tenant"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func serveWithToken(t *testing.T, token string) (int, string) {
+	var tenantID string
+	handler := AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tenantID, _ = tenant.FromContext(r.Context())
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/cars", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec.Code, tenantID
+}
+
+func TestAuthMiddlewareResolvesTenant(t *testing.T) {
+	const dealership = "00000000-0000-0000-0000-000000000002"
+
+	token, err := helpers.GenerateToken(models.User{UserName: "jane", Role: models.RoleStaff, TenantID: dealership})
+	require.NoError(t, err)
+	code, tenantID := serveWithToken(t, token)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, dealership, tenantID)
+
+	// tokens issued before dealerships act for the default one
+	token, err = helpers.GenerateToken(models.User{UserName: "jane", Role: models.RoleStaff})
+	require.NoError(t, err)
+	code, tenantID = serveWithToken(t, token)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, tenant.DefaultID, tenantID)
+}
+
+func TestAuthMiddlewareRejectsChallengeToken(t *testing.T) {
+	token, err := helpers.GenerateChallengeToken(models.User{UserName: "jane", Role: models.RoleStaff})
+	require.NoError(t, err)
+	code, _ := serveWithToken(t, token)
+	assert.Equal(t, http.StatusUnauthorized, code)
+}
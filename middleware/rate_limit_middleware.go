@@ -0,0 +1,85 @@
+package middleware
+
+import (
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/adohong4/carZone/core"
+	"github.com/adohong4/carZone/helpers"
+	"github.com/adohong4/carZone/ratelimit"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var rateLimitedCounter = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "http_rate_limited_total",
+		Help: "Total number of requests rejected by the rate limiter",
+	},
+	[]string{"policy"},
+)
+
+func init() {
+	prometheus.MustRegister(rateLimitedCounter)
+}
+
+// RateLimitKeyFunc identifies the client a request is charged to.
+type RateLimitKeyFunc func(r *http.Request) string
+
+// RateLimitByIP charges the client address.
+func RateLimitByIP(r *http.Request) string {
+	return "ip:" + helpers.ClientIP(r)
+}
+
+// RateLimitByUser charges the username AuthMiddleware put in the context,
+// falling back to the client address for anonymous requests.
+func RateLimitByUser(r *http.Request) string {
+	if username, ok := r.Context().Value("username").(string); ok && username != "" {
+		return "user:" + username
+	}
+	return RateLimitByIP(r)
+}
+
+// RateLimitPolicy is the limit applied to one route group.
+type RateLimitPolicy struct {
+	Name  string
+	Limit ratelimit.Limit
+	Key   RateLimitKeyFunc
+}
+
+// RateLimitMiddleware rejects requests over the policy's budget with 429
+// and advertises the budget with RateLimit-* headers. If the store fails
+// the request is let through, an unavailable limiter must not take the
+// API down.
+func RateLimitMiddleware(store ratelimit.Store, policy RateLimitPolicy) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := policy.Name + ":" + policy.Key(r)
+
+			result, err := store.Take(r.Context(), key, policy.Limit)
+			if err != nil {
+				log.Printf("Rate limit store error for %s: %v", key, err)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set("RateLimit-Limit", strconv.Itoa(policy.Limit.Burst))
+			w.Header().Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+			w.Header().Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+
+			if !result.Allowed {
+				rateLimitedCounter.WithLabelValues(policy.Name).Inc()
+				w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+				core.SendErrorResponse(w, core.NewTooManyRequestsError().ErrorResponse)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/adohong4/carZone/ratelimit"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimitMiddleware(t *testing.T) {
+	policy := RateLimitPolicy{Name: "login", Limit: ratelimit.Limit{Rate: 1.0 / 60, Burst: 2}, Key: RateLimitByIP}
+	handler := RateLimitMiddleware(ratelimit.NewMemoryStore(), policy)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	send := func(remoteAddr string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/login", nil)
+		r.RemoteAddr = remoteAddr
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	first := send("10.0.0.1:1234")
+	assert.Equal(t, http.StatusOK, first.Code)
+	assert.Equal(t, "2", first.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "1", first.Header().Get("RateLimit-Remaining"))
+
+	assert.Equal(t, http.StatusOK, send("10.0.0.1:5678").Code)
+
+	limited := send("10.0.0.1:1234")
+	assert.Equal(t, http.StatusTooManyRequests, limited.Code)
+	assert.Equal(t, "60", limited.Header().Get("Retry-After"))
+	assert.Equal(t, "0", limited.Header().Get("RateLimit-Remaining"))
+
+	assert.Equal(t, http.StatusOK, send("10.0.0.2:1234").Code, "other clients keep their own budget")
+}
+
+func TestRateLimitByUserIgnoresClientChosenHeaders(t *testing.T) {
+	policy := RateLimitPolicy{Name: "api", Limit: ratelimit.Limit{Rate: 1.0 / 60, Burst: 1}, Key: RateLimitByUser}
+	handler := RateLimitMiddleware(ratelimit.NewMemoryStore(), policy)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	send := func(apiKey string) int {
+		r := httptest.NewRequest(http.MethodGet, "/cars", nil)
+		r.RemoteAddr = "10.0.0.1:1234"
+		r.Header.Set("X-API-Key", apiKey)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, send("first"))
+	// a new made-up key does not buy a new budget
+	assert.Equal(t, http.StatusTooManyRequests, send("second"))
+}
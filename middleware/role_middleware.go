@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/adohong4/carZone/core"
+	"github.com/adohong4/carZone/tenant"
+)
+
+// RequireRole only lets through requests whose token carries one of roles.
+// It must run after AuthMiddleware.
+func RequireRole(roles ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			role, _ := r.Context().Value("role").(string)
+			for _, allowed := range roles {
+				if role == allowed {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+			core.SendErrorResponse(w, core.NewForbiddenError("Insufficient role").ErrorResponse)
+		})
+	}
+}
+
+// RequireTenant only lets through requests acting for one of the
+// dealerships tenantIDs. It must run after AuthMiddleware.
+func RequireTenant(tenantIDs ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			current, _ := tenant.FromContext(r.Context())
+			for _, allowed := range tenantIDs {
+				if current == allowed {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+			core.SendErrorResponse(w, core.NewForbiddenError("Not allowed for this dealership").ErrorResponse)
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Brand is a make in the catalogue shared by every dealership. Cars carry
+// its canonical Name, the Aliases are other spellings that resolve to it.
+type Brand struct {
+	ID        uuid.UUID  `json:"id"`
+	Name      string     `json:"name"`
+	Aliases   []string   `json:"aliases"`
+	Models    []CarModel `json:"models"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+type BrandRequest struct {
+	Name    string   `json:"name"`
+	Aliases []string `json:"aliases"`
+}
+
+// CarModel is a model of a brand, the canonical Name is used as the name of
+// the cars of that model.
+type CarModel struct {
+	ID        uuid.UUID `json:"id"`
+	BrandID   uuid.UUID `json:"brand_id"`
+	Name      string    `json:"name"`
+	Aliases   []string  `json:"aliases"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type CarModelRequest struct {
+	Name    string   `json:"name"`
+	Aliases []string `json:"aliases"`
+}
+
+// CatalogueKey is what brand and model names and aliases are matched on,
+// so "Toyota", "toyota" and "TOYOTA " are the same brand.
+func CatalogueKey(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// Model returns the model of the brand whose name or alias matches name.
+func (b Brand) Model(name string) (CarModel, bool) {
+	key := CatalogueKey(name)
+	for _, model := range b.Models {
+		if CatalogueKey(model.Name) == key {
+			return model, true
+		}
+		for _, alias := range model.Aliases {
+			if CatalogueKey(alias) == key {
+				return model, true
+			}
+		}
+	}
+	return CarModel{}, false
+}
+
+func ValidateBrandRequest(brandReq BrandRequest) error {
+	return validateCatalogueNames("Brand", brandReq.Name, brandReq.Aliases)
+}
+
+func ValidateCarModelRequest(modelReq CarModelRequest) error {
+	return validateCatalogueNames("Model", modelReq.Name, modelReq.Aliases)
+}
+
+// validateCatalogueNames checks a name and its aliases are given and that
+// no two of them share a key.
+func validateCatalogueNames(kind, name string, aliases []string) error {
+	if strings.TrimSpace(name) == "" {
+		return errors.New(kind + " name is Required")
+	}
+	if len(name) > 100 {
+		return errors.New(kind + " name must be at most 100 characters")
+	}
+	keys := map[string]bool{CatalogueKey(name): true}
+	for _, alias := range aliases {
+		if strings.TrimSpace(alias) == "" {
+			return errors.New("Aliases cannot be empty")
+		}
+		if len(alias) > 100 {
+			return errors.New("Aliases must be at most 100 characters")
+		}
+		if keys[CatalogueKey(alias)] {
+			return errors.New("Alias " + alias + " repeats the name or another alias")
+		}
+		keys[CatalogueKey(alias)] = true
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CarImage is a photo of a car. The blobs are kept under Key and
+// ThumbnailKey, URL and ThumbnailURL are where clients fetch them.
+type CarImage struct {
+	ID           uuid.UUID `json:"id"`
+	CarID        uuid.UUID `json:"car_id"`
+	ContentType  string    `json:"content_type"`
+	Size         int64     `json:"size"`
+	Width        int       `json:"width"`
+	Height       int       `json:"height"`
+	Position     int       `json:"position"`
+	Primary      bool      `json:"primary"`
+	Key          string    `json:"-"`
+	ThumbnailKey string    `json:"-"`
+	URL          string    `json:"url"`
+	ThumbnailURL string    `json:"thumbnail_url"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// CarImageOrderRequest lists every image of a car in the order to show
+// them.
+type CarImageOrderRequest struct {
+	ImageIDs []uuid.UUID `json:"image_ids"`
+}
+
+func ValidateCarImageOrderRequest(orderReq CarImageOrderRequest) error {
+	if len(orderReq.ImageIDs) == 0 {
+		return errors.New("Image IDs are Required")
+	}
+	seen := make(map[uuid.UUID]bool, len(orderReq.ImageIDs))
+	for _, id := range orderReq.ImageIDs {
+		if seen[id] {
+			return errors.New("Image IDs must not repeat")
+		}
+		seen[id] = true
+	}
+	return nil
+}
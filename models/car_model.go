@@ -5,52 +5,108 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/adohong4/carZone/money"
 	"github.com/google/uuid"
 )
 
 type Car struct {
-	ID        uuid.UUID `json:"id"`
-	Name      string    `json:"name"`
-	Year      string    `json:"year"`
-	Brand     string    `json:"brand"`
-	FuelType  string    `json:"fuel_type"`
-	Engine    Engine    `json:"engine"`
-	Price     float64   `json:"price"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at`
+	ID        uuid.UUID   `json:"id"`
+	Name      string      `json:"name"`
+	Year      string      `json:"year"`
+	Brand     string      `json:"brand"`
+	FuelType  string      `json:"fuel_type"`
+	Engine    Engine      `json:"engine"`
+	Price     money.Money `json:"price"`
+	Spec      *CarSpec    `json:"spec,omitempty"`
+	CreatedAt time.Time   `json:"created_at"`
+	UpdatedAt time.Time   `json:"updated_at"`
+	// OriginalPrice is the price in the car's own currency when Price was
+	// converted to a currency the client asked for.
+	OriginalPrice *money.Money `json:"original_price,omitempty"`
+	// ListPrice is the price before Promotion was taken off Price, it is
+	// only set while a promotion applies to the car.
+	ListPrice *money.Money `json:"list_price,omitempty"`
+	Promotion *Promotion   `json:"promotion,omitempty"`
+	// Reservation is the active hold on the car, if any. It is only filled
+	// in when a single car is fetched.
+	Reservation *Reservation `json:"reservation,omitempty"`
+	// Images are the photos of the car in display order.
+	Images []CarImage `json:"images,omitempty"`
 }
 
 type CarRequest struct {
-	Name     string  `json:"name"`
-	Year     string  `json:"year"`
-	Brand    string  `json:"brand"`
-	FuelType string  `json:"fuel_type"`
-	Engine   Engine  `json:"engine"`
-	Price    float64 `json:"price"`
+	Name     string      `json:"name"`
+	Year     string      `json:"year"`
+	Brand    string      `json:"brand"`
+	FuelType string      `json:"fuel_type"`
+	Engine   Engine      `json:"engine"`
+	Price    money.Money `json:"price"`
+	Spec     *CarSpec    `json:"spec"`
 }
 
-func ValidateRequest(carRequest CarRequest) error {
-	if err := validateName(carRequest.Name); err != nil {
-		return err
+// LastModified is the latest change among the parts of the car a response
+// shows: the car and its engine, its reservation, the promotion taken off
+// its price and its images. Removed parts leave no trace, only the ETag
+// notices those.
+func (c Car) LastModified() time.Time {
+	latest := c.UpdatedAt
+	later := func(t time.Time) {
+		if t.After(latest) {
+			latest = t
+		}
 	}
-	if err := validateYear(carRequest.Year); err != nil {
-		return err
+	later(c.Engine.UpdatedAt)
+	if c.Reservation != nil {
+		later(c.Reservation.UpdatedAt)
 	}
-	if err := validatedBrand(carRequest.Brand); err != nil {
-		return err
+	if c.Promotion != nil {
+		later(c.Promotion.CreatedAt)
+		later(c.Promotion.StartsAt)
 	}
-	if err := ValidateFuelType(carRequest.FuelType); err != nil {
-		return err
+	for _, image := range c.Images {
+		later(image.CreatedAt)
 	}
-	if err := valdateEngine(carRequest.Engine); err != nil {
-		return err
-	}
-	if err := validatePrice(carRequest.Price); err != nil {
-		return err
+	return latest
+}
+
+// ValidateRequest checks a car request. brand is the catalogue entry the
+// requested brand resolves to, nil when it is not in the catalogue.
+func ValidateRequest(carRequest CarRequest, brand *Brand) error {
+	if violations := RequestViolations(carRequest, brand); len(violations) > 0 {
+		return errors.New(violations[0].Message)
 	}
 	return nil
 }
 
+// RequestViolations runs the built-in checks of a car request and returns
+// every one it fails, in the order ValidateRequest makes them.
+func RequestViolations(carRequest CarRequest, brand *Brand) []Violation {
+	type check struct {
+		field string
+		err   error
+	}
+	checks := []check{
+		{"name", validateName(carRequest.Name)},
+		{"year", validateYear(carRequest.Year)},
+		{"brand", validatedBrand(carRequest.Brand, brand)},
+		{"fuel_type", ValidateFuelType(carRequest.FuelType)},
+		{"engine", valdateEngine(carRequest.Engine, carRequest.FuelType)},
+		{"price", validatePrice(carRequest.Price)},
+	}
+	if carRequest.Spec != nil {
+		checks = append(checks, check{"spec", validateSpec(*carRequest.Spec)})
+	}
+	checks = append(checks, check{"spec", validateFuelTypeSpec(carRequest.FuelType, carRequest.Spec)})
+
+	var violations []Violation
+	for _, c := range checks {
+		if c.err != nil {
+			violations = append(violations, Violation{Field: c.field, Rule: BuiltinRule, Message: c.err.Error()})
+		}
+	}
+	return violations
+}
+
 func validateName(name string) error {
 	if name == "" {
 		return errors.New("Name is Required")
@@ -67,18 +123,23 @@ func validateYear(year string) error {
 	if err != nil {
 		return errors.New("Year must be a valid number")
 	}
-	currentYear := time.Now().Year()
+	// models go on sale the year before their model year, as vin.ModelYear
+	// has it
+	nextYear := time.Now().Year() + 1
 	yearInt, _ := strconv.Atoi(year)
-	if yearInt < 1886 || yearInt > currentYear {
-		return errors.New("Year must be between 1886 and the current year")
+	if yearInt < 1886 || yearInt > nextYear {
+		return errors.New("Year must be between 1886 and next year")
 	}
 	return nil
 }
 
-func validatedBrand(brand string) error {
-	if brand == "" {
+func validatedBrand(name string, brand *Brand) error {
+	if name == "" {
 		return errors.New("Brand is Required")
 	}
+	if brand == nil {
+		return errors.New("Brand " + name + " is not in the catalogue")
+	}
 	return nil
 }
 
@@ -92,24 +153,23 @@ func ValidateFuelType(fuelType string) error {
 	return errors.New("FeulType must be one of Petrol, Diesel, Electric, or Hybrid")
 }
 
-func valdateEngine(engine Engine) error {
+// valdateEngine checks the powertrain of the car and that it suits the
+// fuel type.
+func valdateEngine(engine Engine, fuelType string) error {
 	if engine.EngineID == uuid.Nil {
 		return errors.New("Engine ID is Required")
 	}
-	if engine.Displacement <= 0 {
-		return errors.New("Displacement must be greater than 0")
-	}
-	if engine.NoOfCylinders <= 0 {
-		return errors.New("NoOfCylinders must be greater than 0")
-	}
-	if engine.CarRange <= 0 {
-		return errors.New("CarRange must be greater than 0")
+	if err := validatePowertrain(engine); err != nil {
+		return err
 	}
-	return nil
+	return validateFuelTypeEngine(fuelType, engine)
 }
 
-func validatePrice(price float64) error {
-	if price <= 0 {
+func validatePrice(price money.Money) error {
+	if err := money.ValidateCurrency(price.Currency); err != nil {
+		return errors.New("Currency must be an ISO 4217 code")
+	}
+	if price.Amount <= 0 {
 		return errors.New("Price must be greater than 0")
 	}
 	return nil
@@ -0,0 +1,99 @@
+package models
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/adohong4/carZone/decimal"
+)
+
+// The codes of the transmission, drivetrain and body_type reference tables.
+var (
+	Transmissions = []string{"manual", "automatic", "cvt", "dct"}
+	Drivetrains   = []string{"fwd", "rwd", "awd", "4wd"}
+	BodyTypes     = []string{"sedan", "hatchback", "suv", "coupe", "convertible", "wagon", "pickup", "van", "minivan"}
+)
+
+// CarSpec describes a car beyond its engine. Every field may be left out,
+// but the consumption and emissions given must suit the fuel type of the
+// car, see ValidateRequest.
+type CarSpec struct {
+	Transmission string `json:"transmission,omitempty"`
+	Drivetrain   string `json:"drivetrain,omitempty"`
+	BodyType     string `json:"body_type,omitempty"`
+	Seats        int    `json:"seats,omitempty"`
+	Doors        int    `json:"doors,omitempty"`
+	Colour       string `json:"colour,omitempty"`
+	Trim         string `json:"trim,omitempty"`
+	// FuelConsumption is in litres and EnergyConsumption in kWh per 100 km.
+	FuelConsumption   *decimal.Decimal `json:"fuel_consumption,omitempty"`
+	EnergyConsumption *decimal.Decimal `json:"energy_consumption,omitempty"`
+	// CO2Emissions is in grams per km.
+	CO2Emissions *int64 `json:"co2_emissions,omitempty"`
+}
+
+func oneOf(value string, values []string) bool {
+	for _, v := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
+
+func validateSpec(spec CarSpec) error {
+	if spec.Transmission != "" && !oneOf(spec.Transmission, Transmissions) {
+		return errors.New("Transmission must be one of " + strings.Join(Transmissions, ", "))
+	}
+	if spec.Drivetrain != "" && !oneOf(spec.Drivetrain, Drivetrains) {
+		return errors.New("Drivetrain must be one of " + strings.Join(Drivetrains, ", "))
+	}
+	if spec.BodyType != "" && !oneOf(spec.BodyType, BodyTypes) {
+		return errors.New("Body type must be one of " + strings.Join(BodyTypes, ", "))
+	}
+	if spec.Seats < 0 || spec.Seats > 15 {
+		return errors.New("Seats must be between 1 and 15")
+	}
+	if spec.Doors < 0 || spec.Doors > 6 {
+		return errors.New("Doors must be between 1 and 6")
+	}
+	if len(spec.Colour) > 50 {
+		return errors.New("Colour must be at most 50 characters")
+	}
+	if len(spec.Trim) > 100 {
+		return errors.New("Trim must be at most 100 characters")
+	}
+	if spec.FuelConsumption != nil && spec.FuelConsumption.Sign() <= 0 {
+		return errors.New("Fuel consumption must be greater than 0")
+	}
+	if spec.EnergyConsumption != nil && spec.EnergyConsumption.Sign() <= 0 {
+		return errors.New("Energy consumption must be greater than 0")
+	}
+	if spec.CO2Emissions != nil && *spec.CO2Emissions < 0 {
+		return errors.New("CO2 emissions cannot be negative")
+	}
+	return nil
+}
+
+// validateFuelTypeSpec checks the spec fits the fuel type: an electric car
+// uses no fuel and has no tailpipe, a petrol or diesel car uses no
+// electricity. The battery itself belongs to the engine.
+func validateFuelTypeSpec(fuelType string, spec *CarSpec) error {
+	if spec == nil {
+		return nil
+	}
+	switch fuelType {
+	case "Electric":
+		if spec.FuelConsumption != nil {
+			return errors.New("Electric cars have no fuel consumption, use energy consumption")
+		}
+		if spec.CO2Emissions != nil && *spec.CO2Emissions != 0 {
+			return errors.New("Electric cars must have zero CO2 emissions")
+		}
+	case "Petrol", "Diesel":
+		if spec.EnergyConsumption != nil {
+			return errors.New(fuelType + " cars have no energy consumption, use fuel consumption")
+		}
+	}
+	return nil
+}
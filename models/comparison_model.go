@@ -0,0 +1,172 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/adohong4/carZone/decimal"
+	"github.com/adohong4/carZone/money"
+	"github.com/google/uuid"
+)
+
+// How many cars can be compared at once.
+const (
+	MinComparedCars = 2
+	MaxComparedCars = 4
+)
+
+// ValidateComparedIDs checks between MinComparedCars and MaxComparedCars
+// distinct car IDs are given.
+func ValidateComparedIDs(ids []string) error {
+	if len(ids) < MinComparedCars || len(ids) > MaxComparedCars {
+		return fmt.Errorf("Compare %d to %d cars", MinComparedCars, MaxComparedCars)
+	}
+	seen := make(map[uuid.UUID]bool, len(ids))
+	for _, id := range ids {
+		carID, err := uuid.Parse(id)
+		if err != nil {
+			return errors.New("Invalid car ID " + id)
+		}
+		if seen[carID] {
+			return errors.New("Car " + id + " is given twice")
+		}
+		seen[carID] = true
+	}
+	return nil
+}
+
+// numericFields are the comparison rows that get differences.
+var numericFields = map[string]bool{
+	"year": true, "price": true,
+	"spec.seats": true, "spec.doors": true,
+	"spec.fuel_consumption": true, "spec.energy_consumption": true, "spec.co2_emissions": true,
+	"engine.displacement": true, "engine.noOfCylinders": true, "engine.carRange": true,
+	"engine.motorPower": true, "engine.batteryCapacity": true,
+}
+
+// bestInClass says for the marked rows whether the lowest or the highest
+// value is the best one.
+var bestInClass = map[string]bool{
+	"price":               false,
+	"engine.carRange":     true,
+	"engine.displacement": true,
+}
+
+// CarComparison lines cars up side by side: Rows has one entry per car and
+// engine field with the values of Cars in the same order.
+type CarComparison struct {
+	Cars []Car           `json:"cars"`
+	Rows []ComparisonRow `json:"rows"`
+}
+
+// ComparisonRow is one field of the compared cars. A value is null when the
+// car does not have the field. Differences, for numeric fields, are each
+// value less the value of the first car. Best lists the positions of the
+// cars with the best price, range or displacement.
+type ComparisonRow struct {
+	Field       string    `json:"field"`
+	Values      []*string `json:"values"`
+	Differs     bool      `json:"differs"`
+	Differences []*string `json:"differences,omitempty"`
+	Best        []int     `json:"best,omitempty"`
+}
+
+// CompareCars builds the comparison of cars in their order. Prices are
+// only compared when all cars are in the same currency.
+func CompareCars(cars []Car) CarComparison {
+	values := make([]map[string]string, len(cars))
+	for i, car := range cars {
+		values[i] = carRuleValues(CarRequest{
+			Name: car.Name, Year: car.Year, Brand: car.Brand, FuelType: car.FuelType,
+			Engine: car.Engine, Price: car.Price, Spec: car.Spec,
+		})
+		if car.Price.Amount != 0 {
+			units, _ := money.MinorUnits(car.Price.Currency)
+			values[i]["price"] = car.Price.Decimal().StringFixed(units)
+		}
+	}
+	sameCurrency := true
+	for _, car := range cars {
+		if car.Price.Currency != cars[0].Price.Currency {
+			sameCurrency = false
+		}
+	}
+
+	comparison := CarComparison{Cars: cars, Rows: []ComparisonRow{}}
+	for _, field := range RuleFields(RuleTargetCar) {
+		row := ComparisonRow{Field: field, Values: make([]*string, len(cars))}
+		for i := range cars {
+			if value := values[i][field]; value != "" {
+				row.Values[i] = &value
+			}
+			if i > 0 && !sameValue(row.Values[0], row.Values[i]) {
+				row.Differs = true
+			}
+		}
+		if numericFields[field] && (field != "price" || sameCurrency) {
+			row.Differences, row.Best = compareNumbers(field, row.Values, row.Differs)
+		}
+		comparison.Rows = append(comparison.Rows, row)
+	}
+	return comparison
+}
+
+func sameValue(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// compareNumbers works out the differences of a numeric row and, for the
+// best in class fields, the best cars when the values differ.
+func compareNumbers(field string, values []*string, differs bool) ([]*string, []int) {
+	numbers := make([]*decimal.Decimal, len(values))
+	for i, value := range values {
+		if value == nil {
+			continue
+		}
+		if number, err := decimal.Parse(*value); err == nil {
+			numbers[i] = &number
+		}
+	}
+
+	differences := make([]*string, len(values))
+	if numbers[0] != nil {
+		for i, number := range numbers {
+			if number != nil {
+				difference := number.Sub(*numbers[0]).String()
+				differences[i] = &difference
+			}
+		}
+	}
+
+	highest, marked := bestInClass[field]
+	if !marked || !differs {
+		return differences, nil
+	}
+	var (
+		best      []int
+		bestValue *decimal.Decimal
+	)
+	for i, number := range numbers {
+		if number == nil {
+			continue
+		}
+		cmp := 0
+		if bestValue != nil {
+			cmp = number.Cmp(*bestValue)
+			if !highest {
+				cmp = -cmp
+			}
+		}
+		switch {
+		case bestValue == nil || cmp > 0:
+			bestValue = number
+			best = []int{i}
+		case cmp == 0:
+			best = append(best, i)
+		}
+	}
+	return differences, best
+}
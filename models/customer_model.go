@@ -0,0 +1,117 @@
+package models
+
+import (
+	"errors"
+	"net/mail"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Ways a customer can be linked to a car.
+const (
+	CustomerCarViewed    = "viewed"
+	CustomerCarReserved  = "reserved"
+	CustomerCarPurchased = "purchased"
+)
+
+// Customer is a person who showed interest in or bought cars of the
+// dealership. MarketingConsent allows newsletters and offers,
+// ContactConsent allows salespeople to follow up. An erased customer keeps
+// its ID and car links but none of its personal data.
+type Customer struct {
+	ID               uuid.UUID      `json:"id"`
+	Name             string         `json:"name"`
+	Email            string         `json:"email"`
+	Phone            string         `json:"phone"`
+	Address          string         `json:"address"`
+	MarketingConsent bool           `json:"marketing_consent"`
+	ContactConsent   bool           `json:"contact_consent"`
+	ConsentUpdatedAt *time.Time     `json:"consent_updated_at,omitempty"`
+	Notes            []CustomerNote `json:"notes,omitempty"`
+	Cars             []CustomerCar  `json:"cars,omitempty"`
+	CreatedAt        time.Time      `json:"created_at"`
+	UpdatedAt        time.Time      `json:"updated_at"`
+	ErasedAt         *time.Time     `json:"erased_at,omitempty"`
+}
+
+type CustomerNote struct {
+	ID        uuid.UUID `json:"id"`
+	Body      string    `json:"body"`
+	Author    string    `json:"author"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CustomerCar links a customer to a car it viewed, reserved or purchased.
+type CustomerCar struct {
+	CarID     uuid.UUID `json:"car_id"`
+	Kind      string    `json:"kind"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type CustomerRequest struct {
+	Name             string `json:"name"`
+	Email            string `json:"email"`
+	Phone            string `json:"phone"`
+	Address          string `json:"address"`
+	MarketingConsent bool   `json:"marketing_consent"`
+	ContactConsent   bool   `json:"contact_consent"`
+}
+
+type CustomerNoteRequest struct {
+	Body string `json:"body"`
+}
+
+type CustomerCarRequest struct {
+	CarID uuid.UUID `json:"car_id"`
+	Kind  string    `json:"kind"`
+}
+
+// CustomerExport is everything kept about a customer, the answer to a data
+// access request. It includes the reservations and orders linked to the
+// customer.
+type CustomerExport struct {
+	ExportedAt   time.Time     `json:"exported_at"`
+	Customer     Customer      `json:"customer"`
+	Reservations []Reservation `json:"reservations"`
+	Orders       []Order       `json:"orders"`
+}
+
+// maxNoteLength bounds a note, longer texts belong in a document store.
+const maxNoteLength = 4000
+
+func ValidateCustomerRequest(customerReq CustomerRequest) error {
+	if customerReq.Name == "" {
+		return errors.New("Name is Required")
+	}
+	if customerReq.Email == "" && customerReq.Phone == "" {
+		return errors.New("Email or phone is Required")
+	}
+	if customerReq.Email != "" {
+		if _, err := mail.ParseAddress(customerReq.Email); err != nil {
+			return errors.New("Email is not a valid address")
+		}
+	}
+	return nil
+}
+
+func ValidateCustomerNoteRequest(noteReq CustomerNoteRequest) error {
+	if noteReq.Body == "" {
+		return errors.New("Note body is Required")
+	}
+	if len(noteReq.Body) > maxNoteLength {
+		return errors.New("Note must be at most 4000 characters")
+	}
+	return nil
+}
+
+func ValidateCustomerCarRequest(linkReq CustomerCarRequest) error {
+	if linkReq.CarID == uuid.Nil {
+		return errors.New("Car ID is Required")
+	}
+	switch linkReq.Kind {
+	case CustomerCarViewed, CustomerCarReserved, CustomerCarPurchased:
+		return nil
+	}
+	return errors.New("Kind must be one of viewed, reserved or purchased")
+}
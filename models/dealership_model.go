@@ -0,0 +1,29 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Dealership is a tenant of CarZone. Every car and engine belongs to one.
+type Dealership struct {
+	ID        uuid.UUID `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type DealershipRequest struct {
+	Name string `json:"name"`
+}
+
+func ValidateDealershipRequest(dealershipReq DealershipRequest) error {
+	if dealershipReq.Name == "" {
+		return errors.New("Name is Required")
+	}
+	if len(dealershipReq.Name) > 100 {
+		return errors.New("Name must be at most 100 characters")
+	}
+	return nil
+}
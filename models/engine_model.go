@@ -2,32 +2,112 @@ package models
 
 import (
 	"errors"
+	"fmt"
+	"time"
 
+	"github.com/adohong4/carZone/decimal"
 	"github.com/google/uuid"
 )
 
+// Engine types. An engine row describes the whole powertrain of a car: a
+// combustion engine, an electric motor with its battery, or both.
+const (
+	EngineCombustion = "combustion"
+	EngineElectric   = "electric"
+	EngineHybrid     = "hybrid"
+)
+
+// fuelTypeEngines is the engine type each fuel type of a car calls for.
+var fuelTypeEngines = map[string]string{
+	"Petrol":   EngineCombustion,
+	"Diesel":   EngineCombustion,
+	"Electric": EngineElectric,
+	"Hybrid":   EngineHybrid,
+}
+
 type Engine struct {
 	EngineID      uuid.UUID `json:"engine_id"`
+	Type          string    `json:"type"`
 	Displacement  int64     `json:"displacement"`
 	NoOfCylinders int64     `json:"noOfCylinders"`
 	CarRange      int64     `json:"carRange"`
+	// MotorPower is the output of the electric motor in kW and
+	// BatteryCapacity the usable capacity of its battery in kWh.
+	MotorPower      int64            `json:"motorPower,omitempty"`
+	BatteryCapacity *decimal.Decimal `json:"batteryCapacity,omitempty"`
+	UpdatedAt       time.Time        `json:"updated_at"`
 }
 
+// EngineRequest without a Type is a combustion engine, as all engines were
+// before electric motors were supported.
 type EngineRequest struct {
-	Displacement  int64 `json:"displacement"`
-	NoOfCylinders int64 `json:"noOfCylinders"`
-	CarRange      int64 `json:"carRange"`
+	Type            string           `json:"type"`
+	Displacement    int64            `json:"displacement"`
+	NoOfCylinders   int64            `json:"noOfCylinders"`
+	CarRange        int64            `json:"carRange"`
+	MotorPower      int64            `json:"motorPower"`
+	BatteryCapacity *decimal.Decimal `json:"batteryCapacity"`
+}
+
+// EngineType returns the type of an engine, defaulting to combustion.
+func EngineType(engineType string) string {
+	if engineType == "" {
+		return EngineCombustion
+	}
+	return engineType
 }
 
 func ValidateEngineRequest(EngineReq EngineRequest) error {
-	if err := validateDisplacement(EngineReq.Displacement); err != nil {
-		return err
+	return validatePowertrain(Engine{
+		Type:            EngineReq.Type,
+		Displacement:    EngineReq.Displacement,
+		NoOfCylinders:   EngineReq.NoOfCylinders,
+		CarRange:        EngineReq.CarRange,
+		MotorPower:      EngineReq.MotorPower,
+		BatteryCapacity: EngineReq.BatteryCapacity,
+	})
+}
+
+// validatePowertrain checks the fields of an engine fit its type: a
+// combustion engine has cylinders and no motor, an electric motor has a
+// battery and no cylinders, a hybrid has both.
+func validatePowertrain(engine Engine) error {
+	engineType := EngineType(engine.Type)
+	switch engineType {
+	case EngineCombustion, EngineHybrid:
+		if err := validateDisplacement(engine.Displacement); err != nil {
+			return err
+		}
+		if err := validateNoOfCylinders(engine.NoOfCylinders); err != nil {
+			return err
+		}
+	case EngineElectric:
+		if engine.Displacement != 0 || engine.NoOfCylinders != 0 {
+			return errors.New("Electric motors must have zero displacement and noOfCylinders")
+		}
+	default:
+		return fmt.Errorf("Engine type must be one of %s, %s or %s", EngineCombustion, EngineElectric, EngineHybrid)
 	}
-	if err := validateNoOfCylinders(EngineReq.NoOfCylinders); err != nil {
-		return err
+
+	if engineType == EngineCombustion {
+		if engine.MotorPower != 0 || engine.BatteryCapacity != nil {
+			return errors.New("Combustion engines have no motorPower or batteryCapacity")
+		}
+	} else {
+		if engine.MotorPower <= 0 {
+			return errors.New("motorPower must be greater than 0")
+		}
+		if engine.BatteryCapacity == nil || engine.BatteryCapacity.Sign() <= 0 {
+			return errors.New("batteryCapacity must be greater than 0")
+		}
 	}
-	if err := validateCarRange(EngineReq.CarRange); err != nil {
-		return err
+	return validateCarRange(engine.CarRange)
+}
+
+// validateFuelTypeEngine checks the engine can power a car of the fuel type.
+func validateFuelTypeEngine(fuelType string, engine Engine) error {
+	if want := fuelTypeEngines[fuelType]; want != EngineType(engine.Type) {
+		return fmt.Errorf("%s cars need a %s engine, not a %s one", fuelType, want, EngineType(engine.Type))
 	}
 	return nil
 }
@@ -0,0 +1,80 @@
+package models
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// The domain events the car and engine stores record.
+const (
+	EventCarCreated    = "car.created"
+	EventCarUpdated    = "car.updated"
+	EventCarDeleted    = "car.deleted"
+	EventEngineCreated = "engine.created"
+	EventEngineUpdated = "engine.updated"
+	EventEngineDeleted = "engine.deleted"
+)
+
+// EventTypes lists every event type.
+var EventTypes = []string{
+	EventCarCreated, EventCarUpdated, EventCarDeleted,
+	EventEngineCreated, EventEngineUpdated, EventEngineDeleted,
+}
+
+// Event is a change to a car or engine of a dealership, recorded in the
+// outbox with the change itself. Data is the car or engine as the change
+// left it, or as it was before it was deleted. Sequence orders the events
+// of the outbox, the events of one entity in the order they happened.
+type Event struct {
+	ID         uuid.UUID       `json:"id"`
+	Sequence   int64           `json:"sequence"`
+	Type       string          `json:"type"`
+	TenantID   string          `json:"tenant_id"`
+	EntityID   uuid.UUID       `json:"entity_id"`
+	Data       json.RawMessage `json:"data"`
+	OccurredAt time.Time       `json:"occurred_at"`
+}
+
+// NewEvent builds an event of eventType about the entity entityID of a
+// dealership, with data as its payload.
+func NewEvent(eventType, tenantID string, entityID uuid.UUID, data interface{}) (Event, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return Event{}, err
+	}
+	return Event{
+		ID:         uuid.New(),
+		Type:       eventType,
+		TenantID:   tenantID,
+		EntityID:   entityID,
+		Data:       raw,
+		OccurredAt: time.Now().UTC(),
+	}, nil
+}
+
+// Entity names what the event is about, e.g. "car:<id>". Events of one
+// entity are delivered in order.
+func (e Event) Entity() string {
+	kind, _, _ := strings.Cut(e.Type, ".")
+	return kind + ":" + e.EntityID.String()
+}
+
+// PendingEvent is an event claimed by the relay with the number of failed
+// attempts at publishing it so far.
+type PendingEvent struct {
+	Event    Event
+	Attempts int
+}
+
+// EventResult is what became of a claimed event. A published event is
+// done, any other is due again at RetryAt; Error, when set, is recorded as
+// a failed attempt.
+type EventResult struct {
+	Sequence  int64
+	Published bool
+	Error     string
+	RetryAt   time.Time
+}
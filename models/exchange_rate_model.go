@@ -0,0 +1,27 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"github.com/adohong4/carZone/decimal"
+)
+
+// ExchangeRate is how many units of Currency one unit of the base currency
+// buys. The base currency itself is not stored, its rate is always 1.
+type ExchangeRate struct {
+	Currency  string          `json:"currency"`
+	Rate      decimal.Decimal `json:"rate"`
+	UpdatedAt time.Time       `json:"updated_at"`
+}
+
+type ExchangeRateRequest struct {
+	Rate decimal.Decimal `json:"rate"`
+}
+
+func ValidateExchangeRateRequest(rateReq ExchangeRateRequest) error {
+	if rateReq.Rate.Sign() <= 0 {
+		return errors.New("Rate must be greater than zero")
+	}
+	return nil
+}
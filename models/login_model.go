@@ -1,6 +1,60 @@
 package models
 
+import "time"
+
 type Credentials struct {
 	UserName string `json:"userName"`
 	Password string `json:"password"`
 }
+
+// LoginResult carries either the access token or, for users with TOTP
+// enabled, the challenge to present to /login/2fa.
+type LoginResult struct {
+	Token                string `json:"token,omitempty"`
+	SecondFactorRequired bool   `json:"secondFactorRequired,omitempty"`
+	Challenge            string `json:"challenge,omitempty"`
+}
+
+// User is an authenticated CarZone user as carried in the JWT. TenantID is
+// the dealership the user works for.
+type User struct {
+	UserName string `json:"userName"`
+	Role     string `json:"role"`
+	TenantID string `json:"tenantId"`
+}
+
+const (
+	RoleAdmin = "admin"
+	RoleStaff = "staff"
+)
+
+// LoginAttempt is the audit record of a login attempt.
+type LoginAttempt struct {
+	UserName  string    `json:"userName"`
+	IP        string    `json:"ip"`
+	Success   bool      `json:"success"`
+	Reason    string    `json:"reason"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// LoginLockout is the failed-attempt state of one subject, either
+// "user:<name>" or "ip:<address>".
+type LoginLockout struct {
+	Subject        string    `json:"subject"`
+	FailedAttempts int       `json:"failedAttempts"`
+	Lockouts       int       `json:"lockouts"`
+	LockedUntil    time.Time `json:"lockedUntil"`
+}
+
+// IsLocked reports whether the subject is locked at the given time.
+func (l LoginLockout) IsLocked(now time.Time) bool {
+	return now.Before(l.LockedUntil)
+}
+
+// OIDCFlow is what the callback of an OIDC login needs from the request that
+// started it. It travels in a signed cookie.
+type OIDCFlow struct {
+	State        string `json:"state"`
+	Nonce        string `json:"nonce"`
+	CodeVerifier string `json:"codeVerifier"`
+}
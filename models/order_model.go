@@ -0,0 +1,182 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/adohong4/carZone/decimal"
+	"github.com/adohong4/carZone/money"
+	"github.com/google/uuid"
+)
+
+// Order statuses. An order is edited as a draft, gets its invoice number
+// when confirmed, and is then paid and delivered. Drafts and confirmed
+// orders can be cancelled.
+const (
+	OrderDraft     = "draft"
+	OrderConfirmed = "confirmed"
+	OrderPaid      = "paid"
+	OrderDelivered = "delivered"
+	OrderCancelled = "cancelled"
+)
+
+// orderTransitions lists the statuses each status may move to.
+var orderTransitions = map[string][]string{
+	OrderDraft:     {OrderConfirmed, OrderCancelled},
+	OrderConfirmed: {OrderPaid, OrderCancelled},
+	OrderPaid:      {OrderDelivered},
+	OrderDelivered: {},
+	OrderCancelled: {},
+}
+
+// Order is a sale to one customer. Amounts are in Currency and computed
+// from the lines, rates are percentages. CustomerID links it to a customer
+// record, whose erasure also blanks the customer fields.
+type Order struct {
+	ID              uuid.UUID       `json:"id"`
+	InvoiceNumber   string          `json:"invoice_number,omitempty"`
+	Status          string          `json:"status"`
+	CustomerID      *uuid.UUID      `json:"customer_id,omitempty"`
+	CustomerName    string          `json:"customer_name"`
+	CustomerEmail   string          `json:"customer_email"`
+	CustomerAddress string          `json:"customer_address"`
+	Salesperson     string          `json:"salesperson"`
+	Currency        string          `json:"currency"`
+	TaxRate         decimal.Decimal `json:"tax_rate"`
+	Subtotal        decimal.Decimal `json:"subtotal"`
+	DiscountTotal   decimal.Decimal `json:"discount_total"`
+	TaxTotal        decimal.Decimal `json:"tax_total"`
+	Total           decimal.Decimal `json:"total"`
+	Lines           []OrderLine     `json:"lines,omitempty"`
+	CreatedAt       time.Time       `json:"created_at"`
+	UpdatedAt       time.Time       `json:"updated_at"`
+	ConfirmedAt     *time.Time      `json:"confirmed_at,omitempty"`
+}
+
+// OrderLine sells Quantity units of a car. Total is after the discount and
+// before tax.
+type OrderLine struct {
+	ID              uuid.UUID       `json:"id"`
+	CarID           uuid.UUID       `json:"car_id"`
+	Description     string          `json:"description"`
+	Quantity        int64           `json:"quantity"`
+	UnitPrice       decimal.Decimal `json:"unit_price"`
+	DiscountPercent decimal.Decimal `json:"discount_percent"`
+	Discount        decimal.Decimal `json:"discount"`
+	Total           decimal.Decimal `json:"total"`
+}
+
+// OrderRequest sells in Currency, the currency of the first car when
+// empty.
+type OrderRequest struct {
+	CustomerID      *uuid.UUID         `json:"customer_id"`
+	CustomerName    string             `json:"customer_name"`
+	CustomerEmail   string             `json:"customer_email"`
+	CustomerAddress string             `json:"customer_address"`
+	Currency        string             `json:"currency"`
+	TaxRate         decimal.Decimal    `json:"tax_rate"`
+	Lines           []OrderLineRequest `json:"lines"`
+}
+
+// OrderLineRequest sells a car, at its catalogue price and with its
+// catalogue name unless UnitPrice and Description are given.
+type OrderLineRequest struct {
+	CarID           uuid.UUID        `json:"car_id"`
+	Description     string           `json:"description"`
+	Quantity        int64            `json:"quantity"`
+	UnitPrice       *decimal.Decimal `json:"unit_price"`
+	DiscountPercent decimal.Decimal  `json:"discount_percent"`
+}
+
+type OrderStatusRequest struct {
+	Status string `json:"status"`
+}
+
+var hundred = decimal.New(100, 0)
+
+func ValidateOrderRequest(orderReq OrderRequest) error {
+	if orderReq.CustomerName == "" {
+		return errors.New("Customer name is Required")
+	}
+	if orderReq.Currency != "" {
+		if err := money.ValidateCurrency(orderReq.Currency); err != nil {
+			return errors.New("Currency must be an ISO 4217 code")
+		}
+	}
+	if orderReq.TaxRate.Sign() < 0 || orderReq.TaxRate.Cmp(hundred) > 0 {
+		return errors.New("Tax rate must be between 0 and 100")
+	}
+	if len(orderReq.Lines) == 0 {
+		return errors.New("An order needs at least one line")
+	}
+	for _, line := range orderReq.Lines {
+		if line.CarID == uuid.Nil {
+			return errors.New("Car ID is Required")
+		}
+		if line.Quantity <= 0 {
+			return errors.New("Quantity must be greater than 0")
+		}
+		if line.UnitPrice != nil && line.UnitPrice.Sign() < 0 {
+			return errors.New("Unit price must not be negative")
+		}
+		if line.DiscountPercent.Sign() < 0 || line.DiscountPercent.Cmp(hundred) > 0 {
+			return errors.New("Discount must be between 0 and 100 percent")
+		}
+	}
+	return nil
+}
+
+// ValidateOrderStatus checks status is one of the order statuses.
+func ValidateOrderStatus(status string) error {
+	if _, ok := orderTransitions[status]; !ok {
+		return errors.New("Status must be one of draft, confirmed, paid, delivered or cancelled")
+	}
+	return nil
+}
+
+// ValidateOrderTransition checks an order may go from one status to another.
+func ValidateOrderTransition(from, to string) error {
+	if err := ValidateOrderStatus(to); err != nil {
+		return err
+	}
+	for _, allowed := range orderTransitions[from] {
+		if allowed == to {
+			return nil
+		}
+	}
+	return errors.New("Order cannot go from " + from + " to " + to)
+}
+
+// MinorUnits is the number of decimals amounts of the order are rounded
+// to, 2 for orders without a known currency.
+func (o Order) MinorUnits() int32 {
+	if units, ok := money.MinorUnits(o.Currency); ok {
+		return units
+	}
+	return 2
+}
+
+// ComputeTotals fills in the line and order amounts. Each line discount and
+// the tax are rounded to the currency's minor unit, half away from zero,
+// the totals are sums of rounded amounts so the invoice adds up.
+func (o *Order) ComputeTotals() {
+	places := o.MinorUnits()
+	o.Subtotal, o.DiscountTotal = decimal.Zero, decimal.Zero
+	for i := range o.Lines {
+		line := &o.Lines[i]
+		gross := line.UnitPrice.Mul(decimal.New(line.Quantity, 0))
+		line.Discount = gross.Mul(line.DiscountPercent).Div(hundred).Round(places)
+		line.Total = gross.Sub(line.Discount).Round(places)
+
+		o.Subtotal = o.Subtotal.Add(line.Total)
+		o.DiscountTotal = o.DiscountTotal.Add(line.Discount)
+	}
+	o.TaxTotal = o.Subtotal.Mul(o.TaxRate).Div(hundred).Round(places)
+	o.Total = o.Subtotal.Add(o.TaxTotal)
+}
+
+// InvoiceNumber formats the n-th invoice of a dealership.
+func InvoiceNumber(n int64) string {
+	return fmt.Sprintf("INV-%06d", n)
+}
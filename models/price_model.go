@@ -0,0 +1,178 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/adohong4/carZone/decimal"
+	"github.com/adohong4/carZone/money"
+	"github.com/google/uuid"
+)
+
+// Where a car price stands in its history.
+const (
+	CarPricePast      = "past"
+	CarPriceCurrent   = "current"
+	CarPriceScheduled = "scheduled"
+)
+
+// Kinds of promotion.
+const (
+	PromotionPercentage = "percentage"
+	PromotionFixed      = "fixed"
+)
+
+// CarPrice is the list price of a car from EffectiveFrom until EffectiveTo,
+// or until further notice without one. When ranges overlap the price that
+// started last applies.
+type CarPrice struct {
+	ID            uuid.UUID   `json:"id"`
+	CarID         uuid.UUID   `json:"car_id"`
+	Price         money.Money `json:"price"`
+	EffectiveFrom time.Time   `json:"effective_from"`
+	EffectiveTo   *time.Time  `json:"effective_to,omitempty"`
+	CreatedBy     string      `json:"created_by,omitempty"`
+	CreatedAt     time.Time   `json:"created_at"`
+	// Status is filled in for the price history.
+	Status string `json:"status,omitempty"`
+}
+
+// RepricedCar is a car whose price was brought in line with its price
+// history, in the dealership it belongs to.
+type RepricedCar struct {
+	TenantID string
+	CarID    uuid.UUID
+}
+
+// CarPriceRequest schedules a price. Without EffectiveFrom it applies at
+// once.
+type CarPriceRequest struct {
+	Price         money.Money `json:"price"`
+	EffectiveFrom time.Time   `json:"effective_from"`
+	EffectiveTo   *time.Time  `json:"effective_to"`
+}
+
+// Promotion takes Percent percent or a fixed Amount off the price of the
+// cars of Brand and of FuelType while it runs. An empty Brand or FuelType
+// matches every car.
+type Promotion struct {
+	ID        uuid.UUID       `json:"id"`
+	Name      string          `json:"name"`
+	Kind      string          `json:"kind"`
+	Percent   decimal.Decimal `json:"percent"`
+	Amount    money.Money     `json:"amount"`
+	Brand     string          `json:"brand,omitempty"`
+	FuelType  string          `json:"fuel_type,omitempty"`
+	StartsAt  time.Time       `json:"starts_at"`
+	EndsAt    *time.Time      `json:"ends_at,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+type PromotionRequest struct {
+	Name     string          `json:"name"`
+	Kind     string          `json:"kind"`
+	Percent  decimal.Decimal `json:"percent"`
+	Amount   money.Money     `json:"amount"`
+	Brand    string          `json:"brand"`
+	FuelType string          `json:"fuel_type"`
+	StartsAt time.Time       `json:"starts_at"`
+	EndsAt   *time.Time      `json:"ends_at"`
+}
+
+// EffectiveCarPrice picks the price of a car at a given time out of its
+// history.
+func EffectiveCarPrice(prices []CarPrice, at time.Time) (CarPrice, bool) {
+	var (
+		effective CarPrice
+		found     bool
+	)
+	for _, price := range prices {
+		if price.EffectiveFrom.After(at) || (price.EffectiveTo != nil && !price.EffectiveTo.After(at)) {
+			continue
+		}
+		if !found || price.EffectiveFrom.After(effective.EffectiveFrom) ||
+			(price.EffectiveFrom.Equal(effective.EffectiveFrom) && price.CreatedAt.After(effective.CreatedAt)) {
+			effective, found = price, true
+		}
+	}
+	return effective, found
+}
+
+// Active reports whether the promotion runs at the given time.
+func (p Promotion) Active(at time.Time) bool {
+	return !p.StartsAt.After(at) && (p.EndsAt == nil || p.EndsAt.After(at))
+}
+
+// Matches reports whether the promotion is for the car, brands and fuel
+// types are compared ignoring case.
+func (p Promotion) Matches(car Car) bool {
+	return (p.Brand == "" || strings.EqualFold(p.Brand, car.Brand)) &&
+		(p.FuelType == "" || strings.EqualFold(p.FuelType, car.FuelType))
+}
+
+// Discount returns how much the promotion takes off price, never more than
+// the price itself. Fixed amounts only apply to prices in their currency.
+func (p Promotion) Discount(price money.Money) (money.Money, bool) {
+	discount := money.Money{Currency: price.Currency}
+	switch p.Kind {
+	case PromotionPercentage:
+		units, _ := money.MinorUnits(price.Currency)
+		off, err := money.FromDecimal(price.Decimal().Mul(p.Percent).Div(decimal.New(100, 0)).Round(units), price.Currency)
+		if err != nil {
+			return money.Money{}, false
+		}
+		discount.Amount = off.Amount
+	case PromotionFixed:
+		if p.Amount.Currency != price.Currency {
+			return money.Money{}, false
+		}
+		discount.Amount = p.Amount.Amount
+	default:
+		return money.Money{}, false
+	}
+	if discount.Amount > price.Amount {
+		discount.Amount = price.Amount
+	}
+	return discount, true
+}
+
+func ValidateCarPriceRequest(priceReq CarPriceRequest) error {
+	if err := validatePrice(priceReq.Price); err != nil {
+		return err
+	}
+	if priceReq.EffectiveTo != nil && !priceReq.EffectiveTo.After(priceReq.EffectiveFrom) {
+		return errors.New("Effective to must be after effective from")
+	}
+	return nil
+}
+
+func ValidatePromotionRequest(promotionReq PromotionRequest) error {
+	if promotionReq.Name == "" {
+		return errors.New("Name is Required")
+	}
+	switch promotionReq.Kind {
+	case PromotionPercentage:
+		if promotionReq.Percent.Sign() <= 0 || promotionReq.Percent.Cmp(decimal.New(100, 0)) > 0 {
+			return errors.New("Percent must be greater than 0 and at most 100")
+		}
+	case PromotionFixed:
+		if err := validatePrice(promotionReq.Amount); err != nil {
+			return errors.New("Amount must be a positive price")
+		}
+	default:
+		return errors.New("Kind must be one of percentage or fixed")
+	}
+	if promotionReq.FuelType != "" {
+		if err := ValidateFuelType(promotionReq.FuelType); err != nil {
+			return err
+		}
+	}
+	if promotionReq.StartsAt.IsZero() {
+		return errors.New("Starts at is Required")
+	}
+	if promotionReq.EndsAt != nil && !promotionReq.EndsAt.After(promotionReq.StartsAt) {
+		return errors.New("Ends at must be after starts at")
+	}
+	return nil
+}
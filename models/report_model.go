@@ -0,0 +1,200 @@
+package models
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/adohong4/carZone/money"
+)
+
+// The dimensions an inventory report can be grouped by.
+const (
+	ReportByBrand    = "brand"
+	ReportByFuelType = "fuel_type"
+	ReportByYear     = "year"
+)
+
+// ReportDimensions lists the group by dimensions in their column order.
+var ReportDimensions = []string{ReportByBrand, ReportByFuelType, ReportByYear}
+
+// reportDate is the layout of the dates of a report request.
+const reportDate = "2006-01-02"
+
+// InventoryReportRequest is the query string of GET /reports/inventory.
+// From and To are dates or RFC 3339 times, a date To takes in the whole day.
+type InventoryReportRequest struct {
+	GroupBy []string
+	From    string
+	To      string
+}
+
+// InventoryReportQuery selects the cars added in [From, To), either bound
+// may be zero, and groups them by GroupBy, in ReportDimensions order, and
+// always by currency as prices in different currencies do not add up.
+type InventoryReportQuery struct {
+	GroupBy []string  `json:"group_by"`
+	From    time.Time `json:"from"`
+	To      time.Time `json:"to"`
+}
+
+// Groups reports whether the query groups by dimension.
+func (q InventoryReportQuery) Groups(dimension string) bool {
+	return oneOf(dimension, q.GroupBy)
+}
+
+// ValidateInventoryReport checks the dimensions and dates of a report
+// request and turns it into the query the store runs.
+func ValidateInventoryReport(reportReq InventoryReportRequest) (InventoryReportQuery, error) {
+	var query InventoryReportQuery
+	for _, dimension := range reportReq.GroupBy {
+		dimension = strings.TrimSpace(dimension)
+		if dimension == "" || query.Groups(dimension) {
+			continue
+		}
+		if !oneOf(dimension, ReportDimensions) {
+			return query, errors.New("group_by must be one of " + strings.Join(ReportDimensions, ", "))
+		}
+		query.GroupBy = append(query.GroupBy, dimension)
+	}
+	sort.Slice(query.GroupBy, func(i, j int) bool {
+		return dimensionIndex(query.GroupBy[i]) < dimensionIndex(query.GroupBy[j])
+	})
+
+	var err error
+	if query.From, err = parseReportTime(reportReq.From, false); err != nil {
+		return query, errors.New("from must be a date or a time")
+	}
+	if query.To, err = parseReportTime(reportReq.To, true); err != nil {
+		return query, errors.New("to must be a date or a time")
+	}
+	if !query.From.IsZero() && !query.To.IsZero() && !query.From.Before(query.To) {
+		return query, errors.New("from must be before to")
+	}
+	return query, nil
+}
+
+func dimensionIndex(dimension string) int {
+	for i, d := range ReportDimensions {
+		if d == dimension {
+			return i
+		}
+	}
+	return len(ReportDimensions)
+}
+
+// parseReportTime reads a bound of the report period, a date is midnight
+// UTC, or the midnight after it for the end of the period.
+func parseReportTime(value string, end bool) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if day, err := time.Parse(reportDate, value); err == nil {
+		if end {
+			day = day.AddDate(0, 0, 1)
+		}
+		return day, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	return t.UTC(), err
+}
+
+// InventoryReportRow aggregates the cars of one group. The dimensions the
+// report is not grouped by are left empty. UnitsInStock counts the vehicles
+// of the cars in stock or reserved, StockValue adds up their list prices.
+type InventoryReportRow struct {
+	Brand        string      `json:"brand,omitempty"`
+	FuelType     string      `json:"fuel_type,omitempty"`
+	Year         string      `json:"year,omitempty"`
+	Currency     string      `json:"currency"`
+	Cars         int64       `json:"cars"`
+	TotalPrice   money.Money `json:"total_price"`
+	AveragePrice money.Money `json:"average_price"`
+	MinPrice     money.Money `json:"min_price"`
+	MaxPrice     money.Money `json:"max_price"`
+	UnitsInStock int64       `json:"units_in_stock"`
+	StockValue   money.Money `json:"stock_value"`
+}
+
+// Dimension returns the value of a group by dimension of the row.
+func (r InventoryReportRow) Dimension(dimension string) string {
+	switch dimension {
+	case ReportByBrand:
+		return r.Brand
+	case ReportByFuelType:
+		return r.FuelType
+	case ReportByYear:
+		return r.Year
+	}
+	return ""
+}
+
+// InventoryReport is the result of an inventory query. Totals has one row
+// per currency over all the groups.
+type InventoryReport struct {
+	Query       InventoryReportQuery `json:"query"`
+	Rows        []InventoryReportRow `json:"rows"`
+	Totals      []InventoryReportRow `json:"totals"`
+	GeneratedAt time.Time            `json:"generated_at"`
+}
+
+// SummarizeInventory completes the rows of a store with their average
+// prices and works out the totals per currency.
+func SummarizeInventory(query InventoryReportQuery, rows []InventoryReportRow, now time.Time) InventoryReport {
+	report := InventoryReport{
+		Query:       query,
+		Rows:        make([]InventoryReportRow, 0, len(rows)),
+		Totals:      []InventoryReportRow{},
+		GeneratedAt: now,
+	}
+	totals := make(map[string]int)
+	for _, row := range rows {
+		row.AveragePrice = averagePrice(row.TotalPrice, row.Cars)
+		report.Rows = append(report.Rows, row)
+
+		i, ok := totals[row.Currency]
+		if !ok {
+			i = len(report.Totals)
+			totals[row.Currency] = i
+			report.Totals = append(report.Totals, InventoryReportRow{
+				Currency: row.Currency, MinPrice: row.MinPrice, MaxPrice: row.MaxPrice,
+			})
+		}
+		total := &report.Totals[i]
+		total.Cars += row.Cars
+		total.TotalPrice = addMoney(total.TotalPrice, row.TotalPrice)
+		if row.MinPrice.Amount < total.MinPrice.Amount {
+			total.MinPrice = row.MinPrice
+		}
+		if row.MaxPrice.Amount > total.MaxPrice.Amount {
+			total.MaxPrice = row.MaxPrice
+		}
+		total.UnitsInStock += row.UnitsInStock
+		total.StockValue = addMoney(total.StockValue, row.StockValue)
+	}
+	for i := range report.Totals {
+		report.Totals[i].AveragePrice = averagePrice(report.Totals[i].TotalPrice, report.Totals[i].Cars)
+	}
+	sort.Slice(report.Totals, func(i, j int) bool { return report.Totals[i].Currency < report.Totals[j].Currency })
+	return report
+}
+
+func addMoney(a, b money.Money) money.Money {
+	return money.Money{Amount: a.Amount + b.Amount, Currency: b.Currency}
+}
+
+// averagePrice divides total over n cars, rounding half away from zero to
+// the minor unit.
+func averagePrice(total money.Money, n int64) money.Money {
+	if n == 0 {
+		return money.Money{Currency: total.Currency}
+	}
+	amount := total.Amount / n
+	if remainder := total.Amount % n; remainder*2 >= n {
+		amount++
+	} else if remainder*2 <= -n {
+		amount--
+	}
+	return money.Money{Amount: amount, Currency: total.Currency}
+}
@@ -0,0 +1,54 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Reservation statuses. An active reservation holds its car until it is
+// released or expires.
+const (
+	ReservationActive   = "active"
+	ReservationReleased = "released"
+	ReservationExpired  = "expired"
+)
+
+// Reservation is a time-limited hold a salesperson puts on a car for a
+// customer. A car has at most one active reservation. CustomerID links it to
+// a customer record, whose erasure also blanks the name and contact.
+type Reservation struct {
+	ID              uuid.UUID  `json:"id"`
+	CarID           uuid.UUID  `json:"car_id"`
+	CustomerID      *uuid.UUID `json:"customer_id,omitempty"`
+	CustomerName    string     `json:"customer_name"`
+	CustomerContact string     `json:"customer_contact"`
+	ReservedBy      string     `json:"reserved_by"`
+	Status          string     `json:"status"`
+	ExpiresAt       time.Time  `json:"expires_at"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+// ReservationRequest holds a car for HoldHours, the configured default hold
+// when 0.
+type ReservationRequest struct {
+	CustomerID      *uuid.UUID `json:"customer_id"`
+	CustomerName    string     `json:"customer_name"`
+	CustomerContact string     `json:"customer_contact"`
+	HoldHours       int        `json:"hold_hours"`
+}
+
+func ValidateReservationRequest(reservationReq ReservationRequest) error {
+	if reservationReq.CustomerName == "" {
+		return errors.New("Customer name is Required")
+	}
+	if reservationReq.CustomerContact == "" {
+		return errors.New("Customer contact is Required")
+	}
+	if reservationReq.HoldHours < 0 {
+		return errors.New("Hold hours must not be negative")
+	}
+	return nil
+}
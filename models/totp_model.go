@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// TOTPCredential is a user's authenticator secret. It only guards logins
+// once Confirmed, i.e. after the user proved their app produces valid codes.
+type TOTPCredential struct {
+	UserName     string    `json:"userName"`
+	Secret       string    `json:"-"`
+	Confirmed    bool      `json:"confirmed"`
+	LastUsedStep int64     `json:"-"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// TOTPEnrollment is returned when enrolling, QRPayload is what the client
+// renders as a QR code for authenticator apps.
+type TOTPEnrollment struct {
+	Secret    string `json:"secret"`
+	URI       string `json:"uri"`
+	QRPayload string `json:"qrPayload"`
+}
+
+type TOTPCodeRequest struct {
+	Code string `json:"code"`
+}
+
+// SecondFactorRequest completes a login that returned a challenge. Code is
+// either a TOTP code or a recovery code.
+type SecondFactorRequest struct {
+	Challenge string `json:"challenge"`
+	Code      string `json:"code"`
+}
@@ -0,0 +1,311 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/adohong4/carZone/decimal"
+)
+
+// What a validation rule applies to.
+const (
+	RuleTargetCar    = "car"
+	RuleTargetEngine = "engine"
+)
+
+// The checks a validation rule can make on its field.
+const (
+	CheckRequired = "required"
+	CheckMin      = "min"
+	CheckMax      = "max"
+	CheckIn       = "in"
+	CheckNotIn    = "not_in"
+	CheckPattern  = "pattern"
+)
+
+// BuiltinRule names the violations of the checks every car must pass,
+// whatever the rules of the dealership.
+const BuiltinRule = "builtin"
+
+// ValidationRule is a declarative check on one field of a car or engine
+// request, on top of the built-in validation. A dealership rule with the ID
+// of a default rule replaces it, or switches it off when Disabled.
+type ValidationRule struct {
+	ID     string `json:"id"`
+	Target string `json:"target,omitempty"`
+	Field  string `json:"field,omitempty"`
+	Check  string `json:"check,omitempty"`
+	// Value is the bound of min and max, which are inclusive, or the
+	// regular expression of pattern. Values lists what in and not_in allow
+	// or refuse, ignoring case.
+	Value    string   `json:"value,omitempty"`
+	Values   []string `json:"values,omitempty"`
+	Message  string   `json:"message,omitempty"`
+	Disabled bool     `json:"disabled,omitempty"`
+}
+
+type ValidationRulesRequest struct {
+	Rules []ValidationRule `json:"rules"`
+}
+
+// ValidationRules are the rules in force for a dealership, the defaults
+// merged with its Overrides.
+type ValidationRules struct {
+	Rules     []ValidationRule `json:"rules"`
+	Overrides []ValidationRule `json:"overrides"`
+}
+
+// Violation is a check a request failed.
+type Violation struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// ValidationResult is the outcome of a dry run.
+type ValidationResult struct {
+	Valid      bool        `json:"valid"`
+	Violations []Violation `json:"violations"`
+}
+
+// The fields rules can refer to, by target. Car rules reach the engine of
+// the car through the engine. prefix.
+var (
+	engineRuleFields = []string{"type", "displacement", "noOfCylinders", "carRange", "motorPower", "batteryCapacity"}
+	carRuleFields    = []string{
+		"name", "year", "brand", "fuel_type", "price", "currency",
+		"spec.transmission", "spec.drivetrain", "spec.body_type", "spec.seats", "spec.doors",
+		"spec.colour", "spec.trim", "spec.fuel_consumption", "spec.energy_consumption", "spec.co2_emissions",
+	}
+)
+
+// RuleFields returns the fields rules of target can check.
+func RuleFields(target string) []string {
+	switch target {
+	case RuleTargetCar:
+		fields := append([]string{}, carRuleFields...)
+		for _, field := range engineRuleFields {
+			fields = append(fields, "engine."+field)
+		}
+		return fields
+	case RuleTargetEngine:
+		return append([]string{}, engineRuleFields...)
+	}
+	return nil
+}
+
+// ValidateRules checks a rule set can be evaluated: every rule has a unique
+// ID and, unless it only disables a default, a known target, field and
+// check with the arguments the check needs.
+func ValidateRules(rules []ValidationRule) error {
+	ids := make(map[string]bool, len(rules))
+	for _, rule := range rules {
+		if strings.TrimSpace(rule.ID) == "" {
+			return errors.New("Rule id is Required")
+		}
+		if ids[rule.ID] {
+			return errors.New("Rule " + rule.ID + " is given twice")
+		}
+		ids[rule.ID] = true
+		if rule.Disabled {
+			continue
+		}
+		if err := validateRule(rule); err != nil {
+			return fmt.Errorf("Rule %s: %w", rule.ID, err)
+		}
+	}
+	return nil
+}
+
+func validateRule(rule ValidationRule) error {
+	fields := RuleFields(rule.Target)
+	if fields == nil {
+		return fmt.Errorf("target must be %s or %s", RuleTargetCar, RuleTargetEngine)
+	}
+	if !oneOf(rule.Field, fields) {
+		return errors.New("field must be one of " + strings.Join(fields, ", "))
+	}
+	switch rule.Check {
+	case CheckRequired:
+	case CheckMin, CheckMax:
+		if _, err := decimal.Parse(rule.Value); err != nil {
+			return errors.New("value must be a number")
+		}
+	case CheckIn, CheckNotIn:
+		if len(rule.Values) == 0 {
+			return errors.New("values are Required")
+		}
+	case CheckPattern:
+		if _, err := regexp.Compile(rule.Value); err != nil {
+			return errors.New("value must be a regular expression")
+		}
+	default:
+		return errors.New("check must be one of " + strings.Join(
+			[]string{CheckRequired, CheckMin, CheckMax, CheckIn, CheckNotIn, CheckPattern}, ", "))
+	}
+	return nil
+}
+
+// MergeRules applies the rules of a dealership to the defaults: a rule with
+// the ID of a default replaces it, or drops it when disabled, and the other
+// rules are added after the defaults.
+func MergeRules(defaults, overrides []ValidationRule) []ValidationRule {
+	byID := make(map[string]ValidationRule, len(overrides))
+	for _, rule := range overrides {
+		byID[rule.ID] = rule
+	}
+
+	merged := make([]ValidationRule, 0, len(defaults)+len(overrides))
+	for _, rule := range defaults {
+		if override, ok := byID[rule.ID]; ok {
+			rule = override
+			delete(byID, rule.ID)
+		}
+		if !rule.Disabled {
+			merged = append(merged, rule)
+		}
+	}
+	for _, rule := range overrides {
+		if _, ok := byID[rule.ID]; ok && !rule.Disabled {
+			merged = append(merged, rule)
+		}
+	}
+	return merged
+}
+
+// CarRuleViolations evaluates the car rules against a car request.
+func CarRuleViolations(rules []ValidationRule, carReq CarRequest) []Violation {
+	return ruleViolations(rules, RuleTargetCar, carRuleValues(carReq))
+}
+
+// EngineRuleViolations evaluates the engine rules against an engine request.
+func EngineRuleViolations(rules []ValidationRule, engineReq EngineRequest) []Violation {
+	return ruleViolations(rules, RuleTargetEngine, engineRuleValues(Engine{
+		Type:            engineReq.Type,
+		Displacement:    engineReq.Displacement,
+		NoOfCylinders:   engineReq.NoOfCylinders,
+		CarRange:        engineReq.CarRange,
+		MotorPower:      engineReq.MotorPower,
+		BatteryCapacity: engineReq.BatteryCapacity,
+	}))
+}
+
+func ruleViolations(rules []ValidationRule, target string, values map[string]string) []Violation {
+	var violations []Violation
+	for _, rule := range rules {
+		if rule.Disabled || rule.Target != target {
+			continue
+		}
+		if message, ok := evaluateRule(rule, values[rule.Field]); !ok {
+			if rule.Message != "" {
+				message = rule.Message
+			}
+			violations = append(violations, Violation{Field: rule.Field, Rule: rule.ID, Message: message})
+		}
+	}
+	return violations
+}
+
+// evaluateRule checks value, empty when the field was not given, against
+// the rule. Only required fails on a missing field.
+func evaluateRule(rule ValidationRule, value string) (string, bool) {
+	if value == "" {
+		return rule.Field + " is required", rule.Check != CheckRequired
+	}
+	switch rule.Check {
+	case CheckMin, CheckMax:
+		bound, err := decimal.Parse(rule.Value)
+		if err != nil {
+			return rule.Field + " has an invalid bound", false
+		}
+		number, err := decimal.Parse(value)
+		if err != nil {
+			return rule.Field + " must be a number", false
+		}
+		if rule.Check == CheckMin {
+			return rule.Field + " must be at least " + rule.Value, number.Cmp(bound) >= 0
+		}
+		return rule.Field + " must be at most " + rule.Value, number.Cmp(bound) <= 0
+	case CheckIn:
+		return rule.Field + " must be one of " + strings.Join(rule.Values, ", "), containsFold(rule.Values, value)
+	case CheckNotIn:
+		return rule.Field + " cannot be " + value, !containsFold(rule.Values, value)
+	case CheckPattern:
+		pattern, err := regexp.Compile(rule.Value)
+		if err != nil {
+			return rule.Field + " has an invalid pattern", false
+		}
+		return rule.Field + " must match " + rule.Value, pattern.MatchString(value)
+	}
+	return "", true
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(strings.TrimSpace(v), strings.TrimSpace(value)) {
+			return true
+		}
+	}
+	return false
+}
+
+// carRuleValues flattens a car request into the fields rules check, a
+// field left out, or left at zero, is empty.
+func carRuleValues(carReq CarRequest) map[string]string {
+	values := map[string]string{
+		"name":      carReq.Name,
+		"year":      carReq.Year,
+		"brand":     carReq.Brand,
+		"fuel_type": carReq.FuelType,
+		"currency":  carReq.Price.Currency,
+	}
+	if carReq.Price.Amount != 0 {
+		values["price"] = carReq.Price.Decimal().String()
+	}
+	if spec := carReq.Spec; spec != nil {
+		values["spec.transmission"] = spec.Transmission
+		values["spec.drivetrain"] = spec.Drivetrain
+		values["spec.body_type"] = spec.BodyType
+		values["spec.seats"] = nonZero(int64(spec.Seats))
+		values["spec.doors"] = nonZero(int64(spec.Doors))
+		values["spec.colour"] = spec.Colour
+		values["spec.trim"] = spec.Trim
+		if spec.FuelConsumption != nil {
+			values["spec.fuel_consumption"] = spec.FuelConsumption.String()
+		}
+		if spec.EnergyConsumption != nil {
+			values["spec.energy_consumption"] = spec.EnergyConsumption.String()
+		}
+		if spec.CO2Emissions != nil {
+			values["spec.co2_emissions"] = strconv.FormatInt(*spec.CO2Emissions, 10)
+		}
+	}
+	for field, value := range engineRuleValues(carReq.Engine) {
+		values["engine."+field] = value
+	}
+	return values
+}
+
+func engineRuleValues(engine Engine) map[string]string {
+	values := map[string]string{
+		"type":          EngineType(engine.Type),
+		"displacement":  nonZero(engine.Displacement),
+		"noOfCylinders": nonZero(engine.NoOfCylinders),
+		"carRange":      nonZero(engine.CarRange),
+		"motorPower":    nonZero(engine.MotorPower),
+	}
+	if engine.BatteryCapacity != nil {
+		values["batteryCapacity"] = engine.BatteryCapacity.String()
+	}
+	return values
+}
+
+func nonZero(n int64) string {
+	if n == 0 {
+		return ""
+	}
+	return strconv.FormatInt(n, 10)
+}
@@ -0,0 +1,134 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"github.com/adohong4/carZone/vin"
+	"github.com/google/uuid"
+)
+
+// Vehicle statuses. A unit arrives in transit or in stock, can be reserved
+// and released again, and ends sold.
+const (
+	VehicleInTransit = "in_transit"
+	VehicleInStock   = "in_stock"
+	VehicleReserved  = "reserved"
+	VehicleSold      = "sold"
+)
+
+// vehicleTransitions lists the statuses each status may move to.
+var vehicleTransitions = map[string][]string{
+	VehicleInTransit: {VehicleInStock},
+	VehicleInStock:   {VehicleInTransit, VehicleReserved, VehicleSold},
+	VehicleReserved:  {VehicleInStock, VehicleSold},
+	VehicleSold:      {},
+}
+
+// Vehicle is one physical unit of a car model, identified by its VIN.
+type Vehicle struct {
+	VIN        string    `json:"vin"`
+	CarID      uuid.UUID `json:"car_id"`
+	Status     string    `json:"status"`
+	Location   string    `json:"location"`
+	Mileage    int64     `json:"mileage"`
+	Colour     string    `json:"colour"`
+	ReceivedAt time.Time `json:"received_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// VehicleRequest receives a unit. Status is in_stock unless the unit is
+// still in_transit.
+type VehicleRequest struct {
+	VIN      string    `json:"vin"`
+	CarID    uuid.UUID `json:"car_id"`
+	Status   string    `json:"status"`
+	Location string    `json:"location"`
+	Mileage  int64     `json:"mileage"`
+	Colour   string    `json:"colour"`
+}
+
+// VehicleMoveRequest moves a unit to another location and/or status.
+// Empty fields are left unchanged, Mileage can only grow.
+type VehicleMoveRequest struct {
+	Location string `json:"location"`
+	Status   string `json:"status"`
+	Mileage  int64  `json:"mileage"`
+}
+
+// VehicleFilter narrows a unit listing, empty fields match everything.
+type VehicleFilter struct {
+	CarID    string
+	Status   string
+	Location string
+}
+
+// StockCount is the number of units of one car model by status.
+type StockCount struct {
+	CarID     uuid.UUID `json:"car_id"`
+	InTransit int64     `json:"in_transit"`
+	InStock   int64     `json:"in_stock"`
+	Reserved  int64     `json:"reserved"`
+	Sold      int64     `json:"sold"`
+}
+
+// Add counts n units of status.
+func (c *StockCount) Add(status string, n int64) {
+	switch status {
+	case VehicleInTransit:
+		c.InTransit += n
+	case VehicleInStock:
+		c.InStock += n
+	case VehicleReserved:
+		c.Reserved += n
+	case VehicleSold:
+		c.Sold += n
+	}
+}
+
+func ValidateVehicleRequest(vehicleReq VehicleRequest) error {
+	if err := vin.Validate(vehicleReq.VIN); err != nil {
+		return err
+	}
+	if vehicleReq.CarID == uuid.Nil {
+		return errors.New("Car ID is Required")
+	}
+	if vehicleReq.Status != VehicleInStock && vehicleReq.Status != VehicleInTransit {
+		return errors.New("Status of a received vehicle must be in_stock or in_transit")
+	}
+	if vehicleReq.Location == "" {
+		return errors.New("Location is Required")
+	}
+	if vehicleReq.Mileage < 0 {
+		return errors.New("Mileage must not be negative")
+	}
+	if vehicleReq.Colour == "" {
+		return errors.New("Colour is Required")
+	}
+	return nil
+}
+
+// ValidateVehicleStatus checks status is one of the vehicle statuses.
+func ValidateVehicleStatus(status string) error {
+	if _, ok := vehicleTransitions[status]; !ok {
+		return errors.New("Status must be one of in_transit, in_stock, reserved or sold")
+	}
+	return nil
+}
+
+// ValidateVehicleTransition checks a unit may go from one status to another.
+// Staying in the same status is always allowed.
+func ValidateVehicleTransition(from, to string) error {
+	if err := ValidateVehicleStatus(to); err != nil {
+		return err
+	}
+	if from == to {
+		return nil
+	}
+	for _, allowed := range vehicleTransitions[from] {
+		if allowed == to {
+			return nil
+		}
+	}
+	return errors.New("Vehicle cannot go from " + from + " to " + to)
+}
@@ -0,0 +1,14 @@
+package models
+
+import "github.com/adohong4/carZone/vin"
+
+type VINDecodeRequest struct {
+	VIN string `json:"vin"`
+}
+
+// VINDecodeResult is a decoded VIN and the car request it pre-fills. Only
+// what a VIN tells is filled in, the rest is left for the user.
+type VINDecodeResult struct {
+	vin.Decoded
+	Car CarRequest `json:"car"`
+}
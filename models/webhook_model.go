@@ -0,0 +1,141 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// The states of a webhook delivery. A pending delivery is sent at
+// NextAttemptAt, a failed one ran out of attempts and is only sent again
+// when it is redelivered.
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed"
+)
+
+// Bounds of a webhook subscription.
+const (
+	maxWebhookURLLength = 2048
+	minWebhookSecret    = 16
+)
+
+// Webhook subscribes a URL of a partner to the events of the dealership.
+// Events lists event types, "car.*" for every car event or "*" for all.
+// Secret signs the deliveries, it is only shown when the webhook is
+// created.
+type Webhook struct {
+	ID        uuid.UUID `json:"id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	Secret    string    `json:"secret,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Takes reports whether the webhook subscribes to events of eventType.
+func (w Webhook) Takes(eventType string) bool {
+	kind, _, _ := strings.Cut(eventType, ".")
+	for _, filter := range w.Events {
+		if filter == "*" || filter == eventType || filter == kind+".*" {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookRequest is the body of POST /webhooks. A secret is generated when
+// none is given.
+type WebhookRequest struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+	Secret string   `json:"secret"`
+}
+
+func ValidateWebhookRequest(webhookReq WebhookRequest) error {
+	if webhookReq.URL == "" {
+		return errors.New("URL is Required")
+	}
+	parsed, err := url.Parse(webhookReq.URL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return errors.New("URL must be an http or https URL")
+	}
+	if len(webhookReq.URL) > maxWebhookURLLength {
+		return errors.New("URL must be at most 2048 characters")
+	}
+	if len(webhookReq.Events) == 0 {
+		return errors.New("Events is Required")
+	}
+	for _, filter := range webhookReq.Events {
+		if !validEventFilter(filter) {
+			return errors.New("Unknown event " + filter + ", use an event type, car.*, engine.* or *")
+		}
+	}
+	if webhookReq.Secret != "" && len(webhookReq.Secret) < minWebhookSecret {
+		return errors.New("Secret must be at least 16 characters")
+	}
+	return nil
+}
+
+// ValidateWebhookAddress rejects the addresses a webhook must not reach,
+// loopback, link-local, private and unspecified ones, so a webhook cannot
+// make the service call into its own network.
+func ValidateWebhookAddress(ip net.IP) error {
+	if ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsPrivate() || ip.IsUnspecified() {
+		return errors.New("URL must not point to a loopback, link-local or private address")
+	}
+	return nil
+}
+
+func validEventFilter(filter string) bool {
+	if filter == "*" || oneOf(filter, EventTypes) {
+		return true
+	}
+	for _, eventType := range EventTypes {
+		if kind, _, _ := strings.Cut(eventType, "."); filter == kind+".*" {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDelivery is an event sent, or to be sent, to a webhook. Payload is
+// the event as posted. ResponseCode and Error are those of the last
+// attempt, Log has every attempt when a single delivery is asked for.
+type WebhookDelivery struct {
+	ID            uuid.UUID        `json:"id"`
+	WebhookID     uuid.UUID        `json:"webhook_id"`
+	EventID       uuid.UUID        `json:"event_id"`
+	EventType     string           `json:"event_type"`
+	Payload       json.RawMessage  `json:"payload"`
+	Status        string           `json:"status"`
+	Attempts      int              `json:"attempts"`
+	ResponseCode  int              `json:"response_code,omitempty"`
+	Error         string           `json:"error,omitempty"`
+	NextAttemptAt *time.Time       `json:"next_attempt_at,omitempty"`
+	CreatedAt     time.Time        `json:"created_at"`
+	DeliveredAt   *time.Time       `json:"delivered_at,omitempty"`
+	Log           []WebhookAttempt `json:"log,omitempty"`
+}
+
+// WebhookAttempt is one try at a delivery. ResponseCode is zero when no
+// response came back, Error says why then.
+type WebhookAttempt struct {
+	ResponseCode int       `json:"response_code,omitempty"`
+	Error        string    `json:"error,omitempty"`
+	DurationMS   int64     `json:"duration_ms"`
+	AttemptedAt  time.Time `json:"attempted_at"`
+}
+
+// PendingDelivery is a delivery due to be sent with where to and how to
+// sign it.
+type PendingDelivery struct {
+	Delivery WebhookDelivery
+	URL      string
+	Secret   string
+}
@@ -0,0 +1,152 @@
+// Package money keeps amounts as whole minor units of an ISO 4217 currency,
+// cents for USD and dong for VND, so prices add up exactly.
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/adohong4/carZone/decimal"
+)
+
+var (
+	ErrCurrency  = errors.New("unknown currency")
+	ErrPrecision = errors.New("amount has more decimals than the currency allows")
+)
+
+// DefaultCurrency is the currency of prices sent as a bare number and of
+// the prices that existed before cars had a currency.
+const DefaultCurrency = "USD"
+
+// minorUnits lists the supported ISO 4217 currencies with the number of
+// decimals of their minor unit.
+var minorUnits = map[string]int32{
+	"AUD": 2, "BHD": 3, "CAD": 2, "CHF": 2, "CNY": 2, "CZK": 2, "DKK": 2,
+	"EUR": 2, "GBP": 2, "HKD": 2, "IDR": 2, "INR": 2, "JPY": 0, "KRW": 0,
+	"KWD": 3, "MXN": 2, "MYR": 2, "NOK": 2, "NZD": 2, "PHP": 2, "PLN": 2,
+	"SEK": 2, "SGD": 2, "THB": 2, "TWD": 2, "USD": 2, "VND": 0, "ZAR": 2,
+}
+
+// Money is an amount in minor units of Currency. The zero value has no
+// currency and is not a valid price.
+type Money struct {
+	Amount   int64
+	Currency string
+}
+
+// MinorUnits returns the number of decimals of currency.
+func MinorUnits(currency string) (int32, bool) {
+	units, ok := minorUnits[currency]
+	return units, ok
+}
+
+// Currencies lists the supported currency codes in order.
+func Currencies() []string {
+	codes := make([]string, 0, len(minorUnits))
+	for code := range minorUnits {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// ValidateCurrency checks currency is a supported ISO 4217 code.
+func ValidateCurrency(currency string) error {
+	if _, ok := minorUnits[currency]; !ok {
+		return fmt.Errorf("%w: %q", ErrCurrency, currency)
+	}
+	return nil
+}
+
+// FromDecimal converts a major-unit amount such as 19.99 USD. Amounts with
+// more decimals than the currency has are refused rather than rounded.
+func FromDecimal(amount decimal.Decimal, currency string) (Money, error) {
+	units, ok := minorUnits[currency]
+	if !ok {
+		return Money{}, fmt.Errorf("%w: %q", ErrCurrency, currency)
+	}
+	minor, ok := amount.Shift(units).Int64()
+	if !ok {
+		return Money{}, fmt.Errorf("%w: %s %s", ErrPrecision, amount, currency)
+	}
+	return Money{Amount: minor, Currency: currency}, nil
+}
+
+// Parse reads an amount like "19.99" in currency.
+func Parse(amount, currency string) (Money, error) {
+	d, err := decimal.Parse(amount)
+	if err != nil {
+		return Money{}, err
+	}
+	return FromDecimal(d, currency)
+}
+
+// Decimal returns the amount in major units.
+func (m Money) Decimal() decimal.Decimal {
+	return decimal.New(m.Amount, minorUnits[m.Currency])
+}
+
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+// Convert changes m into currency at rate units of currency per unit of
+// m's currency, rounding half away from zero to the target's minor unit.
+func (m Money) Convert(currency string, rate decimal.Decimal) (Money, error) {
+	units, ok := minorUnits[currency]
+	if !ok {
+		return Money{}, fmt.Errorf("%w: %q", ErrCurrency, currency)
+	}
+	return FromDecimal(m.Decimal().Mul(rate).Round(units), currency)
+}
+
+// String formats m as "19.99 USD".
+func (m Money) String() string {
+	return m.Decimal().StringFixed(minorUnits[m.Currency]) + " " + m.Currency
+}
+
+type moneyJSON struct {
+	Amount   decimal.Decimal `json:"amount"`
+	Currency string          `json:"currency"`
+}
+
+// MarshalJSON writes {"amount": "19.99", "currency": "USD"}, the amount in
+// major units as a string like the other decimals of the API. The zero
+// Money is null.
+func (m Money) MarshalJSON() ([]byte, error) {
+	if m == (Money{}) {
+		return []byte("null"), nil
+	}
+	units := minorUnits[m.Currency]
+	return []byte(fmt.Sprintf(`{"amount":"%s","currency":%q}`, m.Decimal().StringFixed(units), m.Currency)), nil
+}
+
+// UnmarshalJSON reads the object MarshalJSON writes. A bare number or
+// string is an amount in DefaultCurrency, as prices were sent before they
+// had a currency.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	trimmed := strings.TrimSpace(string(data))
+	if trimmed == "null" {
+		*m = Money{}
+		return nil
+	}
+
+	v := moneyJSON{Currency: DefaultCurrency}
+	if strings.HasPrefix(trimmed, "{") {
+		if err := json.Unmarshal(data, &v); err != nil {
+			return err
+		}
+	} else if err := v.Amount.UnmarshalJSON(data); err != nil {
+		return err
+	}
+
+	parsed, err := FromDecimal(v.Amount, strings.ToUpper(v.Currency))
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
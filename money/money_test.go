@@ -0,0 +1,66 @@
+package money
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/adohong4/carZone/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	m, err := Parse("19.99", "USD")
+	require.NoError(t, err)
+	assert.Equal(t, Money{Amount: 1999, Currency: "USD"}, m)
+
+	m, err = Parse("25000000", "VND")
+	require.NoError(t, err)
+	assert.Equal(t, int64(25000000), m.Amount)
+	assert.Equal(t, "25000000 VND", m.String())
+
+	_, err = Parse("19.999", "USD")
+	assert.ErrorIs(t, err, ErrPrecision)
+	_, err = Parse("1.5", "VND")
+	assert.ErrorIs(t, err, ErrPrecision)
+	_, err = Parse("1", "XYZ")
+	assert.ErrorIs(t, err, ErrCurrency)
+}
+
+func TestConvert(t *testing.T) {
+	price := Money{Amount: 2500050, Currency: "USD"}
+
+	eur, err := price.Convert("EUR", decimal.New(92, 2))
+	require.NoError(t, err)
+	assert.Equal(t, "23000.46 EUR", eur.String())
+
+	vnd, err := price.Convert("VND", decimal.New(25400, 0))
+	require.NoError(t, err)
+	assert.Equal(t, int64(635012700), vnd.Amount)
+
+	_, err = price.Convert("XYZ", decimal.New(1, 0))
+	assert.ErrorIs(t, err, ErrCurrency)
+}
+
+func TestJSON(t *testing.T) {
+	out, err := json.Marshal(Money{Amount: 1999, Currency: "EUR"})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"amount": "19.99", "currency": "EUR"}`, string(out))
+
+	var m Money
+	require.NoError(t, json.Unmarshal([]byte(`{"amount": 25000.5, "currency": "eur"}`), &m))
+	assert.Equal(t, Money{Amount: 2500050, Currency: "EUR"}, m)
+
+	// bare numbers are in the default currency
+	require.NoError(t, json.Unmarshal([]byte(`25000`), &m))
+	assert.Equal(t, Money{Amount: 2500000, Currency: DefaultCurrency}, m)
+
+	out, err = json.Marshal(Money{})
+	require.NoError(t, err)
+	assert.Equal(t, "null", string(out))
+	require.NoError(t, json.Unmarshal(out, &m))
+	assert.Equal(t, Money{}, m)
+
+	assert.Error(t, json.Unmarshal([]byte(`{"amount": "1.001", "currency": "USD"}`), &m))
+	assert.Error(t, json.Unmarshal([]byte(`{"amount": "1", "currency": "XYZ"}`), &m))
+}
@@ -0,0 +1,112 @@
+package oidc_test
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/adohong4/carZone/oidc"
+	"github.com/adohong4/carZone/oidc/oidctest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const redirectURL = "http://carzone.test/auth/oidc/callback"
+
+// authorize follows the provider's authorize redirect and returns the
+// parameters it sends back to the callback.
+func authorize(t *testing.T, authURL string) url.Values {
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := client.Get(authURL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+
+	location, err := url.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err)
+	return location.Query()
+}
+
+func TestAuthorizationCodeFlow(t *testing.T) {
+	ctx := context.Background()
+	idp := oidctest.NewIdP()
+	defer idp.Close()
+	idp.User = oidc.Identity{Subject: "u-1", Email: "jane@example.com", EmailVerified: true, Groups: []string{"sales"}}
+
+	provider, err := oidc.Discover(ctx, idp.Config(redirectURL), nil)
+	require.NoError(t, err)
+
+	verifier, challenge, err := oidc.NewCodeVerifier()
+	require.NoError(t, err)
+
+	callback := authorize(t, provider.AuthCodeURL("state-1", "nonce-1", challenge))
+	assert.Equal(t, "state-1", callback.Get("state"))
+
+	identity, err := provider.Exchange(ctx, callback.Get("code"), verifier, "nonce-1")
+	require.NoError(t, err)
+	assert.Equal(t, idp.Issuer(), identity.Issuer)
+	assert.Equal(t, "u-1", identity.Subject)
+	assert.Equal(t, "jane@example.com", identity.Email)
+	assert.True(t, identity.EmailVerified)
+	assert.Equal(t, []string{"sales"}, identity.Groups)
+
+	// codes are single use
+	_, err = provider.Exchange(ctx, callback.Get("code"), verifier, "nonce-1")
+	assert.Error(t, err)
+}
+
+func TestExchangeRequiresCodeVerifier(t *testing.T) {
+	ctx := context.Background()
+	idp := oidctest.NewIdP()
+	defer idp.Close()
+	idp.User = oidc.Identity{Subject: "u-1"}
+
+	provider, err := oidc.Discover(ctx, idp.Config(redirectURL), nil)
+	require.NoError(t, err)
+
+	_, challenge, err := oidc.NewCodeVerifier()
+	require.NoError(t, err)
+	callback := authorize(t, provider.AuthCodeURL("state", "nonce", challenge))
+
+	_, err = provider.Exchange(ctx, callback.Get("code"), "another-verifier", "nonce")
+	assert.Error(t, err)
+}
+
+func TestExchangeRejectsWrongNonce(t *testing.T) {
+	ctx := context.Background()
+	idp := oidctest.NewIdP()
+	defer idp.Close()
+	idp.User = oidc.Identity{Subject: "u-1"}
+	idp.Nonce = "replayed"
+
+	provider, err := oidc.Discover(ctx, idp.Config(redirectURL), nil)
+	require.NoError(t, err)
+
+	verifier, challenge, err := oidc.NewCodeVerifier()
+	require.NoError(t, err)
+	callback := authorize(t, provider.AuthCodeURL("state", "nonce", challenge))
+
+	_, err = provider.Exchange(ctx, callback.Get("code"), verifier, "nonce")
+	assert.ErrorIs(t, err, oidc.ErrInvalidIDToken)
+}
+
+func TestVerifyRejectsForeignAudience(t *testing.T) {
+	ctx := context.Background()
+	idp := oidctest.NewIdP()
+	defer idp.Close()
+	idp.User = oidc.Identity{Subject: "u-1"}
+
+	config := idp.Config(redirectURL)
+	config.ClientID = "other-app"
+	provider, err := oidc.Discover(ctx, config, nil)
+	require.NoError(t, err)
+
+	idToken, err := idp.IDToken("nonce")
+	require.NoError(t, err)
+
+	_, err = provider.Verify(ctx, idToken, "nonce")
+	assert.ErrorIs(t, err, oidc.ErrInvalidIDToken)
+}
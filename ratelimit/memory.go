@@ -0,0 +1,61 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// MemoryStore keeps buckets in process. Each replica enforces its own
+// budget, use a shared store when running more than one.
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	takes   int
+	now     func() time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+func (s *MemoryStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst), updated: now}
+		s.buckets[key] = b
+	}
+
+	tokens, result := take(b.tokens, now.Sub(b.updated), limit)
+	b.tokens = tokens
+	b.updated = now
+
+	s.takes++
+	if s.takes%1000 == 0 {
+		s.sweep(now, limit)
+	}
+	return result, nil
+}
+
+// sweep drops buckets that have refilled completely, they hold no state a
+// fresh bucket would not.
+func (s *MemoryStore) sweep(now time.Time, limit Limit) {
+	full := time.Duration(float64(limit.Burst) / limit.Rate * float64(time.Second))
+	for key, b := range s.buckets {
+		if now.Sub(b.updated) > full {
+			delete(s.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package ratelimit
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// PostgresStore shares buckets between replicas through the
+// rate_limit_bucket table. Each take locks the bucket row, so concurrent
+// requests for the same key are serialized by the database.
+type PostgresStore struct {
+	db *sql.DB
+}
+
+func NewPostgresStore(db *sql.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+func (s *PostgresStore) Take(ctx context.Context, key string, limit Limit) (result Result, err error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return Result{}, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO rate_limit_bucket (key, tokens, updated_at) VALUES ($1, $2, now())
+		ON CONFLICT (key) DO NOTHING`,
+		key, float64(limit.Burst))
+	if err != nil {
+		return Result{}, err
+	}
+
+	var tokens float64
+	var updatedAt, now time.Time
+	err = tx.QueryRowContext(ctx,
+		"SELECT tokens, updated_at, now() FROM rate_limit_bucket WHERE key = $1 FOR UPDATE", key,
+	).Scan(&tokens, &updatedAt, &now)
+	if err != nil {
+		return Result{}, err
+	}
+
+	tokens, result = take(tokens, now.Sub(updatedAt), limit)
+
+	_, err = tx.ExecContext(ctx,
+		"UPDATE rate_limit_bucket SET tokens = $2, updated_at = $3 WHERE key = $1",
+		key, tokens, now)
+	if err != nil {
+		return Result{}, err
+	}
+	return result, nil
+}
@@ -0,0 +1,89 @@
+// Package ratelimit implements token-bucket limits with pluggable state
+// stores: in-process for a single replica, Redis or Postgres when several
+// replicas have to share the same budget.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limit is a token bucket holding at most Burst tokens and refilling at
+// Rate tokens per second.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// Result is the outcome of taking one token from a bucket.
+type Result struct {
+	Allowed   bool
+	Remaining int
+	// RetryAfter is how long until the next token is available, zero when allowed.
+	RetryAfter time.Duration
+	// Reset is how long until the bucket is full again.
+	Reset time.Duration
+}
+
+// Store keeps bucket state and takes tokens atomically.
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// ParseLimit reads limits written as "<count>/<unit>", e.g. "5/m" or
+// "100/s"; the count is also the burst. Units are s, m, h and d.
+func ParseLimit(value string) (Limit, error) {
+	parts := strings.SplitN(strings.TrimSpace(value), "/", 2)
+	if len(parts) != 2 {
+		return Limit{}, fmt.Errorf("invalid rate limit %q, expected <count>/<unit>", value)
+	}
+
+	count, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil || count <= 0 {
+		return Limit{}, fmt.Errorf("invalid rate limit count in %q", value)
+	}
+
+	var period time.Duration
+	switch strings.TrimSpace(parts[1]) {
+	case "s":
+		period = time.Second
+	case "m":
+		period = time.Minute
+	case "h":
+		period = time.Hour
+	case "d":
+		period = 24 * time.Hour
+	default:
+		return Limit{}, fmt.Errorf("invalid rate limit unit in %q, use s, m, h or d", value)
+	}
+
+	return Limit{Rate: float64(count) / period.Seconds(), Burst: count}, nil
+}
+
+// take applies one request to a bucket holding tokens that was last
+// refilled elapsed ago. It returns the new token count and the result.
+// Every store funnels through here so they agree on the arithmetic.
+func take(tokens float64, elapsed time.Duration, limit Limit) (float64, Result) {
+	if elapsed > 0 {
+		tokens = math.Min(float64(limit.Burst), tokens+elapsed.Seconds()*limit.Rate)
+	}
+
+	result := Result{}
+	if tokens >= 1 {
+		tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = secondsToDuration((1 - tokens) / limit.Rate)
+	}
+	result.Remaining = int(math.Floor(tokens))
+	result.Reset = secondsToDuration((float64(limit.Burst) - tokens) / limit.Rate)
+	return tokens, result
+}
+
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(math.Ceil(seconds * float64(time.Second)))
+}
@@ -0,0 +1,49 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLimit(t *testing.T) {
+	limit, err := ParseLimit("5/m")
+	require.NoError(t, err)
+	assert.Equal(t, 5, limit.Burst)
+	assert.InDelta(t, 5.0/60, limit.Rate, 1e-9)
+
+	for _, invalid := range []string{"", "5", "0/m", "x/m", "5/w"} {
+		_, err := ParseLimit(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestMemoryStoreTokenBucket(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+	limit := Limit{Rate: 1, Burst: 2}
+
+	first, _ := store.Take(ctx, "ip:1", limit)
+	second, _ := store.Take(ctx, "ip:1", limit)
+	third, _ := store.Take(ctx, "ip:1", limit)
+
+	assert.True(t, first.Allowed)
+	assert.Equal(t, 1, first.Remaining)
+	assert.True(t, second.Allowed)
+	assert.Equal(t, 0, second.Remaining)
+	assert.False(t, third.Allowed)
+	assert.Equal(t, time.Second, third.RetryAfter)
+	assert.Equal(t, 2*time.Second, third.Reset)
+
+	other, _ := store.Take(ctx, "ip:2", limit)
+	assert.True(t, other.Allowed, "buckets are per key")
+
+	now = now.Add(time.Second)
+	refilled, _ := store.Take(ctx, "ip:1", limit)
+	assert.True(t, refilled.Allowed)
+}
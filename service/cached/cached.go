@@ -0,0 +1,118 @@
+// Package cached wraps the car, engine and report services with a
+// read-through cache. Reads go to the cache first, concurrent misses for the
+// same key are collapsed into one call to the wrapped service, and every
+// mutation invalidates the entries it can affect. Keys are scoped to the
+// request's dealership, tenants never share entries.
+package cached
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/adohong4/carZone/cache"
+	"github.com/adohong4/carZone/tenant"
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/sync/singleflight"
+)
+
+var cacheRequests = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "service_cache_requests_total",
+		Help: "Total number of service cache lookups by result",
+	},
+	[]string{"entity", "result"},
+)
+
+func init() {
+	prometheus.MustRegister(cacheRequests)
+}
+
+// loader is the shared read-through logic of the decorators.
+type loader struct {
+	entity string
+	cache  cache.Cache
+	ttl    time.Duration
+	group  singleflight.Group
+}
+
+// load returns the cached value for key or calls fetch, stores its result
+// and decodes it into out. Cache failures are logged and fall through to
+// fetch so a cache outage never takes reads down with it.
+func (l *loader) load(ctx context.Context, key string, out interface{}, fetch func() (interface{}, bool, error)) error {
+	key = scoped(ctx, key)
+	if raw, ok, err := l.cache.Get(ctx, key); err != nil {
+		log.Printf("Cache get %s failed: %v", key, err)
+	} else if ok {
+		if err := json.Unmarshal(raw, out); err == nil {
+			cacheRequests.WithLabelValues(l.entity, "hit").Inc()
+			return nil
+		}
+	}
+	cacheRequests.WithLabelValues(l.entity, "miss").Inc()
+
+	raw, err, _ := l.group.Do(key, func() (interface{}, error) {
+		// a flight that finished between our Get and Do has filled the key
+		if raw, ok, err := l.cache.Get(ctx, key); err == nil && ok {
+			return raw, nil
+		}
+		value, cacheable, err := fetch()
+		if err != nil {
+			return nil, err
+		}
+		raw, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		if cacheable {
+			if err := l.cache.Set(ctx, key, raw, l.ttl); err != nil {
+				log.Printf("Cache set %s failed: %v", key, err)
+			}
+		}
+		return raw, nil
+	})
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw.([]byte), out)
+}
+
+// generation returns the current token for a group of keys. Bumping the
+// token orphans every key built from the previous one, which is how whole
+// groups are invalidated with nothing more than Get/Set on the backend.
+func (l *loader) generation(ctx context.Context, name string) string {
+	raw, ok, err := l.cache.Get(ctx, scoped(ctx, name))
+	if err != nil {
+		log.Printf("Cache get %s failed: %v", name, err)
+		return "nocache-" + uuid.NewString()
+	}
+	if ok {
+		return string(raw)
+	}
+	return l.bump(ctx, name)
+}
+
+func (l *loader) bump(ctx context.Context, name string) string {
+	token := uuid.NewString()
+	if err := l.cache.Set(ctx, scoped(ctx, name), []byte(token), 0); err != nil {
+		log.Printf("Cache set %s failed: %v", name, err)
+	}
+	return token
+}
+
+func (l *loader) invalidate(ctx context.Context, keys ...string) {
+	for i, key := range keys {
+		keys[i] = scoped(ctx, key)
+	}
+	if err := l.cache.Delete(ctx, keys...); err != nil {
+		log.Printf("Cache delete %v failed: %v", keys, err)
+	}
+}
+
+// scoped turns a key into its cache key for the dealership of ctx.
+func scoped(ctx context.Context, key string) string {
+	tenantID, _ := tenant.FromContext(ctx)
+	return "carzone:" + tenantID + ":" + key
+}
@@ -0,0 +1,126 @@
+package cached
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/adohong4/carZone/cache"
+	"github.com/adohong4/carZone/models"
+	"github.com/adohong4/carZone/service"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+)
+
+const (
+	// carGeneration covers every car entry, cars embed their engine so any
+	// engine change has to drop them all.
+	carGeneration = "gen:car"
+	// carListGeneration covers the brand listings, which any car change can alter.
+	carListGeneration = "gen:car-list"
+)
+
+type CarService struct {
+	next   service.CarServiceInterface
+	loader *loader
+}
+
+func NewCarService(next service.CarServiceInterface, c cache.Cache, ttl time.Duration) *CarService {
+	return &CarService{
+		next:   next,
+		loader: &loader{entity: "car", cache: c, ttl: ttl},
+	}
+}
+
+func (s *CarService) carKey(ctx context.Context, id string) string {
+	return fmt.Sprintf("car:%s:%s", s.loader.generation(ctx, carGeneration), id)
+}
+
+func (s *CarService) GetCarById(ctx context.Context, id string) (*models.Car, error) {
+	tracer := otel.Tracer("CachedCarService")
+	ctx, span := tracer.Start(ctx, "GetCarById-Cache")
+	defer span.End()
+
+	var car models.Car
+	err := s.loader.load(ctx, s.carKey(ctx, id), &car, func() (interface{}, bool, error) {
+		found, err := s.next.GetCarById(ctx, id)
+		if err != nil {
+			return nil, false, err
+		}
+		// an empty car means "not found", do not pin that in the cache
+		return found, found.ID != uuid.Nil, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &car, nil
+}
+
+func (s *CarService) GetCarByBrand(ctx context.Context, brand string, isEngine bool) ([]models.Car, error) {
+	tracer := otel.Tracer("CachedCarService")
+	ctx, span := tracer.Start(ctx, "GetCarByBrand-Cache")
+	defer span.End()
+
+	key := fmt.Sprintf("cars:%s:%s:%t:%s",
+		s.loader.generation(ctx, carGeneration), s.loader.generation(ctx, carListGeneration), isEngine, brand)
+
+	var cars []models.Car
+	err := s.loader.load(ctx, key, &cars, func() (interface{}, bool, error) {
+		found, err := s.next.GetCarByBrand(ctx, brand, isEngine)
+		return found, err == nil, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return cars, nil
+}
+
+func (s *CarService) CompareCars(ctx context.Context, ids []string) ([]models.Car, error) {
+	return s.next.CompareCars(ctx, ids)
+}
+
+func (s *CarService) CreateCar(ctx context.Context, carReq *models.CarRequest) (*models.Car, error) {
+	created, err := s.next.CreateCar(ctx, carReq)
+	if err != nil {
+		return nil, err
+	}
+	s.loader.bump(ctx, carListGeneration)
+	return created, nil
+}
+
+func (s *CarService) UpdateCar(ctx context.Context, id string, carReq *models.CarRequest) (*models.Car, error) {
+	key := s.carKey(ctx, id)
+	updated, err := s.next.UpdateCar(ctx, id, carReq)
+	if err != nil {
+		return nil, err
+	}
+	s.loader.invalidate(ctx, key)
+	s.loader.bump(ctx, carListGeneration)
+	return updated, nil
+}
+
+func (s *CarService) DeleteCar(ctx context.Context, id string) (*models.Car, error) {
+	key := s.carKey(ctx, id)
+	deleted, err := s.next.DeleteCar(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	s.loader.invalidate(ctx, key)
+	s.loader.bump(ctx, carListGeneration)
+	return deleted, nil
+}
+
+// InvalidateCars drops the cars of ids and the brand listings, for changes
+// made below the service such as scheduled prices.
+func (s *CarService) InvalidateCars(ctx context.Context, ids ...string) {
+	keys := make([]string, 0, len(ids))
+	for _, id := range ids {
+		keys = append(keys, s.carKey(ctx, id))
+	}
+	s.loader.invalidate(ctx, keys...)
+	s.loader.bump(ctx, carListGeneration)
+}
+
+func (s *CarService) ValidateCar(ctx context.Context, carReq *models.CarRequest) (*models.ValidationResult, error) {
+	return s.next.ValidateCar(ctx, carReq)
+}
@@ -0,0 +1,239 @@
+package cached
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/adohong4/carZone/cache"
+	"github.com/adohong4/carZone/models"
+	"github.com/adohong4/carZone/tenant"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeCarService struct {
+	calls atomic.Int32
+	delay time.Duration
+	car   models.Car
+}
+
+func (f *fakeCarService) GetCarById(ctx context.Context, id string) (*models.Car, error) {
+	f.calls.Add(1)
+	time.Sleep(f.delay)
+	car := f.car
+	return &car, nil
+}
+
+func (f *fakeCarService) GetCarByBrand(ctx context.Context, brand string, isEngine bool) ([]models.Car, error) {
+	f.calls.Add(1)
+	return []models.Car{f.car}, nil
+}
+
+func (f *fakeCarService) CompareCars(ctx context.Context, ids []string) ([]models.Car, error) {
+	f.calls.Add(1)
+	return []models.Car{f.car, f.car}, nil
+}
+
+func (f *fakeCarService) CreateCar(ctx context.Context, carReq *models.CarRequest) (*models.Car, error) {
+	car := f.car
+	return &car, nil
+}
+
+func (f *fakeCarService) UpdateCar(ctx context.Context, id string, carReq *models.CarRequest) (*models.Car, error) {
+	f.car.Name = carReq.Name
+	car := f.car
+	return &car, nil
+}
+
+func (f *fakeCarService) DeleteCar(ctx context.Context, id string) (*models.Car, error) {
+	car := f.car
+	return &car, nil
+}
+
+func (f *fakeCarService) ValidateCar(ctx context.Context, carReq *models.CarRequest) (*models.ValidationResult, error) {
+	return &models.ValidationResult{Valid: true, Violations: []models.Violation{}}, nil
+}
+
+type fakeEngineService struct{}
+
+func (fakeEngineService) GetEngineById(ctx context.Context, id string) (*models.Engine, error) {
+	return &models.Engine{}, nil
+}
+
+func (fakeEngineService) CreateEngine(ctx context.Context, engineReq *models.EngineRequest) (*models.Engine, error) {
+	return &models.Engine{}, nil
+}
+
+func (fakeEngineService) UpdateEngine(ctx context.Context, id string, engineReq *models.EngineRequest) (*models.Engine, error) {
+	return &models.Engine{EngineID: uuid.MustParse(id)}, nil
+}
+
+func (fakeEngineService) DeleteEngine(ctx context.Context, id string) (*models.Engine, error) {
+	return &models.Engine{}, nil
+}
+
+func TestGetCarByIdIsCached(t *testing.T) {
+	ctx := tenant.WithTenant(context.Background(), tenant.DefaultID)
+	next := &fakeCarService{car: models.Car{ID: uuid.New(), Name: "Camry"}}
+	svc := NewCarService(next, cache.NewLRU(100), time.Minute)
+
+	for i := 0; i < 3; i++ {
+		car, err := svc.GetCarById(ctx, next.car.ID.String())
+		require.NoError(t, err)
+		assert.Equal(t, "Camry", car.Name)
+	}
+	assert.Equal(t, int32(1), next.calls.Load())
+}
+
+func TestCacheIsScopedByTenant(t *testing.T) {
+	next := &fakeCarService{car: models.Car{ID: uuid.New(), Name: "Camry"}}
+	svc := NewCarService(next, cache.NewLRU(100), time.Minute)
+	id := next.car.ID.String()
+
+	_, err := svc.GetCarById(tenant.WithTenant(context.Background(), tenant.DefaultID), id)
+	require.NoError(t, err)
+	_, err = svc.GetCarById(tenant.WithTenant(context.Background(), "00000000-0000-0000-0000-000000000002"), id)
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), next.calls.Load())
+}
+
+func TestUpdateCarInvalidates(t *testing.T) {
+	ctx := tenant.WithTenant(context.Background(), tenant.DefaultID)
+	next := &fakeCarService{car: models.Car{ID: uuid.New(), Name: "Camry", Brand: "Toyota"}}
+	svc := NewCarService(next, cache.NewLRU(100), time.Minute)
+	id := next.car.ID.String()
+
+	_, err := svc.GetCarById(ctx, id)
+	require.NoError(t, err)
+	_, err = svc.GetCarByBrand(ctx, "Toyota", false)
+	require.NoError(t, err)
+
+	_, err = svc.UpdateCar(ctx, id, &models.CarRequest{Name: "Corolla"})
+	require.NoError(t, err)
+
+	car, err := svc.GetCarById(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, "Corolla", car.Name)
+
+	cars, err := svc.GetCarByBrand(ctx, "Toyota", false)
+	require.NoError(t, err)
+	assert.Equal(t, "Corolla", cars[0].Name)
+	assert.Equal(t, int32(4), next.calls.Load())
+}
+
+func TestInvalidateCars(t *testing.T) {
+	ctx := tenant.WithTenant(context.Background(), tenant.DefaultID)
+	backend := cache.NewLRU(100)
+	next := &fakeCarService{car: models.Car{ID: uuid.New(), Name: "Camry", Brand: "Toyota"}}
+	svc := NewCarService(next, backend, time.Minute)
+	reportsNext := &fakeReportService{}
+	reports := NewReportService(reportsNext, backend, time.Minute)
+	id := next.car.ID.String()
+
+	_, err := svc.GetCarById(ctx, id)
+	require.NoError(t, err)
+	_, err = svc.GetCarByBrand(ctx, "Toyota", false)
+	require.NoError(t, err)
+	_, err = reports.InventoryReport(ctx, models.InventoryReportRequest{GroupBy: []string{"brand"}})
+	require.NoError(t, err)
+
+	// the price is changed below the cache
+	next.car.Name = "Camry Hybrid"
+	svc.InvalidateCars(ctx, id)
+
+	car, err := svc.GetCarById(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, "Camry Hybrid", car.Name)
+	cars, err := svc.GetCarByBrand(ctx, "Toyota", false)
+	require.NoError(t, err)
+	assert.Equal(t, "Camry Hybrid", cars[0].Name)
+	assert.Equal(t, int32(4), next.calls.Load())
+	_, err = reports.InventoryReport(ctx, models.InventoryReportRequest{GroupBy: []string{"brand"}})
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), reportsNext.calls.Load())
+}
+
+func TestEngineChangeInvalidatesCars(t *testing.T) {
+	ctx := tenant.WithTenant(context.Background(), tenant.DefaultID)
+	backend := cache.NewLRU(100)
+	next := &fakeCarService{car: models.Car{ID: uuid.New(), Name: "Camry"}}
+	cars := NewCarService(next, backend, time.Minute)
+	engines := NewEngineService(fakeEngineService{}, backend, time.Minute)
+
+	_, err := cars.GetCarById(ctx, next.car.ID.String())
+	require.NoError(t, err)
+
+	_, err = engines.UpdateEngine(ctx, uuid.NewString(), &models.EngineRequest{})
+	require.NoError(t, err)
+
+	_, err = cars.GetCarById(ctx, next.car.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), next.calls.Load())
+}
+
+func TestConcurrentMissesAreCollapsed(t *testing.T) {
+	ctx := tenant.WithTenant(context.Background(), tenant.DefaultID)
+	next := &fakeCarService{car: models.Car{ID: uuid.New(), Name: "Camry"}, delay: 50 * time.Millisecond}
+	svc := NewCarService(next, cache.NewLRU(100), time.Minute)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			car, err := svc.GetCarById(ctx, next.car.ID.String())
+			assert.NoError(t, err)
+			assert.Equal(t, "Camry", car.Name)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), next.calls.Load())
+}
+
+func TestMissingCarIsNotCached(t *testing.T) {
+	ctx := tenant.WithTenant(context.Background(), tenant.DefaultID)
+	next := &fakeCarService{}
+	svc := NewCarService(next, cache.NewLRU(100), time.Minute)
+	id := uuid.NewString()
+
+	_, err := svc.GetCarById(ctx, id)
+	require.NoError(t, err)
+	_, err = svc.GetCarById(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), next.calls.Load())
+}
+
+type fakeReportService struct {
+	calls atomic.Int32
+}
+
+func (f *fakeReportService) InventoryReport(ctx context.Context, reportReq models.InventoryReportRequest) (*models.InventoryReport, error) {
+	f.calls.Add(1)
+	return &models.InventoryReport{Rows: []models.InventoryReportRow{{Brand: "Toyota", Currency: "USD", Cars: 1}}}, nil
+}
+
+func TestReportIsCachedUntilCarsChange(t *testing.T) {
+	ctx := tenant.WithTenant(context.Background(), tenant.DefaultID)
+	backend := cache.NewLRU(100)
+	next := &fakeReportService{}
+	reports := NewReportService(next, backend, time.Minute)
+	cars := NewCarService(&fakeCarService{car: models.Car{ID: uuid.New(), Name: "Camry"}}, backend, time.Minute)
+
+	// the same query in another spelling shares the entry
+	for _, groupBy := range [][]string{{"year", "brand"}, {"brand", " year", "brand"}} {
+		report, err := reports.InventoryReport(ctx, models.InventoryReportRequest{GroupBy: groupBy, From: "2024-01-01"})
+		require.NoError(t, err)
+		assert.Equal(t, "Toyota", report.Rows[0].Brand)
+	}
+	assert.Equal(t, int32(1), next.calls.Load())
+
+	_, err := cars.CreateCar(ctx, &models.CarRequest{Name: "Corolla"})
+	require.NoError(t, err)
+	_, err = reports.InventoryReport(ctx, models.InventoryReportRequest{GroupBy: []string{"brand", "year"}, From: "2024-01-01"})
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), next.calls.Load())
+}
@@ -0,0 +1,78 @@
+package cached
+
+import (
+	"context"
+	"time"
+
+	"github.com/adohong4/carZone/cache"
+	"github.com/adohong4/carZone/models"
+	"github.com/adohong4/carZone/service"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+)
+
+type EngineService struct {
+	next   service.EngineServiceInterface
+	loader *loader
+}
+
+// NewEngineService must be given the same cache as the car decorator so
+// engine changes can invalidate the cars embedding them.
+func NewEngineService(next service.EngineServiceInterface, c cache.Cache, ttl time.Duration) *EngineService {
+	return &EngineService{
+		next:   next,
+		loader: &loader{entity: "engine", cache: c, ttl: ttl},
+	}
+}
+
+func engineKey(id string) string {
+	return "engine:" + id
+}
+
+func (s *EngineService) GetEngineById(ctx context.Context, id string) (*models.Engine, error) {
+	tracer := otel.Tracer("CachedEngineService")
+	ctx, span := tracer.Start(ctx, "GetEngineById-Cache")
+	defer span.End()
+
+	var engine models.Engine
+	err := s.loader.load(ctx, engineKey(id), &engine, func() (interface{}, bool, error) {
+		found, err := s.next.GetEngineById(ctx, id)
+		if err != nil {
+			return nil, false, err
+		}
+		return found, found.EngineID != uuid.Nil, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &engine, nil
+}
+
+func (s *EngineService) CreateEngine(ctx context.Context, engineReq *models.EngineRequest) (*models.Engine, error) {
+	return s.next.CreateEngine(ctx, engineReq)
+}
+
+func (s *EngineService) UpdateEngine(ctx context.Context, id string, engineReq *models.EngineRequest) (*models.Engine, error) {
+	updated, err := s.next.UpdateEngine(ctx, id, engineReq)
+	if err != nil {
+		return nil, err
+	}
+	s.invalidate(ctx, id)
+	return updated, nil
+}
+
+func (s *EngineService) DeleteEngine(ctx context.Context, id string) (*models.Engine, error) {
+	deleted, err := s.next.DeleteEngine(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	s.invalidate(ctx, id)
+	return deleted, nil
+}
+
+// invalidate drops the engine and, because cars embed their engine, every
+// cached car and car listing as well.
+func (s *EngineService) invalidate(ctx context.Context, id string) {
+	s.loader.invalidate(ctx, engineKey(id))
+	s.loader.bump(ctx, carGeneration)
+}
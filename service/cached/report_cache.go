@@ -0,0 +1,55 @@
+package cached
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/adohong4/carZone/cache"
+	"github.com/adohong4/carZone/models"
+	"github.com/adohong4/carZone/service"
+	"go.opentelemetry.io/otel"
+)
+
+type ReportService struct {
+	next   service.ReportServiceInterface
+	loader *loader
+}
+
+// NewReportService must be given the same cache as the car decorator:
+// reports are keyed on the car generations so car and engine changes made
+// through the cached services, and scheduled prices, drop them. Stock moves
+// do not, they show once the entries expire after ttl.
+func NewReportService(next service.ReportServiceInterface, c cache.Cache, ttl time.Duration) *ReportService {
+	return &ReportService{
+		next:   next,
+		loader: &loader{entity: "report", cache: c, ttl: ttl},
+	}
+}
+
+func (s *ReportService) InventoryReport(ctx context.Context, reportReq models.InventoryReportRequest) (*models.InventoryReport, error) {
+	tracer := otel.Tracer("CachedReportService")
+	ctx, span := tracer.Start(ctx, "InventoryReport-Cache")
+	defer span.End()
+
+	query, err := models.ValidateInventoryReport(reportReq)
+	if err != nil {
+		// let the service report the invalid request
+		return s.next.InventoryReport(ctx, reportReq)
+	}
+	// requests that differ only in spelling share the entry of their query
+	key := fmt.Sprintf("report:inventory:%s:%s:%s:%d:%d",
+		s.loader.generation(ctx, carGeneration), s.loader.generation(ctx, carListGeneration),
+		strings.Join(query.GroupBy, ","), query.From.Unix(), query.To.Unix())
+
+	var report models.InventoryReport
+	err = s.loader.load(ctx, key, &report, func() (interface{}, bool, error) {
+		found, err := s.next.InventoryReport(ctx, reportReq)
+		return found, err == nil, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
@@ -2,20 +2,105 @@ package car
 
 import (
 	"context"
+	"strings"
 
 	"github.com/adohong4/carZone/models"
+	"github.com/adohong4/carZone/service"
 	"github.com/adohong4/carZone/store"
+	"github.com/google/uuid"
 	"go.opentelemetry.io/otel"
 )
 
+// InvalidError is returned when a car request fails validation. Reason
+// holds the messages of all the Violations.
+type InvalidError struct {
+	Reason     string
+	Violations []models.Violation
+}
+
+func (e *InvalidError) Error() string {
+	return e.Reason
+}
+
+func invalid(violations []models.Violation) error {
+	messages := make([]string, len(violations))
+	for i, violation := range violations {
+		messages[i] = violation.Message
+	}
+	return &InvalidError{Reason: strings.Join(messages, "; "), Violations: violations}
+}
+
 type CarService struct {
-	store store.CarStoreInterface
+	store     store.CarStoreInterface
+	engines   store.EngineStoreInterface
+	catalogue store.CatalogueStoreInterface
+	rules     service.ValidationServiceInterface
 }
 
-func NewCarService(store store.CarStoreInterface) *CarService {
+func NewCarService(store store.CarStoreInterface, engines store.EngineStoreInterface, catalogue store.CatalogueStoreInterface, rules service.ValidationServiceInterface) *CarService {
 	return &CarService{
-		store: store,
+		store:     store,
+		engines:   engines,
+		catalogue: catalogue,
+		rules:     rules,
+	}
+}
+
+// resolveBrand looks the brand of the car up in the catalogue and gives the
+// car the canonical brand name, and model name when the model is listed.
+// It returns nil when the brand is not in the catalogue.
+func (s *CarService) resolveBrand(ctx context.Context, carReq *models.CarRequest) (*models.Brand, error) {
+	if carReq.Brand == "" {
+		return nil, nil
+	}
+	brand, err := s.catalogue.FindBrand(ctx, carReq.Brand)
+	if err != nil {
+		return nil, err
+	}
+	if brand.ID == uuid.Nil {
+		return nil, nil
+	}
+	carReq.Brand = brand.Name
+	if model, ok := brand.Model(carReq.Name); ok {
+		carReq.Name = model.Name
+	}
+	return &brand, nil
+}
+
+// withStoredEngine replaces the engine sent with the car by the stored one,
+// so the powertrain checked against the fuel type is the one the car gets.
+// An unknown engine is left for the store to refuse.
+func (s *CarService) withStoredEngine(ctx context.Context, carReq *models.CarRequest) error {
+	if carReq.Engine.EngineID == uuid.Nil {
+		return nil
+	}
+	engine, err := s.engines.EngineById(ctx, carReq.Engine.EngineID.String())
+	if err != nil {
+		return err
+	}
+	if engine.EngineID != uuid.Nil {
+		carReq.Engine = engine
+	}
+	return nil
+}
+
+// validate resolves the engine and brand of the request, then runs the
+// built-in checks and the validation rules of the dealership. It returns
+// every check the request fails.
+func (s *CarService) validate(ctx context.Context, carReq *models.CarRequest) ([]models.Violation, error) {
+	if err := s.withStoredEngine(ctx, carReq); err != nil {
+		return nil, err
+	}
+	brand, err := s.resolveBrand(ctx, carReq)
+	if err != nil {
+		return nil, err
+	}
+	violations := models.RequestViolations(*carReq, brand)
+	ruleViolations, err := s.rules.CheckCar(ctx, *carReq)
+	if err != nil {
+		return nil, err
 	}
+	return append(violations, ruleViolations...), nil
 }
 
 func (s *CarService) GetCarById(ctx context.Context, id string) (*models.Car, error) {
@@ -35,6 +120,15 @@ func (s *CarService) GetCarByBrand(ctx context.Context, brand string, isEngine b
 	ctx, span := tracer.Start(ctx, "GetCarByBrand-Service")
 	defer span.End()
 
+	// any spelling of the brand finds its cars
+	found, err := s.catalogue.FindBrand(ctx, brand)
+	if err != nil {
+		return nil, err
+	}
+	if found.ID != uuid.Nil {
+		brand = found.Name
+	}
+
 	cars, err := s.store.GetCarByBrand(ctx, brand, isEngine)
 	if err != nil {
 		return nil, err
@@ -42,14 +136,47 @@ func (s *CarService) GetCarByBrand(ctx context.Context, brand string, isEngine b
 	return cars, nil
 }
 
+// CompareCars fetches the cars of ids in that order, in a single query.
+func (s *CarService) CompareCars(ctx context.Context, ids []string) ([]models.Car, error) {
+	tracer := otel.Tracer("CarService")
+	ctx, span := tracer.Start(ctx, "CompareCars-Service")
+	defer span.End()
+
+	if err := models.ValidateComparedIDs(ids); err != nil {
+		return nil, &InvalidError{Reason: err.Error()}
+	}
+	found, err := s.store.GetCarsByIds(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[uuid.UUID]models.Car, len(found))
+	for _, car := range found {
+		byID[car.ID] = car
+	}
+
+	cars := make([]models.Car, len(ids))
+	for i, id := range ids {
+		car, ok := byID[uuid.MustParse(id)]
+		if !ok {
+			return nil, store.ErrCarNotFound
+		}
+		cars[i] = car
+	}
+	return cars, nil
+}
+
 func (s *CarService) CreateCar(ctx context.Context, car *models.CarRequest) (*models.Car, error) {
 	tracer := otel.Tracer("CarService")
 	ctx, span := tracer.Start(ctx, "CreateCar-Service")
 	defer span.End()
 
-	if err := models.ValidateRequest(*car); err != nil {
+	violations, err := s.validate(ctx, car)
+	if err != nil {
 		return nil, err
 	}
+	if len(violations) > 0 {
+		return nil, invalid(violations)
+	}
 
 	createdCar, err := s.store.CreateCar(ctx, car)
 	if err != nil {
@@ -63,9 +190,13 @@ func (s *CarService) UpdateCar(ctx context.Context, id string, carReq *models.Ca
 	ctx, span := tracer.Start(ctx, "UpdateCar-Service")
 	defer span.End()
 
-	if err := models.ValidateRequest(*carReq); err != nil {
+	violations, err := s.validate(ctx, carReq)
+	if err != nil {
 		return nil, err
 	}
+	if len(violations) > 0 {
+		return nil, invalid(violations)
+	}
 
 	updatedCar, err := s.store.UpdateCar(ctx, id, *&carReq)
 	if err != nil {
@@ -85,3 +216,20 @@ func (s *CarService) DeleteCar(ctx context.Context, id string) (*models.Car, err
 	}
 	return &deletedCar, nil
 }
+
+// ValidateCar is a dry run of CreateCar, it reports every violation and
+// saves nothing.
+func (s *CarService) ValidateCar(ctx context.Context, carReq *models.CarRequest) (*models.ValidationResult, error) {
+	tracer := otel.Tracer("CarService")
+	ctx, span := tracer.Start(ctx, "ValidateCar-Service")
+	defer span.End()
+
+	violations, err := s.validate(ctx, carReq)
+	if err != nil {
+		return nil, err
+	}
+	if violations == nil {
+		violations = []models.Violation{}
+	}
+	return &models.ValidationResult{Valid: len(violations) == 0, Violations: violations}, nil
+}
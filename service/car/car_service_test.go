@@ -0,0 +1,155 @@
+package car
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/adohong4/carZone/decimal"
+	"github.com/adohong4/carZone/models"
+	"github.com/adohong4/carZone/money"
+	validationService "github.com/adohong4/carZone/service/validation"
+	"github.com/adohong4/carZone/store"
+	"github.com/adohong4/carZone/store/memory"
+	"github.com/adohong4/carZone/tenant"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestService(t *testing.T) (*CarService, context.Context) {
+	memStore := memory.New()
+	return NewCarService(memStore, memStore, memStore, validationService.NewValidationService(memStore, nil)),
+		tenant.WithTenant(context.Background(), tenant.DefaultID)
+}
+
+func createCar(t *testing.T, ctx context.Context, svc *CarService, brand string, price int64, engineReq models.EngineRequest, fuelType string) models.Car {
+	engine, err := svc.engines.CreateEngine(ctx, &engineReq)
+	require.NoError(t, err)
+	car, err := svc.CreateCar(ctx, &models.CarRequest{
+		Name: "Model", Year: "2023", Brand: brand, FuelType: fuelType,
+		Engine: models.Engine{EngineID: engine.EngineID},
+		Price:  money.Money{Amount: price, Currency: "USD"},
+	})
+	require.NoError(t, err)
+	return *car
+}
+
+func value(values []*string, i int) string {
+	if values[i] == nil {
+		return "<nil>"
+	}
+	return *values[i]
+}
+
+func TestCompareCars(t *testing.T) {
+	svc, ctx := newTestService(t)
+	battery := decimal.New(75, 0)
+	petrol := createCar(t, ctx, svc, "Toyota", 2500000, models.EngineRequest{Displacement: 2500, NoOfCylinders: 4, CarRange: 600}, "Petrol")
+	electric := createCar(t, ctx, svc, "Tesla", 4000000,
+		models.EngineRequest{Type: models.EngineElectric, CarRange: 500, MotorPower: 250, BatteryCapacity: &battery}, "Electric")
+	cheap := createCar(t, ctx, svc, "Kia", 1800000, models.EngineRequest{Displacement: 1600, NoOfCylinders: 4, CarRange: 600}, "Petrol")
+
+	cars, err := svc.CompareCars(ctx, []string{electric.ID.String(), petrol.ID.String(), cheap.ID.String()})
+	require.NoError(t, err)
+	// the cars come in the order asked for
+	require.Len(t, cars, 3)
+	assert.Equal(t, []uuid.UUID{electric.ID, petrol.ID, cheap.ID}, []uuid.UUID{cars[0].ID, cars[1].ID, cars[2].ID})
+
+	rows := make(map[string]models.ComparisonRow)
+	for _, row := range models.CompareCars(cars).Rows {
+		require.Len(t, row.Values, 3)
+		rows[row.Field] = row
+	}
+
+	price := rows["price"]
+	assert.True(t, price.Differs)
+	assert.Equal(t, "40000.00", value(price.Values, 0))
+	assert.Equal(t, "-22000", value(price.Differences, 2))
+	assert.Equal(t, []int{2}, price.Best)
+
+	carRange := rows["engine.carRange"]
+	assert.Equal(t, []int{1, 2}, carRange.Best)
+	assert.Equal(t, "100", value(carRange.Differences, 1))
+
+	displacement := rows["engine.displacement"]
+	assert.Equal(t, "<nil>", value(displacement.Values, 0))
+	assert.Equal(t, []int{1}, displacement.Best)
+	assert.Equal(t, "<nil>", value(displacement.Differences, 1))
+
+	year := rows["year"]
+	assert.False(t, year.Differs)
+	assert.Nil(t, year.Best)
+	assert.Equal(t, "0", value(year.Differences, 2))
+
+	assert.True(t, rows["engine.type"].Differs)
+	assert.Nil(t, rows["engine.type"].Differences)
+}
+
+func TestCompareCarsInvalid(t *testing.T) {
+	svc, ctx := newTestService(t)
+	car := createCar(t, ctx, svc, "Toyota", 2500000, models.EngineRequest{Displacement: 2500, NoOfCylinders: 4, CarRange: 600}, "Petrol")
+
+	for _, ids := range [][]string{
+		{car.ID.String()},
+		{car.ID.String(), car.ID.String()},
+		{car.ID.String(), "not-an-id"},
+		{uuid.NewString(), uuid.NewString(), uuid.NewString(), uuid.NewString(), uuid.NewString()},
+	} {
+		_, err := svc.CompareCars(ctx, ids)
+		var invalidErr *InvalidError
+		assert.True(t, errors.As(err, &invalidErr), ids)
+	}
+
+	_, err := svc.CompareCars(ctx, []string{car.ID.String(), uuid.NewString()})
+	assert.ErrorIs(t, err, store.ErrCarNotFound)
+}
+
+func TestValidateCar(t *testing.T) {
+	svc, ctx := newTestService(t)
+	_, err := svc.rules.SetRules(ctx, &models.ValidationRulesRequest{Rules: []models.ValidationRule{
+		{ID: "price-ceiling", Target: models.RuleTargetCar, Field: "price", Check: models.CheckMax, Value: "30000"},
+	}})
+	require.NoError(t, err)
+
+	result, err := svc.ValidateCar(ctx, &models.CarRequest{
+		Year: "1700", Brand: "toyota", FuelType: "Petrol",
+		Price: money.Money{Amount: 4000000, Currency: "USD"},
+	})
+	require.NoError(t, err)
+	assert.False(t, result.Valid)
+	fields := make([]string, len(result.Violations))
+	for i, violation := range result.Violations {
+		fields[i] = violation.Field + "/" + violation.Rule
+	}
+	assert.Equal(t, []string{"name/builtin", "year/builtin", "engine/builtin", "price/price-ceiling"}, fields)
+
+	// nothing was saved
+	cars, err := svc.GetCarByBrand(ctx, "Toyota", false)
+	require.NoError(t, err)
+	assert.Empty(t, cars)
+}
+
+func TestCreateCarNextModelYear(t *testing.T) {
+	svc, ctx := newTestService(t)
+	engine, err := svc.engines.CreateEngine(ctx, &models.EngineRequest{Displacement: 2000, NoOfCylinders: 4, CarRange: 600})
+	require.NoError(t, err)
+	carReq := func(year int) *models.CarRequest {
+		return &models.CarRequest{
+			Name: "Camry", Year: strconv.Itoa(year), Brand: "Toyota", FuelType: "Petrol",
+			Engine: models.Engine{EngineID: engine.EngineID},
+			Price:  money.Money{Amount: 2500000, Currency: "USD"},
+		}
+	}
+
+	// next year's models are on sale already, as vin.ModelYear decodes them
+	nextYear := time.Now().Year() + 1
+	_, err = svc.CreateCar(ctx, carReq(nextYear))
+	assert.NoError(t, err)
+
+	_, err = svc.CreateCar(ctx, carReq(nextYear+1))
+	var invalidErr *InvalidError
+	assert.True(t, errors.As(err, &invalidErr))
+}
@@ -0,0 +1,271 @@
+package catalogue
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/adohong4/carZone/models"
+	"github.com/adohong4/carZone/store"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+)
+
+var (
+	ErrBrandNotFound = errors.New("brand not found")
+	ErrModelNotFound = errors.New("model not found")
+	// ErrNameTaken is returned when a name or alias is already used by
+	// another brand, or by another model of the same brand.
+	ErrNameTaken = errors.New("name is already in the catalogue")
+)
+
+// InvalidError is returned when a request fails validation.
+type InvalidError struct {
+	Reason string
+}
+
+func (e *InvalidError) Error() string {
+	return e.Reason
+}
+
+func invalid(err error) error {
+	return &InvalidError{Reason: err.Error()}
+}
+
+// CatalogueService manages the brands and models shared by every
+// dealership.
+type CatalogueService struct {
+	store store.CatalogueStoreInterface
+	now   func() time.Time
+}
+
+func NewCatalogueService(store store.CatalogueStoreInterface) *CatalogueService {
+	return &CatalogueService{
+		store: store,
+		now:   time.Now,
+	}
+}
+
+func (s *CatalogueService) ListBrands(ctx context.Context) ([]models.Brand, error) {
+	tracer := otel.Tracer("CatalogueService")
+	ctx, span := tracer.Start(ctx, "ListBrands-Service")
+	defer span.End()
+
+	brands, err := s.store.ListBrands(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if brands == nil {
+		brands = []models.Brand{}
+	}
+	return brands, nil
+}
+
+func (s *CatalogueService) GetBrand(ctx context.Context, id string) (*models.Brand, error) {
+	tracer := otel.Tracer("CatalogueService")
+	ctx, span := tracer.Start(ctx, "GetBrand-Service")
+	defer span.End()
+
+	brand, err := s.brand(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return &brand, nil
+}
+
+func (s *CatalogueService) brand(ctx context.Context, id string) (models.Brand, error) {
+	brandID, err := uuid.Parse(id)
+	if err != nil {
+		return models.Brand{}, ErrBrandNotFound
+	}
+	brand, err := s.store.GetBrand(ctx, brandID.String())
+	if err != nil {
+		return models.Brand{}, err
+	}
+	if brand.ID == uuid.Nil {
+		return models.Brand{}, ErrBrandNotFound
+	}
+	return brand, nil
+}
+
+// checkBrandNames returns ErrNameTaken when the name or an alias resolves
+// to a brand other than brandID.
+func (s *CatalogueService) checkBrandNames(ctx context.Context, brandID uuid.UUID, brandReq *models.BrandRequest) error {
+	for _, name := range append([]string{brandReq.Name}, brandReq.Aliases...) {
+		found, err := s.store.FindBrand(ctx, name)
+		if err != nil {
+			return err
+		}
+		if found.ID != uuid.Nil && found.ID != brandID {
+			return ErrNameTaken
+		}
+	}
+	return nil
+}
+
+func (s *CatalogueService) CreateBrand(ctx context.Context, brandReq *models.BrandRequest) (*models.Brand, error) {
+	tracer := otel.Tracer("CatalogueService")
+	ctx, span := tracer.Start(ctx, "CreateBrand-Service")
+	defer span.End()
+
+	if err := models.ValidateBrandRequest(*brandReq); err != nil {
+		return nil, invalid(err)
+	}
+	brand := models.Brand{
+		ID:        uuid.New(),
+		Name:      brandReq.Name,
+		Aliases:   brandReq.Aliases,
+		CreatedAt: s.now(),
+	}
+	if err := s.checkBrandNames(ctx, brand.ID, brandReq); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.store.SaveBrand(ctx, brand); err != nil {
+		return nil, err
+	}
+	return s.GetBrand(ctx, brand.ID.String())
+}
+
+// UpdateBrand replaces the name and aliases of a brand, the cars of every
+// dealership follow a new name.
+func (s *CatalogueService) UpdateBrand(ctx context.Context, id string, brandReq *models.BrandRequest) (*models.Brand, error) {
+	tracer := otel.Tracer("CatalogueService")
+	ctx, span := tracer.Start(ctx, "UpdateBrand-Service")
+	defer span.End()
+
+	if err := models.ValidateBrandRequest(*brandReq); err != nil {
+		return nil, invalid(err)
+	}
+	brand, err := s.brand(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkBrandNames(ctx, brand.ID, brandReq); err != nil {
+		return nil, err
+	}
+
+	brand.Name = brandReq.Name
+	brand.Aliases = brandReq.Aliases
+	if _, err := s.store.SaveBrand(ctx, brand); err != nil {
+		return nil, err
+	}
+	return s.GetBrand(ctx, brand.ID.String())
+}
+
+func (s *CatalogueService) DeleteBrand(ctx context.Context, id string) (*models.Brand, error) {
+	tracer := otel.Tracer("CatalogueService")
+	ctx, span := tracer.Start(ctx, "DeleteBrand-Service")
+	defer span.End()
+
+	brandID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, ErrBrandNotFound
+	}
+	deleted, err := s.store.DeleteBrand(ctx, brandID.String())
+	if err != nil {
+		return nil, err
+	}
+	if deleted.ID == uuid.Nil {
+		return nil, ErrBrandNotFound
+	}
+	return &deleted, nil
+}
+
+// checkModelNames returns ErrNameTaken when the name or an alias resolves
+// to a model of the brand other than modelID.
+func checkModelNames(brand models.Brand, modelID uuid.UUID, modelReq *models.CarModelRequest) error {
+	for _, name := range append([]string{modelReq.Name}, modelReq.Aliases...) {
+		if found, ok := brand.Model(name); ok && found.ID != modelID {
+			return ErrNameTaken
+		}
+	}
+	return nil
+}
+
+func (s *CatalogueService) CreateModel(ctx context.Context, brandID string, modelReq *models.CarModelRequest) (*models.CarModel, error) {
+	tracer := otel.Tracer("CatalogueService")
+	ctx, span := tracer.Start(ctx, "CreateModel-Service")
+	defer span.End()
+
+	if err := models.ValidateCarModelRequest(*modelReq); err != nil {
+		return nil, invalid(err)
+	}
+	brand, err := s.brand(ctx, brandID)
+	if err != nil {
+		return nil, err
+	}
+	model := models.CarModel{
+		ID:        uuid.New(),
+		BrandID:   brand.ID,
+		Name:      modelReq.Name,
+		Aliases:   modelReq.Aliases,
+		CreatedAt: s.now(),
+	}
+	if err := checkModelNames(brand, model.ID, modelReq); err != nil {
+		return nil, err
+	}
+
+	saved, err := s.store.SaveCarModel(ctx, model)
+	if err != nil {
+		return nil, err
+	}
+	return &saved, nil
+}
+
+func (s *CatalogueService) UpdateModel(ctx context.Context, brandID, id string, modelReq *models.CarModelRequest) (*models.CarModel, error) {
+	tracer := otel.Tracer("CatalogueService")
+	ctx, span := tracer.Start(ctx, "UpdateModel-Service")
+	defer span.End()
+
+	if err := models.ValidateCarModelRequest(*modelReq); err != nil {
+		return nil, invalid(err)
+	}
+	brand, err := s.brand(ctx, brandID)
+	if err != nil {
+		return nil, err
+	}
+	var model models.CarModel
+	for _, current := range brand.Models {
+		if current.ID.String() == id {
+			model = current
+		}
+	}
+	if model.ID == uuid.Nil {
+		return nil, ErrModelNotFound
+	}
+	if err := checkModelNames(brand, model.ID, modelReq); err != nil {
+		return nil, err
+	}
+
+	model.Name = modelReq.Name
+	model.Aliases = modelReq.Aliases
+	saved, err := s.store.SaveCarModel(ctx, model)
+	if err != nil {
+		return nil, err
+	}
+	return &saved, nil
+}
+
+func (s *CatalogueService) DeleteModel(ctx context.Context, brandID, id string) (*models.CarModel, error) {
+	tracer := otel.Tracer("CatalogueService")
+	ctx, span := tracer.Start(ctx, "DeleteModel-Service")
+	defer span.End()
+
+	parsedBrandID, err := uuid.Parse(brandID)
+	if err != nil {
+		return nil, ErrBrandNotFound
+	}
+	modelID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, ErrModelNotFound
+	}
+	deleted, err := s.store.DeleteCarModel(ctx, parsedBrandID.String(), modelID.String())
+	if err != nil {
+		return nil, err
+	}
+	if deleted.ID == uuid.Nil {
+		return nil, ErrModelNotFound
+	}
+	return &deleted, nil
+}
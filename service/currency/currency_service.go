@@ -0,0 +1,167 @@
+package currency
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/adohong4/carZone/decimal"
+	"github.com/adohong4/carZone/models"
+	"github.com/adohong4/carZone/money"
+	"github.com/adohong4/carZone/store"
+	"go.opentelemetry.io/otel"
+)
+
+// ErrNoRate is returned when a conversion needs a rate nobody has set.
+var ErrNoRate = errors.New("no exchange rate for currency")
+
+// InvalidError is returned when a request fails validation.
+type InvalidError struct {
+	Reason string
+}
+
+func (e *InvalidError) Error() string {
+	return e.Reason
+}
+
+func invalid(err error) error {
+	return &InvalidError{Reason: err.Error()}
+}
+
+// CurrencyService converts prices with rates kept against a single base
+// currency, a conversion between two other currencies goes through it.
+type CurrencyService struct {
+	store store.RateStoreInterface
+	base  string
+	now   func() time.Time
+}
+
+func NewCurrencyService(store store.RateStoreInterface, base string) *CurrencyService {
+	return &CurrencyService{
+		store: store,
+		base:  base,
+		now:   time.Now,
+	}
+}
+
+// ListRates returns the stored rates and the base currency at rate 1.
+func (s *CurrencyService) ListRates(ctx context.Context) ([]models.ExchangeRate, error) {
+	tracer := otel.Tracer("CurrencyService")
+	ctx, span := tracer.Start(ctx, "ListRates-Service")
+	defer span.End()
+
+	rates, err := s.store.GetRates(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return append([]models.ExchangeRate{{Currency: s.base, Rate: decimal.New(1, 0)}}, rates...), nil
+}
+
+func (s *CurrencyService) SetRate(ctx context.Context, currency string, rateReq *models.ExchangeRateRequest) (*models.ExchangeRate, error) {
+	tracer := otel.Tracer("CurrencyService")
+	ctx, span := tracer.Start(ctx, "SetRate-Service")
+	defer span.End()
+
+	currency = strings.ToUpper(currency)
+	if err := money.ValidateCurrency(currency); err != nil {
+		return nil, invalid(err)
+	}
+	if currency == s.base {
+		return nil, &InvalidError{Reason: fmt.Sprintf("%s is the base currency, its rate is always 1", s.base)}
+	}
+	if err := models.ValidateExchangeRateRequest(*rateReq); err != nil {
+		return nil, invalid(err)
+	}
+
+	rate, err := s.store.SetRate(ctx, models.ExchangeRate{Currency: currency, Rate: rateReq.Rate, UpdatedAt: s.now()})
+	if err != nil {
+		return nil, err
+	}
+	return &rate, nil
+}
+
+// Convert changes amount into currency, rounding to its minor unit.
+func (s *CurrencyService) Convert(ctx context.Context, amount money.Money, currency string) (money.Money, error) {
+	tracer := otel.Tracer("CurrencyService")
+	ctx, span := tracer.Start(ctx, "Convert-Service")
+	defer span.End()
+
+	rates, err := s.rates(ctx)
+	if err != nil {
+		return money.Money{}, err
+	}
+	return convert(rates, amount, currency)
+}
+
+// ConvertCars prices cars in currency and keeps the price the dealership
+// set as OriginalPrice, list prices are converted along. Cars already
+// priced in currency are left alone.
+func (s *CurrencyService) ConvertCars(ctx context.Context, cars []models.Car, currency string) ([]models.Car, error) {
+	tracer := otel.Tracer("CurrencyService")
+	ctx, span := tracer.Start(ctx, "ConvertCars-Service")
+	defer span.End()
+
+	currency = strings.ToUpper(currency)
+	if err := money.ValidateCurrency(currency); err != nil {
+		return nil, invalid(err)
+	}
+	rates, err := s.rates(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	converted := make([]models.Car, len(cars))
+	for i, car := range cars {
+		if car.Price.Currency != currency {
+			price, err := convert(rates, car.Price, currency)
+			if err != nil {
+				return nil, err
+			}
+			original := car.Price
+			car.OriginalPrice = &original
+			car.Price = price
+		}
+		if car.ListPrice != nil && car.ListPrice.Currency != currency {
+			listPrice, err := convert(rates, *car.ListPrice, currency)
+			if err != nil {
+				return nil, err
+			}
+			car.ListPrice = &listPrice
+		}
+		converted[i] = car
+	}
+	return converted, nil
+}
+
+// rates maps each known currency, the base included, to its rate.
+func (s *CurrencyService) rates(ctx context.Context) (map[string]decimal.Decimal, error) {
+	stored, err := s.store.GetRates(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rates := map[string]decimal.Decimal{s.base: decimal.New(1, 0)}
+	for _, rate := range stored {
+		rates[rate.Currency] = rate.Rate
+	}
+	return rates, nil
+}
+
+func convert(rates map[string]decimal.Decimal, amount money.Money, currency string) (money.Money, error) {
+	if amount.Currency == currency {
+		return amount, nil
+	}
+	if err := money.ValidateCurrency(currency); err != nil {
+		return money.Money{}, invalid(err)
+	}
+	from, ok := rates[amount.Currency]
+	if !ok {
+		return money.Money{}, fmt.Errorf("%w %s", ErrNoRate, amount.Currency)
+	}
+	to, ok := rates[currency]
+	if !ok {
+		return money.Money{}, fmt.Errorf("%w %s", ErrNoRate, currency)
+	}
+	return amount.Convert(currency, to.Div(from))
+}
@@ -0,0 +1,74 @@
+package currency
+
+import (
+	"context"
+	"testing"
+
+	"github.com/adohong4/carZone/decimal"
+	"github.com/adohong4/carZone/models"
+	"github.com/adohong4/carZone/money"
+	"github.com/adohong4/carZone/store/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestService(t *testing.T) *CurrencyService {
+	ctx := context.Background()
+	svc := NewCurrencyService(memory.New(), "USD")
+	_, err := svc.SetRate(ctx, "eur", &models.ExchangeRateRequest{Rate: decimal.New(8, 1)})
+	require.NoError(t, err)
+	_, err = svc.SetRate(ctx, "VND", &models.ExchangeRateRequest{Rate: decimal.New(25000, 0)})
+	require.NoError(t, err)
+	return svc
+}
+
+func TestSetRateValidation(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestService(t)
+
+	var invalidErr *InvalidError
+	_, err := svc.SetRate(ctx, "USD", &models.ExchangeRateRequest{Rate: decimal.New(1, 0)})
+	assert.ErrorAs(t, err, &invalidErr)
+	_, err = svc.SetRate(ctx, "XYZ", &models.ExchangeRateRequest{Rate: decimal.New(1, 0)})
+	assert.ErrorAs(t, err, &invalidErr)
+	_, err = svc.SetRate(ctx, "GBP", &models.ExchangeRateRequest{Rate: decimal.Zero})
+	assert.ErrorAs(t, err, &invalidErr)
+
+	rates, err := svc.ListRates(ctx)
+	require.NoError(t, err)
+	require.Len(t, rates, 3)
+	assert.Equal(t, "USD", rates[0].Currency)
+	assert.Equal(t, "EUR", rates[1].Currency)
+}
+
+func TestConvertThroughBase(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestService(t)
+
+	vnd, err := svc.Convert(ctx, money.Money{Amount: 1999, Currency: "USD"}, "VND")
+	require.NoError(t, err)
+	assert.Equal(t, money.Money{Amount: 499750, Currency: "VND"}, vnd)
+
+	eur, err := svc.Convert(ctx, money.Money{Amount: 1000000, Currency: "VND"}, "EUR")
+	require.NoError(t, err)
+	assert.Equal(t, money.Money{Amount: 3200, Currency: "EUR"}, eur)
+
+	_, err = svc.Convert(ctx, money.Money{Amount: 100, Currency: "USD"}, "GBP")
+	assert.ErrorIs(t, err, ErrNoRate)
+}
+
+func TestConvertCarsKeepsOriginal(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestService(t)
+
+	cars, err := svc.ConvertCars(ctx, []models.Car{
+		{Name: "Camry", Price: money.Money{Amount: 2500000, Currency: "USD"}},
+		{Name: "Golf", Price: money.Money{Amount: 2000000, Currency: "EUR"}},
+	}, "eur")
+	require.NoError(t, err)
+
+	assert.Equal(t, money.Money{Amount: 2000000, Currency: "EUR"}, cars[0].Price)
+	require.NotNil(t, cars[0].OriginalPrice)
+	assert.Equal(t, money.Money{Amount: 2500000, Currency: "USD"}, *cars[0].OriginalPrice)
+	assert.Nil(t, cars[1].OriginalPrice)
+}
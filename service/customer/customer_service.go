@@ -0,0 +1,285 @@
+package customer
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/adohong4/carZone/models"
+	"github.com/adohong4/carZone/store"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+)
+
+// ErrCustomerErased is returned when changing a customer whose personal
+// data has been erased.
+var ErrCustomerErased = errors.New("customer has been erased")
+
+// InvalidError is returned when a request fails validation.
+type InvalidError struct {
+	Reason string
+}
+
+func (e *InvalidError) Error() string {
+	return e.Reason
+}
+
+func invalid(err error) error {
+	return &InvalidError{Reason: err.Error()}
+}
+
+type CustomerService struct {
+	store        store.CustomerStoreInterface
+	reservations store.ReservationStoreInterface
+	orders       store.OrderStoreInterface
+	now          func() time.Time
+}
+
+func NewCustomerService(store store.CustomerStoreInterface, reservations store.ReservationStoreInterface, orders store.OrderStoreInterface) *CustomerService {
+	return &CustomerService{
+		store:        store,
+		reservations: reservations,
+		orders:       orders,
+		now:          time.Now,
+	}
+}
+
+func (s *CustomerService) GetCustomerById(ctx context.Context, id string) (*models.Customer, error) {
+	tracer := otel.Tracer("CustomerService")
+	ctx, span := tracer.Start(ctx, "GetCustomerById-Service")
+	defer span.End()
+
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, store.ErrCustomerNotFound
+	}
+
+	customer, err := s.store.GetCustomerById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if customer.ID == uuid.Nil {
+		return nil, store.ErrCustomerNotFound
+	}
+	return &customer, nil
+}
+
+func (s *CustomerService) ListCustomers(ctx context.Context, search string) ([]models.Customer, error) {
+	tracer := otel.Tracer("CustomerService")
+	ctx, span := tracer.Start(ctx, "ListCustomers-Service")
+	defer span.End()
+
+	customers, err := s.store.ListCustomers(ctx, search)
+	if err != nil {
+		return nil, err
+	}
+	if customers == nil {
+		customers = []models.Customer{}
+	}
+	return customers, nil
+}
+
+// CreateCustomer records when the consents were given, if any were.
+func (s *CustomerService) CreateCustomer(ctx context.Context, customerReq *models.CustomerRequest) (*models.Customer, error) {
+	tracer := otel.Tracer("CustomerService")
+	ctx, span := tracer.Start(ctx, "CreateCustomer-Service")
+	defer span.End()
+
+	if err := models.ValidateCustomerRequest(*customerReq); err != nil {
+		return nil, invalid(err)
+	}
+
+	now := s.now()
+	customer := models.Customer{
+		ID:               uuid.New(),
+		Name:             customerReq.Name,
+		Email:            customerReq.Email,
+		Phone:            customerReq.Phone,
+		Address:          customerReq.Address,
+		MarketingConsent: customerReq.MarketingConsent,
+		ContactConsent:   customerReq.ContactConsent,
+		CreatedAt:        now,
+		UpdatedAt:        now,
+	}
+	if customer.MarketingConsent || customer.ContactConsent {
+		customer.ConsentUpdatedAt = &now
+	}
+
+	created, err := s.store.CreateCustomer(ctx, customer)
+	if err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+// UpdateCustomer replaces the contact details and consents. The consent
+// time only moves when a consent is given or withdrawn.
+func (s *CustomerService) UpdateCustomer(ctx context.Context, id string, customerReq *models.CustomerRequest) (*models.Customer, error) {
+	tracer := otel.Tracer("CustomerService")
+	ctx, span := tracer.Start(ctx, "UpdateCustomer-Service")
+	defer span.End()
+
+	if err := models.ValidateCustomerRequest(*customerReq); err != nil {
+		return nil, invalid(err)
+	}
+	current, err := s.changeable(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	customer := *current
+	if customer.MarketingConsent != customerReq.MarketingConsent || customer.ContactConsent != customerReq.ContactConsent {
+		customer.ConsentUpdatedAt = &now
+	}
+	customer.Name = customerReq.Name
+	customer.Email = customerReq.Email
+	customer.Phone = customerReq.Phone
+	customer.Address = customerReq.Address
+	customer.MarketingConsent = customerReq.MarketingConsent
+	customer.ContactConsent = customerReq.ContactConsent
+	customer.UpdatedAt = now
+
+	updated, err := s.store.UpdateCustomer(ctx, customer)
+	if err != nil {
+		return nil, err
+	}
+	if updated.ID == uuid.Nil {
+		return nil, store.ErrCustomerNotFound
+	}
+	return &updated, nil
+}
+
+func (s *CustomerService) DeleteCustomer(ctx context.Context, id string) (*models.Customer, error) {
+	tracer := otel.Tracer("CustomerService")
+	ctx, span := tracer.Start(ctx, "DeleteCustomer-Service")
+	defer span.End()
+
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, store.ErrCustomerNotFound
+	}
+
+	deleted, err := s.store.DeleteCustomer(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if deleted.ID == uuid.Nil {
+		return nil, store.ErrCustomerNotFound
+	}
+	return &deleted, nil
+}
+
+// AddNote records a note written by author.
+func (s *CustomerService) AddNote(ctx context.Context, id, author string, noteReq *models.CustomerNoteRequest) (*models.CustomerNote, error) {
+	tracer := otel.Tracer("CustomerService")
+	ctx, span := tracer.Start(ctx, "AddNote-Service")
+	defer span.End()
+
+	if err := models.ValidateCustomerNoteRequest(*noteReq); err != nil {
+		return nil, invalid(err)
+	}
+	if _, err := s.changeable(ctx, id); err != nil {
+		return nil, err
+	}
+
+	note, err := s.store.AddCustomerNote(ctx, id, models.CustomerNote{
+		ID:        uuid.New(),
+		Body:      noteReq.Body,
+		Author:    author,
+		CreatedAt: s.now(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &note, nil
+}
+
+// LinkCar records that the customer viewed, reserved or purchased a car.
+func (s *CustomerService) LinkCar(ctx context.Context, id string, linkReq *models.CustomerCarRequest) (*models.CustomerCar, error) {
+	tracer := otel.Tracer("CustomerService")
+	ctx, span := tracer.Start(ctx, "LinkCar-Service")
+	defer span.End()
+
+	if err := models.ValidateCustomerCarRequest(*linkReq); err != nil {
+		return nil, invalid(err)
+	}
+	if _, err := s.changeable(ctx, id); err != nil {
+		return nil, err
+	}
+
+	link, err := s.store.LinkCustomerCar(ctx, id, models.CustomerCar{
+		CarID:     linkReq.CarID,
+		Kind:      linkReq.Kind,
+		CreatedAt: s.now(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+// Export returns everything kept about the customer, including the
+// reservations and orders linked to it.
+func (s *CustomerService) Export(ctx context.Context, id string) (*models.CustomerExport, error) {
+	tracer := otel.Tracer("CustomerService")
+	ctx, span := tracer.Start(ctx, "Export-Service")
+	defer span.End()
+
+	customer, err := s.GetCustomerById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	reservations, err := s.reservations.ListCustomerReservations(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if reservations == nil {
+		reservations = []models.Reservation{}
+	}
+	orders, err := s.orders.ListCustomerOrders(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if orders == nil {
+		orders = []models.Order{}
+	}
+	return &models.CustomerExport{
+		ExportedAt:   s.now(),
+		Customer:     *customer,
+		Reservations: reservations,
+		Orders:       orders,
+	}, nil
+}
+
+// Erase removes the customer's personal data and notes and blanks the
+// customer details of the linked reservations and orders. The rows and the
+// car links stay so sales statistics keep adding up.
+func (s *CustomerService) Erase(ctx context.Context, id string) (*models.Customer, error) {
+	tracer := otel.Tracer("CustomerService")
+	ctx, span := tracer.Start(ctx, "Erase-Service")
+	defer span.End()
+
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, store.ErrCustomerNotFound
+	}
+
+	erased, err := s.store.EraseCustomer(ctx, id, s.now())
+	if err != nil {
+		return nil, err
+	}
+	if erased.ID == uuid.Nil {
+		return nil, store.ErrCustomerNotFound
+	}
+	return &erased, nil
+}
+
+// changeable returns the customer unless it is missing or erased.
+func (s *CustomerService) changeable(ctx context.Context, id string) (*models.Customer, error) {
+	customer, err := s.GetCustomerById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if customer.ErasedAt != nil {
+		return nil, ErrCustomerErased
+	}
+	return customer, nil
+}
@@ -0,0 +1,81 @@
+package customer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/adohong4/carZone/models"
+	"github.com/adohong4/carZone/store"
+	"github.com/adohong4/carZone/store/memory"
+	"github.com/adohong4/carZone/tenant"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var request = models.CustomerRequest{Name: "Jane Doe", Email: "jane@example.com"}
+
+func newTestService(now *time.Time) *CustomerService {
+	s := memory.New()
+	svc := NewCustomerService(s, s, s)
+	svc.now = func() time.Time { return *now }
+	return svc
+}
+
+func TestConsentTimestamp(t *testing.T) {
+	ctx := tenant.WithTenant(context.Background(), tenant.DefaultID)
+	now := time.Now()
+	svc := newTestService(&now)
+
+	created, err := svc.CreateCustomer(ctx, &request)
+	require.NoError(t, err)
+	assert.Nil(t, created.ConsentUpdatedAt)
+
+	now = now.Add(time.Hour)
+	consenting := request
+	consenting.MarketingConsent = true
+	updated, err := svc.UpdateCustomer(ctx, created.ID.String(), &consenting)
+	require.NoError(t, err)
+	require.NotNil(t, updated.ConsentUpdatedAt)
+	assert.Equal(t, now, *updated.ConsentUpdatedAt)
+
+	// changing only the contact details keeps the consent time
+	later := now
+	now = now.Add(time.Hour)
+	consenting.Phone = "+1 555 0100"
+	updated, err = svc.UpdateCustomer(ctx, created.ID.String(), &consenting)
+	require.NoError(t, err)
+	assert.Equal(t, later, *updated.ConsentUpdatedAt)
+
+	var invalidErr *InvalidError
+	_, err = svc.CreateCustomer(ctx, &models.CustomerRequest{Name: "Jane Doe", Email: "not an address"})
+	assert.ErrorAs(t, err, &invalidErr)
+}
+
+func TestErasedCustomerCannotChange(t *testing.T) {
+	ctx := tenant.WithTenant(context.Background(), tenant.DefaultID)
+	now := time.Now()
+	svc := newTestService(&now)
+
+	created, err := svc.CreateCustomer(ctx, &request)
+	require.NoError(t, err)
+	_, err = svc.AddNote(ctx, created.ID.String(), "alice", &models.CustomerNoteRequest{Body: "Called back"})
+	require.NoError(t, err)
+
+	erased, err := svc.Erase(ctx, created.ID.String())
+	require.NoError(t, err)
+	assert.Empty(t, erased.Name)
+	assert.Empty(t, erased.Notes)
+
+	_, err = svc.UpdateCustomer(ctx, created.ID.String(), &request)
+	assert.ErrorIs(t, err, ErrCustomerErased)
+	_, err = svc.AddNote(ctx, created.ID.String(), "alice", &models.CustomerNoteRequest{Body: "Called back"})
+	assert.ErrorIs(t, err, ErrCustomerErased)
+
+	export, err := svc.Export(ctx, created.ID.String())
+	require.NoError(t, err)
+	assert.Empty(t, export.Customer.Email)
+
+	_, err = svc.Erase(ctx, "not-a-uuid")
+	assert.ErrorIs(t, err, store.ErrCustomerNotFound)
+}
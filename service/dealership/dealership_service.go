@@ -0,0 +1,55 @@
+package dealership
+
+import (
+	"context"
+
+	"github.com/adohong4/carZone/models"
+	"github.com/adohong4/carZone/store"
+	"go.opentelemetry.io/otel"
+)
+
+type DealershipService struct {
+	store store.DealershipStoreInterface
+}
+
+func NewDealershipService(store store.DealershipStoreInterface) *DealershipService {
+	return &DealershipService{
+		store: store,
+	}
+}
+
+func (s *DealershipService) GetDealerships(ctx context.Context) ([]models.Dealership, error) {
+	tracer := otel.Tracer("DealershipService")
+	ctx, span := tracer.Start(ctx, "GetDealerships-Service")
+	defer span.End()
+
+	return s.store.GetDealerships(ctx)
+}
+
+func (s *DealershipService) GetDealershipById(ctx context.Context, id string) (*models.Dealership, error) {
+	tracer := otel.Tracer("DealershipService")
+	ctx, span := tracer.Start(ctx, "GetDealershipById-Service")
+	defer span.End()
+
+	dealership, err := s.store.GetDealershipById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return &dealership, nil
+}
+
+func (s *DealershipService) CreateDealership(ctx context.Context, dealershipReq *models.DealershipRequest) (*models.Dealership, error) {
+	tracer := otel.Tracer("DealershipService")
+	ctx, span := tracer.Start(ctx, "CreateDealership-Service")
+	defer span.End()
+
+	if err := models.ValidateDealershipRequest(*dealershipReq); err != nil {
+		return nil, err
+	}
+
+	createdDealership, err := s.store.CreateDealership(ctx, dealershipReq)
+	if err != nil {
+		return nil, err
+	}
+	return &createdDealership, nil
+}
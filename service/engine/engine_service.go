@@ -2,22 +2,63 @@ package engine
 
 import (
 	"context"
+	"strings"
 
 	"github.com/adohong4/carZone/models"
+	"github.com/adohong4/carZone/service"
 	"github.com/adohong4/carZone/store"
 	"go.opentelemetry.io/otel"
 )
 
+// InvalidError is returned when an engine request fails validation.
+type InvalidError struct {
+	Reason string
+}
+
+func (e *InvalidError) Error() string {
+	return e.Reason
+}
+
+func invalid(err error) error {
+	return &InvalidError{Reason: err.Error()}
+}
+
+func invalidViolations(violations []models.Violation) error {
+	messages := make([]string, len(violations))
+	for i, violation := range violations {
+		messages[i] = violation.Message
+	}
+	return &InvalidError{Reason: strings.Join(messages, "; ")}
+}
+
 type EngineService struct {
 	store store.EngineStoreInterface
+	rules service.ValidationServiceInterface
 }
 
-func NewEngineService(store store.EngineStoreInterface) *EngineService {
+func NewEngineService(store store.EngineStoreInterface, rules service.ValidationServiceInterface) *EngineService {
 	return &EngineService{
 		store: store,
+		rules: rules,
 	}
 }
 
+// validate runs the built-in checks, then the validation rules of the
+// dealership.
+func (s *EngineService) validate(ctx context.Context, engineReq *models.EngineRequest) error {
+	if err := models.ValidateEngineRequest(*engineReq); err != nil {
+		return invalid(err)
+	}
+	violations, err := s.rules.CheckEngine(ctx, *engineReq)
+	if err != nil {
+		return err
+	}
+	if len(violations) > 0 {
+		return invalidViolations(violations)
+	}
+	return nil
+}
+
 func (s *EngineService) GetEngineById(ctx context.Context, id string) (*models.Engine, error) {
 	tracer := otel.Tracer("EngineService")
 	ctx, span := tracer.Start(ctx, "GetEngineById-Service")
@@ -35,7 +76,7 @@ func (s *EngineService) CreateEngine(ctx context.Context, engineReq *models.Engi
 	ctx, span := tracer.Start(ctx, "CreateEngine-Service")
 	defer span.End()
 
-	if err := models.ValidateEngineRequest(*engineReq); err != nil {
+	if err := s.validate(ctx, engineReq); err != nil {
 		return nil, err
 	}
 
@@ -51,7 +92,7 @@ func (s *EngineService) UpdateEngine(ctx context.Context, id string, engineReq *
 	ctx, span := tracer.Start(ctx, "UpdateEngine-Service")
 	defer span.End()
 
-	if err := models.ValidateEngineRequest(*engineReq); err != nil {
+	if err := s.validate(ctx, engineReq); err != nil {
 		return nil, err
 	}
 
@@ -2,16 +2,21 @@ package service
 
 import (
 	"context"
+	"io"
 
+	"github.com/adohong4/carZone/invoice"
 	"github.com/adohong4/carZone/models"
+	"github.com/adohong4/carZone/money"
 )
 
 type CarServiceInterface interface {
 	GetCarById(ctx context.Context, id string) (*models.Car, error)
 	GetCarByBrand(ctx context.Context, brand string, isEngine bool) ([]models.Car, error)
+	CompareCars(ctx context.Context, ids []string) ([]models.Car, error)
 	CreateCar(ctx context.Context, car *models.CarRequest) (*models.Car, error)
 	UpdateCar(ctx context.Context, id string, carReq *models.CarRequest) (*models.Car, error)
 	DeleteCar(ctx context.Context, id string) (*models.Car, error)
+	ValidateCar(ctx context.Context, carReq *models.CarRequest) (*models.ValidationResult, error)
 }
 
 type EngineServiceInterface interface {
@@ -20,3 +25,123 @@ type EngineServiceInterface interface {
 	UpdateEngine(ctx context.Context, id string, engineReq *models.EngineRequest) (*models.Engine, error)
 	DeleteEngine(ctx context.Context, id string) (*models.Engine, error)
 }
+
+type LoginServiceInterface interface {
+	Login(ctx context.Context, credentials models.Credentials, ip string) (*models.LoginResult, error)
+	SecondFactor(ctx context.Context, challenge, code, ip string) (*models.LoginResult, error)
+	EnrollTOTP(ctx context.Context, username string) (*models.TOTPEnrollment, error)
+	ConfirmTOTP(ctx context.Context, username, code string) ([]string, error)
+	DisableTOTP(ctx context.Context, username, code string) error
+	Unlock(ctx context.Context, username, ip string) error
+}
+
+type OIDCServiceInterface interface {
+	Begin(ctx context.Context) (string, *models.OIDCFlow, error)
+	Callback(ctx context.Context, flow models.OIDCFlow, state, code string) (*models.LoginResult, error)
+}
+
+type DealershipServiceInterface interface {
+	GetDealerships(ctx context.Context) ([]models.Dealership, error)
+	GetDealershipById(ctx context.Context, id string) (*models.Dealership, error)
+	CreateDealership(ctx context.Context, dealershipReq *models.DealershipRequest) (*models.Dealership, error)
+}
+
+type VehicleServiceInterface interface {
+	GetVehicle(ctx context.Context, vin string) (*models.Vehicle, error)
+	ListVehicles(ctx context.Context, filter models.VehicleFilter) ([]models.Vehicle, error)
+	ReceiveVehicle(ctx context.Context, vehicleReq *models.VehicleRequest) (*models.Vehicle, error)
+	MoveVehicle(ctx context.Context, vin string, moveReq *models.VehicleMoveRequest) (*models.Vehicle, error)
+	StockCounts(ctx context.Context, carID string) ([]models.StockCount, error)
+}
+
+type VINServiceInterface interface {
+	Decode(ctx context.Context, vin string) (*models.VINDecodeResult, error)
+}
+
+type ReservationServiceInterface interface {
+	CreateReservation(ctx context.Context, carID, username string, reservationReq *models.ReservationRequest) (*models.Reservation, error)
+	ActiveReservation(ctx context.Context, carID string) (*models.Reservation, error)
+	ListReservations(ctx context.Context, carID string) ([]models.Reservation, error)
+	ReleaseReservation(ctx context.Context, carID, id string) (*models.Reservation, error)
+}
+
+type OrderServiceInterface interface {
+	CreateOrder(ctx context.Context, salesperson string, orderReq *models.OrderRequest) (*models.Order, error)
+	GetOrder(ctx context.Context, id string) (*models.Order, error)
+	ListOrders(ctx context.Context, status string) ([]models.Order, error)
+	UpdateOrder(ctx context.Context, id string, orderReq *models.OrderRequest) (*models.Order, error)
+	ChangeStatus(ctx context.Context, id, status string) (*models.Order, error)
+	Invoice(ctx context.Context, id string) (*invoice.Invoice, error)
+}
+
+type CustomerServiceInterface interface {
+	GetCustomerById(ctx context.Context, id string) (*models.Customer, error)
+	ListCustomers(ctx context.Context, search string) ([]models.Customer, error)
+	CreateCustomer(ctx context.Context, customerReq *models.CustomerRequest) (*models.Customer, error)
+	UpdateCustomer(ctx context.Context, id string, customerReq *models.CustomerRequest) (*models.Customer, error)
+	DeleteCustomer(ctx context.Context, id string) (*models.Customer, error)
+	AddNote(ctx context.Context, id, author string, noteReq *models.CustomerNoteRequest) (*models.CustomerNote, error)
+	LinkCar(ctx context.Context, id string, linkReq *models.CustomerCarRequest) (*models.CustomerCar, error)
+	Export(ctx context.Context, id string) (*models.CustomerExport, error)
+	Erase(ctx context.Context, id string) (*models.Customer, error)
+}
+
+type CurrencyServiceInterface interface {
+	ListRates(ctx context.Context) ([]models.ExchangeRate, error)
+	SetRate(ctx context.Context, currency string, rateReq *models.ExchangeRateRequest) (*models.ExchangeRate, error)
+	Convert(ctx context.Context, amount money.Money, currency string) (money.Money, error)
+	ConvertCars(ctx context.Context, cars []models.Car, currency string) ([]models.Car, error)
+}
+
+type PricingServiceInterface interface {
+	PriceHistory(ctx context.Context, carID string) ([]models.CarPrice, error)
+	SchedulePrice(ctx context.Context, carID, username string, priceReq *models.CarPriceRequest) (*models.CarPrice, error)
+	CancelPrice(ctx context.Context, carID, priceID string) (*models.CarPrice, error)
+	ListPromotions(ctx context.Context) ([]models.Promotion, error)
+	CreatePromotion(ctx context.Context, promotionReq *models.PromotionRequest) (*models.Promotion, error)
+	DeletePromotion(ctx context.Context, id string) (*models.Promotion, error)
+	ApplyPromotions(ctx context.Context, cars []models.Car) ([]models.Car, error)
+}
+
+type MediaServiceInterface interface {
+	Upload(ctx context.Context, carID string, data []byte) (*models.CarImage, error)
+	ListImages(ctx context.Context, carID string) ([]models.CarImage, error)
+	SetOrder(ctx context.Context, carID string, orderReq *models.CarImageOrderRequest) ([]models.CarImage, error)
+	SetPrimary(ctx context.Context, carID, imageID string) (*models.CarImage, error)
+	DeleteImage(ctx context.Context, carID, imageID string) (*models.CarImage, error)
+	RemoveFiles(ctx context.Context, images []models.CarImage)
+	AttachImages(ctx context.Context, cars []models.Car) ([]models.Car, error)
+	Open(ctx context.Context, key string) (io.ReadCloser, string, error)
+}
+
+type CatalogueServiceInterface interface {
+	ListBrands(ctx context.Context) ([]models.Brand, error)
+	GetBrand(ctx context.Context, id string) (*models.Brand, error)
+	CreateBrand(ctx context.Context, brandReq *models.BrandRequest) (*models.Brand, error)
+	UpdateBrand(ctx context.Context, id string, brandReq *models.BrandRequest) (*models.Brand, error)
+	DeleteBrand(ctx context.Context, id string) (*models.Brand, error)
+	CreateModel(ctx context.Context, brandID string, modelReq *models.CarModelRequest) (*models.CarModel, error)
+	UpdateModel(ctx context.Context, brandID, id string, modelReq *models.CarModelRequest) (*models.CarModel, error)
+	DeleteModel(ctx context.Context, brandID, id string) (*models.CarModel, error)
+}
+
+type ValidationServiceInterface interface {
+	GetRules(ctx context.Context) (*models.ValidationRules, error)
+	SetRules(ctx context.Context, rulesReq *models.ValidationRulesRequest) (*models.ValidationRules, error)
+	CheckCar(ctx context.Context, carReq models.CarRequest) ([]models.Violation, error)
+	CheckEngine(ctx context.Context, engineReq models.EngineRequest) ([]models.Violation, error)
+}
+
+type ReportServiceInterface interface {
+	InventoryReport(ctx context.Context, reportReq models.InventoryReportRequest) (*models.InventoryReport, error)
+}
+
+type WebhookServiceInterface interface {
+	CreateWebhook(ctx context.Context, webhookReq *models.WebhookRequest) (*models.Webhook, error)
+	ListWebhooks(ctx context.Context) ([]models.Webhook, error)
+	GetWebhook(ctx context.Context, id string) (*models.Webhook, error)
+	DeleteWebhook(ctx context.Context, id string) (*models.Webhook, error)
+	ListDeliveries(ctx context.Context, webhookID string) ([]models.WebhookDelivery, error)
+	GetDelivery(ctx context.Context, webhookID, deliveryID string) (*models.WebhookDelivery, error)
+	Redeliver(ctx context.Context, webhookID, deliveryID string) (*models.WebhookDelivery, error)
+}
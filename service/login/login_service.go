@@ -0,0 +1,365 @@
+package login
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/adohong4/carZone/helpers"
+	"github.com/adohong4/carZone/models"
+	"github.com/adohong4/carZone/store"
+	"github.com/adohong4/carZone/tenant"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel"
+)
+
+var loginFailures = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "login_failures_total",
+		Help: "Total number of failed login attempts by reason",
+	},
+	[]string{"reason"},
+)
+
+func init() {
+	prometheus.MustRegister(loginFailures)
+}
+
+var (
+	ErrInvalidCredentials  = errors.New("Incorrect Username or Password")
+	ErrInvalidChallenge    = errors.New("Invalid or expired login challenge")
+	ErrInvalidSecondFactor = errors.New("Invalid authentication code")
+	ErrTOTPNotEnrolled     = errors.New("TOTP is not enrolled")
+	ErrTOTPEnabled         = errors.New("TOTP is already enabled, disable it first")
+)
+
+// recoveryCodeCount is how many recovery codes a confirmed enrollment gets.
+const recoveryCodeCount = 10
+
+// LockedError is returned while the username or the client address is
+// locked out.
+type LockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LockedError) Error() string {
+	return "Too many failed login attempts, try again later"
+}
+
+// Config controls when failed attempts lead to a lockout. The n-th lockout
+// of a subject lasts BaseLockout * 2^(n-1), capped at MaxLockout.
+type Config struct {
+	MaxUserFailures int
+	MaxIPFailures   int
+	BaseLockout     time.Duration
+	MaxLockout      time.Duration
+}
+
+func DefaultConfig() Config {
+	return Config{
+		MaxUserFailures: 5,
+		MaxIPFailures:   20,
+		BaseLockout:     time.Minute,
+		MaxLockout:      24 * time.Hour,
+	}
+}
+
+type LoginService struct {
+	store     store.LoginStoreInterface
+	totpStore store.TOTPStoreInterface
+	config    Config
+	now       func() time.Time
+}
+
+func NewLoginService(store store.LoginStoreInterface, totpStore store.TOTPStoreInterface, config Config) *LoginService {
+	return &LoginService{
+		store:     store,
+		totpStore: totpStore,
+		config:    config,
+		now:       time.Now,
+	}
+}
+
+func userSubject(username string) string {
+	return "user:" + username
+}
+
+func ipSubject(ip string) string {
+	return "ip:" + ip
+}
+
+// Login checks the password. Users without a confirmed TOTP get their access
+// token straight away, the others get a challenge for SecondFactor.
+func (s *LoginService) Login(ctx context.Context, credentials models.Credentials, ip string) (*models.LoginResult, error) {
+	tracer := otel.Tracer("LoginService")
+	ctx, span := tracer.Start(ctx, "Login-Service")
+	defer span.End()
+
+	if err := s.checkLocked(ctx, credentials.UserName, ip); err != nil {
+		return nil, err
+	}
+
+	user, ok := authenticate(credentials)
+	if !ok {
+		if err := s.fail(ctx, credentials.UserName, ip, "invalid_credentials"); err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidCredentials
+	}
+
+	credential, err := s.totpStore.GetTOTP(ctx, user.UserName)
+	if err != nil {
+		return nil, err
+	}
+	if credential.Confirmed {
+		challenge, err := helpers.GenerateChallengeToken(user)
+		if err != nil {
+			return nil, err
+		}
+		return &models.LoginResult{SecondFactorRequired: true, Challenge: challenge}, nil
+	}
+
+	return s.issueToken(ctx, user)
+}
+
+// SecondFactor completes a challenged login with a TOTP code or an unused
+// recovery code. Wrong codes count towards the lockout like wrong passwords.
+func (s *LoginService) SecondFactor(ctx context.Context, challenge, code, ip string) (*models.LoginResult, error) {
+	tracer := otel.Tracer("LoginService")
+	ctx, span := tracer.Start(ctx, "SecondFactor-Service")
+	defer span.End()
+
+	user, err := helpers.ParseChallengeToken(challenge)
+	if err != nil {
+		return nil, ErrInvalidChallenge
+	}
+
+	if err := s.checkLocked(ctx, user.UserName, ip); err != nil {
+		return nil, err
+	}
+
+	credential, err := s.totpStore.GetTOTP(ctx, user.UserName)
+	if err != nil {
+		return nil, err
+	}
+	if !credential.Confirmed {
+		return nil, ErrInvalidChallenge
+	}
+
+	valid, err := s.verifyCode(ctx, credential, code)
+	if err != nil {
+		return nil, err
+	}
+	if !valid {
+		if err := s.fail(ctx, user.UserName, ip, "invalid_second_factor"); err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidSecondFactor
+	}
+
+	return s.issueToken(ctx, user)
+}
+
+// EnrollTOTP generates a new secret for username. It replaces an earlier
+// unconfirmed one and stays inactive until ConfirmTOTP. An active second
+// factor must be disabled with a code first, a token alone cannot replace
+// it.
+func (s *LoginService) EnrollTOTP(ctx context.Context, username string) (*models.TOTPEnrollment, error) {
+	tracer := otel.Tracer("LoginService")
+	ctx, span := tracer.Start(ctx, "EnrollTOTP-Service")
+	defer span.End()
+
+	secret, err := helpers.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	credential := models.TOTPCredential{
+		UserName:  username,
+		Secret:    secret,
+		CreatedAt: s.now(),
+	}
+	if err := s.totpStore.SaveTOTP(ctx, credential); err != nil {
+		if errors.Is(err, store.ErrTOTPConfirmed) {
+			return nil, ErrTOTPEnabled
+		}
+		return nil, err
+	}
+
+	uri := helpers.TOTPURI("CarZone", username, secret)
+	return &models.TOTPEnrollment{Secret: secret, URI: uri, QRPayload: uri}, nil
+}
+
+// ConfirmTOTP activates the enrolled secret once code proves the user's app
+// is set up, and returns the recovery codes. They are only shown this once,
+// the store keeps their hashes.
+func (s *LoginService) ConfirmTOTP(ctx context.Context, username, code string) ([]string, error) {
+	tracer := otel.Tracer("LoginService")
+	ctx, span := tracer.Start(ctx, "ConfirmTOTP-Service")
+	defer span.End()
+
+	credential, err := s.totpStore.GetTOTP(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	if credential.UserName == "" {
+		return nil, ErrTOTPNotEnrolled
+	}
+
+	step, ok := helpers.ValidateTOTP(credential.Secret, code, s.now())
+	if !ok {
+		return nil, ErrInvalidSecondFactor
+	}
+
+	codes, err := helpers.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, err
+	}
+	hashes := make([]string, len(codes))
+	for i, recoveryCode := range codes {
+		hashes[i] = helpers.HashRecoveryCode(recoveryCode)
+	}
+
+	if err := s.totpStore.ConfirmTOTP(ctx, username, step, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// DisableTOTP removes the second factor, code must be a current TOTP or a
+// recovery code.
+func (s *LoginService) DisableTOTP(ctx context.Context, username, code string) error {
+	tracer := otel.Tracer("LoginService")
+	ctx, span := tracer.Start(ctx, "DisableTOTP-Service")
+	defer span.End()
+
+	credential, err := s.totpStore.GetTOTP(ctx, username)
+	if err != nil {
+		return err
+	}
+	if !credential.Confirmed {
+		return ErrTOTPNotEnrolled
+	}
+
+	valid, err := s.verifyCode(ctx, credential, code)
+	if err != nil {
+		return err
+	}
+	if !valid {
+		return ErrInvalidSecondFactor
+	}
+	return s.totpStore.DeleteTOTP(ctx, username)
+}
+
+// verifyCode accepts a TOTP code not used before or an unused recovery code.
+func (s *LoginService) verifyCode(ctx context.Context, credential models.TOTPCredential, code string) (bool, error) {
+	if step, ok := helpers.ValidateTOTP(credential.Secret, code, s.now()); ok {
+		return s.totpStore.UseTOTPStep(ctx, credential.UserName, step)
+	}
+	return s.totpStore.ConsumeRecoveryCode(ctx, credential.UserName, helpers.HashRecoveryCode(code))
+}
+
+func (s *LoginService) checkLocked(ctx context.Context, username, ip string) error {
+	now := s.now()
+	for _, subject := range []string{userSubject(username), ipSubject(ip)} {
+		lockout, err := s.store.GetLockout(ctx, subject)
+		if err != nil {
+			return err
+		}
+		if lockout.IsLocked(now) {
+			s.recordFailure(ctx, username, ip, "locked")
+			return &LockedError{RetryAfter: lockout.LockedUntil.Sub(now)}
+		}
+	}
+	return nil
+}
+
+// fail records a failed attempt and counts it against the username and the address.
+func (s *LoginService) fail(ctx context.Context, username, ip, reason string) error {
+	s.recordFailure(ctx, username, ip, reason)
+	if err := s.registerFailure(ctx, userSubject(username), s.config.MaxUserFailures); err != nil {
+		return err
+	}
+	return s.registerFailure(ctx, ipSubject(ip), s.config.MaxIPFailures)
+}
+
+func (s *LoginService) issueToken(ctx context.Context, user models.User) (*models.LoginResult, error) {
+	if err := s.store.ResetLockout(ctx, userSubject(user.UserName)); err != nil {
+		return nil, err
+	}
+	token, err := helpers.GenerateToken(user)
+	if err != nil {
+		return nil, err
+	}
+	return &models.LoginResult{Token: token}, nil
+}
+
+// Unlock clears the lockout of username and, when given, of ip.
+func (s *LoginService) Unlock(ctx context.Context, username, ip string) error {
+	tracer := otel.Tracer("LoginService")
+	ctx, span := tracer.Start(ctx, "Unlock-Service")
+	defer span.End()
+
+	if err := s.store.ResetLockout(ctx, userSubject(username)); err != nil {
+		return err
+	}
+	if ip != "" {
+		return s.store.ResetLockout(ctx, ipSubject(ip))
+	}
+	return nil
+}
+
+// registerFailure counts a failure against subject and locks it once it
+// reaches maxFailures.
+func (s *LoginService) registerFailure(ctx context.Context, subject string, maxFailures int) error {
+	lockout, err := s.store.IncrementFailure(ctx, subject)
+	if err != nil {
+		return err
+	}
+	if maxFailures <= 0 || lockout.FailedAttempts < maxFailures {
+		return nil
+	}
+
+	until := s.now().Add(s.cooldown(lockout.Lockouts))
+	if _, err := s.store.LockUntil(ctx, subject, until); err != nil {
+		return err
+	}
+	log.Printf("Login locked for %s until %s", subject, until.Format(time.RFC3339))
+	return nil
+}
+
+// cooldown doubles with every previous lockout of the subject.
+func (s *LoginService) cooldown(previousLockouts int) time.Duration {
+	cooldown := s.config.BaseLockout
+	for i := 0; i < previousLockouts && cooldown < s.config.MaxLockout; i++ {
+		cooldown *= 2
+	}
+	if cooldown > s.config.MaxLockout {
+		cooldown = s.config.MaxLockout
+	}
+	return cooldown
+}
+
+func (s *LoginService) recordFailure(ctx context.Context, username, ip, reason string) {
+	loginFailures.WithLabelValues(reason).Inc()
+
+	attempt := models.LoginAttempt{
+		UserName:  username,
+		IP:        ip,
+		Success:   false,
+		Reason:    reason,
+		CreatedAt: s.now(),
+	}
+	if err := s.store.RecordAttempt(ctx, attempt); err != nil {
+		log.Printf("Error recording login attempt: %v", err)
+	}
+}
+
+// authenticate checks the credentials against the built-in account.
+func authenticate(credentials models.Credentials) (models.User, bool) {
+	if credentials.UserName == "admin" && credentials.Password == "admin123" {
+		return models.User{UserName: credentials.UserName, Role: models.RoleAdmin, TenantID: tenant.DefaultID}, true
+	}
+	return models.User{}, false
+}
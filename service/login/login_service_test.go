@@ -0,0 +1,171 @@
+package login
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/adohong4/carZone/helpers"
+	"github.com/adohong4/carZone/models"
+	"github.com/adohong4/carZone/store/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestService(now *time.Time) (*LoginService, *memory.LoginStore) {
+	loginStore := memory.NewLoginStore()
+	svc := NewLoginService(loginStore, memory.NewTOTPStore(), Config{
+		MaxUserFailures: 3,
+		MaxIPFailures:   10,
+		BaseLockout:     time.Minute,
+		MaxLockout:      10 * time.Minute,
+	})
+	svc.now = func() time.Time { return *now }
+	return svc, loginStore
+}
+
+var (
+	goodCredentials = models.Credentials{UserName: "admin", Password: "admin123"}
+	badCredentials  = models.Credentials{UserName: "admin", Password: "wrong"}
+)
+
+func TestLoginLocksAfterRepeatedFailures(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	svc, loginStore := newTestService(&now)
+
+	for i := 0; i < 3; i++ {
+		_, err := svc.Login(ctx, badCredentials, "10.0.0.1")
+		assert.ErrorIs(t, err, ErrInvalidCredentials)
+	}
+
+	_, err := svc.Login(ctx, goodCredentials, "10.0.0.2")
+	var locked *LockedError
+	require.True(t, errors.As(err, &locked), "account should be locked, got %v", err)
+	assert.Equal(t, time.Minute, locked.RetryAfter)
+
+	attempts := loginStore.Attempts()
+	require.Len(t, attempts, 4)
+	assert.Equal(t, "invalid_credentials", attempts[0].Reason)
+	assert.Equal(t, "locked", attempts[3].Reason)
+
+	now = now.Add(time.Minute)
+	result, err := svc.Login(ctx, goodCredentials, "10.0.0.2")
+	require.NoError(t, err)
+	assert.NotEmpty(t, result.Token)
+}
+
+func TestLoginCooldownIsExponential(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	svc, _ := newTestService(&now)
+
+	for _, expected := range []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute} {
+		for i := 0; i < 3; i++ {
+			svc.Login(ctx, badCredentials, "10.0.0.1")
+		}
+		_, err := svc.Login(ctx, badCredentials, "10.0.0.1")
+		var locked *LockedError
+		require.True(t, errors.As(err, &locked))
+		assert.Equal(t, expected, locked.RetryAfter)
+		now = now.Add(expected)
+	}
+}
+
+func TestUnlockLiftsLockout(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	svc, _ := newTestService(&now)
+
+	for i := 0; i < 3; i++ {
+		svc.Login(ctx, badCredentials, "10.0.0.1")
+	}
+	require.NoError(t, svc.Unlock(ctx, "admin", ""))
+
+	_, err := svc.Login(ctx, goodCredentials, "10.0.0.1")
+	assert.NoError(t, err)
+}
+
+func TestSecondFactorLogin(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	svc, _ := newTestService(&now)
+
+	enrollment, err := svc.EnrollTOTP(ctx, "admin")
+	require.NoError(t, err)
+
+	// Enrollment is inactive until confirmed.
+	result, err := svc.Login(ctx, goodCredentials, "10.0.0.1")
+	require.NoError(t, err)
+	assert.NotEmpty(t, result.Token)
+
+	code, err := helpers.TOTPCode(enrollment.Secret, helpers.TOTPStep(now))
+	require.NoError(t, err)
+	recoveryCodes, err := svc.ConfirmTOTP(ctx, "admin", code)
+	require.NoError(t, err)
+	require.Len(t, recoveryCodes, recoveryCodeCount)
+
+	result, err = svc.Login(ctx, goodCredentials, "10.0.0.1")
+	require.NoError(t, err)
+	assert.True(t, result.SecondFactorRequired)
+	assert.Empty(t, result.Token)
+
+	// The code used to confirm cannot be replayed.
+	_, err = svc.SecondFactor(ctx, result.Challenge, code, "10.0.0.1")
+	assert.ErrorIs(t, err, ErrInvalidSecondFactor)
+
+	now = now.Add(30 * time.Second)
+	code, err = helpers.TOTPCode(enrollment.Secret, helpers.TOTPStep(now))
+	require.NoError(t, err)
+	final, err := svc.SecondFactor(ctx, result.Challenge, code, "10.0.0.1")
+	require.NoError(t, err)
+	assert.NotEmpty(t, final.Token)
+
+	// Recovery codes work once.
+	final, err = svc.SecondFactor(ctx, result.Challenge, recoveryCodes[0], "10.0.0.1")
+	require.NoError(t, err)
+	assert.NotEmpty(t, final.Token)
+	_, err = svc.SecondFactor(ctx, result.Challenge, recoveryCodes[0], "10.0.0.1")
+	assert.ErrorIs(t, err, ErrInvalidSecondFactor)
+
+	_, err = svc.SecondFactor(ctx, "not-a-challenge", code, "10.0.0.1")
+	assert.ErrorIs(t, err, ErrInvalidChallenge)
+
+	require.NoError(t, svc.DisableTOTP(ctx, "admin", recoveryCodes[1]))
+	result, err = svc.Login(ctx, goodCredentials, "10.0.0.1")
+	require.NoError(t, err)
+	assert.NotEmpty(t, result.Token)
+}
+
+func TestReenrollDoesNotDisableSecondFactor(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	svc, _ := newTestService(&now)
+
+	enrollment, err := svc.EnrollTOTP(ctx, "admin")
+	require.NoError(t, err)
+	// an unconfirmed enrollment can be started over
+	enrollment, err = svc.EnrollTOTP(ctx, "admin")
+	require.NoError(t, err)
+	code, err := helpers.TOTPCode(enrollment.Secret, helpers.TOTPStep(now))
+	require.NoError(t, err)
+	recoveryCodes, err := svc.ConfirmTOTP(ctx, "admin", code)
+	require.NoError(t, err)
+
+	// a token alone cannot swap the confirmed secret for a new one
+	_, err = svc.EnrollTOTP(ctx, "admin")
+	assert.ErrorIs(t, err, ErrTOTPEnabled)
+
+	result, err := svc.Login(ctx, goodCredentials, "10.0.0.1")
+	require.NoError(t, err)
+	assert.True(t, result.SecondFactorRequired)
+	assert.Empty(t, result.Token)
+	final, err := svc.SecondFactor(ctx, result.Challenge, recoveryCodes[0], "10.0.0.1")
+	require.NoError(t, err)
+	assert.NotEmpty(t, final.Token)
+
+	require.NoError(t, svc.DisableTOTP(ctx, "admin", recoveryCodes[1]))
+	_, err = svc.EnrollTOTP(ctx, "admin")
+	assert.NoError(t, err)
+}
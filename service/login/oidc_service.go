@@ -0,0 +1,143 @@
+package login
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/adohong4/carZone/helpers"
+	"github.com/adohong4/carZone/models"
+	"github.com/adohong4/carZone/oidc"
+	"go.opentelemetry.io/otel"
+)
+
+var (
+	ErrInvalidOIDCState = errors.New("Invalid or expired login state")
+	ErrNoRole           = errors.New("User is not in a group with access to CarZone")
+	ErrEmailNotVerified = errors.New("Email address is not verified by the identity provider")
+)
+
+// rolePriority decides the role of users whose groups map to several.
+var rolePriority = map[string]int{
+	models.RoleStaff: 1,
+	models.RoleAdmin: 2,
+}
+
+// OIDCService signs users in through an external identity provider and
+// issues the same JWT as the password login.
+type OIDCService struct {
+	provider oidc.IdentityProvider
+	roles    map[string]string
+	tenantID string
+}
+
+// NewOIDCService maps identity provider groups to CarZone roles with roles,
+// group name to role. Users in none of the groups are refused. Everyone
+// signing in through provider works for the dealership tenantID.
+func NewOIDCService(provider oidc.IdentityProvider, roles map[string]string, tenantID string) *OIDCService {
+	return &OIDCService{
+		provider: provider,
+		roles:    roles,
+		tenantID: tenantID,
+	}
+}
+
+// Begin starts a login and returns the identity provider URL to redirect to,
+// and the flow to keep until the callback.
+func (s *OIDCService) Begin(ctx context.Context) (string, *models.OIDCFlow, error) {
+	tracer := otel.Tracer("OIDCService")
+	_, span := tracer.Start(ctx, "Begin-Service")
+	defer span.End()
+
+	state, err := oidc.RandomString()
+	if err != nil {
+		return "", nil, err
+	}
+	nonce, err := oidc.RandomString()
+	if err != nil {
+		return "", nil, err
+	}
+	verifier, challenge, err := oidc.NewCodeVerifier()
+	if err != nil {
+		return "", nil, err
+	}
+
+	flow := &models.OIDCFlow{State: state, Nonce: nonce, CodeVerifier: verifier}
+	return s.provider.AuthCodeURL(state, nonce, challenge), flow, nil
+}
+
+// Callback finishes the login started with flow.
+func (s *OIDCService) Callback(ctx context.Context, flow models.OIDCFlow, state, code string) (*models.LoginResult, error) {
+	tracer := otel.Tracer("OIDCService")
+	ctx, span := tracer.Start(ctx, "Callback-Service")
+	defer span.End()
+
+	if flow.State == "" || subtle.ConstantTimeCompare([]byte(flow.State), []byte(state)) != 1 {
+		return nil, ErrInvalidOIDCState
+	}
+
+	identity, err := s.provider.Exchange(ctx, code, flow.CodeVerifier, flow.Nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	if !identity.EmailVerified {
+		log.Printf("OIDC login of %s refused, email not verified", identity.Subject)
+		return nil, ErrEmailNotVerified
+	}
+	role := s.role(identity.Groups)
+	if role == "" {
+		log.Printf("OIDC login of %s refused, groups %v", identity.Subject, identity.Groups)
+		return nil, ErrNoRole
+	}
+
+	token, err := helpers.GenerateToken(models.User{UserName: OIDCUserName(identity.Issuer, identity.Subject), Role: role, TenantID: s.tenantID})
+	if err != nil {
+		return nil, err
+	}
+	return &models.LoginResult{Token: token}, nil
+}
+
+// OIDCUserName is the CarZone user name of an identity provider user. It
+// is namespaced by the issuer and built from the subject, which the
+// provider never reassigns, so it cannot collide with a password user or a
+// user of another provider, nor be taken over by changing an email address.
+func OIDCUserName(issuer, subject string) string {
+	return "oidc:" + issuer + "#" + subject
+}
+
+func (s *OIDCService) role(groups []string) string {
+	role := ""
+	for _, group := range groups {
+		if mapped, ok := s.roles[group]; ok && rolePriority[mapped] > rolePriority[role] {
+			role = mapped
+		}
+	}
+	return role
+}
+
+// ParseRoleMapping reads "group=role" pairs separated by commas, as in
+// "carzone-admins=admin,carzone-sales=staff".
+func ParseRoleMapping(value string) (map[string]string, error) {
+	roles := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		group, role, ok := strings.Cut(pair, "=")
+		group, role = strings.TrimSpace(group), strings.TrimSpace(role)
+		if !ok || group == "" {
+			return nil, fmt.Errorf("invalid role mapping %q", pair)
+		}
+		if _, known := rolePriority[role]; !known {
+			return nil, fmt.Errorf("unknown role %q in mapping %q", role, pair)
+		}
+		roles[group] = role
+	}
+	return roles, nil
+}
@@ -0,0 +1,97 @@
+package login
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/adohong4/carZone/models"
+	"github.com/adohong4/carZone/oidc"
+	"github.com/adohong4/carZone/oidc/oidctest"
+	"github.com/adohong4/carZone/tenant"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newOIDCTestService(t *testing.T) (*OIDCService, *oidctest.IdP) {
+	idp := oidctest.NewIdP()
+	t.Cleanup(idp.Close)
+
+	provider, err := oidc.Discover(context.Background(), idp.Config("http://carzone.test/auth/oidc/callback"), nil)
+	require.NoError(t, err)
+
+	roles, err := ParseRoleMapping("carzone-admins=admin, carzone-sales=staff")
+	require.NoError(t, err)
+	return NewOIDCService(provider, roles, tenant.DefaultID), idp
+}
+
+// signIn runs Begin and the provider's redirect, and returns the flow with
+// the callback parameters.
+func signIn(t *testing.T, svc *OIDCService) (models.OIDCFlow, url.Values) {
+	authURL, flow, err := svc.Begin(context.Background())
+	require.NoError(t, err)
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := client.Get(authURL)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	location, err := url.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err)
+	return *flow, location.Query()
+}
+
+func TestOIDCLoginMapsGroupsToRole(t *testing.T) {
+	svc, idp := newOIDCTestService(t)
+	idp.User = oidc.Identity{Subject: "u-1", Email: "jane@example.com", EmailVerified: true, Groups: []string{"carzone-sales", "carzone-admins"}}
+
+	flow, callback := signIn(t, svc)
+	result, err := svc.Callback(context.Background(), flow, callback.Get("state"), callback.Get("code"))
+	require.NoError(t, err)
+
+	claims := jwt.MapClaims{}
+	_, _, err = jwt.NewParser().ParseUnverified(result.Token, claims)
+	require.NoError(t, err)
+	// the subject namespaced by the issuer, not the email
+	assert.Equal(t, "oidc:"+idp.Issuer()+"#u-1", claims["username"])
+	assert.Equal(t, models.RoleAdmin, claims["role"])
+	assert.Equal(t, tenant.DefaultID, claims["tenant_id"])
+}
+
+func TestOIDCLoginRequiresMappedGroup(t *testing.T) {
+	svc, idp := newOIDCTestService(t)
+	idp.User = oidc.Identity{Subject: "u-2", Email: "joe@example.com", EmailVerified: true, Groups: []string{"accounting"}}
+
+	flow, callback := signIn(t, svc)
+	_, err := svc.Callback(context.Background(), flow, callback.Get("state"), callback.Get("code"))
+	assert.ErrorIs(t, err, ErrNoRole)
+}
+
+func TestOIDCLoginRequiresVerifiedEmail(t *testing.T) {
+	svc, idp := newOIDCTestService(t)
+	// anyone can claim an address they do not own at a provider that does
+	// not verify it
+	idp.User = oidc.Identity{Subject: "u-3", Email: "admin@carzone.example", Groups: []string{"carzone-admins"}}
+
+	flow, callback := signIn(t, svc)
+	_, err := svc.Callback(context.Background(), flow, callback.Get("state"), callback.Get("code"))
+	assert.ErrorIs(t, err, ErrEmailNotVerified)
+}
+
+func TestOIDCLoginRejectsStateMismatch(t *testing.T) {
+	svc, idp := newOIDCTestService(t)
+	idp.User = oidc.Identity{Subject: "u-1", Groups: []string{"carzone-admins"}}
+
+	flow, callback := signIn(t, svc)
+	_, err := svc.Callback(context.Background(), flow, "forged", callback.Get("code"))
+	assert.ErrorIs(t, err, ErrInvalidOIDCState)
+}
+
+func TestParseRoleMappingRejectsUnknownRole(t *testing.T) {
+	_, err := ParseRoleMapping("everyone=root")
+	assert.Error(t, err)
+}
@@ -0,0 +1,76 @@
+package media
+
+import (
+	"context"
+	"errors"
+
+	"github.com/adohong4/carZone/models"
+	"github.com/adohong4/carZone/service"
+	"github.com/adohong4/carZone/store"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+)
+
+// CarService adds the images to the cars next returns and removes the
+// files of deleted cars. It goes in front of the cache so new uploads show
+// at once.
+type CarService struct {
+	service.CarServiceInterface
+	media service.MediaServiceInterface
+}
+
+func NewCarService(next service.CarServiceInterface, media service.MediaServiceInterface) *CarService {
+	return &CarService{
+		CarServiceInterface: next,
+		media:               media,
+	}
+}
+
+func (s *CarService) GetCarById(ctx context.Context, id string) (*models.Car, error) {
+	tracer := otel.Tracer("MediaCarService")
+	ctx, span := tracer.Start(ctx, "GetCarById-Service")
+	defer span.End()
+
+	car, err := s.CarServiceInterface.GetCarById(ctx, id)
+	if err != nil || car.ID == uuid.Nil {
+		return car, err
+	}
+
+	attached, err := s.media.AttachImages(ctx, []models.Car{*car})
+	if err != nil {
+		return nil, err
+	}
+	return &attached[0], nil
+}
+
+func (s *CarService) GetCarByBrand(ctx context.Context, brand string, isEngine bool) ([]models.Car, error) {
+	tracer := otel.Tracer("MediaCarService")
+	ctx, span := tracer.Start(ctx, "GetCarByBrand-Service")
+	defer span.End()
+
+	cars, err := s.CarServiceInterface.GetCarByBrand(ctx, brand, isEngine)
+	if err != nil || len(cars) == 0 {
+		return cars, err
+	}
+	return s.media.AttachImages(ctx, cars)
+}
+
+// DeleteCar looks the images up first, their rows go with the car.
+func (s *CarService) DeleteCar(ctx context.Context, id string) (*models.Car, error) {
+	tracer := otel.Tracer("MediaCarService")
+	ctx, span := tracer.Start(ctx, "DeleteCar-Service")
+	defer span.End()
+
+	// invalid IDs are for next to report
+	images, err := s.media.ListImages(ctx, id)
+	if err != nil && !errors.Is(err, store.ErrCarNotFound) {
+		return nil, err
+	}
+
+	car, err := s.CarServiceInterface.DeleteCar(ctx, id)
+	if err != nil {
+		return car, err
+	}
+	s.media.RemoveFiles(ctx, images)
+	return car, nil
+}
@@ -0,0 +1,339 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"log"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/adohong4/carZone/blob"
+	"github.com/adohong4/carZone/models"
+	"github.com/adohong4/carZone/store"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+)
+
+var (
+	ErrImageNotFound   = errors.New("image not found")
+	ErrTooLarge        = errors.New("image is too large")
+	ErrUnsupportedType = errors.New("image must be a JPEG, PNG or GIF")
+)
+
+// InvalidError is returned when a request fails validation.
+type InvalidError struct {
+	Reason string
+}
+
+func (e *InvalidError) Error() string {
+	return e.Reason
+}
+
+func invalid(err error) error {
+	return &InvalidError{Reason: err.Error()}
+}
+
+// extensions are the image types accepted, by the content type sniffed
+// from the upload.
+var extensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+}
+
+// Config limits uploads and says where clients fetch the images from.
+// PublicURL is prefixed to the blob keys, e.g. "/media/" when the API
+// serves them or the address of a CDN in front of the bucket.
+type Config struct {
+	MaxBytes      int64
+	MaxPixels     int
+	ThumbnailSize int
+	PublicURL     string
+}
+
+func DefaultConfig() Config {
+	return Config{
+		MaxBytes:      10 << 20,
+		MaxPixels:     40_000_000,
+		ThumbnailSize: 320,
+		PublicURL:     "/media/",
+	}
+}
+
+type MediaService struct {
+	store  store.CarImageStoreInterface
+	blobs  blob.Store
+	config Config
+	now    func() time.Time
+}
+
+func NewMediaService(store store.CarImageStoreInterface, blobs blob.Store, config Config) *MediaService {
+	return &MediaService{
+		store:  store,
+		blobs:  blobs,
+		config: config,
+		now:    time.Now,
+	}
+}
+
+// withURLs fills in where clients fetch the image and its thumbnail.
+func (s *MediaService) withURLs(image models.CarImage) models.CarImage {
+	image.URL = s.config.PublicURL + image.Key
+	image.ThumbnailURL = s.config.PublicURL + image.ThumbnailKey
+	return image
+}
+
+// Upload checks data is an image of an accepted type and size, stores it
+// with a thumbnail and adds it after the other images of the car.
+func (s *MediaService) Upload(ctx context.Context, carID string, data []byte) (*models.CarImage, error) {
+	tracer := otel.Tracer("MediaService")
+	ctx, span := tracer.Start(ctx, "Upload-Service")
+	defer span.End()
+
+	id, err := uuid.Parse(carID)
+	if err != nil {
+		return nil, store.ErrCarNotFound
+	}
+	if int64(len(data)) > s.config.MaxBytes {
+		return nil, ErrTooLarge
+	}
+
+	// trust the bytes, not the name or type the client sent
+	contentType := http.DetectContentType(data)
+	ext, ok := extensions[contentType]
+	if !ok {
+		return nil, ErrUnsupportedType
+	}
+
+	// check the dimensions before decoding so a small file cannot claim
+	// a huge canvas
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, &InvalidError{Reason: "Image could not be read"}
+	}
+	if config.Width*config.Height > s.config.MaxPixels {
+		return nil, ErrTooLarge
+	}
+	decoded, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, &InvalidError{Reason: "Image could not be read"}
+	}
+	thumb, _, _, err := thumbnail(decoded, s.config.ThumbnailSize)
+	if err != nil {
+		return nil, err
+	}
+
+	imageID := uuid.New()
+	prefix := "cars/" + id.String() + "/" + imageID.String()
+	carImage := models.CarImage{
+		ID:           imageID,
+		CarID:        id,
+		ContentType:  contentType,
+		Size:         int64(len(data)),
+		Width:        config.Width,
+		Height:       config.Height,
+		Key:          prefix + ext,
+		ThumbnailKey: prefix + "_thumb.jpg",
+		CreatedAt:    s.now(),
+	}
+
+	if err := s.blobs.Put(ctx, carImage.Key, data, contentType); err != nil {
+		return nil, err
+	}
+	if err := s.blobs.Put(ctx, carImage.ThumbnailKey, thumb, "image/jpeg"); err != nil {
+		s.removeBlobs(ctx, carImage.Key)
+		return nil, err
+	}
+
+	added, err := s.store.AddCarImage(ctx, carImage)
+	if err != nil {
+		s.removeBlobs(ctx, carImage.Key, carImage.ThumbnailKey)
+		return nil, err
+	}
+	added = s.withURLs(added)
+	return &added, nil
+}
+
+func (s *MediaService) ListImages(ctx context.Context, carID string) ([]models.CarImage, error) {
+	tracer := otel.Tracer("MediaService")
+	ctx, span := tracer.Start(ctx, "ListImages-Service")
+	defer span.End()
+
+	if _, err := uuid.Parse(carID); err != nil {
+		return nil, store.ErrCarNotFound
+	}
+
+	images, err := s.store.ListCarImages(ctx, []string{carID})
+	if err != nil {
+		return nil, err
+	}
+	listed := make([]models.CarImage, len(images))
+	for i, image := range images {
+		listed[i] = s.withURLs(image)
+	}
+	return listed, nil
+}
+
+// SetOrder puts the images of the car in the order given, which must list
+// each of them once.
+func (s *MediaService) SetOrder(ctx context.Context, carID string, orderReq *models.CarImageOrderRequest) ([]models.CarImage, error) {
+	tracer := otel.Tracer("MediaService")
+	ctx, span := tracer.Start(ctx, "SetOrder-Service")
+	defer span.End()
+
+	if err := models.ValidateCarImageOrderRequest(*orderReq); err != nil {
+		return nil, invalid(err)
+	}
+
+	images, err := s.ListImages(ctx, carID)
+	if err != nil {
+		return nil, err
+	}
+	if len(images) == 0 {
+		return nil, ErrImageNotFound
+	}
+	known := make(map[uuid.UUID]bool, len(images))
+	for _, image := range images {
+		known[image.ID] = true
+	}
+	ids := make([]string, len(orderReq.ImageIDs))
+	for i, id := range orderReq.ImageIDs {
+		if !known[id] {
+			return nil, ErrImageNotFound
+		}
+		ids[i] = id.String()
+	}
+	if len(ids) != len(images) {
+		return nil, &InvalidError{Reason: "Image IDs must list every image of the car"}
+	}
+
+	if err := s.store.SetCarImageOrder(ctx, carID, ids); err != nil {
+		return nil, err
+	}
+	return s.ListImages(ctx, carID)
+}
+
+func (s *MediaService) SetPrimary(ctx context.Context, carID, imageID string) (*models.CarImage, error) {
+	tracer := otel.Tracer("MediaService")
+	ctx, span := tracer.Start(ctx, "SetPrimary-Service")
+	defer span.End()
+
+	if _, err := uuid.Parse(imageID); err != nil {
+		return nil, ErrImageNotFound
+	}
+
+	primary, err := s.store.SetPrimaryCarImage(ctx, carID, imageID)
+	if err != nil {
+		return nil, err
+	}
+	if primary.ID == uuid.Nil {
+		return nil, ErrImageNotFound
+	}
+	primary = s.withURLs(primary)
+	return &primary, nil
+}
+
+// DeleteImage removes the image and then its files.
+func (s *MediaService) DeleteImage(ctx context.Context, carID, imageID string) (*models.CarImage, error) {
+	tracer := otel.Tracer("MediaService")
+	ctx, span := tracer.Start(ctx, "DeleteImage-Service")
+	defer span.End()
+
+	if _, err := uuid.Parse(imageID); err != nil {
+		return nil, ErrImageNotFound
+	}
+
+	deleted, err := s.store.DeleteCarImage(ctx, carID, imageID)
+	if err != nil {
+		return nil, err
+	}
+	if deleted.ID == uuid.Nil {
+		return nil, ErrImageNotFound
+	}
+	s.removeBlobs(ctx, deleted.Key, deleted.ThumbnailKey)
+	deleted = s.withURLs(deleted)
+	return &deleted, nil
+}
+
+// RemoveFiles deletes the files of images whose rows are already gone,
+// such as those of a deleted car.
+func (s *MediaService) RemoveFiles(ctx context.Context, images []models.CarImage) {
+	for _, image := range images {
+		s.removeBlobs(ctx, image.Key, image.ThumbnailKey)
+	}
+}
+
+// removeBlobs only logs failures, a stray file is better than failing a
+// request whose rows are already committed.
+func (s *MediaService) removeBlobs(ctx context.Context, keys ...string) {
+	for _, key := range keys {
+		if err := s.blobs.Delete(ctx, key); err != nil {
+			log.Printf("Error deleting blob %s: %v", key, err)
+		}
+	}
+}
+
+// AttachImages fills in the images of each car with a single query.
+func (s *MediaService) AttachImages(ctx context.Context, cars []models.Car) ([]models.Car, error) {
+	tracer := otel.Tracer("MediaService")
+	ctx, span := tracer.Start(ctx, "AttachImages-Service")
+	defer span.End()
+
+	carIDs := make([]string, len(cars))
+	for i, car := range cars {
+		carIDs[i] = car.ID.String()
+	}
+	images, err := s.store.ListCarImages(ctx, carIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	byCar := make(map[uuid.UUID][]models.CarImage)
+	for _, image := range images {
+		byCar[image.CarID] = append(byCar[image.CarID], s.withURLs(image))
+	}
+	attached := make([]models.Car, len(cars))
+	for i, car := range cars {
+		car.Images = byCar[car.ID]
+		attached[i] = car
+	}
+	return attached, nil
+}
+
+// Open returns the file stored under key with its content type. Keys are
+// random, so the files are served without checking the dealership.
+func (s *MediaService) Open(ctx context.Context, key string) (io.ReadCloser, string, error) {
+	tracer := otel.Tracer("MediaService")
+	ctx, span := tracer.Start(ctx, "Open-Service")
+	defer span.End()
+
+	if !strings.HasPrefix(key, "cars/") {
+		return nil, "", ErrImageNotFound
+	}
+	contentType := ""
+	for imageType, ext := range extensions {
+		if path.Ext(key) == ext {
+			contentType = imageType
+		}
+	}
+	if contentType == "" {
+		return nil, "", ErrImageNotFound
+	}
+
+	body, err := s.blobs.Get(ctx, key)
+	if err != nil {
+		if errors.Is(err, blob.ErrNotFound) {
+			return nil, "", ErrImageNotFound
+		}
+		return nil, "", err
+	}
+	return body, contentType, nil
+}
@@ -0,0 +1,122 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"testing"
+
+	"github.com/adohong4/carZone/blob"
+	"github.com/adohong4/carZone/models"
+	"github.com/adohong4/carZone/money"
+	"github.com/adohong4/carZone/store"
+	"github.com/adohong4/carZone/store/memory"
+	"github.com/adohong4/carZone/tenant"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestService(t *testing.T) (*MediaService, *memory.Store, models.Car) {
+	ctx := tenant.WithTenant(context.Background(), tenant.DefaultID)
+	memStore := memory.New()
+
+	engine, err := memStore.CreateEngine(ctx, &models.EngineRequest{Displacement: 2000, NoOfCylinders: 4, CarRange: 500})
+	require.NoError(t, err)
+	car, err := memStore.CreateCar(ctx, &models.CarRequest{
+		Name: "Camry", Year: "2023", Brand: "Toyota", FuelType: "Petrol",
+		Engine: models.Engine{EngineID: engine.EngineID}, Price: money.Money{Amount: 2500000, Currency: "USD"},
+	})
+	require.NoError(t, err)
+
+	blobs, err := blob.NewFileStore(t.TempDir())
+	require.NoError(t, err)
+	return NewMediaService(memStore, blobs, DefaultConfig()), memStore, car
+}
+
+func pngImage(t *testing.T, width, height int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.RGBA{R: 200, A: 0xff})
+		}
+	}
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+func TestUploadStoresImageAndThumbnail(t *testing.T) {
+	ctx := tenant.WithTenant(context.Background(), tenant.DefaultID)
+	svc, _, car := newTestService(t)
+
+	uploaded, err := svc.Upload(ctx, car.ID.String(), pngImage(t, 800, 400))
+	require.NoError(t, err)
+	assert.Equal(t, "image/png", uploaded.ContentType)
+	assert.Equal(t, 800, uploaded.Width)
+	assert.True(t, uploaded.Primary)
+	assert.Equal(t, "/media/"+uploaded.Key, uploaded.URL)
+
+	body, contentType, err := svc.Open(ctx, uploaded.ThumbnailKey)
+	require.NoError(t, err)
+	defer body.Close()
+	assert.Equal(t, "image/jpeg", contentType)
+	data, _ := io.ReadAll(body)
+	thumb, err := jpeg.DecodeConfig(bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.Equal(t, 320, thumb.Width)
+	assert.Equal(t, 160, thumb.Height)
+
+	_, err = svc.Upload(ctx, car.ID.String(), []byte("GIF89a"))
+	var invalidErr *InvalidError
+	assert.ErrorAs(t, err, &invalidErr)
+	_, err = svc.Upload(ctx, car.ID.String(), []byte("%PDF-1.4"))
+	assert.ErrorIs(t, err, ErrUnsupportedType)
+	_, err = svc.Upload(ctx, uuid.New().String(), pngImage(t, 10, 10))
+	assert.ErrorIs(t, err, store.ErrCarNotFound)
+
+	svc.config.MaxBytes = 10
+	_, err = svc.Upload(ctx, car.ID.String(), pngImage(t, 10, 10))
+	assert.ErrorIs(t, err, ErrTooLarge)
+}
+
+func TestOrderPrimaryAndDelete(t *testing.T) {
+	ctx := tenant.WithTenant(context.Background(), tenant.DefaultID)
+	svc, _, car := newTestService(t)
+	carID := car.ID.String()
+
+	first, err := svc.Upload(ctx, carID, pngImage(t, 10, 10))
+	require.NoError(t, err)
+	second, err := svc.Upload(ctx, carID, pngImage(t, 10, 10))
+	require.NoError(t, err)
+
+	var invalidErr *InvalidError
+	_, err = svc.SetOrder(ctx, carID, &models.CarImageOrderRequest{ImageIDs: []uuid.UUID{second.ID}})
+	assert.ErrorAs(t, err, &invalidErr)
+	_, err = svc.SetOrder(ctx, carID, &models.CarImageOrderRequest{ImageIDs: []uuid.UUID{second.ID, uuid.New()}})
+	assert.ErrorIs(t, err, ErrImageNotFound)
+
+	images, err := svc.SetOrder(ctx, carID, &models.CarImageOrderRequest{ImageIDs: []uuid.UUID{second.ID, first.ID}})
+	require.NoError(t, err)
+	assert.Equal(t, second.ID, images[0].ID)
+
+	primary, err := svc.SetPrimary(ctx, carID, second.ID.String())
+	require.NoError(t, err)
+	assert.True(t, primary.Primary)
+
+	cars, err := svc.AttachImages(ctx, []models.Car{car})
+	require.NoError(t, err)
+	require.Len(t, cars[0].Images, 2)
+	assert.True(t, cars[0].Images[0].Primary)
+
+	_, err = svc.DeleteImage(ctx, carID, second.ID.String())
+	require.NoError(t, err)
+	_, _, err = svc.Open(ctx, second.Key)
+	assert.ErrorIs(t, err, ErrImageNotFound)
+	_, err = svc.DeleteImage(ctx, carID, second.ID.String())
+	assert.ErrorIs(t, err, ErrImageNotFound)
+}
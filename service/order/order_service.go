@@ -0,0 +1,234 @@
+package order
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/adohong4/carZone/invoice"
+	"github.com/adohong4/carZone/models"
+	"github.com/adohong4/carZone/store"
+	"github.com/adohong4/carZone/tenant"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+)
+
+var ErrOrderNotFound = errors.New("order not found")
+
+// InvalidError is returned when a request fails validation.
+type InvalidError struct {
+	Reason string
+}
+
+func (e *InvalidError) Error() string {
+	return e.Reason
+}
+
+func invalid(err error) error {
+	return &InvalidError{Reason: err.Error()}
+}
+
+type OrderService struct {
+	store       store.OrderStoreInterface
+	cars        store.CarStoreInterface
+	dealerships store.DealershipStoreInterface
+	now         func() time.Time
+}
+
+func NewOrderService(store store.OrderStoreInterface, cars store.CarStoreInterface, dealerships store.DealershipStoreInterface) *OrderService {
+	return &OrderService{
+		store:       store,
+		cars:        cars,
+		dealerships: dealerships,
+		now:         time.Now,
+	}
+}
+
+// CreateOrder starts a draft order sold by salesperson.
+func (s *OrderService) CreateOrder(ctx context.Context, salesperson string, orderReq *models.OrderRequest) (*models.Order, error) {
+	tracer := otel.Tracer("OrderService")
+	ctx, span := tracer.Start(ctx, "CreateOrder-Service")
+	defer span.End()
+
+	now := s.now()
+	order := models.Order{
+		ID:          uuid.New(),
+		Status:      models.OrderDraft,
+		Salesperson: salesperson,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if err := s.fill(ctx, &order, orderReq); err != nil {
+		return nil, err
+	}
+
+	created, err := s.store.CreateOrder(ctx, order)
+	if err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+func (s *OrderService) GetOrder(ctx context.Context, id string) (*models.Order, error) {
+	tracer := otel.Tracer("OrderService")
+	ctx, span := tracer.Start(ctx, "GetOrder-Service")
+	defer span.End()
+
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, ErrOrderNotFound
+	}
+
+	order, err := s.store.GetOrder(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if order.ID == uuid.Nil {
+		return nil, ErrOrderNotFound
+	}
+	return &order, nil
+}
+
+func (s *OrderService) ListOrders(ctx context.Context, status string) ([]models.Order, error) {
+	tracer := otel.Tracer("OrderService")
+	ctx, span := tracer.Start(ctx, "ListOrders-Service")
+	defer span.End()
+
+	if status != "" {
+		if err := models.ValidateOrderStatus(status); err != nil {
+			return nil, invalid(err)
+		}
+	}
+
+	orders, err := s.store.ListOrders(ctx, status)
+	if err != nil {
+		return nil, err
+	}
+	if orders == nil {
+		orders = []models.Order{}
+	}
+	return orders, nil
+}
+
+// UpdateOrder replaces the customer, tax rate and lines of a draft.
+func (s *OrderService) UpdateOrder(ctx context.Context, id string, orderReq *models.OrderRequest) (*models.Order, error) {
+	tracer := otel.Tracer("OrderService")
+	ctx, span := tracer.Start(ctx, "UpdateOrder-Service")
+	defer span.End()
+
+	current, err := s.GetOrder(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	order := *current
+	order.UpdatedAt = s.now()
+	if err := s.fill(ctx, &order, orderReq); err != nil {
+		return nil, err
+	}
+
+	updated, err := s.store.UpdateOrder(ctx, order)
+	if err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+// ChangeStatus moves an order along draft, confirmed, paid and delivered,
+// or cancels it. Confirming gives the order its invoice number.
+func (s *OrderService) ChangeStatus(ctx context.Context, id, status string) (*models.Order, error) {
+	tracer := otel.Tracer("OrderService")
+	ctx, span := tracer.Start(ctx, "ChangeStatus-Service")
+	defer span.End()
+
+	current, err := s.GetOrder(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := models.ValidateOrderTransition(current.Status, status); err != nil {
+		return nil, invalid(err)
+	}
+
+	updated, err := s.store.UpdateOrderStatus(ctx, id, current.Status, status, s.now())
+	if err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+// Invoice returns the invoice of a confirmed order, invoice.ErrNotInvoiced
+// for drafts.
+func (s *OrderService) Invoice(ctx context.Context, id string) (*invoice.Invoice, error) {
+	tracer := otel.Tracer("OrderService")
+	ctx, span := tracer.Start(ctx, "Invoice-Service")
+	defer span.End()
+
+	order, err := s.GetOrder(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if order.InvoiceNumber == "" {
+		return nil, invoice.ErrNotInvoiced
+	}
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	seller, err := s.dealerships.GetDealershipById(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	return &invoice.Invoice{Seller: seller, Order: *order}, nil
+}
+
+// fill validates orderReq and copies it into order. Lines take the price
+// and name of their car unless the request sets them, a car priced in
+// another currency than the order needs an explicit price.
+func (s *OrderService) fill(ctx context.Context, order *models.Order, orderReq *models.OrderRequest) error {
+	if err := models.ValidateOrderRequest(*orderReq); err != nil {
+		return invalid(err)
+	}
+
+	order.CustomerID = orderReq.CustomerID
+	order.CustomerName = orderReq.CustomerName
+	order.CustomerEmail = orderReq.CustomerEmail
+	order.CustomerAddress = orderReq.CustomerAddress
+	order.Currency = orderReq.Currency
+	order.TaxRate = orderReq.TaxRate
+	order.Lines = make([]models.OrderLine, 0, len(orderReq.Lines))
+
+	for _, lineReq := range orderReq.Lines {
+		car, err := s.cars.GetCarById(ctx, lineReq.CarID.String())
+		if err != nil {
+			return err
+		}
+		if car.ID == uuid.Nil {
+			return store.ErrCarNotFound
+		}
+		if order.Currency == "" {
+			order.Currency = car.Price.Currency
+		}
+
+		line := models.OrderLine{
+			ID:              uuid.New(),
+			CarID:           car.ID,
+			Description:     lineReq.Description,
+			Quantity:        lineReq.Quantity,
+			UnitPrice:       car.Price.Decimal(),
+			DiscountPercent: lineReq.DiscountPercent,
+		}
+		if lineReq.UnitPrice != nil {
+			line.UnitPrice = lineReq.UnitPrice.Round(order.MinorUnits())
+		} else if car.Price.Currency != order.Currency {
+			return &InvalidError{Reason: "Car " + car.ID.String() + " is priced in " + car.Price.Currency + ", give a unit price in " + order.Currency}
+		}
+		if line.Description == "" {
+			line.Description = strings.Join([]string{car.Brand, car.Name, car.Year}, " ")
+		}
+		order.Lines = append(order.Lines, line)
+	}
+
+	order.ComputeTotals()
+	return nil
+}
@@ -0,0 +1,83 @@
+package order
+
+import (
+	"context"
+	"testing"
+
+	"github.com/adohong4/carZone/decimal"
+	"github.com/adohong4/carZone/invoice"
+	"github.com/adohong4/carZone/models"
+	"github.com/adohong4/carZone/money"
+	"github.com/adohong4/carZone/store/memory"
+	"github.com/adohong4/carZone/tenant"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestService(t *testing.T) (*OrderService, models.Car) {
+	ctx := tenant.WithTenant(context.Background(), tenant.DefaultID)
+	memStore := memory.New()
+
+	engine, err := memStore.CreateEngine(ctx, &models.EngineRequest{Displacement: 2000, NoOfCylinders: 4, CarRange: 500})
+	require.NoError(t, err)
+	car, err := memStore.CreateCar(ctx, &models.CarRequest{
+		Name: "Camry", Year: "2023", Brand: "Toyota", FuelType: "Petrol",
+		Engine: models.Engine{EngineID: engine.EngineID}, Price: money.Money{Amount: 2500050, Currency: "USD"},
+	})
+	require.NoError(t, err)
+
+	return NewOrderService(memStore, memStore, memStore), car
+}
+
+func TestCreateOrderDefaultsFromCar(t *testing.T) {
+	ctx := tenant.WithTenant(context.Background(), tenant.DefaultID)
+	svc, car := newTestService(t)
+
+	order, err := svc.CreateOrder(ctx, "alice", &models.OrderRequest{
+		CustomerName: "Jane Doe",
+		TaxRate:      decimal.New(20, 0),
+		Lines: []models.OrderLineRequest{
+			{CarID: car.ID, Quantity: 2, DiscountPercent: decimal.New(10, 0)},
+		},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, models.OrderDraft, order.Status)
+	assert.Equal(t, "alice", order.Salesperson)
+	assert.Equal(t, "Toyota Camry 2023", order.Lines[0].Description)
+	assert.Equal(t, "25000.50", order.Lines[0].UnitPrice.StringFixed(2))
+	assert.Equal(t, "45000.90", order.Subtotal.StringFixed(2))
+	assert.Equal(t, "9000.18", order.TaxTotal.StringFixed(2))
+	assert.Equal(t, "54001.08", order.Total.StringFixed(2))
+
+	var invalidErr *InvalidError
+	_, err = svc.CreateOrder(ctx, "alice", &models.OrderRequest{CustomerName: "Jane Doe"})
+	assert.ErrorAs(t, err, &invalidErr)
+}
+
+func TestInvoiceAfterConfirm(t *testing.T) {
+	ctx := tenant.WithTenant(context.Background(), tenant.DefaultID)
+	svc, car := newTestService(t)
+
+	order, err := svc.CreateOrder(ctx, "alice", &models.OrderRequest{
+		CustomerName: "Jane Doe",
+		Lines:        []models.OrderLineRequest{{CarID: car.ID, Quantity: 1}},
+	})
+	require.NoError(t, err)
+
+	_, err = svc.Invoice(ctx, order.ID.String())
+	assert.ErrorIs(t, err, invoice.ErrNotInvoiced)
+
+	var invalidErr *InvalidError
+	_, err = svc.ChangeStatus(ctx, order.ID.String(), models.OrderPaid)
+	assert.ErrorAs(t, err, &invalidErr)
+
+	confirmed, err := svc.ChangeStatus(ctx, order.ID.String(), models.OrderConfirmed)
+	require.NoError(t, err)
+	assert.Equal(t, "INV-000001", confirmed.InvoiceNumber)
+
+	inv, err := svc.Invoice(ctx, order.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, tenant.DefaultID, inv.Seller.ID.String())
+	assert.Equal(t, "INV-000001", inv.Order.InvoiceNumber)
+}
@@ -0,0 +1,174 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/adohong4/carZone/events"
+	"github.com/adohong4/carZone/models"
+	"github.com/adohong4/carZone/store"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel"
+)
+
+var publishedEvents = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "outbox_events_published_total",
+		Help: "Total number of outbox events handed to a sink by result",
+	},
+	[]string{"sink", "result"},
+)
+
+func init() {
+	prometheus.MustRegister(publishedEvents)
+}
+
+// Config tunes the relay: it polls the outbox every PollInterval for up to
+// BatchSize events and drops the events published more than Retention ago.
+// Claimed events are left to the relay for ClaimTimeout, the sinks get half
+// of it for the whole batch. A failed event is tried again after
+// BaseBackoff, doubling with each attempt up to MaxBackoff.
+type Config struct {
+	PollInterval time.Duration
+	BatchSize    int
+	Retention    time.Duration
+	ClaimTimeout time.Duration
+	BaseBackoff  time.Duration
+	MaxBackoff   time.Duration
+}
+
+func DefaultConfig() Config {
+	return Config{
+		PollInterval: time.Second,
+		BatchSize:    100,
+		Retention:    7 * 24 * time.Hour,
+		ClaimTimeout: time.Minute,
+		BaseBackoff:  time.Second,
+		MaxBackoff:   5 * time.Minute,
+	}
+}
+
+// Relay publishes the events of the outbox to every sink, at least once and
+// in order per entity: an event is published when all sinks took it, one
+// that failed is backed off and holds back the later events of its entity
+// until it goes through, while the events of other entities carry on. A
+// sink can see an event again after another sink failed on it.
+type Relay struct {
+	store  store.OutboxStoreInterface
+	sinks  []events.Sink
+	config Config
+	now    func() time.Time
+}
+
+func NewRelay(store store.OutboxStoreInterface, sinks []events.Sink, config Config) *Relay {
+	return &Relay{
+		store:  store,
+		sinks:  sinks,
+		config: config,
+		now:    time.Now,
+	}
+}
+
+// RelayEvents publishes a batch of due events and returns how many went
+// out. The batch is claimed and its results recorded in two short
+// transactions, none is open while the sinks are called. The sinks are
+// cut off halfway through the claim, so the results are in before another
+// relay may claim the batch; a claimed event whose result is lost, when
+// the process stops, is published again once the claim runs out.
+func (r *Relay) RelayEvents(ctx context.Context) (int, error) {
+	tracer := otel.Tracer("OutboxRelay")
+	ctx, span := tracer.Start(ctx, "RelayEvents-Service")
+	defer span.End()
+
+	now := r.now().UTC()
+	pending, err := r.store.ClaimEvents(ctx, now, now.Add(r.config.ClaimTimeout), r.config.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+	if len(pending) == 0 {
+		return 0, nil
+	}
+	publishCtx, cancel := context.WithTimeout(ctx, r.config.ClaimTimeout/2)
+	results := r.publish(publishCtx, pending)
+	cancel()
+	return r.store.FinishEvents(ctx, results, r.now().UTC())
+}
+
+// publish hands the batch to the sinks. The events held behind a failed
+// one of their entity are released at once, the outbox keeps them back
+// for as long as the failed one is backed off.
+func (r *Relay) publish(ctx context.Context, batch []models.PendingEvent) []models.EventResult {
+	results := make([]models.EventResult, len(batch))
+	held := make(map[string]bool)
+	for i, next := range batch {
+		event := next.Event
+		results[i].Sequence = event.Sequence
+		if held[event.Entity()] || ctx.Err() != nil {
+			results[i].RetryAt = r.now().UTC()
+			continue
+		}
+		var publishErr error
+		for _, sink := range r.sinks {
+			if err := sink.Publish(ctx, event); err != nil {
+				publishedEvents.WithLabelValues(sink.Name(), "error").Inc()
+				publishErr = fmt.Errorf("%s: %w", sink.Name(), err)
+				break
+			}
+			publishedEvents.WithLabelValues(sink.Name(), "ok").Inc()
+		}
+		if publishErr == nil {
+			results[i].Published = true
+			continue
+		}
+		log.Printf("Error publishing event %s (%s): %v", event.ID, event.Type, publishErr)
+		held[event.Entity()] = true
+		results[i].Error = publishErr.Error()
+		results[i].RetryAt = r.now().UTC().Add(r.backoff(next.Attempts + 1))
+	}
+	return results
+}
+
+// backoff is how long to wait after the given number of failed attempts.
+func (r *Relay) backoff(attempts int) time.Duration {
+	wait := r.config.BaseBackoff
+	for i := 1; i < attempts && wait < r.config.MaxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > r.config.MaxBackoff {
+		wait = r.config.MaxBackoff
+	}
+	return wait
+}
+
+// Run relays the outbox every PollInterval until ctx is done, straight on
+// while full batches keep coming, and prunes it once an hour.
+func (r *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.config.PollInterval)
+	defer ticker.Stop()
+
+	var pruned time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for {
+				published, err := r.RelayEvents(ctx)
+				if err != nil {
+					log.Printf("Error relaying outbox events: %v", err)
+				}
+				if err != nil || published < r.config.BatchSize {
+					break
+				}
+			}
+			if now := r.now(); now.Sub(pruned) >= time.Hour {
+				pruned = now
+				if _, err := r.store.DeletePublishedEvents(ctx, now.Add(-r.config.Retention)); err != nil {
+					log.Printf("Error pruning outbox events: %v", err)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/adohong4/carZone/events"
+	"github.com/adohong4/carZone/models"
+	"github.com/adohong4/carZone/store/memory"
+	"github.com/adohong4/carZone/tenant"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSink records the events it takes and fails the ones fail picks. The
+// ones slow picks are only failed once the context is done.
+type fakeSink struct {
+	name   string
+	fail   func(event models.Event) bool
+	slow   func(event models.Event) bool
+	events []models.Event
+}
+
+func (s *fakeSink) Name() string {
+	return s.name
+}
+
+func (s *fakeSink) Publish(ctx context.Context, event models.Event) error {
+	if s.slow != nil && s.slow(event) {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	if s.fail != nil && s.fail(event) {
+		return errors.New("unavailable")
+	}
+	s.events = append(s.events, event)
+	return nil
+}
+
+func types(events []models.Event) []string {
+	names := make([]string, len(events))
+	for i, event := range events {
+		names[i] = event.Type
+	}
+	return names
+}
+
+func TestRelayHoldsEntityAfterFailure(t *testing.T) {
+	memStore := memory.New()
+	ctx := tenant.WithTenant(context.Background(), tenant.DefaultID)
+	engine, err := memStore.CreateEngine(ctx, &models.EngineRequest{Displacement: 2000, NoOfCylinders: 4, CarRange: 600})
+	require.NoError(t, err)
+	_, err = memStore.EngineUpdate(ctx, engine.EngineID.String(), &models.EngineRequest{Displacement: 2200, NoOfCylinders: 4, CarRange: 650})
+	require.NoError(t, err)
+	other, err := memStore.CreateEngine(ctx, &models.EngineRequest{Displacement: 1600, NoOfCylinders: 4, CarRange: 500})
+	require.NoError(t, err)
+
+	down := true
+	first := &fakeSink{name: "first"}
+	second := &fakeSink{name: "second", fail: func(event models.Event) bool {
+		return down && event.EntityID == engine.EngineID
+	}}
+	relay := NewRelay(memStore, []events.Sink{first, second}, DefaultConfig())
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	relay.now = func() time.Time { return now }
+
+	published, err := relay.RelayEvents(context.Background())
+	require.NoError(t, err)
+	// the update of the failed engine waits, the other engine goes through
+	assert.Equal(t, 1, published)
+	assert.Equal(t, []string{models.EventEngineCreated, models.EventEngineCreated}, types(first.events))
+	require.Len(t, second.events, 1)
+	assert.Equal(t, other.EngineID, second.events[0].EntityID)
+
+	down = false
+	// the failed event is backed off
+	published, err = relay.RelayEvents(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, published)
+
+	now = now.Add(time.Second)
+	published, err = relay.RelayEvents(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, published)
+	// the first sink sees the created event again, then the update in order
+	assert.Equal(t, []string{models.EventEngineCreated, models.EventEngineCreated, models.EventEngineCreated, models.EventEngineUpdated},
+		types(first.events))
+	assert.Equal(t, first.events[0].ID, first.events[2].ID)
+	assert.Equal(t, []string{models.EventEngineCreated, models.EventEngineCreated, models.EventEngineUpdated}, types(second.events))
+
+	published, err = relay.RelayEvents(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, published)
+}
+
+func TestRelayDoesNotStallBehindFailingEntity(t *testing.T) {
+	memStore := memory.New()
+	ctx := tenant.WithTenant(context.Background(), tenant.DefaultID)
+	failing, err := memStore.CreateEngine(ctx, &models.EngineRequest{Displacement: 2000, NoOfCylinders: 4, CarRange: 600})
+	require.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		_, err = memStore.EngineUpdate(ctx, failing.EngineID.String(), &models.EngineRequest{Displacement: 2000 + int64(i), NoOfCylinders: 4, CarRange: 600})
+		require.NoError(t, err)
+	}
+	healthy, err := memStore.CreateEngine(ctx, &models.EngineRequest{Displacement: 1600, NoOfCylinders: 4, CarRange: 500})
+	require.NoError(t, err)
+
+	sink := &fakeSink{name: "sink", fail: func(event models.Event) bool {
+		return event.EntityID == failing.EngineID
+	}}
+	config := DefaultConfig()
+	config.BatchSize = 2
+	relay := NewRelay(memStore, []events.Sink{sink}, config)
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	relay.now = func() time.Time { return now }
+
+	// a full batch of the failing engine's events does not keep the other
+	// engine waiting
+	published, err := relay.RelayEvents(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, published)
+	published, err = relay.RelayEvents(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, published)
+	require.Len(t, sink.events, 1)
+	assert.Equal(t, healthy.EngineID, sink.events[0].EntityID)
+}
+
+func TestRelayFinishesBeforeClaimRunsOut(t *testing.T) {
+	memStore := memory.New()
+	ctx := tenant.WithTenant(context.Background(), tenant.DefaultID)
+	stuck, err := memStore.CreateEngine(ctx, &models.EngineRequest{Displacement: 2000, NoOfCylinders: 4, CarRange: 600})
+	require.NoError(t, err)
+	_, err = memStore.EngineUpdate(ctx, stuck.EngineID.String(), &models.EngineRequest{Displacement: 2200, NoOfCylinders: 4, CarRange: 650})
+	require.NoError(t, err)
+	other, err := memStore.CreateEngine(ctx, &models.EngineRequest{Displacement: 1600, NoOfCylinders: 4, CarRange: 500})
+	require.NoError(t, err)
+
+	config := DefaultConfig()
+	config.ClaimTimeout = 200 * time.Millisecond
+	sink := &fakeSink{name: "sink", slow: func(event models.Event) bool {
+		return event.EntityID == stuck.EngineID
+	}}
+	relay := NewRelay(memStore, []events.Sink{sink}, config)
+
+	// the sink would outlast the claim, it is cut off and the results are
+	// recorded before another relay could claim the batch
+	start := time.Now()
+	published, err := relay.RelayEvents(context.Background())
+	require.NoError(t, err)
+	assert.Less(t, time.Since(start), config.ClaimTimeout)
+	assert.Zero(t, published)
+	assert.Empty(t, sink.events)
+
+	// another replica only gets the released event, the stuck one is
+	// backed off with its update held behind it
+	replicaSink := &fakeSink{name: "sink"}
+	replica := NewRelay(memStore, []events.Sink{replicaSink}, config)
+	published, err = replica.RelayEvents(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, published)
+	require.Len(t, replicaSink.events, 1)
+	assert.Equal(t, other.EngineID, replicaSink.events[0].EntityID)
+}
+
+func TestBackoff(t *testing.T) {
+	relay := NewRelay(memory.New(), nil, DefaultConfig())
+	var waits []time.Duration
+	for attempts := 1; attempts <= 10; attempts++ {
+		waits = append(waits, relay.backoff(attempts))
+	}
+	assert.Equal(t, []time.Duration{
+		time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 16 * time.Second,
+		32 * time.Second, 64 * time.Second, 128 * time.Second, 256 * time.Second, 5 * time.Minute,
+	}, waits)
+}
@@ -0,0 +1,66 @@
+package pricing
+
+import (
+	"context"
+
+	"github.com/adohong4/carZone/models"
+	"github.com/adohong4/carZone/service"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+)
+
+// CarService takes running promotions off the prices of the cars next
+// returns. It goes in front of the cache so promotions start and stop on
+// time.
+type CarService struct {
+	service.CarServiceInterface
+	pricing service.PricingServiceInterface
+}
+
+func NewCarService(next service.CarServiceInterface, pricing service.PricingServiceInterface) *CarService {
+	return &CarService{
+		CarServiceInterface: next,
+		pricing:             pricing,
+	}
+}
+
+func (s *CarService) GetCarById(ctx context.Context, id string) (*models.Car, error) {
+	tracer := otel.Tracer("PricingCarService")
+	ctx, span := tracer.Start(ctx, "GetCarById-Service")
+	defer span.End()
+
+	car, err := s.CarServiceInterface.GetCarById(ctx, id)
+	if err != nil || car.ID == uuid.Nil {
+		return car, err
+	}
+
+	promoted, err := s.pricing.ApplyPromotions(ctx, []models.Car{*car})
+	if err != nil {
+		return nil, err
+	}
+	return &promoted[0], nil
+}
+
+func (s *CarService) CompareCars(ctx context.Context, ids []string) ([]models.Car, error) {
+	tracer := otel.Tracer("PricingCarService")
+	ctx, span := tracer.Start(ctx, "CompareCars-Service")
+	defer span.End()
+
+	cars, err := s.CarServiceInterface.CompareCars(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	return s.pricing.ApplyPromotions(ctx, cars)
+}
+
+func (s *CarService) GetCarByBrand(ctx context.Context, brand string, isEngine bool) ([]models.Car, error) {
+	tracer := otel.Tracer("PricingCarService")
+	ctx, span := tracer.Start(ctx, "GetCarByBrand-Service")
+	defer span.End()
+
+	cars, err := s.CarServiceInterface.GetCarByBrand(ctx, brand, isEngine)
+	if err != nil || len(cars) == 0 {
+		return cars, err
+	}
+	return s.pricing.ApplyPromotions(ctx, cars)
+}
@@ -0,0 +1,363 @@
+package pricing
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/adohong4/carZone/models"
+	"github.com/adohong4/carZone/store"
+	"github.com/adohong4/carZone/tenant"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+)
+
+var (
+	ErrPriceNotFound     = errors.New("price not found")
+	ErrPriceInEffect     = errors.New("price is already in effect")
+	ErrPromotionNotFound = errors.New("promotion not found")
+)
+
+// InvalidError is returned when a request fails validation.
+type InvalidError struct {
+	Reason string
+}
+
+func (e *InvalidError) Error() string {
+	return e.Reason
+}
+
+func invalid(err error) error {
+	return &InvalidError{Reason: err.Error()}
+}
+
+// clockSkew is how far in the past a price may start, so a client asking
+// for "now" with a clock slightly behind is not refused.
+const clockSkew = time.Minute
+
+// Config controls how often the scheduler brings car prices up to date.
+type Config struct {
+	ScheduleInterval time.Duration
+}
+
+func DefaultConfig() Config {
+	return Config{
+		ScheduleInterval: time.Minute,
+	}
+}
+
+// CarCache drops the cached entries of cars, cached.CarService implements
+// it.
+type CarCache interface {
+	InvalidateCars(ctx context.Context, ids ...string)
+}
+
+type PricingService struct {
+	store  store.PriceStoreInterface
+	config Config
+	now    func() time.Time
+	cache  CarCache
+}
+
+func NewPricingService(store store.PriceStoreInterface, config Config) *PricingService {
+	return &PricingService{
+		store:  store,
+		config: config,
+		now:    time.Now,
+	}
+}
+
+// UseCarCache has the cars the scheduler reprices dropped from c. Prices are
+// written below the cached car service, without it the cache would keep
+// serving the old price until the entries expire.
+func (s *PricingService) UseCarCache(c CarCache) {
+	s.cache = c
+}
+
+// applyCarPrices brings car prices in line with their history at now and
+// invalidates the cached cars it changed, per dealership.
+func (s *PricingService) applyCarPrices(ctx context.Context, now time.Time) (int64, error) {
+	repriced, err := s.store.ApplyCarPrices(ctx, now)
+	if err != nil {
+		return 0, err
+	}
+	if s.cache != nil && len(repriced) > 0 {
+		byTenant := make(map[string][]string)
+		for _, car := range repriced {
+			byTenant[car.TenantID] = append(byTenant[car.TenantID], car.CarID.String())
+		}
+		for tenantID, ids := range byTenant {
+			s.cache.InvalidateCars(tenant.WithTenant(ctx, tenantID), ids...)
+		}
+	}
+	return int64(len(repriced)), nil
+}
+
+// PriceHistory lists every price of the car, oldest first, marked past,
+// current or scheduled.
+func (s *PricingService) PriceHistory(ctx context.Context, carID string) ([]models.CarPrice, error) {
+	tracer := otel.Tracer("PricingService")
+	ctx, span := tracer.Start(ctx, "PriceHistory-Service")
+	defer span.End()
+
+	if _, err := uuid.Parse(carID); err != nil {
+		return nil, store.ErrCarNotFound
+	}
+
+	prices, err := s.store.ListCarPrices(ctx, carID)
+	if err != nil {
+		return nil, err
+	}
+	// every car starts its history when it is created
+	if len(prices) == 0 {
+		return nil, store.ErrCarNotFound
+	}
+
+	now := s.now()
+	current, _ := models.EffectiveCarPrice(prices, now)
+	for i := range prices {
+		switch {
+		case prices[i].ID == current.ID:
+			prices[i].Status = models.CarPriceCurrent
+		case prices[i].EffectiveFrom.After(now):
+			prices[i].Status = models.CarPriceScheduled
+		default:
+			prices[i].Status = models.CarPricePast
+		}
+	}
+	return prices, nil
+}
+
+// SchedulePrice adds a price set by username. Prices without a start apply
+// at once, they cannot start in the past.
+func (s *PricingService) SchedulePrice(ctx context.Context, carID, username string, priceReq *models.CarPriceRequest) (*models.CarPrice, error) {
+	tracer := otel.Tracer("PricingService")
+	ctx, span := tracer.Start(ctx, "SchedulePrice-Service")
+	defer span.End()
+
+	id, err := uuid.Parse(carID)
+	if err != nil {
+		return nil, store.ErrCarNotFound
+	}
+
+	now := s.now()
+	if priceReq.EffectiveFrom.IsZero() {
+		priceReq.EffectiveFrom = now
+	}
+	if err := models.ValidateCarPriceRequest(*priceReq); err != nil {
+		return nil, invalid(err)
+	}
+	if priceReq.EffectiveFrom.Before(now.Add(-clockSkew)) {
+		return nil, &InvalidError{Reason: "Effective from cannot be in the past"}
+	}
+	if priceReq.EffectiveFrom.Before(now) {
+		priceReq.EffectiveFrom = now
+	}
+
+	price, err := s.store.AddCarPrice(ctx, models.CarPrice{
+		ID:            uuid.New(),
+		CarID:         id,
+		Price:         priceReq.Price,
+		EffectiveFrom: priceReq.EffectiveFrom,
+		EffectiveTo:   priceReq.EffectiveTo,
+		CreatedBy:     username,
+		CreatedAt:     now,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// a price starting now should not wait for the scheduler
+	if !price.EffectiveFrom.After(now) {
+		if _, err := s.applyCarPrices(ctx, now); err != nil {
+			return nil, err
+		}
+	}
+	return &price, nil
+}
+
+// CancelPrice removes a price that has not started yet.
+func (s *PricingService) CancelPrice(ctx context.Context, carID, priceID string) (*models.CarPrice, error) {
+	tracer := otel.Tracer("PricingService")
+	ctx, span := tracer.Start(ctx, "CancelPrice-Service")
+	defer span.End()
+
+	prices, err := s.PriceHistory(ctx, carID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, price := range prices {
+		if price.ID.String() != priceID {
+			continue
+		}
+		if price.Status != models.CarPriceScheduled {
+			return nil, ErrPriceInEffect
+		}
+		deleted, err := s.store.DeleteCarPrice(ctx, carID, priceID, s.now())
+		if err != nil {
+			return nil, err
+		}
+		if deleted.ID == uuid.Nil {
+			return nil, ErrPriceInEffect
+		}
+		return &deleted, nil
+	}
+	return nil, ErrPriceNotFound
+}
+
+// ApplyPrices brings the price of every car, in every dealership, in line
+// with its price history.
+func (s *PricingService) ApplyPrices(ctx context.Context) (int64, error) {
+	tracer := otel.Tracer("PricingService")
+	ctx, span := tracer.Start(ctx, "ApplyPrices-Service")
+	defer span.End()
+
+	return s.applyCarPrices(ctx, s.now())
+}
+
+// RunScheduler applies the price history every ScheduleInterval until ctx
+// is done.
+func (s *PricingService) RunScheduler(ctx context.Context) {
+	ticker := time.NewTicker(s.config.ScheduleInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			applied, err := s.ApplyPrices(ctx)
+			if err != nil {
+				log.Printf("Error applying scheduled prices: %v", err)
+				continue
+			}
+			if applied > 0 {
+				log.Printf("Applied scheduled prices to %d cars", applied)
+			}
+		}
+	}
+}
+
+func (s *PricingService) ListPromotions(ctx context.Context) ([]models.Promotion, error) {
+	tracer := otel.Tracer("PricingService")
+	ctx, span := tracer.Start(ctx, "ListPromotions-Service")
+	defer span.End()
+
+	promotions, err := s.store.ListPromotions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if promotions == nil {
+		promotions = []models.Promotion{}
+	}
+	return promotions, nil
+}
+
+func (s *PricingService) CreatePromotion(ctx context.Context, promotionReq *models.PromotionRequest) (*models.Promotion, error) {
+	tracer := otel.Tracer("PricingService")
+	ctx, span := tracer.Start(ctx, "CreatePromotion-Service")
+	defer span.End()
+
+	if err := models.ValidatePromotionRequest(*promotionReq); err != nil {
+		return nil, invalid(err)
+	}
+
+	promotion := models.Promotion{
+		ID:        uuid.New(),
+		Name:      promotionReq.Name,
+		Kind:      promotionReq.Kind,
+		Brand:     promotionReq.Brand,
+		FuelType:  promotionReq.FuelType,
+		StartsAt:  promotionReq.StartsAt,
+		EndsAt:    promotionReq.EndsAt,
+		CreatedAt: s.now(),
+	}
+	// only the field of the kind is kept
+	if promotion.Kind == models.PromotionPercentage {
+		promotion.Percent = promotionReq.Percent
+	} else {
+		promotion.Amount = promotionReq.Amount
+	}
+
+	created, err := s.store.CreatePromotion(ctx, promotion)
+	if err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+func (s *PricingService) DeletePromotion(ctx context.Context, id string) (*models.Promotion, error) {
+	tracer := otel.Tracer("PricingService")
+	ctx, span := tracer.Start(ctx, "DeletePromotion-Service")
+	defer span.End()
+
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, ErrPromotionNotFound
+	}
+
+	deleted, err := s.store.DeletePromotion(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if deleted.ID == uuid.Nil {
+		return nil, ErrPromotionNotFound
+	}
+	return &deleted, nil
+}
+
+// ApplyPromotions takes the best running promotion off the price of each
+// car and keeps the price it replaced as ListPrice.
+func (s *PricingService) ApplyPromotions(ctx context.Context, cars []models.Car) ([]models.Car, error) {
+	tracer := otel.Tracer("PricingService")
+	ctx, span := tracer.Start(ctx, "ApplyPromotions-Service")
+	defer span.End()
+
+	promotions, err := s.store.ListPromotions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	var running []models.Promotion
+	for _, promotion := range promotions {
+		if promotion.Active(now) {
+			running = append(running, promotion)
+		}
+	}
+
+	promoted := make([]models.Car, len(cars))
+	for i, car := range cars {
+		promoted[i] = applyBest(car, running)
+	}
+	return promoted, nil
+}
+
+// applyBest picks the promotion taking the most off the car's price, the
+// one that started first on a tie.
+func applyBest(car models.Car, promotions []models.Promotion) models.Car {
+	var (
+		best     *models.Promotion
+		discount int64
+	)
+	for i := range promotions {
+		if !promotions[i].Matches(car) {
+			continue
+		}
+		off, ok := promotions[i].Discount(car.Price)
+		if ok && off.Amount > discount {
+			best, discount = &promotions[i], off.Amount
+		}
+	}
+	if best == nil {
+		return car
+	}
+
+	listPrice := car.Price
+	promotion := *best
+	car.ListPrice = &listPrice
+	car.Promotion = &promotion
+	car.Price.Amount -= discount
+	return car
+}
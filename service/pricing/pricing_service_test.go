@@ -0,0 +1,146 @@
+package pricing
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/adohong4/carZone/decimal"
+	"github.com/adohong4/carZone/models"
+	"github.com/adohong4/carZone/money"
+	"github.com/adohong4/carZone/store"
+	"github.com/adohong4/carZone/store/memory"
+	"github.com/adohong4/carZone/tenant"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestService(t *testing.T, now *time.Time) (*PricingService, *memory.Store, models.Car) {
+	ctx := tenant.WithTenant(context.Background(), tenant.DefaultID)
+	memStore := memory.New()
+
+	engine, err := memStore.CreateEngine(ctx, &models.EngineRequest{Displacement: 2000, NoOfCylinders: 4, CarRange: 500})
+	require.NoError(t, err)
+	car, err := memStore.CreateCar(ctx, &models.CarRequest{
+		Name: "Camry", Year: "2023", Brand: "Toyota", FuelType: "Hybrid",
+		Engine: models.Engine{EngineID: engine.EngineID}, Price: money.Money{Amount: 2500000, Currency: "USD"},
+	})
+	require.NoError(t, err)
+
+	svc := NewPricingService(memStore, DefaultConfig())
+	svc.now = func() time.Time { return *now }
+	return svc, memStore, car
+}
+
+func TestScheduledPriceHistory(t *testing.T) {
+	ctx := tenant.WithTenant(context.Background(), tenant.DefaultID)
+	now := time.Now().Add(time.Second)
+	svc, memStore, car := newTestService(t, &now)
+
+	var invalidErr *InvalidError
+	_, err := svc.SchedulePrice(ctx, car.ID.String(), "alice", &models.CarPriceRequest{
+		Price: money.Money{Amount: 2000000, Currency: "USD"}, EffectiveFrom: now.Add(-time.Hour),
+	})
+	assert.ErrorAs(t, err, &invalidErr)
+
+	scheduled, err := svc.SchedulePrice(ctx, car.ID.String(), "alice", &models.CarPriceRequest{
+		Price: money.Money{Amount: 2000000, Currency: "USD"}, EffectiveFrom: now.Add(time.Hour),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "alice", scheduled.CreatedBy)
+
+	// without a start the price applies at once
+	_, err = svc.SchedulePrice(ctx, car.ID.String(), "alice", &models.CarPriceRequest{
+		Price: money.Money{Amount: 2400000, Currency: "USD"},
+	})
+	require.NoError(t, err)
+	got, err := memStore.GetCarById(ctx, car.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, int64(2400000), got.Price.Amount)
+
+	history, err := svc.PriceHistory(ctx, car.ID.String())
+	require.NoError(t, err)
+	require.Len(t, history, 3)
+	assert.Equal(t, models.CarPricePast, history[0].Status)
+	assert.Equal(t, models.CarPriceCurrent, history[1].Status)
+	assert.Equal(t, models.CarPriceScheduled, history[2].Status)
+
+	_, err = svc.CancelPrice(ctx, car.ID.String(), history[1].ID.String())
+	assert.ErrorIs(t, err, ErrPriceInEffect)
+	_, err = svc.CancelPrice(ctx, car.ID.String(), car.ID.String())
+	assert.ErrorIs(t, err, ErrPriceNotFound)
+	cancelled, err := svc.CancelPrice(ctx, car.ID.String(), scheduled.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, scheduled.ID, cancelled.ID)
+
+	_, err = svc.PriceHistory(ctx, "00000000-0000-0000-0000-00000000cafe")
+	assert.ErrorIs(t, err, store.ErrCarNotFound)
+}
+
+// carCache records the cars invalidated per dealership.
+type carCache struct {
+	invalidated map[string][]string
+}
+
+func (c *carCache) InvalidateCars(ctx context.Context, ids ...string) {
+	tenantID, _ := tenant.FromContext(ctx)
+	c.invalidated[tenantID] = append(c.invalidated[tenantID], ids...)
+}
+
+func TestScheduledPriceInvalidatesCache(t *testing.T) {
+	ctx := tenant.WithTenant(context.Background(), tenant.DefaultID)
+	now := time.Now().Add(time.Second)
+	svc, _, car := newTestService(t, &now)
+	cache := &carCache{invalidated: make(map[string][]string)}
+	svc.UseCarCache(cache)
+
+	_, err := svc.SchedulePrice(ctx, car.ID.String(), "alice", &models.CarPriceRequest{
+		Price: money.Money{Amount: 2000000, Currency: "USD"}, EffectiveFrom: now.Add(time.Hour),
+	})
+	require.NoError(t, err)
+	assert.Empty(t, cache.invalidated)
+
+	// the scheduler runs with no dealership of its own
+	now = now.Add(time.Hour)
+	applied, err := svc.ApplyPrices(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), applied)
+	assert.Equal(t, map[string][]string{tenant.DefaultID: {car.ID.String()}}, cache.invalidated)
+
+	// a price starting at once is seen at once
+	_, err = svc.SchedulePrice(ctx, car.ID.String(), "alice", &models.CarPriceRequest{
+		Price: money.Money{Amount: 2100000, Currency: "USD"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{car.ID.String(), car.ID.String()}, cache.invalidated[tenant.DefaultID])
+}
+
+func TestApplyPromotionsPicksBest(t *testing.T) {
+	ctx := tenant.WithTenant(context.Background(), tenant.DefaultID)
+	now := time.Now()
+	svc, _, car := newTestService(t, &now)
+
+	for _, promotionReq := range []models.PromotionRequest{
+		{Name: "Toyota week", Kind: models.PromotionPercentage, Percent: decimal.New(10, 0), Brand: "toyota", StartsAt: now.Add(-time.Hour)},
+		{Name: "Hybrid bonus", Kind: models.PromotionFixed, Amount: money.Money{Amount: 300000, Currency: "USD"}, FuelType: "Hybrid", StartsAt: now.Add(-time.Hour)},
+		{Name: "Euro bonus", Kind: models.PromotionFixed, Amount: money.Money{Amount: 900000, Currency: "EUR"}, StartsAt: now.Add(-time.Hour)},
+		{Name: "Next week", Kind: models.PromotionPercentage, Percent: decimal.New(50, 0), StartsAt: now.Add(time.Hour)},
+		{Name: "Diesel days", Kind: models.PromotionPercentage, Percent: decimal.New(50, 0), FuelType: "Diesel", StartsAt: now.Add(-time.Hour)},
+	} {
+		_, err := svc.CreatePromotion(ctx, &promotionReq)
+		require.NoError(t, err)
+	}
+
+	cars, err := svc.ApplyPromotions(ctx, []models.Car{car})
+	require.NoError(t, err)
+	promoted := cars[0]
+	assert.Equal(t, money.Money{Amount: 2200000, Currency: "USD"}, promoted.Price)
+	require.NotNil(t, promoted.ListPrice)
+	assert.Equal(t, car.Price, *promoted.ListPrice)
+	require.NotNil(t, promoted.Promotion)
+	assert.Equal(t, "Hybrid bonus", promoted.Promotion.Name)
+
+	var invalidErr *InvalidError
+	_, err = svc.CreatePromotion(ctx, &models.PromotionRequest{Name: "Too much", Kind: models.PromotionPercentage, Percent: decimal.New(101, 0), StartsAt: now})
+	assert.ErrorAs(t, err, &invalidErr)
+}
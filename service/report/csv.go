@@ -0,0 +1,43 @@
+package report
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+
+	"github.com/adohong4/carZone/models"
+	"github.com/adohong4/carZone/money"
+)
+
+// WriteInventoryCSV writes the rows of an inventory report as CSV: a header,
+// a column per group by dimension and currency, then the figures with the
+// amounts in major units.
+func WriteInventoryCSV(w io.Writer, report models.InventoryReport) error {
+	header := append([]string{}, report.Query.GroupBy...)
+	header = append(header, "currency", "cars", "total_price", "average_price", "min_price", "max_price",
+		"units_in_stock", "stock_value")
+
+	out := csv.NewWriter(w)
+	if err := out.Write(header); err != nil {
+		return err
+	}
+	for _, row := range report.Rows {
+		record := make([]string, 0, len(header))
+		for _, dimension := range report.Query.GroupBy {
+			record = append(record, row.Dimension(dimension))
+		}
+		record = append(record, row.Currency, strconv.FormatInt(row.Cars, 10),
+			amount(row.TotalPrice), amount(row.AveragePrice), amount(row.MinPrice), amount(row.MaxPrice),
+			strconv.FormatInt(row.UnitsInStock, 10), amount(row.StockValue))
+		if err := out.Write(record); err != nil {
+			return err
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
+
+func amount(m money.Money) string {
+	units, _ := money.MinorUnits(m.Currency)
+	return m.Decimal().StringFixed(units)
+}
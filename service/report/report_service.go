@@ -0,0 +1,52 @@
+package report
+
+import (
+	"context"
+	"time"
+
+	"github.com/adohong4/carZone/models"
+	"github.com/adohong4/carZone/store"
+	"go.opentelemetry.io/otel"
+)
+
+// InvalidError is returned when a report request fails validation.
+type InvalidError struct {
+	Reason string
+}
+
+func (e *InvalidError) Error() string {
+	return e.Reason
+}
+
+// ReportService builds the management reports of the dealership of the
+// context from the aggregates of the report store.
+type ReportService struct {
+	store store.ReportStoreInterface
+	now   func() time.Time
+}
+
+func NewReportService(store store.ReportStoreInterface) *ReportService {
+	return &ReportService{
+		store: store,
+		now:   time.Now,
+	}
+}
+
+// InventoryReport counts the cars, their prices and the value of the stock
+// per group of the request and currency.
+func (s *ReportService) InventoryReport(ctx context.Context, reportReq models.InventoryReportRequest) (*models.InventoryReport, error) {
+	tracer := otel.Tracer("ReportService")
+	ctx, span := tracer.Start(ctx, "InventoryReport-Service")
+	defer span.End()
+
+	query, err := models.ValidateInventoryReport(reportReq)
+	if err != nil {
+		return nil, &InvalidError{Reason: err.Error()}
+	}
+	rows, err := s.store.InventoryReport(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	report := models.SummarizeInventory(query, rows, s.now().UTC())
+	return &report, nil
+}
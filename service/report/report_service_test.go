@@ -0,0 +1,96 @@
+package report
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/adohong4/carZone/models"
+	"github.com/adohong4/carZone/money"
+	"github.com/adohong4/carZone/store/memory"
+	"github.com/adohong4/carZone/tenant"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestService(t *testing.T) (*ReportService, context.Context) {
+	ctx := tenant.WithTenant(context.Background(), tenant.DefaultID)
+	memStore := memory.New()
+	engine, err := memStore.CreateEngine(ctx, &models.EngineRequest{Displacement: 2000, NoOfCylinders: 4, CarRange: 600})
+	require.NoError(t, err)
+	for _, car := range []struct {
+		brand string
+		price money.Money
+	}{
+		{"Toyota", money.Money{Amount: 2500000, Currency: "USD"}},
+		{"Toyota", money.Money{Amount: 2600001, Currency: "USD"}},
+		{"Honda", money.Money{Amount: 1900000, Currency: "USD"}},
+		{"Toyota", money.Money{Amount: 6500000, Currency: "JPY"}},
+	} {
+		_, err := memStore.CreateCar(ctx, &models.CarRequest{
+			Name: "Model", Year: "2023", Brand: car.brand, FuelType: "Petrol",
+			Engine: models.Engine{EngineID: engine.EngineID}, Price: car.price,
+		})
+		require.NoError(t, err)
+	}
+
+	svc := NewReportService(memStore)
+	svc.now = func() time.Time { return time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC) }
+	return svc, ctx
+}
+
+func TestInventoryReport(t *testing.T) {
+	svc, ctx := newTestService(t)
+
+	report, err := svc.InventoryReport(ctx, models.InventoryReportRequest{GroupBy: []string{"brand"}})
+	require.NoError(t, err)
+	require.Len(t, report.Rows, 3)
+	toyota := report.Rows[2]
+	assert.Equal(t, "Toyota", toyota.Brand)
+	assert.Equal(t, int64(2), toyota.Cars)
+	// 25000.00 and 26000.01 average to 25500.005, rounded up
+	assert.Equal(t, money.Money{Amount: 2550001, Currency: "USD"}, toyota.AveragePrice)
+
+	require.Len(t, report.Totals, 2)
+	assert.Equal(t, "JPY", report.Totals[0].Currency)
+	usd := report.Totals[1]
+	assert.Equal(t, int64(3), usd.Cars)
+	assert.Equal(t, money.Money{Amount: 7000001, Currency: "USD"}, usd.TotalPrice)
+	assert.Equal(t, money.Money{Amount: 2333334, Currency: "USD"}, usd.AveragePrice)
+	assert.Equal(t, money.Money{Amount: 1900000, Currency: "USD"}, usd.MinPrice)
+	assert.Equal(t, money.Money{Amount: 2600001, Currency: "USD"}, usd.MaxPrice)
+}
+
+func TestInventoryReportCSV(t *testing.T) {
+	svc, ctx := newTestService(t)
+
+	report, err := svc.InventoryReport(ctx, models.InventoryReportRequest{GroupBy: []string{"year", "brand"}})
+	require.NoError(t, err)
+	var body bytes.Buffer
+	require.NoError(t, WriteInventoryCSV(&body, *report))
+	assert.Equal(t, "brand,year,currency,cars,total_price,average_price,min_price,max_price,units_in_stock,stock_value\n"+
+		"Honda,2023,USD,1,19000.00,19000.00,19000.00,19000.00,0,0.00\n"+
+		"Toyota,2023,JPY,1,6500000,6500000,6500000,6500000,0,0\n"+
+		"Toyota,2023,USD,2,51000.01,25500.01,25000.00,26000.01,0,0.00\n", body.String())
+}
+
+func TestInventoryReportInvalid(t *testing.T) {
+	svc, ctx := newTestService(t)
+
+	for name, reportReq := range map[string]models.InventoryReportRequest{
+		"unknown dimension": {GroupBy: []string{"colour"}},
+		"bad date":          {From: "01/05/2024"},
+		"empty period":      {From: "2024-05-02", To: "2024-05-01"},
+	} {
+		_, err := svc.InventoryReport(ctx, reportReq)
+		var invalidErr *InvalidError
+		assert.True(t, errors.As(err, &invalidErr), name)
+	}
+
+	// a date To takes in the whole day
+	report, err := svc.InventoryReport(ctx, models.InventoryReportRequest{From: "2024-05-01", To: "2024-05-01"})
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC), report.Query.To)
+}
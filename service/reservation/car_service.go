@@ -0,0 +1,42 @@
+package reservation
+
+import (
+	"context"
+
+	"github.com/adohong4/carZone/models"
+	"github.com/adohong4/carZone/service"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+)
+
+// CarService adds the active reservation to the cars next returns. It goes
+// in front of the cache, holds change too often to be cached with the car.
+type CarService struct {
+	service.CarServiceInterface
+	reservations service.ReservationServiceInterface
+}
+
+func NewCarService(next service.CarServiceInterface, reservations service.ReservationServiceInterface) *CarService {
+	return &CarService{
+		CarServiceInterface: next,
+		reservations:        reservations,
+	}
+}
+
+func (s *CarService) GetCarById(ctx context.Context, id string) (*models.Car, error) {
+	tracer := otel.Tracer("ReservationCarService")
+	ctx, span := tracer.Start(ctx, "GetCarById-Service")
+	defer span.End()
+
+	car, err := s.CarServiceInterface.GetCarById(ctx, id)
+	if err != nil || car.ID == uuid.Nil {
+		return car, err
+	}
+
+	reservation, err := s.reservations.ActiveReservation(ctx, car.ID.String())
+	if err != nil {
+		return nil, err
+	}
+	car.Reservation = reservation
+	return car, nil
+}
@@ -0,0 +1,188 @@
+package reservation
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/adohong4/carZone/models"
+	"github.com/adohong4/carZone/store"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+)
+
+var ErrReservationNotFound = errors.New("reservation not found")
+
+// InvalidError is returned when a request fails validation.
+type InvalidError struct {
+	Reason string
+}
+
+func (e *InvalidError) Error() string {
+	return e.Reason
+}
+
+// Config controls how long cars are held. Holds without a length last
+// DefaultHold, none lasts longer than MaxHold. The sweeper marks expired
+// holds every SweepInterval.
+type Config struct {
+	DefaultHold   time.Duration
+	MaxHold       time.Duration
+	SweepInterval time.Duration
+}
+
+func DefaultConfig() Config {
+	return Config{
+		DefaultHold:   48 * time.Hour,
+		MaxHold:       7 * 24 * time.Hour,
+		SweepInterval: time.Minute,
+	}
+}
+
+type ReservationService struct {
+	store  store.ReservationStoreInterface
+	config Config
+	now    func() time.Time
+}
+
+func NewReservationService(store store.ReservationStoreInterface, config Config) *ReservationService {
+	return &ReservationService{
+		store:  store,
+		config: config,
+		now:    time.Now,
+	}
+}
+
+// CreateReservation holds the car for a customer on behalf of username.
+func (s *ReservationService) CreateReservation(ctx context.Context, carID, username string, reservationReq *models.ReservationRequest) (*models.Reservation, error) {
+	tracer := otel.Tracer("ReservationService")
+	ctx, span := tracer.Start(ctx, "CreateReservation-Service")
+	defer span.End()
+
+	id, err := uuid.Parse(carID)
+	if err != nil {
+		return nil, &InvalidError{Reason: "Invalid car ID"}
+	}
+	if err := models.ValidateReservationRequest(*reservationReq); err != nil {
+		return nil, &InvalidError{Reason: err.Error()}
+	}
+
+	hold := s.config.DefaultHold
+	if reservationReq.HoldHours > 0 {
+		hold = time.Duration(reservationReq.HoldHours) * time.Hour
+	}
+	if hold > s.config.MaxHold {
+		return nil, &InvalidError{Reason: "A car cannot be held longer than " + s.config.MaxHold.String()}
+	}
+
+	now := s.now()
+	reservation, err := s.store.CreateReservation(ctx, models.Reservation{
+		ID:              uuid.New(),
+		CarID:           id,
+		CustomerID:      reservationReq.CustomerID,
+		CustomerName:    reservationReq.CustomerName,
+		CustomerContact: reservationReq.CustomerContact,
+		ReservedBy:      username,
+		Status:          models.ReservationActive,
+		ExpiresAt:       now.Add(hold),
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &reservation, nil
+}
+
+// ActiveReservation returns the hold on the car, nil when it is not held.
+func (s *ReservationService) ActiveReservation(ctx context.Context, carID string) (*models.Reservation, error) {
+	tracer := otel.Tracer("ReservationService")
+	ctx, span := tracer.Start(ctx, "ActiveReservation-Service")
+	defer span.End()
+
+	reservation, err := s.store.GetActiveReservation(ctx, carID, s.now())
+	if err != nil {
+		return nil, err
+	}
+	if reservation.ID == uuid.Nil {
+		return nil, nil
+	}
+	return &reservation, nil
+}
+
+func (s *ReservationService) ListReservations(ctx context.Context, carID string) ([]models.Reservation, error) {
+	tracer := otel.Tracer("ReservationService")
+	ctx, span := tracer.Start(ctx, "ListReservations-Service")
+	defer span.End()
+
+	if _, err := uuid.Parse(carID); err != nil {
+		return nil, &InvalidError{Reason: "Invalid car ID"}
+	}
+
+	reservations, err := s.store.ListReservations(ctx, carID)
+	if err != nil {
+		return nil, err
+	}
+	if reservations == nil {
+		reservations = []models.Reservation{}
+	}
+	return reservations, nil
+}
+
+// ReleaseReservation ends a hold before it expires.
+func (s *ReservationService) ReleaseReservation(ctx context.Context, carID, id string) (*models.Reservation, error) {
+	tracer := otel.Tracer("ReservationService")
+	ctx, span := tracer.Start(ctx, "ReleaseReservation-Service")
+	defer span.End()
+
+	if _, err := uuid.Parse(carID); err != nil {
+		return nil, ErrReservationNotFound
+	}
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, ErrReservationNotFound
+	}
+
+	reservation, err := s.store.ReleaseReservation(ctx, carID, id, s.now())
+	if err != nil {
+		return nil, err
+	}
+	if reservation.ID == uuid.Nil {
+		return nil, ErrReservationNotFound
+	}
+	return &reservation, nil
+}
+
+// ExpireReservations marks every hold past its expiry, in every
+// dealership, as expired.
+func (s *ReservationService) ExpireReservations(ctx context.Context) (int64, error) {
+	tracer := otel.Tracer("ReservationService")
+	ctx, span := tracer.Start(ctx, "ExpireReservations-Service")
+	defer span.End()
+
+	return s.store.ExpireReservations(ctx, s.now())
+}
+
+// RunSweeper expires holds every SweepInterval until ctx is done. Holds
+// stop counting at their expiry whether or not the sweeper ran, it only
+// brings the stored status up to date.
+func (s *ReservationService) RunSweeper(ctx context.Context) {
+	ticker := time.NewTicker(s.config.SweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			expired, err := s.ExpireReservations(ctx)
+			if err != nil {
+				log.Printf("Error expiring reservations: %v", err)
+				continue
+			}
+			if expired > 0 {
+				log.Printf("Expired %d reservations", expired)
+			}
+		}
+	}
+}
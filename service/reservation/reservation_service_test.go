@@ -0,0 +1,106 @@
+package reservation
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/adohong4/carZone/models"
+	"github.com/adohong4/carZone/money"
+	carService "github.com/adohong4/carZone/service/car"
+	validationService "github.com/adohong4/carZone/service/validation"
+	"github.com/adohong4/carZone/store"
+	"github.com/adohong4/carZone/store/memory"
+	"github.com/adohong4/carZone/tenant"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestService(t *testing.T, now *time.Time) (*ReservationService, *memory.Store, models.Car) {
+	ctx := tenant.WithTenant(context.Background(), tenant.DefaultID)
+	memStore := memory.New()
+
+	engine, err := memStore.CreateEngine(ctx, &models.EngineRequest{Displacement: 2000, NoOfCylinders: 4, CarRange: 500})
+	require.NoError(t, err)
+	car, err := memStore.CreateCar(ctx, &models.CarRequest{
+		Name: "Camry", Year: "2023", Brand: "Toyota", FuelType: "Petrol",
+		Engine: models.Engine{EngineID: engine.EngineID}, Price: money.Money{Amount: 2500000, Currency: "USD"},
+	})
+	require.NoError(t, err)
+
+	svc := NewReservationService(memStore, Config{DefaultHold: time.Hour, MaxHold: 24 * time.Hour, SweepInterval: time.Minute})
+	svc.now = func() time.Time { return *now }
+	return svc, memStore, car
+}
+
+var request = models.ReservationRequest{CustomerName: "Jane Doe", CustomerContact: "jane@example.com"}
+
+func TestCreateReservationHoldLength(t *testing.T) {
+	ctx := tenant.WithTenant(context.Background(), tenant.DefaultID)
+	now := time.Now()
+	svc, _, car := newTestService(t, &now)
+
+	reservation, err := svc.CreateReservation(ctx, car.ID.String(), "alice", &request)
+	require.NoError(t, err)
+	assert.Equal(t, "alice", reservation.ReservedBy)
+	assert.Equal(t, now.Add(time.Hour), reservation.ExpiresAt)
+
+	_, err = svc.CreateReservation(ctx, car.ID.String(), "bob", &request)
+	assert.ErrorIs(t, err, store.ErrCarReserved)
+
+	long := request
+	long.HoldHours = 25
+	_, err = svc.CreateReservation(ctx, car.ID.String(), "bob", &long)
+	var invalid *InvalidError
+	assert.ErrorAs(t, err, &invalid)
+
+	_, err = svc.CreateReservation(ctx, "not-a-uuid", "bob", &request)
+	assert.ErrorAs(t, err, &invalid)
+}
+
+func TestExpiredReservationFreesTheCar(t *testing.T) {
+	ctx := tenant.WithTenant(context.Background(), tenant.DefaultID)
+	now := time.Now()
+	svc, _, car := newTestService(t, &now)
+
+	_, err := svc.CreateReservation(ctx, car.ID.String(), "alice", &request)
+	require.NoError(t, err)
+
+	now = now.Add(2 * time.Hour)
+	active, err := svc.ActiveReservation(ctx, car.ID.String())
+	require.NoError(t, err)
+	assert.Nil(t, active)
+
+	expired, err := svc.ExpireReservations(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), expired)
+
+	reservation, err := svc.CreateReservation(ctx, car.ID.String(), "bob", &request)
+	require.NoError(t, err)
+	assert.Equal(t, "bob", reservation.ReservedBy)
+}
+
+func TestCarServiceShowsReservation(t *testing.T) {
+	ctx := tenant.WithTenant(context.Background(), tenant.DefaultID)
+	now := time.Now()
+	svc, memStore, car := newTestService(t, &now)
+	cars := NewCarService(carService.NewCarService(memStore, memStore, memStore, validationService.NewValidationService(memStore, nil)), svc)
+
+	got, err := cars.GetCarById(ctx, car.ID.String())
+	require.NoError(t, err)
+	assert.Nil(t, got.Reservation)
+
+	reservation, err := svc.CreateReservation(ctx, car.ID.String(), "alice", &request)
+	require.NoError(t, err)
+
+	got, err = cars.GetCarById(ctx, car.ID.String())
+	require.NoError(t, err)
+	require.NotNil(t, got.Reservation)
+	assert.Equal(t, reservation.ID, got.Reservation.ID)
+
+	_, err = svc.ReleaseReservation(ctx, car.ID.String(), reservation.ID.String())
+	require.NoError(t, err)
+	got, err = cars.GetCarById(ctx, car.ID.String())
+	require.NoError(t, err)
+	assert.Nil(t, got.Reservation)
+}
@@ -0,0 +1,112 @@
+package validation
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/adohong4/carZone/models"
+	"github.com/adohong4/carZone/store"
+	"go.opentelemetry.io/otel"
+)
+
+// InvalidError is returned when a rule set fails validation.
+type InvalidError struct {
+	Reason string
+}
+
+func (e *InvalidError) Error() string {
+	return e.Reason
+}
+
+func invalid(err error) error {
+	return &InvalidError{Reason: err.Error()}
+}
+
+// ParseRules reads a rule set in the {"rules": [...]} form of the rules
+// file and of PUT /admin/validation-rules.
+func ParseRules(data []byte) ([]models.ValidationRule, error) {
+	var rulesReq models.ValidationRulesRequest
+	if err := json.Unmarshal(data, &rulesReq); err != nil {
+		return nil, err
+	}
+	if err := models.ValidateRules(rulesReq.Rules); err != nil {
+		return nil, err
+	}
+	return rulesReq.Rules, nil
+}
+
+// ValidationService evaluates the validation rules of the dealership of
+// the context: the configured defaults as overridden by the dealership.
+type ValidationService struct {
+	store    store.ValidationRuleStoreInterface
+	defaults []models.ValidationRule
+}
+
+func NewValidationService(store store.ValidationRuleStoreInterface, defaults []models.ValidationRule) *ValidationService {
+	return &ValidationService{
+		store:    store,
+		defaults: defaults,
+	}
+}
+
+func (s *ValidationService) GetRules(ctx context.Context) (*models.ValidationRules, error) {
+	tracer := otel.Tracer("ValidationService")
+	ctx, span := tracer.Start(ctx, "GetRules-Service")
+	defer span.End()
+
+	overrides, err := s.store.GetValidationRules(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.ruleSet(overrides), nil
+}
+
+// SetRules replaces the overrides of the dealership.
+func (s *ValidationService) SetRules(ctx context.Context, rulesReq *models.ValidationRulesRequest) (*models.ValidationRules, error) {
+	tracer := otel.Tracer("ValidationService")
+	ctx, span := tracer.Start(ctx, "SetRules-Service")
+	defer span.End()
+
+	if err := models.ValidateRules(rulesReq.Rules); err != nil {
+		return nil, invalid(err)
+	}
+	overrides, err := s.store.SetValidationRules(ctx, rulesReq.Rules)
+	if err != nil {
+		return nil, err
+	}
+	return s.ruleSet(overrides), nil
+}
+
+func (s *ValidationService) ruleSet(overrides []models.ValidationRule) *models.ValidationRules {
+	if overrides == nil {
+		overrides = []models.ValidationRule{}
+	}
+	return &models.ValidationRules{
+		Rules:     models.MergeRules(s.defaults, overrides),
+		Overrides: overrides,
+	}
+}
+
+func (s *ValidationService) CheckCar(ctx context.Context, carReq models.CarRequest) ([]models.Violation, error) {
+	tracer := otel.Tracer("ValidationService")
+	ctx, span := tracer.Start(ctx, "CheckCar-Service")
+	defer span.End()
+
+	rules, err := s.GetRules(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return models.CarRuleViolations(rules.Rules, carReq), nil
+}
+
+func (s *ValidationService) CheckEngine(ctx context.Context, engineReq models.EngineRequest) ([]models.Violation, error) {
+	tracer := otel.Tracer("ValidationService")
+	ctx, span := tracer.Start(ctx, "CheckEngine-Service")
+	defer span.End()
+
+	rules, err := s.GetRules(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return models.EngineRuleViolations(rules.Rules, engineReq), nil
+}
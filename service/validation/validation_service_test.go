@@ -0,0 +1,116 @@
+package validation
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/adohong4/carZone/models"
+	"github.com/adohong4/carZone/money"
+	"github.com/adohong4/carZone/store/memory"
+	"github.com/adohong4/carZone/tenant"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const otherTenant = "00000000-0000-0000-0000-000000000002"
+
+var defaultRules = []models.ValidationRule{
+	{ID: "price-ceiling", Target: models.RuleTargetCar, Field: "price", Check: models.CheckMax, Value: "200000"},
+	{ID: "trim", Target: models.RuleTargetCar, Field: "spec.trim", Check: models.CheckRequired},
+	{ID: "range", Target: models.RuleTargetEngine, Field: "carRange", Check: models.CheckMin, Value: "100"},
+}
+
+func carRequest() models.CarRequest {
+	return models.CarRequest{
+		Name:     "Camry",
+		Year:     "2023",
+		Brand:    "Toyota",
+		FuelType: "Petrol",
+		Price:    money.Money{Amount: 15000000, Currency: "USD"},
+	}
+}
+
+func TestDefaultRules(t *testing.T) {
+	ctx := tenant.WithTenant(context.Background(), tenant.DefaultID)
+	svc := NewValidationService(memory.New(), defaultRules)
+
+	violations, err := svc.CheckCar(ctx, carRequest())
+	require.NoError(t, err)
+	require.Len(t, violations, 1)
+	assert.Equal(t, models.Violation{Field: "spec.trim", Rule: "trim", Message: "spec.trim is required"}, violations[0])
+
+	carReq := carRequest()
+	carReq.Price.Amount = 25000000
+	carReq.Spec = &models.CarSpec{Trim: "XLE"}
+	violations, err = svc.CheckCar(ctx, carReq)
+	require.NoError(t, err)
+	require.Len(t, violations, 1)
+	assert.Equal(t, "price must be at most 200000", violations[0].Message)
+
+	violations, err = svc.CheckEngine(ctx, models.EngineRequest{Displacement: 2000, NoOfCylinders: 4, CarRange: 50})
+	require.NoError(t, err)
+	require.Len(t, violations, 1)
+	assert.Equal(t, "range", violations[0].Rule)
+}
+
+func TestTenantOverrides(t *testing.T) {
+	ctx := tenant.WithTenant(context.Background(), tenant.DefaultID)
+	other := tenant.WithTenant(context.Background(), otherTenant)
+	svc := NewValidationService(memory.New(), defaultRules)
+
+	rules, err := svc.SetRules(ctx, &models.ValidationRulesRequest{Rules: []models.ValidationRule{
+		{ID: "price-ceiling", Target: models.RuleTargetCar, Field: "price", Check: models.CheckMax, Value: "100000",
+			Message: "We do not sell cars above 100000"},
+		{ID: "trim", Disabled: true},
+		{ID: "brands", Target: models.RuleTargetCar, Field: "brand", Check: models.CheckIn, Values: []string{"honda", "mazda"}},
+	}})
+	require.NoError(t, err)
+	assert.Len(t, rules.Overrides, 3)
+	require.Len(t, rules.Rules, 3)
+	assert.Equal(t, []string{"price-ceiling", "range", "brands"},
+		[]string{rules.Rules[0].ID, rules.Rules[1].ID, rules.Rules[2].ID})
+
+	// every violation is reported, not only the first
+	violations, err := svc.CheckCar(ctx, carRequest())
+	require.NoError(t, err)
+	require.Len(t, violations, 2)
+	assert.Equal(t, "We do not sell cars above 100000", violations[0].Message)
+	assert.Equal(t, "brand must be one of honda, mazda", violations[1].Message)
+
+	// other dealerships keep the defaults
+	violations, err = svc.CheckCar(other, carRequest())
+	require.NoError(t, err)
+	require.Len(t, violations, 1)
+	assert.Equal(t, "trim", violations[0].Rule)
+}
+
+func TestSetRulesInvalid(t *testing.T) {
+	ctx := tenant.WithTenant(context.Background(), tenant.DefaultID)
+	svc := NewValidationService(memory.New(), nil)
+
+	for name, rule := range map[string]models.ValidationRule{
+		"no id":         {Target: models.RuleTargetCar, Field: "price", Check: models.CheckRequired},
+		"unknown field": {ID: "x", Target: models.RuleTargetCar, Field: "colour", Check: models.CheckRequired},
+		"unknown check": {ID: "x", Target: models.RuleTargetCar, Field: "price", Check: "between"},
+		"bad bound":     {ID: "x", Target: models.RuleTargetCar, Field: "price", Check: models.CheckMax, Value: "lots"},
+		"bad pattern":   {ID: "x", Target: models.RuleTargetCar, Field: "name", Check: models.CheckPattern, Value: "("},
+		"no values":     {ID: "x", Target: models.RuleTargetEngine, Field: "type", Check: models.CheckIn},
+	} {
+		_, err := svc.SetRules(ctx, &models.ValidationRulesRequest{Rules: []models.ValidationRule{rule}})
+		var invalidErr *InvalidError
+		assert.True(t, errors.As(err, &invalidErr), name)
+	}
+}
+
+func TestParseRules(t *testing.T) {
+	rules, err := ParseRules([]byte(`{"rules": [
+		{"id": "fuel", "target": "car", "field": "fuel_type", "check": "not_in", "values": ["Diesel"]}
+	]}`))
+	require.NoError(t, err)
+	require.Len(t, rules, 1)
+	assert.Equal(t, []string{"Diesel"}, rules[0].Values)
+
+	_, err = ParseRules([]byte(`{"rules": [{"id": "fuel", "target": "boat"}]}`))
+	assert.Error(t, err)
+}
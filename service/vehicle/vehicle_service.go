@@ -0,0 +1,152 @@
+package vehicle
+
+import (
+	"context"
+	"errors"
+
+	"github.com/adohong4/carZone/models"
+	"github.com/adohong4/carZone/store"
+	vinDecoder "github.com/adohong4/carZone/vin"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+)
+
+var ErrVehicleNotFound = errors.New("vehicle not found")
+
+// InvalidError is returned when a request fails validation.
+type InvalidError struct {
+	Reason string
+}
+
+func (e *InvalidError) Error() string {
+	return e.Reason
+}
+
+func invalid(err error) error {
+	return &InvalidError{Reason: err.Error()}
+}
+
+type VehicleService struct {
+	store store.VehicleStoreInterface
+}
+
+func NewVehicleService(store store.VehicleStoreInterface) *VehicleService {
+	return &VehicleService{
+		store: store,
+	}
+}
+
+func (s *VehicleService) GetVehicle(ctx context.Context, vin string) (*models.Vehicle, error) {
+	tracer := otel.Tracer("VehicleService")
+	ctx, span := tracer.Start(ctx, "GetVehicle-Service")
+	defer span.End()
+
+	vehicle, err := s.store.GetVehicle(ctx, vinDecoder.Normalize(vin))
+	if err != nil {
+		return nil, err
+	}
+	if vehicle.VIN == "" {
+		return nil, ErrVehicleNotFound
+	}
+	return &vehicle, nil
+}
+
+func (s *VehicleService) ListVehicles(ctx context.Context, filter models.VehicleFilter) ([]models.Vehicle, error) {
+	tracer := otel.Tracer("VehicleService")
+	ctx, span := tracer.Start(ctx, "ListVehicles-Service")
+	defer span.End()
+
+	if filter.Status != "" {
+		if err := models.ValidateVehicleStatus(filter.Status); err != nil {
+			return nil, invalid(err)
+		}
+	}
+	return s.store.ListVehicles(ctx, filter)
+}
+
+// ReceiveVehicle registers a unit arriving at the dealership.
+func (s *VehicleService) ReceiveVehicle(ctx context.Context, vehicleReq *models.VehicleRequest) (*models.Vehicle, error) {
+	tracer := otel.Tracer("VehicleService")
+	ctx, span := tracer.Start(ctx, "ReceiveVehicle-Service")
+	defer span.End()
+
+	vehicleReq.VIN = vinDecoder.Normalize(vehicleReq.VIN)
+	if vehicleReq.Status == "" {
+		vehicleReq.Status = models.VehicleInStock
+	}
+	if err := models.ValidateVehicleRequest(*vehicleReq); err != nil {
+		return nil, invalid(err)
+	}
+
+	vehicle, err := s.store.ReceiveVehicle(ctx, vehicleReq)
+	if err != nil {
+		return nil, err
+	}
+	return &vehicle, nil
+}
+
+// MoveVehicle changes the location, status or mileage of a unit. The change
+// is refused when another request moved the unit in the meantime.
+func (s *VehicleService) MoveVehicle(ctx context.Context, vin string, moveReq *models.VehicleMoveRequest) (*models.Vehicle, error) {
+	tracer := otel.Tracer("VehicleService")
+	ctx, span := tracer.Start(ctx, "MoveVehicle-Service")
+	defer span.End()
+
+	current, err := s.GetVehicle(ctx, vin)
+	if err != nil {
+		return nil, err
+	}
+
+	moved := *current
+	if moveReq.Location != "" {
+		moved.Location = moveReq.Location
+	}
+	if moveReq.Status != "" {
+		if err := models.ValidateVehicleTransition(current.Status, moveReq.Status); err != nil {
+			return nil, invalid(err)
+		}
+		moved.Status = moveReq.Status
+	}
+	if moveReq.Mileage != 0 {
+		if moveReq.Mileage < current.Mileage {
+			return nil, &InvalidError{Reason: "Mileage cannot go down"}
+		}
+		moved.Mileage = moveReq.Mileage
+	}
+
+	updated, err := s.store.UpdateVehicle(ctx, moved, current.Status)
+	if err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+// StockCounts returns the units per status of one car model, or of every
+// model when carID is empty.
+func (s *VehicleService) StockCounts(ctx context.Context, carID string) ([]models.StockCount, error) {
+	tracer := otel.Tracer("VehicleService")
+	ctx, span := tracer.Start(ctx, "StockCounts-Service")
+	defer span.End()
+
+	var id uuid.UUID
+	if carID != "" {
+		parsed, err := uuid.Parse(carID)
+		if err != nil {
+			return nil, &InvalidError{Reason: "Invalid car ID"}
+		}
+		id = parsed
+	}
+
+	counts, err := s.store.StockCounts(ctx, carID)
+	if err != nil {
+		return nil, err
+	}
+	// a model without units still has a count, of zero
+	if len(counts) == 0 && carID != "" {
+		return []models.StockCount{{CarID: id}}, nil
+	}
+	if counts == nil {
+		counts = []models.StockCount{}
+	}
+	return counts, nil
+}
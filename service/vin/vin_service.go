@@ -0,0 +1,43 @@
+package vin
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/adohong4/carZone/models"
+	vinDecoder "github.com/adohong4/carZone/vin"
+	"go.opentelemetry.io/otel"
+)
+
+type VINService struct {
+	now func() time.Time
+}
+
+func NewVINService() *VINService {
+	return &VINService{
+		now: time.Now,
+	}
+}
+
+// Decode decodes vin and pre-fills a car request with its brand and model
+// year.
+func (s *VINService) Decode(ctx context.Context, vin string) (*models.VINDecodeResult, error) {
+	tracer := otel.Tracer("VINService")
+	_, span := tracer.Start(ctx, "Decode-Service")
+	defer span.End()
+
+	decoded, err := vinDecoder.Decode(vin, s.now())
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.VINDecodeResult{Decoded: *decoded}
+	if decoded.Manufacturer != nil {
+		result.Car.Brand = decoded.Manufacturer.Brand
+	}
+	if decoded.ModelYear != 0 {
+		result.Car.Year = strconv.Itoa(decoded.ModelYear)
+	}
+	return result, nil
+}
@@ -0,0 +1,481 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/adohong4/carZone/models"
+	"github.com/adohong4/carZone/store"
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel"
+)
+
+// The headers of a delivery. The signature is "sha256=" and the hex
+// HMAC-SHA256, keyed with the secret of the webhook, of the timestamp, a
+// dot and the body.
+const (
+	HeaderEvent     = "X-CarZone-Event"
+	HeaderDelivery  = "X-CarZone-Delivery"
+	HeaderTimestamp = "X-CarZone-Timestamp"
+	HeaderSignature = "X-CarZone-Signature"
+)
+
+// deliveryLimit bounds the delivery log of a webhook.
+const deliveryLimit = 100
+
+var webhookDeliveries = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "webhook_delivery_attempts_total",
+		Help: "Total number of webhook delivery attempts by result",
+	},
+	[]string{"result"},
+)
+
+func init() {
+	prometheus.MustRegister(webhookDeliveries)
+}
+
+// InvalidError is returned when a request fails validation.
+type InvalidError struct {
+	Reason string
+}
+
+func (e *InvalidError) Error() string {
+	return e.Reason
+}
+
+func invalid(err error) error {
+	return &InvalidError{Reason: err.Error()}
+}
+
+// Config tunes the deliveries: every PollInterval up to BatchSize due
+// deliveries are sent at once, each waiting Timeout for a response. A
+// failed attempt is retried after BaseBackoff, doubling on every attempt
+// up to MaxBackoff, until MaxAttempts were made. Finished deliveries are
+// kept for Retention.
+type Config struct {
+	PollInterval time.Duration
+	BatchSize    int
+	Timeout      time.Duration
+	MaxAttempts  int
+	BaseBackoff  time.Duration
+	MaxBackoff   time.Duration
+	Retention    time.Duration
+}
+
+func DefaultConfig() Config {
+	return Config{
+		PollInterval: time.Second,
+		BatchSize:    20,
+		Timeout:      10 * time.Second,
+		MaxAttempts:  10,
+		BaseBackoff:  10 * time.Second,
+		MaxBackoff:   time.Hour,
+		Retention:    30 * 24 * time.Hour,
+	}
+}
+
+// WebhookService manages the webhook subscriptions and sends them the
+// events. It is the events.Sink of the outbox relay that queues a delivery
+// per subscription taking the event; deliveries are then sent on their own
+// so a slow partner holds back neither the relay nor the other partners.
+type WebhookService struct {
+	store  store.WebhookStoreInterface
+	client *http.Client
+	config Config
+	now    func() time.Time
+	// checkAddress vets every address a webhook resolves to or is sent to,
+	// tests let their local receivers through.
+	checkAddress func(ip net.IP) error
+}
+
+func NewWebhookService(store store.WebhookStoreInterface, config Config) *WebhookService {
+	s := &WebhookService{
+		store:        store,
+		config:       config,
+		now:          time.Now,
+		checkAddress: models.ValidateWebhookAddress,
+	}
+	// the address is checked again when connecting, a host can resolve to
+	// another address than when the webhook was created
+	dialer := &net.Dialer{
+		Timeout:   config.Timeout,
+		KeepAlive: 30 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip := net.ParseIP(host)
+			if ip == nil {
+				return fmt.Errorf("unexpected address %s", address)
+			}
+			return s.checkAddress(ip)
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	s.client = &http.Client{
+		Transport: transport,
+		Timeout:   config.Timeout,
+		// a redirect is a failed delivery, the partner fixes the URL
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	return s
+}
+
+// CreateWebhook generates a secret when none is given. The response is the
+// only place the secret is shown.
+func (s *WebhookService) CreateWebhook(ctx context.Context, webhookReq *models.WebhookRequest) (*models.Webhook, error) {
+	tracer := otel.Tracer("WebhookService")
+	ctx, span := tracer.Start(ctx, "CreateWebhook-Service")
+	defer span.End()
+
+	if err := models.ValidateWebhookRequest(*webhookReq); err != nil {
+		return nil, invalid(err)
+	}
+	if err := s.validateHost(ctx, webhookReq.URL); err != nil {
+		return nil, err
+	}
+	secret := webhookReq.Secret
+	if secret == "" {
+		var err error
+		if secret, err = newSecret(); err != nil {
+			return nil, err
+		}
+	}
+
+	webhook, err := s.store.CreateWebhook(ctx, models.Webhook{
+		ID:        uuid.New(),
+		URL:       webhookReq.URL,
+		Events:    webhookReq.Events,
+		Secret:    secret,
+		CreatedAt: s.now().UTC(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+func newSecret() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(buf), nil
+}
+
+func (s *WebhookService) ListWebhooks(ctx context.Context) ([]models.Webhook, error) {
+	tracer := otel.Tracer("WebhookService")
+	ctx, span := tracer.Start(ctx, "ListWebhooks-Service")
+	defer span.End()
+
+	webhooks, err := s.store.ListWebhooks(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if webhooks == nil {
+		webhooks = []models.Webhook{}
+	}
+	for i := range webhooks {
+		webhooks[i].Secret = ""
+	}
+	return webhooks, nil
+}
+
+func (s *WebhookService) GetWebhook(ctx context.Context, id string) (*models.Webhook, error) {
+	tracer := otel.Tracer("WebhookService")
+	ctx, span := tracer.Start(ctx, "GetWebhook-Service")
+	defer span.End()
+
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, store.ErrWebhookNotFound
+	}
+	webhook, err := s.store.GetWebhook(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	webhook.Secret = ""
+	return &webhook, nil
+}
+
+// DeleteWebhook drops the webhook with its deliveries, pending ones are
+// not sent.
+func (s *WebhookService) DeleteWebhook(ctx context.Context, id string) (*models.Webhook, error) {
+	tracer := otel.Tracer("WebhookService")
+	ctx, span := tracer.Start(ctx, "DeleteWebhook-Service")
+	defer span.End()
+
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, store.ErrWebhookNotFound
+	}
+	webhook, err := s.store.DeleteWebhook(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	webhook.Secret = ""
+	return &webhook, nil
+}
+
+// ListDeliveries returns the latest deliveries of a webhook without their
+// attempts.
+func (s *WebhookService) ListDeliveries(ctx context.Context, webhookID string) ([]models.WebhookDelivery, error) {
+	tracer := otel.Tracer("WebhookService")
+	ctx, span := tracer.Start(ctx, "ListDeliveries-Service")
+	defer span.End()
+
+	if _, err := uuid.Parse(webhookID); err != nil {
+		return nil, store.ErrWebhookNotFound
+	}
+	deliveries, err := s.store.ListDeliveries(ctx, webhookID, deliveryLimit)
+	if err != nil {
+		return nil, err
+	}
+	if deliveries == nil {
+		deliveries = []models.WebhookDelivery{}
+	}
+	return deliveries, nil
+}
+
+func (s *WebhookService) GetDelivery(ctx context.Context, webhookID, deliveryID string) (*models.WebhookDelivery, error) {
+	tracer := otel.Tracer("WebhookService")
+	ctx, span := tracer.Start(ctx, "GetDelivery-Service")
+	defer span.End()
+
+	if !validIDs(webhookID, deliveryID) {
+		return nil, store.ErrDeliveryNotFound
+	}
+	delivery, err := s.store.GetDelivery(ctx, webhookID, deliveryID)
+	if err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+// Redeliver sends a delivery again at once, whatever became of it, with a
+// fresh round of attempts.
+func (s *WebhookService) Redeliver(ctx context.Context, webhookID, deliveryID string) (*models.WebhookDelivery, error) {
+	tracer := otel.Tracer("WebhookService")
+	ctx, span := tracer.Start(ctx, "Redeliver-Service")
+	defer span.End()
+
+	if !validIDs(webhookID, deliveryID) {
+		return nil, store.ErrDeliveryNotFound
+	}
+	delivery, err := s.store.RedeliverDelivery(ctx, webhookID, deliveryID, s.now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+func validIDs(ids ...string) bool {
+	for _, id := range ids {
+		if _, err := uuid.Parse(id); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *WebhookService) Name() string {
+	return "webhooks"
+}
+
+// Publish queues event for the webhooks of its dealership, the relay
+// publishing it again does not queue it twice.
+func (s *WebhookService) Publish(ctx context.Context, event models.Event) error {
+	tracer := otel.Tracer("WebhookService")
+	ctx, span := tracer.Start(ctx, "Publish-Service")
+	defer span.End()
+
+	_, err := s.store.EnqueueDeliveries(ctx, event, s.now().UTC())
+	return err
+}
+
+// Sign returns the signature of a delivery body sent at timestamp, for
+// receivers to compare with the X-CarZone-Signature header.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// backoff is the wait after the attempts-th failed attempt.
+func (s *WebhookService) backoff(attempts int) time.Duration {
+	wait := s.config.BaseBackoff
+	for i := 1; i < attempts && wait < s.config.MaxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > s.config.MaxBackoff {
+		wait = s.config.MaxBackoff
+	}
+	return wait
+}
+
+// DeliverWebhooks sends a batch of due deliveries in parallel and returns
+// how many were attempted. A claimed delivery whose outcome is lost, when
+// the process stops, is sent again once the claim runs out.
+func (s *WebhookService) DeliverWebhooks(ctx context.Context) (int, error) {
+	tracer := otel.Tracer("WebhookService")
+	ctx, span := tracer.Start(ctx, "DeliverWebhooks-Service")
+	defer span.End()
+
+	now := s.now().UTC()
+	pending, err := s.store.ClaimDeliveries(ctx, now, now.Add(2*s.config.Timeout), s.config.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	var wg sync.WaitGroup
+	for _, next := range pending {
+		wg.Add(1)
+		go func(next models.PendingDelivery) {
+			defer wg.Done()
+			s.deliver(ctx, next)
+		}(next)
+	}
+	wg.Wait()
+	return len(pending), nil
+}
+
+func (s *WebhookService) deliver(ctx context.Context, next models.PendingDelivery) {
+	delivery := next.Delivery
+	attemptedAt := s.now().UTC()
+	code, sendErr := s.send(ctx, next, attemptedAt)
+	finishedAt := s.now().UTC()
+
+	attempt := models.WebhookAttempt{
+		ResponseCode: code,
+		DurationMS:   finishedAt.Sub(attemptedAt).Milliseconds(),
+		AttemptedAt:  attemptedAt,
+	}
+	delivery.Attempts++
+	delivery.ResponseCode = code
+	delivery.Error = ""
+	delivery.NextAttemptAt = nil
+	switch {
+	case sendErr == nil:
+		delivery.Status = models.DeliverySucceeded
+		delivery.DeliveredAt = &finishedAt
+		webhookDeliveries.WithLabelValues("ok").Inc()
+	case delivery.Attempts >= s.config.MaxAttempts:
+		attempt.Error = sendErr.Error()
+		delivery.Status = models.DeliveryFailed
+		delivery.Error = attempt.Error
+		webhookDeliveries.WithLabelValues("failed").Inc()
+	default:
+		attempt.Error = sendErr.Error()
+		retryAt := finishedAt.Add(s.backoff(delivery.Attempts))
+		delivery.Status = models.DeliveryPending
+		delivery.Error = attempt.Error
+		delivery.NextAttemptAt = &retryAt
+		webhookDeliveries.WithLabelValues("retry").Inc()
+	}
+
+	// the webhook was deleted in the meantime
+	if err := s.store.RecordAttempt(ctx, delivery, attempt); err != nil && !errors.Is(err, store.ErrDeliveryNotFound) {
+		log.Printf("Error recording webhook delivery %s: %v", delivery.ID, err)
+	}
+}
+
+// validateHost resolves the host of a webhook URL and rejects it when any
+// of its addresses is one a webhook must not reach.
+func (s *WebhookService) validateHost(ctx context.Context, rawURL string) error {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return invalid(err)
+	}
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, parsed.Hostname())
+	if err != nil || len(addrs) == 0 {
+		return &InvalidError{Reason: "URL host " + parsed.Hostname() + " does not resolve"}
+	}
+	for _, addr := range addrs {
+		if err := s.checkAddress(addr.IP); err != nil {
+			return invalid(err)
+		}
+	}
+	return nil
+}
+
+// send posts the payload of a delivery and returns the response code, any
+// 2xx accepts it.
+func (s *WebhookService) send(ctx context.Context, next models.PendingDelivery, at time.Time) (int, error) {
+	delivery := next.Delivery
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, next.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := at.Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "CarZone-Webhooks/1.0")
+	req.Header.Set(HeaderEvent, delivery.EventType)
+	req.Header.Set(HeaderDelivery, delivery.ID.String())
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(next.Secret, timestamp, delivery.Payload))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("webhook responded %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// Run sends the due deliveries every PollInterval until ctx is done,
+// straight on while full batches keep coming, and drops the finished ones
+// past Retention once an hour.
+func (s *WebhookService) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.config.PollInterval)
+	defer ticker.Stop()
+
+	var pruned time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for {
+				attempted, err := s.DeliverWebhooks(ctx)
+				if err != nil {
+					log.Printf("Error delivering webhooks: %v", err)
+				}
+				if err != nil || attempted < s.config.BatchSize {
+					break
+				}
+			}
+			if now := s.now(); now.Sub(pruned) >= time.Hour {
+				pruned = now
+				if _, err := s.store.DeleteFinishedDeliveries(ctx, now.Add(-s.config.Retention)); err != nil {
+					log.Printf("Error pruning webhook deliveries: %v", err)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,255 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/adohong4/carZone/models"
+	"github.com/adohong4/carZone/store/memory"
+	"github.com/adohong4/carZone/tenant"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// receiver is a partner endpoint answering with the next of its codes and
+// then with the last one, and remembering the requests it got.
+type receiver struct {
+	mu       sync.Mutex
+	codes    []int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.requests = append(rc.requests, r)
+	rc.bodies = append(rc.bodies, body)
+	code := rc.codes[0]
+	if len(rc.codes) > 1 {
+		rc.codes = rc.codes[1:]
+	}
+	w.WriteHeader(code)
+}
+
+type clock struct {
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	return c.now
+}
+
+func newTestService(t *testing.T, config Config, codes ...int) (*WebhookService, *receiver, *httptest.Server, *clock, context.Context) {
+	rc := &receiver{codes: codes}
+	server := httptest.NewServer(rc)
+	t.Cleanup(server.Close)
+
+	c := &clock{now: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)}
+	svc := NewWebhookService(memory.New(), config)
+	svc.now = c.Now
+	// the receiver listens on loopback
+	svc.checkAddress = func(net.IP) error { return nil }
+	return svc, rc, server, c, tenant.WithTenant(context.Background(), tenant.DefaultID)
+}
+
+func publishCarEvent(t *testing.T, svc *WebhookService) models.Event {
+	event, err := models.NewEvent(models.EventCarCreated, tenant.DefaultID, uuid.New(), map[string]string{"name": "Camry"})
+	require.NoError(t, err)
+	require.NoError(t, svc.Publish(context.Background(), event))
+	return event
+}
+
+func TestCreateWebhook(t *testing.T) {
+	svc, _, server, _, ctx := newTestService(t, DefaultConfig(), http.StatusOK)
+
+	for _, webhookReq := range []models.WebhookRequest{
+		{URL: "ftp://partner.example", Events: []string{"*"}},
+		{URL: server.URL},
+		{URL: server.URL, Events: []string{"car.sold"}},
+		{URL: server.URL, Events: []string{"vehicle.*"}},
+		{URL: server.URL, Events: []string{"*"}, Secret: "short"},
+	} {
+		_, err := svc.CreateWebhook(ctx, &webhookReq)
+		var invalidErr *InvalidError
+		assert.True(t, errors.As(err, &invalidErr), webhookReq)
+	}
+
+	webhook, err := svc.CreateWebhook(ctx, &models.WebhookRequest{URL: server.URL, Events: []string{"car.*", models.EventEngineDeleted}})
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(webhook.Secret, "whsec_"))
+	assert.True(t, webhook.Takes(models.EventCarUpdated))
+	assert.True(t, webhook.Takes(models.EventEngineDeleted))
+	assert.False(t, webhook.Takes(models.EventEngineCreated))
+
+	// the secret is only shown once
+	webhooks, err := svc.ListWebhooks(ctx)
+	require.NoError(t, err)
+	require.Len(t, webhooks, 1)
+	assert.Empty(t, webhooks[0].Secret)
+	got, err := svc.GetWebhook(ctx, webhook.ID.String())
+	require.NoError(t, err)
+	assert.Empty(t, got.Secret)
+}
+
+func TestInternalAddressesRejected(t *testing.T) {
+	svc := NewWebhookService(memory.New(), DefaultConfig())
+	ctx := tenant.WithTenant(context.Background(), tenant.DefaultID)
+	for _, target := range []string{
+		"http://127.0.0.1:8080/hook",
+		"http://localhost/hook",
+		"http://10.0.0.1/hook",
+		"http://192.168.1.10/hook",
+		"http://169.254.169.254/latest/meta-data",
+		"http://[::1]/hook",
+		"http://[fe80::1]/hook",
+		"http://0.0.0.0/hook",
+	} {
+		_, err := svc.CreateWebhook(ctx, &models.WebhookRequest{URL: target, Events: []string{"*"}})
+		var invalidErr *InvalidError
+		assert.True(t, errors.As(err, &invalidErr), target)
+	}
+}
+
+func TestDeliveryRefusesInternalAddress(t *testing.T) {
+	svc, rc, server, _, ctx := newTestService(t, DefaultConfig(), http.StatusOK)
+	webhook, err := svc.CreateWebhook(ctx, &models.WebhookRequest{URL: server.URL, Events: []string{"*"}})
+	require.NoError(t, err)
+	publishCarEvent(t, svc)
+
+	// the host now resolves to a loopback address
+	svc.checkAddress = models.ValidateWebhookAddress
+	attempted, err := svc.DeliverWebhooks(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, attempted)
+	assert.Empty(t, rc.requests)
+	deliveries, err := svc.ListDeliveries(ctx, webhook.ID.String())
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, models.DeliveryPending, deliveries[0].Status)
+	assert.Contains(t, deliveries[0].Error, "loopback")
+}
+
+func TestDeliverySignedAndRetried(t *testing.T) {
+	svc, rc, server, c, ctx := newTestService(t, DefaultConfig(), http.StatusServiceUnavailable, http.StatusNoContent)
+	webhook, err := svc.CreateWebhook(ctx, &models.WebhookRequest{
+		URL: server.URL, Events: []string{"car.*"}, Secret: "a-secret-of-16-chars",
+	})
+	require.NoError(t, err)
+	event := publishCarEvent(t, svc)
+	// engine events are not taken
+	engineEvent, err := models.NewEvent(models.EventEngineCreated, tenant.DefaultID, uuid.New(), nil)
+	require.NoError(t, err)
+	require.NoError(t, svc.Publish(context.Background(), engineEvent))
+
+	attempted, err := svc.DeliverWebhooks(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, attempted)
+	require.Len(t, rc.requests, 1)
+	req, body := rc.requests[0], rc.bodies[0]
+	assert.Equal(t, models.EventCarCreated, req.Header.Get(HeaderEvent))
+	assert.Equal(t, strconv.FormatInt(c.now.Unix(), 10), req.Header.Get(HeaderTimestamp))
+	assert.Equal(t, Sign("a-secret-of-16-chars", c.now.Unix(), body), req.Header.Get(HeaderSignature))
+	assert.Contains(t, string(body), event.ID.String())
+
+	deliveries, err := svc.ListDeliveries(ctx, webhook.ID.String())
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	delivery := deliveries[0]
+	assert.Equal(t, req.Header.Get(HeaderDelivery), delivery.ID.String())
+	assert.Equal(t, models.DeliveryPending, delivery.Status)
+	assert.Equal(t, http.StatusServiceUnavailable, delivery.ResponseCode)
+	require.NotNil(t, delivery.NextAttemptAt)
+	assert.Equal(t, c.now.Add(10*time.Second), *delivery.NextAttemptAt)
+
+	// nothing is due before the backoff ran out
+	c.now = c.now.Add(9 * time.Second)
+	attempted, err = svc.DeliverWebhooks(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, attempted)
+
+	c.now = c.now.Add(time.Second)
+	attempted, err = svc.DeliverWebhooks(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, attempted)
+	// a retry is signed again at its own time
+	require.Len(t, rc.requests, 2)
+	assert.Equal(t, Sign("a-secret-of-16-chars", c.now.Unix(), rc.bodies[1]), rc.requests[1].Header.Get(HeaderSignature))
+
+	got, err := svc.GetDelivery(ctx, webhook.ID.String(), delivery.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, models.DeliverySucceeded, got.Status)
+	assert.Equal(t, 2, got.Attempts)
+	assert.Equal(t, http.StatusNoContent, got.ResponseCode)
+	require.Len(t, got.Log, 2)
+	assert.Equal(t, []int{http.StatusServiceUnavailable, http.StatusNoContent}, []int{got.Log[0].ResponseCode, got.Log[1].ResponseCode})
+	assert.Equal(t, "webhook responded 503 Service Unavailable", got.Log[0].Error)
+
+	// the relay publishing the event again does not send it again
+	require.NoError(t, svc.Publish(context.Background(), event))
+	attempted, err = svc.DeliverWebhooks(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, attempted)
+}
+
+func TestDeliveryGivesUpAndIsRedelivered(t *testing.T) {
+	config := DefaultConfig()
+	config.MaxAttempts = 2
+	svc, rc, server, c, ctx := newTestService(t, config, http.StatusInternalServerError, http.StatusInternalServerError, http.StatusOK)
+	webhook, err := svc.CreateWebhook(ctx, &models.WebhookRequest{URL: server.URL, Events: []string{"*"}})
+	require.NoError(t, err)
+	publishCarEvent(t, svc)
+
+	for i := 0; i < 2; i++ {
+		_, err := svc.DeliverWebhooks(context.Background())
+		require.NoError(t, err)
+		c.now = c.now.Add(time.Hour)
+	}
+	deliveries, err := svc.ListDeliveries(ctx, webhook.ID.String())
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, models.DeliveryFailed, deliveries[0].Status)
+	assert.Nil(t, deliveries[0].NextAttemptAt)
+	attempted, err := svc.DeliverWebhooks(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, attempted)
+
+	_, err = svc.Redeliver(ctx, webhook.ID.String(), uuid.NewString())
+	assert.Error(t, err)
+	redelivered, err := svc.Redeliver(ctx, webhook.ID.String(), deliveries[0].ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, models.DeliveryPending, redelivered.Status)
+
+	attempted, err = svc.DeliverWebhooks(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, attempted)
+	assert.Len(t, rc.requests, 3)
+	got, err := svc.GetDelivery(ctx, webhook.ID.String(), deliveries[0].ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, models.DeliverySucceeded, got.Status)
+	assert.Equal(t, 1, got.Attempts)
+	assert.Len(t, got.Log, 3)
+}
+
+func TestBackoff(t *testing.T) {
+	svc := NewWebhookService(memory.New(), DefaultConfig())
+	var waits []time.Duration
+	for attempts := 1; attempts <= 11; attempts++ {
+		waits = append(waits, svc.backoff(attempts))
+	}
+	assert.Equal(t, []time.Duration{
+		10 * time.Second, 20 * time.Second, 40 * time.Second, 80 * time.Second, 160 * time.Second,
+		320 * time.Second, 640 * time.Second, 1280 * time.Second, 2560 * time.Second, time.Hour, time.Hour,
+	}, waits)
+}
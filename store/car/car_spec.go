@@ -0,0 +1,89 @@
+package car
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/adohong4/carZone/decimal"
+	"github.com/adohong4/carZone/models"
+	"github.com/google/uuid"
+)
+
+// carSpecColumns are read through LEFT JOIN car_spec s, they are all NULL
+// for a car without a spec.
+const carSpecColumns = "s.car_id, s.transmission, s.drivetrain, s.body_type, s.seats, s.doors, s.colour, s.trim_level, s.fuel_consumption, s.energy_consumption, s.co2_emissions"
+
+type specRow struct {
+	carID                                            *uuid.UUID
+	transmission, drivetrain, bodyType, colour, trim *string
+	seats, doors                                     *int
+	fuelConsumption, energyConsumption               *decimal.Decimal
+	co2Emissions                                     *int64
+}
+
+func (r *specRow) dest() []interface{} {
+	return []interface{}{
+		&r.carID, &r.transmission, &r.drivetrain, &r.bodyType, &r.seats, &r.doors, &r.colour, &r.trim,
+		&r.fuelConsumption, &r.energyConsumption, &r.co2Emissions,
+	}
+}
+
+func (r *specRow) spec() *models.CarSpec {
+	if r.carID == nil {
+		return nil
+	}
+	return &models.CarSpec{
+		Transmission:      stringOrEmpty(r.transmission),
+		Drivetrain:        stringOrEmpty(r.drivetrain),
+		BodyType:          stringOrEmpty(r.bodyType),
+		Seats:             intOrZero(r.seats),
+		Doors:             intOrZero(r.doors),
+		Colour:            stringOrEmpty(r.colour),
+		Trim:              stringOrEmpty(r.trim),
+		FuelConsumption:   r.fuelConsumption,
+		EnergyConsumption: r.energyConsumption,
+		CO2Emissions:      r.co2Emissions,
+	}
+}
+
+func stringOrEmpty(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func intOrZero(n *int) int {
+	if n == nil {
+		return 0
+	}
+	return *n
+}
+
+// nullIfZero stores the zero value of a spec field, which means not given,
+// as NULL.
+func nullIfZero[T comparable](value T) interface{} {
+	var zero T
+	if value == zero {
+		return nil
+	}
+	return value
+}
+
+// saveCarSpec replaces the spec of the car, a nil spec just removes it.
+func saveCarSpec(ctx context.Context, tx *sql.Tx, carID uuid.UUID, spec *models.CarSpec) error {
+	if _, err := tx.ExecContext(ctx, "DELETE FROM car_spec WHERE car_id = $1", carID); err != nil {
+		return err
+	}
+	if spec == nil {
+		return nil
+	}
+	_, err := tx.ExecContext(ctx,
+		`INSERT INTO car_spec (car_id, transmission, drivetrain, body_type, seats, doors, colour, trim_level,
+			fuel_consumption, energy_consumption, co2_emissions)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`,
+		carID, nullIfZero(spec.Transmission), nullIfZero(spec.Drivetrain), nullIfZero(spec.BodyType),
+		nullIfZero(spec.Seats), nullIfZero(spec.Doors), nullIfZero(spec.Colour), nullIfZero(spec.Trim),
+		spec.FuelConsumption, spec.EnergyConsumption, spec.CO2Emissions)
+	return err
+}
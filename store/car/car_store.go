@@ -7,7 +7,12 @@ import (
 	"time"
 
 	"github.com/adohong4/carZone/models"
+	"github.com/adohong4/carZone/money"
+	"github.com/adohong4/carZone/store"
+	"github.com/adohong4/carZone/store/outbox"
+	"github.com/adohong4/carZone/tenant"
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 	"go.opentelemetry.io/otel"
 )
 
@@ -25,23 +30,32 @@ func (s Store) GetCarById(ctx context.Context, id string) (models.Car, error) {
 	defer span.End()
 
 	var car models.Car
-	query := `SELECT c.id, c.name, c.year, c.brand, c.fuel_type, c.price, c.created_at, c.updated_at,
-				e.id, e.displacement, e.no_of_cylinders, e.car_range 
-				FROM car c LEFT JOIN engine e ON c.engine_id = e.id 
-				WHERE c.id = $1`
-
-	row := s.db.QueryRowContext(ctx, query, id)
-	err := row.Scan(
-		&car.ID, &car.Name, &car.Year, &car.Brand, &car.FuelType, &car.Price,
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return car, err
+	}
+
+	query := `SELECT c.id, c.name, c.year, c.brand, c.fuel_type, c.price_minor, c.currency, c.created_at, c.updated_at,
+				e.id, e.engine_type, e.displacement, e.no_of_cylinders, e.car_range, e.motor_power, e.battery_capacity, e.updated_at, ` + carSpecColumns + `
+				FROM car c LEFT JOIN engine e ON c.engine_id = e.id AND e.tenant_id = c.tenant_id
+				LEFT JOIN car_spec s ON s.car_id = c.id
+				WHERE c.id = $1 AND c.tenant_id = $2`
+
+	var spec specRow
+	row := s.db.QueryRowContext(ctx, query, id, tenantID)
+	err = row.Scan(append([]interface{}{
+		&car.ID, &car.Name, &car.Year, &car.Brand, &car.FuelType, &car.Price.Amount, &car.Price.Currency,
 		&car.CreatedAt, &car.UpdatedAt,
-		&car.Engine.EngineID, &car.Engine.Displacement, &car.Engine.NoOfCylinders, &car.Engine.CarRange,
-	)
+		&car.Engine.EngineID, &car.Engine.Type, &car.Engine.Displacement, &car.Engine.NoOfCylinders, &car.Engine.CarRange,
+		&car.Engine.MotorPower, &car.Engine.BatteryCapacity, &car.Engine.UpdatedAt,
+	}, spec.dest()...)...)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return car, nil
 		}
 		return car, err
 	}
+	car.Spec = spec.spec()
 	return car, nil
 }
 
@@ -50,44 +64,106 @@ func (s Store) GetCarByBrand(ctx context.Context, brand string, isEngine bool) (
 	ctx, span := tracer.Start(ctx, "GetCarByBrand-Store")
 	defer span.End()
 
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	var cars []models.Car
 	var query string
 	if isEngine {
-		query = `SELECT c.id, c.name, c.year, c.brand, c.fuel_type, c.price, c.created_at, c.updated_at,
-				e.id, e.displacement, e.no_of_cylinders, e.car_range
-				FROM car c LEFT JOIN engine e ON c.engine_id = e.id
-				WHERE c.brand = $1`
+		query = `SELECT c.id, c.name, c.year, c.brand, c.fuel_type, c.price_minor, c.currency, c.created_at, c.updated_at,
+				e.id, e.engine_type, e.displacement, e.no_of_cylinders, e.car_range, e.motor_power, e.battery_capacity, e.updated_at, ` + carSpecColumns + `
+				FROM car c LEFT JOIN engine e ON c.engine_id = e.id AND e.tenant_id = c.tenant_id
+				LEFT JOIN car_spec s ON s.car_id = c.id
+				WHERE c.brand = $1 AND c.tenant_id = $2
+				ORDER BY c.created_at, c.id`
 	} else {
-		query = `SELECT id, name, year, brand, fuel_type, price, created_at, updated_at
-		FROM car WHERE brand = $1`
+		query = `SELECT c.id, c.name, c.year, c.brand, c.fuel_type, c.price_minor, c.currency, c.created_at, c.updated_at, ` + carSpecColumns + `
+		FROM car c LEFT JOIN car_spec s ON s.car_id = c.id
+		WHERE c.brand = $1 AND c.tenant_id = $2
+		ORDER BY c.created_at, c.id`
 	}
 
-	rows, err := s.db.QueryContext(ctx, query, brand)
+	rows, err := s.db.QueryContext(ctx, query, brand, tenantID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 	for rows.Next() {
-		var car models.Car
+		var (
+			car  models.Car
+			spec specRow
+		)
 		if isEngine {
-			var engine models.Engine
-			err = rows.Scan(
-				&car.ID, &car.Name, &car.Year, &car.Brand, &car.FuelType, &car.Price,
+			err = rows.Scan(append([]interface{}{
+				&car.ID, &car.Name, &car.Year, &car.Brand, &car.FuelType, &car.Price.Amount, &car.Price.Currency,
 				&car.CreatedAt, &car.UpdatedAt,
-				&car.Engine.EngineID, &car.Engine.Displacement, &car.Engine.NoOfCylinders, &car.Engine.CarRange,
-			)
+				&car.Engine.EngineID, &car.Engine.Type, &car.Engine.Displacement, &car.Engine.NoOfCylinders, &car.Engine.CarRange,
+				&car.Engine.MotorPower, &car.Engine.BatteryCapacity, &car.Engine.UpdatedAt,
+			}, spec.dest()...)...)
 			if err != nil {
 				return nil, err
 			}
-			car.Engine = engine
 		} else {
-			err = rows.Scan(
-				&car.ID, &car.Name, &car.Year, &car.Brand, &car.FuelType, &car.Price,
-				&car.CreatedAt, &car.UpdatedAt)
+			err = rows.Scan(append([]interface{}{
+				&car.ID, &car.Name, &car.Year, &car.Brand, &car.FuelType, &car.Price.Amount, &car.Price.Currency,
+				&car.CreatedAt, &car.UpdatedAt,
+			}, spec.dest()...)...)
 			if err != nil {
 				return nil, err
 			}
 		}
+		car.Spec = spec.spec()
+		cars = append(cars, car)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return cars, nil
+}
+
+// GetCarsByIds fetches the cars of ids with their engines in one query.
+// Missing cars are left out.
+func (s Store) GetCarsByIds(ctx context.Context, ids []string) ([]models.Car, error) {
+	tracer := otel.Tracer("CarStore")
+	ctx, span := tracer.Start(ctx, "GetCarsByIds-Store")
+	defer span.End()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT c.id, c.name, c.year, c.brand, c.fuel_type, c.price_minor, c.currency, c.created_at, c.updated_at,
+				e.id, e.engine_type, e.displacement, e.no_of_cylinders, e.car_range, e.motor_power, e.battery_capacity, e.updated_at, ` + carSpecColumns + `
+				FROM car c LEFT JOIN engine e ON c.engine_id = e.id AND e.tenant_id = c.tenant_id
+				LEFT JOIN car_spec s ON s.car_id = c.id
+				WHERE c.id = ANY($1) AND c.tenant_id = $2
+				ORDER BY c.created_at, c.id`
+
+	rows, err := s.db.QueryContext(ctx, query, pq.Array(ids), tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var cars []models.Car
+	for rows.Next() {
+		var (
+			car  models.Car
+			spec specRow
+		)
+		err = rows.Scan(append([]interface{}{
+			&car.ID, &car.Name, &car.Year, &car.Brand, &car.FuelType, &car.Price.Amount, &car.Price.Currency,
+			&car.CreatedAt, &car.UpdatedAt,
+			&car.Engine.EngineID, &car.Engine.Type, &car.Engine.Displacement, &car.Engine.NoOfCylinders, &car.Engine.CarRange,
+			&car.Engine.MotorPower, &car.Engine.BatteryCapacity, &car.Engine.UpdatedAt,
+		}, spec.dest()...)...)
+		if err != nil {
+			return nil, err
+		}
+		car.Spec = spec.spec()
 		cars = append(cars, car)
 	}
 	if err = rows.Err(); err != nil {
@@ -104,7 +180,12 @@ func (s Store) CreateCar(ctx context.Context, carReq *models.CarRequest) (models
 	var createdCar models.Car
 	var engineID uuid.UUID
 
-	err := s.db.QueryRowContext(ctx, "SELECT id FROM engine WHERE id = $1", carReq.Engine.EngineID).Scan(&engineID)
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return createdCar, err
+	}
+
+	err = s.db.QueryRowContext(ctx, "SELECT id FROM engine WHERE id = $1 AND tenant_id = $2", carReq.Engine.EngineID, tenantID).Scan(&engineID)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return createdCar, errors.New("engine_id does not exists in the engine table")
@@ -142,9 +223,9 @@ func (s Store) CreateCar(ctx context.Context, carReq *models.CarRequest) (models
 		err = tx.Commit()
 	}()
 
-	query := `INSERT INTO car (id, name, year, brand, fuel_type, engine_id, price, created_at, updated_at)
-				VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
-				RETURNING id, name, year, brand, fuel_type, engine_id, price, created_at, updated_at`
+	query := `INSERT INTO car (id, name, year, brand, fuel_type, engine_id, price_minor, currency, created_at, updated_at, tenant_id)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+				RETURNING id, name, year, brand, fuel_type, engine_id, price_minor, currency, created_at, updated_at`
 
 	err = tx.QueryRowContext(ctx, query,
 		&newCar.ID,
@@ -153,9 +234,11 @@ func (s Store) CreateCar(ctx context.Context, carReq *models.CarRequest) (models
 		&newCar.Brand,
 		&newCar.FuelType,
 		&newCar.Engine.EngineID,
-		&newCar.Price,
+		&newCar.Price.Amount,
+		&newCar.Price.Currency,
 		&newCar.CreatedAt,
 		&newCar.UpdatedAt,
+		tenantID,
 	).Scan(
 		&createdCar.ID,
 		&createdCar.Name,
@@ -163,11 +246,25 @@ func (s Store) CreateCar(ctx context.Context, carReq *models.CarRequest) (models
 		&createdCar.Brand,
 		&createdCar.FuelType,
 		&createdCar.Engine.EngineID,
-		&createdCar.Price,
+		&createdCar.Price.Amount,
+		&createdCar.Price.Currency,
 		&createdCar.CreatedAt,
 		&createdCar.UpdatedAt,
 	)
 
+	if err != nil {
+		return createdCar, err
+	}
+	err = insertCarPrice(ctx, tx, createdCar, tenantID)
+	if err != nil {
+		return createdCar, err
+	}
+	err = saveCarSpec(ctx, tx, createdCar.ID, carReq.Spec)
+	if err != nil {
+		return createdCar, err
+	}
+	createdCar.Spec = carReq.Spec
+	err = outbox.RecordNew(ctx, tx, models.EventCarCreated, tenantID, createdCar.ID, createdCar)
 	if err != nil {
 		return createdCar, err
 	}
@@ -181,6 +278,11 @@ func (s Store) UpdateCar(ctx context.Context, id string, carReq *models.CarReque
 
 	var updatedCar models.Car
 
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return updatedCar, err
+	}
+
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
 		return updatedCar, err
@@ -192,10 +294,30 @@ func (s Store) UpdateCar(ctx context.Context, id string, carReq *models.CarReque
 		}
 		err = tx.Commit()
 	}()
+	var engineID uuid.UUID
+	err = tx.QueryRowContext(ctx, "SELECT id FROM engine WHERE id = $1 AND tenant_id = $2", carReq.Engine.EngineID, tenantID).Scan(&engineID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = errors.New("engine_id does not exists in the engine table")
+		}
+		return updatedCar, err
+	}
+
+	var oldPrice money.Money
+	err = tx.QueryRowContext(ctx, "SELECT price_minor, currency FROM car WHERE id = $1 AND tenant_id = $2 FOR UPDATE", id, tenantID).Scan(
+		&oldPrice.Amount, &oldPrice.Currency,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return updatedCar, errors.New("car not found")
+		}
+		return updatedCar, err
+	}
+
 	query := `UPDATE car
-				SET name = $2, year = $3, brand = $4, fuel_type = $5, engine_id = $6, price = $7, updated_at = $8
-				WHERE id = $8
-				RETURNING id, name, year, brand, fuel_type, engine_id, price, created_at, updated_at`
+				SET name = $2, year = $3, brand = $4, fuel_type = $5, engine_id = $6, price_minor = $7, currency = $8, updated_at = $9
+				WHERE id = $1 AND tenant_id = $10
+				RETURNING id, name, year, brand, fuel_type, engine_id, price_minor, currency, created_at, updated_at`
 
 	err = tx.QueryRowContext(ctx, query,
 		id,
@@ -204,8 +326,10 @@ func (s Store) UpdateCar(ctx context.Context, id string, carReq *models.CarReque
 		carReq.Brand,
 		carReq.FuelType,
 		carReq.Engine.EngineID,
-		carReq.Price,
+		carReq.Price.Amount,
+		carReq.Price.Currency,
 		time.Now(),
+		tenantID,
 	).Scan(
 		&updatedCar.ID,
 		&updatedCar.Name,
@@ -213,10 +337,29 @@ func (s Store) UpdateCar(ctx context.Context, id string, carReq *models.CarReque
 		&updatedCar.Brand,
 		&updatedCar.FuelType,
 		&updatedCar.Engine.EngineID,
-		&updatedCar.Price,
+		&updatedCar.Price.Amount,
+		&updatedCar.Price.Currency,
 		&updatedCar.CreatedAt,
 		&updatedCar.UpdatedAt,
 	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return updatedCar, errors.New("car not found")
+		}
+		return updatedCar, err
+	}
+	if updatedCar.Price != oldPrice {
+		err = insertCarPrice(ctx, tx, updatedCar, tenantID)
+		if err != nil {
+			return updatedCar, err
+		}
+	}
+	err = saveCarSpec(ctx, tx, updatedCar.ID, carReq.Spec)
+	if err != nil {
+		return updatedCar, err
+	}
+	updatedCar.Spec = carReq.Spec
+	err = outbox.RecordNew(ctx, tx, models.EventCarUpdated, tenantID, updatedCar.ID, updatedCar)
 	if err != nil {
 		return updatedCar, err
 	}
@@ -230,6 +373,11 @@ func (s Store) DeleteCar(ctx context.Context, id string) (models.Car, error) {
 
 	var deletedCar models.Car
 
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return deletedCar, err
+	}
+
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
 		return deletedCar, err
@@ -242,14 +390,15 @@ func (s Store) DeleteCar(ctx context.Context, id string) (models.Car, error) {
 		err = tx.Commit()
 	}()
 
-	err = tx.QueryRowContext(ctx, "SELECT id, name, year, brand, fuel_type, engine_id, price, created_at, updated_at FROM car WHERE id = $1", id).Scan(
+	err = tx.QueryRowContext(ctx, "SELECT id, name, year, brand, fuel_type, engine_id, price_minor, currency, created_at, updated_at FROM car WHERE id = $1 AND tenant_id = $2", id, tenantID).Scan(
 		&deletedCar.ID,
 		&deletedCar.Name,
 		&deletedCar.Year,
 		&deletedCar.Brand,
 		&deletedCar.FuelType,
 		&deletedCar.Engine.EngineID,
-		&deletedCar.Price,
+		&deletedCar.Price.Amount,
+		&deletedCar.Price.Currency,
 		&deletedCar.CreatedAt,
 		&deletedCar.UpdatedAt,
 	)
@@ -261,7 +410,24 @@ func (s Store) DeleteCar(ctx context.Context, id string) (models.Car, error) {
 		return models.Car{}, err
 	}
 
-	result, err := tx.ExecContext(ctx, "DELETE FROM car WHERE id = $1", id)
+	// vehicle.car_id and order_line.car_id REFERENCES car(id)
+	var hasVehicles, onOrder bool
+	err = tx.QueryRowContext(ctx,
+		"SELECT EXISTS (SELECT 1 FROM vehicle WHERE tenant_id = $2 AND car_id = $1), EXISTS (SELECT 1 FROM order_line WHERE car_id = $1)",
+		id, tenantID).Scan(&hasVehicles, &onOrder)
+	if err != nil {
+		return models.Car{}, err
+	}
+	if hasVehicles {
+		err = store.ErrCarHasVehicles
+		return models.Car{}, err
+	}
+	if onOrder {
+		err = store.ErrCarOnOrder
+		return models.Car{}, err
+	}
+
+	result, err := tx.ExecContext(ctx, "DELETE FROM car WHERE id = $1 AND tenant_id = $2", id, tenantID)
 	if err != nil {
 		return models.Car{}, err
 	}
@@ -273,5 +439,19 @@ func (s Store) DeleteCar(ctx context.Context, id string) (models.Car, error) {
 	if rowsAffected == 0 {
 		return models.Car{}, errors.New("No rows were deleted")
 	}
+	err = outbox.RecordNew(ctx, tx, models.EventCarDeleted, tenantID, deletedCar.ID, deletedCar)
+	if err != nil {
+		return models.Car{}, err
+	}
 	return deletedCar, nil
 }
+
+// insertCarPrice starts the price history of car, or continues it, with
+// its price from UpdatedAt on.
+func insertCarPrice(ctx context.Context, tx *sql.Tx, car models.Car, tenantID string) error {
+	_, err := tx.ExecContext(ctx,
+		`INSERT INTO car_price (id, tenant_id, car_id, price_minor, currency, effective_from, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $6)`,
+		uuid.New(), tenantID, car.ID, car.Price.Amount, car.Price.Currency, car.UpdatedAt)
+	return err
+}
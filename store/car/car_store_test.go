@@ -2,15 +2,43 @@ package car
 
 import (
 	"context"
+	"database/sql/driver"
+	"regexp"
 	"testing"
 	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/adohong4/carZone/models"
+	"github.com/adohong4/carZone/money"
+	"github.com/adohong4/carZone/store"
+	"github.com/adohong4/carZone/tenant"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 )
 
+var tenantCtx = tenant.WithTenant(context.Background(), tenant.DefaultID)
+
+var (
+	carColumns    = []string{"id", "name", "year", "brand", "fuel_type", "price_minor", "currency", "created_at", "updated_at"}
+	engineColumns = []string{"engine_id", "engine_type", "displacement", "no_of_cylinders", "car_range", "motor_power", "battery_capacity", "engine_updated_at"}
+	specColumns   = []string{"car_id", "transmission", "drivetrain", "body_type", "seats", "doors", "colour", "trim_level",
+		"fuel_consumption", "energy_consumption", "co2_emissions"}
+	returnedColumns = []string{"id", "name", "year", "brand", "fuel_type", "engine_id", "price_minor", "currency", "created_at", "updated_at"}
+)
+
+func columns(groups ...[]string) []string {
+	var all []string
+	for _, group := range groups {
+		all = append(all, group...)
+	}
+	return all
+}
+
+// noSpec are the car_spec columns of a car without a spec.
+func noSpec() []driver.Value {
+	return make([]driver.Value, len(specColumns))
+}
+
 func TestGetCarById(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	if err != nil {
@@ -20,16 +48,23 @@ func TestGetCarById(t *testing.T) {
 
 	store := New(db)
 
-	carID := uuid.New().String()
-	mock.ExpectQuery("SELECT c.id, c.name, c.year, c.brand, c.fuel_type, c.price, c.created_at, c.updated_at").
-		WithArgs(carID).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "year", "brand", "fuel_type", "price", "created_at", "updated_at"}).
-			AddRow(carID, "Test Car", 2020, "Test Brand", "Petrol", 20000, time.Now(), time.Now()))
-
-	car, err := store.GetCarById(context.Background(), carID)
+	carID := uuid.New()
+	engineID := uuid.New()
+	row := append([]driver.Value{
+		carID.String(), "Test Car", "2020", "Test Brand", "Petrol", 2000000, "USD", time.Now(), time.Now(),
+		engineID.String(), "combustion", 2000, 4, 600, 0, nil, time.Now(),
+	}, noSpec()...)
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT c.id, c.name, c.year, c.brand, c.fuel_type, c.price_minor, c.currency, c.created_at, c.updated_at")).
+		WithArgs(carID.String(), tenant.DefaultID).
+		WillReturnRows(sqlmock.NewRows(columns(carColumns, engineColumns, specColumns)).AddRow(row...))
+
+	car, err := store.GetCarById(tenantCtx, carID.String())
 	assert.NoError(t, err)
 	assert.Equal(t, carID, car.ID)
 	assert.Equal(t, "Test Car", car.Name)
+	assert.Equal(t, engineID, car.Engine.EngineID)
+	assert.Nil(t, car.Spec)
+	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
 func TestGetCarByBrand(t *testing.T) {
@@ -42,15 +77,16 @@ func TestGetCarByBrand(t *testing.T) {
 	store := New(db)
 
 	brand := "Test Brand"
-	mock.ExpectQuery("SELECT c.id, c.name, c.year, c.brand, c.fuel_type, c.price, c.created_at, c.updated_at").
-		WithArgs(brand).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "year", "brand", "fuel_type", "price", "created_at", "updated_at"}).
-			AddRow(uuid.New().String(), "Test Car 1", 2020, brand, "Petrol", 20000, time.Now(), time.Now()).
-			AddRow(uuid.New().String(), "Test Car 2", 2021, brand, "Diesel", 25000, time.Now(), time.Now()))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT c.id, c.name, c.year, c.brand, c.fuel_type, c.price_minor, c.currency, c.created_at, c.updated_at")).
+		WithArgs(brand, tenant.DefaultID).
+		WillReturnRows(sqlmock.NewRows(columns(carColumns, specColumns)).
+			AddRow(append([]driver.Value{uuid.NewString(), "Test Car 1", "2020", brand, "Petrol", 2000000, "USD", time.Now(), time.Now()}, noSpec()...)...).
+			AddRow(append([]driver.Value{uuid.NewString(), "Test Car 2", "2021", brand, "Diesel", 2500000, "USD", time.Now(), time.Now()}, noSpec()...)...))
 
-	cars, err := store.GetCarByBrand(context.Background(), brand, false)
+	cars, err := store.GetCarByBrand(tenantCtx, brand, false)
 	assert.NoError(t, err)
 	assert.Len(t, cars, 2)
+	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
 func TestCreateCar(t *testing.T) {
@@ -75,22 +111,34 @@ func TestCreateCar(t *testing.T) {
 			NoOfCylinders: 4,
 			CarRange:      300,
 		},
-		Price: 30000,
+		Price: money.Money{Amount: 3000000, Currency: "USD"},
 	}
 
-	mock.ExpectQuery("SELECT id FROM engine WHERE id = $1").
-		WithArgs(carReq.Engine.EngineID).
-		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(carReq.Engine.EngineID))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id FROM engine WHERE id = $1 AND tenant_id = $2")).
+		WithArgs(engineID, tenant.DefaultID).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(engineID.String()))
 
 	mock.ExpectBegin()
-	mock.ExpectExec("INSERT INTO car").
-		WithArgs(carID, carReq.Name, carReq.Year, carReq.Brand, carReq.FuelType, carReq.Engine.EngineID, carReq.Price, sqlmock.AnyArg(), sqlmock.AnyArg()).
+	mock.ExpectQuery("INSERT INTO car ").
+		WithArgs(sqlmock.AnyArg(), carReq.Name, carReq.Year, carReq.Brand, carReq.FuelType, engineID, carReq.Price.Amount, carReq.Price.Currency, sqlmock.AnyArg(), sqlmock.AnyArg(), tenant.DefaultID).
+		WillReturnRows(sqlmock.NewRows(returnedColumns).
+			AddRow(carID.String(), carReq.Name, carReq.Year, carReq.Brand, carReq.FuelType, engineID.String(), carReq.Price.Amount, carReq.Price.Currency, time.Now(), time.Now()))
+	mock.ExpectExec("INSERT INTO car_price").
+		WithArgs(sqlmock.AnyArg(), tenant.DefaultID, carID, carReq.Price.Amount, carReq.Price.Currency, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM car_spec WHERE car_id = $1")).
+		WithArgs(carID).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO outbox_event").
+		WithArgs(sqlmock.AnyArg(), tenant.DefaultID, models.EventCarCreated, carID, sqlmock.AnyArg(), sqlmock.AnyArg()).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 	mock.ExpectCommit()
 
-	car, err := store.CreateCar(context.Background(), carReq)
+	car, err := store.CreateCar(tenantCtx, carReq)
 	assert.NoError(t, err)
-	assert.Equal(t, carID.String(), car.ID.String())
+	assert.Equal(t, carID, car.ID)
+	assert.Equal(t, carReq.Price, car.Price)
+	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
 func TestUpdateCar(t *testing.T) {
@@ -103,29 +151,49 @@ func TestUpdateCar(t *testing.T) {
 	store := New(db)
 
 	carID := uuid.New()
+	engineID := uuid.New()
 	carReq := &models.CarRequest{
 		Name:     "Updated Car",
 		Year:     "2023",
 		Brand:    "Updated Brand",
 		FuelType: "Hybrid",
 		Engine: models.Engine{
-			EngineID:      uuid.New(),
+			EngineID:      engineID,
 			Displacement:  1600,
 			NoOfCylinders: 4,
 			CarRange:      350,
 		},
-		Price: 35000,
+		Price: money.Money{Amount: 3500000, Currency: "USD"},
 	}
 
 	mock.ExpectBegin()
-	mock.ExpectExec("UPDATE car").
-		WithArgs(carReq.Name, carReq.Year, carReq.Brand, carReq.FuelType, carReq.Engine.EngineID, carReq.Price, sqlmock.AnyArg(), carID.String()).
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id FROM engine WHERE id = $1 AND tenant_id = $2")).
+		WithArgs(engineID, tenant.DefaultID).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(engineID.String()))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT price_minor, currency FROM car WHERE id = $1 AND tenant_id = $2 FOR UPDATE")).
+		WithArgs(carID.String(), tenant.DefaultID).
+		WillReturnRows(sqlmock.NewRows([]string{"price_minor", "currency"}).AddRow(3000000, "USD"))
+	mock.ExpectQuery("UPDATE car").
+		WithArgs(carID.String(), carReq.Name, carReq.Year, carReq.Brand, carReq.FuelType, engineID, carReq.Price.Amount, carReq.Price.Currency, sqlmock.AnyArg(), tenant.DefaultID).
+		WillReturnRows(sqlmock.NewRows(returnedColumns).
+			AddRow(carID.String(), carReq.Name, carReq.Year, carReq.Brand, carReq.FuelType, engineID.String(), carReq.Price.Amount, carReq.Price.Currency, time.Now(), time.Now()))
+	// the price changed, so the history gets a new entry
+	mock.ExpectExec("INSERT INTO car_price").
+		WithArgs(sqlmock.AnyArg(), tenant.DefaultID, carID, carReq.Price.Amount, carReq.Price.Currency, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM car_spec WHERE car_id = $1")).
+		WithArgs(carID).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO outbox_event").
+		WithArgs(sqlmock.AnyArg(), tenant.DefaultID, models.EventCarUpdated, carID, sqlmock.AnyArg(), sqlmock.AnyArg()).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 	mock.ExpectCommit()
 
-	car, err := store.UpdateCar(context.Background(), carID.String(), carReq)
+	car, err := store.UpdateCar(tenantCtx, carID.String(), carReq)
 	assert.NoError(t, err)
-	assert.Equal(t, carID.String(), car.ID.String())
+	assert.Equal(t, carID, car.ID)
+	assert.Equal(t, carReq.Price, car.Price)
+	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
 func TestDeleteCar(t *testing.T) {
@@ -137,18 +205,51 @@ func TestDeleteCar(t *testing.T) {
 
 	store := New(db)
 
-	carID := uuid.New().String()
+	carID := uuid.New()
 	mock.ExpectBegin()
-	mock.ExpectQuery("SELECT id, name, year, brand").
-		WithArgs(carID).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "year", "brand", "fuel_type", "engine_id", "price", "created_at", "updated_at"}).
-			AddRow(carID, "Deleted Car", 2020, "Deleted Brand", "Petrol", uuid.New(), 20000, time.Now(), time.Now()))
-	mock.ExpectExec("DELETE FROM car WHERE id = $1").
-		WithArgs(carID).
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id, name, year, brand, fuel_type, engine_id, price_minor, currency, created_at, updated_at FROM car WHERE id = $1 AND tenant_id = $2")).
+		WithArgs(carID.String(), tenant.DefaultID).
+		WillReturnRows(sqlmock.NewRows(returnedColumns).
+			AddRow(carID.String(), "Deleted Car", "2020", "Deleted Brand", "Petrol", uuid.NewString(), 2000000, "USD", time.Now(), time.Now()))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT EXISTS (SELECT 1 FROM vehicle WHERE tenant_id = $2 AND car_id = $1)")).
+		WithArgs(carID.String(), tenant.DefaultID).
+		WillReturnRows(sqlmock.NewRows([]string{"vehicles", "orders"}).AddRow(false, false))
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM car WHERE id = $1 AND tenant_id = $2")).
+		WithArgs(carID.String(), tenant.DefaultID).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO outbox_event").
+		WithArgs(sqlmock.AnyArg(), tenant.DefaultID, models.EventCarDeleted, carID, sqlmock.AnyArg(), sqlmock.AnyArg()).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 	mock.ExpectCommit()
 
-	car, err := store.DeleteCar(context.Background(), carID)
+	car, err := store.DeleteCar(tenantCtx, carID.String())
 	assert.NoError(t, err)
 	assert.Equal(t, carID, car.ID)
+	assert.Equal(t, "Deleted Car", car.Name)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleteCarWithVehicles(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' occurred when opening a database connection", err)
+	}
+	defer db.Close()
+
+	carStore := New(db)
+
+	carID := uuid.New()
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id, name, year, brand, fuel_type, engine_id, price_minor, currency, created_at, updated_at FROM car WHERE id = $1 AND tenant_id = $2")).
+		WithArgs(carID.String(), tenant.DefaultID).
+		WillReturnRows(sqlmock.NewRows(returnedColumns).
+			AddRow(carID.String(), "Stocked Car", "2020", "Stocked Brand", "Petrol", uuid.NewString(), 2000000, "USD", time.Now(), time.Now()))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT EXISTS (SELECT 1 FROM vehicle WHERE tenant_id = $2 AND car_id = $1)")).
+		WithArgs(carID.String(), tenant.DefaultID).
+		WillReturnRows(sqlmock.NewRows([]string{"vehicles", "orders"}).AddRow(true, false))
+	mock.ExpectRollback()
+
+	_, err = carStore.DeleteCar(tenantCtx, carID.String())
+	assert.ErrorIs(t, err, store.ErrCarHasVehicles)
+	assert.NoError(t, mock.ExpectationsWereMet())
 }
@@ -0,0 +1,257 @@
+package catalogue
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/adohong4/carZone/models"
+	"github.com/adohong4/carZone/store"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+)
+
+type Store struct {
+	db *sql.DB
+}
+
+func New(db *sql.DB) Store {
+	return Store{db: db}
+}
+
+// brands reads the brands matching cond, which may only refer to brand b,
+// with their aliases and models. Aliases come one row each, so rows of the
+// same brand or model are folded together.
+func (s Store) brands(ctx context.Context, cond string, args ...interface{}) ([]models.Brand, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT b.id, b.name, b.created_at, a.alias
+		FROM brand b LEFT JOIN brand_alias a ON a.brand_id = b.id
+		WHERE `+cond+`
+		ORDER BY b.name_key, a.alias_key`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var brands []models.Brand
+	brandIndex := make(map[uuid.UUID]int)
+	for rows.Next() {
+		var (
+			brand models.Brand
+			alias *string
+		)
+		if err := rows.Scan(&brand.ID, &brand.Name, &brand.CreatedAt, &alias); err != nil {
+			return nil, err
+		}
+		i, ok := brandIndex[brand.ID]
+		if !ok {
+			brand.Aliases = []string{}
+			brand.Models = []models.CarModel{}
+			i = len(brands)
+			brandIndex[brand.ID] = i
+			brands = append(brands, brand)
+		}
+		if alias != nil {
+			brands[i].Aliases = append(brands[i].Aliases, *alias)
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(brands) == 0 {
+		return brands, nil
+	}
+
+	rows, err = s.db.QueryContext(ctx, `SELECT m.id, m.brand_id, m.name, m.created_at, a.alias
+		FROM car_model m JOIN brand b ON b.id = m.brand_id
+		LEFT JOIN car_model_alias a ON a.model_id = m.id
+		WHERE `+cond+`
+		ORDER BY m.name_key, a.alias_key`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	modelIndex := make(map[uuid.UUID]int)
+	for rows.Next() {
+		var (
+			model models.CarModel
+			alias *string
+		)
+		if err := rows.Scan(&model.ID, &model.BrandID, &model.Name, &model.CreatedAt, &alias); err != nil {
+			return nil, err
+		}
+		brand := &brands[brandIndex[model.BrandID]]
+		i, ok := modelIndex[model.ID]
+		if !ok {
+			model.Aliases = []string{}
+			i = len(brand.Models)
+			modelIndex[model.ID] = i
+			brand.Models = append(brand.Models, model)
+		}
+		if alias != nil {
+			brand.Models[i].Aliases = append(brand.Models[i].Aliases, *alias)
+		}
+	}
+	return brands, rows.Err()
+}
+
+func (s Store) ListBrands(ctx context.Context) ([]models.Brand, error) {
+	tracer := otel.Tracer("CatalogueStore")
+	ctx, span := tracer.Start(ctx, "ListBrands-Store")
+	defer span.End()
+
+	return s.brands(ctx, "1 = 1")
+}
+
+func (s Store) GetBrand(ctx context.Context, id string) (models.Brand, error) {
+	tracer := otel.Tracer("CatalogueStore")
+	ctx, span := tracer.Start(ctx, "GetBrand-Store")
+	defer span.End()
+
+	brands, err := s.brands(ctx, "b.id = $1", id)
+	if err != nil || len(brands) == 0 {
+		return models.Brand{}, err
+	}
+	return brands[0], nil
+}
+
+func (s Store) FindBrand(ctx context.Context, name string) (models.Brand, error) {
+	tracer := otel.Tracer("CatalogueStore")
+	ctx, span := tracer.Start(ctx, "FindBrand-Store")
+	defer span.End()
+
+	brands, err := s.brands(ctx,
+		"b.id IN (SELECT id FROM brand WHERE name_key = $1 UNION SELECT brand_id FROM brand_alias WHERE alias_key = $1)",
+		models.CatalogueKey(name))
+	if err != nil || len(brands) == 0 {
+		return models.Brand{}, err
+	}
+	return brands[0], nil
+}
+
+// SaveBrand renames the cars of the brand in the same transaction, so cars
+// always carry a name from the catalogue.
+func (s Store) SaveBrand(ctx context.Context, brand models.Brand) (models.Brand, error) {
+	tracer := otel.Tracer("CatalogueStore")
+	ctx, span := tracer.Start(ctx, "SaveBrand-Store")
+	defer span.End()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Brand{}, err
+	}
+	defer tx.Rollback()
+
+	var oldName string
+	err = tx.QueryRowContext(ctx, "SELECT name FROM brand WHERE id = $1 FOR UPDATE", brand.ID).Scan(&oldName)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		_, err = tx.ExecContext(ctx, "INSERT INTO brand (id, name, name_key, created_at) VALUES ($1, $2, $3, $4)",
+			brand.ID, brand.Name, models.CatalogueKey(brand.Name), brand.CreatedAt)
+	case err == nil:
+		_, err = tx.ExecContext(ctx, "UPDATE brand SET name = $1, name_key = $2 WHERE id = $3",
+			brand.Name, models.CatalogueKey(brand.Name), brand.ID)
+		if err == nil && oldName != brand.Name {
+			_, err = tx.ExecContext(ctx, "UPDATE car SET brand = $1 WHERE brand = $2", brand.Name, oldName)
+		}
+	}
+	if err != nil {
+		return models.Brand{}, err
+	}
+
+	if _, err = tx.ExecContext(ctx, "DELETE FROM brand_alias WHERE brand_id = $1", brand.ID); err != nil {
+		return models.Brand{}, err
+	}
+	for _, alias := range brand.Aliases {
+		_, err = tx.ExecContext(ctx, "INSERT INTO brand_alias (alias_key, brand_id, alias) VALUES ($1, $2, $3)",
+			models.CatalogueKey(alias), brand.ID, alias)
+		if err != nil {
+			return models.Brand{}, err
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		return models.Brand{}, err
+	}
+	return brand, nil
+}
+
+func (s Store) DeleteBrand(ctx context.Context, id string) (models.Brand, error) {
+	tracer := otel.Tracer("CatalogueStore")
+	ctx, span := tracer.Start(ctx, "DeleteBrand-Store")
+	defer span.End()
+
+	brand, err := s.GetBrand(ctx, id)
+	if err != nil || brand.ID == uuid.Nil {
+		return models.Brand{}, err
+	}
+
+	// cars of every dealership count, the catalogue is shared
+	var inUse bool
+	err = s.db.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM car WHERE brand = $1)", brand.Name).Scan(&inUse)
+	if err != nil {
+		return models.Brand{}, err
+	}
+	if inUse {
+		return models.Brand{}, store.ErrBrandInUse
+	}
+
+	if _, err = s.db.ExecContext(ctx, "DELETE FROM brand WHERE id = $1", id); err != nil {
+		return models.Brand{}, err
+	}
+	return brand, nil
+}
+
+func (s Store) SaveCarModel(ctx context.Context, model models.CarModel) (models.CarModel, error) {
+	tracer := otel.Tracer("CatalogueStore")
+	ctx, span := tracer.Start(ctx, "SaveCarModel-Store")
+	defer span.End()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.CarModel{}, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO car_model (id, brand_id, name, name_key, created_at) VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (id) DO UPDATE SET name = EXCLUDED.name, name_key = EXCLUDED.name_key`,
+		model.ID, model.BrandID, model.Name, models.CatalogueKey(model.Name), model.CreatedAt)
+	if err != nil {
+		return models.CarModel{}, err
+	}
+
+	if _, err = tx.ExecContext(ctx, "DELETE FROM car_model_alias WHERE model_id = $1", model.ID); err != nil {
+		return models.CarModel{}, err
+	}
+	for _, alias := range model.Aliases {
+		_, err = tx.ExecContext(ctx,
+			"INSERT INTO car_model_alias (brand_id, alias_key, model_id, alias) VALUES ($1, $2, $3, $4)",
+			model.BrandID, models.CatalogueKey(alias), model.ID, alias)
+		if err != nil {
+			return models.CarModel{}, err
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		return models.CarModel{}, err
+	}
+	return model, nil
+}
+
+func (s Store) DeleteCarModel(ctx context.Context, brandID, id string) (models.CarModel, error) {
+	tracer := otel.Tracer("CatalogueStore")
+	ctx, span := tracer.Start(ctx, "DeleteCarModel-Store")
+	defer span.End()
+
+	var model models.CarModel
+	err := s.db.QueryRowContext(ctx,
+		"DELETE FROM car_model WHERE id = $1 AND brand_id = $2 RETURNING id, brand_id, name, created_at", id, brandID).Scan(
+		&model.ID, &model.BrandID, &model.Name, &model.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.CarModel{}, nil
+		}
+		return models.CarModel{}, err
+	}
+	model.Aliases = []string{}
+	return model, nil
+}
@@ -0,0 +1,371 @@
+package customer
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/adohong4/carZone/models"
+	"github.com/adohong4/carZone/store"
+	"github.com/adohong4/carZone/tenant"
+	"go.opentelemetry.io/otel"
+)
+
+const customerColumns = `id, name, email, phone, address, marketing_consent, contact_consent,
+	consent_updated_at, created_at, updated_at, erased_at`
+
+type Store struct {
+	db *sql.DB
+}
+
+func New(db *sql.DB) Store {
+	return Store{db: db}
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanCustomer(row scanner) (models.Customer, error) {
+	var (
+		customer         models.Customer
+		consentUpdatedAt sql.NullTime
+		erasedAt         sql.NullTime
+	)
+	err := row.Scan(
+		&customer.ID, &customer.Name, &customer.Email, &customer.Phone, &customer.Address,
+		&customer.MarketingConsent, &customer.ContactConsent, &consentUpdatedAt,
+		&customer.CreatedAt, &customer.UpdatedAt, &erasedAt,
+	)
+	if consentUpdatedAt.Valid {
+		customer.ConsentUpdatedAt = &consentUpdatedAt.Time
+	}
+	if erasedAt.Valid {
+		customer.ErasedAt = &erasedAt.Time
+	}
+	return customer, err
+}
+
+// likePattern matches search anywhere in a lower-cased column, with the
+// LIKE wildcards in it taken literally.
+func likePattern(search string) string {
+	escaped := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(strings.ToLower(search))
+	return "%" + escaped + "%"
+}
+
+func (s Store) GetCustomerById(ctx context.Context, id string) (models.Customer, error) {
+	tracer := otel.Tracer("CustomerStore")
+	ctx, span := tracer.Start(ctx, "GetCustomerById-Store")
+	defer span.End()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return models.Customer{}, err
+	}
+
+	query := "SELECT " + customerColumns + " FROM customer WHERE id = $1 AND tenant_id = $2"
+	customer, err := scanCustomer(s.db.QueryRowContext(ctx, query, id, tenantID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Customer{}, nil
+		}
+		return models.Customer{}, err
+	}
+
+	customer.Notes, err = s.notes(ctx, id)
+	if err != nil {
+		return models.Customer{}, err
+	}
+	customer.Cars, err = s.cars(ctx, id)
+	if err != nil {
+		return models.Customer{}, err
+	}
+	return customer, nil
+}
+
+func (s Store) notes(ctx context.Context, customerID string) ([]models.CustomerNote, error) {
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT id, body, author, created_at FROM customer_note WHERE customer_id = $1 ORDER BY created_at, id", customerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var notes []models.CustomerNote
+	for rows.Next() {
+		var note models.CustomerNote
+		if err := rows.Scan(&note.ID, &note.Body, &note.Author, &note.CreatedAt); err != nil {
+			return nil, err
+		}
+		notes = append(notes, note)
+	}
+	return notes, rows.Err()
+}
+
+func (s Store) cars(ctx context.Context, customerID string) ([]models.CustomerCar, error) {
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT car_id, kind, created_at FROM customer_car WHERE customer_id = $1 ORDER BY created_at, car_id, kind", customerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var cars []models.CustomerCar
+	for rows.Next() {
+		var link models.CustomerCar
+		if err := rows.Scan(&link.CarID, &link.Kind, &link.CreatedAt); err != nil {
+			return nil, err
+		}
+		cars = append(cars, link)
+	}
+	return cars, rows.Err()
+}
+
+// ListCustomers leaves erased customers out, they have nothing left to
+// search for.
+func (s Store) ListCustomers(ctx context.Context, search string) ([]models.Customer, error) {
+	tracer := otel.Tracer("CustomerStore")
+	ctx, span := tracer.Start(ctx, "ListCustomers-Store")
+	defer span.End()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := "SELECT " + customerColumns + ` FROM customer
+				WHERE tenant_id = $1 AND erased_at IS NULL
+				AND (lower(name) LIKE $2 ESCAPE '\' OR lower(email) LIKE $2 ESCAPE '\')
+				ORDER BY lower(name), id`
+	rows, err := s.db.QueryContext(ctx, query, tenantID, likePattern(search))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var customers []models.Customer
+	for rows.Next() {
+		customer, err := scanCustomer(rows)
+		if err != nil {
+			return nil, err
+		}
+		customers = append(customers, customer)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return customers, nil
+}
+
+func (s Store) CreateCustomer(ctx context.Context, customer models.Customer) (models.Customer, error) {
+	tracer := otel.Tracer("CustomerStore")
+	ctx, span := tracer.Start(ctx, "CreateCustomer-Store")
+	defer span.End()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return models.Customer{}, err
+	}
+
+	query := `INSERT INTO customer (id, tenant_id, name, email, phone, address, marketing_consent, contact_consent,
+				consent_updated_at, created_at, updated_at)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+				RETURNING ` + customerColumns
+	return scanCustomer(s.db.QueryRowContext(ctx, query,
+		customer.ID, tenantID, customer.Name, customer.Email, customer.Phone, customer.Address,
+		customer.MarketingConsent, customer.ContactConsent, customer.ConsentUpdatedAt,
+		customer.CreatedAt, customer.UpdatedAt,
+	))
+}
+
+func (s Store) UpdateCustomer(ctx context.Context, customer models.Customer) (models.Customer, error) {
+	tracer := otel.Tracer("CustomerStore")
+	ctx, span := tracer.Start(ctx, "UpdateCustomer-Store")
+	defer span.End()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return models.Customer{}, err
+	}
+
+	result, err := s.db.ExecContext(ctx,
+		`UPDATE customer SET name = $1, email = $2, phone = $3, address = $4, marketing_consent = $5,
+			contact_consent = $6, consent_updated_at = $7, updated_at = $8
+			WHERE id = $9 AND tenant_id = $10`,
+		customer.Name, customer.Email, customer.Phone, customer.Address, customer.MarketingConsent,
+		customer.ContactConsent, customer.ConsentUpdatedAt, customer.UpdatedAt, customer.ID, tenantID)
+	if err != nil {
+		return models.Customer{}, err
+	}
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return models.Customer{}, err
+	}
+	if updated == 0 {
+		return models.Customer{}, nil
+	}
+	return s.GetCustomerById(ctx, customer.ID.String())
+}
+
+// DeleteCustomer removes the customer, its notes and its car links.
+func (s Store) DeleteCustomer(ctx context.Context, id string) (models.Customer, error) {
+	tracer := otel.Tracer("CustomerStore")
+	ctx, span := tracer.Start(ctx, "DeleteCustomer-Store")
+	defer span.End()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return models.Customer{}, err
+	}
+
+	query := "DELETE FROM customer WHERE id = $1 AND tenant_id = $2 RETURNING " + customerColumns
+	customer, err := scanCustomer(s.db.QueryRowContext(ctx, query, id, tenantID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Customer{}, nil
+		}
+		return models.Customer{}, err
+	}
+	return customer, nil
+}
+
+func (s Store) AddCustomerNote(ctx context.Context, customerID string, note models.CustomerNote) (models.CustomerNote, error) {
+	tracer := otel.Tracer("CustomerStore")
+	ctx, span := tracer.Start(ctx, "AddCustomerNote-Store")
+	defer span.End()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return models.CustomerNote{}, err
+	}
+
+	// inserting from the customer row checks it belongs to the dealership
+	result, err := s.db.ExecContext(ctx,
+		`INSERT INTO customer_note (id, customer_id, body, author, created_at)
+			SELECT $1, id, $2, $3, $4 FROM customer WHERE id = $5 AND tenant_id = $6`,
+		note.ID, note.Body, note.Author, note.CreatedAt, customerID, tenantID)
+	if err != nil {
+		return models.CustomerNote{}, err
+	}
+	inserted, err := result.RowsAffected()
+	if err != nil {
+		return models.CustomerNote{}, err
+	}
+	if inserted == 0 {
+		return models.CustomerNote{}, store.ErrCustomerNotFound
+	}
+	return note, nil
+}
+
+func (s Store) LinkCustomerCar(ctx context.Context, customerID string, link models.CustomerCar) (models.CustomerCar, error) {
+	tracer := otel.Tracer("CustomerStore")
+	ctx, span := tracer.Start(ctx, "LinkCustomerCar-Store")
+	defer span.End()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return models.CustomerCar{}, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.CustomerCar{}, err
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	err = tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM customer WHERE id = $1 AND tenant_id = $2)",
+		customerID, tenantID).Scan(&exists)
+	if err != nil {
+		return models.CustomerCar{}, err
+	}
+	if !exists {
+		return models.CustomerCar{}, store.ErrCustomerNotFound
+	}
+	err = tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM car WHERE id = $1 AND tenant_id = $2)",
+		link.CarID, tenantID).Scan(&exists)
+	if err != nil {
+		return models.CustomerCar{}, err
+	}
+	if !exists {
+		return models.CustomerCar{}, store.ErrCarNotFound
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO customer_car (customer_id, car_id, kind, created_at) VALUES ($1, $2, $3, $4)
+			ON CONFLICT (customer_id, car_id, kind) DO NOTHING`,
+		customerID, link.CarID, link.Kind, link.CreatedAt)
+	if err != nil {
+		return models.CustomerCar{}, err
+	}
+
+	var linked models.CustomerCar
+	err = tx.QueryRowContext(ctx,
+		"SELECT car_id, kind, created_at FROM customer_car WHERE customer_id = $1 AND car_id = $2 AND kind = $3",
+		customerID, link.CarID, link.Kind).Scan(&linked.CarID, &linked.Kind, &linked.CreatedAt)
+	if err != nil {
+		return models.CustomerCar{}, err
+	}
+	if err = tx.Commit(); err != nil {
+		return models.CustomerCar{}, err
+	}
+	return linked, nil
+}
+
+// EraseCustomer blanks the personal data, withdraws the consents, drops
+// the notes and blanks the customer columns of the linked reservations and
+// orders in one transaction. Erasing twice keeps the first erased_at.
+func (s Store) EraseCustomer(ctx context.Context, id string, now time.Time) (models.Customer, error) {
+	tracer := otel.Tracer("CustomerStore")
+	ctx, span := tracer.Start(ctx, "EraseCustomer-Store")
+	defer span.End()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return models.Customer{}, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Customer{}, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx,
+		`UPDATE customer SET name = '', email = '', phone = '', address = '', marketing_consent = false,
+			contact_consent = false, consent_updated_at = $1, updated_at = $1, erased_at = COALESCE(erased_at, $1)
+			WHERE id = $2 AND tenant_id = $3`,
+		now, id, tenantID)
+	if err != nil {
+		return models.Customer{}, err
+	}
+	erased, err := result.RowsAffected()
+	if err != nil {
+		return models.Customer{}, err
+	}
+	if erased == 0 {
+		return models.Customer{}, nil
+	}
+
+	if _, err = tx.ExecContext(ctx, "DELETE FROM customer_note WHERE customer_id = $1", id); err != nil {
+		return models.Customer{}, err
+	}
+	_, err = tx.ExecContext(ctx,
+		"UPDATE reservation SET customer_name = '', customer_contact = '' WHERE customer_id = $1 AND tenant_id = $2",
+		id, tenantID)
+	if err != nil {
+		return models.Customer{}, err
+	}
+	_, err = tx.ExecContext(ctx,
+		"UPDATE sales_order SET customer_name = '', customer_email = '', customer_address = '' WHERE customer_id = $1 AND tenant_id = $2",
+		id, tenantID)
+	if err != nil {
+		return models.Customer{}, err
+	}
+	if err = tx.Commit(); err != nil {
+		return models.Customer{}, err
+	}
+	return s.GetCustomerById(ctx, id)
+}
@@ -0,0 +1,82 @@
+package dealership
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/adohong4/carZone/models"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+)
+
+type Store struct {
+	db *sql.DB
+}
+
+func New(db *sql.DB) Store {
+	return Store{db: db}
+}
+
+func (s Store) GetDealerships(ctx context.Context) ([]models.Dealership, error) {
+	tracer := otel.Tracer("DealershipStore")
+	ctx, span := tracer.Start(ctx, "GetDealerships-Store")
+	defer span.End()
+
+	rows, err := s.db.QueryContext(ctx, "SELECT id, name, created_at FROM dealership ORDER BY created_at, id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var dealerships []models.Dealership
+	for rows.Next() {
+		var dealership models.Dealership
+		if err := rows.Scan(&dealership.ID, &dealership.Name, &dealership.CreatedAt); err != nil {
+			return nil, err
+		}
+		dealerships = append(dealerships, dealership)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return dealerships, nil
+}
+
+func (s Store) GetDealershipById(ctx context.Context, id string) (models.Dealership, error) {
+	tracer := otel.Tracer("DealershipStore")
+	ctx, span := tracer.Start(ctx, "GetDealershipById-Store")
+	defer span.End()
+
+	var dealership models.Dealership
+	err := s.db.QueryRowContext(ctx, "SELECT id, name, created_at FROM dealership WHERE id = $1", id).Scan(
+		&dealership.ID, &dealership.Name, &dealership.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return dealership, nil
+		}
+		return dealership, err
+	}
+	return dealership, nil
+}
+
+func (s Store) CreateDealership(ctx context.Context, dealershipReq *models.DealershipRequest) (models.Dealership, error) {
+	tracer := otel.Tracer("DealershipStore")
+	ctx, span := tracer.Start(ctx, "CreateDealership-Store")
+	defer span.End()
+
+	dealership := models.Dealership{
+		ID:        uuid.New(),
+		Name:      dealershipReq.Name,
+		CreatedAt: time.Now(),
+	}
+
+	_, err := s.db.ExecContext(ctx, "INSERT INTO dealership (id, name, created_at) VALUES ($1, $2, $3)",
+		dealership.ID, dealership.Name, dealership.CreatedAt)
+	if err != nil {
+		return models.Dealership{}, err
+	}
+	return dealership, nil
+}
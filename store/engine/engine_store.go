@@ -6,8 +6,11 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"time"
 
 	"github.com/adohong4/carZone/models"
+	"github.com/adohong4/carZone/store/outbox"
+	"github.com/adohong4/carZone/tenant"
 	"github.com/google/uuid"
 	"go.opentelemetry.io/otel"
 )
@@ -27,6 +30,11 @@ func (e EngineSstore) EngineById(ctx context.Context, id string) (models.Engine,
 
 	var engine models.Engine
 
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return engine, err
+	}
+
 	tx, err := e.db.BeginTx(ctx, nil)
 	if err != nil {
 		log.Fatalf("Failed to begin transaction: %v", err)
@@ -44,8 +52,9 @@ func (e EngineSstore) EngineById(ctx context.Context, id string) (models.Engine,
 		}
 	}()
 
-	err = tx.QueryRowContext(ctx, "SELECT id, displacement, no_of_cylinders, car_range FROM engine WHERE id = $1", id).Scan(
-		&engine.EngineID, &engine.Displacement, &engine.NoOfCylinders, &engine.CarRange,
+	err = tx.QueryRowContext(ctx, "SELECT id, engine_type, displacement, no_of_cylinders, car_range, motor_power, battery_capacity, updated_at FROM engine WHERE id = $1 AND tenant_id = $2", id, tenantID).Scan(
+		&engine.EngineID, &engine.Type, &engine.Displacement, &engine.NoOfCylinders, &engine.CarRange,
+		&engine.MotorPower, &engine.BatteryCapacity, &engine.UpdatedAt,
 	)
 
 	if err != nil {
@@ -63,6 +72,11 @@ func (e EngineSstore) CreateEngine(ctx context.Context, engineReq *models.Engine
 	ctx, span := tracer.Start(ctx, "CreateEngine-Store")
 	defer span.End()
 
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return models.Engine{}, err
+	}
+
 	tx, err := e.db.BeginTx(ctx, nil)
 	if err != nil {
 		log.Fatalf("Failed to begin transaction: %v", err)
@@ -81,10 +95,13 @@ func (e EngineSstore) CreateEngine(ctx context.Context, engineReq *models.Engine
 	}()
 
 	engineID := uuid.New()
+	updatedAt := time.Now()
 
 	_, err = tx.ExecContext(ctx,
-		"INSERT INTO engine (id, displacement, no_of_cylinders, car_range) VALUES ($1, $2, $3, $4)",
-		engineID, engineReq.Displacement, engineReq.NoOfCylinders, engineReq.CarRange,
+		`INSERT INTO engine (id, engine_type, displacement, no_of_cylinders, car_range, motor_power, battery_capacity, updated_at, tenant_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+		engineID, models.EngineType(engineReq.Type), engineReq.Displacement, engineReq.NoOfCylinders, engineReq.CarRange,
+		engineReq.MotorPower, engineReq.BatteryCapacity, updatedAt, tenantID,
 	)
 	if err != nil {
 		log.Printf("Error inserting engine: %v", err)
@@ -92,10 +109,18 @@ func (e EngineSstore) CreateEngine(ctx context.Context, engineReq *models.Engine
 	}
 
 	engine := models.Engine{
-		EngineID:      engineID,
-		Displacement:  engineReq.Displacement,
-		NoOfCylinders: engineReq.NoOfCylinders,
-		CarRange:      engineReq.CarRange,
+		EngineID:        engineID,
+		Type:            models.EngineType(engineReq.Type),
+		Displacement:    engineReq.Displacement,
+		NoOfCylinders:   engineReq.NoOfCylinders,
+		CarRange:        engineReq.CarRange,
+		MotorPower:      engineReq.MotorPower,
+		BatteryCapacity: engineReq.BatteryCapacity,
+		UpdatedAt:       updatedAt,
+	}
+	err = outbox.RecordNew(ctx, tx, models.EventEngineCreated, tenantID, engine.EngineID, engine)
+	if err != nil {
+		return models.Engine{}, err
 	}
 
 	return engine, nil
@@ -111,6 +136,11 @@ func (e EngineSstore) EngineUpdate(ctx context.Context, id string, engineReq *mo
 		return models.Engine{}, fmt.Errorf("invalid engine ID: %w", err)
 	}
 
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return models.Engine{}, err
+	}
+
 	tx, err := e.db.BeginTx(ctx, nil)
 	if err != nil {
 		return models.Engine{}, err
@@ -127,9 +157,12 @@ func (e EngineSstore) EngineUpdate(ctx context.Context, id string, engineReq *mo
 		}
 	}()
 
+	updatedAt := time.Now()
 	results, err := tx.ExecContext(ctx,
-		"UPDATE engine SET displacement = $1, no_of_cylinders = $2, car_range = $3 WHERE id = $4",
-		engineReq.Displacement, engineReq.NoOfCylinders, engineReq.CarRange)
+		`UPDATE engine SET engine_type = $1, displacement = $2, no_of_cylinders = $3, car_range = $4, motor_power = $5, battery_capacity = $6, updated_at = $7
+		WHERE id = $8 AND tenant_id = $9`,
+		models.EngineType(engineReq.Type), engineReq.Displacement, engineReq.NoOfCylinders, engineReq.CarRange,
+		engineReq.MotorPower, engineReq.BatteryCapacity, updatedAt, engineID, tenantID)
 
 	if err != nil {
 		return models.Engine{}, err
@@ -144,10 +177,18 @@ func (e EngineSstore) EngineUpdate(ctx context.Context, id string, engineReq *mo
 	}
 
 	engine := models.Engine{
-		EngineID:      engineID,
-		Displacement:  engineReq.Displacement,
-		NoOfCylinders: engineReq.NoOfCylinders,
-		CarRange:      engineReq.CarRange,
+		EngineID:        engineID,
+		Type:            models.EngineType(engineReq.Type),
+		Displacement:    engineReq.Displacement,
+		NoOfCylinders:   engineReq.NoOfCylinders,
+		CarRange:        engineReq.CarRange,
+		MotorPower:      engineReq.MotorPower,
+		BatteryCapacity: engineReq.BatteryCapacity,
+		UpdatedAt:       updatedAt,
+	}
+	err = outbox.RecordNew(ctx, tx, models.EventEngineUpdated, tenantID, engine.EngineID, engine)
+	if err != nil {
+		return models.Engine{}, err
 	}
 
 	return engine, nil
@@ -160,6 +201,11 @@ func (e EngineSstore) EngineDelete(ctx context.Context, id string) (models.Engin
 
 	var engine models.Engine
 
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return engine, err
+	}
+
 	tx, err := e.db.BeginTx(ctx, nil)
 	if err != nil {
 		return models.Engine{}, err
@@ -176,8 +222,9 @@ func (e EngineSstore) EngineDelete(ctx context.Context, id string) (models.Engin
 		}
 	}()
 
-	err = tx.QueryRowContext(ctx, "SELECT id, displacement, no_of_cylinders, car_range FROM engine WHERE id = $1", id).Scan(
-		&engine.EngineID, &engine.Displacement, &engine.NoOfCylinders, &engine.CarRange,
+	err = tx.QueryRowContext(ctx, "SELECT id, engine_type, displacement, no_of_cylinders, car_range, motor_power, battery_capacity, updated_at FROM engine WHERE id = $1 AND tenant_id = $2", id, tenantID).Scan(
+		&engine.EngineID, &engine.Type, &engine.Displacement, &engine.NoOfCylinders, &engine.CarRange,
+		&engine.MotorPower, &engine.BatteryCapacity, &engine.UpdatedAt,
 	)
 
 	if err != nil {
@@ -187,7 +234,7 @@ func (e EngineSstore) EngineDelete(ctx context.Context, id string) (models.Engin
 		return engine, err
 	}
 
-	result, err := tx.ExecContext(ctx, "DELETE FROM engine WHERE id = $1", id)
+	result, err := tx.ExecContext(ctx, "DELETE FROM engine WHERE id = $1 AND tenant_id = $2", id, tenantID)
 	if err != nil {
 		return models.Engine{}, err
 	}
@@ -199,6 +246,10 @@ func (e EngineSstore) EngineDelete(ctx context.Context, id string) (models.Engin
 	if rowsAffected == 0 {
 		return models.Engine{}, errors.New("No Rows Were Deleted")
 	}
+	err = outbox.RecordNew(ctx, tx, models.EventEngineDeleted, tenantID, engine.EngineID, engine)
+	if err != nil {
+		return models.Engine{}, err
+	}
 
 	return engine, nil
 }
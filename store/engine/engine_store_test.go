@@ -2,14 +2,21 @@ package engine
 
 import (
 	"context"
+	"regexp"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/adohong4/carZone/models"
+	"github.com/adohong4/carZone/tenant"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 )
 
+var tenantCtx = tenant.WithTenant(context.Background(), tenant.DefaultID)
+
+var engineColumns = []string{"id", "engine_type", "displacement", "no_of_cylinders", "car_range", "motor_power", "battery_capacity", "updated_at"}
+
 func TestEngineById(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	if err != nil {
@@ -20,15 +27,20 @@ func TestEngineById(t *testing.T) {
 	store := New(db)
 
 	engineID := uuid.New().String()
-	mock.ExpectQuery("SELECT id, displacement, no_of_cylinders, car_range").
-		WithArgs(engineID).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "displacement", "no_of_cylinders", "car_range"}).
-			AddRow(engineID, 2000, 4, 500))
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id, engine_type, displacement, no_of_cylinders, car_range, motor_power, battery_capacity, updated_at FROM engine WHERE id = $1 AND tenant_id = $2")).
+		WithArgs(engineID, tenant.DefaultID).
+		WillReturnRows(sqlmock.NewRows(engineColumns).
+			AddRow(engineID, models.EngineCombustion, 2000, 4, 500, 0, nil, time.Now()))
+	mock.ExpectCommit()
 
-	engine, err := store.EngineById(context.Background(), engineID)
+	engine, err := store.EngineById(tenantCtx, engineID)
 	assert.NoError(t, err)
 	assert.Equal(t, engineID, engine.EngineID.String())
-	assert.Equal(t, 2000, engine.Displacement)
+	assert.Equal(t, models.EngineCombustion, engine.Type)
+	assert.Equal(t, int64(2000), engine.Displacement)
+	assert.Nil(t, engine.BatteryCapacity)
+	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
 func TestCreateEngine(t *testing.T) {
@@ -46,18 +58,22 @@ func TestCreateEngine(t *testing.T) {
 		CarRange:      500,
 	}
 
-	engineID := uuid.New()
-
 	mock.ExpectBegin()
 	mock.ExpectExec("INSERT INTO engine").
-		WithArgs(engineID.String(), engineReq.Displacement, engineReq.NoOfCylinders, engineReq.CarRange).
+		WithArgs(sqlmock.AnyArg(), models.EngineCombustion, engineReq.Displacement, engineReq.NoOfCylinders, engineReq.CarRange,
+			engineReq.MotorPower, nil, sqlmock.AnyArg(), tenant.DefaultID).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO outbox_event").
+		WithArgs(sqlmock.AnyArg(), tenant.DefaultID, models.EventEngineCreated, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 	mock.ExpectCommit()
 
-	engine, err := store.CreateEngine(context.Background(), engineReq)
+	engine, err := store.CreateEngine(tenantCtx, engineReq)
 	assert.NoError(t, err)
-	assert.Equal(t, engineID.String(), engine.EngineID.String())
+	assert.NotEqual(t, uuid.Nil, engine.EngineID)
+	assert.Equal(t, models.EngineCombustion, engine.Type)
 	assert.Equal(t, engineReq.Displacement, engine.Displacement)
+	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
 func TestEngineUpdate(t *testing.T) {
@@ -69,7 +85,7 @@ func TestEngineUpdate(t *testing.T) {
 
 	store := New(db)
 
-	engineID := uuid.New().String()
+	engineID := uuid.New()
 	engineReq := &models.EngineRequest{
 		Displacement:  2500,
 		NoOfCylinders: 6,
@@ -78,14 +94,19 @@ func TestEngineUpdate(t *testing.T) {
 
 	mock.ExpectBegin()
 	mock.ExpectExec("UPDATE engine").
-		WithArgs(engineReq.Displacement, engineReq.NoOfCylinders, engineReq.CarRange, engineID).
+		WithArgs(models.EngineCombustion, engineReq.Displacement, engineReq.NoOfCylinders, engineReq.CarRange,
+			engineReq.MotorPower, nil, sqlmock.AnyArg(), engineID, tenant.DefaultID).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO outbox_event").
+		WithArgs(sqlmock.AnyArg(), tenant.DefaultID, models.EventEngineUpdated, engineID, sqlmock.AnyArg(), sqlmock.AnyArg()).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 	mock.ExpectCommit()
 
-	engine, err := store.EngineUpdate(context.Background(), engineID, engineReq)
+	engine, err := store.EngineUpdate(tenantCtx, engineID.String(), engineReq)
 	assert.NoError(t, err)
-	assert.Equal(t, engineID, engine.EngineID.String())
+	assert.Equal(t, engineID, engine.EngineID)
 	assert.Equal(t, engineReq.Displacement, engine.Displacement)
+	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
 func TestEngineDelete(t *testing.T) {
@@ -97,18 +118,22 @@ func TestEngineDelete(t *testing.T) {
 
 	store := New(db)
 
-	engineID := uuid.New().String()
+	engineID := uuid.New()
 	mock.ExpectBegin()
-	mock.ExpectQuery("SELECT id, displacement, no_of_cylinders, car_range").
-		WithArgs(engineID).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "displacement", "no_of_cylinders", "car_range"}).
-			AddRow(engineID, 2000, 4, 500))
-	mock.ExpectExec("DELETE FROM engine WHERE id = $1").
-		WithArgs(engineID).
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id, engine_type, displacement, no_of_cylinders, car_range, motor_power, battery_capacity, updated_at FROM engine WHERE id = $1 AND tenant_id = $2")).
+		WithArgs(engineID.String(), tenant.DefaultID).
+		WillReturnRows(sqlmock.NewRows(engineColumns).
+			AddRow(engineID.String(), models.EngineCombustion, 2000, 4, 500, 0, nil, time.Now()))
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM engine WHERE id = $1 AND tenant_id = $2")).
+		WithArgs(engineID.String(), tenant.DefaultID).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO outbox_event").
+		WithArgs(sqlmock.AnyArg(), tenant.DefaultID, models.EventEngineDeleted, engineID, sqlmock.AnyArg(), sqlmock.AnyArg()).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 	mock.ExpectCommit()
 
-	engine, err := store.EngineDelete(context.Background(), engineID)
+	engine, err := store.EngineDelete(tenantCtx, engineID.String())
 	assert.NoError(t, err)
-	assert.Equal(t, engineID, engine.EngineID.String())
+	assert.Equal(t, engineID, engine.EngineID)
+	assert.NoError(t, mock.ExpectationsWereMet())
 }
@@ -0,0 +1,29 @@
+package store
+
+import "errors"
+
+// Errors the car, vehicle, reservation, order, customer, catalogue, outbox and
+// webhook stores share so the services can tell them apart.
+var (
+	ErrVehicleExists   = errors.New("vehicle already exists")
+	ErrVehicleConflict = errors.New("vehicle was changed by another request")
+	ErrCarNotFound     = errors.New("car not found")
+	ErrCarHasVehicles  = errors.New("car has vehicles")
+	ErrCarOnOrder      = errors.New("car is on an order")
+
+	ErrCarReserved          = errors.New("car already has an active reservation")
+	ErrReservationNotActive = errors.New("reservation is not active")
+
+	ErrOrderConflict = errors.New("order was changed by another request")
+
+	ErrCustomerNotFound = errors.New("customer not found")
+
+	ErrBrandInUse = errors.New("brand is used by cars")
+
+	// ErrTOTPConfirmed is returned when enrolling a user whose second
+	// factor is already active.
+	ErrTOTPConfirmed = errors.New("totp is already confirmed")
+
+	ErrWebhookNotFound  = errors.New("webhook not found")
+	ErrDeliveryNotFound = errors.New("webhook delivery not found")
+)
@@ -2,13 +2,18 @@ package store
 
 import (
 	"context"
+	"time"
 
 	"github.com/adohong4/carZone/models"
 )
 
+// CarStoreInterface keeps the cars of the dealership of the context.
+// DeleteCar returns ErrCarHasVehicles while vehicles of the car are
+// stocked and ErrCarOnOrder while an order sells it.
 type CarStoreInterface interface {
 	GetCarById(ctx context.Context, id string) (models.Car, error)
 	GetCarByBrand(ctx context.Context, brand string, isEngine bool) ([]models.Car, error)
+	GetCarsByIds(ctx context.Context, ids []string) ([]models.Car, error)
 	CreateCar(ctx context.Context, carReq *models.CarRequest) (models.Car, error)
 	UpdateCar(ctx context.Context, id string, carReq *models.CarRequest) (models.Car, error)
 	DeleteCar(ctx context.Context, id string) (models.Car, error)
@@ -20,3 +25,216 @@ type EngineStoreInterface interface {
 	EngineUpdate(ctx context.Context, id string, engineReq *models.EngineRequest) (models.Engine, error)
 	EngineDelete(ctx context.Context, id string) (models.Engine, error)
 }
+
+type LoginStoreInterface interface {
+	RecordAttempt(ctx context.Context, attempt models.LoginAttempt) error
+	GetLockout(ctx context.Context, subject string) (models.LoginLockout, error)
+	IncrementFailure(ctx context.Context, subject string) (models.LoginLockout, error)
+	LockUntil(ctx context.Context, subject string, until time.Time) (models.LoginLockout, error)
+	ResetLockout(ctx context.Context, subject string) error
+}
+
+// TOTPStoreInterface keeps the second factor of the users. SaveTOTP starts
+// an enrollment and returns ErrTOTPConfirmed while the user has a confirmed
+// one, which only DeleteTOTP ends.
+type TOTPStoreInterface interface {
+	GetTOTP(ctx context.Context, username string) (models.TOTPCredential, error)
+	SaveTOTP(ctx context.Context, credential models.TOTPCredential) error
+	ConfirmTOTP(ctx context.Context, username string, step int64, recoveryCodeHashes []string) error
+	UseTOTPStep(ctx context.Context, username string, step int64) (bool, error)
+	ConsumeRecoveryCode(ctx context.Context, username, codeHash string) (bool, error)
+	DeleteTOTP(ctx context.Context, username string) error
+}
+
+// DealershipStoreInterface manages the dealerships themselves, it is not
+// scoped to a tenant.
+type DealershipStoreInterface interface {
+	GetDealerships(ctx context.Context) ([]models.Dealership, error)
+	GetDealershipById(ctx context.Context, id string) (models.Dealership, error)
+	CreateDealership(ctx context.Context, dealershipReq *models.DealershipRequest) (models.Dealership, error)
+}
+
+// VehicleStoreInterface keeps the physical units of the car models.
+// UpdateVehicle only applies while the unit still has status fromStatus and
+// returns ErrVehicleConflict otherwise.
+type VehicleStoreInterface interface {
+	GetVehicle(ctx context.Context, vin string) (models.Vehicle, error)
+	ListVehicles(ctx context.Context, filter models.VehicleFilter) ([]models.Vehicle, error)
+	ReceiveVehicle(ctx context.Context, vehicleReq *models.VehicleRequest) (models.Vehicle, error)
+	UpdateVehicle(ctx context.Context, vehicle models.Vehicle, fromStatus string) (models.Vehicle, error)
+	StockCounts(ctx context.Context, carID string) ([]models.StockCount, error)
+}
+
+// ReservationStoreInterface keeps the holds on cars. CreateReservation
+// returns ErrCarReserved while the car has an active reservation expiring
+// after the new one's CreatedAt, ErrCarNotFound for unknown cars and
+// ErrCustomerNotFound when it is linked to an unknown or erased customer.
+// ListCustomerReservations lists the reservations linked to a customer.
+// ExpireReservations works across every dealership, it is run by the
+// sweeper rather than on behalf of a user.
+type ReservationStoreInterface interface {
+	CreateReservation(ctx context.Context, reservation models.Reservation) (models.Reservation, error)
+	GetActiveReservation(ctx context.Context, carID string, now time.Time) (models.Reservation, error)
+	ListReservations(ctx context.Context, carID string) ([]models.Reservation, error)
+	ReleaseReservation(ctx context.Context, carID, id string, now time.Time) (models.Reservation, error)
+	ExpireReservations(ctx context.Context, now time.Time) (int64, error)
+	ListCustomerReservations(ctx context.Context, customerID string) ([]models.Reservation, error)
+}
+
+// OrderStoreInterface keeps sales orders and their lines. CreateOrder
+// returns ErrCarNotFound when a line sells an unknown car, CreateOrder and
+// UpdateOrder ErrCustomerNotFound when the order is linked to an unknown or
+// erased customer. UpdateOrder only
+// applies to drafts and UpdateOrderStatus only while the order still has
+// status fromStatus, both return ErrOrderConflict otherwise. Confirming an
+// order gives it the dealership's next invoice number. ListOrders leaves
+// the lines out, ListCustomerOrders lists the orders linked to a customer
+// with their lines.
+type OrderStoreInterface interface {
+	CreateOrder(ctx context.Context, order models.Order) (models.Order, error)
+	GetOrder(ctx context.Context, id string) (models.Order, error)
+	ListOrders(ctx context.Context, status string) ([]models.Order, error)
+	UpdateOrder(ctx context.Context, order models.Order) (models.Order, error)
+	UpdateOrderStatus(ctx context.Context, id, fromStatus, toStatus string, now time.Time) (models.Order, error)
+	ListCustomerOrders(ctx context.Context, customerID string) ([]models.Order, error)
+}
+
+// CustomerStoreInterface keeps customers with their notes and car links.
+// GetCustomerById includes the notes and links, ListCustomers leaves them
+// and erased customers out and matches search against name and email
+// ignoring case.
+// AddCustomerNote and LinkCustomerCar return ErrCustomerNotFound for
+// unknown customers, LinkCustomerCar also ErrCarNotFound for unknown cars
+// and the existing link when the customer is already linked that way.
+// EraseCustomer clears the personal data and notes but keeps the row, and
+// blanks the customer details of the linked reservations and orders.
+type CustomerStoreInterface interface {
+	GetCustomerById(ctx context.Context, id string) (models.Customer, error)
+	ListCustomers(ctx context.Context, search string) ([]models.Customer, error)
+	CreateCustomer(ctx context.Context, customer models.Customer) (models.Customer, error)
+	UpdateCustomer(ctx context.Context, customer models.Customer) (models.Customer, error)
+	DeleteCustomer(ctx context.Context, id string) (models.Customer, error)
+	AddCustomerNote(ctx context.Context, customerID string, note models.CustomerNote) (models.CustomerNote, error)
+	LinkCustomerCar(ctx context.Context, customerID string, link models.CustomerCar) (models.CustomerCar, error)
+	EraseCustomer(ctx context.Context, id string, now time.Time) (models.Customer, error)
+}
+
+// RateStoreInterface keeps the exchange rates against the base currency,
+// it is not scoped to a tenant. SetRate inserts or replaces the rate.
+type RateStoreInterface interface {
+	GetRates(ctx context.Context) ([]models.ExchangeRate, error)
+	SetRate(ctx context.Context, rate models.ExchangeRate) (models.ExchangeRate, error)
+}
+
+// PriceStoreInterface keeps the price history of cars and the promotions.
+// The car stores add a price starting at once whenever a car is created or
+// its price is changed. AddCarPrice returns ErrCarNotFound for unknown cars
+// and DeleteCarPrice only removes prices that start after now.
+// ApplyCarPrices sets every car, in every dealership, to the price in
+// effect at now and returns the cars it changed; it is run by the
+// scheduler rather than on behalf of a user.
+type PriceStoreInterface interface {
+	ListCarPrices(ctx context.Context, carID string) ([]models.CarPrice, error)
+	AddCarPrice(ctx context.Context, price models.CarPrice) (models.CarPrice, error)
+	DeleteCarPrice(ctx context.Context, carID, id string, now time.Time) (models.CarPrice, error)
+	ApplyCarPrices(ctx context.Context, now time.Time) ([]models.RepricedCar, error)
+	ListPromotions(ctx context.Context) ([]models.Promotion, error)
+	CreatePromotion(ctx context.Context, promotion models.Promotion) (models.Promotion, error)
+	DeletePromotion(ctx context.Context, id string) (models.Promotion, error)
+}
+
+// CarImageStoreInterface keeps the photos of cars, the files themselves are
+// kept in a blob.Store. ListCarImages returns the images of the given cars
+// by car and position. AddCarImage returns ErrCarNotFound for unknown cars
+// and puts the image last, the first image of a car becomes its primary
+// one. SetCarImageOrder moves each listed image to its index in ids.
+// SetPrimaryCarImage and DeleteCarImage return an empty image when it is
+// missing; deleting the primary image hands the flag to the first image
+// left.
+type CarImageStoreInterface interface {
+	ListCarImages(ctx context.Context, carIDs []string) ([]models.CarImage, error)
+	AddCarImage(ctx context.Context, image models.CarImage) (models.CarImage, error)
+	SetCarImageOrder(ctx context.Context, carID string, ids []string) error
+	SetPrimaryCarImage(ctx context.Context, carID, id string) (models.CarImage, error)
+	DeleteCarImage(ctx context.Context, carID, id string) (models.CarImage, error)
+}
+
+// CatalogueStoreInterface keeps the brands and models cars are named after,
+// it is not scoped to a tenant. Brands come with their aliases and models.
+// GetBrand and FindBrand return an empty brand when it is missing, FindBrand
+// matches the models.CatalogueKey of a brand name or alias. SaveBrand
+// inserts or replaces a brand and its aliases, renaming the cars of every
+// dealership along with it. DeleteBrand returns ErrBrandInUse while any car
+// carries the brand. SaveCarModel inserts or replaces a model and its
+// aliases, DeleteCarModel returns an empty model when it is missing.
+type CatalogueStoreInterface interface {
+	ListBrands(ctx context.Context) ([]models.Brand, error)
+	GetBrand(ctx context.Context, id string) (models.Brand, error)
+	FindBrand(ctx context.Context, name string) (models.Brand, error)
+	SaveBrand(ctx context.Context, brand models.Brand) (models.Brand, error)
+	DeleteBrand(ctx context.Context, id string) (models.Brand, error)
+	SaveCarModel(ctx context.Context, model models.CarModel) (models.CarModel, error)
+	DeleteCarModel(ctx context.Context, brandID, id string) (models.CarModel, error)
+}
+
+// ValidationRuleStoreInterface keeps the validation rules of the dealership
+// of the context, in the order they were set. SetValidationRules replaces
+// them all.
+type ValidationRuleStoreInterface interface {
+	GetValidationRules(ctx context.Context) ([]models.ValidationRule, error)
+	SetValidationRules(ctx context.Context, rules []models.ValidationRule) ([]models.ValidationRule, error)
+}
+
+// ReportStoreInterface aggregates the inventory of the dealership of the
+// context. InventoryReport returns one row per group of the query and
+// currency, ordered by the group columns, without the average prices which
+// the service works out from the totals.
+type ReportStoreInterface interface {
+	InventoryReport(ctx context.Context, query models.InventoryReportQuery) ([]models.InventoryReportRow, error)
+}
+
+// OutboxStoreInterface is the outbox the car and engine stores record their
+// events in, in the transaction of the change, for every dealership.
+// ClaimEvents returns the oldest unpublished events due at now, up to limit
+// and in sequence order, and makes them due again only at until so no other
+// relay takes them meanwhile. An event is not due while an earlier event of
+// its entity is pending and not due, so an entity's events are claimed in
+// order. FinishEvents records the results of the claimed events and returns
+// how many were published. DeletePublishedEvents drops the events published
+// before the given time.
+type OutboxStoreInterface interface {
+	ClaimEvents(ctx context.Context, now, until time.Time, limit int) ([]models.PendingEvent, error)
+	FinishEvents(ctx context.Context, results []models.EventResult, now time.Time) (int, error)
+	DeletePublishedEvents(ctx context.Context, before time.Time) (int64, error)
+}
+
+// WebhookStoreInterface keeps the webhook subscriptions of the dealership
+// of the context and the log of their deliveries, newest first.
+// GetWebhook, DeleteWebhook and the delivery lookups return
+// ErrWebhookNotFound or ErrDeliveryNotFound, deleting a webhook drops its
+// deliveries. RedeliverDelivery makes a delivery pending again, due at
+// once, with its attempts count back to zero but its log kept.
+//
+// The other methods work for every dealership. EnqueueDeliveries queues
+// event for each webhook of its dealership that takes it, once per webhook
+// however often it is called, and returns how many it queued.
+// ClaimDeliveries returns up to limit pending deliveries due at now and
+// moves their next attempt to until, so that nobody else sends them in the
+// meantime. RecordAttempt saves the outcome of an attempt on the delivery
+// and appends attempt to its log, it returns ErrDeliveryNotFound when the
+// webhook was deleted. DeleteFinishedDeliveries drops the succeeded and
+// failed deliveries created before the given time.
+type WebhookStoreInterface interface {
+	CreateWebhook(ctx context.Context, webhook models.Webhook) (models.Webhook, error)
+	ListWebhooks(ctx context.Context) ([]models.Webhook, error)
+	GetWebhook(ctx context.Context, id string) (models.Webhook, error)
+	DeleteWebhook(ctx context.Context, id string) (models.Webhook, error)
+	ListDeliveries(ctx context.Context, webhookID string, limit int) ([]models.WebhookDelivery, error)
+	GetDelivery(ctx context.Context, webhookID, deliveryID string) (models.WebhookDelivery, error)
+	RedeliverDelivery(ctx context.Context, webhookID, deliveryID string, now time.Time) (models.WebhookDelivery, error)
+
+	EnqueueDeliveries(ctx context.Context, event models.Event, now time.Time) (int, error)
+	ClaimDeliveries(ctx context.Context, now, until time.Time, limit int) ([]models.PendingDelivery, error)
+	RecordAttempt(ctx context.Context, delivery models.WebhookDelivery, attempt models.WebhookAttempt) error
+	DeleteFinishedDeliveries(ctx context.Context, before time.Time) (int64, error)
+}
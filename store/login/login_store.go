@@ -0,0 +1,99 @@
+package login
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/adohong4/carZone/models"
+	"go.opentelemetry.io/otel"
+)
+
+type Store struct {
+	db *sql.DB
+}
+
+func New(db *sql.DB) Store {
+	return Store{db: db}
+}
+
+func (s Store) RecordAttempt(ctx context.Context, attempt models.LoginAttempt) error {
+	tracer := otel.Tracer("LoginStore")
+	ctx, span := tracer.Start(ctx, "RecordAttempt-Store")
+	defer span.End()
+
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO login_attempt (username, ip, success, reason, created_at) VALUES ($1, $2, $3, $4, $5)`,
+		attempt.UserName, attempt.IP, attempt.Success, attempt.Reason, attempt.CreatedAt)
+	return err
+}
+
+func (s Store) GetLockout(ctx context.Context, subject string) (models.LoginLockout, error) {
+	tracer := otel.Tracer("LoginStore")
+	ctx, span := tracer.Start(ctx, "GetLockout-Store")
+	defer span.End()
+
+	lockout := models.LoginLockout{Subject: subject}
+	var lockedUntil sql.NullTime
+	err := s.db.QueryRowContext(ctx,
+		"SELECT failed_attempts, lockouts, locked_until FROM login_lockout WHERE subject = $1", subject,
+	).Scan(&lockout.FailedAttempts, &lockout.Lockouts, &lockedUntil)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return lockout, nil
+		}
+		return lockout, err
+	}
+	lockout.LockedUntil = lockedUntil.Time
+	return lockout, nil
+}
+
+func (s Store) IncrementFailure(ctx context.Context, subject string) (models.LoginLockout, error) {
+	tracer := otel.Tracer("LoginStore")
+	ctx, span := tracer.Start(ctx, "IncrementFailure-Store")
+	defer span.End()
+
+	lockout := models.LoginLockout{Subject: subject}
+	var lockedUntil sql.NullTime
+	err := s.db.QueryRowContext(ctx,
+		`INSERT INTO login_lockout (subject, failed_attempts, lockouts, updated_at) VALUES ($1, 1, 0, now())
+		ON CONFLICT (subject) DO UPDATE
+		SET failed_attempts = login_lockout.failed_attempts + 1, updated_at = now()
+		RETURNING failed_attempts, lockouts, locked_until`, subject,
+	).Scan(&lockout.FailedAttempts, &lockout.Lockouts, &lockedUntil)
+	if err != nil {
+		return lockout, err
+	}
+	lockout.LockedUntil = lockedUntil.Time
+	return lockout, nil
+}
+
+func (s Store) LockUntil(ctx context.Context, subject string, until time.Time) (models.LoginLockout, error) {
+	tracer := otel.Tracer("LoginStore")
+	ctx, span := tracer.Start(ctx, "LockUntil-Store")
+	defer span.End()
+
+	lockout := models.LoginLockout{Subject: subject}
+	var lockedUntil sql.NullTime
+	err := s.db.QueryRowContext(ctx,
+		`UPDATE login_lockout
+		SET failed_attempts = 0, lockouts = lockouts + 1, locked_until = $2, updated_at = now()
+		WHERE subject = $1
+		RETURNING failed_attempts, lockouts, locked_until`, subject, until,
+	).Scan(&lockout.FailedAttempts, &lockout.Lockouts, &lockedUntil)
+	if err != nil {
+		return lockout, err
+	}
+	lockout.LockedUntil = lockedUntil.Time
+	return lockout, nil
+}
+
+func (s Store) ResetLockout(ctx context.Context, subject string) error {
+	tracer := otel.Tracer("LoginStore")
+	ctx, span := tracer.Start(ctx, "ResetLockout-Store")
+	defer span.End()
+
+	_, err := s.db.ExecContext(ctx, "DELETE FROM login_lockout WHERE subject = $1", subject)
+	return err
+}
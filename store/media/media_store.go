@@ -0,0 +1,214 @@
+package media
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/adohong4/carZone/models"
+	"github.com/adohong4/carZone/store"
+	"github.com/adohong4/carZone/tenant"
+	"github.com/lib/pq"
+	"go.opentelemetry.io/otel"
+)
+
+const carImageColumns = "id, car_id, storage_key, thumbnail_key, content_type, size_bytes, width, height, position, is_primary, created_at"
+
+type Store struct {
+	db *sql.DB
+}
+
+func New(db *sql.DB) Store {
+	return Store{db: db}
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanCarImage(row scanner) (models.CarImage, error) {
+	var image models.CarImage
+	err := row.Scan(
+		&image.ID, &image.CarID, &image.Key, &image.ThumbnailKey, &image.ContentType,
+		&image.Size, &image.Width, &image.Height, &image.Position, &image.Primary, &image.CreatedAt,
+	)
+	return image, err
+}
+
+func (s Store) ListCarImages(ctx context.Context, carIDs []string) ([]models.CarImage, error) {
+	tracer := otel.Tracer("MediaStore")
+	ctx, span := tracer.Start(ctx, "ListCarImages-Store")
+	defer span.End()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT "+carImageColumns+" FROM car_image WHERE car_id::text = ANY($1) AND tenant_id = $2 ORDER BY car_id, position",
+		pq.Array(carIDs), tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var images []models.CarImage
+	for rows.Next() {
+		image, err := scanCarImage(rows)
+		if err != nil {
+			return nil, err
+		}
+		images = append(images, image)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return images, nil
+}
+
+// AddCarImage locks the car so two uploads do not take the same position
+// or both become primary.
+func (s Store) AddCarImage(ctx context.Context, image models.CarImage) (models.CarImage, error) {
+	tracer := otel.Tracer("MediaStore")
+	ctx, span := tracer.Start(ctx, "AddCarImage-Store")
+	defer span.End()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return models.CarImage{}, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.CarImage{}, err
+	}
+	defer tx.Rollback()
+
+	var carID string
+	err = tx.QueryRowContext(ctx, "SELECT id FROM car WHERE id = $1 AND tenant_id = $2 FOR UPDATE", image.CarID, tenantID).Scan(&carID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.CarImage{}, store.ErrCarNotFound
+		}
+		return models.CarImage{}, err
+	}
+
+	var count, next int
+	err = tx.QueryRowContext(ctx,
+		"SELECT COUNT(*), COALESCE(MAX(position) + 1, 0) FROM car_image WHERE car_id = $1", carID).Scan(&count, &next)
+	if err != nil {
+		return models.CarImage{}, err
+	}
+
+	query := `INSERT INTO car_image (id, tenant_id, car_id, storage_key, thumbnail_key, content_type, size_bytes, width, height, position, is_primary, created_at)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+				RETURNING ` + carImageColumns
+	added, err := scanCarImage(tx.QueryRowContext(ctx, query,
+		image.ID, tenantID, carID, image.Key, image.ThumbnailKey, image.ContentType,
+		image.Size, image.Width, image.Height, next, count == 0, image.CreatedAt,
+	))
+	if err != nil {
+		return models.CarImage{}, err
+	}
+	if err = tx.Commit(); err != nil {
+		return models.CarImage{}, err
+	}
+	return added, nil
+}
+
+func (s Store) SetCarImageOrder(ctx context.Context, carID string, ids []string) error {
+	tracer := otel.Tracer("MediaStore")
+	ctx, span := tracer.Start(ctx, "SetCarImageOrder-Store")
+	defer span.End()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	// array_position gives each image its index in ids, counted from 1
+	_, err = s.db.ExecContext(ctx,
+		`UPDATE car_image SET position = array_position($1::text[], id::text) - 1
+		WHERE car_id = $2 AND tenant_id = $3 AND id::text = ANY($1)`,
+		pq.Array(ids), carID, tenantID)
+	return err
+}
+
+func (s Store) SetPrimaryCarImage(ctx context.Context, carID, id string) (models.CarImage, error) {
+	tracer := otel.Tracer("MediaStore")
+	ctx, span := tracer.Start(ctx, "SetPrimaryCarImage-Store")
+	defer span.End()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return models.CarImage{}, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.CarImage{}, err
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	err = tx.QueryRowContext(ctx,
+		"SELECT EXISTS (SELECT 1 FROM car_image WHERE id = $1 AND car_id = $2 AND tenant_id = $3)", id, carID, tenantID).Scan(&exists)
+	if err != nil || !exists {
+		return models.CarImage{}, err
+	}
+
+	// clear the old flag first, car_image_primary_idx allows one per car
+	if _, err = tx.ExecContext(ctx, "UPDATE car_image SET is_primary = FALSE WHERE car_id = $1 AND is_primary", carID); err != nil {
+		return models.CarImage{}, err
+	}
+	primary, err := scanCarImage(tx.QueryRowContext(ctx,
+		"UPDATE car_image SET is_primary = TRUE WHERE id = $1 RETURNING "+carImageColumns, id))
+	if err != nil {
+		return models.CarImage{}, err
+	}
+	if err = tx.Commit(); err != nil {
+		return models.CarImage{}, err
+	}
+	return primary, nil
+}
+
+func (s Store) DeleteCarImage(ctx context.Context, carID, id string) (models.CarImage, error) {
+	tracer := otel.Tracer("MediaStore")
+	ctx, span := tracer.Start(ctx, "DeleteCarImage-Store")
+	defer span.End()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return models.CarImage{}, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.CarImage{}, err
+	}
+	defer tx.Rollback()
+
+	deleted, err := scanCarImage(tx.QueryRowContext(ctx,
+		"DELETE FROM car_image WHERE id = $1 AND car_id = $2 AND tenant_id = $3 RETURNING "+carImageColumns,
+		id, carID, tenantID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.CarImage{}, nil
+		}
+		return models.CarImage{}, err
+	}
+
+	if deleted.Primary {
+		_, err = tx.ExecContext(ctx,
+			`UPDATE car_image SET is_primary = TRUE
+			WHERE id = (SELECT id FROM car_image WHERE car_id = $1 ORDER BY position LIMIT 1)`, carID)
+		if err != nil {
+			return models.CarImage{}, err
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		return models.CarImage{}, err
+	}
+	return deleted, nil
+}
@@ -0,0 +1,226 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/adohong4/carZone/models"
+	"github.com/adohong4/carZone/store"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+)
+
+// seedBrands are the brands, with their aliases, the migrations start the
+// catalogue with.
+var seedBrands = map[string][]string{
+	"Audi": nil, "BMW": nil, "Chevrolet": {"Chevy"}, "Ford": nil, "Honda": nil, "Hyundai": nil,
+	"Kia": nil, "Lexus": nil, "Mazda": nil, "Mercedes-Benz": {"Mercedes", "Mercedes Benz"},
+	"Mitsubishi": nil, "Nissan": nil, "Porsche": nil, "Subaru": nil, "Tesla": nil, "Toyota": nil,
+	"VinFast": nil, "Volkswagen": {"VW"},
+}
+
+func newSeedBrands() map[uuid.UUID]models.Brand {
+	brands := make(map[uuid.UUID]models.Brand, len(seedBrands))
+	for name, aliases := range seedBrands {
+		brand := models.Brand{
+			ID:        uuid.New(),
+			Name:      name,
+			Aliases:   append([]string{}, aliases...),
+			Models:    []models.CarModel{},
+			CreatedAt: time.Now(),
+		}
+		brands[brand.ID] = brand
+	}
+	return brands
+}
+
+// copyBrand keeps a stored brand apart from what callers do with it, with
+// its models and aliases in the order the SQL stores return them.
+func copyBrand(brand models.Brand) models.Brand {
+	brand.Aliases = sortedAliases(brand.Aliases)
+	catalogueModels := make([]models.CarModel, len(brand.Models))
+	for i, model := range brand.Models {
+		model.Aliases = sortedAliases(model.Aliases)
+		catalogueModels[i] = model
+	}
+	sort.Slice(catalogueModels, func(i, j int) bool {
+		return models.CatalogueKey(catalogueModels[i].Name) < models.CatalogueKey(catalogueModels[j].Name)
+	})
+	brand.Models = catalogueModels
+	return brand
+}
+
+func sortedAliases(aliases []string) []string {
+	sorted := append([]string{}, aliases...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return models.CatalogueKey(sorted[i]) < models.CatalogueKey(sorted[j])
+	})
+	return sorted
+}
+
+func (s *Store) ListBrands(ctx context.Context) ([]models.Brand, error) {
+	tracer := otel.Tracer("MemoryStore")
+	_, span := tracer.Start(ctx, "ListBrands-MemoryStore")
+	defer span.End()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	brands := make([]models.Brand, 0, len(s.brands))
+	for _, brand := range s.brands {
+		brands = append(brands, copyBrand(brand))
+	}
+	sort.Slice(brands, func(i, j int) bool {
+		return models.CatalogueKey(brands[i].Name) < models.CatalogueKey(brands[j].Name)
+	})
+	return brands, nil
+}
+
+func (s *Store) GetBrand(ctx context.Context, id string) (models.Brand, error) {
+	tracer := otel.Tracer("MemoryStore")
+	_, span := tracer.Start(ctx, "GetBrand-MemoryStore")
+	defer span.End()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	brandID, err := uuid.Parse(id)
+	if err != nil {
+		return models.Brand{}, nil
+	}
+	brand, ok := s.brands[brandID]
+	if !ok {
+		return models.Brand{}, nil
+	}
+	return copyBrand(brand), nil
+}
+
+func (s *Store) FindBrand(ctx context.Context, name string) (models.Brand, error) {
+	tracer := otel.Tracer("MemoryStore")
+	_, span := tracer.Start(ctx, "FindBrand-MemoryStore")
+	defer span.End()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	key := models.CatalogueKey(name)
+	for _, brand := range s.brands {
+		if models.CatalogueKey(brand.Name) == key {
+			return copyBrand(brand), nil
+		}
+		for _, alias := range brand.Aliases {
+			if models.CatalogueKey(alias) == key {
+				return copyBrand(brand), nil
+			}
+		}
+	}
+	return models.Brand{}, nil
+}
+
+func (s *Store) SaveBrand(ctx context.Context, brand models.Brand) (models.Brand, error) {
+	tracer := otel.Tracer("MemoryStore")
+	_, span := tracer.Start(ctx, "SaveBrand-MemoryStore")
+	defer span.End()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	saved := brand
+	saved.Aliases = append([]string{}, brand.Aliases...)
+	saved.Models = []models.CarModel{}
+	if current, ok := s.brands[brand.ID]; ok {
+		saved.Models = current.Models
+		saved.CreatedAt = current.CreatedAt
+		if current.Name != brand.Name {
+			for id, row := range s.cars {
+				if row.car.Brand == current.Name {
+					row.car.Brand = brand.Name
+					s.cars[id] = row
+				}
+			}
+		}
+	}
+	s.brands[brand.ID] = saved
+	return brand, nil
+}
+
+func (s *Store) DeleteBrand(ctx context.Context, id string) (models.Brand, error) {
+	tracer := otel.Tracer("MemoryStore")
+	_, span := tracer.Start(ctx, "DeleteBrand-MemoryStore")
+	defer span.End()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	brandID, err := uuid.Parse(id)
+	if err != nil {
+		return models.Brand{}, nil
+	}
+	brand, ok := s.brands[brandID]
+	if !ok {
+		return models.Brand{}, nil
+	}
+	// cars of every dealership count, the catalogue is shared
+	for _, row := range s.cars {
+		if row.car.Brand == brand.Name {
+			return models.Brand{}, store.ErrBrandInUse
+		}
+	}
+	delete(s.brands, brandID)
+	return copyBrand(brand), nil
+}
+
+func (s *Store) SaveCarModel(ctx context.Context, model models.CarModel) (models.CarModel, error) {
+	tracer := otel.Tracer("MemoryStore")
+	_, span := tracer.Start(ctx, "SaveCarModel-MemoryStore")
+	defer span.End()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	brand, ok := s.brands[model.BrandID]
+	if !ok {
+		return models.CarModel{}, errors.New("brand_id does not exists in the brand table")
+	}
+	saved := model
+	saved.Aliases = append([]string{}, model.Aliases...)
+	catalogueModels := make([]models.CarModel, 0, len(brand.Models)+1)
+	for _, current := range brand.Models {
+		if current.ID == model.ID {
+			saved.CreatedAt = current.CreatedAt
+			continue
+		}
+		catalogueModels = append(catalogueModels, current)
+	}
+	brand.Models = append(catalogueModels, saved)
+	s.brands[brand.ID] = brand
+	return model, nil
+}
+
+func (s *Store) DeleteCarModel(ctx context.Context, brandID, id string) (models.CarModel, error) {
+	tracer := otel.Tracer("MemoryStore")
+	_, span := tracer.Start(ctx, "DeleteCarModel-MemoryStore")
+	defer span.End()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	parsedBrandID, err := uuid.Parse(brandID)
+	if err != nil {
+		return models.CarModel{}, nil
+	}
+	brand, ok := s.brands[parsedBrandID]
+	if !ok {
+		return models.CarModel{}, nil
+	}
+	for i, model := range brand.Models {
+		if model.ID.String() == id {
+			brand.Models = append(append([]models.CarModel{}, brand.Models[:i]...), brand.Models[i+1:]...)
+			s.brands[brand.ID] = brand
+			return model, nil
+		}
+	}
+	return models.CarModel{}, nil
+}
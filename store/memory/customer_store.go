@@ -0,0 +1,312 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/adohong4/carZone/models"
+	"github.com/adohong4/carZone/store"
+	"github.com/adohong4/carZone/tenant"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+)
+
+// customerRow remembers the dealership a customer belongs to. The notes
+// and car links are kept on the customer itself.
+type customerRow struct {
+	customer models.Customer
+	tenantID string
+}
+
+// copyCustomer keeps callers from changing stored notes and links through
+// the slices, and orders them like the SQL stores do.
+func copyCustomer(customer models.Customer) models.Customer {
+	customer.Notes = append([]models.CustomerNote(nil), customer.Notes...)
+	customer.Cars = append([]models.CustomerCar(nil), customer.Cars...)
+	sort.SliceStable(customer.Notes, func(i, j int) bool {
+		return customer.Notes[i].CreatedAt.Before(customer.Notes[j].CreatedAt)
+	})
+	sort.SliceStable(customer.Cars, func(i, j int) bool {
+		return customer.Cars[i].CreatedAt.Before(customer.Cars[j].CreatedAt)
+	})
+	return customer
+}
+
+func (s *Store) customer(id string, tenantID string) (customerRow, bool) {
+	customerID, err := uuid.Parse(id)
+	if err != nil {
+		return customerRow{}, false
+	}
+	row, ok := s.customers[customerID]
+	if !ok || row.tenantID != tenantID {
+		return customerRow{}, false
+	}
+	return row, true
+}
+
+// checkCustomer returns ErrCustomerNotFound unless customerID is empty or a
+// customer of the dealership that has not been erased.
+func (s *Store) checkCustomer(customerID *uuid.UUID, tenantID string) error {
+	if customerID == nil {
+		return nil
+	}
+	row, ok := s.customers[*customerID]
+	if !ok || row.tenantID != tenantID || row.customer.ErasedAt != nil {
+		return store.ErrCustomerNotFound
+	}
+	return nil
+}
+
+func (s *Store) GetCustomerById(ctx context.Context, id string) (models.Customer, error) {
+	tracer := otel.Tracer("MemoryStore")
+	_, span := tracer.Start(ctx, "GetCustomerById-MemoryStore")
+	defer span.End()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return models.Customer{}, err
+	}
+
+	row, ok := s.customer(id, tenantID)
+	if !ok {
+		return models.Customer{}, nil
+	}
+	return copyCustomer(row.customer), nil
+}
+
+func (s *Store) ListCustomers(ctx context.Context, search string) ([]models.Customer, error) {
+	tracer := otel.Tracer("MemoryStore")
+	_, span := tracer.Start(ctx, "ListCustomers-MemoryStore")
+	defer span.End()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	search = strings.ToLower(search)
+	var customers []models.Customer
+	for _, row := range s.customers {
+		customer := row.customer
+		if row.tenantID != tenantID || customer.ErasedAt != nil {
+			continue
+		}
+		if !strings.Contains(strings.ToLower(customer.Name), search) &&
+			!strings.Contains(strings.ToLower(customer.Email), search) {
+			continue
+		}
+		customer.Notes, customer.Cars = nil, nil
+		customers = append(customers, customer)
+	}
+	sort.Slice(customers, func(i, j int) bool {
+		a, b := strings.ToLower(customers[i].Name), strings.ToLower(customers[j].Name)
+		if a != b {
+			return a < b
+		}
+		return customers[i].ID.String() < customers[j].ID.String()
+	})
+	return customers, nil
+}
+
+func (s *Store) CreateCustomer(ctx context.Context, customer models.Customer) (models.Customer, error) {
+	tracer := otel.Tracer("MemoryStore")
+	_, span := tracer.Start(ctx, "CreateCustomer-MemoryStore")
+	defer span.End()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return models.Customer{}, err
+	}
+
+	customer.Notes, customer.Cars, customer.ErasedAt = nil, nil, nil
+	s.customers[customer.ID] = customerRow{customer: customer, tenantID: tenantID}
+	return customer, nil
+}
+
+func (s *Store) UpdateCustomer(ctx context.Context, customer models.Customer) (models.Customer, error) {
+	tracer := otel.Tracer("MemoryStore")
+	_, span := tracer.Start(ctx, "UpdateCustomer-MemoryStore")
+	defer span.End()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return models.Customer{}, err
+	}
+
+	row, ok := s.customer(customer.ID.String(), tenantID)
+	if !ok {
+		return models.Customer{}, nil
+	}
+	stored := &row.customer
+	stored.Name, stored.Email, stored.Phone, stored.Address = customer.Name, customer.Email, customer.Phone, customer.Address
+	stored.MarketingConsent, stored.ContactConsent = customer.MarketingConsent, customer.ContactConsent
+	stored.ConsentUpdatedAt, stored.UpdatedAt = customer.ConsentUpdatedAt, customer.UpdatedAt
+	s.customers[customer.ID] = row
+	return copyCustomer(row.customer), nil
+}
+
+func (s *Store) DeleteCustomer(ctx context.Context, id string) (models.Customer, error) {
+	tracer := otel.Tracer("MemoryStore")
+	_, span := tracer.Start(ctx, "DeleteCustomer-MemoryStore")
+	defer span.End()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return models.Customer{}, err
+	}
+
+	row, ok := s.customer(id, tenantID)
+	if !ok {
+		return models.Customer{}, nil
+	}
+	delete(s.customers, row.customer.ID)
+	// reservation.customer_id and sales_order.customer_id are ON DELETE SET NULL
+	for id, reservation := range s.reservations {
+		if linkedTo(reservation.reservation.CustomerID, row.customer.ID) {
+			reservation.reservation.CustomerID = nil
+			s.reservations[id] = reservation
+		}
+	}
+	for id, order := range s.orders {
+		if linkedTo(order.order.CustomerID, row.customer.ID) {
+			order.order.CustomerID = nil
+			s.orders[id] = order
+		}
+	}
+
+	deleted := row.customer
+	deleted.Notes, deleted.Cars = nil, nil
+	return deleted, nil
+}
+
+func (s *Store) AddCustomerNote(ctx context.Context, customerID string, note models.CustomerNote) (models.CustomerNote, error) {
+	tracer := otel.Tracer("MemoryStore")
+	_, span := tracer.Start(ctx, "AddCustomerNote-MemoryStore")
+	defer span.End()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return models.CustomerNote{}, err
+	}
+
+	row, ok := s.customer(customerID, tenantID)
+	if !ok {
+		return models.CustomerNote{}, store.ErrCustomerNotFound
+	}
+	row.customer.Notes = append(row.customer.Notes, note)
+	s.customers[row.customer.ID] = row
+	return note, nil
+}
+
+func (s *Store) LinkCustomerCar(ctx context.Context, customerID string, link models.CustomerCar) (models.CustomerCar, error) {
+	tracer := otel.Tracer("MemoryStore")
+	_, span := tracer.Start(ctx, "LinkCustomerCar-MemoryStore")
+	defer span.End()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return models.CustomerCar{}, err
+	}
+
+	row, ok := s.customer(customerID, tenantID)
+	if !ok {
+		return models.CustomerCar{}, store.ErrCustomerNotFound
+	}
+	if _, ok := s.car(link.CarID, tenantID); !ok {
+		return models.CustomerCar{}, store.ErrCarNotFound
+	}
+
+	for _, existing := range row.customer.Cars {
+		if existing.CarID == link.CarID && existing.Kind == link.Kind {
+			return existing, nil
+		}
+	}
+	row.customer.Cars = append(row.customer.Cars, link)
+	s.customers[row.customer.ID] = row
+	return link, nil
+}
+
+func (s *Store) EraseCustomer(ctx context.Context, id string, now time.Time) (models.Customer, error) {
+	tracer := otel.Tracer("MemoryStore")
+	_, span := tracer.Start(ctx, "EraseCustomer-MemoryStore")
+	defer span.End()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return models.Customer{}, err
+	}
+
+	row, ok := s.customer(id, tenantID)
+	if !ok {
+		return models.Customer{}, nil
+	}
+	erased := &row.customer
+	erased.Name, erased.Email, erased.Phone, erased.Address = "", "", "", ""
+	erased.MarketingConsent, erased.ContactConsent = false, false
+	erased.ConsentUpdatedAt, erased.UpdatedAt = &now, now
+	if erased.ErasedAt == nil {
+		erased.ErasedAt = &now
+	}
+	erased.Notes = nil
+	s.customers[erased.ID] = row
+
+	for id, reservation := range s.reservations {
+		if linkedTo(reservation.reservation.CustomerID, erased.ID) {
+			reservation.reservation.CustomerName, reservation.reservation.CustomerContact = "", ""
+			s.reservations[id] = reservation
+		}
+	}
+	for id, order := range s.orders {
+		if linkedTo(order.order.CustomerID, erased.ID) {
+			order.order.CustomerName, order.order.CustomerEmail, order.order.CustomerAddress = "", "", ""
+			s.orders[id] = order
+		}
+	}
+	return copyCustomer(row.customer), nil
+}
+
+// linkedTo reports whether a reservation or order was made for the customer.
+func linkedTo(customerID *uuid.UUID, id uuid.UUID) bool {
+	return customerID != nil && *customerID == id
+}
+
+// unlinkCar drops the links to a deleted car, customer_car.car_id
+// REFERENCES car(id) ON DELETE CASCADE.
+func (s *Store) unlinkCar(carID uuid.UUID) {
+	for id, row := range s.customers {
+		cars := row.customer.Cars[:0:0]
+		for _, link := range row.customer.Cars {
+			if link.CarID != carID {
+				cars = append(cars, link)
+			}
+		}
+		row.customer.Cars = cars
+		s.customers[id] = row
+	}
+}
@@ -0,0 +1,65 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/adohong4/carZone/models"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+)
+
+func (s *Store) GetDealerships(ctx context.Context) ([]models.Dealership, error) {
+	tracer := otel.Tracer("MemoryStore")
+	_, span := tracer.Start(ctx, "GetDealerships-MemoryStore")
+	defer span.End()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	dealerships := make([]models.Dealership, 0, len(s.dealerships))
+	for _, dealership := range s.dealerships {
+		dealerships = append(dealerships, dealership)
+	}
+	sort.Slice(dealerships, func(i, j int) bool {
+		if dealerships[i].CreatedAt.Equal(dealerships[j].CreatedAt) {
+			return dealerships[i].ID.String() < dealerships[j].ID.String()
+		}
+		return dealerships[i].CreatedAt.Before(dealerships[j].CreatedAt)
+	})
+	return dealerships, nil
+}
+
+func (s *Store) GetDealershipById(ctx context.Context, id string) (models.Dealership, error) {
+	tracer := otel.Tracer("MemoryStore")
+	_, span := tracer.Start(ctx, "GetDealershipById-MemoryStore")
+	defer span.End()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	dealershipID, err := uuid.Parse(id)
+	if err != nil {
+		return models.Dealership{}, fmt.Errorf("invalid dealership ID: %w", err)
+	}
+	return s.dealerships[dealershipID], nil
+}
+
+func (s *Store) CreateDealership(ctx context.Context, dealershipReq *models.DealershipRequest) (models.Dealership, error) {
+	tracer := otel.Tracer("MemoryStore")
+	_, span := tracer.Start(ctx, "CreateDealership-MemoryStore")
+	defer span.End()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	dealership := models.Dealership{
+		ID:        uuid.New(),
+		Name:      dealershipReq.Name,
+		CreatedAt: time.Now(),
+	}
+	s.dealerships[dealership.ID] = dealership
+	return dealership, nil
+}
@@ -0,0 +1,85 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/adohong4/carZone/models"
+)
+
+// maxLoginAttempts bounds the in-memory audit trail.
+const maxLoginAttempts = 10000
+
+// LoginStore implements store.LoginStoreInterface in process. It is used by
+// the memory backend, where a single node owns all logins.
+type LoginStore struct {
+	mu       sync.RWMutex
+	lockouts map[string]models.LoginLockout
+	attempts []models.LoginAttempt
+}
+
+func NewLoginStore() *LoginStore {
+	return &LoginStore{lockouts: make(map[string]models.LoginLockout)}
+}
+
+func (l *LoginStore) RecordAttempt(ctx context.Context, attempt models.LoginAttempt) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.attempts = append(l.attempts, attempt)
+	if len(l.attempts) > maxLoginAttempts {
+		l.attempts = l.attempts[len(l.attempts)-maxLoginAttempts:]
+	}
+	return nil
+}
+
+// Attempts returns a copy of the recorded audit trail, oldest first.
+func (l *LoginStore) Attempts() []models.LoginAttempt {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return append([]models.LoginAttempt(nil), l.attempts...)
+}
+
+func (l *LoginStore) GetLockout(ctx context.Context, subject string) (models.LoginLockout, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	lockout, ok := l.lockouts[subject]
+	if !ok {
+		return models.LoginLockout{Subject: subject}, nil
+	}
+	return lockout, nil
+}
+
+func (l *LoginStore) IncrementFailure(ctx context.Context, subject string) (models.LoginLockout, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	lockout := l.lockouts[subject]
+	lockout.Subject = subject
+	lockout.FailedAttempts++
+	l.lockouts[subject] = lockout
+	return lockout, nil
+}
+
+func (l *LoginStore) LockUntil(ctx context.Context, subject string, until time.Time) (models.LoginLockout, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	lockout := l.lockouts[subject]
+	lockout.Subject = subject
+	lockout.FailedAttempts = 0
+	lockout.Lockouts++
+	lockout.LockedUntil = until
+	l.lockouts[subject] = lockout
+	return lockout, nil
+}
+
+func (l *LoginStore) ResetLockout(ctx context.Context, subject string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.lockouts, subject)
+	return nil
+}
@@ -0,0 +1,193 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"github.com/adohong4/carZone/models"
+	"github.com/adohong4/carZone/store"
+	"github.com/adohong4/carZone/tenant"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+)
+
+// carImageRow remembers the dealership an image belongs to.
+type carImageRow struct {
+	image    models.CarImage
+	tenantID string
+}
+
+// dropCarImages removes the images of a deleted car, car_image.car_id
+// REFERENCES car(id) ON DELETE CASCADE.
+func (s *Store) dropCarImages(carID uuid.UUID) {
+	for id, row := range s.carImages {
+		if row.image.CarID == carID {
+			delete(s.carImages, id)
+		}
+	}
+}
+
+// imagesOf returns the images of a car by position.
+func (s *Store) imagesOf(carID uuid.UUID) []models.CarImage {
+	var images []models.CarImage
+	for _, row := range s.carImages {
+		if row.image.CarID == carID {
+			images = append(images, row.image)
+		}
+	}
+	sort.Slice(images, func(i, j int) bool {
+		return images[i].Position < images[j].Position
+	})
+	return images
+}
+
+func (s *Store) ListCarImages(ctx context.Context, carIDs []string) ([]models.CarImage, error) {
+	tracer := otel.Tracer("MemoryStore")
+	_, span := tracer.Start(ctx, "ListCarImages-MemoryStore")
+	defer span.End()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	wanted := make(map[string]bool, len(carIDs))
+	for _, id := range carIDs {
+		wanted[id] = true
+	}
+
+	var images []models.CarImage
+	for _, row := range s.carImages {
+		if row.tenantID == tenantID && wanted[row.image.CarID.String()] {
+			images = append(images, row.image)
+		}
+	}
+	sort.Slice(images, func(i, j int) bool {
+		if images[i].CarID != images[j].CarID {
+			return images[i].CarID.String() < images[j].CarID.String()
+		}
+		return images[i].Position < images[j].Position
+	})
+	return images, nil
+}
+
+func (s *Store) AddCarImage(ctx context.Context, image models.CarImage) (models.CarImage, error) {
+	tracer := otel.Tracer("MemoryStore")
+	_, span := tracer.Start(ctx, "AddCarImage-MemoryStore")
+	defer span.End()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return models.CarImage{}, err
+	}
+	if _, ok := s.car(image.CarID, tenantID); !ok {
+		return models.CarImage{}, store.ErrCarNotFound
+	}
+
+	images := s.imagesOf(image.CarID)
+	image.Position = 0
+	if len(images) > 0 {
+		image.Position = images[len(images)-1].Position + 1
+	}
+	image.Primary = len(images) == 0
+	s.carImages[image.ID] = carImageRow{image: image, tenantID: tenantID}
+	return image, nil
+}
+
+func (s *Store) SetCarImageOrder(ctx context.Context, carID string, ids []string) error {
+	tracer := otel.Tracer("MemoryStore")
+	_, span := tracer.Start(ctx, "SetCarImageOrder-MemoryStore")
+	defer span.End()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	for position, id := range ids {
+		imageID, err := uuid.Parse(id)
+		if err != nil {
+			continue
+		}
+		row, ok := s.carImages[imageID]
+		if !ok || row.tenantID != tenantID || row.image.CarID.String() != carID {
+			continue
+		}
+		row.image.Position = position
+		s.carImages[imageID] = row
+	}
+	return nil
+}
+
+func (s *Store) SetPrimaryCarImage(ctx context.Context, carID, id string) (models.CarImage, error) {
+	tracer := otel.Tracer("MemoryStore")
+	_, span := tracer.Start(ctx, "SetPrimaryCarImage-MemoryStore")
+	defer span.End()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return models.CarImage{}, err
+	}
+	imageID, err := uuid.Parse(id)
+	if err != nil {
+		return models.CarImage{}, nil
+	}
+	row, ok := s.carImages[imageID]
+	if !ok || row.tenantID != tenantID || row.image.CarID.String() != carID {
+		return models.CarImage{}, nil
+	}
+
+	for otherID, other := range s.carImages {
+		if other.image.CarID == row.image.CarID && other.image.Primary {
+			other.image.Primary = false
+			s.carImages[otherID] = other
+		}
+	}
+	row.image.Primary = true
+	s.carImages[imageID] = row
+	return row.image, nil
+}
+
+func (s *Store) DeleteCarImage(ctx context.Context, carID, id string) (models.CarImage, error) {
+	tracer := otel.Tracer("MemoryStore")
+	_, span := tracer.Start(ctx, "DeleteCarImage-MemoryStore")
+	defer span.End()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return models.CarImage{}, err
+	}
+	imageID, err := uuid.Parse(id)
+	if err != nil {
+		return models.CarImage{}, nil
+	}
+	row, ok := s.carImages[imageID]
+	if !ok || row.tenantID != tenantID || row.image.CarID.String() != carID {
+		return models.CarImage{}, nil
+	}
+
+	delete(s.carImages, imageID)
+	if row.image.Primary {
+		if rest := s.imagesOf(row.image.CarID); len(rest) > 0 {
+			next := s.carImages[rest[0].ID]
+			next.image.Primary = true
+			s.carImages[rest[0].ID] = next
+		}
+	}
+	return row.image, nil
+}
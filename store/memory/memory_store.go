@@ -0,0 +1,489 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/adohong4/carZone/models"
+	"github.com/adohong4/carZone/store"
+	"github.com/adohong4/carZone/tenant"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+)
+
+// Store keeps cars and engines in process memory. It implements both
+// store.CarStoreInterface and store.EngineStoreInterface so the engine
+// foreign-key checks can be enforced the same way Postgres does.
+type Store struct {
+	mu          sync.RWMutex
+	cars        map[uuid.UUID]carRow
+	engines     map[uuid.UUID]engineRow
+	dealerships map[uuid.UUID]models.Dealership
+	vehicles    map[vehicleKey]models.Vehicle
+
+	reservations map[uuid.UUID]reservationRow
+
+	orders         map[uuid.UUID]orderRow
+	invoiceNumbers map[string]int64
+
+	customers map[uuid.UUID]customerRow
+
+	rates map[string]models.ExchangeRate
+
+	carPrices  map[uuid.UUID]carPriceRow
+	promotions map[uuid.UUID]promotionRow
+
+	carImages map[uuid.UUID]carImageRow
+
+	brands map[uuid.UUID]models.Brand
+
+	validationRules map[string][]models.ValidationRule
+
+	outbox         []outboxRow
+	outboxSequence int64
+
+	webhooks          map[uuid.UUID]webhookRow
+	webhookDeliveries map[uuid.UUID]models.WebhookDelivery
+}
+
+// carRow and engineRow remember the dealership a row belongs to, rows of
+// other tenants are treated as missing.
+type carRow struct {
+	car      models.Car
+	tenantID string
+}
+
+type engineRow struct {
+	engine   models.Engine
+	tenantID string
+}
+
+func New() *Store {
+	defaultDealership := models.Dealership{
+		ID:        uuid.MustParse(tenant.DefaultID),
+		Name:      "Default dealership",
+		CreatedAt: time.Now(),
+	}
+	return &Store{
+		cars:        make(map[uuid.UUID]carRow),
+		engines:     make(map[uuid.UUID]engineRow),
+		dealerships: map[uuid.UUID]models.Dealership{defaultDealership.ID: defaultDealership},
+		vehicles:    make(map[vehicleKey]models.Vehicle),
+
+		reservations: make(map[uuid.UUID]reservationRow),
+
+		orders:         make(map[uuid.UUID]orderRow),
+		invoiceNumbers: make(map[string]int64),
+
+		customers: make(map[uuid.UUID]customerRow),
+
+		rates: make(map[string]models.ExchangeRate),
+
+		carPrices:  make(map[uuid.UUID]carPriceRow),
+		promotions: make(map[uuid.UUID]promotionRow),
+
+		carImages: make(map[uuid.UUID]carImageRow),
+
+		brands: newSeedBrands(),
+
+		validationRules: make(map[string][]models.ValidationRule),
+
+		webhooks:          make(map[uuid.UUID]webhookRow),
+		webhookDeliveries: make(map[uuid.UUID]models.WebhookDelivery),
+	}
+}
+
+func (s *Store) car(id uuid.UUID, tenantID string) (models.Car, bool) {
+	row, ok := s.cars[id]
+	if !ok || row.tenantID != tenantID {
+		return models.Car{}, false
+	}
+	return row.car, true
+}
+
+func (s *Store) engine(id uuid.UUID, tenantID string) (models.Engine, bool) {
+	row, ok := s.engines[id]
+	if !ok || row.tenantID != tenantID {
+		return models.Engine{}, false
+	}
+	return row.engine, true
+}
+
+// copySpec keeps the spec of a stored car apart from the request it came
+// with.
+func copySpec(spec *models.CarSpec) *models.CarSpec {
+	if spec == nil {
+		return nil
+	}
+	copied := *spec
+	return &copied
+}
+
+func (s *Store) GetCarById(ctx context.Context, id string) (models.Car, error) {
+	tracer := otel.Tracer("MemoryStore")
+	_, span := tracer.Start(ctx, "GetCarById-MemoryStore")
+	defer span.End()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return models.Car{}, err
+	}
+	carID, err := uuid.Parse(id)
+	if err != nil {
+		return models.Car{}, fmt.Errorf("invalid car ID: %w", err)
+	}
+
+	car, ok := s.car(carID, tenantID)
+	if !ok {
+		return models.Car{}, nil
+	}
+	car.Engine, _ = s.engine(car.Engine.EngineID, tenantID)
+	return car, nil
+}
+
+func (s *Store) GetCarByBrand(ctx context.Context, brand string, isEngine bool) ([]models.Car, error) {
+	tracer := otel.Tracer("MemoryStore")
+	_, span := tracer.Start(ctx, "GetCarByBrand-MemoryStore")
+	defer span.End()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var cars []models.Car
+	for _, row := range s.cars {
+		car := row.car
+		if row.tenantID != tenantID || car.Brand != brand {
+			continue
+		}
+		if isEngine {
+			car.Engine, _ = s.engine(car.Engine.EngineID, tenantID)
+		} else {
+			car.Engine = models.Engine{}
+		}
+		cars = append(cars, car)
+	}
+	sortCars(cars)
+	return cars, nil
+}
+
+func (s *Store) GetCarsByIds(ctx context.Context, ids []string) ([]models.Car, error) {
+	tracer := otel.Tracer("MemoryStore")
+	_, span := tracer.Start(ctx, "GetCarsByIds-MemoryStore")
+	defer span.End()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var cars []models.Car
+	for _, id := range ids {
+		carID, err := uuid.Parse(id)
+		if err != nil {
+			return nil, fmt.Errorf("invalid car ID: %w", err)
+		}
+		car, ok := s.car(carID, tenantID)
+		if !ok {
+			continue
+		}
+		car.Engine, _ = s.engine(car.Engine.EngineID, tenantID)
+		car.Spec = copySpec(car.Spec)
+		cars = append(cars, car)
+	}
+	sortCars(cars)
+	return cars, nil
+}
+
+func (s *Store) CreateCar(ctx context.Context, carReq *models.CarRequest) (models.Car, error) {
+	tracer := otel.Tracer("MemoryStore")
+	_, span := tracer.Start(ctx, "CreateCar-MemoryStore")
+	defer span.End()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return models.Car{}, err
+	}
+	if _, ok := s.engine(carReq.Engine.EngineID, tenantID); !ok {
+		return models.Car{}, errors.New("engine_id does not exists in the engine table")
+	}
+
+	now := time.Now()
+	car := models.Car{
+		ID:        uuid.New(),
+		Name:      carReq.Name,
+		Year:      carReq.Year,
+		Brand:     carReq.Brand,
+		FuelType:  carReq.FuelType,
+		Engine:    models.Engine{EngineID: carReq.Engine.EngineID},
+		Price:     carReq.Price,
+		Spec:      copySpec(carReq.Spec),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	event, err := models.NewEvent(models.EventCarCreated, tenantID, car.ID, car)
+	if err != nil {
+		return models.Car{}, err
+	}
+	s.cars[car.ID] = carRow{car: car, tenantID: tenantID}
+	s.addCarPrice(car, tenantID)
+	s.recordEvent(event)
+	return car, nil
+}
+
+func (s *Store) UpdateCar(ctx context.Context, id string, carReq *models.CarRequest) (models.Car, error) {
+	tracer := otel.Tracer("MemoryStore")
+	_, span := tracer.Start(ctx, "UpdateCar-MemoryStore")
+	defer span.End()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return models.Car{}, err
+	}
+	carID, err := uuid.Parse(id)
+	if err != nil {
+		return models.Car{}, fmt.Errorf("invalid car ID: %w", err)
+	}
+
+	car, ok := s.car(carID, tenantID)
+	if !ok {
+		return models.Car{}, errors.New("car not found")
+	}
+	if _, ok := s.engine(carReq.Engine.EngineID, tenantID); !ok {
+		return models.Car{}, errors.New("engine_id does not exists in the engine table")
+	}
+
+	car.Name = carReq.Name
+	car.Year = carReq.Year
+	car.Brand = carReq.Brand
+	car.FuelType = carReq.FuelType
+	car.Engine = models.Engine{EngineID: carReq.Engine.EngineID}
+	priceChanged := car.Price != carReq.Price
+	car.Price = carReq.Price
+	car.Spec = copySpec(carReq.Spec)
+	car.UpdatedAt = time.Now()
+	event, err := models.NewEvent(models.EventCarUpdated, tenantID, carID, car)
+	if err != nil {
+		return models.Car{}, err
+	}
+	s.cars[carID] = carRow{car: car, tenantID: tenantID}
+	if priceChanged {
+		s.addCarPrice(car, tenantID)
+	}
+	s.recordEvent(event)
+	return car, nil
+}
+
+func (s *Store) DeleteCar(ctx context.Context, id string) (models.Car, error) {
+	tracer := otel.Tracer("MemoryStore")
+	_, span := tracer.Start(ctx, "DeleteCar-MemoryStore")
+	defer span.End()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return models.Car{}, err
+	}
+	carID, err := uuid.Parse(id)
+	if err != nil {
+		return models.Car{}, fmt.Errorf("invalid car ID: %w", err)
+	}
+
+	car, ok := s.car(carID, tenantID)
+	if !ok {
+		return models.Car{}, errors.New("car not found")
+	}
+
+	// vehicle.car_id REFERENCES car(id)
+	for _, vehicle := range s.vehicles {
+		if vehicle.CarID == carID {
+			return models.Car{}, store.ErrCarHasVehicles
+		}
+	}
+	// order_line.car_id REFERENCES car(id)
+	for _, row := range s.orders {
+		for _, line := range row.order.Lines {
+			if line.CarID == carID {
+				return models.Car{}, store.ErrCarOnOrder
+			}
+		}
+	}
+	event, err := models.NewEvent(models.EventCarDeleted, tenantID, carID, car)
+	if err != nil {
+		return models.Car{}, err
+	}
+	// reservation.car_id REFERENCES car(id) ON DELETE CASCADE
+	for id, row := range s.reservations {
+		if row.reservation.CarID == carID {
+			delete(s.reservations, id)
+		}
+	}
+	s.unlinkCar(carID)
+	s.dropCarPrices(carID)
+	s.dropCarImages(carID)
+	delete(s.cars, carID)
+	s.recordEvent(event)
+	return car, nil
+}
+
+func (s *Store) EngineById(ctx context.Context, id string) (models.Engine, error) {
+	tracer := otel.Tracer("MemoryStore")
+	_, span := tracer.Start(ctx, "EngineById-MemoryStore")
+	defer span.End()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return models.Engine{}, err
+	}
+	engineID, err := uuid.Parse(id)
+	if err != nil {
+		return models.Engine{}, fmt.Errorf("invalid engine ID: %w", err)
+	}
+	engine, _ := s.engine(engineID, tenantID)
+	return engine, nil
+}
+
+func (s *Store) CreateEngine(ctx context.Context, engineReq *models.EngineRequest) (models.Engine, error) {
+	tracer := otel.Tracer("MemoryStore")
+	_, span := tracer.Start(ctx, "CreateEngine-MemoryStore")
+	defer span.End()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return models.Engine{}, err
+	}
+
+	engine := models.Engine{
+		EngineID:        uuid.New(),
+		Type:            models.EngineType(engineReq.Type),
+		Displacement:    engineReq.Displacement,
+		NoOfCylinders:   engineReq.NoOfCylinders,
+		CarRange:        engineReq.CarRange,
+		MotorPower:      engineReq.MotorPower,
+		BatteryCapacity: engineReq.BatteryCapacity,
+		UpdatedAt:       time.Now(),
+	}
+	event, err := models.NewEvent(models.EventEngineCreated, tenantID, engine.EngineID, engine)
+	if err != nil {
+		return models.Engine{}, err
+	}
+	s.engines[engine.EngineID] = engineRow{engine: engine, tenantID: tenantID}
+	s.recordEvent(event)
+	return engine, nil
+}
+
+func (s *Store) EngineUpdate(ctx context.Context, id string, engineReq *models.EngineRequest) (models.Engine, error) {
+	tracer := otel.Tracer("MemoryStore")
+	_, span := tracer.Start(ctx, "EngineUpdate-MemoryStore")
+	defer span.End()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return models.Engine{}, err
+	}
+	engineID, err := uuid.Parse(id)
+	if err != nil {
+		return models.Engine{}, fmt.Errorf("invalid engine ID: %w", err)
+	}
+	if _, ok := s.engine(engineID, tenantID); !ok {
+		return models.Engine{}, errors.New("No Rows Were Updated")
+	}
+
+	engine := models.Engine{
+		EngineID:        engineID,
+		Type:            models.EngineType(engineReq.Type),
+		Displacement:    engineReq.Displacement,
+		NoOfCylinders:   engineReq.NoOfCylinders,
+		CarRange:        engineReq.CarRange,
+		MotorPower:      engineReq.MotorPower,
+		BatteryCapacity: engineReq.BatteryCapacity,
+		UpdatedAt:       time.Now(),
+	}
+	event, err := models.NewEvent(models.EventEngineUpdated, tenantID, engineID, engine)
+	if err != nil {
+		return models.Engine{}, err
+	}
+	s.engines[engineID] = engineRow{engine: engine, tenantID: tenantID}
+	s.recordEvent(event)
+	return engine, nil
+}
+
+func (s *Store) EngineDelete(ctx context.Context, id string) (models.Engine, error) {
+	tracer := otel.Tracer("MemoryStore")
+	_, span := tracer.Start(ctx, "EngineDelete-MemoryStore")
+	defer span.End()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return models.Engine{}, err
+	}
+	engineID, err := uuid.Parse(id)
+	if err != nil {
+		return models.Engine{}, fmt.Errorf("invalid engine ID: %w", err)
+	}
+
+	engine, ok := s.engine(engineID, tenantID)
+	if !ok {
+		return models.Engine{}, nil
+	}
+
+	// car.engine_id REFERENCES engine(id), so Postgres refuses the delete
+	// while a car still points at the engine.
+	for _, row := range s.cars {
+		if row.car.Engine.EngineID == engineID {
+			return models.Engine{}, errors.New("engine is still referenced by a car")
+		}
+	}
+
+	event, err := models.NewEvent(models.EventEngineDeleted, tenantID, engineID, engine)
+	if err != nil {
+		return models.Engine{}, err
+	}
+	delete(s.engines, engineID)
+	s.recordEvent(event)
+	return engine, nil
+}
+
+// sortCars gives listings a stable order, the map iteration order is random.
+func sortCars(cars []models.Car) {
+	sort.Slice(cars, func(i, j int) bool {
+		if cars[i].CreatedAt.Equal(cars[j].CreatedAt) {
+			return cars[i].ID.String() < cars[j].ID.String()
+		}
+		return cars[i].CreatedAt.Before(cars[j].CreatedAt)
+	})
+}
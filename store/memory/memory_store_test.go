@@ -0,0 +1,15 @@
+package memory
+
+import (
+	"testing"
+
+	"github.com/adohong4/carZone/store/storetest"
+)
+
+func TestConformance(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) storetest.Stores {
+		s := New()
+		return storetest.Stores{Cars: s, Engines: s, Vehicles: s, Reservations: s, Orders: s, Customers: s, Rates: s, Prices: s, Images: s, Catalogue: s, Rules: s, Reports: s, Outbox: s, Webhooks: s,
+			Logins: NewLoginStore(), TOTP: NewTOTPStore()}
+	})
+}
@@ -0,0 +1,210 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/adohong4/carZone/models"
+	"github.com/adohong4/carZone/store"
+	"github.com/adohong4/carZone/tenant"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+)
+
+// orderRow remembers the dealership an order belongs to.
+type orderRow struct {
+	order    models.Order
+	tenantID string
+}
+
+// copyOrder keeps callers from changing stored lines through the slice.
+func copyOrder(order models.Order) models.Order {
+	order.Lines = append([]models.OrderLine(nil), order.Lines...)
+	return order
+}
+
+func (s *Store) checkOrderCars(order models.Order, tenantID string) error {
+	for _, line := range order.Lines {
+		if _, ok := s.car(line.CarID, tenantID); !ok {
+			return store.ErrCarNotFound
+		}
+	}
+	return nil
+}
+
+func (s *Store) CreateOrder(ctx context.Context, order models.Order) (models.Order, error) {
+	tracer := otel.Tracer("MemoryStore")
+	_, span := tracer.Start(ctx, "CreateOrder-MemoryStore")
+	defer span.End()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return models.Order{}, err
+	}
+	if err := s.checkCustomer(order.CustomerID, tenantID); err != nil {
+		return models.Order{}, err
+	}
+	if err := s.checkOrderCars(order, tenantID); err != nil {
+		return models.Order{}, err
+	}
+
+	s.orders[order.ID] = orderRow{order: copyOrder(order), tenantID: tenantID}
+	return copyOrder(order), nil
+}
+
+func (s *Store) GetOrder(ctx context.Context, id string) (models.Order, error) {
+	tracer := otel.Tracer("MemoryStore")
+	_, span := tracer.Start(ctx, "GetOrder-MemoryStore")
+	defer span.End()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return models.Order{}, err
+	}
+	orderID, err := uuid.Parse(id)
+	if err != nil {
+		return models.Order{}, nil
+	}
+
+	row, ok := s.orders[orderID]
+	if !ok || row.tenantID != tenantID {
+		return models.Order{}, nil
+	}
+	return copyOrder(row.order), nil
+}
+
+func (s *Store) ListOrders(ctx context.Context, status string) ([]models.Order, error) {
+	tracer := otel.Tracer("MemoryStore")
+	_, span := tracer.Start(ctx, "ListOrders-MemoryStore")
+	defer span.End()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var orders []models.Order
+	for _, row := range s.orders {
+		if row.tenantID != tenantID || status != "" && row.order.Status != status {
+			continue
+		}
+		order := row.order
+		order.Lines = nil
+		orders = append(orders, order)
+	}
+	sort.Slice(orders, func(i, j int) bool {
+		if orders[i].CreatedAt.Equal(orders[j].CreatedAt) {
+			return orders[i].ID.String() < orders[j].ID.String()
+		}
+		return orders[i].CreatedAt.After(orders[j].CreatedAt)
+	})
+	return orders, nil
+}
+
+func (s *Store) ListCustomerOrders(ctx context.Context, customerID string) ([]models.Order, error) {
+	tracer := otel.Tracer("MemoryStore")
+	_, span := tracer.Start(ctx, "ListCustomerOrders-MemoryStore")
+	defer span.End()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	id, err := uuid.Parse(customerID)
+	if err != nil {
+		return nil, nil
+	}
+
+	var orders []models.Order
+	for _, row := range s.orders {
+		if row.tenantID == tenantID && linkedTo(row.order.CustomerID, id) {
+			orders = append(orders, copyOrder(row.order))
+		}
+	}
+	sort.Slice(orders, func(i, j int) bool {
+		if orders[i].CreatedAt.Equal(orders[j].CreatedAt) {
+			return orders[i].ID.String() < orders[j].ID.String()
+		}
+		return orders[i].CreatedAt.After(orders[j].CreatedAt)
+	})
+	return orders, nil
+}
+
+func (s *Store) UpdateOrder(ctx context.Context, order models.Order) (models.Order, error) {
+	tracer := otel.Tracer("MemoryStore")
+	_, span := tracer.Start(ctx, "UpdateOrder-MemoryStore")
+	defer span.End()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return models.Order{}, err
+	}
+
+	row, ok := s.orders[order.ID]
+	if !ok || row.tenantID != tenantID || row.order.Status != models.OrderDraft {
+		return models.Order{}, store.ErrOrderConflict
+	}
+	if err := s.checkCustomer(order.CustomerID, tenantID); err != nil {
+		return models.Order{}, err
+	}
+	if err := s.checkOrderCars(order, tenantID); err != nil {
+		return models.Order{}, err
+	}
+
+	updated := copyOrder(order)
+	updated.Status = row.order.Status
+	updated.Salesperson = row.order.Salesperson
+	updated.CreatedAt = row.order.CreatedAt
+	s.orders[order.ID] = orderRow{order: updated, tenantID: tenantID}
+	return copyOrder(updated), nil
+}
+
+func (s *Store) UpdateOrderStatus(ctx context.Context, id, fromStatus, toStatus string, now time.Time) (models.Order, error) {
+	tracer := otel.Tracer("MemoryStore")
+	_, span := tracer.Start(ctx, "UpdateOrderStatus-MemoryStore")
+	defer span.End()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return models.Order{}, err
+	}
+	orderID, err := uuid.Parse(id)
+	if err != nil {
+		return models.Order{}, store.ErrOrderConflict
+	}
+
+	row, ok := s.orders[orderID]
+	if !ok || row.tenantID != tenantID || row.order.Status != fromStatus {
+		return models.Order{}, store.ErrOrderConflict
+	}
+
+	row.order.Status = toStatus
+	row.order.UpdatedAt = now
+	if toStatus == models.OrderConfirmed {
+		s.invoiceNumbers[tenantID]++
+		row.order.InvoiceNumber = models.InvoiceNumber(s.invoiceNumbers[tenantID])
+		confirmedAt := now
+		row.order.ConfirmedAt = &confirmedAt
+	}
+	s.orders[orderID] = row
+	return copyOrder(row.order), nil
+}
@@ -0,0 +1,112 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/adohong4/carZone/models"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+)
+
+// outboxRow mirrors the outbox_event table.
+type outboxRow struct {
+	event         models.Event
+	attempts      int
+	lastError     string
+	nextAttemptAt time.Time
+	publishedAt   time.Time
+}
+
+// recordEvent appends event to the outbox, the caller holds the write lock
+// of the change.
+func (s *Store) recordEvent(event models.Event) {
+	s.outboxSequence++
+	event.Sequence = s.outboxSequence
+	s.outbox = append(s.outbox, outboxRow{event: event})
+}
+
+// outboxIndex finds the row of sequence, the outbox is kept in sequence order.
+func (s *Store) outboxIndex(sequence int64) (int, bool) {
+	i := sort.Search(len(s.outbox), func(i int) bool { return s.outbox[i].event.Sequence >= sequence })
+	return i, i < len(s.outbox) && s.outbox[i].event.Sequence == sequence
+}
+
+func (s *Store) ClaimEvents(ctx context.Context, now, until time.Time, limit int) ([]models.PendingEvent, error) {
+	tracer := otel.Tracer("MemoryStore")
+	_, span := tracer.Start(ctx, "ClaimEvents-MemoryStore")
+	defer span.End()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// the entities with a pending event that is not due yet
+	waiting := make(map[uuid.UUID]bool)
+	var pending []models.PendingEvent
+	for i := range s.outbox {
+		if len(pending) == limit {
+			break
+		}
+		row := &s.outbox[i]
+		if !row.publishedAt.IsZero() || waiting[row.event.EntityID] {
+			continue
+		}
+		if row.nextAttemptAt.After(now) {
+			waiting[row.event.EntityID] = true
+			continue
+		}
+		row.nextAttemptAt = until
+		pending = append(pending, models.PendingEvent{Event: row.event, Attempts: row.attempts})
+	}
+	return pending, nil
+}
+
+func (s *Store) FinishEvents(ctx context.Context, results []models.EventResult, now time.Time) (int, error) {
+	tracer := otel.Tracer("MemoryStore")
+	_, span := tracer.Start(ctx, "FinishEvents-MemoryStore")
+	defer span.End()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	published := 0
+	for _, result := range results {
+		index, ok := s.outboxIndex(result.Sequence)
+		if !ok {
+			continue
+		}
+		row := &s.outbox[index]
+		switch {
+		case result.Published:
+			row.publishedAt = now
+			published++
+		case result.Error != "":
+			row.attempts++
+			row.lastError = result.Error
+			row.nextAttemptAt = result.RetryAt
+		default:
+			row.nextAttemptAt = result.RetryAt
+		}
+	}
+	return published, nil
+}
+
+func (s *Store) DeletePublishedEvents(ctx context.Context, before time.Time) (int64, error) {
+	tracer := otel.Tracer("MemoryStore")
+	_, span := tracer.Start(ctx, "DeletePublishedEvents-MemoryStore")
+	defer span.End()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	kept := s.outbox[:0]
+	for _, row := range s.outbox {
+		if row.publishedAt.IsZero() || !row.publishedAt.Before(before) {
+			kept = append(kept, row)
+		}
+	}
+	deleted := int64(len(s.outbox) - len(kept))
+	s.outbox = kept
+	return deleted, nil
+}
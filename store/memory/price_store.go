@@ -0,0 +1,227 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/adohong4/carZone/models"
+	"github.com/adohong4/carZone/store"
+	"github.com/adohong4/carZone/tenant"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+)
+
+// carPriceRow and promotionRow remember the dealership a row belongs to.
+type carPriceRow struct {
+	price    models.CarPrice
+	tenantID string
+}
+
+type promotionRow struct {
+	promotion models.Promotion
+	tenantID  string
+}
+
+// addCarPrice continues the price history of car with its price from
+// UpdatedAt on, like the car_price insert of the SQL car stores.
+func (s *Store) addCarPrice(car models.Car, tenantID string) {
+	price := models.CarPrice{
+		ID:            uuid.New(),
+		CarID:         car.ID,
+		Price:         car.Price,
+		EffectiveFrom: car.UpdatedAt,
+		CreatedAt:     car.UpdatedAt,
+	}
+	s.carPrices[price.ID] = carPriceRow{price: price, tenantID: tenantID}
+}
+
+// dropCarPrices removes the history of a deleted car, car_price.car_id
+// REFERENCES car(id) ON DELETE CASCADE.
+func (s *Store) dropCarPrices(carID uuid.UUID) {
+	for id, row := range s.carPrices {
+		if row.price.CarID == carID {
+			delete(s.carPrices, id)
+		}
+	}
+}
+
+func sortCarPrices(prices []models.CarPrice) {
+	sort.Slice(prices, func(i, j int) bool {
+		if !prices[i].EffectiveFrom.Equal(prices[j].EffectiveFrom) {
+			return prices[i].EffectiveFrom.Before(prices[j].EffectiveFrom)
+		}
+		return prices[i].CreatedAt.Before(prices[j].CreatedAt)
+	})
+}
+
+func (s *Store) ListCarPrices(ctx context.Context, carID string) ([]models.CarPrice, error) {
+	tracer := otel.Tracer("MemoryStore")
+	_, span := tracer.Start(ctx, "ListCarPrices-MemoryStore")
+	defer span.End()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var prices []models.CarPrice
+	for _, row := range s.carPrices {
+		if row.tenantID == tenantID && row.price.CarID.String() == carID {
+			prices = append(prices, row.price)
+		}
+	}
+	sortCarPrices(prices)
+	return prices, nil
+}
+
+func (s *Store) AddCarPrice(ctx context.Context, price models.CarPrice) (models.CarPrice, error) {
+	tracer := otel.Tracer("MemoryStore")
+	_, span := tracer.Start(ctx, "AddCarPrice-MemoryStore")
+	defer span.End()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return models.CarPrice{}, err
+	}
+	if _, ok := s.car(price.CarID, tenantID); !ok {
+		return models.CarPrice{}, store.ErrCarNotFound
+	}
+
+	s.carPrices[price.ID] = carPriceRow{price: price, tenantID: tenantID}
+	return price, nil
+}
+
+func (s *Store) DeleteCarPrice(ctx context.Context, carID, id string, now time.Time) (models.CarPrice, error) {
+	tracer := otel.Tracer("MemoryStore")
+	_, span := tracer.Start(ctx, "DeleteCarPrice-MemoryStore")
+	defer span.End()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return models.CarPrice{}, err
+	}
+	priceID, err := uuid.Parse(id)
+	if err != nil {
+		return models.CarPrice{}, nil
+	}
+
+	row, ok := s.carPrices[priceID]
+	if !ok || row.tenantID != tenantID || row.price.CarID.String() != carID || !row.price.EffectiveFrom.After(now) {
+		return models.CarPrice{}, nil
+	}
+	delete(s.carPrices, priceID)
+	return row.price, nil
+}
+
+func (s *Store) ApplyCarPrices(ctx context.Context, now time.Time) ([]models.RepricedCar, error) {
+	tracer := otel.Tracer("MemoryStore")
+	_, span := tracer.Start(ctx, "ApplyCarPrices-MemoryStore")
+	defer span.End()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	history := make(map[uuid.UUID][]models.CarPrice)
+	for _, row := range s.carPrices {
+		history[row.price.CarID] = append(history[row.price.CarID], row.price)
+	}
+
+	var applied []models.RepricedCar
+	for carID, prices := range history {
+		effective, ok := models.EffectiveCarPrice(prices, now)
+		row, exists := s.cars[carID]
+		if !ok || !exists || row.car.Price == effective.Price {
+			continue
+		}
+		row.car.Price = effective.Price
+		row.car.UpdatedAt = now
+		event, err := models.NewEvent(models.EventCarUpdated, row.tenantID, carID, row.car)
+		if err != nil {
+			return applied, err
+		}
+		s.cars[carID] = row
+		s.recordEvent(event)
+		applied = append(applied, models.RepricedCar{TenantID: row.tenantID, CarID: carID})
+	}
+	return applied, nil
+}
+
+func (s *Store) ListPromotions(ctx context.Context) ([]models.Promotion, error) {
+	tracer := otel.Tracer("MemoryStore")
+	_, span := tracer.Start(ctx, "ListPromotions-MemoryStore")
+	defer span.End()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var promotions []models.Promotion
+	for _, row := range s.promotions {
+		if row.tenantID == tenantID {
+			promotions = append(promotions, row.promotion)
+		}
+	}
+	sort.Slice(promotions, func(i, j int) bool {
+		if !promotions[i].StartsAt.Equal(promotions[j].StartsAt) {
+			return promotions[i].StartsAt.Before(promotions[j].StartsAt)
+		}
+		return promotions[i].CreatedAt.Before(promotions[j].CreatedAt)
+	})
+	return promotions, nil
+}
+
+func (s *Store) CreatePromotion(ctx context.Context, promotion models.Promotion) (models.Promotion, error) {
+	tracer := otel.Tracer("MemoryStore")
+	_, span := tracer.Start(ctx, "CreatePromotion-MemoryStore")
+	defer span.End()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return models.Promotion{}, err
+	}
+
+	s.promotions[promotion.ID] = promotionRow{promotion: promotion, tenantID: tenantID}
+	return promotion, nil
+}
+
+func (s *Store) DeletePromotion(ctx context.Context, id string) (models.Promotion, error) {
+	tracer := otel.Tracer("MemoryStore")
+	_, span := tracer.Start(ctx, "DeletePromotion-MemoryStore")
+	defer span.End()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return models.Promotion{}, err
+	}
+	promotionID, err := uuid.Parse(id)
+	if err != nil {
+		return models.Promotion{}, nil
+	}
+
+	row, ok := s.promotions[promotionID]
+	if !ok || row.tenantID != tenantID {
+		return models.Promotion{}, nil
+	}
+	delete(s.promotions, promotionID)
+	return row.promotion, nil
+}
@@ -0,0 +1,39 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"github.com/adohong4/carZone/models"
+	"go.opentelemetry.io/otel"
+)
+
+func (s *Store) GetRates(ctx context.Context) ([]models.ExchangeRate, error) {
+	tracer := otel.Tracer("MemoryStore")
+	_, span := tracer.Start(ctx, "GetRates-MemoryStore")
+	defer span.End()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rates := make([]models.ExchangeRate, 0, len(s.rates))
+	for _, rate := range s.rates {
+		rates = append(rates, rate)
+	}
+	sort.Slice(rates, func(i, j int) bool {
+		return rates[i].Currency < rates[j].Currency
+	})
+	return rates, nil
+}
+
+func (s *Store) SetRate(ctx context.Context, rate models.ExchangeRate) (models.ExchangeRate, error) {
+	tracer := otel.Tracer("MemoryStore")
+	_, span := tracer.Start(ctx, "SetRate-MemoryStore")
+	defer span.End()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.rates[rate.Currency] = rate
+	return rate, nil
+}
@@ -0,0 +1,94 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"strconv"
+
+	"github.com/adohong4/carZone/models"
+	"github.com/adohong4/carZone/tenant"
+	"go.opentelemetry.io/otel"
+)
+
+func (s *Store) InventoryReport(ctx context.Context, query models.InventoryReportQuery) ([]models.InventoryReportRow, error) {
+	tracer := otel.Tracer("MemoryStore")
+	_, span := tracer.Start(ctx, "InventoryReport-MemoryStore")
+	defer span.End()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	units := make(map[string]int64)
+	for key, vehicle := range s.vehicles {
+		if key.tenantID == tenantID &&
+			(vehicle.Status == models.VehicleInStock || vehicle.Status == models.VehicleReserved) {
+			units[vehicle.CarID.String()]++
+		}
+	}
+
+	groups := make(map[models.InventoryReportRow]*models.InventoryReportRow)
+	for _, row := range s.cars {
+		car := row.car
+		if row.tenantID != tenantID ||
+			(!query.From.IsZero() && car.CreatedAt.Before(query.From)) ||
+			(!query.To.IsZero() && !car.CreatedAt.Before(query.To)) {
+			continue
+		}
+		key := models.InventoryReportRow{Currency: car.Price.Currency}
+		if query.Groups(models.ReportByBrand) {
+			key.Brand = car.Brand
+		}
+		if query.Groups(models.ReportByFuelType) {
+			key.FuelType = car.FuelType
+		}
+		if query.Groups(models.ReportByYear) {
+			key.Year = car.Year
+		}
+		group, ok := groups[key]
+		if !ok {
+			group = &models.InventoryReportRow{
+				Brand: key.Brand, FuelType: key.FuelType, Year: key.Year, Currency: key.Currency,
+				MinPrice: car.Price, MaxPrice: car.Price,
+			}
+			group.TotalPrice.Currency = key.Currency
+			group.StockValue.Currency = key.Currency
+			groups[key] = group
+		}
+		group.Cars++
+		group.TotalPrice.Amount += car.Price.Amount
+		if car.Price.Amount < group.MinPrice.Amount {
+			group.MinPrice = car.Price
+		}
+		if car.Price.Amount > group.MaxPrice.Amount {
+			group.MaxPrice = car.Price
+		}
+		n := units[car.ID.String()]
+		group.UnitsInStock += n
+		group.StockValue.Amount += car.Price.Amount * n
+	}
+
+	report := make([]models.InventoryReportRow, 0, len(groups))
+	for _, group := range groups {
+		report = append(report, *group)
+	}
+	sort.Slice(report, func(i, j int) bool {
+		a, b := report[i], report[j]
+		switch {
+		case a.Brand != b.Brand:
+			return a.Brand < b.Brand
+		case a.FuelType != b.FuelType:
+			return a.FuelType < b.FuelType
+		case a.Year != b.Year:
+			ya, _ := strconv.Atoi(a.Year)
+			yb, _ := strconv.Atoi(b.Year)
+			return ya < yb
+		}
+		return a.Currency < b.Currency
+	})
+	return report, nil
+}
@@ -0,0 +1,190 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/adohong4/carZone/models"
+	"github.com/adohong4/carZone/store"
+	"github.com/adohong4/carZone/tenant"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+)
+
+// reservationRow remembers the dealership a reservation belongs to.
+type reservationRow struct {
+	reservation models.Reservation
+	tenantID    string
+}
+
+// CreateReservation checks and inserts under the store lock, which plays
+// the part of the car row lock.
+func (s *Store) CreateReservation(ctx context.Context, reservation models.Reservation) (models.Reservation, error) {
+	tracer := otel.Tracer("MemoryStore")
+	_, span := tracer.Start(ctx, "CreateReservation-MemoryStore")
+	defer span.End()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return models.Reservation{}, err
+	}
+	if _, ok := s.car(reservation.CarID, tenantID); !ok {
+		return models.Reservation{}, store.ErrCarNotFound
+	}
+
+	for id, row := range s.reservations {
+		if row.reservation.CarID != reservation.CarID || row.reservation.Status != models.ReservationActive {
+			continue
+		}
+		if row.reservation.ExpiresAt.After(reservation.CreatedAt) {
+			return models.Reservation{}, store.ErrCarReserved
+		}
+		// a hold past its expiry that the sweeper did not get to yet is over
+		row.reservation.Status = models.ReservationExpired
+		row.reservation.UpdatedAt = reservation.CreatedAt
+		s.reservations[id] = row
+	}
+	if err := s.checkCustomer(reservation.CustomerID, tenantID); err != nil {
+		return models.Reservation{}, err
+	}
+
+	s.reservations[reservation.ID] = reservationRow{reservation: reservation, tenantID: tenantID}
+	return reservation, nil
+}
+
+func (s *Store) GetActiveReservation(ctx context.Context, carID string, now time.Time) (models.Reservation, error) {
+	tracer := otel.Tracer("MemoryStore")
+	_, span := tracer.Start(ctx, "GetActiveReservation-MemoryStore")
+	defer span.End()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return models.Reservation{}, err
+	}
+
+	for _, row := range s.reservations {
+		if row.tenantID == tenantID && row.reservation.CarID.String() == carID &&
+			row.reservation.Status == models.ReservationActive && row.reservation.ExpiresAt.After(now) {
+			return row.reservation, nil
+		}
+	}
+	return models.Reservation{}, nil
+}
+
+func (s *Store) ListReservations(ctx context.Context, carID string) ([]models.Reservation, error) {
+	tracer := otel.Tracer("MemoryStore")
+	_, span := tracer.Start(ctx, "ListReservations-MemoryStore")
+	defer span.End()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var reservations []models.Reservation
+	for _, row := range s.reservations {
+		if row.tenantID == tenantID && row.reservation.CarID.String() == carID {
+			reservations = append(reservations, row.reservation)
+		}
+	}
+	sort.Slice(reservations, func(i, j int) bool {
+		if reservations[i].CreatedAt.Equal(reservations[j].CreatedAt) {
+			return reservations[i].ID.String() < reservations[j].ID.String()
+		}
+		return reservations[i].CreatedAt.After(reservations[j].CreatedAt)
+	})
+	return reservations, nil
+}
+
+func (s *Store) ListCustomerReservations(ctx context.Context, customerID string) ([]models.Reservation, error) {
+	tracer := otel.Tracer("MemoryStore")
+	_, span := tracer.Start(ctx, "ListCustomerReservations-MemoryStore")
+	defer span.End()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	id, err := uuid.Parse(customerID)
+	if err != nil {
+		return nil, nil
+	}
+
+	var reservations []models.Reservation
+	for _, row := range s.reservations {
+		if row.tenantID == tenantID && linkedTo(row.reservation.CustomerID, id) {
+			reservations = append(reservations, row.reservation)
+		}
+	}
+	sort.Slice(reservations, func(i, j int) bool {
+		if reservations[i].CreatedAt.Equal(reservations[j].CreatedAt) {
+			return reservations[i].ID.String() < reservations[j].ID.String()
+		}
+		return reservations[i].CreatedAt.After(reservations[j].CreatedAt)
+	})
+	return reservations, nil
+}
+
+func (s *Store) ReleaseReservation(ctx context.Context, carID, id string, now time.Time) (models.Reservation, error) {
+	tracer := otel.Tracer("MemoryStore")
+	_, span := tracer.Start(ctx, "ReleaseReservation-MemoryStore")
+	defer span.End()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return models.Reservation{}, err
+	}
+	reservationID, err := uuid.Parse(id)
+	if err != nil {
+		return models.Reservation{}, nil
+	}
+
+	row, ok := s.reservations[reservationID]
+	if !ok || row.tenantID != tenantID || row.reservation.CarID.String() != carID {
+		return models.Reservation{}, nil
+	}
+	if row.reservation.Status != models.ReservationActive || !row.reservation.ExpiresAt.After(now) {
+		return models.Reservation{}, store.ErrReservationNotActive
+	}
+
+	row.reservation.Status = models.ReservationReleased
+	row.reservation.UpdatedAt = now
+	s.reservations[reservationID] = row
+	return row.reservation, nil
+}
+
+func (s *Store) ExpireReservations(ctx context.Context, now time.Time) (int64, error) {
+	tracer := otel.Tracer("MemoryStore")
+	_, span := tracer.Start(ctx, "ExpireReservations-MemoryStore")
+	defer span.End()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var expired int64
+	for id, row := range s.reservations {
+		if row.reservation.Status == models.ReservationActive && !row.reservation.ExpiresAt.After(now) {
+			row.reservation.Status = models.ReservationExpired
+			row.reservation.UpdatedAt = now
+			s.reservations[id] = row
+			expired++
+		}
+	}
+	return expired, nil
+}
@@ -0,0 +1,54 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/adohong4/carZone/models"
+	"github.com/adohong4/carZone/tenant"
+	"go.opentelemetry.io/otel"
+)
+
+// copyRules keeps stored rules apart from what callers do with them.
+func copyRules(rules []models.ValidationRule) []models.ValidationRule {
+	copied := make([]models.ValidationRule, len(rules))
+	for i, rule := range rules {
+		rule.Values = append([]string(nil), rule.Values...)
+		copied[i] = rule
+	}
+	return copied
+}
+
+func (s *Store) GetValidationRules(ctx context.Context) ([]models.ValidationRule, error) {
+	tracer := otel.Tracer("MemoryStore")
+	_, span := tracer.Start(ctx, "GetValidationRules-MemoryStore")
+	defer span.End()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rules, ok := s.validationRules[tenantID]
+	if !ok {
+		return nil, nil
+	}
+	return copyRules(rules), nil
+}
+
+func (s *Store) SetValidationRules(ctx context.Context, rules []models.ValidationRule) ([]models.ValidationRule, error) {
+	tracer := otel.Tracer("MemoryStore")
+	_, span := tracer.Start(ctx, "SetValidationRules-MemoryStore")
+	defer span.End()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s.validationRules[tenantID] = copyRules(rules)
+	return rules, nil
+}
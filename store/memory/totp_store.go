@@ -0,0 +1,96 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/adohong4/carZone/models"
+	"github.com/adohong4/carZone/store"
+)
+
+// TOTPStore implements store.TOTPStoreInterface in process.
+type TOTPStore struct {
+	mu            sync.Mutex
+	credentials   map[string]models.TOTPCredential
+	recoveryCodes map[string]map[string]bool
+}
+
+func NewTOTPStore() *TOTPStore {
+	return &TOTPStore{
+		credentials:   make(map[string]models.TOTPCredential),
+		recoveryCodes: make(map[string]map[string]bool),
+	}
+}
+
+func (s *TOTPStore) GetTOTP(ctx context.Context, username string) (models.TOTPCredential, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.credentials[username], nil
+}
+
+func (s *TOTPStore) SaveTOTP(ctx context.Context, credential models.TOTPCredential) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.credentials[credential.UserName].Confirmed {
+		return store.ErrTOTPConfirmed
+	}
+	credential.Confirmed = false
+	s.credentials[credential.UserName] = credential
+	delete(s.recoveryCodes, credential.UserName)
+	return nil
+}
+
+func (s *TOTPStore) ConfirmTOTP(ctx context.Context, username string, step int64, recoveryCodeHashes []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	credential, ok := s.credentials[username]
+	if !ok {
+		return errors.New("totp not enrolled")
+	}
+	credential.Confirmed = true
+	credential.LastUsedStep = step
+	s.credentials[username] = credential
+
+	codes := make(map[string]bool, len(recoveryCodeHashes))
+	for _, hash := range recoveryCodeHashes {
+		codes[hash] = true
+	}
+	s.recoveryCodes[username] = codes
+	return nil
+}
+
+func (s *TOTPStore) UseTOTPStep(ctx context.Context, username string, step int64) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	credential, ok := s.credentials[username]
+	if !ok || credential.LastUsedStep >= step {
+		return false, nil
+	}
+	credential.LastUsedStep = step
+	s.credentials[username] = credential
+	return true, nil
+}
+
+func (s *TOTPStore) ConsumeRecoveryCode(ctx context.Context, username, codeHash string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.recoveryCodes[username][codeHash] {
+		return false, nil
+	}
+	delete(s.recoveryCodes[username], codeHash)
+	return true, nil
+}
+
+func (s *TOTPStore) DeleteTOTP(ctx context.Context, username string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.credentials, username)
+	delete(s.recoveryCodes, username)
+	return nil
+}
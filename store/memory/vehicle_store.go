@@ -0,0 +1,165 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/adohong4/carZone/models"
+	"github.com/adohong4/carZone/store"
+	"github.com/adohong4/carZone/tenant"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+)
+
+// vehicleKey mirrors the (tenant_id, vin) primary key.
+type vehicleKey struct {
+	tenantID string
+	vin      string
+}
+
+func (s *Store) GetVehicle(ctx context.Context, vin string) (models.Vehicle, error) {
+	tracer := otel.Tracer("MemoryStore")
+	_, span := tracer.Start(ctx, "GetVehicle-MemoryStore")
+	defer span.End()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return models.Vehicle{}, err
+	}
+	return s.vehicles[vehicleKey{tenantID, vin}], nil
+}
+
+func (s *Store) ListVehicles(ctx context.Context, filter models.VehicleFilter) ([]models.Vehicle, error) {
+	tracer := otel.Tracer("MemoryStore")
+	_, span := tracer.Start(ctx, "ListVehicles-MemoryStore")
+	defer span.End()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var vehicles []models.Vehicle
+	for key, vehicle := range s.vehicles {
+		if key.tenantID != tenantID ||
+			filter.CarID != "" && vehicle.CarID.String() != filter.CarID ||
+			filter.Status != "" && vehicle.Status != filter.Status ||
+			filter.Location != "" && vehicle.Location != filter.Location {
+			continue
+		}
+		vehicles = append(vehicles, vehicle)
+	}
+	sort.Slice(vehicles, func(i, j int) bool {
+		if vehicles[i].ReceivedAt.Equal(vehicles[j].ReceivedAt) {
+			return vehicles[i].VIN < vehicles[j].VIN
+		}
+		return vehicles[i].ReceivedAt.Before(vehicles[j].ReceivedAt)
+	})
+	return vehicles, nil
+}
+
+func (s *Store) ReceiveVehicle(ctx context.Context, vehicleReq *models.VehicleRequest) (models.Vehicle, error) {
+	tracer := otel.Tracer("MemoryStore")
+	_, span := tracer.Start(ctx, "ReceiveVehicle-MemoryStore")
+	defer span.End()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return models.Vehicle{}, err
+	}
+	if _, ok := s.car(vehicleReq.CarID, tenantID); !ok {
+		return models.Vehicle{}, store.ErrCarNotFound
+	}
+
+	key := vehicleKey{tenantID, vehicleReq.VIN}
+	if _, exists := s.vehicles[key]; exists {
+		return models.Vehicle{}, store.ErrVehicleExists
+	}
+
+	now := time.Now()
+	vehicle := models.Vehicle{
+		VIN:        vehicleReq.VIN,
+		CarID:      vehicleReq.CarID,
+		Status:     vehicleReq.Status,
+		Location:   vehicleReq.Location,
+		Mileage:    vehicleReq.Mileage,
+		Colour:     vehicleReq.Colour,
+		ReceivedAt: now,
+		UpdatedAt:  now,
+	}
+	s.vehicles[key] = vehicle
+	return vehicle, nil
+}
+
+func (s *Store) UpdateVehicle(ctx context.Context, vehicle models.Vehicle, fromStatus string) (models.Vehicle, error) {
+	tracer := otel.Tracer("MemoryStore")
+	_, span := tracer.Start(ctx, "UpdateVehicle-MemoryStore")
+	defer span.End()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return models.Vehicle{}, err
+	}
+
+	key := vehicleKey{tenantID, vehicle.VIN}
+	current, ok := s.vehicles[key]
+	if !ok || current.Status != fromStatus {
+		return models.Vehicle{}, store.ErrVehicleConflict
+	}
+
+	current.Status = vehicle.Status
+	current.Location = vehicle.Location
+	current.Mileage = vehicle.Mileage
+	current.UpdatedAt = time.Now()
+	s.vehicles[key] = current
+	return current, nil
+}
+
+func (s *Store) StockCounts(ctx context.Context, carID string) ([]models.StockCount, error) {
+	tracer := otel.Tracer("MemoryStore")
+	_, span := tracer.Start(ctx, "StockCounts-MemoryStore")
+	defer span.End()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	byCar := make(map[uuid.UUID]*models.StockCount)
+	for key, vehicle := range s.vehicles {
+		if key.tenantID != tenantID || carID != "" && vehicle.CarID.String() != carID {
+			continue
+		}
+		count, ok := byCar[vehicle.CarID]
+		if !ok {
+			count = &models.StockCount{CarID: vehicle.CarID}
+			byCar[vehicle.CarID] = count
+		}
+		count.Add(vehicle.Status, 1)
+	}
+
+	counts := make([]models.StockCount, 0, len(byCar))
+	for _, count := range byCar {
+		counts = append(counts, *count)
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		return counts[i].CarID.String() < counts[j].CarID.String()
+	})
+	return counts, nil
+}
@@ -0,0 +1,354 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/adohong4/carZone/models"
+	"github.com/adohong4/carZone/store"
+	"github.com/adohong4/carZone/tenant"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+)
+
+// webhookRow remembers the dealership a webhook belongs to.
+type webhookRow struct {
+	webhook  models.Webhook
+	tenantID string
+}
+
+// copyDelivery keeps callers from changing the stored log through the
+// slice.
+func copyDelivery(delivery models.WebhookDelivery) models.WebhookDelivery {
+	delivery.Log = append([]models.WebhookAttempt(nil), delivery.Log...)
+	return delivery
+}
+
+func (s *Store) webhook(id string, tenantID string) (webhookRow, bool) {
+	webhookID, err := uuid.Parse(id)
+	if err != nil {
+		return webhookRow{}, false
+	}
+	row, ok := s.webhooks[webhookID]
+	if !ok || row.tenantID != tenantID {
+		return webhookRow{}, false
+	}
+	return row, true
+}
+
+// delivery finds a delivery of a webhook of the dealership.
+func (s *Store) delivery(webhookID, deliveryID string, tenantID string) (models.WebhookDelivery, bool) {
+	row, ok := s.webhook(webhookID, tenantID)
+	if !ok {
+		return models.WebhookDelivery{}, false
+	}
+	id, err := uuid.Parse(deliveryID)
+	if err != nil {
+		return models.WebhookDelivery{}, false
+	}
+	delivery, ok := s.webhookDeliveries[id]
+	if !ok || delivery.WebhookID != row.webhook.ID {
+		return models.WebhookDelivery{}, false
+	}
+	return delivery, true
+}
+
+func (s *Store) CreateWebhook(ctx context.Context, webhook models.Webhook) (models.Webhook, error) {
+	tracer := otel.Tracer("MemoryStore")
+	_, span := tracer.Start(ctx, "CreateWebhook-MemoryStore")
+	defer span.End()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return models.Webhook{}, err
+	}
+
+	webhook.Events = append([]string(nil), webhook.Events...)
+	s.webhooks[webhook.ID] = webhookRow{webhook: webhook, tenantID: tenantID}
+	return webhook, nil
+}
+
+func (s *Store) ListWebhooks(ctx context.Context) ([]models.Webhook, error) {
+	tracer := otel.Tracer("MemoryStore")
+	_, span := tracer.Start(ctx, "ListWebhooks-MemoryStore")
+	defer span.End()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var webhooks []models.Webhook
+	for _, row := range s.webhooks {
+		if row.tenantID == tenantID {
+			webhooks = append(webhooks, row.webhook)
+		}
+	}
+	sort.Slice(webhooks, func(i, j int) bool {
+		if !webhooks[i].CreatedAt.Equal(webhooks[j].CreatedAt) {
+			return webhooks[i].CreatedAt.After(webhooks[j].CreatedAt)
+		}
+		return webhooks[i].ID.String() < webhooks[j].ID.String()
+	})
+	return webhooks, nil
+}
+
+func (s *Store) GetWebhook(ctx context.Context, id string) (models.Webhook, error) {
+	tracer := otel.Tracer("MemoryStore")
+	_, span := tracer.Start(ctx, "GetWebhook-MemoryStore")
+	defer span.End()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return models.Webhook{}, err
+	}
+
+	row, ok := s.webhook(id, tenantID)
+	if !ok {
+		return models.Webhook{}, store.ErrWebhookNotFound
+	}
+	return row.webhook, nil
+}
+
+func (s *Store) DeleteWebhook(ctx context.Context, id string) (models.Webhook, error) {
+	tracer := otel.Tracer("MemoryStore")
+	_, span := tracer.Start(ctx, "DeleteWebhook-MemoryStore")
+	defer span.End()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return models.Webhook{}, err
+	}
+
+	row, ok := s.webhook(id, tenantID)
+	if !ok {
+		return models.Webhook{}, store.ErrWebhookNotFound
+	}
+	delete(s.webhooks, row.webhook.ID)
+	for deliveryID, delivery := range s.webhookDeliveries {
+		if delivery.WebhookID == row.webhook.ID {
+			delete(s.webhookDeliveries, deliveryID)
+		}
+	}
+	return row.webhook, nil
+}
+
+func (s *Store) ListDeliveries(ctx context.Context, webhookID string, limit int) ([]models.WebhookDelivery, error) {
+	tracer := otel.Tracer("MemoryStore")
+	_, span := tracer.Start(ctx, "ListDeliveries-MemoryStore")
+	defer span.End()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	row, ok := s.webhook(webhookID, tenantID)
+	if !ok {
+		return nil, store.ErrWebhookNotFound
+	}
+	var deliveries []models.WebhookDelivery
+	for _, delivery := range s.webhookDeliveries {
+		if delivery.WebhookID == row.webhook.ID {
+			delivery.Log = nil
+			deliveries = append(deliveries, delivery)
+		}
+	}
+	sort.Slice(deliveries, func(i, j int) bool {
+		if !deliveries[i].CreatedAt.Equal(deliveries[j].CreatedAt) {
+			return deliveries[i].CreatedAt.After(deliveries[j].CreatedAt)
+		}
+		return deliveries[i].ID.String() < deliveries[j].ID.String()
+	})
+	if len(deliveries) > limit {
+		deliveries = deliveries[:limit]
+	}
+	return deliveries, nil
+}
+
+func (s *Store) GetDelivery(ctx context.Context, webhookID, deliveryID string) (models.WebhookDelivery, error) {
+	tracer := otel.Tracer("MemoryStore")
+	_, span := tracer.Start(ctx, "GetDelivery-MemoryStore")
+	defer span.End()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return models.WebhookDelivery{}, err
+	}
+
+	delivery, ok := s.delivery(webhookID, deliveryID, tenantID)
+	if !ok {
+		return models.WebhookDelivery{}, store.ErrDeliveryNotFound
+	}
+	return copyDelivery(delivery), nil
+}
+
+func (s *Store) RedeliverDelivery(ctx context.Context, webhookID, deliveryID string, now time.Time) (models.WebhookDelivery, error) {
+	tracer := otel.Tracer("MemoryStore")
+	_, span := tracer.Start(ctx, "RedeliverDelivery-MemoryStore")
+	defer span.End()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return models.WebhookDelivery{}, err
+	}
+
+	delivery, ok := s.delivery(webhookID, deliveryID, tenantID)
+	if !ok {
+		return models.WebhookDelivery{}, store.ErrDeliveryNotFound
+	}
+	delivery.Status = models.DeliveryPending
+	delivery.Attempts = 0
+	delivery.NextAttemptAt = &now
+	delivery.DeliveredAt = nil
+	s.webhookDeliveries[delivery.ID] = delivery
+	return copyDelivery(delivery), nil
+}
+
+func (s *Store) EnqueueDeliveries(ctx context.Context, event models.Event, now time.Time) (int, error) {
+	tracer := otel.Tracer("MemoryStore")
+	_, span := tracer.Start(ctx, "EnqueueDeliveries-MemoryStore")
+	defer span.End()
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	queued := 0
+	for _, row := range s.webhooks {
+		if row.tenantID != event.TenantID || !row.webhook.Takes(event.Type) {
+			continue
+		}
+		queuedBefore := false
+		for _, delivery := range s.webhookDeliveries {
+			if delivery.WebhookID == row.webhook.ID && delivery.EventID == event.ID {
+				queuedBefore = true
+				break
+			}
+		}
+		if queuedBefore {
+			continue
+		}
+		due := now
+		delivery := models.WebhookDelivery{
+			ID:            uuid.New(),
+			WebhookID:     row.webhook.ID,
+			EventID:       event.ID,
+			EventType:     event.Type,
+			Payload:       payload,
+			Status:        models.DeliveryPending,
+			NextAttemptAt: &due,
+			CreatedAt:     now,
+		}
+		s.webhookDeliveries[delivery.ID] = delivery
+		queued++
+	}
+	return queued, nil
+}
+
+func (s *Store) ClaimDeliveries(ctx context.Context, now, until time.Time, limit int) ([]models.PendingDelivery, error) {
+	tracer := otel.Tracer("MemoryStore")
+	_, span := tracer.Start(ctx, "ClaimDeliveries-MemoryStore")
+	defer span.End()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var due []models.WebhookDelivery
+	for _, delivery := range s.webhookDeliveries {
+		if delivery.Status == models.DeliveryPending && !delivery.NextAttemptAt.After(now) {
+			due = append(due, delivery)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		if !due[i].NextAttemptAt.Equal(*due[j].NextAttemptAt) {
+			return due[i].NextAttemptAt.Before(*due[j].NextAttemptAt)
+		}
+		return due[i].CreatedAt.Before(due[j].CreatedAt)
+	})
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	pending := make([]models.PendingDelivery, 0, len(due))
+	for _, delivery := range due {
+		claimed := until
+		delivery.NextAttemptAt = &claimed
+		s.webhookDeliveries[delivery.ID] = delivery
+		webhook := s.webhooks[delivery.WebhookID].webhook
+		pending = append(pending, models.PendingDelivery{
+			Delivery: copyDelivery(delivery),
+			URL:      webhook.URL,
+			Secret:   webhook.Secret,
+		})
+	}
+	return pending, nil
+}
+
+func (s *Store) RecordAttempt(ctx context.Context, delivery models.WebhookDelivery, attempt models.WebhookAttempt) error {
+	tracer := otel.Tracer("MemoryStore")
+	_, span := tracer.Start(ctx, "RecordAttempt-MemoryStore")
+	defer span.End()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.webhookDeliveries[delivery.ID]
+	if !ok {
+		return store.ErrDeliveryNotFound
+	}
+	stored.Status = delivery.Status
+	stored.Attempts = delivery.Attempts
+	stored.ResponseCode = delivery.ResponseCode
+	stored.Error = delivery.Error
+	stored.NextAttemptAt = delivery.NextAttemptAt
+	stored.DeliveredAt = delivery.DeliveredAt
+	stored.Log = append(stored.Log, attempt)
+	s.webhookDeliveries[delivery.ID] = stored
+	return nil
+}
+
+func (s *Store) DeleteFinishedDeliveries(ctx context.Context, before time.Time) (int64, error) {
+	tracer := otel.Tracer("MemoryStore")
+	_, span := tracer.Start(ctx, "DeleteFinishedDeliveries-MemoryStore")
+	defer span.End()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var deleted int64
+	for id, delivery := range s.webhookDeliveries {
+		if delivery.Status != models.DeliveryPending && delivery.CreatedAt.Before(before) {
+			delete(s.webhookDeliveries, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
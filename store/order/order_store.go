@@ -0,0 +1,349 @@
+package order
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/adohong4/carZone/models"
+	"github.com/adohong4/carZone/store"
+	"github.com/adohong4/carZone/tenant"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+)
+
+const orderColumns = `id, invoice_number, status, customer_id, customer_name, customer_email, customer_address, salesperson,
+	currency, tax_rate, subtotal, discount_total, tax_total, total, created_at, updated_at, confirmed_at`
+
+const lineColumns = "id, car_id, description, quantity, unit_price, discount_percent, discount, total"
+
+type Store struct {
+	db *sql.DB
+}
+
+func New(db *sql.DB) Store {
+	return Store{db: db}
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanOrder(row scanner) (models.Order, error) {
+	var (
+		order         models.Order
+		invoiceNumber sql.NullString
+		customerID    uuid.NullUUID
+		confirmedAt   sql.NullTime
+	)
+	err := row.Scan(
+		&order.ID, &invoiceNumber, &order.Status, &customerID, &order.CustomerName, &order.CustomerEmail,
+		&order.CustomerAddress, &order.Salesperson, &order.Currency, &order.TaxRate, &order.Subtotal,
+		&order.DiscountTotal, &order.TaxTotal, &order.Total, &order.CreatedAt, &order.UpdatedAt, &confirmedAt,
+	)
+	order.InvoiceNumber = invoiceNumber.String
+	if customerID.Valid {
+		order.CustomerID = &customerID.UUID
+	}
+	if confirmedAt.Valid {
+		order.ConfirmedAt = &confirmedAt.Time
+	}
+	return order, err
+}
+
+func (s Store) lines(ctx context.Context, orderID uuid.UUID) ([]models.OrderLine, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT "+lineColumns+" FROM order_line WHERE order_id = $1 ORDER BY position", orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var lines []models.OrderLine
+	for rows.Next() {
+		var line models.OrderLine
+		err := rows.Scan(&line.ID, &line.CarID, &line.Description, &line.Quantity,
+			&line.UnitPrice, &line.DiscountPercent, &line.Discount, &line.Total)
+		if err != nil {
+			return nil, err
+		}
+		lines = append(lines, line)
+	}
+	return lines, rows.Err()
+}
+
+// insertLines checks every car belongs to the dealership and writes the
+// lines in their order.
+func insertLines(ctx context.Context, tx *sql.Tx, tenantID string, order models.Order) error {
+	for i, line := range order.Lines {
+		var carID uuid.UUID
+		err := tx.QueryRowContext(ctx, "SELECT id FROM car WHERE id = $1 AND tenant_id = $2", line.CarID, tenantID).Scan(&carID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return store.ErrCarNotFound
+			}
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, `INSERT INTO order_line (id, order_id, position, car_id, description, quantity, unit_price, discount_percent, discount, total)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+			line.ID, order.ID, i, line.CarID, line.Description, line.Quantity,
+			line.UnitPrice, line.DiscountPercent, line.Discount, line.Total)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkCustomer returns ErrCustomerNotFound unless customerID is empty or a
+// customer of the dealership that has not been erased.
+func checkCustomer(ctx context.Context, tx *sql.Tx, tenantID string, customerID *uuid.UUID) error {
+	if customerID == nil {
+		return nil
+	}
+	var id uuid.UUID
+	err := tx.QueryRowContext(ctx, "SELECT id FROM customer WHERE id = $1 AND tenant_id = $2 AND erased_at IS NULL",
+		*customerID, tenantID).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return store.ErrCustomerNotFound
+	}
+	return err
+}
+
+func (s Store) CreateOrder(ctx context.Context, order models.Order) (models.Order, error) {
+	tracer := otel.Tracer("OrderStore")
+	ctx, span := tracer.Start(ctx, "CreateOrder-Store")
+	defer span.End()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return models.Order{}, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Order{}, err
+	}
+	defer tx.Rollback()
+
+	if err = checkCustomer(ctx, tx, tenantID, order.CustomerID); err != nil {
+		return models.Order{}, err
+	}
+	_, err = tx.ExecContext(ctx, `INSERT INTO sales_order (id, tenant_id, status, customer_id, customer_name, customer_email, customer_address, salesperson,
+				currency, tax_rate, subtotal, discount_total, tax_total, total, created_at, updated_at)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)`,
+		order.ID, tenantID, order.Status, order.CustomerID, order.CustomerName, order.CustomerEmail, order.CustomerAddress, order.Salesperson,
+		order.Currency, order.TaxRate, order.Subtotal, order.DiscountTotal, order.TaxTotal, order.Total, order.CreatedAt, order.UpdatedAt)
+	if err != nil {
+		return models.Order{}, err
+	}
+	if err = insertLines(ctx, tx, tenantID, order); err != nil {
+		return models.Order{}, err
+	}
+	if err = tx.Commit(); err != nil {
+		return models.Order{}, err
+	}
+	return s.GetOrder(ctx, order.ID.String())
+}
+
+func (s Store) GetOrder(ctx context.Context, id string) (models.Order, error) {
+	tracer := otel.Tracer("OrderStore")
+	ctx, span := tracer.Start(ctx, "GetOrder-Store")
+	defer span.End()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return models.Order{}, err
+	}
+
+	query := "SELECT " + orderColumns + " FROM sales_order WHERE id = $1 AND tenant_id = $2"
+	order, err := scanOrder(s.db.QueryRowContext(ctx, query, id, tenantID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Order{}, nil
+		}
+		return models.Order{}, err
+	}
+
+	order.Lines, err = s.lines(ctx, order.ID)
+	if err != nil {
+		return models.Order{}, err
+	}
+	return order, nil
+}
+
+func (s Store) ListOrders(ctx context.Context, status string) ([]models.Order, error) {
+	tracer := otel.Tracer("OrderStore")
+	ctx, span := tracer.Start(ctx, "ListOrders-Store")
+	defer span.End()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := "SELECT " + orderColumns + " FROM sales_order WHERE tenant_id = $1"
+	args := []interface{}{tenantID}
+	if status != "" {
+		query += " AND status = $2"
+		args = append(args, status)
+	}
+	query += " ORDER BY created_at DESC, id"
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var orders []models.Order
+	for rows.Next() {
+		order, err := scanOrder(rows)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, order)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
+
+// ListCustomerOrders lists the orders made for the customer with their
+// lines, the latest first.
+func (s Store) ListCustomerOrders(ctx context.Context, customerID string) ([]models.Order, error) {
+	tracer := otel.Tracer("OrderStore")
+	ctx, span := tracer.Start(ctx, "ListCustomerOrders-Store")
+	defer span.End()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := "SELECT " + orderColumns + " FROM sales_order WHERE customer_id = $1 AND tenant_id = $2 ORDER BY created_at DESC, id"
+	rows, err := s.db.QueryContext(ctx, query, customerID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var orders []models.Order
+	for rows.Next() {
+		order, err := scanOrder(rows)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, order)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	for i := range orders {
+		if orders[i].Lines, err = s.lines(ctx, orders[i].ID); err != nil {
+			return nil, err
+		}
+	}
+	return orders, nil
+}
+
+func (s Store) UpdateOrder(ctx context.Context, order models.Order) (models.Order, error) {
+	tracer := otel.Tracer("OrderStore")
+	ctx, span := tracer.Start(ctx, "UpdateOrder-Store")
+	defer span.End()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return models.Order{}, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Order{}, err
+	}
+	defer tx.Rollback()
+
+	if err = checkCustomer(ctx, tx, tenantID, order.CustomerID); err != nil {
+		return models.Order{}, err
+	}
+	result, err := tx.ExecContext(ctx, `UPDATE sales_order
+				SET customer_id = $1, customer_name = $2, customer_email = $3, customer_address = $4, currency = $5, tax_rate = $6,
+					subtotal = $7, discount_total = $8, tax_total = $9, total = $10, updated_at = $11
+				WHERE id = $12 AND tenant_id = $13 AND status = $14`,
+		order.CustomerID, order.CustomerName, order.CustomerEmail, order.CustomerAddress, order.Currency, order.TaxRate,
+		order.Subtotal, order.DiscountTotal, order.TaxTotal, order.Total, order.UpdatedAt,
+		order.ID, tenantID, models.OrderDraft)
+	if err != nil {
+		return models.Order{}, err
+	}
+	if updated, err := result.RowsAffected(); err != nil {
+		return models.Order{}, err
+	} else if updated == 0 {
+		return models.Order{}, store.ErrOrderConflict
+	}
+
+	if _, err = tx.ExecContext(ctx, "DELETE FROM order_line WHERE order_id = $1", order.ID); err != nil {
+		return models.Order{}, err
+	}
+	if err = insertLines(ctx, tx, tenantID, order); err != nil {
+		return models.Order{}, err
+	}
+	if err = tx.Commit(); err != nil {
+		return models.Order{}, err
+	}
+	return s.GetOrder(ctx, order.ID.String())
+}
+
+// UpdateOrderStatus takes the invoice number in the same transaction as the
+// confirmation, the counter row stays locked until the commit so numbers
+// are handed out in order and never skipped.
+func (s Store) UpdateOrderStatus(ctx context.Context, id, fromStatus, toStatus string, now time.Time) (models.Order, error) {
+	tracer := otel.Tracer("OrderStore")
+	ctx, span := tracer.Start(ctx, "UpdateOrderStatus-Store")
+	defer span.End()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return models.Order{}, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Order{}, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, "UPDATE sales_order SET status = $1, updated_at = $2 WHERE id = $3 AND tenant_id = $4 AND status = $5",
+		toStatus, now, id, tenantID, fromStatus)
+	if err != nil {
+		return models.Order{}, err
+	}
+	if updated, err := result.RowsAffected(); err != nil {
+		return models.Order{}, err
+	} else if updated == 0 {
+		return models.Order{}, store.ErrOrderConflict
+	}
+
+	if toStatus == models.OrderConfirmed {
+		var number int64
+		err = tx.QueryRowContext(ctx, `INSERT INTO invoice_sequence (tenant_id, last_number) VALUES ($1, 1)
+				ON CONFLICT (tenant_id) DO UPDATE SET last_number = invoice_sequence.last_number + 1
+				RETURNING last_number`, tenantID).Scan(&number)
+		if err != nil {
+			return models.Order{}, err
+		}
+
+		_, err = tx.ExecContext(ctx, "UPDATE sales_order SET invoice_number = $1, confirmed_at = $2 WHERE id = $3",
+			models.InvoiceNumber(number), now, id)
+		if err != nil {
+			return models.Order{}, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return models.Order{}, err
+	}
+	return s.GetOrder(ctx, id)
+}
@@ -0,0 +1,156 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"time"
+
+	"github.com/adohong4/carZone/models"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"go.opentelemetry.io/otel"
+)
+
+// relayLock is the advisory lock key that keeps relays of several
+// instances from claiming the same events at once.
+const relayLock = 0x6f7574626f78
+
+// Record writes event to the outbox within tx, the transaction of the
+// change it is about. Writers of one entity hold its row lock until they
+// commit, so the entity's events commit in sequence order.
+func Record(ctx context.Context, tx *sql.Tx, event models.Event) error {
+	_, err := tx.ExecContext(ctx,
+		`INSERT INTO outbox_event (id, tenant_id, type, entity_id, data, occurred_at)
+		VALUES ($1, $2, $3, $4, $5, $6)`,
+		event.ID, event.TenantID, event.Type, event.EntityID, []byte(event.Data), event.OccurredAt)
+	return err
+}
+
+// RecordNew builds an event with models.NewEvent and records it.
+func RecordNew(ctx context.Context, tx *sql.Tx, eventType, tenantID string, entityID uuid.UUID, data interface{}) error {
+	event, err := models.NewEvent(eventType, tenantID, entityID, data)
+	if err != nil {
+		return err
+	}
+	return Record(ctx, tx, event)
+}
+
+type Store struct {
+	db *sql.DB
+}
+
+func New(db *sql.DB) Store {
+	return Store{db: db}
+}
+
+// ClaimEvents takes the advisory lock for the length of its short
+// transaction only, it keeps claims of several instances from interleaving
+// while the events themselves are published without a transaction open.
+func (s Store) ClaimEvents(ctx context.Context, now, until time.Time, limit int) ([]models.PendingEvent, error) {
+	tracer := otel.Tracer("OutboxStore")
+	ctx, span := tracer.Start(ctx, "ClaimEvents-Store")
+	defer span.End()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err = tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock($1)", relayLock); err != nil {
+		return nil, err
+	}
+	rows, err := tx.QueryContext(ctx,
+		`UPDATE outbox_event SET next_attempt_at = $1 WHERE sequence IN (
+			SELECT o.sequence FROM outbox_event o
+			WHERE o.published_at IS NULL AND (o.next_attempt_at IS NULL OR o.next_attempt_at <= $2)
+			AND NOT EXISTS (
+				SELECT 1 FROM outbox_event e
+				WHERE e.entity_id = o.entity_id AND e.sequence < o.sequence
+				AND e.published_at IS NULL AND e.next_attempt_at > $2
+			)
+			ORDER BY o.sequence LIMIT $3
+		)
+		RETURNING sequence, id, tenant_id, type, entity_id, data, occurred_at, attempts`,
+		until.UTC(), now.UTC(), limit)
+	if err != nil {
+		return nil, err
+	}
+	var pending []models.PendingEvent
+	for rows.Next() {
+		var (
+			next models.PendingEvent
+			data []byte
+		)
+		event := &next.Event
+		if err := rows.Scan(&event.Sequence, &event.ID, &event.TenantID, &event.Type, &event.EntityID, &data, &event.OccurredAt, &next.Attempts); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		event.Data = data
+		pending = append(pending, next)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	sort.Slice(pending, func(i, j int) bool { return pending[i].Event.Sequence < pending[j].Event.Sequence })
+	return pending, nil
+}
+
+func (s Store) FinishEvents(ctx context.Context, results []models.EventResult, now time.Time) (int, error) {
+	tracer := otel.Tracer("OutboxStore")
+	ctx, span := tracer.Start(ctx, "FinishEvents-Store")
+	defer span.End()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var published []int64
+	for _, result := range results {
+		switch {
+		case result.Published:
+			published = append(published, result.Sequence)
+		case result.Error != "":
+			_, err = tx.ExecContext(ctx,
+				"UPDATE outbox_event SET attempts = attempts + 1, last_error = $2, next_attempt_at = $3 WHERE sequence = $1",
+				result.Sequence, result.Error, result.RetryAt.UTC())
+		default:
+			_, err = tx.ExecContext(ctx, "UPDATE outbox_event SET next_attempt_at = $2 WHERE sequence = $1",
+				result.Sequence, result.RetryAt.UTC())
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+	if len(published) > 0 {
+		_, err = tx.ExecContext(ctx, "UPDATE outbox_event SET published_at = $2 WHERE sequence = ANY($1)",
+			pq.Array(published), now.UTC())
+		if err != nil {
+			return 0, err
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		return 0, err
+	}
+	return len(published), nil
+}
+
+func (s Store) DeletePublishedEvents(ctx context.Context, before time.Time) (int64, error) {
+	tracer := otel.Tracer("OutboxStore")
+	ctx, span := tracer.Start(ctx, "DeletePublishedEvents-Store")
+	defer span.End()
+
+	result, err := s.db.ExecContext(ctx, "DELETE FROM outbox_event WHERE published_at < $1", before.UTC())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
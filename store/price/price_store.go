@@ -0,0 +1,266 @@
+package price
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/adohong4/carZone/models"
+	"github.com/adohong4/carZone/store"
+	"github.com/adohong4/carZone/store/outbox"
+	"github.com/adohong4/carZone/tenant"
+	"go.opentelemetry.io/otel"
+)
+
+const (
+	carPriceColumns  = "id, car_id, price_minor, currency, effective_from, effective_to, created_by, created_at"
+	promotionColumns = "id, name, kind, percent, amount_minor, currency, brand, fuel_type, starts_at, ends_at, created_at"
+)
+
+type Store struct {
+	db *sql.DB
+}
+
+func New(db *sql.DB) Store {
+	return Store{db: db}
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanCarPrice(row scanner) (models.CarPrice, error) {
+	var price models.CarPrice
+	err := row.Scan(
+		&price.ID, &price.CarID, &price.Price.Amount, &price.Price.Currency,
+		&price.EffectiveFrom, &price.EffectiveTo, &price.CreatedBy, &price.CreatedAt,
+	)
+	return price, err
+}
+
+func scanPromotion(row scanner) (models.Promotion, error) {
+	var promotion models.Promotion
+	err := row.Scan(
+		&promotion.ID, &promotion.Name, &promotion.Kind, &promotion.Percent,
+		&promotion.Amount.Amount, &promotion.Amount.Currency, &promotion.Brand, &promotion.FuelType,
+		&promotion.StartsAt, &promotion.EndsAt, &promotion.CreatedAt,
+	)
+	return promotion, err
+}
+
+func (s Store) ListCarPrices(ctx context.Context, carID string) ([]models.CarPrice, error) {
+	tracer := otel.Tracer("PriceStore")
+	ctx, span := tracer.Start(ctx, "ListCarPrices-Store")
+	defer span.End()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT "+carPriceColumns+" FROM car_price WHERE car_id = $1 AND tenant_id = $2 ORDER BY effective_from, created_at",
+		carID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var prices []models.CarPrice
+	for rows.Next() {
+		price, err := scanCarPrice(rows)
+		if err != nil {
+			return nil, err
+		}
+		prices = append(prices, price)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return prices, nil
+}
+
+func (s Store) AddCarPrice(ctx context.Context, price models.CarPrice) (models.CarPrice, error) {
+	tracer := otel.Tracer("PriceStore")
+	ctx, span := tracer.Start(ctx, "AddCarPrice-Store")
+	defer span.End()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return models.CarPrice{}, err
+	}
+
+	query := `INSERT INTO car_price (id, tenant_id, car_id, price_minor, currency, effective_from, effective_to, created_by, created_at)
+				SELECT $1, $2, id, $4, $5, $6, $7, $8, $9 FROM car WHERE id = $3 AND tenant_id = $2
+				RETURNING ` + carPriceColumns
+	added, err := scanCarPrice(s.db.QueryRowContext(ctx, query,
+		price.ID, tenantID, price.CarID, price.Price.Amount, price.Price.Currency,
+		price.EffectiveFrom, price.EffectiveTo, price.CreatedBy, price.CreatedAt,
+	))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.CarPrice{}, store.ErrCarNotFound
+		}
+		return models.CarPrice{}, err
+	}
+	return added, nil
+}
+
+func (s Store) DeleteCarPrice(ctx context.Context, carID, id string, now time.Time) (models.CarPrice, error) {
+	tracer := otel.Tracer("PriceStore")
+	ctx, span := tracer.Start(ctx, "DeleteCarPrice-Store")
+	defer span.End()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return models.CarPrice{}, err
+	}
+
+	deleted, err := scanCarPrice(s.db.QueryRowContext(ctx,
+		"DELETE FROM car_price WHERE id = $1 AND car_id = $2 AND tenant_id = $3 AND effective_from > $4 RETURNING "+carPriceColumns,
+		id, carID, tenantID, now))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.CarPrice{}, nil
+		}
+		return models.CarPrice{}, err
+	}
+	return deleted, nil
+}
+
+// ApplyCarPrices picks the price in effect per car, latest start first,
+// and copies it onto cars that still show another price.
+func (s Store) ApplyCarPrices(ctx context.Context, now time.Time) ([]models.RepricedCar, error) {
+	tracer := otel.Tracer("PriceStore")
+	ctx, span := tracer.Start(ctx, "ApplyCarPrices-Store")
+	defer span.End()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query := `UPDATE car SET price_minor = effective.price_minor, currency = effective.currency, updated_at = $1
+			FROM (
+				SELECT DISTINCT ON (car_id) car_id, price_minor, currency
+				FROM car_price
+				WHERE effective_from <= $1 AND (effective_to IS NULL OR effective_to > $1)
+				ORDER BY car_id, effective_from DESC, created_at DESC
+			) effective
+			WHERE car.id = effective.car_id AND (car.price_minor <> effective.price_minor OR car.currency <> effective.currency)
+			RETURNING car.tenant_id, car.id, car.name, car.year, car.brand, car.fuel_type, car.engine_id,
+				car.price_minor, car.currency, car.created_at, car.updated_at`
+
+	rows, err := tx.QueryContext(ctx, query, now)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		tenantIDs []string
+		repriced  []models.Car
+	)
+	for rows.Next() {
+		var (
+			tenantID string
+			car      models.Car
+		)
+		err := rows.Scan(&tenantID, &car.ID, &car.Name, &car.Year, &car.Brand, &car.FuelType, &car.Engine.EngineID,
+			&car.Price.Amount, &car.Price.Currency, &car.CreatedAt, &car.UpdatedAt)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		tenantIDs = append(tenantIDs, tenantID)
+		repriced = append(repriced, car)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	applied := make([]models.RepricedCar, 0, len(repriced))
+	for i, car := range repriced {
+		if err = outbox.RecordNew(ctx, tx, models.EventCarUpdated, tenantIDs[i], car.ID, car); err != nil {
+			return nil, err
+		}
+		applied = append(applied, models.RepricedCar{TenantID: tenantIDs[i], CarID: car.ID})
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	return applied, nil
+}
+
+func (s Store) ListPromotions(ctx context.Context) ([]models.Promotion, error) {
+	tracer := otel.Tracer("PriceStore")
+	ctx, span := tracer.Start(ctx, "ListPromotions-Store")
+	defer span.End()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT "+promotionColumns+" FROM promotion WHERE tenant_id = $1 ORDER BY starts_at, created_at", tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var promotions []models.Promotion
+	for rows.Next() {
+		promotion, err := scanPromotion(rows)
+		if err != nil {
+			return nil, err
+		}
+		promotions = append(promotions, promotion)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return promotions, nil
+}
+
+func (s Store) CreatePromotion(ctx context.Context, promotion models.Promotion) (models.Promotion, error) {
+	tracer := otel.Tracer("PriceStore")
+	ctx, span := tracer.Start(ctx, "CreatePromotion-Store")
+	defer span.End()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return models.Promotion{}, err
+	}
+
+	query := `INSERT INTO promotion (id, tenant_id, name, kind, percent, amount_minor, currency, brand, fuel_type, starts_at, ends_at, created_at)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+				RETURNING ` + promotionColumns
+	return scanPromotion(s.db.QueryRowContext(ctx, query,
+		promotion.ID, tenantID, promotion.Name, promotion.Kind, promotion.Percent,
+		promotion.Amount.Amount, promotion.Amount.Currency, promotion.Brand, promotion.FuelType,
+		promotion.StartsAt, promotion.EndsAt, promotion.CreatedAt,
+	))
+}
+
+func (s Store) DeletePromotion(ctx context.Context, id string) (models.Promotion, error) {
+	tracer := otel.Tracer("PriceStore")
+	ctx, span := tracer.Start(ctx, "DeletePromotion-Store")
+	defer span.End()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return models.Promotion{}, err
+	}
+
+	deleted, err := scanPromotion(s.db.QueryRowContext(ctx,
+		"DELETE FROM promotion WHERE id = $1 AND tenant_id = $2 RETURNING "+promotionColumns, id, tenantID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Promotion{}, nil
+		}
+		return models.Promotion{}, err
+	}
+	return deleted, nil
+}
@@ -0,0 +1,57 @@
+package rate
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/adohong4/carZone/models"
+	"go.opentelemetry.io/otel"
+)
+
+type Store struct {
+	db *sql.DB
+}
+
+func New(db *sql.DB) Store {
+	return Store{db: db}
+}
+
+func (s Store) GetRates(ctx context.Context) ([]models.ExchangeRate, error) {
+	tracer := otel.Tracer("RateStore")
+	ctx, span := tracer.Start(ctx, "GetRates-Store")
+	defer span.End()
+
+	rows, err := s.db.QueryContext(ctx, "SELECT currency, rate, updated_at FROM exchange_rate ORDER BY currency")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rates []models.ExchangeRate
+	for rows.Next() {
+		var rate models.ExchangeRate
+		if err := rows.Scan(&rate.Currency, &rate.Rate, &rate.UpdatedAt); err != nil {
+			return nil, err
+		}
+		rates = append(rates, rate)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return rates, nil
+}
+
+func (s Store) SetRate(ctx context.Context, rate models.ExchangeRate) (models.ExchangeRate, error) {
+	tracer := otel.Tracer("RateStore")
+	ctx, span := tracer.Start(ctx, "SetRate-Store")
+	defer span.End()
+
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO exchange_rate (currency, rate, updated_at) VALUES ($1, $2, $3)
+		ON CONFLICT (currency) DO UPDATE SET rate = EXCLUDED.rate, updated_at = EXCLUDED.updated_at`,
+		rate.Currency, rate.Rate, rate.UpdatedAt)
+	if err != nil {
+		return models.ExchangeRate{}, err
+	}
+	return rate, nil
+}
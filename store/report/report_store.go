@@ -0,0 +1,104 @@
+package report
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/adohong4/carZone/models"
+	"github.com/adohong4/carZone/tenant"
+	"go.opentelemetry.io/otel"
+)
+
+// dimensionColumns maps the group by dimensions to the car columns.
+var dimensionColumns = map[string]string{
+	models.ReportByBrand:    "c.brand",
+	models.ReportByFuelType: "c.fuel_type",
+	models.ReportByYear:     "c.year",
+}
+
+type Store struct {
+	db *sql.DB
+}
+
+func New(db *sql.DB) Store {
+	return Store{db: db}
+}
+
+func (s Store) InventoryReport(ctx context.Context, query models.InventoryReportQuery) ([]models.InventoryReportRow, error) {
+	tracer := otel.Tracer("ReportStore")
+	ctx, span := tracer.Start(ctx, "InventoryReport-Store")
+	defer span.End()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var columns []string
+	for _, dimension := range query.GroupBy {
+		columns = append(columns, dimensionColumns[dimension])
+	}
+	columns = append(columns, "c.currency")
+	groups := strings.Join(columns, ", ")
+
+	where := "c.tenant_id = $1"
+	args := []interface{}{tenantID, models.VehicleInStock, models.VehicleReserved}
+	if !query.From.IsZero() {
+		args = append(args, query.From)
+		where += fmt.Sprintf(" AND c.created_at >= $%d", len(args))
+	}
+	if !query.To.IsZero() {
+		args = append(args, query.To)
+		where += fmt.Sprintf(" AND c.created_at < $%d", len(args))
+	}
+
+	sqlQuery := `SELECT ` + groups + `, COUNT(*), SUM(c.price_minor), MIN(c.price_minor), MAX(c.price_minor),
+				COALESCE(SUM(v.units), 0), COALESCE(SUM(c.price_minor * v.units), 0)
+			FROM car c
+			LEFT JOIN (
+				SELECT car_id, COUNT(*) AS units FROM vehicle
+				WHERE tenant_id = $1 AND status IN ($2, $3)
+				GROUP BY car_id
+			) v ON v.car_id = c.id
+			WHERE ` + where + `
+			GROUP BY ` + groups + `
+			ORDER BY ` + groups
+
+	rows, err := s.db.QueryContext(ctx, sqlQuery, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var report []models.InventoryReportRow
+	for rows.Next() {
+		var row models.InventoryReportRow
+		dest := make([]interface{}, 0, len(query.GroupBy)+7)
+		for _, dimension := range query.GroupBy {
+			switch dimension {
+			case models.ReportByBrand:
+				dest = append(dest, &row.Brand)
+			case models.ReportByFuelType:
+				dest = append(dest, &row.FuelType)
+			case models.ReportByYear:
+				dest = append(dest, &row.Year)
+			}
+		}
+		dest = append(dest, &row.Currency, &row.Cars, &row.TotalPrice.Amount, &row.MinPrice.Amount,
+			&row.MaxPrice.Amount, &row.UnitsInStock, &row.StockValue.Amount)
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		row.TotalPrice.Currency = row.Currency
+		row.MinPrice.Currency = row.Currency
+		row.MaxPrice.Currency = row.Currency
+		row.StockValue.Currency = row.Currency
+		report = append(report, row)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
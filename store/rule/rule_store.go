@@ -0,0 +1,93 @@
+package rule
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/adohong4/carZone/models"
+	"github.com/adohong4/carZone/tenant"
+	"go.opentelemetry.io/otel"
+)
+
+type Store struct {
+	db *sql.DB
+}
+
+func New(db *sql.DB) Store {
+	return Store{db: db}
+}
+
+func (s Store) GetValidationRules(ctx context.Context) ([]models.ValidationRule, error) {
+	tracer := otel.Tracer("RuleStore")
+	ctx, span := tracer.Start(ctx, "GetValidationRules-Store")
+	defer span.End()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT definition FROM validation_rule WHERE tenant_id = $1 ORDER BY position", tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rules []models.ValidationRule
+	for rows.Next() {
+		var (
+			definition []byte
+			rule       models.ValidationRule
+		)
+		if err := rows.Scan(&definition); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(definition, &rule); err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+func (s Store) SetValidationRules(ctx context.Context, rules []models.ValidationRule) ([]models.ValidationRule, error) {
+	tracer := otel.Tracer("RuleStore")
+	ctx, span := tracer.Start(ctx, "SetValidationRules-Store")
+	defer span.End()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err = tx.ExecContext(ctx, "DELETE FROM validation_rule WHERE tenant_id = $1", tenantID); err != nil {
+		return nil, err
+	}
+	for i, rule := range rules {
+		definition, err := json.Marshal(rule)
+		if err != nil {
+			return nil, err
+		}
+		_, err = tx.ExecContext(ctx,
+			"INSERT INTO validation_rule (tenant_id, id, position, definition) VALUES ($1, $2, $3, $4)",
+			tenantID, rule.ID, i, definition)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
@@ -1,26 +1,60 @@
--- -- Kích hoạt tiện ích mở rộng uuid-ossp để hỗ trợ kiểu UUID
--- CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
-
--- -- Tạo bảng engine
--- CREATE TABLE IF NOT EXISTS engine (
---     id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
---     displacement BIGINT NOT NULL,
---     no_of_cylinders BIGINT NOT NULL,
---     car_range BIGINT NOT NULL
--- );
-
--- -- Tạo bảng car
--- CREATE TABLE IF NOT EXISTS car (
---     id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
---     name VARCHAR(100) NOT NULL,
---     year INTEGER NOT NULL,
---     brand VARCHAR(100) NOT NULL,
---     fuel_type VARCHAR(50) NOT NULL,
---     engine_id UUID REFERENCES engine(id),
---     price NUMERIC NOT NULL,
---     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
---     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
--- );
+-- Kích hoạt tiện ích mở rộng uuid-ossp để hỗ trợ kiểu UUID
+CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
+
+-- Dealerships (tenants); every car and engine belongs to one
+CREATE TABLE IF NOT EXISTS dealership (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name VARCHAR(100) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Existing data and tokens without a tenant belong to the default dealership
+INSERT INTO dealership (id, name)
+VALUES ('00000000-0000-0000-0000-000000000001', 'Default dealership')
+ON CONFLICT (id) DO NOTHING;
+
+-- Tạo bảng engine
+CREATE TABLE IF NOT EXISTS engine (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    displacement BIGINT NOT NULL,
+    no_of_cylinders BIGINT NOT NULL,
+    car_range BIGINT NOT NULL
+);
+
+-- Tạo bảng car
+CREATE TABLE IF NOT EXISTS car (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name VARCHAR(100) NOT NULL,
+    year INTEGER NOT NULL,
+    brand VARCHAR(100) NOT NULL,
+    fuel_type VARCHAR(50) NOT NULL,
+    engine_id UUID REFERENCES engine(id),
+    price_minor BIGINT NOT NULL,
+    currency CHAR(3) NOT NULL DEFAULT 'USD',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE engine ADD COLUMN IF NOT EXISTS tenant_id UUID NOT NULL
+    DEFAULT '00000000-0000-0000-0000-000000000001' REFERENCES dealership(id);
+ALTER TABLE car ADD COLUMN IF NOT EXISTS tenant_id UUID NOT NULL
+    DEFAULT '00000000-0000-0000-0000-000000000001' REFERENCES dealership(id);
+CREATE INDEX IF NOT EXISTS car_tenant_brand_idx ON car (tenant_id, brand);
+CREATE INDEX IF NOT EXISTS car_tenant_created_idx ON car (tenant_id, created_at);
+
+-- Prices used to be a NUMERIC of dollars, they are now whole minor units
+-- (cents, dong, ...) of the car's ISO 4217 currency
+ALTER TABLE car ADD COLUMN IF NOT EXISTS currency CHAR(3) NOT NULL DEFAULT 'USD';
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'car' AND column_name = 'price') THEN
+        ALTER TABLE car ADD COLUMN IF NOT EXISTS price_minor BIGINT;
+        UPDATE car SET price_minor = ROUND(price * 100);
+        ALTER TABLE car ALTER COLUMN price_minor SET NOT NULL;
+        ALTER TABLE car DROP COLUMN price;
+    END IF;
+END $$;
+CREATE INDEX IF NOT EXISTS engine_tenant_idx ON engine (tenant_id);
 
 -- -- Thêm dữ liệu mẫu vào bảng engine (tùy chọn để thử nghiệm)
 -- INSERT INTO engine (id, displacement, no_of_cylinders, car_range) 
@@ -53,4 +87,451 @@
 --     22000.00, 
 --     CURRENT_TIMESTAMP, 
 --     CURRENT_TIMESTAMP 
--- FROM engine WHERE displacement = 3000;
\ No newline at end of file
+-- FROM engine WHERE displacement = 3000;
+-- Token buckets of the shared rate limiter (RATE_LIMIT_STORE=postgres)
+CREATE TABLE IF NOT EXISTS rate_limit_bucket (
+    key TEXT PRIMARY KEY,
+    tokens DOUBLE PRECISION NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL
+);
+
+-- Audit trail of login attempts
+CREATE TABLE IF NOT EXISTS login_attempt (
+    id BIGSERIAL PRIMARY KEY,
+    username VARCHAR(100) NOT NULL,
+    ip VARCHAR(64) NOT NULL,
+    success BOOLEAN NOT NULL,
+    reason VARCHAR(100) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_attempt_username ON login_attempt (username, created_at);
+
+-- Failed-attempt counters and lockouts, subject is "user:<name>" or "ip:<address>"
+CREATE TABLE IF NOT EXISTS login_lockout (
+    subject VARCHAR(200) PRIMARY KEY,
+    failed_attempts INTEGER NOT NULL DEFAULT 0,
+    lockouts INTEGER NOT NULL DEFAULT 0,
+    locked_until TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- TOTP second factor, secret is base32 and only active once confirmed
+CREATE TABLE IF NOT EXISTS user_totp (
+    username VARCHAR(100) PRIMARY KEY,
+    secret VARCHAR(64) NOT NULL,
+    confirmed BOOLEAN NOT NULL DEFAULT FALSE,
+    last_used_step BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Single-use recovery codes, stored as SHA-256 hashes
+CREATE TABLE IF NOT EXISTS user_recovery_code (
+    username VARCHAR(100) NOT NULL REFERENCES user_totp(username) ON DELETE CASCADE,
+    code_hash CHAR(64) NOT NULL,
+    used_at TIMESTAMPTZ,
+    PRIMARY KEY (username, code_hash)
+);
+
+-- Physical units of a car model, keyed by VIN within a dealership
+CREATE TABLE IF NOT EXISTS vehicle (
+    vin VARCHAR(17) NOT NULL,
+    tenant_id UUID NOT NULL REFERENCES dealership(id),
+    car_id UUID NOT NULL REFERENCES car(id),
+    status VARCHAR(20) NOT NULL,
+    location VARCHAR(100) NOT NULL,
+    mileage BIGINT NOT NULL DEFAULT 0,
+    colour VARCHAR(50) NOT NULL,
+    received_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (tenant_id, vin)
+);
+
+CREATE INDEX IF NOT EXISTS vehicle_tenant_car_status_idx ON vehicle (tenant_id, car_id, status);
+
+CREATE TABLE IF NOT EXISTS reservation (
+    id UUID PRIMARY KEY,
+    tenant_id UUID NOT NULL REFERENCES dealership(id),
+    car_id UUID NOT NULL REFERENCES car(id) ON DELETE CASCADE,
+    customer_name VARCHAR(255) NOT NULL,
+    customer_contact VARCHAR(255) NOT NULL,
+    reserved_by VARCHAR(255) NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- a car has at most one active hold, the row lock on the car orders the
+-- reservations and this index backs it up
+CREATE UNIQUE INDEX IF NOT EXISTS reservation_active_car_idx ON reservation (car_id) WHERE status = 'active';
+CREATE INDEX IF NOT EXISTS reservation_active_expiry_idx ON reservation (expires_at) WHERE status = 'active';
+
+-- "order" is a reserved word, orders live in sales_order
+CREATE TABLE IF NOT EXISTS sales_order (
+    id UUID PRIMARY KEY,
+    tenant_id UUID NOT NULL REFERENCES dealership(id),
+    invoice_number VARCHAR(20),
+    status VARCHAR(20) NOT NULL,
+    customer_name VARCHAR(255) NOT NULL,
+    customer_email VARCHAR(255) NOT NULL,
+    customer_address TEXT NOT NULL,
+    salesperson VARCHAR(255) NOT NULL,
+    tax_rate NUMERIC(7,4) NOT NULL,
+    subtotal NUMERIC(15,3) NOT NULL,
+    discount_total NUMERIC(15,3) NOT NULL,
+    tax_total NUMERIC(15,3) NOT NULL,
+    total NUMERIC(15,3) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    confirmed_at TIMESTAMP,
+    UNIQUE (tenant_id, invoice_number)
+);
+
+CREATE INDEX IF NOT EXISTS sales_order_tenant_status_idx ON sales_order (tenant_id, status);
+
+CREATE TABLE IF NOT EXISTS order_line (
+    id UUID PRIMARY KEY,
+    order_id UUID NOT NULL REFERENCES sales_order(id) ON DELETE CASCADE,
+    position INT NOT NULL,
+    car_id UUID NOT NULL REFERENCES car(id),
+    description VARCHAR(255) NOT NULL,
+    quantity BIGINT NOT NULL,
+    unit_price NUMERIC(15,3) NOT NULL,
+    discount_percent NUMERIC(7,4) NOT NULL,
+    discount NUMERIC(15,3) NOT NULL,
+    total NUMERIC(15,3) NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS order_line_order_idx ON order_line (order_id, position);
+
+-- invoice numbers are sequential per dealership without gaps, a sequence
+-- would skip the numbers of rolled back transactions
+CREATE TABLE IF NOT EXISTS invoice_sequence (
+    tenant_id UUID PRIMARY KEY REFERENCES dealership(id),
+    last_number BIGINT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS customer (
+    id UUID PRIMARY KEY,
+    tenant_id UUID NOT NULL REFERENCES dealership(id),
+    name VARCHAR(255) NOT NULL,
+    email VARCHAR(255) NOT NULL,
+    phone VARCHAR(50) NOT NULL,
+    address TEXT NOT NULL,
+    marketing_consent BOOLEAN NOT NULL DEFAULT false,
+    contact_consent BOOLEAN NOT NULL DEFAULT false,
+    consent_updated_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    erased_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS customer_tenant_name_idx ON customer (tenant_id, lower(name));
+
+CREATE TABLE IF NOT EXISTS customer_note (
+    id UUID PRIMARY KEY,
+    customer_id UUID NOT NULL REFERENCES customer(id) ON DELETE CASCADE,
+    body TEXT NOT NULL,
+    author VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS customer_note_customer_idx ON customer_note (customer_id, created_at);
+
+-- cars a customer viewed, reserved or purchased, once per kind
+CREATE TABLE IF NOT EXISTS customer_car (
+    customer_id UUID NOT NULL REFERENCES customer(id) ON DELETE CASCADE,
+    car_id UUID NOT NULL REFERENCES car(id) ON DELETE CASCADE,
+    kind VARCHAR(20) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (customer_id, car_id, kind)
+);
+
+ALTER TABLE sales_order ADD COLUMN IF NOT EXISTS currency CHAR(3) NOT NULL DEFAULT 'USD';
+
+-- order amounts were NUMERIC(14,2), which cut the third decimal of BHD, KWD
+-- and the other 3 minor unit currencies; widening keeps every stored value
+ALTER TABLE sales_order
+    ALTER COLUMN subtotal TYPE NUMERIC(15,3),
+    ALTER COLUMN discount_total TYPE NUMERIC(15,3),
+    ALTER COLUMN tax_total TYPE NUMERIC(15,3),
+    ALTER COLUMN total TYPE NUMERIC(15,3);
+ALTER TABLE order_line
+    ALTER COLUMN unit_price TYPE NUMERIC(15,3),
+    ALTER COLUMN discount TYPE NUMERIC(15,3),
+    ALTER COLUMN total TYPE NUMERIC(15,3);
+
+-- reservations and orders made for a customer record, erasing the customer
+-- blanks their customer columns too
+ALTER TABLE reservation ADD COLUMN IF NOT EXISTS customer_id UUID REFERENCES customer(id) ON DELETE SET NULL;
+ALTER TABLE sales_order ADD COLUMN IF NOT EXISTS customer_id UUID REFERENCES customer(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS reservation_customer_idx ON reservation (customer_id) WHERE customer_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS sales_order_customer_idx ON sales_order (customer_id) WHERE customer_id IS NOT NULL;
+
+-- Units of each currency per unit of the base currency (CURRENCY_BASE),
+-- used to show prices in the currency a client asks for
+CREATE TABLE IF NOT EXISTS exchange_rate (
+    currency CHAR(3) PRIMARY KEY,
+    rate NUMERIC(24,10) NOT NULL CHECK (rate > 0),
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- List prices of cars over time. When ranges overlap the price that started
+-- last applies; car.price_minor is kept in step by the price scheduler.
+CREATE TABLE IF NOT EXISTS car_price (
+    id UUID PRIMARY KEY,
+    tenant_id UUID NOT NULL REFERENCES dealership(id),
+    car_id UUID NOT NULL REFERENCES car(id) ON DELETE CASCADE,
+    price_minor BIGINT NOT NULL,
+    currency CHAR(3) NOT NULL,
+    effective_from TIMESTAMP NOT NULL,
+    effective_to TIMESTAMP,
+    created_by VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS car_price_car_idx ON car_price (car_id, effective_from);
+
+-- cars from before the price history start it with their current price,
+-- the car ID doubles as the ID of that first price
+INSERT INTO car_price (id, tenant_id, car_id, price_minor, currency, effective_from, created_at)
+SELECT c.id, c.tenant_id, c.id, c.price_minor, c.currency, COALESCE(c.created_at, CURRENT_TIMESTAMP), COALESCE(c.created_at, CURRENT_TIMESTAMP)
+FROM car c
+WHERE NOT EXISTS (SELECT 1 FROM car_price p WHERE p.car_id = c.id);
+
+CREATE TABLE IF NOT EXISTS promotion (
+    id UUID PRIMARY KEY,
+    tenant_id UUID NOT NULL REFERENCES dealership(id),
+    name VARCHAR(255) NOT NULL,
+    kind VARCHAR(20) NOT NULL,
+    percent NUMERIC(5,2) NOT NULL DEFAULT 0,
+    amount_minor BIGINT NOT NULL DEFAULT 0,
+    currency VARCHAR(3) NOT NULL DEFAULT '',
+    brand VARCHAR(100) NOT NULL DEFAULT '',
+    fuel_type VARCHAR(50) NOT NULL DEFAULT '',
+    starts_at TIMESTAMP NOT NULL,
+    ends_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS promotion_tenant_idx ON promotion (tenant_id, starts_at);
+
+-- Photos of cars. The image files live in the blob store under
+-- storage_key and thumbnail_key; a car has at most one primary image.
+CREATE TABLE IF NOT EXISTS car_image (
+    id UUID PRIMARY KEY,
+    tenant_id UUID NOT NULL REFERENCES dealership(id),
+    car_id UUID NOT NULL REFERENCES car(id) ON DELETE CASCADE,
+    storage_key VARCHAR(255) NOT NULL,
+    thumbnail_key VARCHAR(255) NOT NULL,
+    content_type VARCHAR(50) NOT NULL,
+    size_bytes BIGINT NOT NULL,
+    width INT NOT NULL,
+    height INT NOT NULL,
+    position INT NOT NULL,
+    is_primary BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS car_image_car_idx ON car_image (car_id, position);
+CREATE UNIQUE INDEX IF NOT EXISTS car_image_primary_idx ON car_image (car_id) WHERE is_primary;
+
+-- Specifications of cars beyond the engine, one row per car. Transmission,
+-- drivetrain and body type codes come from their reference tables; a NULL
+-- column is a value that was not given.
+CREATE TABLE IF NOT EXISTS transmission (
+    code VARCHAR(20) PRIMARY KEY,
+    name VARCHAR(50) NOT NULL
+);
+INSERT INTO transmission (code, name) VALUES
+    ('manual', 'Manual'), ('automatic', 'Automatic'), ('cvt', 'Continuously variable'), ('dct', 'Dual-clutch')
+ON CONFLICT (code) DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS drivetrain (
+    code VARCHAR(20) PRIMARY KEY,
+    name VARCHAR(50) NOT NULL
+);
+INSERT INTO drivetrain (code, name) VALUES
+    ('fwd', 'Front-wheel drive'), ('rwd', 'Rear-wheel drive'), ('awd', 'All-wheel drive'), ('4wd', 'Four-wheel drive')
+ON CONFLICT (code) DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS body_type (
+    code VARCHAR(20) PRIMARY KEY,
+    name VARCHAR(50) NOT NULL
+);
+INSERT INTO body_type (code, name) VALUES
+    ('sedan', 'Sedan'), ('hatchback', 'Hatchback'), ('suv', 'SUV'), ('coupe', 'Coupe'), ('convertible', 'Convertible'),
+    ('wagon', 'Wagon'), ('pickup', 'Pickup'), ('van', 'Van'), ('minivan', 'Minivan')
+ON CONFLICT (code) DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS car_spec (
+    car_id UUID PRIMARY KEY REFERENCES car(id) ON DELETE CASCADE,
+    transmission VARCHAR(20) REFERENCES transmission(code),
+    drivetrain VARCHAR(20) REFERENCES drivetrain(code),
+    body_type VARCHAR(20) REFERENCES body_type(code),
+    seats INT,
+    doors INT,
+    colour VARCHAR(50),
+    trim_level VARCHAR(100),
+    fuel_consumption NUMERIC(5,2),
+    energy_consumption NUMERIC(5,2),
+    co2_emissions INT
+);
+
+-- Engines are powertrains: a combustion engine, an electric motor or a
+-- hybrid of both. Motor power is in kW and battery capacity in kWh; the
+-- battery used to be part of car_spec and moves to the engine of the car.
+ALTER TABLE engine ADD COLUMN IF NOT EXISTS engine_type VARCHAR(20) NOT NULL DEFAULT 'combustion'
+    CHECK (engine_type IN ('combustion', 'electric', 'hybrid'));
+ALTER TABLE engine ADD COLUMN IF NOT EXISTS motor_power BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE engine ADD COLUMN IF NOT EXISTS battery_capacity NUMERIC(6,2);
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'car_spec' AND column_name = 'battery_capacity') THEN
+        UPDATE engine e SET battery_capacity = s.battery_capacity
+        FROM car c JOIN car_spec s ON s.car_id = c.id
+        WHERE c.engine_id = e.id AND s.battery_capacity IS NOT NULL;
+        ALTER TABLE car_spec DROP COLUMN battery_capacity;
+    END IF;
+END $$;
+
+-- updated_at is when the engine last changed, it backs Last-Modified
+ALTER TABLE engine ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP;
+
+-- The catalogue of brands and models shared by every dealership. Names and
+-- aliases are matched on lower(trim(...)), kept in the *_key columns; cars
+-- carry the canonical brand name.
+CREATE TABLE IF NOT EXISTS brand (
+    id UUID PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    name_key VARCHAR(100) NOT NULL UNIQUE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE TABLE IF NOT EXISTS brand_alias (
+    alias_key VARCHAR(100) PRIMARY KEY,
+    brand_id UUID NOT NULL REFERENCES brand(id) ON DELETE CASCADE,
+    alias VARCHAR(100) NOT NULL
+);
+CREATE TABLE IF NOT EXISTS car_model (
+    id UUID PRIMARY KEY,
+    brand_id UUID NOT NULL REFERENCES brand(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    name_key VARCHAR(100) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (brand_id, name_key)
+);
+CREATE TABLE IF NOT EXISTS car_model_alias (
+    brand_id UUID NOT NULL REFERENCES brand(id) ON DELETE CASCADE,
+    alias_key VARCHAR(100) NOT NULL,
+    model_id UUID NOT NULL REFERENCES car_model(id) ON DELETE CASCADE,
+    alias VARCHAR(100) NOT NULL,
+    PRIMARY KEY (brand_id, alias_key)
+);
+
+-- The first time round, seed the catalogue, add the brands of existing cars
+-- under their most used spelling and give every car its canonical name.
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM brand) THEN
+        INSERT INTO brand (id, name, name_key)
+        SELECT uuid_generate_v4(), seed.name, lower(seed.name)
+        FROM (VALUES ('Audi'), ('BMW'), ('Chevrolet'), ('Ford'), ('Honda'), ('Hyundai'), ('Kia'), ('Lexus'),
+            ('Mazda'), ('Mercedes-Benz'), ('Mitsubishi'), ('Nissan'), ('Porsche'), ('Subaru'), ('Tesla'),
+            ('Toyota'), ('VinFast'), ('Volkswagen')) AS seed (name);
+        INSERT INTO brand_alias (alias_key, brand_id, alias)
+        SELECT lower(seed.alias), b.id, seed.alias
+        FROM (VALUES ('Chevy', 'chevrolet'), ('Mercedes', 'mercedes-benz'), ('Mercedes Benz', 'mercedes-benz'),
+            ('VW', 'volkswagen')) AS seed (alias, brand_key)
+        JOIN brand b ON b.name_key = seed.brand_key;
+
+        INSERT INTO brand (id, name, name_key)
+        SELECT uuid_generate_v4(), spelling, key FROM (
+            SELECT DISTINCT ON (lower(trim(brand))) trim(brand) AS spelling, lower(trim(brand)) AS key
+            FROM car
+            WHERE trim(brand) <> ''
+                AND lower(trim(brand)) NOT IN (SELECT alias_key FROM brand_alias)
+            GROUP BY trim(brand)
+            ORDER BY lower(trim(brand)), COUNT(*) DESC, trim(brand)
+        ) spellings
+        ON CONFLICT (name_key) DO NOTHING;
+
+        UPDATE car SET brand = b.name
+        FROM brand_alias a JOIN brand b ON b.id = a.brand_id
+        WHERE lower(trim(car.brand)) = a.alias_key;
+        UPDATE car SET brand = b.name
+        FROM brand b
+        WHERE lower(trim(car.brand)) = b.name_key AND car.brand <> b.name;
+    END IF;
+END $$;
+
+-- Validation rules a dealership adds to, or overrides in, the configured
+-- defaults. definition holds the models.ValidationRule as JSON.
+CREATE TABLE IF NOT EXISTS validation_rule (
+    tenant_id UUID NOT NULL REFERENCES dealership(id),
+    id VARCHAR(100) NOT NULL,
+    position INT NOT NULL,
+    definition JSONB NOT NULL,
+    PRIMARY KEY (tenant_id, id)
+);
+
+-- Car and engine events, written in the transaction of the change and
+-- published by the relay in sequence order. data holds the car or engine
+-- as JSON.
+CREATE TABLE IF NOT EXISTS outbox_event (
+    sequence BIGSERIAL PRIMARY KEY,
+    id UUID NOT NULL UNIQUE,
+    tenant_id UUID NOT NULL,
+    type VARCHAR(50) NOT NULL,
+    entity_id UUID NOT NULL,
+    data JSONB NOT NULL,
+    occurred_at TIMESTAMP NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT,
+    next_attempt_at TIMESTAMP,
+    published_at TIMESTAMP
+);
+-- next_attempt_at is when a claimed or failed event is due again, NULL for
+-- an event never claimed.
+ALTER TABLE outbox_event ADD COLUMN IF NOT EXISTS next_attempt_at TIMESTAMP;
+CREATE INDEX IF NOT EXISTS outbox_event_pending_idx ON outbox_event (sequence) WHERE published_at IS NULL;
+CREATE INDEX IF NOT EXISTS outbox_event_entity_idx ON outbox_event (entity_id, sequence) WHERE published_at IS NULL;
+CREATE INDEX IF NOT EXISTS outbox_event_published_idx ON outbox_event (published_at);
+
+-- Webhook subscriptions of partners, the events queued for them and the
+-- log of every attempt at sending one. events holds event types, "car.*"
+-- or "*".
+CREATE TABLE IF NOT EXISTS webhook (
+    id UUID PRIMARY KEY,
+    tenant_id UUID NOT NULL REFERENCES dealership(id),
+    url TEXT NOT NULL,
+    events TEXT[] NOT NULL,
+    secret TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS webhook_tenant_idx ON webhook (tenant_id, created_at);
+
+CREATE TABLE IF NOT EXISTS webhook_delivery (
+    id UUID PRIMARY KEY,
+    webhook_id UUID NOT NULL REFERENCES webhook(id) ON DELETE CASCADE,
+    event_id UUID NOT NULL,
+    event_type VARCHAR(50) NOT NULL,
+    payload JSONB NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'succeeded', 'failed')),
+    attempts INT NOT NULL DEFAULT 0,
+    response_code INT NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    next_attempt_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL,
+    delivered_at TIMESTAMP,
+    UNIQUE (webhook_id, event_id)
+);
+CREATE INDEX IF NOT EXISTS webhook_delivery_due_idx ON webhook_delivery (next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS webhook_delivery_webhook_idx ON webhook_delivery (webhook_id, created_at);
+
+CREATE TABLE IF NOT EXISTS webhook_attempt (
+    id BIGSERIAL PRIMARY KEY,
+    delivery_id UUID NOT NULL REFERENCES webhook_delivery(id) ON DELETE CASCADE,
+    response_code INT NOT NULL DEFAULT 0,
+    error TEXT NOT NULL DEFAULT '',
+    duration_ms BIGINT NOT NULL,
+    attempted_at TIMESTAMP NOT NULL
+);
+CREATE INDEX IF NOT EXISTS webhook_attempt_delivery_idx ON webhook_attempt (delivery_id, id);
@@ -0,0 +1,81 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/adohong4/carZone/models"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+)
+
+func (s *Store) GetDealerships(ctx context.Context) ([]models.Dealership, error) {
+	tracer := otel.Tracer("SQLiteStore")
+	ctx, span := tracer.Start(ctx, "GetDealerships-SQLiteStore")
+	defer span.End()
+
+	rows, err := s.db.QueryContext(ctx, "SELECT id, name, created_at FROM dealership ORDER BY created_at, id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var dealerships []models.Dealership
+	for rows.Next() {
+		var dealership models.Dealership
+		if err := rows.Scan(&dealership.ID, &dealership.Name, &dealership.CreatedAt); err != nil {
+			return nil, err
+		}
+		dealerships = append(dealerships, dealership)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return dealerships, nil
+}
+
+func (s *Store) GetDealershipById(ctx context.Context, id string) (models.Dealership, error) {
+	tracer := otel.Tracer("SQLiteStore")
+	ctx, span := tracer.Start(ctx, "GetDealershipById-SQLiteStore")
+	defer span.End()
+
+	var dealership models.Dealership
+
+	dealershipID, err := uuid.Parse(id)
+	if err != nil {
+		return dealership, fmt.Errorf("invalid dealership ID: %w", err)
+	}
+
+	err = s.db.QueryRowContext(ctx, "SELECT id, name, created_at FROM dealership WHERE id = ?", dealershipID.String()).Scan(
+		&dealership.ID, &dealership.Name, &dealership.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return dealership, nil
+		}
+		return dealership, err
+	}
+	return dealership, nil
+}
+
+func (s *Store) CreateDealership(ctx context.Context, dealershipReq *models.DealershipRequest) (models.Dealership, error) {
+	tracer := otel.Tracer("SQLiteStore")
+	ctx, span := tracer.Start(ctx, "CreateDealership-SQLiteStore")
+	defer span.End()
+
+	dealership := models.Dealership{
+		ID:        uuid.New(),
+		Name:      dealershipReq.Name,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	_, err := s.db.ExecContext(ctx, "INSERT INTO dealership (id, name, created_at) VALUES (?, ?, ?)",
+		dealership.ID.String(), dealership.Name, dealership.CreatedAt)
+	if err != nil {
+		return models.Dealership{}, err
+	}
+	return dealership, nil
+}
@@ -0,0 +1,92 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/adohong4/carZone/models"
+	"go.opentelemetry.io/otel"
+)
+
+// LoginStore implements store.LoginStoreInterface on the database of Store.
+// It is a type of its own like memory.LoginStore, RecordAttempt of the
+// webhook deliveries already takes the name on Store.
+type LoginStore struct {
+	db *sql.DB
+}
+
+func NewLoginStore(db *sql.DB) *LoginStore {
+	return &LoginStore{db: db}
+}
+
+func (s *LoginStore) RecordAttempt(ctx context.Context, attempt models.LoginAttempt) error {
+	tracer := otel.Tracer("SQLiteStore")
+	ctx, span := tracer.Start(ctx, "RecordAttempt-SQLiteStore")
+	defer span.End()
+
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO login_attempt (username, ip, success, reason, created_at) VALUES (?, ?, ?, ?, ?)`,
+		attempt.UserName, attempt.IP, attempt.Success, attempt.Reason, attempt.CreatedAt.UTC())
+	return err
+}
+
+func scanLockout(row scanner, subject string) (models.LoginLockout, error) {
+	lockout := models.LoginLockout{Subject: subject}
+	var lockedUntil sql.NullTime
+	if err := row.Scan(&lockout.FailedAttempts, &lockout.Lockouts, &lockedUntil); err != nil {
+		return lockout, err
+	}
+	lockout.LockedUntil = lockedUntil.Time
+	return lockout, nil
+}
+
+func (s *LoginStore) GetLockout(ctx context.Context, subject string) (models.LoginLockout, error) {
+	tracer := otel.Tracer("SQLiteStore")
+	ctx, span := tracer.Start(ctx, "GetLockout-SQLiteStore")
+	defer span.End()
+
+	lockout, err := scanLockout(s.db.QueryRowContext(ctx,
+		"SELECT failed_attempts, lockouts, locked_until FROM login_lockout WHERE subject = ?", subject,
+	), subject)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.LoginLockout{Subject: subject}, nil
+	}
+	return lockout, err
+}
+
+func (s *LoginStore) IncrementFailure(ctx context.Context, subject string) (models.LoginLockout, error) {
+	tracer := otel.Tracer("SQLiteStore")
+	ctx, span := tracer.Start(ctx, "IncrementFailure-SQLiteStore")
+	defer span.End()
+
+	return scanLockout(s.db.QueryRowContext(ctx,
+		`INSERT INTO login_lockout (subject, failed_attempts, lockouts, updated_at) VALUES (?, 1, 0, ?)
+		ON CONFLICT (subject) DO UPDATE
+		SET failed_attempts = login_lockout.failed_attempts + 1, updated_at = excluded.updated_at
+		RETURNING failed_attempts, lockouts, locked_until`, subject, time.Now().UTC(),
+	), subject)
+}
+
+func (s *LoginStore) LockUntil(ctx context.Context, subject string, until time.Time) (models.LoginLockout, error) {
+	tracer := otel.Tracer("SQLiteStore")
+	ctx, span := tracer.Start(ctx, "LockUntil-SQLiteStore")
+	defer span.End()
+
+	return scanLockout(s.db.QueryRowContext(ctx,
+		`UPDATE login_lockout
+		SET failed_attempts = 0, lockouts = lockouts + 1, locked_until = ?, updated_at = ?
+		WHERE subject = ?
+		RETURNING failed_attempts, lockouts, locked_until`, until.UTC(), time.Now().UTC(), subject,
+	), subject)
+}
+
+func (s *LoginStore) ResetLockout(ctx context.Context, subject string) error {
+	tracer := otel.Tracer("SQLiteStore")
+	ctx, span := tracer.Start(ctx, "ResetLockout-SQLiteStore")
+	defer span.End()
+
+	_, err := s.db.ExecContext(ctx, "DELETE FROM login_lockout WHERE subject = ?", subject)
+	return err
+}
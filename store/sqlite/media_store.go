@@ -0,0 +1,199 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/adohong4/carZone/models"
+	"github.com/adohong4/carZone/store"
+	"github.com/adohong4/carZone/tenant"
+	"go.opentelemetry.io/otel"
+)
+
+const carImageColumns = "id, car_id, storage_key, thumbnail_key, content_type, size_bytes, width, height, position, is_primary, created_at"
+
+func scanCarImage(row scanner) (models.CarImage, error) {
+	var image models.CarImage
+	err := row.Scan(
+		&image.ID, &image.CarID, &image.Key, &image.ThumbnailKey, &image.ContentType,
+		&image.Size, &image.Width, &image.Height, &image.Position, &image.Primary, &image.CreatedAt,
+	)
+	return image, err
+}
+
+func (s *Store) ListCarImages(ctx context.Context, carIDs []string) ([]models.CarImage, error) {
+	tracer := otel.Tracer("SQLiteStore")
+	ctx, span := tracer.Start(ctx, "ListCarImages-SQLiteStore")
+	defer span.End()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(carIDs) == 0 {
+		return nil, nil
+	}
+
+	args := []interface{}{tenantID}
+	for _, id := range carIDs {
+		args = append(args, id)
+	}
+	query := "SELECT " + carImageColumns + " FROM car_image WHERE tenant_id = ? AND car_id IN (?" +
+		strings.Repeat(", ?", len(carIDs)-1) + ") ORDER BY car_id, position"
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var images []models.CarImage
+	for rows.Next() {
+		image, err := scanCarImage(rows)
+		if err != nil {
+			return nil, err
+		}
+		images = append(images, image)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return images, nil
+}
+
+func (s *Store) AddCarImage(ctx context.Context, image models.CarImage) (models.CarImage, error) {
+	tracer := otel.Tracer("SQLiteStore")
+	ctx, span := tracer.Start(ctx, "AddCarImage-SQLiteStore")
+	defer span.End()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return models.CarImage{}, err
+	}
+
+	var added models.CarImage
+	err = s.withTx(ctx, func(tx *sql.Tx) error {
+		var carID string
+		err := tx.QueryRowContext(ctx, "SELECT id FROM car WHERE id = ? AND tenant_id = ?", image.CarID.String(), tenantID).Scan(&carID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return store.ErrCarNotFound
+			}
+			return err
+		}
+
+		var count, next int
+		err = tx.QueryRowContext(ctx,
+			"SELECT COUNT(*), COALESCE(MAX(position) + 1, 0) FROM car_image WHERE car_id = ?", carID).Scan(&count, &next)
+		if err != nil {
+			return err
+		}
+
+		query := `INSERT INTO car_image (id, tenant_id, car_id, storage_key, thumbnail_key, content_type, size_bytes, width, height, position, is_primary, created_at)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+				RETURNING ` + carImageColumns
+		added, err = scanCarImage(tx.QueryRowContext(ctx, query,
+			image.ID.String(), tenantID, carID, image.Key, image.ThumbnailKey, image.ContentType,
+			image.Size, image.Width, image.Height, next, count == 0, image.CreatedAt.UTC(),
+		))
+		return err
+	})
+	if err != nil {
+		return models.CarImage{}, err
+	}
+	return added, nil
+}
+
+func (s *Store) SetCarImageOrder(ctx context.Context, carID string, ids []string) error {
+	tracer := otel.Tracer("SQLiteStore")
+	ctx, span := tracer.Start(ctx, "SetCarImageOrder-SQLiteStore")
+	defer span.End()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	return s.withTx(ctx, func(tx *sql.Tx) error {
+		for position, id := range ids {
+			_, err := tx.ExecContext(ctx,
+				"UPDATE car_image SET position = ? WHERE id = ? AND car_id = ? AND tenant_id = ?",
+				position, id, carID, tenantID)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *Store) SetPrimaryCarImage(ctx context.Context, carID, id string) (models.CarImage, error) {
+	tracer := otel.Tracer("SQLiteStore")
+	ctx, span := tracer.Start(ctx, "SetPrimaryCarImage-SQLiteStore")
+	defer span.End()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return models.CarImage{}, err
+	}
+
+	var primary models.CarImage
+	err = s.withTx(ctx, func(tx *sql.Tx) error {
+		var exists bool
+		err := tx.QueryRowContext(ctx,
+			"SELECT EXISTS (SELECT 1 FROM car_image WHERE id = ? AND car_id = ? AND tenant_id = ?)", id, carID, tenantID).Scan(&exists)
+		if err != nil || !exists {
+			return err
+		}
+
+		// clear the old flag first, idx_car_image_primary allows one per car
+		if _, err = tx.ExecContext(ctx, "UPDATE car_image SET is_primary = 0 WHERE car_id = ? AND is_primary", carID); err != nil {
+			return err
+		}
+		primary, err = scanCarImage(tx.QueryRowContext(ctx,
+			"UPDATE car_image SET is_primary = 1 WHERE id = ? RETURNING "+carImageColumns, id))
+		return err
+	})
+	if err != nil {
+		return models.CarImage{}, err
+	}
+	return primary, nil
+}
+
+func (s *Store) DeleteCarImage(ctx context.Context, carID, id string) (models.CarImage, error) {
+	tracer := otel.Tracer("SQLiteStore")
+	ctx, span := tracer.Start(ctx, "DeleteCarImage-SQLiteStore")
+	defer span.End()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return models.CarImage{}, err
+	}
+
+	var deleted models.CarImage
+	err = s.withTx(ctx, func(tx *sql.Tx) error {
+		var err error
+		deleted, err = scanCarImage(tx.QueryRowContext(ctx,
+			"DELETE FROM car_image WHERE id = ? AND car_id = ? AND tenant_id = ? RETURNING "+carImageColumns,
+			id, carID, tenantID))
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil
+			}
+			return err
+		}
+
+		if deleted.Primary {
+			_, err = tx.ExecContext(ctx,
+				`UPDATE car_image SET is_primary = 1
+				WHERE id = (SELECT id FROM car_image WHERE car_id = ? ORDER BY position LIMIT 1)`, carID)
+		}
+		return err
+	})
+	if err != nil {
+		return models.CarImage{}, err
+	}
+	return deleted, nil
+}
@@ -0,0 +1,21 @@
+-- UUIDs are stored as their canonical 36 character text form.
+CREATE TABLE IF NOT EXISTS engine (
+    id TEXT PRIMARY KEY,
+    displacement INTEGER NOT NULL,
+    no_of_cylinders INTEGER NOT NULL,
+    car_range INTEGER NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS car (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    year INTEGER NOT NULL,
+    brand TEXT NOT NULL,
+    fuel_type TEXT NOT NULL,
+    engine_id TEXT REFERENCES engine(id),
+    price REAL NOT NULL,
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_car_brand ON car (brand);
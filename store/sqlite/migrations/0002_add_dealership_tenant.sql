@@ -0,0 +1,17 @@
+-- Dealerships (tenants); every car and engine belongs to one.
+CREATE TABLE IF NOT EXISTS dealership (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    created_at DATETIME NOT NULL
+);
+
+-- Existing rows belong to the default dealership.
+INSERT OR IGNORE INTO dealership (id, name, created_at)
+VALUES ('00000000-0000-0000-0000-000000000001', 'Default dealership', CURRENT_TIMESTAMP);
+
+-- SQLite cannot add a column with a foreign key and a non NULL default.
+ALTER TABLE engine ADD COLUMN tenant_id TEXT NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001';
+ALTER TABLE car ADD COLUMN tenant_id TEXT NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001';
+
+CREATE INDEX IF NOT EXISTS idx_car_tenant_brand ON car (tenant_id, brand);
+CREATE INDEX IF NOT EXISTS idx_engine_tenant ON engine (tenant_id);
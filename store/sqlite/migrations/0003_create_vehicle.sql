@@ -0,0 +1,15 @@
+-- Physical units of a car model, keyed by VIN within a dealership.
+CREATE TABLE IF NOT EXISTS vehicle (
+    vin TEXT NOT NULL,
+    tenant_id TEXT NOT NULL,
+    car_id TEXT NOT NULL REFERENCES car(id),
+    status TEXT NOT NULL,
+    location TEXT NOT NULL,
+    mileage INTEGER NOT NULL DEFAULT 0,
+    colour TEXT NOT NULL,
+    received_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL,
+    PRIMARY KEY (tenant_id, vin)
+);
+
+CREATE INDEX IF NOT EXISTS idx_vehicle_tenant_car_status ON vehicle (tenant_id, car_id, status);
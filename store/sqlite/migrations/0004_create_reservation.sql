@@ -0,0 +1,16 @@
+-- Time-limited holds on cars, a car has at most one active hold.
+CREATE TABLE IF NOT EXISTS reservation (
+    id TEXT PRIMARY KEY,
+    tenant_id TEXT NOT NULL,
+    car_id TEXT NOT NULL REFERENCES car(id) ON DELETE CASCADE,
+    customer_name TEXT NOT NULL,
+    customer_contact TEXT NOT NULL,
+    reserved_by TEXT NOT NULL,
+    status TEXT NOT NULL,
+    expires_at DATETIME NOT NULL,
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_reservation_active_car ON reservation (car_id) WHERE status = 'active';
+CREATE INDEX IF NOT EXISTS idx_reservation_active_expiry ON reservation (expires_at) WHERE status = 'active';
@@ -0,0 +1,43 @@
+-- Sales orders, their lines and the per dealership invoice numbering.
+-- Amounts are stored as decimal text.
+CREATE TABLE IF NOT EXISTS sales_order (
+    id TEXT PRIMARY KEY,
+    tenant_id TEXT NOT NULL,
+    invoice_number TEXT,
+    status TEXT NOT NULL,
+    customer_name TEXT NOT NULL,
+    customer_email TEXT NOT NULL,
+    customer_address TEXT NOT NULL,
+    salesperson TEXT NOT NULL,
+    tax_rate TEXT NOT NULL,
+    subtotal TEXT NOT NULL,
+    discount_total TEXT NOT NULL,
+    tax_total TEXT NOT NULL,
+    total TEXT NOT NULL,
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL,
+    confirmed_at DATETIME,
+    UNIQUE (tenant_id, invoice_number)
+);
+
+CREATE INDEX IF NOT EXISTS idx_sales_order_tenant_status ON sales_order (tenant_id, status);
+
+CREATE TABLE IF NOT EXISTS order_line (
+    id TEXT PRIMARY KEY,
+    order_id TEXT NOT NULL REFERENCES sales_order(id) ON DELETE CASCADE,
+    position INTEGER NOT NULL,
+    car_id TEXT NOT NULL REFERENCES car(id),
+    description TEXT NOT NULL,
+    quantity INTEGER NOT NULL,
+    unit_price TEXT NOT NULL,
+    discount_percent TEXT NOT NULL,
+    discount TEXT NOT NULL,
+    total TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_line_order ON order_line (order_id, position);
+
+CREATE TABLE IF NOT EXISTS invoice_sequence (
+    tenant_id TEXT PRIMARY KEY,
+    last_number INTEGER NOT NULL
+);
@@ -0,0 +1,36 @@
+-- Customers with their notes and the cars they viewed, reserved or
+-- purchased.
+CREATE TABLE IF NOT EXISTS customer (
+    id TEXT PRIMARY KEY,
+    tenant_id TEXT NOT NULL,
+    name TEXT NOT NULL,
+    email TEXT NOT NULL,
+    phone TEXT NOT NULL,
+    address TEXT NOT NULL,
+    marketing_consent INTEGER NOT NULL DEFAULT 0,
+    contact_consent INTEGER NOT NULL DEFAULT 0,
+    consent_updated_at DATETIME,
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL,
+    erased_at DATETIME
+);
+
+CREATE INDEX IF NOT EXISTS idx_customer_tenant_name ON customer (tenant_id, name COLLATE NOCASE);
+
+CREATE TABLE IF NOT EXISTS customer_note (
+    id TEXT PRIMARY KEY,
+    customer_id TEXT NOT NULL REFERENCES customer(id) ON DELETE CASCADE,
+    body TEXT NOT NULL,
+    author TEXT NOT NULL,
+    created_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_customer_note_customer ON customer_note (customer_id, created_at);
+
+CREATE TABLE IF NOT EXISTS customer_car (
+    customer_id TEXT NOT NULL REFERENCES customer(id) ON DELETE CASCADE,
+    car_id TEXT NOT NULL REFERENCES car(id) ON DELETE CASCADE,
+    kind TEXT NOT NULL,
+    created_at DATETIME NOT NULL,
+    PRIMARY KEY (customer_id, car_id, kind)
+);
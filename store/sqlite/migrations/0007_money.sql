@@ -0,0 +1,15 @@
+-- Prices become whole minor units (cents, dong, ...) of the car's ISO 4217
+-- currency, existing prices were dollars.
+ALTER TABLE car ADD COLUMN price_minor INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE car ADD COLUMN currency TEXT NOT NULL DEFAULT 'USD';
+UPDATE car SET price_minor = CAST(ROUND(price * 100) AS INTEGER);
+ALTER TABLE car DROP COLUMN price;
+
+ALTER TABLE sales_order ADD COLUMN currency TEXT NOT NULL DEFAULT 'USD';
+
+-- Units of each currency per unit of the base currency.
+CREATE TABLE IF NOT EXISTS exchange_rate (
+    currency TEXT PRIMARY KEY,
+    rate TEXT NOT NULL,
+    updated_at DATETIME NOT NULL
+);
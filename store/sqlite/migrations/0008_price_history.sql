@@ -0,0 +1,37 @@
+-- List prices of cars over time. When ranges overlap the price that started
+-- last applies; car.price_minor is kept in step by the price scheduler.
+CREATE TABLE IF NOT EXISTS car_price (
+    id TEXT PRIMARY KEY,
+    tenant_id TEXT NOT NULL,
+    car_id TEXT NOT NULL REFERENCES car(id) ON DELETE CASCADE,
+    price_minor INTEGER NOT NULL,
+    currency TEXT NOT NULL,
+    effective_from DATETIME NOT NULL,
+    effective_to DATETIME,
+    created_by TEXT NOT NULL DEFAULT '',
+    created_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_car_price_car ON car_price (car_id, effective_from);
+
+-- cars from before the price history start it with their current price,
+-- the car ID doubles as the ID of that first price
+INSERT INTO car_price (id, tenant_id, car_id, price_minor, currency, effective_from, created_at)
+SELECT id, tenant_id, id, price_minor, currency, created_at, created_at FROM car;
+
+CREATE TABLE IF NOT EXISTS promotion (
+    id TEXT PRIMARY KEY,
+    tenant_id TEXT NOT NULL,
+    name TEXT NOT NULL,
+    kind TEXT NOT NULL,
+    percent TEXT NOT NULL DEFAULT '0',
+    amount_minor INTEGER NOT NULL DEFAULT 0,
+    currency TEXT NOT NULL DEFAULT '',
+    brand TEXT NOT NULL DEFAULT '',
+    fuel_type TEXT NOT NULL DEFAULT '',
+    starts_at DATETIME NOT NULL,
+    ends_at DATETIME,
+    created_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_promotion_tenant ON promotion (tenant_id, starts_at);
@@ -0,0 +1,19 @@
+-- Photos of cars. The image files live in the blob store under
+-- storage_key and thumbnail_key; a car has at most one primary image.
+CREATE TABLE IF NOT EXISTS car_image (
+    id TEXT PRIMARY KEY,
+    tenant_id TEXT NOT NULL,
+    car_id TEXT NOT NULL REFERENCES car(id) ON DELETE CASCADE,
+    storage_key TEXT NOT NULL,
+    thumbnail_key TEXT NOT NULL,
+    content_type TEXT NOT NULL,
+    size_bytes INTEGER NOT NULL,
+    width INTEGER NOT NULL,
+    height INTEGER NOT NULL,
+    position INTEGER NOT NULL,
+    is_primary INTEGER NOT NULL DEFAULT 0,
+    created_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_car_image_car ON car_image (car_id, position);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_car_image_primary ON car_image (car_id) WHERE is_primary;
@@ -0,0 +1,39 @@
+-- Specifications of cars beyond the engine, one row per car. Transmission,
+-- drivetrain and body type codes come from their reference tables; a NULL
+-- column is a value that was not given.
+CREATE TABLE IF NOT EXISTS transmission (
+    code TEXT PRIMARY KEY,
+    name TEXT NOT NULL
+);
+INSERT OR IGNORE INTO transmission (code, name) VALUES
+    ('manual', 'Manual'), ('automatic', 'Automatic'), ('cvt', 'Continuously variable'), ('dct', 'Dual-clutch');
+
+CREATE TABLE IF NOT EXISTS drivetrain (
+    code TEXT PRIMARY KEY,
+    name TEXT NOT NULL
+);
+INSERT OR IGNORE INTO drivetrain (code, name) VALUES
+    ('fwd', 'Front-wheel drive'), ('rwd', 'Rear-wheel drive'), ('awd', 'All-wheel drive'), ('4wd', 'Four-wheel drive');
+
+CREATE TABLE IF NOT EXISTS body_type (
+    code TEXT PRIMARY KEY,
+    name TEXT NOT NULL
+);
+INSERT OR IGNORE INTO body_type (code, name) VALUES
+    ('sedan', 'Sedan'), ('hatchback', 'Hatchback'), ('suv', 'SUV'), ('coupe', 'Coupe'), ('convertible', 'Convertible'),
+    ('wagon', 'Wagon'), ('pickup', 'Pickup'), ('van', 'Van'), ('minivan', 'Minivan');
+
+CREATE TABLE IF NOT EXISTS car_spec (
+    car_id TEXT PRIMARY KEY REFERENCES car(id) ON DELETE CASCADE,
+    transmission TEXT REFERENCES transmission(code),
+    drivetrain TEXT REFERENCES drivetrain(code),
+    body_type TEXT REFERENCES body_type(code),
+    seats INTEGER,
+    doors INTEGER,
+    colour TEXT,
+    trim_level TEXT,
+    fuel_consumption TEXT,
+    energy_consumption TEXT,
+    co2_emissions INTEGER,
+    battery_capacity TEXT
+);
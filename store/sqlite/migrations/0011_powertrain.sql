@@ -0,0 +1,14 @@
+-- Engines are powertrains: a combustion engine, an electric motor or a
+-- hybrid of both. Motor power is in kW and battery capacity in kWh; the
+-- battery used to be part of car_spec and moves to the engine of the car.
+ALTER TABLE engine ADD COLUMN engine_type TEXT NOT NULL DEFAULT 'combustion'
+    CHECK (engine_type IN ('combustion', 'electric', 'hybrid'));
+ALTER TABLE engine ADD COLUMN motor_power INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE engine ADD COLUMN battery_capacity TEXT;
+
+UPDATE engine SET battery_capacity = (
+    SELECT s.battery_capacity FROM car c JOIN car_spec s ON s.car_id = c.id
+    WHERE c.engine_id = engine.id AND s.battery_capacity IS NOT NULL
+    LIMIT 1
+);
+ALTER TABLE car_spec DROP COLUMN battery_capacity;
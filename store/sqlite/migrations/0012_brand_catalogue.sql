@@ -0,0 +1,66 @@
+-- The catalogue of brands and models shared by every dealership. Names and
+-- aliases are matched on lower(trim(...)), kept in the *_key columns; cars
+-- carry the canonical brand name.
+CREATE TABLE IF NOT EXISTS brand (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    name_key TEXT NOT NULL UNIQUE,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE TABLE IF NOT EXISTS brand_alias (
+    alias_key TEXT PRIMARY KEY,
+    brand_id TEXT NOT NULL REFERENCES brand(id) ON DELETE CASCADE,
+    alias TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS car_model (
+    id TEXT PRIMARY KEY,
+    brand_id TEXT NOT NULL REFERENCES brand(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    name_key TEXT NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (brand_id, name_key)
+);
+CREATE TABLE IF NOT EXISTS car_model_alias (
+    brand_id TEXT NOT NULL REFERENCES brand(id) ON DELETE CASCADE,
+    alias_key TEXT NOT NULL,
+    model_id TEXT NOT NULL REFERENCES car_model(id) ON DELETE CASCADE,
+    alias TEXT NOT NULL,
+    PRIMARY KEY (brand_id, alias_key)
+);
+
+-- SQLite has no UUID function, the ids are built as random version 4 UUIDs
+INSERT INTO brand (id, name, name_key)
+SELECT lower(hex(randomblob(4))) || '-' || lower(hex(randomblob(2))) || '-4' ||
+    substr(lower(hex(randomblob(2))), 2) || '-' || substr('89ab', 1 + abs(random()) % 4, 1) ||
+    substr(lower(hex(randomblob(2))), 2) || '-' || lower(hex(randomblob(6))),
+    seed.column1, lower(seed.column1)
+FROM (VALUES ('Audi'), ('BMW'), ('Chevrolet'), ('Ford'), ('Honda'), ('Hyundai'), ('Kia'), ('Lexus'),
+    ('Mazda'), ('Mercedes-Benz'), ('Mitsubishi'), ('Nissan'), ('Porsche'), ('Subaru'), ('Tesla'),
+    ('Toyota'), ('VinFast'), ('Volkswagen')) AS seed;
+INSERT INTO brand_alias (alias_key, brand_id, alias)
+SELECT lower(seed.column1), b.id, seed.column1
+FROM (VALUES ('Chevy', 'chevrolet'), ('Mercedes', 'mercedes-benz'), ('Mercedes Benz', 'mercedes-benz'),
+    ('VW', 'volkswagen')) AS seed
+JOIN brand b ON b.name_key = seed.column2;
+
+-- brands of existing cars, under their most used spelling
+INSERT OR IGNORE INTO brand (id, name, name_key)
+SELECT lower(hex(randomblob(4))) || '-' || lower(hex(randomblob(2))) || '-4' ||
+    substr(lower(hex(randomblob(2))), 2) || '-' || substr('89ab', 1 + abs(random()) % 4, 1) ||
+    substr(lower(hex(randomblob(2))), 2) || '-' || lower(hex(randomblob(6))),
+    spelling, key FROM (
+    SELECT trim(brand) AS spelling, lower(trim(brand)) AS key,
+        ROW_NUMBER() OVER (PARTITION BY lower(trim(brand)) ORDER BY COUNT(*) DESC, trim(brand)) AS spelling_rank
+    FROM car
+    WHERE trim(brand) <> ''
+        AND lower(trim(brand)) NOT IN (SELECT alias_key FROM brand_alias)
+    GROUP BY trim(brand)
+)
+WHERE spelling_rank = 1;
+
+UPDATE car SET brand = b.name
+FROM brand_alias a JOIN brand b ON b.id = a.brand_id
+WHERE lower(trim(car.brand)) = a.alias_key;
+UPDATE car SET brand = b.name
+FROM brand b
+WHERE lower(trim(car.brand)) = b.name_key AND car.brand <> b.name;
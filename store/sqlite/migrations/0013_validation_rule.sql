@@ -0,0 +1,9 @@
+-- Validation rules a dealership adds to, or overrides in, the configured
+-- defaults. definition holds the models.ValidationRule as JSON.
+CREATE TABLE IF NOT EXISTS validation_rule (
+    tenant_id TEXT NOT NULL,
+    id TEXT NOT NULL,
+    position INTEGER NOT NULL,
+    definition TEXT NOT NULL,
+    PRIMARY KEY (tenant_id, id)
+);
@@ -0,0 +1,2 @@
+-- Inventory reports aggregate the cars of a dealership added over a period.
+CREATE INDEX IF NOT EXISTS idx_car_tenant_created ON car (tenant_id, created_at);
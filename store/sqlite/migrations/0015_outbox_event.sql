@@ -0,0 +1,17 @@
+-- Car and engine events, written in the transaction of the change and
+-- published by the relay in sequence order. data holds the car or engine
+-- as JSON.
+CREATE TABLE IF NOT EXISTS outbox_event (
+    sequence INTEGER PRIMARY KEY AUTOINCREMENT,
+    id TEXT NOT NULL UNIQUE,
+    tenant_id TEXT NOT NULL,
+    type TEXT NOT NULL,
+    entity_id TEXT NOT NULL,
+    data TEXT NOT NULL,
+    occurred_at DATETIME NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    published_at DATETIME
+);
+
+CREATE INDEX IF NOT EXISTS idx_outbox_event_published ON outbox_event (published_at);
@@ -0,0 +1,35 @@
+-- Login audit trail, lockouts and the TOTP second factor, so they outlive a
+-- restart like on Postgres. Subjects are "user:<name>" or "ip:<address>".
+CREATE TABLE IF NOT EXISTS login_attempt (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    username TEXT NOT NULL,
+    ip TEXT NOT NULL,
+    success BOOLEAN NOT NULL,
+    reason TEXT NOT NULL,
+    created_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_attempt_username ON login_attempt (username, created_at);
+
+CREATE TABLE IF NOT EXISTS login_lockout (
+    subject TEXT PRIMARY KEY,
+    failed_attempts INTEGER NOT NULL DEFAULT 0,
+    lockouts INTEGER NOT NULL DEFAULT 0,
+    locked_until DATETIME,
+    updated_at DATETIME NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS user_totp (
+    username TEXT PRIMARY KEY,
+    secret TEXT NOT NULL,
+    confirmed BOOLEAN NOT NULL DEFAULT FALSE,
+    last_used_step INTEGER NOT NULL DEFAULT 0,
+    created_at DATETIME NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS user_recovery_code (
+    username TEXT NOT NULL REFERENCES user_totp(username) ON DELETE CASCADE,
+    code_hash TEXT NOT NULL,
+    used_at DATETIME,
+    PRIMARY KEY (username, code_hash)
+);
@@ -0,0 +1,7 @@
+-- Reservations and orders made for a customer record, erasing the customer
+-- blanks their customer columns too.
+ALTER TABLE reservation ADD COLUMN customer_id TEXT REFERENCES customer(id) ON DELETE SET NULL;
+ALTER TABLE sales_order ADD COLUMN customer_id TEXT REFERENCES customer(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_reservation_customer ON reservation (customer_id) WHERE customer_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_sales_order_customer ON sales_order (customer_id) WHERE customer_id IS NOT NULL;
@@ -0,0 +1,5 @@
+-- next_attempt_at is when a claimed or failed outbox event is due again,
+-- NULL for an event never claimed.
+ALTER TABLE outbox_event ADD COLUMN next_attempt_at DATETIME;
+
+CREATE INDEX IF NOT EXISTS idx_outbox_event_entity ON outbox_event (entity_id, sequence);
@@ -0,0 +1,4 @@
+-- updated_at is when the engine last changed, engines stored before the
+-- column existed count as changed when it was added.
+ALTER TABLE engine ADD COLUMN updated_at DATETIME NOT NULL DEFAULT '1970-01-01 00:00:00';
+UPDATE engine SET updated_at = CURRENT_TIMESTAMP;
@@ -0,0 +1,323 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/adohong4/carZone/models"
+	"github.com/adohong4/carZone/store"
+	"github.com/adohong4/carZone/tenant"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+)
+
+const orderColumns = `id, invoice_number, status, customer_id, customer_name, customer_email, customer_address, salesperson,
+	currency, tax_rate, subtotal, discount_total, tax_total, total, created_at, updated_at, confirmed_at`
+
+const orderLineColumns = "id, car_id, description, quantity, unit_price, discount_percent, discount, total"
+
+func scanOrder(row scanner) (models.Order, error) {
+	var (
+		order         models.Order
+		invoiceNumber sql.NullString
+		customerID    uuid.NullUUID
+		confirmedAt   sql.NullTime
+	)
+	err := row.Scan(
+		&order.ID, &invoiceNumber, &order.Status, &customerID, &order.CustomerName, &order.CustomerEmail,
+		&order.CustomerAddress, &order.Salesperson, &order.Currency, &order.TaxRate, &order.Subtotal,
+		&order.DiscountTotal, &order.TaxTotal, &order.Total, &order.CreatedAt, &order.UpdatedAt, &confirmedAt,
+	)
+	order.InvoiceNumber = invoiceNumber.String
+	if customerID.Valid {
+		order.CustomerID = &customerID.UUID
+	}
+	if confirmedAt.Valid {
+		order.ConfirmedAt = &confirmedAt.Time
+	}
+	return order, err
+}
+
+func (s *Store) orderLines(ctx context.Context, orderID uuid.UUID) ([]models.OrderLine, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT "+orderLineColumns+" FROM order_line WHERE order_id = ? ORDER BY position", orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var lines []models.OrderLine
+	for rows.Next() {
+		var line models.OrderLine
+		err := rows.Scan(&line.ID, &line.CarID, &line.Description, &line.Quantity,
+			&line.UnitPrice, &line.DiscountPercent, &line.Discount, &line.Total)
+		if err != nil {
+			return nil, err
+		}
+		lines = append(lines, line)
+	}
+	return lines, rows.Err()
+}
+
+// insertOrderLines checks every car belongs to the dealership and writes the
+// lines in their order.
+func insertOrderLines(ctx context.Context, tx *sql.Tx, tenantID string, order models.Order) error {
+	for i, line := range order.Lines {
+		var carID uuid.UUID
+		err := tx.QueryRowContext(ctx, "SELECT id FROM car WHERE id = ? AND tenant_id = ?", line.CarID, tenantID).Scan(&carID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return store.ErrCarNotFound
+			}
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, `INSERT INTO order_line (id, order_id, position, car_id, description, quantity, unit_price, discount_percent, discount, total)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			line.ID, order.ID, i, line.CarID, line.Description, line.Quantity,
+			line.UnitPrice, line.DiscountPercent, line.Discount, line.Total)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Store) CreateOrder(ctx context.Context, order models.Order) (models.Order, error) {
+	tracer := otel.Tracer("SQLiteStore")
+	ctx, span := tracer.Start(ctx, "CreateOrder-SQLiteStore")
+	defer span.End()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return models.Order{}, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Order{}, err
+	}
+	defer tx.Rollback()
+
+	if err = checkCustomer(ctx, tx, tenantID, order.CustomerID); err != nil {
+		return models.Order{}, err
+	}
+	_, err = tx.ExecContext(ctx, `INSERT INTO sales_order (id, tenant_id, status, customer_id, customer_name, customer_email, customer_address, salesperson,
+				currency, tax_rate, subtotal, discount_total, tax_total, total, created_at, updated_at)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		order.ID, tenantID, order.Status, nullableID(order.CustomerID), order.CustomerName, order.CustomerEmail, order.CustomerAddress, order.Salesperson,
+		order.Currency, order.TaxRate, order.Subtotal, order.DiscountTotal, order.TaxTotal, order.Total, order.CreatedAt.UTC(), order.UpdatedAt.UTC())
+	if err != nil {
+		return models.Order{}, err
+	}
+	if err = insertOrderLines(ctx, tx, tenantID, order); err != nil {
+		return models.Order{}, err
+	}
+	if err = tx.Commit(); err != nil {
+		return models.Order{}, err
+	}
+	return s.GetOrder(ctx, order.ID.String())
+}
+
+func (s *Store) GetOrder(ctx context.Context, id string) (models.Order, error) {
+	tracer := otel.Tracer("SQLiteStore")
+	ctx, span := tracer.Start(ctx, "GetOrder-SQLiteStore")
+	defer span.End()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return models.Order{}, err
+	}
+
+	query := "SELECT " + orderColumns + " FROM sales_order WHERE id = ? AND tenant_id = ?"
+	order, err := scanOrder(s.db.QueryRowContext(ctx, query, id, tenantID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Order{}, nil
+		}
+		return models.Order{}, err
+	}
+
+	order.Lines, err = s.orderLines(ctx, order.ID)
+	if err != nil {
+		return models.Order{}, err
+	}
+	return order, nil
+}
+
+func (s *Store) ListOrders(ctx context.Context, status string) ([]models.Order, error) {
+	tracer := otel.Tracer("SQLiteStore")
+	ctx, span := tracer.Start(ctx, "ListOrders-SQLiteStore")
+	defer span.End()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := "SELECT " + orderColumns + " FROM sales_order WHERE tenant_id = ?"
+	args := []interface{}{tenantID}
+	if status != "" {
+		query += " AND status = ?"
+		args = append(args, status)
+	}
+	query += " ORDER BY created_at DESC, id"
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var orders []models.Order
+	for rows.Next() {
+		order, err := scanOrder(rows)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, order)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
+
+// ListCustomerOrders lists the orders made for the customer with their
+// lines, the latest first.
+func (s *Store) ListCustomerOrders(ctx context.Context, customerID string) ([]models.Order, error) {
+	tracer := otel.Tracer("SQLiteStore")
+	ctx, span := tracer.Start(ctx, "ListCustomerOrders-SQLiteStore")
+	defer span.End()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := "SELECT " + orderColumns + " FROM sales_order WHERE customer_id = ? AND tenant_id = ? ORDER BY created_at DESC, id"
+	rows, err := s.db.QueryContext(ctx, query, customerID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var orders []models.Order
+	for rows.Next() {
+		order, err := scanOrder(rows)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, order)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+	for i := range orders {
+		if orders[i].Lines, err = s.orderLines(ctx, orders[i].ID); err != nil {
+			return nil, err
+		}
+	}
+	return orders, nil
+}
+
+func (s *Store) UpdateOrder(ctx context.Context, order models.Order) (models.Order, error) {
+	tracer := otel.Tracer("SQLiteStore")
+	ctx, span := tracer.Start(ctx, "UpdateOrder-SQLiteStore")
+	defer span.End()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return models.Order{}, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Order{}, err
+	}
+	defer tx.Rollback()
+
+	if err = checkCustomer(ctx, tx, tenantID, order.CustomerID); err != nil {
+		return models.Order{}, err
+	}
+	result, err := tx.ExecContext(ctx, `UPDATE sales_order
+				SET customer_id = ?, customer_name = ?, customer_email = ?, customer_address = ?, currency = ?, tax_rate = ?,
+					subtotal = ?, discount_total = ?, tax_total = ?, total = ?, updated_at = ?
+				WHERE id = ? AND tenant_id = ? AND status = ?`,
+		nullableID(order.CustomerID), order.CustomerName, order.CustomerEmail, order.CustomerAddress, order.Currency, order.TaxRate,
+		order.Subtotal, order.DiscountTotal, order.TaxTotal, order.Total, order.UpdatedAt.UTC(),
+		order.ID, tenantID, models.OrderDraft)
+	if err != nil {
+		return models.Order{}, err
+	}
+	if updated, err := result.RowsAffected(); err != nil {
+		return models.Order{}, err
+	} else if updated == 0 {
+		return models.Order{}, store.ErrOrderConflict
+	}
+
+	if _, err = tx.ExecContext(ctx, "DELETE FROM order_line WHERE order_id = ?", order.ID); err != nil {
+		return models.Order{}, err
+	}
+	if err = insertOrderLines(ctx, tx, tenantID, order); err != nil {
+		return models.Order{}, err
+	}
+	if err = tx.Commit(); err != nil {
+		return models.Order{}, err
+	}
+	return s.GetOrder(ctx, order.ID.String())
+}
+
+// UpdateOrderStatus takes the invoice number in the same immediate
+// transaction as the confirmation, so numbers are handed out in order and
+// never skipped.
+func (s *Store) UpdateOrderStatus(ctx context.Context, id, fromStatus, toStatus string, now time.Time) (models.Order, error) {
+	tracer := otel.Tracer("SQLiteStore")
+	ctx, span := tracer.Start(ctx, "UpdateOrderStatus-SQLiteStore")
+	defer span.End()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return models.Order{}, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Order{}, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, "UPDATE sales_order SET status = ?, updated_at = ? WHERE id = ? AND tenant_id = ? AND status = ?",
+		toStatus, now.UTC(), id, tenantID, fromStatus)
+	if err != nil {
+		return models.Order{}, err
+	}
+	if updated, err := result.RowsAffected(); err != nil {
+		return models.Order{}, err
+	} else if updated == 0 {
+		return models.Order{}, store.ErrOrderConflict
+	}
+
+	if toStatus == models.OrderConfirmed {
+		var number int64
+		err = tx.QueryRowContext(ctx, `INSERT INTO invoice_sequence (tenant_id, last_number) VALUES (?, 1)
+				ON CONFLICT (tenant_id) DO UPDATE SET last_number = invoice_sequence.last_number + 1
+				RETURNING last_number`, tenantID).Scan(&number)
+		if err != nil {
+			return models.Order{}, err
+		}
+
+		_, err = tx.ExecContext(ctx, "UPDATE sales_order SET invoice_number = ?, confirmed_at = ? WHERE id = ?",
+			models.InvoiceNumber(number), now.UTC(), id)
+		if err != nil {
+			return models.Order{}, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return models.Order{}, err
+	}
+	return s.GetOrder(ctx, id)
+}
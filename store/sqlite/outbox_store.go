@@ -0,0 +1,122 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/adohong4/carZone/models"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+)
+
+// recordEvent writes an event about entityID to the outbox within tx, the
+// transaction of the change.
+func recordEvent(ctx context.Context, tx *sql.Tx, eventType, tenantID string, entityID uuid.UUID, data interface{}) error {
+	event, err := models.NewEvent(eventType, tenantID, entityID, data)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO outbox_event (id, tenant_id, type, entity_id, data, occurred_at)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		event.ID.String(), event.TenantID, event.Type, event.EntityID.String(), string(event.Data), event.OccurredAt)
+	return err
+}
+
+func (s *Store) ClaimEvents(ctx context.Context, now, until time.Time, limit int) ([]models.PendingEvent, error) {
+	tracer := otel.Tracer("SQLiteStore")
+	ctx, span := tracer.Start(ctx, "ClaimEvents-SQLiteStore")
+	defer span.End()
+
+	var pending []models.PendingEvent
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx,
+			`SELECT o.sequence, o.id, o.tenant_id, o.type, o.entity_id, o.data, o.occurred_at, o.attempts
+				FROM outbox_event o
+				WHERE o.published_at IS NULL AND (o.next_attempt_at IS NULL OR o.next_attempt_at <= ?)
+				AND NOT EXISTS (
+					SELECT 1 FROM outbox_event e
+					WHERE e.entity_id = o.entity_id AND e.sequence < o.sequence
+					AND e.published_at IS NULL AND e.next_attempt_at > ?
+				)
+				ORDER BY o.sequence LIMIT ?`, now.UTC(), now.UTC(), limit)
+		if err != nil {
+			return err
+		}
+		for rows.Next() {
+			var (
+				next models.PendingEvent
+				data string
+			)
+			event := &next.Event
+			if err := rows.Scan(&event.Sequence, &event.ID, &event.TenantID, &event.Type, &event.EntityID, &data, &event.OccurredAt, &next.Attempts); err != nil {
+				rows.Close()
+				return err
+			}
+			event.Data = []byte(data)
+			pending = append(pending, next)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		for _, next := range pending {
+			if _, err := tx.ExecContext(ctx, "UPDATE outbox_event SET next_attempt_at = ? WHERE sequence = ?",
+				until.UTC(), next.Event.Sequence); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pending, nil
+}
+
+func (s *Store) FinishEvents(ctx context.Context, results []models.EventResult, now time.Time) (int, error) {
+	tracer := otel.Tracer("SQLiteStore")
+	ctx, span := tracer.Start(ctx, "FinishEvents-SQLiteStore")
+	defer span.End()
+
+	published := 0
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		for _, result := range results {
+			var err error
+			switch {
+			case result.Published:
+				_, err = tx.ExecContext(ctx, "UPDATE outbox_event SET published_at = ? WHERE sequence = ?", now.UTC(), result.Sequence)
+				published++
+			case result.Error != "":
+				_, err = tx.ExecContext(ctx,
+					"UPDATE outbox_event SET attempts = attempts + 1, last_error = ?, next_attempt_at = ? WHERE sequence = ?",
+					result.Error, result.RetryAt.UTC(), result.Sequence)
+			default:
+				_, err = tx.ExecContext(ctx, "UPDATE outbox_event SET next_attempt_at = ? WHERE sequence = ?",
+					result.RetryAt.UTC(), result.Sequence)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return published, nil
+}
+
+func (s *Store) DeletePublishedEvents(ctx context.Context, before time.Time) (int64, error) {
+	tracer := otel.Tracer("SQLiteStore")
+	ctx, span := tracer.Start(ctx, "DeletePublishedEvents-SQLiteStore")
+	defer span.End()
+
+	result, err := s.db.ExecContext(ctx, "DELETE FROM outbox_event WHERE published_at < ?", before.UTC())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
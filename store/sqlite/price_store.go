@@ -0,0 +1,274 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/adohong4/carZone/models"
+	"github.com/adohong4/carZone/store"
+	"github.com/adohong4/carZone/tenant"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+)
+
+const (
+	carPriceColumns  = "id, car_id, price_minor, currency, effective_from, effective_to, created_by, created_at"
+	promotionColumns = "id, name, kind, percent, amount_minor, currency, brand, fuel_type, starts_at, ends_at, created_at"
+)
+
+func scanCarPrice(row scanner) (models.CarPrice, error) {
+	var price models.CarPrice
+	err := row.Scan(
+		&price.ID, &price.CarID, &price.Price.Amount, &price.Price.Currency,
+		&price.EffectiveFrom, &price.EffectiveTo, &price.CreatedBy, &price.CreatedAt,
+	)
+	return price, err
+}
+
+func scanPromotion(row scanner) (models.Promotion, error) {
+	var promotion models.Promotion
+	err := row.Scan(
+		&promotion.ID, &promotion.Name, &promotion.Kind, &promotion.Percent,
+		&promotion.Amount.Amount, &promotion.Amount.Currency, &promotion.Brand, &promotion.FuelType,
+		&promotion.StartsAt, &promotion.EndsAt, &promotion.CreatedAt,
+	)
+	return promotion, err
+}
+
+// insertCarPrice starts the price history of car, or continues it, with
+// its price from UpdatedAt on.
+func insertCarPrice(ctx context.Context, tx *sql.Tx, car models.Car, tenantID string) error {
+	_, err := tx.ExecContext(ctx,
+		`INSERT INTO car_price (id, tenant_id, car_id, price_minor, currency, effective_from, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		uuid.New().String(), tenantID, car.ID.String(), car.Price.Amount, car.Price.Currency,
+		car.UpdatedAt.UTC(), car.UpdatedAt.UTC())
+	return err
+}
+
+func (s *Store) ListCarPrices(ctx context.Context, carID string) ([]models.CarPrice, error) {
+	tracer := otel.Tracer("SQLiteStore")
+	ctx, span := tracer.Start(ctx, "ListCarPrices-SQLiteStore")
+	defer span.End()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT "+carPriceColumns+" FROM car_price WHERE car_id = ? AND tenant_id = ? ORDER BY effective_from, created_at",
+		carID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var prices []models.CarPrice
+	for rows.Next() {
+		price, err := scanCarPrice(rows)
+		if err != nil {
+			return nil, err
+		}
+		prices = append(prices, price)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return prices, nil
+}
+
+func (s *Store) AddCarPrice(ctx context.Context, price models.CarPrice) (models.CarPrice, error) {
+	tracer := otel.Tracer("SQLiteStore")
+	ctx, span := tracer.Start(ctx, "AddCarPrice-SQLiteStore")
+	defer span.End()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return models.CarPrice{}, err
+	}
+
+	var added models.CarPrice
+	err = s.withTx(ctx, func(tx *sql.Tx) error {
+		var carID string
+		err := tx.QueryRowContext(ctx, "SELECT id FROM car WHERE id = ? AND tenant_id = ?", price.CarID.String(), tenantID).Scan(&carID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return store.ErrCarNotFound
+			}
+			return err
+		}
+
+		query := `INSERT INTO car_price (id, tenant_id, car_id, price_minor, currency, effective_from, effective_to, created_by, created_at)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+				RETURNING ` + carPriceColumns
+		added, err = scanCarPrice(tx.QueryRowContext(ctx, query,
+			price.ID.String(), tenantID, carID, price.Price.Amount, price.Price.Currency,
+			price.EffectiveFrom.UTC(), utcOrNil(price.EffectiveTo), price.CreatedBy, price.CreatedAt.UTC(),
+		))
+		return err
+	})
+	if err != nil {
+		return models.CarPrice{}, err
+	}
+	return added, nil
+}
+
+func (s *Store) DeleteCarPrice(ctx context.Context, carID, id string, now time.Time) (models.CarPrice, error) {
+	tracer := otel.Tracer("SQLiteStore")
+	ctx, span := tracer.Start(ctx, "DeleteCarPrice-SQLiteStore")
+	defer span.End()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return models.CarPrice{}, err
+	}
+
+	deleted, err := scanCarPrice(s.db.QueryRowContext(ctx,
+		"DELETE FROM car_price WHERE id = ? AND car_id = ? AND tenant_id = ? AND effective_from > ? RETURNING "+carPriceColumns,
+		id, carID, tenantID, now.UTC()))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.CarPrice{}, nil
+		}
+		return models.CarPrice{}, err
+	}
+	return deleted, nil
+}
+
+// ApplyCarPrices ranks the prices in effect per car, latest start first,
+// and copies the winner onto cars that still show another price.
+func (s *Store) ApplyCarPrices(ctx context.Context, now time.Time) ([]models.RepricedCar, error) {
+	tracer := otel.Tracer("SQLiteStore")
+	ctx, span := tracer.Start(ctx, "ApplyCarPrices-SQLiteStore")
+	defer span.End()
+
+	query := `WITH effective AS (
+				SELECT car_id, price_minor, currency FROM (
+					SELECT car_id, price_minor, currency,
+						ROW_NUMBER() OVER (PARTITION BY car_id ORDER BY effective_from DESC, created_at DESC) AS rank
+					FROM car_price
+					WHERE effective_from <= ? AND (effective_to IS NULL OR effective_to > ?)
+				) WHERE rank = 1
+			)
+			UPDATE car SET price_minor = effective.price_minor, currency = effective.currency, updated_at = ?
+			FROM effective
+			WHERE car.id = effective.car_id AND (car.price_minor <> effective.price_minor OR car.currency <> effective.currency)
+			RETURNING tenant_id, id, name, year, brand, fuel_type, engine_id, price_minor, currency, created_at, updated_at`
+
+	var applied []models.RepricedCar
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx, query, now.UTC(), now.UTC(), now.UTC())
+		if err != nil {
+			return err
+		}
+		var (
+			tenantIDs []string
+			repriced  []models.Car
+		)
+		for rows.Next() {
+			var (
+				tenantID string
+				car      models.Car
+			)
+			err := rows.Scan(&tenantID, &car.ID, &car.Name, &car.Year, &car.Brand, &car.FuelType, &car.Engine.EngineID,
+				&car.Price.Amount, &car.Price.Currency, &car.CreatedAt, &car.UpdatedAt)
+			if err != nil {
+				rows.Close()
+				return err
+			}
+			tenantIDs = append(tenantIDs, tenantID)
+			repriced = append(repriced, car)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		for i, car := range repriced {
+			if err := recordEvent(ctx, tx, models.EventCarUpdated, tenantIDs[i], car.ID, car); err != nil {
+				return err
+			}
+			applied = append(applied, models.RepricedCar{TenantID: tenantIDs[i], CarID: car.ID})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return applied, nil
+}
+
+func (s *Store) ListPromotions(ctx context.Context) ([]models.Promotion, error) {
+	tracer := otel.Tracer("SQLiteStore")
+	ctx, span := tracer.Start(ctx, "ListPromotions-SQLiteStore")
+	defer span.End()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT "+promotionColumns+" FROM promotion WHERE tenant_id = ? ORDER BY starts_at, created_at", tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var promotions []models.Promotion
+	for rows.Next() {
+		promotion, err := scanPromotion(rows)
+		if err != nil {
+			return nil, err
+		}
+		promotions = append(promotions, promotion)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return promotions, nil
+}
+
+func (s *Store) CreatePromotion(ctx context.Context, promotion models.Promotion) (models.Promotion, error) {
+	tracer := otel.Tracer("SQLiteStore")
+	ctx, span := tracer.Start(ctx, "CreatePromotion-SQLiteStore")
+	defer span.End()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return models.Promotion{}, err
+	}
+
+	query := `INSERT INTO promotion (id, tenant_id, name, kind, percent, amount_minor, currency, brand, fuel_type, starts_at, ends_at, created_at)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+				RETURNING ` + promotionColumns
+	return scanPromotion(s.db.QueryRowContext(ctx, query,
+		promotion.ID.String(), tenantID, promotion.Name, promotion.Kind, promotion.Percent,
+		promotion.Amount.Amount, promotion.Amount.Currency, promotion.Brand, promotion.FuelType,
+		promotion.StartsAt.UTC(), utcOrNil(promotion.EndsAt), promotion.CreatedAt.UTC(),
+	))
+}
+
+func (s *Store) DeletePromotion(ctx context.Context, id string) (models.Promotion, error) {
+	tracer := otel.Tracer("SQLiteStore")
+	ctx, span := tracer.Start(ctx, "DeletePromotion-SQLiteStore")
+	defer span.End()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return models.Promotion{}, err
+	}
+
+	deleted, err := scanPromotion(s.db.QueryRowContext(ctx,
+		"DELETE FROM promotion WHERE id = ? AND tenant_id = ? RETURNING "+promotionColumns, id, tenantID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Promotion{}, nil
+		}
+		return models.Promotion{}, err
+	}
+	return deleted, nil
+}
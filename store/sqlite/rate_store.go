@@ -0,0 +1,48 @@
+package sqlite
+
+import (
+	"context"
+
+	"github.com/adohong4/carZone/models"
+	"go.opentelemetry.io/otel"
+)
+
+func (s *Store) GetRates(ctx context.Context) ([]models.ExchangeRate, error) {
+	tracer := otel.Tracer("SQLiteStore")
+	ctx, span := tracer.Start(ctx, "GetRates-SQLiteStore")
+	defer span.End()
+
+	rows, err := s.db.QueryContext(ctx, "SELECT currency, rate, updated_at FROM exchange_rate ORDER BY currency")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rates []models.ExchangeRate
+	for rows.Next() {
+		var rate models.ExchangeRate
+		if err := rows.Scan(&rate.Currency, &rate.Rate, &rate.UpdatedAt); err != nil {
+			return nil, err
+		}
+		rates = append(rates, rate)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return rates, nil
+}
+
+func (s *Store) SetRate(ctx context.Context, rate models.ExchangeRate) (models.ExchangeRate, error) {
+	tracer := otel.Tracer("SQLiteStore")
+	ctx, span := tracer.Start(ctx, "SetRate-SQLiteStore")
+	defer span.End()
+
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO exchange_rate (currency, rate, updated_at) VALUES (?, ?, ?)
+		ON CONFLICT (currency) DO UPDATE SET rate = excluded.rate, updated_at = excluded.updated_at`,
+		rate.Currency, rate.Rate, rate.UpdatedAt.UTC())
+	if err != nil {
+		return models.ExchangeRate{}, err
+	}
+	return rate, nil
+}
@@ -0,0 +1,257 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/adohong4/carZone/models"
+	"github.com/adohong4/carZone/store"
+	"github.com/adohong4/carZone/tenant"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+)
+
+const reservationColumns = "id, car_id, customer_id, customer_name, customer_contact, reserved_by, status, expires_at, created_at, updated_at"
+
+func scanReservation(row scanner) (models.Reservation, error) {
+	var (
+		reservation models.Reservation
+		customerID  uuid.NullUUID
+	)
+	err := row.Scan(
+		&reservation.ID, &reservation.CarID, &customerID, &reservation.CustomerName, &reservation.CustomerContact,
+		&reservation.ReservedBy, &reservation.Status, &reservation.ExpiresAt,
+		&reservation.CreatedAt, &reservation.UpdatedAt,
+	)
+	if customerID.Valid {
+		reservation.CustomerID = &customerID.UUID
+	}
+	return reservation, err
+}
+
+// checkCustomer returns ErrCustomerNotFound unless customerID is empty or a
+// customer of the dealership that has not been erased.
+func checkCustomer(ctx context.Context, tx *sql.Tx, tenantID string, customerID *uuid.UUID) error {
+	if customerID == nil {
+		return nil
+	}
+	var id string
+	err := tx.QueryRowContext(ctx, "SELECT id FROM customer WHERE id = ? AND tenant_id = ? AND erased_at IS NULL",
+		customerID.String(), tenantID).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return store.ErrCustomerNotFound
+	}
+	return err
+}
+
+// nullableID stores an optional UUID in a TEXT column.
+func nullableID(id *uuid.UUID) interface{} {
+	if id == nil {
+		return nil
+	}
+	return id.String()
+}
+
+// CreateReservation relies on the immediate transactions the driver is
+// opened with: the write lock is taken at BEGIN, so two salespeople
+// reserving the same car are served one after the other and the second one
+// sees the first hold.
+func (s *Store) CreateReservation(ctx context.Context, reservation models.Reservation) (models.Reservation, error) {
+	tracer := otel.Tracer("SQLiteStore")
+	ctx, span := tracer.Start(ctx, "CreateReservation-SQLiteStore")
+	defer span.End()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return models.Reservation{}, err
+	}
+
+	createdAt := reservation.CreatedAt.UTC()
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Reservation{}, err
+	}
+	defer tx.Rollback()
+
+	var carID string
+	err = tx.QueryRowContext(ctx, "SELECT id FROM car WHERE id = ? AND tenant_id = ?", reservation.CarID.String(), tenantID).Scan(&carID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Reservation{}, store.ErrCarNotFound
+		}
+		return models.Reservation{}, err
+	}
+
+	// a hold past its expiry that the sweeper did not get to yet is over
+	_, err = tx.ExecContext(ctx,
+		"UPDATE reservation SET status = ?, updated_at = ? WHERE car_id = ? AND status = ? AND expires_at <= ?",
+		models.ReservationExpired, createdAt, reservation.CarID.String(), models.ReservationActive, createdAt)
+	if err != nil {
+		return models.Reservation{}, err
+	}
+
+	var held int
+	err = tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM reservation WHERE car_id = ? AND status = ?",
+		reservation.CarID.String(), models.ReservationActive).Scan(&held)
+	if err != nil {
+		return models.Reservation{}, err
+	}
+	if held > 0 {
+		return models.Reservation{}, store.ErrCarReserved
+	}
+	if err = checkCustomer(ctx, tx, tenantID, reservation.CustomerID); err != nil {
+		return models.Reservation{}, err
+	}
+
+	query := `INSERT INTO reservation (id, tenant_id, car_id, customer_id, customer_name, customer_contact, reserved_by, status, expires_at, created_at, updated_at)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+				RETURNING ` + reservationColumns
+	created, err := scanReservation(tx.QueryRowContext(ctx, query,
+		reservation.ID.String(), tenantID, reservation.CarID.String(), nullableID(reservation.CustomerID),
+		reservation.CustomerName, reservation.CustomerContact,
+		reservation.ReservedBy, reservation.Status, reservation.ExpiresAt.UTC(), createdAt, reservation.UpdatedAt.UTC(),
+	))
+	if err != nil {
+		return models.Reservation{}, err
+	}
+	if err = tx.Commit(); err != nil {
+		return models.Reservation{}, err
+	}
+	return created, nil
+}
+
+func (s *Store) GetActiveReservation(ctx context.Context, carID string, now time.Time) (models.Reservation, error) {
+	tracer := otel.Tracer("SQLiteStore")
+	ctx, span := tracer.Start(ctx, "GetActiveReservation-SQLiteStore")
+	defer span.End()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return models.Reservation{}, err
+	}
+
+	query := "SELECT " + reservationColumns + " FROM reservation WHERE car_id = ? AND tenant_id = ? AND status = ? AND expires_at > ?"
+	reservation, err := scanReservation(s.db.QueryRowContext(ctx, query, carID, tenantID, models.ReservationActive, now.UTC()))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Reservation{}, nil
+		}
+		return models.Reservation{}, err
+	}
+	return reservation, nil
+}
+
+func (s *Store) ListReservations(ctx context.Context, carID string) ([]models.Reservation, error) {
+	tracer := otel.Tracer("SQLiteStore")
+	ctx, span := tracer.Start(ctx, "ListReservations-SQLiteStore")
+	defer span.End()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := "SELECT " + reservationColumns + " FROM reservation WHERE car_id = ? AND tenant_id = ? ORDER BY created_at DESC, id"
+	rows, err := s.db.QueryContext(ctx, query, carID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var reservations []models.Reservation
+	for rows.Next() {
+		reservation, err := scanReservation(rows)
+		if err != nil {
+			return nil, err
+		}
+		reservations = append(reservations, reservation)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return reservations, nil
+}
+
+// ListCustomerReservations lists the reservations made for the customer,
+// the latest first.
+func (s *Store) ListCustomerReservations(ctx context.Context, customerID string) ([]models.Reservation, error) {
+	tracer := otel.Tracer("SQLiteStore")
+	ctx, span := tracer.Start(ctx, "ListCustomerReservations-SQLiteStore")
+	defer span.End()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := "SELECT " + reservationColumns + " FROM reservation WHERE customer_id = ? AND tenant_id = ? ORDER BY created_at DESC, id"
+	rows, err := s.db.QueryContext(ctx, query, customerID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var reservations []models.Reservation
+	for rows.Next() {
+		reservation, err := scanReservation(rows)
+		if err != nil {
+			return nil, err
+		}
+		reservations = append(reservations, reservation)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return reservations, nil
+}
+
+func (s *Store) ReleaseReservation(ctx context.Context, carID, id string, now time.Time) (models.Reservation, error) {
+	tracer := otel.Tracer("SQLiteStore")
+	ctx, span := tracer.Start(ctx, "ReleaseReservation-SQLiteStore")
+	defer span.End()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return models.Reservation{}, err
+	}
+
+	query := `UPDATE reservation SET status = ?, updated_at = ?
+				WHERE id = ? AND car_id = ? AND tenant_id = ? AND status = ? AND expires_at > ?
+				RETURNING ` + reservationColumns
+	released, err := scanReservation(s.db.QueryRowContext(ctx, query,
+		models.ReservationReleased, now.UTC(), id, carID, tenantID, models.ReservationActive, now.UTC()))
+	if err == nil {
+		return released, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return models.Reservation{}, err
+	}
+
+	// tell a missing reservation from one that is no longer active
+	var exists bool
+	err = s.db.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM reservation WHERE id = ? AND car_id = ? AND tenant_id = ?)",
+		id, carID, tenantID).Scan(&exists)
+	if err != nil {
+		return models.Reservation{}, err
+	}
+	if exists {
+		return models.Reservation{}, store.ErrReservationNotActive
+	}
+	return models.Reservation{}, nil
+}
+
+func (s *Store) ExpireReservations(ctx context.Context, now time.Time) (int64, error) {
+	tracer := otel.Tracer("SQLiteStore")
+	ctx, span := tracer.Start(ctx, "ExpireReservations-SQLiteStore")
+	defer span.End()
+
+	result, err := s.db.ExecContext(ctx,
+		"UPDATE reservation SET status = ?, updated_at = ? WHERE status = ? AND expires_at <= ?",
+		models.ReservationExpired, now.UTC(), models.ReservationActive, now.UTC())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
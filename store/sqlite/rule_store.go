@@ -0,0 +1,82 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/adohong4/carZone/models"
+	"github.com/adohong4/carZone/tenant"
+	"go.opentelemetry.io/otel"
+)
+
+func (s *Store) GetValidationRules(ctx context.Context) ([]models.ValidationRule, error) {
+	tracer := otel.Tracer("SQLiteStore")
+	ctx, span := tracer.Start(ctx, "GetValidationRules-SQLiteStore")
+	defer span.End()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT definition FROM validation_rule WHERE tenant_id = ? ORDER BY position", tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rules []models.ValidationRule
+	for rows.Next() {
+		var (
+			definition string
+			rule       models.ValidationRule
+		)
+		if err := rows.Scan(&definition); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(definition), &rule); err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+func (s *Store) SetValidationRules(ctx context.Context, rules []models.ValidationRule) ([]models.ValidationRule, error) {
+	tracer := otel.Tracer("SQLiteStore")
+	ctx, span := tracer.Start(ctx, "SetValidationRules-SQLiteStore")
+	defer span.End()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.withTx(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM validation_rule WHERE tenant_id = ?", tenantID); err != nil {
+			return err
+		}
+		for i, rule := range rules {
+			definition, err := json.Marshal(rule)
+			if err != nil {
+				return err
+			}
+			_, err = tx.ExecContext(ctx,
+				"INSERT INTO validation_rule (tenant_id, id, position, definition) VALUES (?, ?, ?, ?)",
+				tenantID, rule.ID, i, string(definition))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
@@ -0,0 +1,595 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/adohong4/carZone/models"
+	"github.com/adohong4/carZone/money"
+	"github.com/adohong4/carZone/store"
+	"github.com/adohong4/carZone/tenant"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+)
+
+// Store implements store.CarStoreInterface and store.EngineStoreInterface on
+// top of SQLite. It follows the Postgres stores query for query, only the
+// placeholders and the UUID/time column types differ.
+type Store struct {
+	db *sql.DB
+}
+
+func New(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+// withTx runs fn in a transaction, committing when fn succeeds.
+func (s *Store) withTx(ctx context.Context, fn func(tx *sql.Tx) error) (err error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+	return fn(tx)
+}
+
+func (s *Store) GetCarById(ctx context.Context, id string) (models.Car, error) {
+	tracer := otel.Tracer("SQLiteStore")
+	ctx, span := tracer.Start(ctx, "GetCarById-SQLiteStore")
+	defer span.End()
+
+	var car models.Car
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return car, err
+	}
+	carID, err := uuid.Parse(id)
+	if err != nil {
+		return car, fmt.Errorf("invalid car ID: %w", err)
+	}
+
+	query := `SELECT c.id, c.name, c.year, c.brand, c.fuel_type, c.price_minor, c.currency, c.created_at, c.updated_at,
+				e.id, e.engine_type, e.displacement, e.no_of_cylinders, e.car_range, e.motor_power, e.battery_capacity, e.updated_at, ` + carSpecColumns + `
+				FROM car c LEFT JOIN engine e ON c.engine_id = e.id AND e.tenant_id = c.tenant_id
+				LEFT JOIN car_spec s ON s.car_id = c.id
+				WHERE c.id = ? AND c.tenant_id = ?`
+
+	var spec specRow
+	err = s.db.QueryRowContext(ctx, query, carID.String(), tenantID).Scan(append([]interface{}{
+		&car.ID, &car.Name, &car.Year, &car.Brand, &car.FuelType, &car.Price.Amount, &car.Price.Currency,
+		&car.CreatedAt, &car.UpdatedAt,
+		&car.Engine.EngineID, &car.Engine.Type, &car.Engine.Displacement, &car.Engine.NoOfCylinders, &car.Engine.CarRange,
+		&car.Engine.MotorPower, &car.Engine.BatteryCapacity, &car.Engine.UpdatedAt,
+	}, spec.dest()...)...)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return car, nil
+		}
+		return car, err
+	}
+	car.Spec = spec.spec()
+	return car, nil
+}
+
+func (s *Store) GetCarByBrand(ctx context.Context, brand string, isEngine bool) ([]models.Car, error) {
+	tracer := otel.Tracer("SQLiteStore")
+	ctx, span := tracer.Start(ctx, "GetCarByBrand-SQLiteStore")
+	defer span.End()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var cars []models.Car
+	var query string
+	if isEngine {
+		query = `SELECT c.id, c.name, c.year, c.brand, c.fuel_type, c.price_minor, c.currency, c.created_at, c.updated_at,
+				e.id, e.engine_type, e.displacement, e.no_of_cylinders, e.car_range, e.motor_power, e.battery_capacity, e.updated_at, ` + carSpecColumns + `
+				FROM car c LEFT JOIN engine e ON c.engine_id = e.id AND e.tenant_id = c.tenant_id
+				LEFT JOIN car_spec s ON s.car_id = c.id
+				WHERE c.brand = ? AND c.tenant_id = ?
+				ORDER BY c.created_at, c.id`
+	} else {
+		query = `SELECT c.id, c.name, c.year, c.brand, c.fuel_type, c.price_minor, c.currency, c.created_at, c.updated_at, ` + carSpecColumns + `
+		FROM car c LEFT JOIN car_spec s ON s.car_id = c.id
+		WHERE c.brand = ? AND c.tenant_id = ?
+		ORDER BY c.created_at, c.id`
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, brand, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			car  models.Car
+			spec specRow
+		)
+		if isEngine {
+			err = rows.Scan(append([]interface{}{
+				&car.ID, &car.Name, &car.Year, &car.Brand, &car.FuelType, &car.Price.Amount, &car.Price.Currency,
+				&car.CreatedAt, &car.UpdatedAt,
+				&car.Engine.EngineID, &car.Engine.Type, &car.Engine.Displacement, &car.Engine.NoOfCylinders, &car.Engine.CarRange,
+				&car.Engine.MotorPower, &car.Engine.BatteryCapacity, &car.Engine.UpdatedAt,
+			}, spec.dest()...)...)
+		} else {
+			err = rows.Scan(append([]interface{}{
+				&car.ID, &car.Name, &car.Year, &car.Brand, &car.FuelType, &car.Price.Amount, &car.Price.Currency,
+				&car.CreatedAt, &car.UpdatedAt,
+			}, spec.dest()...)...)
+		}
+		if err != nil {
+			return nil, err
+		}
+		car.Spec = spec.spec()
+		cars = append(cars, car)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return cars, nil
+}
+
+// GetCarsByIds fetches the cars of ids with their engines in one query.
+// Missing cars are left out.
+func (s *Store) GetCarsByIds(ctx context.Context, ids []string) ([]models.Car, error) {
+	tracer := otel.Tracer("SQLiteStore")
+	ctx, span := tracer.Start(ctx, "GetCarsByIds-SQLiteStore")
+	defer span.End()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	args := []interface{}{tenantID}
+	for _, id := range ids {
+		carID, err := uuid.Parse(id)
+		if err != nil {
+			return nil, fmt.Errorf("invalid car ID: %w", err)
+		}
+		args = append(args, carID.String())
+	}
+	query := `SELECT c.id, c.name, c.year, c.brand, c.fuel_type, c.price_minor, c.currency, c.created_at, c.updated_at,
+				e.id, e.engine_type, e.displacement, e.no_of_cylinders, e.car_range, e.motor_power, e.battery_capacity, e.updated_at, ` + carSpecColumns + `
+				FROM car c LEFT JOIN engine e ON c.engine_id = e.id AND e.tenant_id = c.tenant_id
+				LEFT JOIN car_spec s ON s.car_id = c.id
+				WHERE c.tenant_id = ? AND c.id IN (?` + strings.Repeat(", ?", len(ids)-1) + `)
+				ORDER BY c.created_at, c.id`
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var cars []models.Car
+	for rows.Next() {
+		var (
+			car  models.Car
+			spec specRow
+		)
+		err = rows.Scan(append([]interface{}{
+			&car.ID, &car.Name, &car.Year, &car.Brand, &car.FuelType, &car.Price.Amount, &car.Price.Currency,
+			&car.CreatedAt, &car.UpdatedAt,
+			&car.Engine.EngineID, &car.Engine.Type, &car.Engine.Displacement, &car.Engine.NoOfCylinders, &car.Engine.CarRange,
+			&car.Engine.MotorPower, &car.Engine.BatteryCapacity, &car.Engine.UpdatedAt,
+		}, spec.dest()...)...)
+		if err != nil {
+			return nil, err
+		}
+		car.Spec = spec.spec()
+		cars = append(cars, car)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return cars, nil
+}
+
+func (s *Store) CreateCar(ctx context.Context, carReq *models.CarRequest) (models.Car, error) {
+	tracer := otel.Tracer("SQLiteStore")
+	ctx, span := tracer.Start(ctx, "CreateCar-SQLiteStore")
+	defer span.End()
+
+	var createdCar models.Car
+	now := time.Now().UTC()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return createdCar, err
+	}
+
+	err = s.withTx(ctx, func(tx *sql.Tx) error {
+		var engineID string
+		err := tx.QueryRowContext(ctx, "SELECT id FROM engine WHERE id = ? AND tenant_id = ?", carReq.Engine.EngineID.String(), tenantID).Scan(&engineID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return errors.New("engine_id does not exists in the engine table")
+			}
+			return err
+		}
+
+		query := `INSERT INTO car (id, name, year, brand, fuel_type, engine_id, price_minor, currency, created_at, updated_at, tenant_id)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+				RETURNING id, name, year, brand, fuel_type, engine_id, price_minor, currency, created_at, updated_at`
+
+		err = tx.QueryRowContext(ctx, query,
+			uuid.New().String(),
+			carReq.Name,
+			carReq.Year,
+			carReq.Brand,
+			carReq.FuelType,
+			carReq.Engine.EngineID.String(),
+			carReq.Price.Amount,
+			carReq.Price.Currency,
+			now,
+			now,
+			tenantID,
+		).Scan(
+			&createdCar.ID,
+			&createdCar.Name,
+			&createdCar.Year,
+			&createdCar.Brand,
+			&createdCar.FuelType,
+			&createdCar.Engine.EngineID,
+			&createdCar.Price.Amount,
+			&createdCar.Price.Currency,
+			&createdCar.CreatedAt,
+			&createdCar.UpdatedAt,
+		)
+		if err != nil {
+			return err
+		}
+		if err := insertCarPrice(ctx, tx, createdCar, tenantID); err != nil {
+			return err
+		}
+		if err := saveCarSpec(ctx, tx, createdCar.ID, carReq.Spec); err != nil {
+			return err
+		}
+		createdCar.Spec = carReq.Spec
+		return recordEvent(ctx, tx, models.EventCarCreated, tenantID, createdCar.ID, createdCar)
+	})
+	if err != nil {
+		return models.Car{}, err
+	}
+	return createdCar, nil
+}
+
+func (s *Store) UpdateCar(ctx context.Context, id string, carReq *models.CarRequest) (models.Car, error) {
+	tracer := otel.Tracer("SQLiteStore")
+	ctx, span := tracer.Start(ctx, "UpdateCar-SQLiteStore")
+	defer span.End()
+
+	var updatedCar models.Car
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return updatedCar, err
+	}
+	carID, err := uuid.Parse(id)
+	if err != nil {
+		return updatedCar, fmt.Errorf("invalid car ID: %w", err)
+	}
+
+	err = s.withTx(ctx, func(tx *sql.Tx) error {
+		var engineID string
+		err := tx.QueryRowContext(ctx, "SELECT id FROM engine WHERE id = ? AND tenant_id = ?", carReq.Engine.EngineID.String(), tenantID).Scan(&engineID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return errors.New("engine_id does not exists in the engine table")
+			}
+			return err
+		}
+
+		var oldPrice money.Money
+		err = tx.QueryRowContext(ctx, "SELECT price_minor, currency FROM car WHERE id = ? AND tenant_id = ?", carID.String(), tenantID).Scan(
+			&oldPrice.Amount, &oldPrice.Currency,
+		)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return errors.New("car not found")
+			}
+			return err
+		}
+
+		query := `UPDATE car
+				SET name = ?, year = ?, brand = ?, fuel_type = ?, engine_id = ?, price_minor = ?, currency = ?, updated_at = ?
+				WHERE id = ? AND tenant_id = ?
+				RETURNING id, name, year, brand, fuel_type, engine_id, price_minor, currency, created_at, updated_at`
+
+		err = tx.QueryRowContext(ctx, query,
+			carReq.Name,
+			carReq.Year,
+			carReq.Brand,
+			carReq.FuelType,
+			carReq.Engine.EngineID.String(),
+			carReq.Price.Amount,
+			carReq.Price.Currency,
+			time.Now().UTC(),
+			carID.String(),
+			tenantID,
+		).Scan(
+			&updatedCar.ID,
+			&updatedCar.Name,
+			&updatedCar.Year,
+			&updatedCar.Brand,
+			&updatedCar.FuelType,
+			&updatedCar.Engine.EngineID,
+			&updatedCar.Price.Amount,
+			&updatedCar.Price.Currency,
+			&updatedCar.CreatedAt,
+			&updatedCar.UpdatedAt,
+		)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return errors.New("car not found")
+			}
+			return err
+		}
+		if updatedCar.Price != oldPrice {
+			if err := insertCarPrice(ctx, tx, updatedCar, tenantID); err != nil {
+				return err
+			}
+		}
+		if err := saveCarSpec(ctx, tx, updatedCar.ID, carReq.Spec); err != nil {
+			return err
+		}
+		updatedCar.Spec = carReq.Spec
+		return recordEvent(ctx, tx, models.EventCarUpdated, tenantID, updatedCar.ID, updatedCar)
+	})
+	if err != nil {
+		return models.Car{}, err
+	}
+	return updatedCar, nil
+}
+
+func (s *Store) DeleteCar(ctx context.Context, id string) (models.Car, error) {
+	tracer := otel.Tracer("SQLiteStore")
+	ctx, span := tracer.Start(ctx, "DeleteCar-SQLiteStore")
+	defer span.End()
+
+	var deletedCar models.Car
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return deletedCar, err
+	}
+	carID, err := uuid.Parse(id)
+	if err != nil {
+		return deletedCar, fmt.Errorf("invalid car ID: %w", err)
+	}
+
+	err = s.withTx(ctx, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(ctx, "SELECT id, name, year, brand, fuel_type, engine_id, price_minor, currency, created_at, updated_at FROM car WHERE id = ? AND tenant_id = ?", carID.String(), tenantID).Scan(
+			&deletedCar.ID,
+			&deletedCar.Name,
+			&deletedCar.Year,
+			&deletedCar.Brand,
+			&deletedCar.FuelType,
+			&deletedCar.Engine.EngineID,
+			&deletedCar.Price.Amount,
+			&deletedCar.Price.Currency,
+			&deletedCar.CreatedAt,
+			&deletedCar.UpdatedAt,
+		)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return errors.New("car not found")
+			}
+			return err
+		}
+
+		// vehicle.car_id and order_line.car_id REFERENCES car(id)
+		var hasVehicles, onOrder bool
+		err = tx.QueryRowContext(ctx,
+			"SELECT EXISTS (SELECT 1 FROM vehicle WHERE tenant_id = ? AND car_id = ?), EXISTS (SELECT 1 FROM order_line WHERE car_id = ?)",
+			tenantID, carID.String(), carID.String()).Scan(&hasVehicles, &onOrder)
+		if err != nil {
+			return err
+		}
+		if hasVehicles {
+			return store.ErrCarHasVehicles
+		}
+		if onOrder {
+			return store.ErrCarOnOrder
+		}
+
+		result, err := tx.ExecContext(ctx, "DELETE FROM car WHERE id = ? AND tenant_id = ?", carID.String(), tenantID)
+		if err != nil {
+			return err
+		}
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rowsAffected == 0 {
+			return errors.New("No rows were deleted")
+		}
+		return recordEvent(ctx, tx, models.EventCarDeleted, tenantID, deletedCar.ID, deletedCar)
+	})
+	if err != nil {
+		return models.Car{}, err
+	}
+	return deletedCar, nil
+}
+
+func (s *Store) EngineById(ctx context.Context, id string) (models.Engine, error) {
+	tracer := otel.Tracer("SQLiteStore")
+	ctx, span := tracer.Start(ctx, "EngineById-SQLiteStore")
+	defer span.End()
+
+	var engine models.Engine
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return engine, err
+	}
+	engineID, err := uuid.Parse(id)
+	if err != nil {
+		return engine, fmt.Errorf("invalid engine ID: %w", err)
+	}
+
+	err = s.db.QueryRowContext(ctx, "SELECT id, engine_type, displacement, no_of_cylinders, car_range, motor_power, battery_capacity, updated_at FROM engine WHERE id = ? AND tenant_id = ?", engineID.String(), tenantID).Scan(
+		&engine.EngineID, &engine.Type, &engine.Displacement, &engine.NoOfCylinders, &engine.CarRange,
+		&engine.MotorPower, &engine.BatteryCapacity, &engine.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return engine, nil // No rows found, return empty engine
+		}
+		return engine, err
+	}
+	return engine, nil
+}
+
+func (s *Store) CreateEngine(ctx context.Context, engineReq *models.EngineRequest) (models.Engine, error) {
+	tracer := otel.Tracer("SQLiteStore")
+	ctx, span := tracer.Start(ctx, "CreateEngine-SQLiteStore")
+	defer span.End()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return models.Engine{}, err
+	}
+
+	engine := models.Engine{
+		EngineID:        uuid.New(),
+		Type:            models.EngineType(engineReq.Type),
+		Displacement:    engineReq.Displacement,
+		NoOfCylinders:   engineReq.NoOfCylinders,
+		CarRange:        engineReq.CarRange,
+		MotorPower:      engineReq.MotorPower,
+		BatteryCapacity: engineReq.BatteryCapacity,
+		UpdatedAt:       time.Now().UTC(),
+	}
+
+	err = s.withTx(ctx, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO engine (id, engine_type, displacement, no_of_cylinders, car_range, motor_power, battery_capacity, updated_at, tenant_id)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			engine.EngineID.String(), engine.Type, engine.Displacement, engine.NoOfCylinders, engine.CarRange,
+			engine.MotorPower, engine.BatteryCapacity, engine.UpdatedAt, tenantID,
+		)
+		if err != nil {
+			return err
+		}
+		return recordEvent(ctx, tx, models.EventEngineCreated, tenantID, engine.EngineID, engine)
+	})
+	if err != nil {
+		return models.Engine{}, err
+	}
+	return engine, nil
+}
+
+func (s *Store) EngineUpdate(ctx context.Context, id string, engineReq *models.EngineRequest) (models.Engine, error) {
+	tracer := otel.Tracer("SQLiteStore")
+	ctx, span := tracer.Start(ctx, "EngineUpdate-SQLiteStore")
+	defer span.End()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return models.Engine{}, err
+	}
+	engineID, err := uuid.Parse(id)
+	if err != nil {
+		return models.Engine{}, fmt.Errorf("invalid engine ID: %w", err)
+	}
+
+	engine := models.Engine{
+		EngineID:        engineID,
+		Type:            models.EngineType(engineReq.Type),
+		Displacement:    engineReq.Displacement,
+		NoOfCylinders:   engineReq.NoOfCylinders,
+		CarRange:        engineReq.CarRange,
+		MotorPower:      engineReq.MotorPower,
+		BatteryCapacity: engineReq.BatteryCapacity,
+		UpdatedAt:       time.Now().UTC(),
+	}
+
+	err = s.withTx(ctx, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx,
+			`UPDATE engine SET engine_type = ?, displacement = ?, no_of_cylinders = ?, car_range = ?, motor_power = ?, battery_capacity = ?, updated_at = ?
+			WHERE id = ? AND tenant_id = ?`,
+			engine.Type, engine.Displacement, engine.NoOfCylinders, engine.CarRange,
+			engine.MotorPower, engine.BatteryCapacity, engine.UpdatedAt, engineID.String(), tenantID)
+		if err != nil {
+			return err
+		}
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rowsAffected == 0 {
+			return errors.New("No Rows Were Updated")
+		}
+		return recordEvent(ctx, tx, models.EventEngineUpdated, tenantID, engine.EngineID, engine)
+	})
+	if err != nil {
+		return models.Engine{}, err
+	}
+	return engine, nil
+}
+
+func (s *Store) EngineDelete(ctx context.Context, id string) (models.Engine, error) {
+	tracer := otel.Tracer("SQLiteStore")
+	ctx, span := tracer.Start(ctx, "EngineDelete-SQLiteStore")
+	defer span.End()
+
+	var engine models.Engine
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return engine, err
+	}
+	engineID, err := uuid.Parse(id)
+	if err != nil {
+		return engine, fmt.Errorf("invalid engine ID: %w", err)
+	}
+
+	err = s.withTx(ctx, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(ctx, "SELECT id, engine_type, displacement, no_of_cylinders, car_range, motor_power, battery_capacity, updated_at FROM engine WHERE id = ? AND tenant_id = ?", engineID.String(), tenantID).Scan(
+			&engine.EngineID, &engine.Type, &engine.Displacement, &engine.NoOfCylinders, &engine.CarRange,
+			&engine.MotorPower, &engine.BatteryCapacity, &engine.UpdatedAt,
+		)
+		if err != nil {
+			return err
+		}
+
+		result, err := tx.ExecContext(ctx, "DELETE FROM engine WHERE id = ? AND tenant_id = ?", engineID.String(), tenantID)
+		if err != nil {
+			return err
+		}
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rowsAffected == 0 {
+			return errors.New("No Rows Were Deleted")
+		}
+		return recordEvent(ctx, tx, models.EventEngineDeleted, tenantID, engine.EngineID, engine)
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Engine{}, nil // No rows found, return empty engine
+		}
+		return models.Engine{}, err
+	}
+	return engine, nil
+}
@@ -0,0 +1,156 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/adohong4/carZone/models"
+	"github.com/adohong4/carZone/store/storetest"
+	"github.com/adohong4/carZone/tenant"
+	_ "modernc.org/sqlite"
+)
+
+func openTestDB(t *testing.T) *sql.DB {
+	path := filepath.Join(t.TempDir(), "carzone.db")
+	db, err := sql.Open("sqlite", fmt.Sprintf("file:%s?_pragma=foreign_keys(1)&_txlock=immediate", path))
+	if err != nil {
+		t.Fatalf("cannot open sqlite database: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	if err := Migrate(context.Background(), db); err != nil {
+		t.Fatalf("cannot migrate sqlite database: %v", err)
+	}
+	return db
+}
+
+func TestConformance(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) storetest.Stores {
+		db := openTestDB(t)
+		s := New(db)
+		return storetest.Stores{Cars: s, Engines: s, Vehicles: s, Reservations: s, Orders: s, Customers: s, Rates: s, Prices: s, Images: s, Catalogue: s, Rules: s, Reports: s, Outbox: s, Webhooks: s,
+			Logins: NewLoginStore(db), TOTP: s}
+	})
+}
+
+func TestMigrateIsIdempotent(t *testing.T) {
+	db := openTestDB(t)
+	if err := Migrate(context.Background(), db); err != nil {
+		t.Fatalf("second migration run failed: %v", err)
+	}
+
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM schema_migrations").Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count == 0 {
+		t.Fatal("expected applied migrations to be recorded")
+	}
+}
+
+// TestMigrateNormalizesBrands checks the catalogue migration gives the cars
+// already stored one spelling of their brand.
+func TestMigrateNormalizesBrands(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "carzone.db")
+	db, err := sql.Open("sqlite", fmt.Sprintf("file:%s?_pragma=foreign_keys(1)&_txlock=immediate", path))
+	if err != nil {
+		t.Fatalf("cannot open sqlite database: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	// stop short of the catalogue, as a database from before it
+	if _, err := db.Exec("CREATE TABLE schema_migrations (version TEXT PRIMARY KEY, applied_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP)"); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := migrationFiles.ReadDir("migrations")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if entry.Name() >= "0012" {
+			break
+		}
+		content, err := migrationFiles.ReadFile("migrations/" + entry.Name())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := applyMigration(ctx, db, strings.TrimSuffix(entry.Name(), ".sql"), string(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := db.Exec("INSERT INTO engine (id, displacement, no_of_cylinders, car_range) VALUES ('engine', 2000, 4, 500)"); err != nil {
+		t.Fatal(err)
+	}
+	for i, brand := range []string{"toyota", "TOYOTA ", "vw", "Rivian", "rivian", "rivian"} {
+		_, err := db.Exec(`INSERT INTO car (id, name, year, brand, fuel_type, engine_id, price_minor, created_at, updated_at)
+			VALUES (?, 'Car', 2023, ?, 'Petrol', 'engine', 100, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)`, fmt.Sprint(i), brand)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := Migrate(ctx, db); err != nil {
+		t.Fatal(err)
+	}
+	brands := map[string]int{}
+	rows, err := db.Query("SELECT brand, COUNT(*) FROM car GROUP BY brand")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			brand string
+			count int
+		)
+		if err := rows.Scan(&brand, &count); err != nil {
+			t.Fatal(err)
+		}
+		brands[brand] = count
+	}
+	if len(brands) != 3 || brands["Toyota"] != 2 || brands["Volkswagen"] != 1 || brands["rivian"] != 3 {
+		t.Fatalf("unexpected brands %v", brands)
+	}
+
+	found, err := New(db).FindBrand(ctx, "RIVIAN")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found.Name != "rivian" {
+		t.Fatalf("expected the brand of the existing cars, got %+v", found)
+	}
+}
+
+func TestDealerships(t *testing.T) {
+	ctx := context.Background()
+	s := New(openTestDB(t))
+
+	created, err := s.CreateDealership(ctx, &models.DealershipRequest{Name: "North"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dealerships, err := s.GetDealerships(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dealerships) != 2 || dealerships[0].ID.String() != tenant.DefaultID || dealerships[1].ID != created.ID {
+		t.Fatalf("unexpected dealerships %+v", dealerships)
+	}
+
+	got, err := s.GetDealershipById(ctx, created.ID.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "North" {
+		t.Fatalf("expected North, got %q", got.Name)
+	}
+}
@@ -0,0 +1,142 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/adohong4/carZone/models"
+	"github.com/adohong4/carZone/store"
+	"go.opentelemetry.io/otel"
+)
+
+func (s *Store) GetTOTP(ctx context.Context, username string) (models.TOTPCredential, error) {
+	tracer := otel.Tracer("SQLiteStore")
+	ctx, span := tracer.Start(ctx, "GetTOTP-SQLiteStore")
+	defer span.End()
+
+	var credential models.TOTPCredential
+	err := s.db.QueryRowContext(ctx,
+		"SELECT username, secret, confirmed, last_used_step, created_at FROM user_totp WHERE username = ?", username,
+	).Scan(&credential.UserName, &credential.Secret, &credential.Confirmed, &credential.LastUsedStep, &credential.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.TOTPCredential{}, nil
+		}
+		return models.TOTPCredential{}, err
+	}
+	return credential, nil
+}
+
+// SaveTOTP stores a new, unconfirmed secret in place of an unconfirmed one.
+// A confirmed secret and its recovery codes stay until DeleteTOTP.
+func (s *Store) SaveTOTP(ctx context.Context, credential models.TOTPCredential) error {
+	tracer := otel.Tracer("SQLiteStore")
+	ctx, span := tracer.Start(ctx, "SaveTOTP-SQLiteStore")
+	defer span.End()
+
+	return s.withTx(ctx, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx,
+			`INSERT INTO user_totp (username, secret, confirmed, last_used_step, created_at)
+			VALUES (?, ?, FALSE, ?, ?)
+			ON CONFLICT (username) DO UPDATE
+			SET secret = excluded.secret, confirmed = FALSE,
+				last_used_step = excluded.last_used_step, created_at = excluded.created_at
+			WHERE user_totp.confirmed = FALSE`,
+			credential.UserName, credential.Secret, credential.LastUsedStep, credential.CreatedAt.UTC())
+		if err != nil {
+			return err
+		}
+		saved, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if saved == 0 {
+			return store.ErrTOTPConfirmed
+		}
+
+		_, err = tx.ExecContext(ctx, "DELETE FROM user_recovery_code WHERE username = ?", credential.UserName)
+		return err
+	})
+}
+
+func (s *Store) ConfirmTOTP(ctx context.Context, username string, step int64, recoveryCodeHashes []string) error {
+	tracer := otel.Tracer("SQLiteStore")
+	ctx, span := tracer.Start(ctx, "ConfirmTOTP-SQLiteStore")
+	defer span.End()
+
+	return s.withTx(ctx, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx,
+			"UPDATE user_totp SET confirmed = TRUE, last_used_step = ? WHERE username = ?", step, username)
+		if err != nil {
+			return err
+		}
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rowsAffected == 0 {
+			return errors.New("totp not enrolled")
+		}
+
+		_, err = tx.ExecContext(ctx, "DELETE FROM user_recovery_code WHERE username = ?", username)
+		if err != nil {
+			return err
+		}
+		for _, hash := range recoveryCodeHashes {
+			_, err = tx.ExecContext(ctx,
+				"INSERT INTO user_recovery_code (username, code_hash) VALUES (?, ?)", username, hash)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// UseTOTPStep records step as used, it returns false when that step or a
+// later one was already used so a code cannot be replayed.
+func (s *Store) UseTOTPStep(ctx context.Context, username string, step int64) (bool, error) {
+	tracer := otel.Tracer("SQLiteStore")
+	ctx, span := tracer.Start(ctx, "UseTOTPStep-SQLiteStore")
+	defer span.End()
+
+	result, err := s.db.ExecContext(ctx,
+		"UPDATE user_totp SET last_used_step = ? WHERE username = ? AND last_used_step < ?", step, username, step)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected == 1, nil
+}
+
+func (s *Store) ConsumeRecoveryCode(ctx context.Context, username, codeHash string) (bool, error) {
+	tracer := otel.Tracer("SQLiteStore")
+	ctx, span := tracer.Start(ctx, "ConsumeRecoveryCode-SQLiteStore")
+	defer span.End()
+
+	result, err := s.db.ExecContext(ctx,
+		"UPDATE user_recovery_code SET used_at = ? WHERE username = ? AND code_hash = ? AND used_at IS NULL",
+		time.Now().UTC(), username, codeHash)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected == 1, nil
+}
+
+func (s *Store) DeleteTOTP(ctx context.Context, username string) error {
+	tracer := otel.Tracer("SQLiteStore")
+	ctx, span := tracer.Start(ctx, "DeleteTOTP-SQLiteStore")
+	defer span.End()
+
+	_, err := s.db.ExecContext(ctx, "DELETE FROM user_totp WHERE username = ?", username)
+	return err
+}
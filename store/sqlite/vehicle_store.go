@@ -0,0 +1,211 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/adohong4/carZone/models"
+	"github.com/adohong4/carZone/store"
+	"github.com/adohong4/carZone/tenant"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+)
+
+const vehicleColumns = "vin, car_id, status, location, mileage, colour, received_at, updated_at"
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanVehicle(row scanner) (models.Vehicle, error) {
+	var vehicle models.Vehicle
+	err := row.Scan(
+		&vehicle.VIN, &vehicle.CarID, &vehicle.Status, &vehicle.Location,
+		&vehicle.Mileage, &vehicle.Colour, &vehicle.ReceivedAt, &vehicle.UpdatedAt,
+	)
+	return vehicle, err
+}
+
+func (s *Store) GetVehicle(ctx context.Context, vin string) (models.Vehicle, error) {
+	tracer := otel.Tracer("SQLiteStore")
+	ctx, span := tracer.Start(ctx, "GetVehicle-SQLiteStore")
+	defer span.End()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return models.Vehicle{}, err
+	}
+
+	query := "SELECT " + vehicleColumns + " FROM vehicle WHERE vin = ? AND tenant_id = ?"
+	vehicle, err := scanVehicle(s.db.QueryRowContext(ctx, query, vin, tenantID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Vehicle{}, nil
+		}
+		return models.Vehicle{}, err
+	}
+	return vehicle, nil
+}
+
+func (s *Store) ListVehicles(ctx context.Context, filter models.VehicleFilter) ([]models.Vehicle, error) {
+	tracer := otel.Tracer("SQLiteStore")
+	ctx, span := tracer.Start(ctx, "ListVehicles-SQLiteStore")
+	defer span.End()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	conditions := []string{"tenant_id = ?"}
+	args := []interface{}{tenantID}
+	for _, field := range []struct{ column, value string }{
+		{"car_id", filter.CarID},
+		{"status", filter.Status},
+		{"location", filter.Location},
+	} {
+		if field.value != "" {
+			args = append(args, field.value)
+			conditions = append(conditions, field.column+" = ?")
+		}
+	}
+
+	query := "SELECT " + vehicleColumns + " FROM vehicle WHERE " + strings.Join(conditions, " AND ") +
+		" ORDER BY received_at, vin"
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var vehicles []models.Vehicle
+	for rows.Next() {
+		vehicle, err := scanVehicle(rows)
+		if err != nil {
+			return nil, err
+		}
+		vehicles = append(vehicles, vehicle)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return vehicles, nil
+}
+
+func (s *Store) ReceiveVehicle(ctx context.Context, vehicleReq *models.VehicleRequest) (models.Vehicle, error) {
+	tracer := otel.Tracer("SQLiteStore")
+	ctx, span := tracer.Start(ctx, "ReceiveVehicle-SQLiteStore")
+	defer span.End()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return models.Vehicle{}, err
+	}
+
+	var carID uuid.UUID
+	err = s.db.QueryRowContext(ctx, "SELECT id FROM car WHERE id = ? AND tenant_id = ?", vehicleReq.CarID.String(), tenantID).Scan(&carID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Vehicle{}, store.ErrCarNotFound
+		}
+		return models.Vehicle{}, err
+	}
+
+	now := time.Now().UTC()
+	query := `INSERT INTO vehicle (vin, tenant_id, car_id, status, location, mileage, colour, received_at, updated_at)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+				ON CONFLICT (tenant_id, vin) DO NOTHING
+				RETURNING ` + vehicleColumns
+
+	vehicle, err := scanVehicle(s.db.QueryRowContext(ctx, query,
+		vehicleReq.VIN, tenantID, vehicleReq.CarID.String(), vehicleReq.Status,
+		vehicleReq.Location, vehicleReq.Mileage, vehicleReq.Colour, now, now,
+	))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Vehicle{}, store.ErrVehicleExists
+		}
+		return models.Vehicle{}, err
+	}
+	return vehicle, nil
+}
+
+func (s *Store) UpdateVehicle(ctx context.Context, vehicle models.Vehicle, fromStatus string) (models.Vehicle, error) {
+	tracer := otel.Tracer("SQLiteStore")
+	ctx, span := tracer.Start(ctx, "UpdateVehicle-SQLiteStore")
+	defer span.End()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return models.Vehicle{}, err
+	}
+
+	query := `UPDATE vehicle
+				SET status = ?, location = ?, mileage = ?, updated_at = ?
+				WHERE vin = ? AND tenant_id = ? AND status = ?
+				RETURNING ` + vehicleColumns
+
+	updated, err := scanVehicle(s.db.QueryRowContext(ctx, query,
+		vehicle.Status, vehicle.Location, vehicle.Mileage, time.Now().UTC(), vehicle.VIN, tenantID, fromStatus,
+	))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Vehicle{}, store.ErrVehicleConflict
+		}
+		return models.Vehicle{}, err
+	}
+	return updated, nil
+}
+
+func (s *Store) StockCounts(ctx context.Context, carID string) ([]models.StockCount, error) {
+	tracer := otel.Tracer("SQLiteStore")
+	ctx, span := tracer.Start(ctx, "StockCounts-SQLiteStore")
+	defer span.End()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := "SELECT car_id, status, COUNT(*) FROM vehicle WHERE tenant_id = ?"
+	args := []interface{}{tenantID}
+	if carID != "" {
+		id, err := uuid.Parse(carID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid car ID: %w", err)
+		}
+		query += " AND car_id = ?"
+		args = append(args, id.String())
+	}
+	query += " GROUP BY car_id, status ORDER BY car_id"
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var counts []models.StockCount
+	for rows.Next() {
+		var (
+			id     uuid.UUID
+			status string
+			n      int64
+		)
+		if err := rows.Scan(&id, &status, &n); err != nil {
+			return nil, err
+		}
+		if len(counts) == 0 || counts[len(counts)-1].CarID != id {
+			counts = append(counts, models.StockCount{CarID: id})
+		}
+		counts[len(counts)-1].Add(status, n)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
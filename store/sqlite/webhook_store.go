@@ -0,0 +1,376 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/adohong4/carZone/models"
+	"github.com/adohong4/carZone/store"
+	"github.com/adohong4/carZone/tenant"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+)
+
+const webhookColumns = "id, url, events, secret, created_at"
+
+const deliveryColumns = `d.id, d.webhook_id, d.event_id, d.event_type, d.payload, d.status, d.attempts,
+	d.response_code, d.last_error, d.next_attempt_at, d.created_at, d.delivered_at`
+
+func scanWebhook(row scanner) (models.Webhook, error) {
+	var (
+		webhook models.Webhook
+		events  string
+	)
+	if err := row.Scan(&webhook.ID, &webhook.URL, &events, &webhook.Secret, &webhook.CreatedAt); err != nil {
+		return webhook, err
+	}
+	return webhook, json.Unmarshal([]byte(events), &webhook.Events)
+}
+
+func scanDelivery(row scanner, extra ...interface{}) (models.WebhookDelivery, error) {
+	var (
+		delivery      models.WebhookDelivery
+		payload       string
+		nextAttemptAt sql.NullTime
+		deliveredAt   sql.NullTime
+	)
+	dest := []interface{}{
+		&delivery.ID, &delivery.WebhookID, &delivery.EventID, &delivery.EventType, &payload, &delivery.Status,
+		&delivery.Attempts, &delivery.ResponseCode, &delivery.Error, &nextAttemptAt, &delivery.CreatedAt, &deliveredAt,
+	}
+	err := row.Scan(append(dest, extra...)...)
+	delivery.Payload = []byte(payload)
+	if nextAttemptAt.Valid {
+		delivery.NextAttemptAt = &nextAttemptAt.Time
+	}
+	if deliveredAt.Valid {
+		delivery.DeliveredAt = &deliveredAt.Time
+	}
+	return delivery, err
+}
+
+func (s *Store) CreateWebhook(ctx context.Context, webhook models.Webhook) (models.Webhook, error) {
+	tracer := otel.Tracer("SQLiteStore")
+	ctx, span := tracer.Start(ctx, "CreateWebhook-SQLiteStore")
+	defer span.End()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return models.Webhook{}, err
+	}
+
+	events, err := json.Marshal(webhook.Events)
+	if err != nil {
+		return models.Webhook{}, err
+	}
+	_, err = s.db.ExecContext(ctx,
+		"INSERT INTO webhook (id, tenant_id, url, events, secret, created_at) VALUES (?, ?, ?, ?, ?, ?)",
+		webhook.ID.String(), tenantID, webhook.URL, string(events), webhook.Secret, webhook.CreatedAt.UTC())
+	if err != nil {
+		return models.Webhook{}, err
+	}
+	return webhook, nil
+}
+
+func (s *Store) ListWebhooks(ctx context.Context) ([]models.Webhook, error) {
+	tracer := otel.Tracer("SQLiteStore")
+	ctx, span := tracer.Start(ctx, "ListWebhooks-SQLiteStore")
+	defer span.End()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.webhooks(ctx, "SELECT "+webhookColumns+" FROM webhook WHERE tenant_id = ? ORDER BY created_at DESC, id", tenantID)
+}
+
+func (s *Store) webhooks(ctx context.Context, query string, args ...interface{}) ([]models.Webhook, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var webhooks []models.Webhook
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, webhook)
+	}
+	return webhooks, rows.Err()
+}
+
+func (s *Store) GetWebhook(ctx context.Context, id string) (models.Webhook, error) {
+	tracer := otel.Tracer("SQLiteStore")
+	ctx, span := tracer.Start(ctx, "GetWebhook-SQLiteStore")
+	defer span.End()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return models.Webhook{}, err
+	}
+
+	query := "SELECT " + webhookColumns + " FROM webhook WHERE id = ? AND tenant_id = ?"
+	webhook, err := scanWebhook(s.db.QueryRowContext(ctx, query, id, tenantID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Webhook{}, store.ErrWebhookNotFound
+	}
+	return webhook, err
+}
+
+func (s *Store) DeleteWebhook(ctx context.Context, id string) (models.Webhook, error) {
+	tracer := otel.Tracer("SQLiteStore")
+	ctx, span := tracer.Start(ctx, "DeleteWebhook-SQLiteStore")
+	defer span.End()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return models.Webhook{}, err
+	}
+
+	query := "DELETE FROM webhook WHERE id = ? AND tenant_id = ? RETURNING " + webhookColumns
+	webhook, err := scanWebhook(s.db.QueryRowContext(ctx, query, id, tenantID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Webhook{}, store.ErrWebhookNotFound
+	}
+	return webhook, err
+}
+
+func (s *Store) ListDeliveries(ctx context.Context, webhookID string, limit int) ([]models.WebhookDelivery, error) {
+	tracer := otel.Tracer("SQLiteStore")
+	ctx, span := tracer.Start(ctx, "ListDeliveries-SQLiteStore")
+	defer span.End()
+
+	if _, err := s.GetWebhook(ctx, webhookID); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT "+deliveryColumns+` FROM webhook_delivery d
+			WHERE d.webhook_id = ? ORDER BY d.created_at DESC, d.id LIMIT ?`, webhookID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []models.WebhookDelivery
+	for rows.Next() {
+		delivery, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, rows.Err()
+}
+
+func (s *Store) GetDelivery(ctx context.Context, webhookID, deliveryID string) (models.WebhookDelivery, error) {
+	tracer := otel.Tracer("SQLiteStore")
+	ctx, span := tracer.Start(ctx, "GetDelivery-SQLiteStore")
+	defer span.End()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return models.WebhookDelivery{}, err
+	}
+
+	query := "SELECT " + deliveryColumns + ` FROM webhook_delivery d JOIN webhook w ON w.id = d.webhook_id
+				WHERE d.id = ? AND d.webhook_id = ? AND w.tenant_id = ?`
+	delivery, err := scanDelivery(s.db.QueryRowContext(ctx, query, deliveryID, webhookID, tenantID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.WebhookDelivery{}, store.ErrDeliveryNotFound
+		}
+		return models.WebhookDelivery{}, err
+	}
+
+	delivery.Log, err = s.attempts(ctx, deliveryID)
+	if err != nil {
+		return models.WebhookDelivery{}, err
+	}
+	return delivery, nil
+}
+
+func (s *Store) attempts(ctx context.Context, deliveryID string) ([]models.WebhookAttempt, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT response_code, error, duration_ms, attempted_at FROM webhook_attempt
+			WHERE delivery_id = ? ORDER BY id`, deliveryID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var attempts []models.WebhookAttempt
+	for rows.Next() {
+		var attempt models.WebhookAttempt
+		if err := rows.Scan(&attempt.ResponseCode, &attempt.Error, &attempt.DurationMS, &attempt.AttemptedAt); err != nil {
+			return nil, err
+		}
+		attempts = append(attempts, attempt)
+	}
+	return attempts, rows.Err()
+}
+
+func (s *Store) RedeliverDelivery(ctx context.Context, webhookID, deliveryID string, now time.Time) (models.WebhookDelivery, error) {
+	tracer := otel.Tracer("SQLiteStore")
+	ctx, span := tracer.Start(ctx, "RedeliverDelivery-SQLiteStore")
+	defer span.End()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return models.WebhookDelivery{}, err
+	}
+
+	result, err := s.db.ExecContext(ctx,
+		`UPDATE webhook_delivery
+			SET status = 'pending', attempts = 0, next_attempt_at = ?, delivered_at = NULL
+			WHERE id = ? AND webhook_id = ?
+			AND webhook_id IN (SELECT id FROM webhook WHERE tenant_id = ?)`,
+		now.UTC(), deliveryID, webhookID, tenantID)
+	if err != nil {
+		return models.WebhookDelivery{}, err
+	}
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return models.WebhookDelivery{}, err
+	}
+	if updated == 0 {
+		return models.WebhookDelivery{}, store.ErrDeliveryNotFound
+	}
+	return s.GetDelivery(ctx, webhookID, deliveryID)
+}
+
+func (s *Store) EnqueueDeliveries(ctx context.Context, event models.Event, now time.Time) (int, error) {
+	tracer := otel.Tracer("SQLiteStore")
+	ctx, span := tracer.Start(ctx, "EnqueueDeliveries-SQLiteStore")
+	defer span.End()
+
+	webhooks, err := s.webhooks(ctx, "SELECT "+webhookColumns+" FROM webhook WHERE tenant_id = ?", event.TenantID)
+	if err != nil {
+		return 0, err
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return 0, err
+	}
+
+	queued := 0
+	err = s.withTx(ctx, func(tx *sql.Tx) error {
+		for _, webhook := range webhooks {
+			if !webhook.Takes(event.Type) {
+				continue
+			}
+			result, err := tx.ExecContext(ctx,
+				`INSERT OR IGNORE INTO webhook_delivery
+					(id, webhook_id, event_id, event_type, payload, next_attempt_at, created_at)
+					VALUES (?, ?, ?, ?, ?, ?, ?)`,
+				uuid.NewString(), webhook.ID.String(), event.ID.String(), event.Type, string(payload), now.UTC(), now.UTC())
+			if err != nil {
+				return err
+			}
+			inserted, err := result.RowsAffected()
+			if err != nil {
+				return err
+			}
+			queued += int(inserted)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return queued, nil
+}
+
+func (s *Store) ClaimDeliveries(ctx context.Context, now, until time.Time, limit int) ([]models.PendingDelivery, error) {
+	tracer := otel.Tracer("SQLiteStore")
+	ctx, span := tracer.Start(ctx, "ClaimDeliveries-SQLiteStore")
+	defer span.End()
+
+	var pending []models.PendingDelivery
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx,
+			"SELECT "+deliveryColumns+`, w.url, w.secret FROM webhook_delivery d JOIN webhook w ON w.id = d.webhook_id
+				WHERE d.status = 'pending' AND d.next_attempt_at <= ?
+				ORDER BY d.next_attempt_at, d.created_at LIMIT ?`, now.UTC(), limit)
+		if err != nil {
+			return err
+		}
+		for rows.Next() {
+			var next models.PendingDelivery
+			next.Delivery, err = scanDelivery(rows, &next.URL, &next.Secret)
+			if err != nil {
+				rows.Close()
+				return err
+			}
+			pending = append(pending, next)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		for i := range pending {
+			if _, err := tx.ExecContext(ctx, "UPDATE webhook_delivery SET next_attempt_at = ? WHERE id = ?",
+				until.UTC(), pending[i].Delivery.ID.String()); err != nil {
+				return err
+			}
+			claimed := until.UTC()
+			pending[i].Delivery.NextAttemptAt = &claimed
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pending, nil
+}
+
+func (s *Store) RecordAttempt(ctx context.Context, delivery models.WebhookDelivery, attempt models.WebhookAttempt) error {
+	tracer := otel.Tracer("SQLiteStore")
+	ctx, span := tracer.Start(ctx, "RecordAttempt-SQLiteStore")
+	defer span.End()
+
+	return s.withTx(ctx, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx,
+			`UPDATE webhook_delivery SET status = ?, attempts = ?, response_code = ?, last_error = ?,
+				next_attempt_at = ?, delivered_at = ?
+				WHERE id = ?`,
+			delivery.Status, delivery.Attempts, delivery.ResponseCode, delivery.Error,
+			utcOrNil(delivery.NextAttemptAt), utcOrNil(delivery.DeliveredAt), delivery.ID.String())
+		if err != nil {
+			return err
+		}
+		updated, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if updated == 0 {
+			return store.ErrDeliveryNotFound
+		}
+
+		_, err = tx.ExecContext(ctx,
+			`INSERT INTO webhook_attempt (delivery_id, response_code, error, duration_ms, attempted_at)
+				VALUES (?, ?, ?, ?, ?)`,
+			delivery.ID.String(), attempt.ResponseCode, attempt.Error, attempt.DurationMS, attempt.AttemptedAt.UTC())
+		return err
+	})
+}
+
+func (s *Store) DeleteFinishedDeliveries(ctx context.Context, before time.Time) (int64, error) {
+	tracer := otel.Tracer("SQLiteStore")
+	ctx, span := tracer.Start(ctx, "DeleteFinishedDeliveries-SQLiteStore")
+	defer span.End()
+
+	result, err := s.db.ExecContext(ctx,
+		"DELETE FROM webhook_delivery WHERE status IN ('succeeded', 'failed') AND created_at < ?", before.UTC())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,77 @@
+package storetest_test
+
+import (
+	"database/sql"
+	"os"
+	"testing"
+
+	carStore "github.com/adohong4/carZone/store/car"
+	catalogueStore "github.com/adohong4/carZone/store/catalogue"
+	customerStore "github.com/adohong4/carZone/store/customer"
+	engineStore "github.com/adohong4/carZone/store/engine"
+	loginStore "github.com/adohong4/carZone/store/login"
+	mediaStore "github.com/adohong4/carZone/store/media"
+	orderStore "github.com/adohong4/carZone/store/order"
+	outboxStore "github.com/adohong4/carZone/store/outbox"
+	priceStore "github.com/adohong4/carZone/store/price"
+	rateStore "github.com/adohong4/carZone/store/rate"
+	reportStore "github.com/adohong4/carZone/store/report"
+	reservationStore "github.com/adohong4/carZone/store/reservation"
+	ruleStore "github.com/adohong4/carZone/store/rule"
+	"github.com/adohong4/carZone/store/storetest"
+	totpStore "github.com/adohong4/carZone/store/totp"
+	vehicleStore "github.com/adohong4/carZone/store/vehicle"
+	webhookStore "github.com/adohong4/carZone/store/webhook"
+	_ "github.com/lib/pq"
+)
+
+// TestPostgresConformance runs the suite against a real database. It is
+// skipped unless TEST_DATABASE_DSN points at a disposable Postgres, e.g.
+// "host=localhost port=5433 user=postgres password=12345 dbname=postgres sslmode=disable".
+func TestPostgresConformance(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_DSN not set, skipping Postgres conformance tests")
+	}
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("cannot open database: %v", err)
+	}
+	defer db.Close()
+
+	schema, err := os.ReadFile("../schema.sql")
+	if err != nil {
+		t.Fatalf("cannot read schema: %v", err)
+	}
+	if _, err := db.Exec(string(schema)); err != nil {
+		t.Fatalf("cannot execute schema: %v", err)
+	}
+
+	storetest.Run(t, func(t *testing.T) storetest.Stores {
+		if _, err := db.Exec("TRUNCATE webhook_attempt, webhook_delivery, webhook, outbox_event, validation_rule, car_model_alias, car_model, brand_alias, brand, car_spec, car_image, promotion, car_price, exchange_rate, customer_car, customer_note, customer, order_line, sales_order, invoice_sequence, reservation, vehicle, car, engine, user_recovery_code, user_totp, login_lockout, login_attempt"); err != nil {
+			t.Fatalf("cannot truncate tables: %v", err)
+		}
+		if _, err := db.Exec("INSERT INTO dealership (id, name) VALUES ($1, 'Other dealership') ON CONFLICT (id) DO NOTHING", storetest.OtherTenant); err != nil {
+			t.Fatalf("cannot create dealership: %v", err)
+		}
+		return storetest.Stores{
+			Cars:         carStore.New(db),
+			Engines:      engineStore.New(db),
+			Vehicles:     vehicleStore.New(db),
+			Reservations: reservationStore.New(db),
+			Orders:       orderStore.New(db),
+			Customers:    customerStore.New(db),
+			Rates:        rateStore.New(db),
+			Prices:       priceStore.New(db),
+			Images:       mediaStore.New(db),
+			Catalogue:    catalogueStore.New(db),
+			Rules:        ruleStore.New(db),
+			Reports:      reportStore.New(db),
+			Outbox:       outboxStore.New(db),
+			Webhooks:     webhookStore.New(db),
+			Logins:       loginStore.New(db),
+			TOTP:         totpStore.New(db),
+		}
+	})
+}
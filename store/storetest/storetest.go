@@ -0,0 +1,1846 @@
+// Package storetest holds the conformance suite every car/engine store
+// backend has to pass, so the Postgres and in-memory stores keep the same
+// observable behaviour.
+package storetest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/adohong4/carZone/decimal"
+	"github.com/adohong4/carZone/models"
+	"github.com/adohong4/carZone/money"
+	"github.com/adohong4/carZone/store"
+	"github.com/adohong4/carZone/tenant"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Stores is the pair of stores under test. Both must share the same backing
+// data so the engine foreign-key rules can be checked.
+type Stores struct {
+	Cars     store.CarStoreInterface
+	Engines  store.EngineStoreInterface
+	Vehicles store.VehicleStoreInterface
+
+	Reservations store.ReservationStoreInterface
+	Orders       store.OrderStoreInterface
+	Customers    store.CustomerStoreInterface
+	Rates        store.RateStoreInterface
+	Prices       store.PriceStoreInterface
+	Images       store.CarImageStoreInterface
+	Catalogue    store.CatalogueStoreInterface
+	Rules        store.ValidationRuleStoreInterface
+	Reports      store.ReportStoreInterface
+	Outbox       store.OutboxStoreInterface
+	Webhooks     store.WebhookStoreInterface
+	Logins       store.LoginStoreInterface
+	TOTP         store.TOTPStoreInterface
+}
+
+// OtherTenant is the second dealership of the isolation tests. Backends
+// that check tenant_id against the dealership table must create it.
+const OtherTenant = "00000000-0000-0000-0000-000000000002"
+
+// Factory returns empty stores for a single sub-test.
+type Factory func(t *testing.T) Stores
+
+// Run executes the whole conformance suite against the stores built by newStores.
+func Run(t *testing.T, newStores Factory) {
+	t.Run("EngineCRUD", func(t *testing.T) { testEngineCRUD(t, newStores(t)) })
+	t.Run("EngineMissing", func(t *testing.T) { testEngineMissing(t, newStores(t)) })
+	t.Run("ElectricPowertrain", func(t *testing.T) { testElectricPowertrain(t, newStores(t)) })
+	t.Run("CarCRUD", func(t *testing.T) { testCarCRUD(t, newStores(t)) })
+	t.Run("CarMissing", func(t *testing.T) { testCarMissing(t, newStores(t)) })
+	t.Run("CarEngineForeignKey", func(t *testing.T) { testCarEngineForeignKey(t, newStores(t)) })
+	t.Run("CarByBrand", func(t *testing.T) { testCarByBrand(t, newStores(t)) })
+	t.Run("CarsByIds", func(t *testing.T) { testCarsByIds(t, newStores(t)) })
+	t.Run("CarSpec", func(t *testing.T) { testCarSpec(t, newStores(t)) })
+	t.Run("TenantIsolation", func(t *testing.T) { testTenantIsolation(t, newStores(t)) })
+	t.Run("TenantRequired", func(t *testing.T) { testTenantRequired(t, newStores(t)) })
+	t.Run("VehicleLifecycle", func(t *testing.T) { testVehicleLifecycle(t, newStores(t)) })
+	t.Run("VehicleStock", func(t *testing.T) { testVehicleStock(t, newStores(t)) })
+	t.Run("ReservationLifecycle", func(t *testing.T) { testReservationLifecycle(t, newStores(t)) })
+	t.Run("ReservationConcurrency", func(t *testing.T) { testReservationConcurrency(t, newStores(t)) })
+	t.Run("ReservationExpiry", func(t *testing.T) { testReservationExpiry(t, newStores(t)) })
+	t.Run("OrderLifecycle", func(t *testing.T) { testOrderLifecycle(t, newStores(t)) })
+	t.Run("InvoiceNumbers", func(t *testing.T) { testInvoiceNumbers(t, newStores(t)) })
+	t.Run("CustomerLifecycle", func(t *testing.T) { testCustomerLifecycle(t, newStores(t)) })
+	t.Run("CustomerErase", func(t *testing.T) { testCustomerErase(t, newStores(t)) })
+	t.Run("CustomerLinkedRecords", func(t *testing.T) { testCustomerLinkedRecords(t, newStores(t)) })
+	t.Run("ExchangeRates", func(t *testing.T) { testExchangeRates(t, newStores(t)) })
+	t.Run("CarPriceHistory", func(t *testing.T) { testCarPriceHistory(t, newStores(t)) })
+	t.Run("Promotions", func(t *testing.T) { testPromotions(t, newStores(t)) })
+	t.Run("CarImages", func(t *testing.T) { testCarImages(t, newStores(t)) })
+	t.Run("Catalogue", func(t *testing.T) { testCatalogue(t, newStores(t)) })
+	t.Run("ValidationRules", func(t *testing.T) { testValidationRules(t, newStores(t)) })
+	t.Run("InventoryReport", func(t *testing.T) { testInventoryReport(t, newStores(t)) })
+	t.Run("Outbox", func(t *testing.T) { testOutbox(t, newStores(t)) })
+	t.Run("Webhooks", func(t *testing.T) { testWebhooks(t, newStores(t)) })
+	t.Run("LoginLockout", func(t *testing.T) { testLoginLockout(t, newStores(t)) })
+	t.Run("TOTP", func(t *testing.T) { testTOTP(t, newStores(t)) })
+}
+
+func engineRequest() *models.EngineRequest {
+	return &models.EngineRequest{Displacement: 2000, NoOfCylinders: 4, CarRange: 500}
+}
+
+func carRequest(engineID uuid.UUID, brand string) *models.CarRequest {
+	return &models.CarRequest{
+		Name:     "Camry",
+		Year:     "2023",
+		Brand:    brand,
+		FuelType: "Petrol",
+		Engine:   models.Engine{EngineID: engineID},
+		Price:    money.Money{Amount: 2500000, Currency: "USD"},
+	}
+}
+
+func testEngineCRUD(t *testing.T, s Stores) {
+	ctx := tenant.WithTenant(context.Background(), tenant.DefaultID)
+
+	created, err := s.Engines.CreateEngine(ctx, engineRequest())
+	require.NoError(t, err)
+	assert.NotEqual(t, uuid.Nil, created.EngineID)
+	assert.Equal(t, int64(2000), created.Displacement)
+	assert.False(t, created.UpdatedAt.IsZero())
+
+	got, err := s.Engines.EngineById(ctx, created.EngineID.String())
+	require.NoError(t, err)
+	assert.Equal(t, created, got)
+
+	updated, err := s.Engines.EngineUpdate(ctx, created.EngineID.String(),
+		&models.EngineRequest{Displacement: 3000, NoOfCylinders: 6, CarRange: 600})
+	require.NoError(t, err)
+	assert.Equal(t, created.EngineID, updated.EngineID)
+	assert.Equal(t, int64(6), updated.NoOfCylinders)
+	assert.False(t, updated.UpdatedAt.Before(created.UpdatedAt))
+
+	got, err = s.Engines.EngineById(ctx, created.EngineID.String())
+	require.NoError(t, err)
+	assert.Equal(t, updated, got)
+
+	deleted, err := s.Engines.EngineDelete(ctx, created.EngineID.String())
+	require.NoError(t, err)
+	assert.Equal(t, updated, deleted)
+
+	got, err = s.Engines.EngineById(ctx, created.EngineID.String())
+	require.NoError(t, err)
+	assert.Equal(t, uuid.Nil, got.EngineID)
+}
+
+func testElectricPowertrain(t *testing.T, s Stores) {
+	ctx := tenant.WithTenant(context.Background(), tenant.DefaultID)
+
+	battery := decimal.New(755, 1)
+	engine, err := s.Engines.CreateEngine(ctx, &models.EngineRequest{
+		Type: models.EngineElectric, CarRange: 520, MotorPower: 250, BatteryCapacity: &battery,
+	})
+	require.NoError(t, err)
+
+	got, err := s.Engines.EngineById(ctx, engine.EngineID.String())
+	require.NoError(t, err)
+	assert.Equal(t, models.EngineElectric, got.Type)
+	assert.Equal(t, int64(0), got.NoOfCylinders)
+	assert.Equal(t, int64(250), got.MotorPower)
+	require.NotNil(t, got.BatteryCapacity)
+	assert.True(t, battery.Equal(*got.BatteryCapacity))
+
+	req := carRequest(engine.EngineID, "Tesla")
+	req.FuelType = "Electric"
+	car, err := s.Cars.CreateCar(ctx, req)
+	require.NoError(t, err)
+	gotCar, err := s.Cars.GetCarById(ctx, car.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, models.EngineElectric, gotCar.Engine.Type)
+	assert.True(t, got.UpdatedAt.Equal(gotCar.Engine.UpdatedAt))
+	require.NotNil(t, gotCar.Engine.BatteryCapacity)
+	assert.True(t, battery.Equal(*gotCar.Engine.BatteryCapacity))
+
+	// a combustion engine keeps no battery
+	_, err = s.Engines.EngineUpdate(ctx, engine.EngineID.String(), engineRequest())
+	require.NoError(t, err)
+	got, err = s.Engines.EngineById(ctx, engine.EngineID.String())
+	require.NoError(t, err)
+	assert.Equal(t, models.EngineCombustion, got.Type)
+	assert.Equal(t, int64(0), got.MotorPower)
+	assert.Nil(t, got.BatteryCapacity)
+}
+
+func testEngineMissing(t *testing.T, s Stores) {
+	ctx := tenant.WithTenant(context.Background(), tenant.DefaultID)
+	missing := uuid.New().String()
+
+	got, err := s.Engines.EngineById(ctx, missing)
+	require.NoError(t, err)
+	assert.Equal(t, uuid.Nil, got.EngineID)
+
+	_, err = s.Engines.EngineUpdate(ctx, missing, engineRequest())
+	assert.Error(t, err)
+
+	_, err = s.Engines.EngineUpdate(ctx, "not-a-uuid", engineRequest())
+	assert.Error(t, err)
+
+	deleted, err := s.Engines.EngineDelete(ctx, missing)
+	require.NoError(t, err)
+	assert.Equal(t, uuid.Nil, deleted.EngineID)
+}
+
+func testCarCRUD(t *testing.T, s Stores) {
+	ctx := tenant.WithTenant(context.Background(), tenant.DefaultID)
+
+	engine, err := s.Engines.CreateEngine(ctx, engineRequest())
+	require.NoError(t, err)
+
+	created, err := s.Cars.CreateCar(ctx, carRequest(engine.EngineID, "Toyota"))
+	require.NoError(t, err)
+	assert.NotEqual(t, uuid.Nil, created.ID)
+	assert.Equal(t, "Camry", created.Name)
+	assert.Equal(t, engine.EngineID, created.Engine.EngineID)
+	assert.False(t, created.CreatedAt.IsZero())
+
+	got, err := s.Cars.GetCarById(ctx, created.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, created.ID, got.ID)
+	assert.Equal(t, "Toyota", got.Brand)
+	assert.Equal(t, money.Money{Amount: 2500000, Currency: "USD"}, got.Price)
+	assert.Equal(t, engine, got.Engine)
+
+	req := carRequest(engine.EngineID, "Toyota")
+	req.Name = "Corolla"
+	req.Price = money.Money{Amount: 2100050, Currency: "EUR"}
+	updated, err := s.Cars.UpdateCar(ctx, created.ID.String(), req)
+	require.NoError(t, err)
+	assert.Equal(t, created.ID, updated.ID)
+	assert.Equal(t, "Corolla", updated.Name)
+	assert.Equal(t, money.Money{Amount: 2100050, Currency: "EUR"}, updated.Price)
+	assert.False(t, updated.UpdatedAt.Before(created.UpdatedAt))
+
+	got, err = s.Cars.GetCarById(ctx, created.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, "Corolla", got.Name)
+	assert.Equal(t, money.Money{Amount: 2100050, Currency: "EUR"}, got.Price)
+
+	deleted, err := s.Cars.DeleteCar(ctx, created.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, created.ID, deleted.ID)
+	assert.Equal(t, "Corolla", deleted.Name)
+
+	got, err = s.Cars.GetCarById(ctx, created.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, uuid.Nil, got.ID)
+}
+
+func testCarSpec(t *testing.T, s Stores) {
+	ctx := tenant.WithTenant(context.Background(), tenant.DefaultID)
+
+	engine, err := s.Engines.CreateEngine(ctx, engineRequest())
+	require.NoError(t, err)
+
+	consumption := decimal.New(65, 1)
+	emissions := int64(148)
+	req := carRequest(engine.EngineID, "Toyota")
+	req.Spec = &models.CarSpec{
+		Transmission: "automatic", Drivetrain: "fwd", BodyType: "sedan", Seats: 5, Doors: 4,
+		Colour: "Pearl White", Trim: "XLE", FuelConsumption: &consumption, CO2Emissions: &emissions,
+	}
+	created, err := s.Cars.CreateCar(ctx, req)
+	require.NoError(t, err)
+	require.NotNil(t, created.Spec)
+	assert.Equal(t, "XLE", created.Spec.Trim)
+
+	got, err := s.Cars.GetCarById(ctx, created.ID.String())
+	require.NoError(t, err)
+	require.NotNil(t, got.Spec)
+	assert.Equal(t, "automatic", got.Spec.Transmission)
+	assert.Equal(t, "fwd", got.Spec.Drivetrain)
+	assert.Equal(t, "sedan", got.Spec.BodyType)
+	assert.Equal(t, 5, got.Spec.Seats)
+	assert.Equal(t, 4, got.Spec.Doors)
+	assert.Equal(t, "Pearl White", got.Spec.Colour)
+	require.NotNil(t, got.Spec.FuelConsumption)
+	assert.True(t, consumption.Equal(*got.Spec.FuelConsumption))
+	require.NotNil(t, got.Spec.CO2Emissions)
+	assert.Equal(t, int64(148), *got.Spec.CO2Emissions)
+	assert.Nil(t, got.Spec.EnergyConsumption)
+
+	for _, isEngine := range []bool{true, false} {
+		cars, err := s.Cars.GetCarByBrand(ctx, "Toyota", isEngine)
+		require.NoError(t, err)
+		require.Len(t, cars, 1)
+		require.NotNil(t, cars[0].Spec)
+		assert.Equal(t, "Pearl White", cars[0].Spec.Colour)
+	}
+
+	// an update replaces the whole spec
+	req.Spec = &models.CarSpec{Colour: "Midnight Black"}
+	_, err = s.Cars.UpdateCar(ctx, created.ID.String(), req)
+	require.NoError(t, err)
+	got, err = s.Cars.GetCarById(ctx, created.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, &models.CarSpec{Colour: "Midnight Black"}, got.Spec)
+
+	req.Spec = nil
+	_, err = s.Cars.UpdateCar(ctx, created.ID.String(), req)
+	require.NoError(t, err)
+	got, err = s.Cars.GetCarById(ctx, created.ID.String())
+	require.NoError(t, err)
+	assert.Nil(t, got.Spec)
+
+	_, err = s.Cars.DeleteCar(ctx, created.ID.String())
+	require.NoError(t, err)
+}
+
+func testCarMissing(t *testing.T, s Stores) {
+	ctx := tenant.WithTenant(context.Background(), tenant.DefaultID)
+	missing := uuid.New().String()
+
+	engine, err := s.Engines.CreateEngine(ctx, engineRequest())
+	require.NoError(t, err)
+
+	got, err := s.Cars.GetCarById(ctx, missing)
+	require.NoError(t, err)
+	assert.Equal(t, uuid.Nil, got.ID)
+
+	_, err = s.Cars.UpdateCar(ctx, missing, carRequest(engine.EngineID, "Toyota"))
+	assert.EqualError(t, err, "car not found")
+
+	_, err = s.Cars.DeleteCar(ctx, missing)
+	assert.EqualError(t, err, "car not found")
+}
+
+func testCarEngineForeignKey(t *testing.T, s Stores) {
+	ctx := tenant.WithTenant(context.Background(), tenant.DefaultID)
+
+	_, err := s.Cars.CreateCar(ctx, carRequest(uuid.New(), "Toyota"))
+	assert.EqualError(t, err, "engine_id does not exists in the engine table")
+
+	engine, err := s.Engines.CreateEngine(ctx, engineRequest())
+	require.NoError(t, err)
+	car, err := s.Cars.CreateCar(ctx, carRequest(engine.EngineID, "Toyota"))
+	require.NoError(t, err)
+
+	_, err = s.Cars.UpdateCar(ctx, car.ID.String(), carRequest(uuid.New(), "Toyota"))
+	assert.Error(t, err, "updating a car to an unknown engine must fail")
+
+	_, err = s.Engines.EngineDelete(ctx, engine.EngineID.String())
+	assert.Error(t, err, "deleting an engine still used by a car must fail")
+
+	got, err := s.Engines.EngineById(ctx, engine.EngineID.String())
+	require.NoError(t, err)
+	assert.Equal(t, engine.EngineID, got.EngineID)
+
+	_, err = s.Cars.DeleteCar(ctx, car.ID.String())
+	require.NoError(t, err)
+	_, err = s.Engines.EngineDelete(ctx, engine.EngineID.String())
+	assert.NoError(t, err)
+}
+
+func testCarByBrand(t *testing.T, s Stores) {
+	ctx := tenant.WithTenant(context.Background(), tenant.DefaultID)
+	brand := "Brand-" + uuid.NewString()
+
+	engine, err := s.Engines.CreateEngine(ctx, engineRequest())
+	require.NoError(t, err)
+
+	first, err := s.Cars.CreateCar(ctx, carRequest(engine.EngineID, brand))
+	require.NoError(t, err)
+	second, err := s.Cars.CreateCar(ctx, carRequest(engine.EngineID, brand))
+	require.NoError(t, err)
+	_, err = s.Cars.CreateCar(ctx, carRequest(engine.EngineID, brand+"-other"))
+	require.NoError(t, err)
+
+	cars, err := s.Cars.GetCarByBrand(ctx, brand, false)
+	require.NoError(t, err)
+	require.Len(t, cars, 2)
+	assert.ElementsMatch(t, []uuid.UUID{first.ID, second.ID}, []uuid.UUID{cars[0].ID, cars[1].ID})
+	assert.Equal(t, uuid.Nil, cars[0].Engine.EngineID)
+
+	cars, err = s.Cars.GetCarByBrand(ctx, brand, true)
+	require.NoError(t, err)
+	require.Len(t, cars, 2)
+	assert.Equal(t, engine, cars[0].Engine)
+	assert.Equal(t, engine, cars[1].Engine)
+
+	cars, err = s.Cars.GetCarByBrand(ctx, "Brand-"+uuid.NewString(), true)
+	require.NoError(t, err)
+	assert.Empty(t, cars)
+}
+
+func testCarsByIds(t *testing.T, s Stores) {
+	ctx := tenant.WithTenant(context.Background(), tenant.DefaultID)
+	other := tenant.WithTenant(context.Background(), OtherTenant)
+
+	engine, err := s.Engines.CreateEngine(ctx, engineRequest())
+	require.NoError(t, err)
+	first, err := s.Cars.CreateCar(ctx, carRequest(engine.EngineID, "Toyota"))
+	require.NoError(t, err)
+	secondReq := carRequest(engine.EngineID, "Honda")
+	secondReq.Spec = &models.CarSpec{Transmission: "manual", Seats: 5}
+	second, err := s.Cars.CreateCar(ctx, secondReq)
+	require.NoError(t, err)
+	_, err = s.Cars.CreateCar(ctx, carRequest(engine.EngineID, "Mazda"))
+	require.NoError(t, err)
+
+	cars, err := s.Cars.GetCarsByIds(ctx, []string{second.ID.String(), first.ID.String(), uuid.NewString()})
+	require.NoError(t, err)
+	require.Len(t, cars, 2)
+	byID := map[uuid.UUID]models.Car{cars[0].ID: cars[0], cars[1].ID: cars[1]}
+	assert.Equal(t, engine, byID[first.ID].Engine)
+	assert.Equal(t, engine, byID[second.ID].Engine)
+	assert.Equal(t, "Honda", byID[second.ID].Brand)
+	assert.Equal(t, secondReq.Spec, byID[second.ID].Spec)
+	assert.Nil(t, byID[first.ID].Spec)
+
+	cars, err = s.Cars.GetCarsByIds(other, []string{first.ID.String(), second.ID.String()})
+	require.NoError(t, err)
+	assert.Empty(t, cars)
+}
+
+func testTenantIsolation(t *testing.T, s Stores) {
+	ctx := tenant.WithTenant(context.Background(), tenant.DefaultID)
+	other := tenant.WithTenant(context.Background(), OtherTenant)
+
+	engine, err := s.Engines.CreateEngine(ctx, engineRequest())
+	require.NoError(t, err)
+	car, err := s.Cars.CreateCar(ctx, carRequest(engine.EngineID, "Toyota"))
+	require.NoError(t, err)
+
+	gotCar, err := s.Cars.GetCarById(other, car.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, uuid.Nil, gotCar.ID)
+
+	cars, err := s.Cars.GetCarByBrand(other, "Toyota", true)
+	require.NoError(t, err)
+	assert.Empty(t, cars)
+
+	_, err = s.Cars.UpdateCar(other, car.ID.String(), carRequest(engine.EngineID, "Toyota"))
+	assert.Error(t, err)
+	_, err = s.Cars.DeleteCar(other, car.ID.String())
+	assert.Error(t, err)
+
+	gotEngine, err := s.Engines.EngineById(other, engine.EngineID.String())
+	require.NoError(t, err)
+	assert.Equal(t, uuid.Nil, gotEngine.EngineID)
+
+	_, err = s.Engines.EngineUpdate(other, engine.EngineID.String(), engineRequest())
+	assert.Error(t, err)
+
+	// another dealership cannot build on this dealership's engine either
+	_, err = s.Cars.CreateCar(other, carRequest(engine.EngineID, "Toyota"))
+	assert.Error(t, err)
+
+	// nothing above touched the owner's rows
+	gotCar, err = s.Cars.GetCarById(ctx, car.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, car.ID, gotCar.ID)
+	assert.Equal(t, engine.EngineID, gotCar.Engine.EngineID)
+}
+
+func testTenantRequired(t *testing.T, s Stores) {
+	ctx := context.Background()
+
+	_, err := s.Engines.CreateEngine(ctx, engineRequest())
+	assert.ErrorIs(t, err, tenant.ErrMissing)
+
+	_, err = s.Cars.GetCarById(ctx, uuid.New().String())
+	assert.ErrorIs(t, err, tenant.ErrMissing)
+
+	_, err = s.Cars.GetCarByBrand(ctx, "Toyota", false)
+	assert.ErrorIs(t, err, tenant.ErrMissing)
+}
+
+func vehicleRequest(carID uuid.UUID, vin string) *models.VehicleRequest {
+	return &models.VehicleRequest{
+		VIN:      vin,
+		CarID:    carID,
+		Status:   models.VehicleInTransit,
+		Location: "Port",
+		Colour:   "Red",
+	}
+}
+
+func createCar(t *testing.T, ctx context.Context, s Stores) models.Car {
+	engine, err := s.Engines.CreateEngine(ctx, engineRequest())
+	require.NoError(t, err)
+	car, err := s.Cars.CreateCar(ctx, carRequest(engine.EngineID, "Toyota"))
+	require.NoError(t, err)
+	return car
+}
+
+func testVehicleLifecycle(t *testing.T, s Stores) {
+	ctx := tenant.WithTenant(context.Background(), tenant.DefaultID)
+	other := tenant.WithTenant(context.Background(), OtherTenant)
+	car := createCar(t, ctx, s)
+	const vin = "1HGCM82633A004352"
+
+	received, err := s.Vehicles.ReceiveVehicle(ctx, vehicleRequest(car.ID, vin))
+	require.NoError(t, err)
+	assert.Equal(t, vin, received.VIN)
+	assert.Equal(t, car.ID, received.CarID)
+	assert.Equal(t, models.VehicleInTransit, received.Status)
+
+	_, err = s.Vehicles.ReceiveVehicle(ctx, vehicleRequest(car.ID, vin))
+	assert.ErrorIs(t, err, store.ErrVehicleExists)
+	_, err = s.Vehicles.ReceiveVehicle(ctx, vehicleRequest(uuid.New(), "2HGCM82633A004352"))
+	assert.ErrorIs(t, err, store.ErrCarNotFound)
+
+	moved := received
+	moved.Status = models.VehicleInStock
+	moved.Location = "Showroom"
+	moved.Mileage = 12
+	updated, err := s.Vehicles.UpdateVehicle(ctx, moved, models.VehicleInTransit)
+	require.NoError(t, err)
+	assert.Equal(t, "Showroom", updated.Location)
+	assert.Equal(t, int64(12), updated.Mileage)
+
+	// a stale status means someone else moved the unit first
+	_, err = s.Vehicles.UpdateVehicle(ctx, moved, models.VehicleInTransit)
+	assert.ErrorIs(t, err, store.ErrVehicleConflict)
+
+	got, err := s.Vehicles.GetVehicle(ctx, vin)
+	require.NoError(t, err)
+	assert.Equal(t, models.VehicleInStock, got.Status)
+	assert.Equal(t, "Red", got.Colour)
+
+	listed, err := s.Vehicles.ListVehicles(ctx, models.VehicleFilter{Location: "Showroom"})
+	require.NoError(t, err)
+	require.Len(t, listed, 1)
+	assert.Equal(t, vin, listed[0].VIN)
+
+	listed, err = s.Vehicles.ListVehicles(ctx, models.VehicleFilter{Status: models.VehicleSold})
+	require.NoError(t, err)
+	assert.Empty(t, listed)
+
+	// units belong to their dealership
+	got, err = s.Vehicles.GetVehicle(other, vin)
+	require.NoError(t, err)
+	assert.Empty(t, got.VIN)
+	_, err = s.Vehicles.UpdateVehicle(other, moved, models.VehicleInStock)
+	assert.ErrorIs(t, err, store.ErrVehicleConflict)
+
+	// a car with units cannot be deleted
+	_, err = s.Cars.DeleteCar(ctx, car.ID.String())
+	assert.ErrorIs(t, err, store.ErrCarHasVehicles)
+	got, err = s.Vehicles.GetVehicle(ctx, vin)
+	require.NoError(t, err)
+	assert.Equal(t, car.ID, got.CarID)
+}
+
+func testVehicleStock(t *testing.T, s Stores) {
+	ctx := tenant.WithTenant(context.Background(), tenant.DefaultID)
+	car := createCar(t, ctx, s)
+
+	for i, vin := range []string{"1HGCM82633A000001", "1HGCM82633A000002", "1HGCM82633A000003"} {
+		req := vehicleRequest(car.ID, vin)
+		if i > 0 {
+			req.Status = models.VehicleInStock
+		}
+		_, err := s.Vehicles.ReceiveVehicle(ctx, req)
+		require.NoError(t, err)
+	}
+
+	counts, err := s.Vehicles.StockCounts(ctx, car.ID.String())
+	require.NoError(t, err)
+	require.Len(t, counts, 1)
+	assert.Equal(t, models.StockCount{CarID: car.ID, InTransit: 1, InStock: 2}, counts[0])
+
+	counts, err = s.Vehicles.StockCounts(ctx, "")
+	require.NoError(t, err)
+	assert.Len(t, counts, 1)
+
+	counts, err = s.Vehicles.StockCounts(tenant.WithTenant(context.Background(), OtherTenant), "")
+	require.NoError(t, err)
+	assert.Empty(t, counts)
+}
+
+func reservation(carID uuid.UUID, now time.Time, hold time.Duration) models.Reservation {
+	return models.Reservation{
+		ID:              uuid.New(),
+		CarID:           carID,
+		CustomerName:    "Jane Doe",
+		CustomerContact: "jane@example.com",
+		ReservedBy:      "staff",
+		Status:          models.ReservationActive,
+		ExpiresAt:       now.Add(hold),
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+}
+
+func testReservationLifecycle(t *testing.T, s Stores) {
+	ctx := tenant.WithTenant(context.Background(), tenant.DefaultID)
+	other := tenant.WithTenant(context.Background(), OtherTenant)
+	car := createCar(t, ctx, s)
+	now := time.Now().Truncate(time.Second)
+
+	first, err := s.Reservations.CreateReservation(ctx, reservation(car.ID, now, time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, car.ID, first.CarID)
+
+	_, err = s.Reservations.CreateReservation(ctx, reservation(car.ID, now, time.Hour))
+	assert.ErrorIs(t, err, store.ErrCarReserved)
+	_, err = s.Reservations.CreateReservation(ctx, reservation(uuid.New(), now, time.Hour))
+	assert.ErrorIs(t, err, store.ErrCarNotFound)
+	_, err = s.Reservations.CreateReservation(other, reservation(car.ID, now, time.Hour))
+	assert.ErrorIs(t, err, store.ErrCarNotFound)
+
+	active, err := s.Reservations.GetActiveReservation(ctx, car.ID.String(), now)
+	require.NoError(t, err)
+	assert.Equal(t, first.ID, active.ID)
+	active, err = s.Reservations.GetActiveReservation(other, car.ID.String(), now)
+	require.NoError(t, err)
+	assert.Equal(t, uuid.Nil, active.ID)
+
+	released, err := s.Reservations.ReleaseReservation(ctx, car.ID.String(), first.ID.String(), now)
+	require.NoError(t, err)
+	assert.Equal(t, models.ReservationReleased, released.Status)
+	_, err = s.Reservations.ReleaseReservation(ctx, car.ID.String(), first.ID.String(), now)
+	assert.ErrorIs(t, err, store.ErrReservationNotActive)
+	missing, err := s.Reservations.ReleaseReservation(ctx, car.ID.String(), uuid.NewString(), now)
+	require.NoError(t, err)
+	assert.Equal(t, uuid.Nil, missing.ID)
+
+	// a released car can be reserved again
+	second, err := s.Reservations.CreateReservation(ctx, reservation(car.ID, now.Add(time.Second), time.Hour))
+	require.NoError(t, err)
+
+	listed, err := s.Reservations.ListReservations(ctx, car.ID.String())
+	require.NoError(t, err)
+	require.Len(t, listed, 2)
+	assert.Equal(t, second.ID, listed[0].ID)
+	assert.Equal(t, first.ID, listed[1].ID)
+}
+
+func testReservationConcurrency(t *testing.T, s Stores) {
+	ctx := tenant.WithTenant(context.Background(), tenant.DefaultID)
+	car := createCar(t, ctx, s)
+	now := time.Now()
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		created  int
+		reserved int
+	)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := s.Reservations.CreateReservation(ctx, reservation(car.ID, now, time.Hour))
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+				created++
+			case errors.Is(err, store.ErrCarReserved):
+				reserved++
+			default:
+				t.Errorf("unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 1, created)
+	assert.Equal(t, 7, reserved)
+}
+
+func testReservationExpiry(t *testing.T, s Stores) {
+	ctx := tenant.WithTenant(context.Background(), tenant.DefaultID)
+	car := createCar(t, ctx, s)
+	otherCar := createCar(t, ctx, s)
+	now := time.Now().Truncate(time.Second)
+
+	_, err := s.Reservations.CreateReservation(ctx, reservation(car.ID, now, time.Minute))
+	require.NoError(t, err)
+	_, err = s.Reservations.CreateReservation(ctx, reservation(otherCar.ID, now, time.Hour))
+	require.NoError(t, err)
+
+	// past its expiry a hold no longer counts, even before the sweeper runs
+	later := now.Add(2 * time.Minute)
+	active, err := s.Reservations.GetActiveReservation(ctx, car.ID.String(), later)
+	require.NoError(t, err)
+	assert.Equal(t, uuid.Nil, active.ID)
+
+	expired, err := s.Reservations.ExpireReservations(context.Background(), later)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), expired)
+
+	listed, err := s.Reservations.ListReservations(ctx, car.ID.String())
+	require.NoError(t, err)
+	require.Len(t, listed, 1)
+	assert.Equal(t, models.ReservationExpired, listed[0].Status)
+
+	_, err = s.Reservations.CreateReservation(ctx, reservation(car.ID, later, time.Hour))
+	assert.NoError(t, err)
+	_, err = s.Reservations.CreateReservation(ctx, reservation(otherCar.ID, later, time.Hour))
+	assert.ErrorIs(t, err, store.ErrCarReserved)
+}
+
+func order(now time.Time, carIDs ...uuid.UUID) models.Order {
+	o := models.Order{
+		ID:              uuid.New(),
+		Status:          models.OrderDraft,
+		CustomerName:    "Jane Doe",
+		CustomerEmail:   "jane@example.com",
+		CustomerAddress: "1 Main Street",
+		Salesperson:     "staff",
+		Currency:        "USD",
+		TaxRate:         decimal.New(825, 2),
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+	for _, carID := range carIDs {
+		o.Lines = append(o.Lines, models.OrderLine{
+			ID:              uuid.New(),
+			CarID:           carID,
+			Description:     "Toyota Camry 2023",
+			Quantity:        1,
+			UnitPrice:       decimal.New(2499999, 2),
+			DiscountPercent: decimal.New(5, 0),
+		})
+	}
+	o.ComputeTotals()
+	return o
+}
+
+func testOrderLifecycle(t *testing.T, s Stores) {
+	ctx := tenant.WithTenant(context.Background(), tenant.DefaultID)
+	other := tenant.WithTenant(context.Background(), OtherTenant)
+	first, second := createCar(t, ctx, s), createCar(t, ctx, s)
+	now := time.Now().Truncate(time.Second)
+
+	created, err := s.Orders.CreateOrder(ctx, order(now, first.ID, second.ID))
+	require.NoError(t, err)
+	require.Len(t, created.Lines, 2)
+	assert.Equal(t, first.ID, created.Lines[0].CarID)
+	assert.Equal(t, second.ID, created.Lines[1].CarID)
+	assert.Equal(t, "47499.98", created.Subtotal.String())
+	assert.Equal(t, "2500", created.DiscountTotal.String())
+	assert.Equal(t, "3918.75", created.TaxTotal.String())
+	assert.Equal(t, "51418.73", created.Total.String())
+	assert.Empty(t, created.InvoiceNumber)
+
+	got, err := s.Orders.GetOrder(ctx, created.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, created.ID, got.ID)
+	assert.Equal(t, "8.25", got.TaxRate.String())
+	assert.Equal(t, "USD", got.Currency)
+	missing, err := s.Orders.GetOrder(other, created.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, uuid.Nil, missing.ID)
+
+	_, err = s.Orders.CreateOrder(ctx, order(now, uuid.New()))
+	assert.ErrorIs(t, err, store.ErrCarNotFound)
+	_, err = s.Orders.CreateOrder(other, order(now, first.ID))
+	assert.ErrorIs(t, err, store.ErrCarNotFound)
+
+	edited := order(now, second.ID)
+	edited.ID = created.ID
+	edited.CustomerName = "John Doe"
+	edited.UpdatedAt = now.Add(time.Second)
+	updated, err := s.Orders.UpdateOrder(ctx, edited)
+	require.NoError(t, err)
+	assert.Equal(t, "John Doe", updated.CustomerName)
+	require.Len(t, updated.Lines, 1)
+	assert.Equal(t, second.ID, updated.Lines[0].CarID)
+
+	confirmed, err := s.Orders.UpdateOrderStatus(ctx, created.ID.String(), models.OrderDraft, models.OrderConfirmed, now)
+	require.NoError(t, err)
+	assert.Equal(t, models.OrderConfirmed, confirmed.Status)
+	assert.Equal(t, "INV-000001", confirmed.InvoiceNumber)
+	require.NotNil(t, confirmed.ConfirmedAt)
+
+	// only drafts can be edited, and only from the status they are in
+	_, err = s.Orders.UpdateOrder(ctx, edited)
+	assert.ErrorIs(t, err, store.ErrOrderConflict)
+	_, err = s.Orders.UpdateOrderStatus(ctx, created.ID.String(), models.OrderDraft, models.OrderConfirmed, now)
+	assert.ErrorIs(t, err, store.ErrOrderConflict)
+	_, err = s.Orders.UpdateOrderStatus(other, created.ID.String(), models.OrderConfirmed, models.OrderPaid, now)
+	assert.ErrorIs(t, err, store.ErrOrderConflict)
+
+	paid, err := s.Orders.UpdateOrderStatus(ctx, created.ID.String(), models.OrderConfirmed, models.OrderPaid, now)
+	require.NoError(t, err)
+	assert.Equal(t, "INV-000001", paid.InvoiceNumber)
+
+	// a car that was sold stays
+	_, err = s.Cars.DeleteCar(ctx, second.ID.String())
+	assert.ErrorIs(t, err, store.ErrCarOnOrder)
+
+	_, err = s.Orders.CreateOrder(ctx, order(now.Add(time.Second), first.ID))
+	require.NoError(t, err)
+
+	listed, err := s.Orders.ListOrders(ctx, "")
+	require.NoError(t, err)
+	assert.Len(t, listed, 2)
+	listed, err = s.Orders.ListOrders(ctx, models.OrderPaid)
+	require.NoError(t, err)
+	require.Len(t, listed, 1)
+	assert.Equal(t, created.ID, listed[0].ID)
+	assert.Empty(t, listed[0].Lines)
+
+	// amounts in a 3 minor unit currency keep their third decimal
+	dinars := order(now, first.ID)
+	dinars.Currency = "BHD"
+	dinars.Lines[0].UnitPrice = decimal.New(12345, 3)
+	dinars.ComputeTotals()
+	_, err = s.Orders.CreateOrder(ctx, dinars)
+	require.NoError(t, err)
+	got, err = s.Orders.GetOrder(ctx, dinars.ID.String())
+	require.NoError(t, err)
+	require.Len(t, got.Lines, 1)
+	assert.Equal(t, "12.345", got.Lines[0].UnitPrice.String())
+	assert.Equal(t, "0.617", got.Lines[0].Discount.String())
+	assert.Equal(t, "11.728", got.Subtotal.String())
+	assert.Equal(t, "0.968", got.TaxTotal.String())
+	assert.Equal(t, "12.696", got.Total.String())
+}
+
+func testInvoiceNumbers(t *testing.T, s Stores) {
+	ctx := tenant.WithTenant(context.Background(), tenant.DefaultID)
+	car := createCar(t, ctx, s)
+	now := time.Now()
+
+	var ids []string
+	for i := 0; i < 5; i++ {
+		created, err := s.Orders.CreateOrder(ctx, order(now, car.ID))
+		require.NoError(t, err)
+		ids = append(ids, created.ID.String())
+	}
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		numbers = map[string]bool{}
+	)
+	for _, id := range ids {
+		wg.Add(1)
+		go func(id string) {
+			defer wg.Done()
+			confirmed, err := s.Orders.UpdateOrderStatus(ctx, id, models.OrderDraft, models.OrderConfirmed, now)
+			if !assert.NoError(t, err) {
+				return
+			}
+			mu.Lock()
+			numbers[confirmed.InvoiceNumber] = true
+			mu.Unlock()
+		}(id)
+	}
+	wg.Wait()
+
+	// sequential without gaps or duplicates
+	for n := int64(1); n <= 5; n++ {
+		assert.True(t, numbers[models.InvoiceNumber(n)], models.InvoiceNumber(n))
+	}
+
+	// every dealership counts on its own
+	other := tenant.WithTenant(context.Background(), OtherTenant)
+	otherCar := createCar(t, other, s)
+	created, err := s.Orders.CreateOrder(other, order(now, otherCar.ID))
+	require.NoError(t, err)
+	confirmed, err := s.Orders.UpdateOrderStatus(other, created.ID.String(), models.OrderDraft, models.OrderConfirmed, now)
+	require.NoError(t, err)
+	assert.Equal(t, "INV-000001", confirmed.InvoiceNumber)
+}
+
+func customer(now time.Time, name, email string) models.Customer {
+	return models.Customer{
+		ID:             uuid.New(),
+		Name:           name,
+		Email:          email,
+		Phone:          "+1 555 0100",
+		Address:        "1 Main St",
+		ContactConsent: true,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+}
+
+func testCustomerLifecycle(t *testing.T, s Stores) {
+	ctx := tenant.WithTenant(context.Background(), tenant.DefaultID)
+	other := tenant.WithTenant(context.Background(), OtherTenant)
+	car := createCar(t, ctx, s)
+	now := time.Now().Truncate(time.Second)
+
+	created, err := s.Customers.CreateCustomer(ctx, customer(now, "Jane Doe", "jane@example.com"))
+	require.NoError(t, err)
+	assert.Equal(t, "Jane Doe", created.Name)
+	assert.True(t, created.ContactConsent)
+	assert.Nil(t, created.ErasedAt)
+	_, err = s.Customers.CreateCustomer(ctx, customer(now, "John 100%_Smith", "john@example.com"))
+	require.NoError(t, err)
+
+	missing, err := s.Customers.GetCustomerById(other, created.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, uuid.Nil, missing.ID)
+
+	note := models.CustomerNote{ID: uuid.New(), Body: "Wants a test drive", Author: "alice", CreatedAt: now}
+	_, err = s.Customers.AddCustomerNote(ctx, created.ID.String(), note)
+	require.NoError(t, err)
+	_, err = s.Customers.AddCustomerNote(other, created.ID.String(), note)
+	assert.ErrorIs(t, err, store.ErrCustomerNotFound)
+
+	viewed := models.CustomerCar{CarID: car.ID, Kind: models.CustomerCarViewed, CreatedAt: now}
+	_, err = s.Customers.LinkCustomerCar(ctx, created.ID.String(), viewed)
+	require.NoError(t, err)
+	// linking the same way again keeps the first link
+	again, err := s.Customers.LinkCustomerCar(ctx, created.ID.String(),
+		models.CustomerCar{CarID: car.ID, Kind: models.CustomerCarViewed, CreatedAt: now.Add(time.Hour)})
+	require.NoError(t, err)
+	assert.True(t, now.Equal(again.CreatedAt))
+	_, err = s.Customers.LinkCustomerCar(ctx, created.ID.String(),
+		models.CustomerCar{CarID: car.ID, Kind: models.CustomerCarReserved, CreatedAt: now.Add(time.Second)})
+	require.NoError(t, err)
+	_, err = s.Customers.LinkCustomerCar(ctx, created.ID.String(),
+		models.CustomerCar{CarID: uuid.New(), Kind: models.CustomerCarViewed, CreatedAt: now})
+	assert.ErrorIs(t, err, store.ErrCarNotFound)
+	_, err = s.Customers.LinkCustomerCar(ctx, uuid.New().String(), viewed)
+	assert.ErrorIs(t, err, store.ErrCustomerNotFound)
+
+	got, err := s.Customers.GetCustomerById(ctx, created.ID.String())
+	require.NoError(t, err)
+	require.Len(t, got.Notes, 1)
+	assert.Equal(t, "Wants a test drive", got.Notes[0].Body)
+	require.Len(t, got.Cars, 2)
+	assert.Equal(t, models.CustomerCarViewed, got.Cars[0].Kind)
+	assert.Equal(t, models.CustomerCarReserved, got.Cars[1].Kind)
+
+	edited := got
+	edited.Email = "jane.doe@example.com"
+	edited.MarketingConsent = true
+	edited.UpdatedAt = now.Add(time.Minute)
+	updated, err := s.Customers.UpdateCustomer(ctx, edited)
+	require.NoError(t, err)
+	assert.Equal(t, "jane.doe@example.com", updated.Email)
+	assert.True(t, updated.MarketingConsent)
+	assert.Len(t, updated.Cars, 2)
+	missing, err = s.Customers.UpdateCustomer(other, edited)
+	require.NoError(t, err)
+	assert.Equal(t, uuid.Nil, missing.ID)
+
+	listed, err := s.Customers.ListCustomers(ctx, "")
+	require.NoError(t, err)
+	assert.Len(t, listed, 2)
+	listed, err = s.Customers.ListCustomers(ctx, "JANE.DOE")
+	require.NoError(t, err)
+	require.Len(t, listed, 1)
+	assert.Equal(t, created.ID, listed[0].ID)
+	assert.Empty(t, listed[0].Notes)
+	// wildcards in the search are literal
+	listed, err = s.Customers.ListCustomers(ctx, "100%_")
+	require.NoError(t, err)
+	assert.Len(t, listed, 1)
+	listed, err = s.Customers.ListCustomers(ctx, "0%s")
+	require.NoError(t, err)
+	assert.Empty(t, listed)
+	listed, err = s.Customers.ListCustomers(other, "")
+	require.NoError(t, err)
+	assert.Empty(t, listed)
+
+	// deleting the car drops its links
+	_, err = s.Cars.DeleteCar(ctx, car.ID.String())
+	require.NoError(t, err)
+	got, err = s.Customers.GetCustomerById(ctx, created.ID.String())
+	require.NoError(t, err)
+	assert.Empty(t, got.Cars)
+
+	deleted, err := s.Customers.DeleteCustomer(ctx, created.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, created.ID, deleted.ID)
+	got, err = s.Customers.GetCustomerById(ctx, created.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, uuid.Nil, got.ID)
+}
+
+func testCustomerErase(t *testing.T, s Stores) {
+	ctx := tenant.WithTenant(context.Background(), tenant.DefaultID)
+	other := tenant.WithTenant(context.Background(), OtherTenant)
+	car := createCar(t, ctx, s)
+	now := time.Now().Truncate(time.Second)
+
+	created, err := s.Customers.CreateCustomer(ctx, customer(now, "Jane Doe", "jane@example.com"))
+	require.NoError(t, err)
+	_, err = s.Customers.AddCustomerNote(ctx, created.ID.String(),
+		models.CustomerNote{ID: uuid.New(), Body: "Lives next to the showroom", Author: "alice", CreatedAt: now})
+	require.NoError(t, err)
+	_, err = s.Customers.LinkCustomerCar(ctx, created.ID.String(),
+		models.CustomerCar{CarID: car.ID, Kind: models.CustomerCarPurchased, CreatedAt: now})
+	require.NoError(t, err)
+
+	missing, err := s.Customers.EraseCustomer(other, created.ID.String(), now)
+	require.NoError(t, err)
+	assert.Equal(t, uuid.Nil, missing.ID)
+
+	erasedAt := now.Add(time.Hour)
+	erased, err := s.Customers.EraseCustomer(ctx, created.ID.String(), erasedAt)
+	require.NoError(t, err)
+	assert.Equal(t, created.ID, erased.ID)
+	assert.Empty(t, erased.Name)
+	assert.Empty(t, erased.Email)
+	assert.Empty(t, erased.Phone)
+	assert.Empty(t, erased.Address)
+	assert.False(t, erased.ContactConsent)
+	assert.Empty(t, erased.Notes)
+	require.NotNil(t, erased.ErasedAt)
+	assert.True(t, erasedAt.Equal(*erased.ErasedAt))
+	// the links stay, they no longer say who the customer was
+	assert.Len(t, erased.Cars, 1)
+
+	again, err := s.Customers.EraseCustomer(ctx, created.ID.String(), erasedAt.Add(time.Hour))
+	require.NoError(t, err)
+	require.NotNil(t, again.ErasedAt)
+	assert.True(t, erasedAt.Equal(*again.ErasedAt))
+
+	listed, err := s.Customers.ListCustomers(ctx, "")
+	require.NoError(t, err)
+	assert.Empty(t, listed)
+}
+
+func testCustomerLinkedRecords(t *testing.T, s Stores) {
+	ctx := tenant.WithTenant(context.Background(), tenant.DefaultID)
+	first, second := createCar(t, ctx, s), createCar(t, ctx, s)
+	now := time.Now().Truncate(time.Second)
+
+	created, err := s.Customers.CreateCustomer(ctx, customer(now, "Jane Doe", "jane@example.com"))
+	require.NoError(t, err)
+
+	unknown := uuid.New()
+	held := reservation(first.ID, now, time.Hour)
+	held.CustomerID = &unknown
+	_, err = s.Reservations.CreateReservation(ctx, held)
+	assert.ErrorIs(t, err, store.ErrCustomerNotFound)
+	sold := order(now, second.ID)
+	sold.CustomerID = &unknown
+	_, err = s.Orders.CreateOrder(ctx, sold)
+	assert.ErrorIs(t, err, store.ErrCustomerNotFound)
+
+	held.CustomerID = &created.ID
+	reserved, err := s.Reservations.CreateReservation(ctx, held)
+	require.NoError(t, err)
+	require.NotNil(t, reserved.CustomerID)
+	assert.Equal(t, created.ID, *reserved.CustomerID)
+	sold.CustomerID = &created.ID
+	ordered, err := s.Orders.CreateOrder(ctx, sold)
+	require.NoError(t, err)
+	require.NotNil(t, ordered.CustomerID)
+	assert.Equal(t, created.ID, *ordered.CustomerID)
+	// records without a customer are not listed
+	_, err = s.Orders.CreateOrder(ctx, order(now, second.ID))
+	require.NoError(t, err)
+
+	reservations, err := s.Reservations.ListCustomerReservations(ctx, created.ID.String())
+	require.NoError(t, err)
+	require.Len(t, reservations, 1)
+	assert.Equal(t, reserved.ID, reservations[0].ID)
+	assert.Equal(t, "Jane Doe", reservations[0].CustomerName)
+	orders, err := s.Orders.ListCustomerOrders(ctx, created.ID.String())
+	require.NoError(t, err)
+	require.Len(t, orders, 1)
+	assert.Equal(t, ordered.ID, orders[0].ID)
+	assert.Len(t, orders[0].Lines, 1)
+
+	_, err = s.Customers.EraseCustomer(ctx, created.ID.String(), now)
+	require.NoError(t, err)
+
+	// no name or contact of the customer survives the erasure
+	listed, err := s.Reservations.ListReservations(ctx, first.ID.String())
+	require.NoError(t, err)
+	require.Len(t, listed, 1)
+	assert.Empty(t, listed[0].CustomerName)
+	assert.Empty(t, listed[0].CustomerContact)
+	got, err := s.Orders.GetOrder(ctx, ordered.ID.String())
+	require.NoError(t, err)
+	assert.Empty(t, got.CustomerName)
+	assert.Empty(t, got.CustomerEmail)
+	assert.Empty(t, got.CustomerAddress)
+	require.NotNil(t, got.CustomerID)
+	assert.Equal(t, created.ID, *got.CustomerID)
+
+	// an erased customer cannot be linked again
+	draft := order(now, second.ID)
+	draft.CustomerID = &created.ID
+	_, err = s.Orders.CreateOrder(ctx, draft)
+	assert.ErrorIs(t, err, store.ErrCustomerNotFound)
+}
+
+func testExchangeRates(t *testing.T, s Stores) {
+	ctx := context.Background()
+	now := time.Now().Truncate(time.Second)
+
+	rates, err := s.Rates.GetRates(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, rates)
+
+	_, err = s.Rates.SetRate(ctx, models.ExchangeRate{Currency: "VND", Rate: decimal.New(25400, 0), UpdatedAt: now})
+	require.NoError(t, err)
+	_, err = s.Rates.SetRate(ctx, models.ExchangeRate{Currency: "EUR", Rate: decimal.New(92, 2), UpdatedAt: now})
+	require.NoError(t, err)
+	_, err = s.Rates.SetRate(ctx, models.ExchangeRate{Currency: "EUR", Rate: decimal.New(9125, 4), UpdatedAt: now.Add(time.Hour)})
+	require.NoError(t, err)
+
+	rates, err = s.Rates.GetRates(ctx)
+	require.NoError(t, err)
+	require.Len(t, rates, 2)
+	assert.Equal(t, "EUR", rates[0].Currency)
+	assert.Equal(t, "0.9125", rates[0].Rate.String())
+	assert.True(t, now.Add(time.Hour).Equal(rates[0].UpdatedAt))
+	assert.Equal(t, "VND", rates[1].Currency)
+	assert.Equal(t, "25400", rates[1].Rate.String())
+}
+
+func testCarPriceHistory(t *testing.T, s Stores) {
+	ctx := tenant.WithTenant(context.Background(), tenant.DefaultID)
+	other := tenant.WithTenant(context.Background(), OtherTenant)
+	car := createCar(t, ctx, s)
+
+	prices, err := s.Prices.ListCarPrices(ctx, car.ID.String())
+	require.NoError(t, err)
+	require.Len(t, prices, 1)
+	assert.Equal(t, car.Price, prices[0].Price)
+
+	update := carRequest(car.Engine.EngineID, "Toyota")
+	update.Price = money.Money{Amount: 2400000, Currency: "USD"}
+	_, err = s.Cars.UpdateCar(ctx, car.ID.String(), update)
+	require.NoError(t, err)
+	// renaming the car does not touch its price history
+	update.Name = "Camry Hybrid"
+	_, err = s.Cars.UpdateCar(ctx, car.ID.String(), update)
+	require.NoError(t, err)
+
+	// a whole second after the price change, it started before now
+	now := time.Now().Truncate(time.Second).Add(time.Second)
+	saleEnds := now.Add(2 * time.Hour)
+	sale, err := s.Prices.AddCarPrice(ctx, models.CarPrice{
+		ID: uuid.New(), CarID: car.ID, Price: money.Money{Amount: 2000000, Currency: "USD"},
+		EffectiveFrom: now.Add(time.Hour), EffectiveTo: &saleEnds, CreatedBy: "alice", CreatedAt: now,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "alice", sale.CreatedBy)
+	require.NotNil(t, sale.EffectiveTo)
+	assert.True(t, saleEnds.Equal(*sale.EffectiveTo))
+
+	_, err = s.Prices.AddCarPrice(other, models.CarPrice{
+		ID: uuid.New(), CarID: car.ID, Price: money.Money{Amount: 1, Currency: "USD"}, EffectiveFrom: now, CreatedAt: now,
+	})
+	assert.ErrorIs(t, err, store.ErrCarNotFound)
+
+	prices, err = s.Prices.ListCarPrices(ctx, car.ID.String())
+	require.NoError(t, err)
+	require.Len(t, prices, 3)
+	assert.Equal(t, int64(2400000), prices[1].Price.Amount)
+	assert.Equal(t, sale.ID, prices[2].ID)
+
+	hidden, err := s.Prices.ListCarPrices(other, car.ID.String())
+	require.NoError(t, err)
+	assert.Empty(t, hidden)
+
+	applied, err := s.Prices.ApplyCarPrices(context.Background(), now)
+	require.NoError(t, err)
+	assert.Empty(t, applied)
+
+	applied, err = s.Prices.ApplyCarPrices(context.Background(), now.Add(90*time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, []models.RepricedCar{{TenantID: tenant.DefaultID, CarID: car.ID}}, applied)
+	got, err := s.Cars.GetCarById(ctx, car.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, int64(2000000), got.Price.Amount)
+
+	// once the sale ends the list price it covered applies again
+	applied, err = s.Prices.ApplyCarPrices(context.Background(), now.Add(3*time.Hour))
+	require.NoError(t, err)
+	assert.Len(t, applied, 1)
+	got, err = s.Cars.GetCarById(ctx, car.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, int64(2400000), got.Price.Amount)
+
+	started, err := s.Prices.DeleteCarPrice(ctx, car.ID.String(), prices[1].ID.String(), now)
+	require.NoError(t, err)
+	assert.Equal(t, uuid.Nil, started.ID)
+	missing, err := s.Prices.DeleteCarPrice(other, car.ID.String(), sale.ID.String(), now)
+	require.NoError(t, err)
+	assert.Equal(t, uuid.Nil, missing.ID)
+	deleted, err := s.Prices.DeleteCarPrice(ctx, car.ID.String(), sale.ID.String(), now)
+	require.NoError(t, err)
+	assert.Equal(t, sale.ID, deleted.ID)
+
+	prices, err = s.Prices.ListCarPrices(ctx, car.ID.String())
+	require.NoError(t, err)
+	assert.Len(t, prices, 2)
+}
+
+func testPromotions(t *testing.T, s Stores) {
+	ctx := tenant.WithTenant(context.Background(), tenant.DefaultID)
+	other := tenant.WithTenant(context.Background(), OtherTenant)
+	now := time.Now().Truncate(time.Second)
+	ends := now.Add(24 * time.Hour)
+
+	spring, err := s.Prices.CreatePromotion(ctx, models.Promotion{
+		ID: uuid.New(), Name: "Spring sale", Kind: models.PromotionPercentage, Percent: decimal.New(125, 1),
+		Brand: "Toyota", StartsAt: now.Add(time.Hour), EndsAt: &ends, CreatedAt: now,
+	})
+	require.NoError(t, err)
+	_, err = s.Prices.CreatePromotion(ctx, models.Promotion{
+		ID: uuid.New(), Name: "Electric bonus", Kind: models.PromotionFixed, Amount: money.Money{Amount: 150000, Currency: "EUR"},
+		FuelType: "Electric", StartsAt: now, CreatedAt: now,
+	})
+	require.NoError(t, err)
+
+	promotions, err := s.Prices.ListPromotions(ctx)
+	require.NoError(t, err)
+	require.Len(t, promotions, 2)
+	assert.Equal(t, "Electric bonus", promotions[0].Name)
+	assert.Equal(t, money.Money{Amount: 150000, Currency: "EUR"}, promotions[0].Amount)
+	assert.Nil(t, promotions[0].EndsAt)
+	assert.Equal(t, spring.ID, promotions[1].ID)
+	assert.Equal(t, "12.5", promotions[1].Percent.String())
+	assert.Equal(t, "Toyota", promotions[1].Brand)
+	require.NotNil(t, promotions[1].EndsAt)
+	assert.True(t, ends.Equal(*promotions[1].EndsAt))
+
+	promotions, err = s.Prices.ListPromotions(other)
+	require.NoError(t, err)
+	assert.Empty(t, promotions)
+
+	missing, err := s.Prices.DeletePromotion(other, spring.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, uuid.Nil, missing.ID)
+	deleted, err := s.Prices.DeletePromotion(ctx, spring.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, spring.ID, deleted.ID)
+
+	promotions, err = s.Prices.ListPromotions(ctx)
+	require.NoError(t, err)
+	assert.Len(t, promotions, 1)
+}
+
+func carImage(carID uuid.UUID, now time.Time) models.CarImage {
+	id := uuid.New()
+	return models.CarImage{
+		ID: id, CarID: carID, ContentType: "image/jpeg", Size: 2048, Width: 800, Height: 600,
+		Key: "cars/" + carID.String() + "/" + id.String() + ".jpg", ThumbnailKey: "cars/" + carID.String() + "/" + id.String() + "_thumb.jpg",
+		CreatedAt: now,
+	}
+}
+
+func imageIDs(images []models.CarImage) []uuid.UUID {
+	ids := make([]uuid.UUID, len(images))
+	for i, image := range images {
+		ids[i] = image.ID
+	}
+	return ids
+}
+
+func testCarImages(t *testing.T, s Stores) {
+	ctx := tenant.WithTenant(context.Background(), tenant.DefaultID)
+	other := tenant.WithTenant(context.Background(), OtherTenant)
+	now := time.Now().Truncate(time.Second)
+	car := createCar(t, ctx, s)
+	carID := car.ID.String()
+
+	first, err := s.Images.AddCarImage(ctx, carImage(car.ID, now))
+	require.NoError(t, err)
+	assert.True(t, first.Primary)
+	assert.Equal(t, 0, first.Position)
+	assert.Equal(t, int64(2048), first.Size)
+	second, err := s.Images.AddCarImage(ctx, carImage(car.ID, now))
+	require.NoError(t, err)
+	assert.False(t, second.Primary)
+	assert.Equal(t, 1, second.Position)
+	third, err := s.Images.AddCarImage(ctx, carImage(car.ID, now))
+	require.NoError(t, err)
+
+	_, err = s.Images.AddCarImage(other, carImage(car.ID, now))
+	assert.ErrorIs(t, err, store.ErrCarNotFound)
+
+	images, err := s.Images.ListCarImages(ctx, []string{carID, uuid.New().String()})
+	require.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{first.ID, second.ID, third.ID}, imageIDs(images))
+	assert.Equal(t, first.Key, images[0].Key)
+	images, err = s.Images.ListCarImages(other, []string{carID})
+	require.NoError(t, err)
+	assert.Empty(t, images)
+
+	require.NoError(t, s.Images.SetCarImageOrder(ctx, carID, []string{third.ID.String(), first.ID.String(), second.ID.String()}))
+	images, err = s.Images.ListCarImages(ctx, []string{carID})
+	require.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{third.ID, first.ID, second.ID}, imageIDs(images))
+
+	missing, err := s.Images.SetPrimaryCarImage(other, carID, second.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, uuid.Nil, missing.ID)
+	primary, err := s.Images.SetPrimaryCarImage(ctx, carID, second.ID.String())
+	require.NoError(t, err)
+	assert.True(t, primary.Primary)
+	images, err = s.Images.ListCarImages(ctx, []string{carID})
+	require.NoError(t, err)
+	assert.Equal(t, []bool{false, false, true}, []bool{images[0].Primary, images[1].Primary, images[2].Primary})
+
+	// the first image left takes over as primary
+	deleted, err := s.Images.DeleteCarImage(ctx, carID, second.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, second.ID, deleted.ID)
+	images, err = s.Images.ListCarImages(ctx, []string{carID})
+	require.NoError(t, err)
+	require.Len(t, images, 2)
+	assert.Equal(t, third.ID, images[0].ID)
+	assert.True(t, images[0].Primary)
+
+	missing, err = s.Images.DeleteCarImage(ctx, carID, second.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, uuid.Nil, missing.ID)
+
+	// deleting the car takes its images with it
+	_, err = s.Cars.DeleteCar(ctx, carID)
+	require.NoError(t, err)
+	images, err = s.Images.ListCarImages(ctx, []string{carID})
+	require.NoError(t, err)
+	assert.Empty(t, images)
+}
+
+func testCatalogue(t *testing.T, s Stores) {
+	ctx := tenant.WithTenant(context.Background(), tenant.DefaultID)
+
+	brand, err := s.Catalogue.SaveBrand(ctx, models.Brand{
+		ID: uuid.New(), Name: "Rivian", Aliases: []string{"Rivian Motors", "RIV"}, CreatedAt: time.Now(),
+	})
+	require.NoError(t, err)
+
+	found, err := s.Catalogue.FindBrand(ctx, "  rivian ")
+	require.NoError(t, err)
+	assert.Equal(t, brand.ID, found.ID)
+	assert.Equal(t, []string{"RIV", "Rivian Motors"}, found.Aliases)
+	found, err = s.Catalogue.FindBrand(ctx, "riv")
+	require.NoError(t, err)
+	assert.Equal(t, brand.ID, found.ID)
+	found, err = s.Catalogue.FindBrand(ctx, "Rivia")
+	require.NoError(t, err)
+	assert.Equal(t, uuid.Nil, found.ID)
+
+	model, err := s.Catalogue.SaveCarModel(ctx, models.CarModel{
+		ID: uuid.New(), BrandID: brand.ID, Name: "R1T", Aliases: []string{"R1-T"}, CreatedAt: time.Now(),
+	})
+	require.NoError(t, err)
+	got, err := s.Catalogue.GetBrand(ctx, brand.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, "Rivian", got.Name)
+	require.Len(t, got.Models, 1)
+	assert.Equal(t, "R1T", got.Models[0].Name)
+	assert.Equal(t, []string{"R1-T"}, got.Models[0].Aliases)
+
+	brands, err := s.Catalogue.ListBrands(ctx)
+	require.NoError(t, err)
+	var listed bool
+	for _, b := range brands {
+		listed = listed || b.ID == brand.ID
+	}
+	assert.True(t, listed)
+
+	// renaming the brand renames its cars
+	engine, err := s.Engines.CreateEngine(ctx, engineRequest())
+	require.NoError(t, err)
+	car, err := s.Cars.CreateCar(ctx, carRequest(engine.EngineID, "Rivian"))
+	require.NoError(t, err)
+	brand.Name = "Rivian Automotive"
+	brand.Aliases = []string{"Rivian"}
+	_, err = s.Catalogue.SaveBrand(ctx, brand)
+	require.NoError(t, err)
+	gotCar, err := s.Cars.GetCarById(ctx, car.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, "Rivian Automotive", gotCar.Brand)
+	found, err = s.Catalogue.FindBrand(ctx, "RIVIAN")
+	require.NoError(t, err)
+	assert.Equal(t, brand.ID, found.ID)
+	found, err = s.Catalogue.FindBrand(ctx, "riv")
+	require.NoError(t, err)
+	assert.Equal(t, uuid.Nil, found.ID)
+
+	_, err = s.Catalogue.DeleteBrand(ctx, brand.ID.String())
+	assert.ErrorIs(t, err, store.ErrBrandInUse)
+	_, err = s.Cars.DeleteCar(ctx, car.ID.String())
+	require.NoError(t, err)
+
+	deletedModel, err := s.Catalogue.DeleteCarModel(ctx, brand.ID.String(), model.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, model.ID, deletedModel.ID)
+	deletedModel, err = s.Catalogue.DeleteCarModel(ctx, brand.ID.String(), model.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, uuid.Nil, deletedModel.ID)
+
+	deleted, err := s.Catalogue.DeleteBrand(ctx, brand.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, brand.ID, deleted.ID)
+	got, err = s.Catalogue.GetBrand(ctx, brand.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, uuid.Nil, got.ID)
+}
+
+func testValidationRules(t *testing.T, s Stores) {
+	ctx := tenant.WithTenant(context.Background(), tenant.DefaultID)
+	other := tenant.WithTenant(context.Background(), OtherTenant)
+
+	rules, err := s.Rules.GetValidationRules(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, rules)
+
+	set := []models.ValidationRule{
+		{ID: "price-ceiling", Target: models.RuleTargetCar, Field: "price", Check: models.CheckMax, Value: "100000"},
+		{ID: "brands", Target: models.RuleTargetCar, Field: "brand", Check: models.CheckIn, Values: []string{"Toyota", "Honda"}},
+		{ID: "year-min", Disabled: true},
+	}
+	_, err = s.Rules.SetValidationRules(ctx, set)
+	require.NoError(t, err)
+
+	rules, err = s.Rules.GetValidationRules(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, set, rules)
+
+	// the rules of a dealership are its own
+	rules, err = s.Rules.GetValidationRules(other)
+	require.NoError(t, err)
+	assert.Empty(t, rules)
+
+	// setting the rules replaces them all, in the new order
+	_, err = s.Rules.SetValidationRules(ctx, []models.ValidationRule{set[1], set[0]})
+	require.NoError(t, err)
+	rules, err = s.Rules.GetValidationRules(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []models.ValidationRule{set[1], set[0]}, rules)
+
+	_, err = s.Rules.SetValidationRules(ctx, nil)
+	require.NoError(t, err)
+	rules, err = s.Rules.GetValidationRules(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, rules)
+
+	_, err = s.Rules.GetValidationRules(context.Background())
+	assert.ErrorIs(t, err, tenant.ErrMissing)
+}
+
+func testInventoryReport(t *testing.T, s Stores) {
+	ctx := tenant.WithTenant(context.Background(), tenant.DefaultID)
+	engine, err := s.Engines.CreateEngine(ctx, engineRequest())
+	require.NoError(t, err)
+
+	newCar := func(brand, fuelType, year string, price money.Money) models.Car {
+		req := carRequest(engine.EngineID, brand)
+		req.FuelType, req.Year, req.Price = fuelType, year, price
+		car, err := s.Cars.CreateCar(ctx, req)
+		require.NoError(t, err)
+		return car
+	}
+	camry := newCar("Toyota", "Petrol", "2023", money.Money{Amount: 2500000, Currency: "USD"})
+	corolla := newCar("Toyota", "Petrol", "2023", money.Money{Amount: 3500000, Currency: "USD"})
+	newCar("Honda", "Diesel", "2022", money.Money{Amount: 2000000, Currency: "USD"})
+	newCar("Toyota", "Hybrid", "2023", money.Money{Amount: 3000000, Currency: "EUR"})
+
+	for i, unit := range []struct {
+		car    models.Car
+		status string
+	}{
+		{camry, models.VehicleInStock},
+		{camry, models.VehicleInStock},
+		{camry, models.VehicleSold},
+		{corolla, models.VehicleReserved},
+		{corolla, models.VehicleInTransit},
+	} {
+		req := vehicleRequest(unit.car.ID, fmt.Sprintf("1HGCM82633A00%04d", i))
+		req.Status = unit.status
+		_, err := s.Vehicles.ReceiveVehicle(ctx, req)
+		require.NoError(t, err)
+	}
+
+	rows, err := s.Reports.InventoryReport(ctx, models.InventoryReportQuery{GroupBy: []string{models.ReportByBrand}})
+	require.NoError(t, err)
+	require.Len(t, rows, 3)
+	assert.Equal(t, []string{"Honda/USD", "Toyota/EUR", "Toyota/USD"},
+		[]string{rows[0].Brand + "/" + rows[0].Currency, rows[1].Brand + "/" + rows[1].Currency, rows[2].Brand + "/" + rows[2].Currency})
+	usd := func(amount int64) money.Money { return money.Money{Amount: amount, Currency: "USD"} }
+	assert.Equal(t, models.InventoryReportRow{
+		Brand: "Toyota", Currency: "USD", Cars: 2,
+		TotalPrice: usd(6000000), MinPrice: usd(2500000), MaxPrice: usd(3500000),
+		UnitsInStock: 3, StockValue: usd(8500000),
+	}, rows[2])
+	assert.Equal(t, int64(0), rows[0].UnitsInStock)
+
+	rows, err = s.Reports.InventoryReport(ctx, models.InventoryReportQuery{GroupBy: []string{models.ReportByFuelType, models.ReportByYear}})
+	require.NoError(t, err)
+	require.Len(t, rows, 3)
+	assert.Equal(t, "Diesel", rows[0].FuelType)
+	assert.Equal(t, "2022", rows[0].Year)
+	assert.Empty(t, rows[0].Brand)
+
+	// the period takes in the cars added from From until before To
+	hourAgo := time.Now().Add(-time.Hour)
+	rows, err = s.Reports.InventoryReport(ctx, models.InventoryReportQuery{From: hourAgo})
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+	assert.Equal(t, int64(3), rows[1].Cars)
+	rows, err = s.Reports.InventoryReport(ctx, models.InventoryReportQuery{To: hourAgo})
+	require.NoError(t, err)
+	assert.Empty(t, rows)
+
+	rows, err = s.Reports.InventoryReport(tenant.WithTenant(context.Background(), OtherTenant), models.InventoryReportQuery{})
+	require.NoError(t, err)
+	assert.Empty(t, rows)
+
+	_, err = s.Reports.InventoryReport(context.Background(), models.InventoryReportQuery{})
+	assert.ErrorIs(t, err, tenant.ErrMissing)
+}
+
+func testOutbox(t *testing.T, s Stores) {
+	ctx := tenant.WithTenant(context.Background(), tenant.DefaultID)
+	engine, err := s.Engines.CreateEngine(ctx, engineRequest())
+	require.NoError(t, err)
+	car, err := s.Cars.CreateCar(ctx, carRequest(engine.EngineID, "Toyota"))
+	require.NoError(t, err)
+	repriced := carRequest(engine.EngineID, "Toyota")
+	repriced.Price.Amount = 2600000
+	_, err = s.Cars.UpdateCar(ctx, car.ID.String(), repriced)
+	require.NoError(t, err)
+	_, err = s.Cars.DeleteCar(ctx, car.ID.String())
+	require.NoError(t, err)
+	// a change that fails records nothing
+	_, err = s.Cars.UpdateCar(ctx, uuid.NewString(), repriced)
+	require.Error(t, err)
+
+	now := time.Now().UTC().Truncate(time.Second)
+	claim := func(at time.Time, limit int) []models.Event {
+		pending, err := s.Outbox.ClaimEvents(context.Background(), at, at.Add(time.Minute), limit)
+		require.NoError(t, err)
+		claimed := make([]models.Event, len(pending))
+		for i, next := range pending {
+			claimed[i] = next.Event
+		}
+		return claimed
+	}
+	types := func(events []models.Event) []string {
+		names := make([]string, len(events))
+		for i, event := range events {
+			names[i] = event.Type
+		}
+		return names
+	}
+
+	relayed := claim(now, 10)
+	require.Len(t, relayed, 4)
+	assert.Equal(t, []string{models.EventEngineCreated, models.EventCarCreated, models.EventCarUpdated, models.EventCarDeleted},
+		types(relayed))
+	assert.Equal(t, engine.EngineID, relayed[0].EntityID)
+	for i, event := range relayed[1:] {
+		assert.Equal(t, car.ID, event.EntityID)
+		assert.Equal(t, tenant.DefaultID, event.TenantID)
+		assert.Greater(t, event.Sequence, relayed[i].Sequence)
+	}
+	var data models.Car
+	require.NoError(t, json.Unmarshal(relayed[2].Data, &data))
+	assert.Equal(t, int64(2600000), data.Price.Amount)
+	// claimed events are not handed out again
+	assert.Empty(t, claim(now, 10))
+
+	published, err := s.Outbox.FinishEvents(context.Background(), []models.EventResult{
+		{Sequence: relayed[0].Sequence, Published: true},
+		{Sequence: relayed[1].Sequence, Error: "sink is down", RetryAt: now.Add(10 * time.Second)},
+		{Sequence: relayed[2].Sequence, RetryAt: now},
+		{Sequence: relayed[3].Sequence, RetryAt: now},
+	}, now)
+	require.NoError(t, err)
+	assert.Equal(t, 1, published)
+
+	// the car's events wait behind the failed one, other entities go on
+	other, err := s.Engines.CreateEngine(ctx, engineRequest())
+	require.NoError(t, err)
+	relayed = claim(now, 10)
+	require.Len(t, relayed, 1)
+	assert.Equal(t, other.EngineID, relayed[0].EntityID)
+	_, err = s.Outbox.FinishEvents(context.Background(), []models.EventResult{{Sequence: relayed[0].Sequence, Published: true}}, now)
+	require.NoError(t, err)
+
+	// once the failed event is due, it and the held ones come again in order
+	due := now.Add(10 * time.Second)
+	pending, err := s.Outbox.ClaimEvents(context.Background(), due, due.Add(time.Minute), 2)
+	require.NoError(t, err)
+	require.Len(t, pending, 2)
+	assert.Equal(t, 1, pending[0].Attempts)
+	relayed = []models.Event{pending[0].Event, pending[1].Event}
+	// the last one waits while the earlier ones are claimed
+	assert.Empty(t, claim(due, 10))
+	published, err = s.Outbox.FinishEvents(context.Background(), []models.EventResult{
+		{Sequence: relayed[0].Sequence, Published: true},
+		{Sequence: relayed[1].Sequence, Published: true},
+	}, due)
+	require.NoError(t, err)
+	assert.Equal(t, 2, published)
+	relayed = append(relayed, claim(due, 10)...)
+	assert.Equal(t, []string{models.EventCarCreated, models.EventCarUpdated, models.EventCarDeleted}, types(relayed))
+	published, err = s.Outbox.FinishEvents(context.Background(), []models.EventResult{{Sequence: relayed[2].Sequence, Published: true}}, due)
+	require.NoError(t, err)
+	assert.Equal(t, 1, published)
+	assert.Empty(t, claim(due.Add(time.Hour), 10))
+
+	deleted, err := s.Outbox.DeletePublishedEvents(context.Background(), time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Zero(t, deleted)
+	deleted, err = s.Outbox.DeletePublishedEvents(context.Background(), time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), deleted)
+}
+
+func testWebhooks(t *testing.T, s Stores) {
+	ctx := tenant.WithTenant(context.Background(), tenant.DefaultID)
+	other := tenant.WithTenant(context.Background(), OtherTenant)
+	now := time.Now().UTC().Truncate(time.Second)
+
+	cars, err := s.Webhooks.CreateWebhook(ctx, models.Webhook{
+		ID: uuid.New(), URL: "https://partner.example/cars", Events: []string{"car.*"},
+		Secret: "a-secret-of-16-chars", CreatedAt: now,
+	})
+	require.NoError(t, err)
+	all, err := s.Webhooks.CreateWebhook(ctx, models.Webhook{
+		ID: uuid.New(), URL: "https://partner.example/all", Events: []string{"*"},
+		Secret: "another-secret-16", CreatedAt: now.Add(time.Second),
+	})
+	require.NoError(t, err)
+
+	webhooks, err := s.Webhooks.ListWebhooks(ctx)
+	require.NoError(t, err)
+	require.Len(t, webhooks, 2)
+	assert.Equal(t, all.ID, webhooks[0].ID)
+	assert.Equal(t, []string{"car.*"}, webhooks[1].Events)
+	got, err := s.Webhooks.GetWebhook(ctx, cars.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, "a-secret-of-16-chars", got.Secret)
+	_, err = s.Webhooks.GetWebhook(other, cars.ID.String())
+	assert.ErrorIs(t, err, store.ErrWebhookNotFound)
+
+	carEvent, err := models.NewEvent(models.EventCarCreated, tenant.DefaultID, uuid.New(), map[string]string{"name": "Camry"})
+	require.NoError(t, err)
+	engineEvent, err := models.NewEvent(models.EventEngineUpdated, tenant.DefaultID, uuid.New(), map[string]int{"displacement": 2000})
+	require.NoError(t, err)
+	otherEvent, err := models.NewEvent(models.EventCarCreated, OtherTenant, uuid.New(), nil)
+	require.NoError(t, err)
+
+	queued, err := s.Webhooks.EnqueueDeliveries(context.Background(), carEvent, now)
+	require.NoError(t, err)
+	assert.Equal(t, 2, queued)
+	// a relayed event is queued once
+	queued, err = s.Webhooks.EnqueueDeliveries(context.Background(), carEvent, now)
+	require.NoError(t, err)
+	assert.Zero(t, queued)
+	queued, err = s.Webhooks.EnqueueDeliveries(context.Background(), engineEvent, now.Add(time.Second))
+	require.NoError(t, err)
+	assert.Equal(t, 1, queued)
+	queued, err = s.Webhooks.EnqueueDeliveries(context.Background(), otherEvent, now)
+	require.NoError(t, err)
+	assert.Zero(t, queued)
+
+	// only due deliveries are claimed, and not twice
+	pending, err := s.Webhooks.ClaimDeliveries(context.Background(), now, now.Add(time.Minute), 10)
+	require.NoError(t, err)
+	require.Len(t, pending, 2)
+	for _, next := range pending {
+		assert.Equal(t, carEvent.ID, next.Delivery.EventID)
+		assert.Equal(t, models.DeliveryPending, next.Delivery.Status)
+		var payload models.Event
+		require.NoError(t, json.Unmarshal(next.Delivery.Payload, &payload))
+		assert.Equal(t, carEvent.ID, payload.ID)
+		if next.Delivery.WebhookID == cars.ID {
+			assert.Equal(t, "https://partner.example/cars", next.URL)
+			assert.Equal(t, "a-secret-of-16-chars", next.Secret)
+		}
+	}
+	again, err := s.Webhooks.ClaimDeliveries(context.Background(), now.Add(time.Second), now.Add(time.Minute), 10)
+	require.NoError(t, err)
+	require.Len(t, again, 1)
+	assert.Equal(t, engineEvent.ID, again[0].Delivery.EventID)
+
+	// one delivery is retried and then succeeds, the other gives up
+	var toCars, toAll models.WebhookDelivery
+	for _, next := range pending {
+		if next.Delivery.WebhookID == cars.ID {
+			toCars = next.Delivery
+		} else {
+			toAll = next.Delivery
+		}
+	}
+	retryAt := now.Add(10 * time.Second)
+	toCars.Attempts, toCars.ResponseCode, toCars.Error, toCars.NextAttemptAt = 1, 503, "webhook responded 503", &retryAt
+	require.NoError(t, s.Webhooks.RecordAttempt(context.Background(), toCars,
+		models.WebhookAttempt{ResponseCode: 503, Error: "webhook responded 503", DurationMS: 12, AttemptedAt: now}))
+	pending, err = s.Webhooks.ClaimDeliveries(context.Background(), retryAt, retryAt.Add(time.Minute), 10)
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	assert.Equal(t, toCars.ID, pending[0].Delivery.ID)
+	assert.Equal(t, 1, pending[0].Delivery.Attempts)
+
+	deliveredAt := retryAt.Add(time.Second)
+	toCars.Status, toCars.Attempts, toCars.ResponseCode, toCars.Error = models.DeliverySucceeded, 2, 200, ""
+	toCars.NextAttemptAt, toCars.DeliveredAt = nil, &deliveredAt
+	require.NoError(t, s.Webhooks.RecordAttempt(context.Background(), toCars,
+		models.WebhookAttempt{ResponseCode: 200, DurationMS: 8, AttemptedAt: deliveredAt}))
+	toAll.Status, toAll.Attempts, toAll.Error, toAll.NextAttemptAt = models.DeliveryFailed, 1, "connection refused", nil
+	require.NoError(t, s.Webhooks.RecordAttempt(context.Background(), toAll,
+		models.WebhookAttempt{Error: "connection refused", AttemptedAt: now}))
+
+	delivery, err := s.Webhooks.GetDelivery(ctx, cars.ID.String(), toCars.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, models.DeliverySucceeded, delivery.Status)
+	assert.Equal(t, 200, delivery.ResponseCode)
+	assert.Nil(t, delivery.NextAttemptAt)
+	require.NotNil(t, delivery.DeliveredAt)
+	require.Len(t, delivery.Log, 2)
+	assert.Equal(t, []int{503, 200}, []int{delivery.Log[0].ResponseCode, delivery.Log[1].ResponseCode})
+	assert.Equal(t, "webhook responded 503", delivery.Log[0].Error)
+	assert.Equal(t, int64(12), delivery.Log[0].DurationMS)
+	_, err = s.Webhooks.GetDelivery(ctx, all.ID.String(), toCars.ID.String())
+	assert.ErrorIs(t, err, store.ErrDeliveryNotFound)
+	_, err = s.Webhooks.GetDelivery(other, cars.ID.String(), toCars.ID.String())
+	assert.ErrorIs(t, err, store.ErrDeliveryNotFound)
+
+	deliveries, err := s.Webhooks.ListDeliveries(ctx, all.ID.String(), 10)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 2)
+	assert.Equal(t, engineEvent.ID, deliveries[0].EventID)
+	assert.Equal(t, models.DeliveryFailed, deliveries[1].Status)
+	assert.Empty(t, deliveries[1].Log)
+	deliveries, err = s.Webhooks.ListDeliveries(ctx, all.ID.String(), 1)
+	require.NoError(t, err)
+	assert.Len(t, deliveries, 1)
+	_, err = s.Webhooks.ListDeliveries(other, all.ID.String(), 10)
+	assert.ErrorIs(t, err, store.ErrWebhookNotFound)
+
+	// a redelivered delivery is due again with its log kept
+	redeliverAt := now.Add(time.Hour)
+	_, err = s.Webhooks.RedeliverDelivery(other, all.ID.String(), toAll.ID.String(), redeliverAt)
+	assert.ErrorIs(t, err, store.ErrDeliveryNotFound)
+	redelivered, err := s.Webhooks.RedeliverDelivery(ctx, all.ID.String(), toAll.ID.String(), redeliverAt)
+	require.NoError(t, err)
+	assert.Equal(t, models.DeliveryPending, redelivered.Status)
+	assert.Zero(t, redelivered.Attempts)
+	assert.Len(t, redelivered.Log, 1)
+	pending, err = s.Webhooks.ClaimDeliveries(context.Background(), redeliverAt, redeliverAt.Add(time.Minute), 10)
+	require.NoError(t, err)
+	ids := make([]uuid.UUID, len(pending))
+	for i, next := range pending {
+		ids[i] = next.Delivery.ID
+	}
+	assert.Contains(t, ids, toAll.ID)
+
+	deleted, err := s.Webhooks.DeleteFinishedDeliveries(context.Background(), now.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+	_, err = s.Webhooks.GetDelivery(ctx, cars.ID.String(), toCars.ID.String())
+	assert.ErrorIs(t, err, store.ErrDeliveryNotFound)
+
+	// deleting a webhook drops its deliveries
+	_, err = s.Webhooks.DeleteWebhook(other, all.ID.String())
+	assert.ErrorIs(t, err, store.ErrWebhookNotFound)
+	_, err = s.Webhooks.DeleteWebhook(ctx, all.ID.String())
+	require.NoError(t, err)
+	assert.ErrorIs(t, s.Webhooks.RecordAttempt(context.Background(), toAll, models.WebhookAttempt{AttemptedAt: now}),
+		store.ErrDeliveryNotFound)
+	webhooks, err = s.Webhooks.ListWebhooks(ctx)
+	require.NoError(t, err)
+	assert.Len(t, webhooks, 1)
+}
+
+func testLoginLockout(t *testing.T, s Stores) {
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	require.NoError(t, s.Logins.RecordAttempt(ctx, models.LoginAttempt{
+		UserName: "admin", IP: "10.0.0.1", Reason: "invalid_credentials", CreatedAt: now,
+	}))
+
+	lockout, err := s.Logins.GetLockout(ctx, "user:admin")
+	require.NoError(t, err)
+	assert.Equal(t, models.LoginLockout{Subject: "user:admin"}, lockout)
+
+	for i := 1; i <= 3; i++ {
+		lockout, err = s.Logins.IncrementFailure(ctx, "user:admin")
+		require.NoError(t, err)
+		assert.Equal(t, i, lockout.FailedAttempts)
+	}
+	lockout, err = s.Logins.LockUntil(ctx, "user:admin", now.Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, 0, lockout.FailedAttempts)
+	assert.Equal(t, 1, lockout.Lockouts)
+	assert.True(t, lockout.IsLocked(now))
+	assert.False(t, lockout.IsLocked(now.Add(time.Minute)))
+
+	got, err := s.Logins.GetLockout(ctx, "user:admin")
+	require.NoError(t, err)
+	assert.Equal(t, "user:admin", got.Subject)
+	assert.Equal(t, 1, got.Lockouts)
+	assert.True(t, got.LockedUntil.Equal(now.Add(time.Minute)))
+
+	// subjects are counted apart
+	other, err := s.Logins.IncrementFailure(ctx, "ip:10.0.0.1")
+	require.NoError(t, err)
+	assert.Equal(t, 1, other.FailedAttempts)
+
+	require.NoError(t, s.Logins.ResetLockout(ctx, "user:admin"))
+	got, err = s.Logins.GetLockout(ctx, "user:admin")
+	require.NoError(t, err)
+	assert.Equal(t, models.LoginLockout{Subject: "user:admin"}, got)
+}
+
+func testTOTP(t *testing.T, s Stores) {
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	credential, err := s.TOTP.GetTOTP(ctx, "admin")
+	require.NoError(t, err)
+	assert.Empty(t, credential.UserName)
+	require.Error(t, s.TOTP.ConfirmTOTP(ctx, "admin", 1, nil))
+
+	require.NoError(t, s.TOTP.SaveTOTP(ctx, models.TOTPCredential{UserName: "admin", Secret: "FIRST", CreatedAt: now}))
+	// an unconfirmed secret can be replaced
+	require.NoError(t, s.TOTP.SaveTOTP(ctx, models.TOTPCredential{UserName: "admin", Secret: "SECOND", CreatedAt: now}))
+	credential, err = s.TOTP.GetTOTP(ctx, "admin")
+	require.NoError(t, err)
+	assert.Equal(t, "SECOND", credential.Secret)
+	assert.False(t, credential.Confirmed)
+
+	require.NoError(t, s.TOTP.ConfirmTOTP(ctx, "admin", 100, []string{"hash-1", "hash-2"}))
+	credential, err = s.TOTP.GetTOTP(ctx, "admin")
+	require.NoError(t, err)
+	assert.True(t, credential.Confirmed)
+	assert.Equal(t, int64(100), credential.LastUsedStep)
+
+	// a confirmed secret is not replaced and keeps its recovery codes
+	err = s.TOTP.SaveTOTP(ctx, models.TOTPCredential{UserName: "admin", Secret: "THIRD", CreatedAt: now})
+	assert.ErrorIs(t, err, store.ErrTOTPConfirmed)
+	credential, err = s.TOTP.GetTOTP(ctx, "admin")
+	require.NoError(t, err)
+	assert.Equal(t, "SECOND", credential.Secret)
+	assert.True(t, credential.Confirmed)
+
+	used, err := s.TOTP.UseTOTPStep(ctx, "admin", 100)
+	require.NoError(t, err)
+	assert.False(t, used)
+	used, err = s.TOTP.UseTOTPStep(ctx, "admin", 101)
+	require.NoError(t, err)
+	assert.True(t, used)
+
+	consumed, err := s.TOTP.ConsumeRecoveryCode(ctx, "admin", "hash-1")
+	require.NoError(t, err)
+	assert.True(t, consumed)
+	consumed, err = s.TOTP.ConsumeRecoveryCode(ctx, "admin", "hash-1")
+	require.NoError(t, err)
+	assert.False(t, consumed)
+
+	require.NoError(t, s.TOTP.DeleteTOTP(ctx, "admin"))
+	credential, err = s.TOTP.GetTOTP(ctx, "admin")
+	require.NoError(t, err)
+	assert.Empty(t, credential.UserName)
+	consumed, err = s.TOTP.ConsumeRecoveryCode(ctx, "admin", "hash-2")
+	require.NoError(t, err)
+	assert.False(t, consumed)
+}
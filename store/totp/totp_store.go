@@ -0,0 +1,171 @@
+package totp
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/adohong4/carZone/models"
+	"github.com/adohong4/carZone/store"
+	"go.opentelemetry.io/otel"
+)
+
+type Store struct {
+	db *sql.DB
+}
+
+func New(db *sql.DB) Store {
+	return Store{db: db}
+}
+
+func (s Store) GetTOTP(ctx context.Context, username string) (models.TOTPCredential, error) {
+	tracer := otel.Tracer("TOTPStore")
+	ctx, span := tracer.Start(ctx, "GetTOTP-Store")
+	defer span.End()
+
+	var credential models.TOTPCredential
+	err := s.db.QueryRowContext(ctx,
+		"SELECT username, secret, confirmed, last_used_step, created_at FROM user_totp WHERE username = $1", username,
+	).Scan(&credential.UserName, &credential.Secret, &credential.Confirmed, &credential.LastUsedStep, &credential.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.TOTPCredential{}, nil
+		}
+		return models.TOTPCredential{}, err
+	}
+	return credential, nil
+}
+
+// SaveTOTP stores a new, unconfirmed secret in place of an unconfirmed one.
+// A confirmed secret and its recovery codes stay until DeleteTOTP.
+func (s Store) SaveTOTP(ctx context.Context, credential models.TOTPCredential) error {
+	tracer := otel.Tracer("TOTPStore")
+	ctx, span := tracer.Start(ctx, "SaveTOTP-Store")
+	defer span.End()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	result, err := tx.ExecContext(ctx,
+		`INSERT INTO user_totp (username, secret, confirmed, last_used_step, created_at)
+		VALUES ($1, $2, FALSE, $3, $4)
+		ON CONFLICT (username) DO UPDATE
+		SET secret = EXCLUDED.secret, confirmed = FALSE,
+			last_used_step = EXCLUDED.last_used_step, created_at = EXCLUDED.created_at
+		WHERE user_totp.confirmed = FALSE`,
+		credential.UserName, credential.Secret, credential.LastUsedStep, credential.CreatedAt)
+	if err != nil {
+		return err
+	}
+	saved, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if saved == 0 {
+		err = store.ErrTOTPConfirmed
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, "DELETE FROM user_recovery_code WHERE username = $1", credential.UserName)
+	return err
+}
+
+func (s Store) ConfirmTOTP(ctx context.Context, username string, step int64, recoveryCodeHashes []string) error {
+	tracer := otel.Tracer("TOTPStore")
+	ctx, span := tracer.Start(ctx, "ConfirmTOTP-Store")
+	defer span.End()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	result, err := tx.ExecContext(ctx,
+		"UPDATE user_totp SET confirmed = TRUE, last_used_step = $2 WHERE username = $1", username, step)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		err = errors.New("totp not enrolled")
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, "DELETE FROM user_recovery_code WHERE username = $1", username)
+	if err != nil {
+		return err
+	}
+	for _, hash := range recoveryCodeHashes {
+		_, err = tx.ExecContext(ctx,
+			"INSERT INTO user_recovery_code (username, code_hash) VALUES ($1, $2)", username, hash)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UseTOTPStep records step as used, it returns false when that step or a
+// later one was already used so a code cannot be replayed.
+func (s Store) UseTOTPStep(ctx context.Context, username string, step int64) (bool, error) {
+	tracer := otel.Tracer("TOTPStore")
+	ctx, span := tracer.Start(ctx, "UseTOTPStep-Store")
+	defer span.End()
+
+	result, err := s.db.ExecContext(ctx,
+		"UPDATE user_totp SET last_used_step = $2 WHERE username = $1 AND last_used_step < $2", username, step)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected == 1, nil
+}
+
+func (s Store) ConsumeRecoveryCode(ctx context.Context, username, codeHash string) (bool, error) {
+	tracer := otel.Tracer("TOTPStore")
+	ctx, span := tracer.Start(ctx, "ConsumeRecoveryCode-Store")
+	defer span.End()
+
+	result, err := s.db.ExecContext(ctx,
+		"UPDATE user_recovery_code SET used_at = now() WHERE username = $1 AND code_hash = $2 AND used_at IS NULL",
+		username, codeHash)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected == 1, nil
+}
+
+func (s Store) DeleteTOTP(ctx context.Context, username string) error {
+	tracer := otel.Tracer("TOTPStore")
+	ctx, span := tracer.Start(ctx, "DeleteTOTP-Store")
+	defer span.End()
+
+	_, err := s.db.ExecContext(ctx, "DELETE FROM user_totp WHERE username = $1", username)
+	return err
+}
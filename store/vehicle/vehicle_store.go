@@ -0,0 +1,215 @@
+package vehicle
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/adohong4/carZone/models"
+	"github.com/adohong4/carZone/store"
+	"github.com/adohong4/carZone/tenant"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+)
+
+const vehicleColumns = "vin, car_id, status, location, mileage, colour, received_at, updated_at"
+
+type Store struct {
+	db *sql.DB
+}
+
+func New(db *sql.DB) Store {
+	return Store{db: db}
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanVehicle(row scanner) (models.Vehicle, error) {
+	var vehicle models.Vehicle
+	err := row.Scan(
+		&vehicle.VIN, &vehicle.CarID, &vehicle.Status, &vehicle.Location,
+		&vehicle.Mileage, &vehicle.Colour, &vehicle.ReceivedAt, &vehicle.UpdatedAt,
+	)
+	return vehicle, err
+}
+
+func (s Store) GetVehicle(ctx context.Context, vin string) (models.Vehicle, error) {
+	tracer := otel.Tracer("VehicleStore")
+	ctx, span := tracer.Start(ctx, "GetVehicle-Store")
+	defer span.End()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return models.Vehicle{}, err
+	}
+
+	query := "SELECT " + vehicleColumns + " FROM vehicle WHERE vin = $1 AND tenant_id = $2"
+	vehicle, err := scanVehicle(s.db.QueryRowContext(ctx, query, vin, tenantID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Vehicle{}, nil
+		}
+		return models.Vehicle{}, err
+	}
+	return vehicle, nil
+}
+
+func (s Store) ListVehicles(ctx context.Context, filter models.VehicleFilter) ([]models.Vehicle, error) {
+	tracer := otel.Tracer("VehicleStore")
+	ctx, span := tracer.Start(ctx, "ListVehicles-Store")
+	defer span.End()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	conditions := []string{"tenant_id = $1"}
+	args := []interface{}{tenantID}
+	for _, field := range []struct{ column, value string }{
+		{"car_id", filter.CarID},
+		{"status", filter.Status},
+		{"location", filter.Location},
+	} {
+		if field.value != "" {
+			args = append(args, field.value)
+			conditions = append(conditions, fmt.Sprintf("%s = $%d", field.column, len(args)))
+		}
+	}
+
+	query := "SELECT " + vehicleColumns + " FROM vehicle WHERE " + strings.Join(conditions, " AND ") +
+		" ORDER BY received_at, vin"
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var vehicles []models.Vehicle
+	for rows.Next() {
+		vehicle, err := scanVehicle(rows)
+		if err != nil {
+			return nil, err
+		}
+		vehicles = append(vehicles, vehicle)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return vehicles, nil
+}
+
+func (s Store) ReceiveVehicle(ctx context.Context, vehicleReq *models.VehicleRequest) (models.Vehicle, error) {
+	tracer := otel.Tracer("VehicleStore")
+	ctx, span := tracer.Start(ctx, "ReceiveVehicle-Store")
+	defer span.End()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return models.Vehicle{}, err
+	}
+
+	var carID uuid.UUID
+	err = s.db.QueryRowContext(ctx, "SELECT id FROM car WHERE id = $1 AND tenant_id = $2", vehicleReq.CarID, tenantID).Scan(&carID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Vehicle{}, store.ErrCarNotFound
+		}
+		return models.Vehicle{}, err
+	}
+
+	now := time.Now()
+	query := `INSERT INTO vehicle (vin, tenant_id, car_id, status, location, mileage, colour, received_at, updated_at)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+				ON CONFLICT (tenant_id, vin) DO NOTHING
+				RETURNING ` + vehicleColumns
+
+	vehicle, err := scanVehicle(s.db.QueryRowContext(ctx, query,
+		vehicleReq.VIN, tenantID, vehicleReq.CarID, vehicleReq.Status,
+		vehicleReq.Location, vehicleReq.Mileage, vehicleReq.Colour, now, now,
+	))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Vehicle{}, store.ErrVehicleExists
+		}
+		return models.Vehicle{}, err
+	}
+	return vehicle, nil
+}
+
+func (s Store) UpdateVehicle(ctx context.Context, vehicle models.Vehicle, fromStatus string) (models.Vehicle, error) {
+	tracer := otel.Tracer("VehicleStore")
+	ctx, span := tracer.Start(ctx, "UpdateVehicle-Store")
+	defer span.End()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return models.Vehicle{}, err
+	}
+
+	query := `UPDATE vehicle
+				SET status = $3, location = $4, mileage = $5, updated_at = $6
+				WHERE vin = $1 AND tenant_id = $2 AND status = $7
+				RETURNING ` + vehicleColumns
+
+	updated, err := scanVehicle(s.db.QueryRowContext(ctx, query,
+		vehicle.VIN, tenantID, vehicle.Status, vehicle.Location, vehicle.Mileage, time.Now(), fromStatus,
+	))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Vehicle{}, store.ErrVehicleConflict
+		}
+		return models.Vehicle{}, err
+	}
+	return updated, nil
+}
+
+func (s Store) StockCounts(ctx context.Context, carID string) ([]models.StockCount, error) {
+	tracer := otel.Tracer("VehicleStore")
+	ctx, span := tracer.Start(ctx, "StockCounts-Store")
+	defer span.End()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := "SELECT car_id, status, COUNT(*) FROM vehicle WHERE tenant_id = $1"
+	args := []interface{}{tenantID}
+	if carID != "" {
+		query += " AND car_id = $2"
+		args = append(args, carID)
+	}
+	query += " GROUP BY car_id, status ORDER BY car_id"
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var counts []models.StockCount
+	for rows.Next() {
+		var (
+			id     uuid.UUID
+			status string
+			n      int64
+		)
+		if err := rows.Scan(&id, &status, &n); err != nil {
+			return nil, err
+		}
+		if len(counts) == 0 || counts[len(counts)-1].CarID != id {
+			counts = append(counts, models.StockCount{CarID: id})
+		}
+		counts[len(counts)-1].Add(status, n)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
@@ -0,0 +1,384 @@
+package webhook
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/adohong4/carZone/models"
+	"github.com/adohong4/carZone/store"
+	"github.com/adohong4/carZone/tenant"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"go.opentelemetry.io/otel"
+)
+
+const webhookColumns = "id, url, events, secret, created_at"
+
+const deliveryColumns = `d.id, d.webhook_id, d.event_id, d.event_type, d.payload, d.status, d.attempts,
+	d.response_code, d.last_error, d.next_attempt_at, d.created_at, d.delivered_at`
+
+type Store struct {
+	db *sql.DB
+}
+
+func New(db *sql.DB) Store {
+	return Store{db: db}
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanWebhook(row scanner) (models.Webhook, error) {
+	var webhook models.Webhook
+	err := row.Scan(&webhook.ID, &webhook.URL, pq.Array(&webhook.Events), &webhook.Secret, &webhook.CreatedAt)
+	return webhook, err
+}
+
+func scanDelivery(row scanner, extra ...interface{}) (models.WebhookDelivery, error) {
+	var (
+		delivery      models.WebhookDelivery
+		payload       []byte
+		nextAttemptAt sql.NullTime
+		deliveredAt   sql.NullTime
+	)
+	dest := []interface{}{
+		&delivery.ID, &delivery.WebhookID, &delivery.EventID, &delivery.EventType, &payload, &delivery.Status,
+		&delivery.Attempts, &delivery.ResponseCode, &delivery.Error, &nextAttemptAt, &delivery.CreatedAt, &deliveredAt,
+	}
+	err := row.Scan(append(dest, extra...)...)
+	delivery.Payload = payload
+	if nextAttemptAt.Valid {
+		delivery.NextAttemptAt = &nextAttemptAt.Time
+	}
+	if deliveredAt.Valid {
+		delivery.DeliveredAt = &deliveredAt.Time
+	}
+	return delivery, err
+}
+
+func (s Store) CreateWebhook(ctx context.Context, webhook models.Webhook) (models.Webhook, error) {
+	tracer := otel.Tracer("WebhookStore")
+	ctx, span := tracer.Start(ctx, "CreateWebhook-Store")
+	defer span.End()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return models.Webhook{}, err
+	}
+
+	_, err = s.db.ExecContext(ctx,
+		"INSERT INTO webhook (id, tenant_id, url, events, secret, created_at) VALUES ($1, $2, $3, $4, $5, $6)",
+		webhook.ID, tenantID, webhook.URL, pq.Array(webhook.Events), webhook.Secret, webhook.CreatedAt)
+	if err != nil {
+		return models.Webhook{}, err
+	}
+	return webhook, nil
+}
+
+func (s Store) ListWebhooks(ctx context.Context) ([]models.Webhook, error) {
+	tracer := otel.Tracer("WebhookStore")
+	ctx, span := tracer.Start(ctx, "ListWebhooks-Store")
+	defer span.End()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT "+webhookColumns+" FROM webhook WHERE tenant_id = $1 ORDER BY created_at DESC, id", tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var webhooks []models.Webhook
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, webhook)
+	}
+	return webhooks, rows.Err()
+}
+
+func (s Store) GetWebhook(ctx context.Context, id string) (models.Webhook, error) {
+	tracer := otel.Tracer("WebhookStore")
+	ctx, span := tracer.Start(ctx, "GetWebhook-Store")
+	defer span.End()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return models.Webhook{}, err
+	}
+
+	query := "SELECT " + webhookColumns + " FROM webhook WHERE id = $1 AND tenant_id = $2"
+	webhook, err := scanWebhook(s.db.QueryRowContext(ctx, query, id, tenantID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Webhook{}, store.ErrWebhookNotFound
+	}
+	return webhook, err
+}
+
+func (s Store) DeleteWebhook(ctx context.Context, id string) (models.Webhook, error) {
+	tracer := otel.Tracer("WebhookStore")
+	ctx, span := tracer.Start(ctx, "DeleteWebhook-Store")
+	defer span.End()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return models.Webhook{}, err
+	}
+
+	query := "DELETE FROM webhook WHERE id = $1 AND tenant_id = $2 RETURNING " + webhookColumns
+	webhook, err := scanWebhook(s.db.QueryRowContext(ctx, query, id, tenantID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Webhook{}, store.ErrWebhookNotFound
+	}
+	return webhook, err
+}
+
+func (s Store) ListDeliveries(ctx context.Context, webhookID string, limit int) ([]models.WebhookDelivery, error) {
+	tracer := otel.Tracer("WebhookStore")
+	ctx, span := tracer.Start(ctx, "ListDeliveries-Store")
+	defer span.End()
+
+	if _, err := s.GetWebhook(ctx, webhookID); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT "+deliveryColumns+` FROM webhook_delivery d
+			WHERE d.webhook_id = $1 ORDER BY d.created_at DESC, d.id LIMIT $2`, webhookID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []models.WebhookDelivery
+	for rows.Next() {
+		delivery, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, rows.Err()
+}
+
+func (s Store) GetDelivery(ctx context.Context, webhookID, deliveryID string) (models.WebhookDelivery, error) {
+	tracer := otel.Tracer("WebhookStore")
+	ctx, span := tracer.Start(ctx, "GetDelivery-Store")
+	defer span.End()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return models.WebhookDelivery{}, err
+	}
+
+	query := "SELECT " + deliveryColumns + ` FROM webhook_delivery d JOIN webhook w ON w.id = d.webhook_id
+				WHERE d.id = $1 AND d.webhook_id = $2 AND w.tenant_id = $3`
+	delivery, err := scanDelivery(s.db.QueryRowContext(ctx, query, deliveryID, webhookID, tenantID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.WebhookDelivery{}, store.ErrDeliveryNotFound
+		}
+		return models.WebhookDelivery{}, err
+	}
+
+	delivery.Log, err = s.attempts(ctx, deliveryID)
+	if err != nil {
+		return models.WebhookDelivery{}, err
+	}
+	return delivery, nil
+}
+
+func (s Store) attempts(ctx context.Context, deliveryID string) ([]models.WebhookAttempt, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT response_code, error, duration_ms, attempted_at FROM webhook_attempt
+			WHERE delivery_id = $1 ORDER BY id`, deliveryID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var attempts []models.WebhookAttempt
+	for rows.Next() {
+		var attempt models.WebhookAttempt
+		if err := rows.Scan(&attempt.ResponseCode, &attempt.Error, &attempt.DurationMS, &attempt.AttemptedAt); err != nil {
+			return nil, err
+		}
+		attempts = append(attempts, attempt)
+	}
+	return attempts, rows.Err()
+}
+
+func (s Store) RedeliverDelivery(ctx context.Context, webhookID, deliveryID string, now time.Time) (models.WebhookDelivery, error) {
+	tracer := otel.Tracer("WebhookStore")
+	ctx, span := tracer.Start(ctx, "RedeliverDelivery-Store")
+	defer span.End()
+
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return models.WebhookDelivery{}, err
+	}
+
+	result, err := s.db.ExecContext(ctx,
+		`UPDATE webhook_delivery d
+			SET status = 'pending', attempts = 0, next_attempt_at = $1, delivered_at = NULL
+			FROM webhook w
+			WHERE w.id = d.webhook_id AND d.id = $2 AND d.webhook_id = $3 AND w.tenant_id = $4`,
+		now, deliveryID, webhookID, tenantID)
+	if err != nil {
+		return models.WebhookDelivery{}, err
+	}
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return models.WebhookDelivery{}, err
+	}
+	if updated == 0 {
+		return models.WebhookDelivery{}, store.ErrDeliveryNotFound
+	}
+	return s.GetDelivery(ctx, webhookID, deliveryID)
+}
+
+// EnqueueDeliveries matches the event filters here rather than in SQL, a
+// dealership has a handful of webhooks.
+func (s Store) EnqueueDeliveries(ctx context.Context, event models.Event, now time.Time) (int, error) {
+	tracer := otel.Tracer("WebhookStore")
+	ctx, span := tracer.Start(ctx, "EnqueueDeliveries-Store")
+	defer span.End()
+
+	rows, err := s.db.QueryContext(ctx, "SELECT "+webhookColumns+" FROM webhook WHERE tenant_id = $1", event.TenantID)
+	if err != nil {
+		return 0, err
+	}
+	var webhooks []models.Webhook
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		if err != nil {
+			rows.Close()
+			return 0, err
+		}
+		if webhook.Takes(event.Type) {
+			webhooks = append(webhooks, webhook)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return 0, err
+	}
+	queued := 0
+	for _, webhook := range webhooks {
+		result, err := s.db.ExecContext(ctx,
+			`INSERT INTO webhook_delivery (id, webhook_id, event_id, event_type, payload, next_attempt_at, created_at)
+				VALUES ($1, $2, $3, $4, $5, $6, $6)
+				ON CONFLICT (webhook_id, event_id) DO NOTHING`,
+			uuid.New(), webhook.ID, event.ID, event.Type, payload, now)
+		if err != nil {
+			return queued, err
+		}
+		inserted, err := result.RowsAffected()
+		if err != nil {
+			return queued, err
+		}
+		queued += int(inserted)
+	}
+	return queued, nil
+}
+
+// ClaimDeliveries skips the rows other instances are claiming at the same
+// time.
+func (s Store) ClaimDeliveries(ctx context.Context, now, until time.Time, limit int) ([]models.PendingDelivery, error) {
+	tracer := otel.Tracer("WebhookStore")
+	ctx, span := tracer.Start(ctx, "ClaimDeliveries-Store")
+	defer span.End()
+
+	rows, err := s.db.QueryContext(ctx,
+		`UPDATE webhook_delivery d SET next_attempt_at = $1
+			FROM webhook w
+			WHERE w.id = d.webhook_id AND d.id IN (
+				SELECT id FROM webhook_delivery
+				WHERE status = 'pending' AND next_attempt_at <= $2
+				ORDER BY next_attempt_at, created_at
+				LIMIT $3
+				FOR UPDATE SKIP LOCKED
+			)
+			RETURNING `+deliveryColumns+", w.url, w.secret", until, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var pending []models.PendingDelivery
+	for rows.Next() {
+		var next models.PendingDelivery
+		next.Delivery, err = scanDelivery(rows, &next.URL, &next.Secret)
+		if err != nil {
+			return nil, err
+		}
+		pending = append(pending, next)
+	}
+	return pending, rows.Err()
+}
+
+func (s Store) RecordAttempt(ctx context.Context, delivery models.WebhookDelivery, attempt models.WebhookAttempt) error {
+	tracer := otel.Tracer("WebhookStore")
+	ctx, span := tracer.Start(ctx, "RecordAttempt-Store")
+	defer span.End()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx,
+		`UPDATE webhook_delivery SET status = $1, attempts = $2, response_code = $3, last_error = $4,
+			next_attempt_at = $5, delivered_at = $6
+			WHERE id = $7`,
+		delivery.Status, delivery.Attempts, delivery.ResponseCode, delivery.Error,
+		delivery.NextAttemptAt, delivery.DeliveredAt, delivery.ID)
+	if err != nil {
+		return err
+	}
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		return store.ErrDeliveryNotFound
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO webhook_attempt (delivery_id, response_code, error, duration_ms, attempted_at)
+			VALUES ($1, $2, $3, $4, $5)`,
+		delivery.ID, attempt.ResponseCode, attempt.Error, attempt.DurationMS, attempt.AttemptedAt)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s Store) DeleteFinishedDeliveries(ctx context.Context, before time.Time) (int64, error) {
+	tracer := otel.Tracer("WebhookStore")
+	ctx, span := tracer.Start(ctx, "DeleteFinishedDeliveries-Store")
+	defer span.End()
+
+	result, err := s.db.ExecContext(ctx,
+		"DELETE FROM webhook_delivery WHERE status IN ('succeeded', 'failed') AND created_at < $1", before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,31 @@
+// Package tenant carries the dealership a request acts for. Stores read it
+// from the context and scope every query to it.
+package tenant
+
+import (
+	"context"
+	"errors"
+)
+
+// DefaultID is the dealership that data and tokens from before
+// multi-tenancy belong to.
+const DefaultID = "00000000-0000-0000-0000-000000000001"
+
+var ErrMissing = errors.New("tenant is required")
+
+type contextKey struct{}
+
+// WithTenant returns a context acting for the dealership id.
+func WithTenant(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the dealership of ctx, or ErrMissing when there is
+// none. Stores refuse to run without one rather than see every tenant.
+func FromContext(ctx context.Context) (string, error) {
+	id, _ := ctx.Value(contextKey{}).(string)
+	if id == "" {
+		return "", ErrMissing
+	}
+	return id, nil
+}
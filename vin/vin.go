@@ -0,0 +1,132 @@
+// Package vin validates and decodes vehicle identification numbers
+// (ISO 3779). Manufacturers come from a bundled WMI table, so decoding
+// works offline.
+package vin
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+var (
+	ErrLength     = errors.New("VIN must be 17 characters")
+	ErrCharacters = errors.New("VIN may only contain digits and capital letters except I, O and Q")
+	ErrCheckDigit = errors.New("VIN check digit does not match")
+)
+
+// transliteration gives the value of each VIN character in the check digit
+// sum, letters I, O and Q are not allowed.
+var transliteration = map[rune]int{
+	'A': 1, 'B': 2, 'C': 3, 'D': 4, 'E': 5, 'F': 6, 'G': 7, 'H': 8,
+	'J': 1, 'K': 2, 'L': 3, 'M': 4, 'N': 5, 'P': 7, 'R': 9,
+	'S': 2, 'T': 3, 'U': 4, 'V': 5, 'W': 6, 'X': 7, 'Y': 8, 'Z': 9,
+}
+
+var weights = [17]int{8, 7, 6, 5, 4, 3, 2, 10, 0, 9, 8, 7, 6, 5, 4, 3, 2}
+
+// yearCodes is the order of the model year character, repeating every
+// 30 years from 1980.
+const yearCodes = "ABCDEFGHJKLMNPRSTVWXY123456789"
+
+// Normalize upper-cases a VIN and strips the spaces and dashes people type
+// into it.
+func Normalize(vin string) string {
+	vin = strings.ToUpper(strings.TrimSpace(vin))
+	return strings.NewReplacer(" ", "", "-", "").Replace(vin)
+}
+
+// ValidateFormat checks the shape of a VIN: 17 characters, digits and
+// capital letters except I, O and Q.
+func ValidateFormat(vin string) error {
+	if len(vin) != 17 {
+		return ErrLength
+	}
+	for _, c := range vin {
+		if !valid(c) {
+			return ErrCharacters
+		}
+	}
+	return nil
+}
+
+// Validate checks the format of vin, and its check digit where the check
+// digit is mandatory: North America and China. Elsewhere the ninth
+// character is often not a check digit, see Decoded.CheckDigitValid.
+func Validate(vin string) error {
+	if err := ValidateFormat(vin); err != nil {
+		return err
+	}
+	if checkDigitRequired(vin) && !CheckDigitValid(vin) {
+		return ErrCheckDigit
+	}
+	return nil
+}
+
+// CheckDigit computes the check digit of vin, '0' to '9' or 'X'. vin must
+// have a valid format.
+func CheckDigit(vin string) byte {
+	sum := 0
+	for i, c := range vin {
+		sum += value(c) * weights[i]
+	}
+	if sum%11 == 10 {
+		return 'X'
+	}
+	return byte('0' + sum%11)
+}
+
+// CheckDigitValid reports whether the ninth character of vin is its check
+// digit.
+func CheckDigitValid(vin string) bool {
+	return ValidateFormat(vin) == nil && vin[8] == CheckDigit(vin)
+}
+
+// ModelYears returns the model years the tenth character of vin can stand
+// for, oldest first. The code repeats every 30 years.
+func ModelYears(vin string) []int {
+	if len(vin) != 17 {
+		return nil
+	}
+	i := strings.IndexByte(yearCodes, vin[9])
+	if i < 0 {
+		return nil
+	}
+	years := []int{}
+	for year := 1980 + i; year <= 2100; year += 30 {
+		years = append(years, year)
+	}
+	return years
+}
+
+// ModelYear picks the latest of ModelYears that is not after the year
+// following now, models go on sale the year before their model year. It
+// returns 0 when the character is not a year code.
+func ModelYear(vin string, now time.Time) int {
+	year := 0
+	for _, candidate := range ModelYears(vin) {
+		if candidate <= now.Year()+1 {
+			year = candidate
+		}
+	}
+	return year
+}
+
+func valid(c rune) bool {
+	if c >= '0' && c <= '9' {
+		return true
+	}
+	_, ok := transliteration[c]
+	return ok
+}
+
+func value(c rune) int {
+	if c >= '0' && c <= '9' {
+		return int(c - '0')
+	}
+	return transliteration[c]
+}
+
+func checkDigitRequired(vin string) bool {
+	return (vin[0] >= '1' && vin[0] <= '5') || vin[0] == 'L'
+}
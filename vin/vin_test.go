@@ -0,0 +1,81 @@
+package vin
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var now = time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+
+func TestCheckDigit(t *testing.T) {
+	assert.Equal(t, byte('3'), CheckDigit("1HGCM82633A004352"))
+	assert.Equal(t, byte('2'), CheckDigit("5YJ3E1EA7KF317000"))
+	assert.True(t, CheckDigitValid("1HGCM82633A004352"))
+	assert.False(t, CheckDigitValid("1HGCM82643A004352"))
+}
+
+func TestValidate(t *testing.T) {
+	assert.NoError(t, Validate("1HGCM82633A004352"))
+	assert.ErrorIs(t, Validate("1HGCM82633A00435"), ErrLength)
+	assert.ErrorIs(t, Validate("1HGCM82633O004352"), ErrCharacters)
+	assert.ErrorIs(t, Validate("1hgcm82633a004352"), ErrCharacters)
+
+	// the check digit is mandatory in North America, not in Europe
+	assert.ErrorIs(t, Validate("5YJ3E1EA7KF317000"), ErrCheckDigit)
+	assert.NoError(t, Validate("WBA3A5C5XCF256551"))
+}
+
+func TestModelYear(t *testing.T) {
+	assert.Equal(t, []int{1983, 2013, 2043, 2073}, ModelYears("1HGCM8263DA004352"))
+	assert.Equal(t, 2003, ModelYear("1HGCM82633A004352", now))
+	assert.Equal(t, 2019, ModelYear("5YJ3E1EA2KF317000", now))
+	// next year's models are on sale already
+	assert.Equal(t, 2027, ModelYear("5YJ3E1EA2VF317000", now))
+	assert.Equal(t, 1998, ModelYear("5YJ3E1EA2WF317000", now))
+	// 0 is not a year code
+	assert.Equal(t, 0, ModelYear("5YJ3E1EA20F317000", now))
+}
+
+func TestDecode(t *testing.T) {
+	decoded, err := Decode(" 1hgcm8-2633a004352 ", now)
+	require.NoError(t, err)
+	assert.Equal(t, "1HGCM82633A004352", decoded.VIN)
+	assert.Equal(t, "1HG", decoded.WMI)
+	assert.Equal(t, "CM8263", decoded.VDS)
+	assert.Equal(t, "3A004352", decoded.VIS)
+	assert.Equal(t, "North America", decoded.Region)
+	assert.Equal(t, 2003, decoded.ModelYear)
+	assert.Equal(t, "A", decoded.PlantCode)
+	assert.Equal(t, "004352", decoded.SerialNumber)
+	assert.True(t, decoded.CheckDigitValid)
+	require.NotNil(t, decoded.Manufacturer)
+	assert.Equal(t, "Honda", decoded.Manufacturer.Brand)
+	assert.Equal(t, "United States", decoded.Manufacturer.Country)
+
+	decoded, err = Decode("WBA3A5C5XCF256551", now)
+	require.NoError(t, err)
+	assert.Equal(t, "Europe", decoded.Region)
+	assert.Equal(t, "BMW", decoded.Manufacturer.Brand)
+	assert.False(t, decoded.CheckDigitValid)
+
+	// unknown manufacturers still decode
+	decoded, err = Decode("9ZZ3E1EA7KF317000", now)
+	require.NoError(t, err)
+	assert.Nil(t, decoded.Manufacturer)
+	assert.Equal(t, "South America", decoded.Region)
+
+	_, err = Decode("5YJ3E1EA7KF317000", now)
+	assert.ErrorIs(t, err, ErrCheckDigit)
+}
+
+func TestWMITable(t *testing.T) {
+	for wmi, m := range manufacturers {
+		assert.Len(t, wmi, 3)
+		assert.Equal(t, wmi, m.WMI)
+		assert.NoError(t, ValidateFormat(wmi+"00000000000000"), wmi)
+		assert.NotEmpty(t, m.Brand, wmi)
+	}
+}
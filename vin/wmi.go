@@ -0,0 +1,113 @@
+package vin
+
+import (
+	_ "embed"
+	"encoding/csv"
+	"strings"
+	"time"
+)
+
+//go:embed wmi.csv
+var wmiCSV string
+
+// Manufacturer is the maker a world manufacturer identifier is assigned to.
+type Manufacturer struct {
+	WMI     string `json:"wmi"`
+	Name    string `json:"name"`
+	Brand   string `json:"brand"`
+	Country string `json:"country"`
+}
+
+// Decoded is what a VIN tells about a vehicle.
+type Decoded struct {
+	VIN             string        `json:"vin"`
+	WMI             string        `json:"wmi"`
+	VDS             string        `json:"vds"`
+	VIS             string        `json:"vis"`
+	Region          string        `json:"region"`
+	Manufacturer    *Manufacturer `json:"manufacturer"`
+	ModelYear       int           `json:"model_year"`
+	CheckDigitValid bool          `json:"check_digit_valid"`
+	SerialNumber    string        `json:"serial_number"`
+	PlantCode       string        `json:"plant_code"`
+}
+
+var manufacturers = loadManufacturers(wmiCSV)
+
+func loadManufacturers(table string) map[string]Manufacturer {
+	records, err := csv.NewReader(strings.NewReader(table)).ReadAll()
+	if err != nil {
+		panic("vin: invalid WMI table: " + err.Error())
+	}
+
+	byWMI := make(map[string]Manufacturer, len(records))
+	for _, record := range records[1:] {
+		byWMI[record[0]] = Manufacturer{WMI: record[0], Name: record[1], Brand: record[2], Country: record[3]}
+	}
+	return byWMI
+}
+
+// LookupWMI finds the manufacturer of vin in the bundled table. Makers of
+// fewer than 1000 vehicles a year share a WMI ending in 9 and are told apart
+// by characters 12 to 14, those are looked up with them.
+func LookupWMI(vin string) (Manufacturer, bool) {
+	if len(vin) < 3 {
+		return Manufacturer{}, false
+	}
+	if vin[2] == '9' && len(vin) == 17 {
+		if m, ok := manufacturers[vin[:3]+vin[11:14]]; ok {
+			return m, true
+		}
+	}
+	m, ok := manufacturers[vin[:3]]
+	return m, ok
+}
+
+// Region is the part of the world the first character of vin is assigned
+// to.
+func Region(vin string) string {
+	if vin == "" {
+		return ""
+	}
+	switch c := vin[0]; {
+	case c >= 'A' && c <= 'H':
+		return "Africa"
+	case c >= 'J' && c <= 'R':
+		return "Asia"
+	case c >= 'S' && c <= 'Z':
+		return "Europe"
+	case c >= '1' && c <= '5':
+		return "North America"
+	case c == '6' || c == '7':
+		return "Oceania"
+	case c == '8' || c == '9':
+		return "South America"
+	}
+	return ""
+}
+
+// Decode validates vin and splits it into what it tells. Manufacturer is
+// nil when the WMI is not in the bundled table, ModelYear is 0 when the
+// tenth character is not a year code.
+func Decode(vin string, now time.Time) (*Decoded, error) {
+	vin = Normalize(vin)
+	if err := Validate(vin); err != nil {
+		return nil, err
+	}
+
+	decoded := &Decoded{
+		VIN:             vin,
+		WMI:             vin[:3],
+		VDS:             vin[3:9],
+		VIS:             vin[9:],
+		Region:          Region(vin),
+		ModelYear:       ModelYear(vin, now),
+		CheckDigitValid: CheckDigitValid(vin),
+		PlantCode:       vin[10:11],
+		SerialNumber:    vin[11:],
+	}
+	if m, ok := LookupWMI(vin); ok {
+		decoded.Manufacturer = &m
+	}
+	return decoded, nil
+}